@@ -0,0 +1,422 @@
+// Command e2e drives built extensions through the pair app's own extension
+// client and, when a pair binary is available, through pair's non-interactive
+// commands. It catches app↔extension protocol mismatches (argument order,
+// output envelopes, field names) that the extension-only tester cannot see.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// StepResult records the outcome of a single harness step
+type StepResult struct {
+	Extension string `json:"extension"`
+	Step      string `json:"step"`
+	Passed    bool   `json:"passed"`
+	Details   string `json:"details,omitempty"`
+	Duration  string `json:"duration"`
+}
+
+// Harness holds the temporary pair environment the extensions are installed into
+type Harness struct {
+	root       string // Temporary root holding the fake home and pair directories
+	scraperDir string // Directory pair loads installed extensions from
+	pairBinary string
+	verbose    bool
+	results    []StepResult
+
+	metadataOnly map[string]bool // IDs of installed sources declaring the metadata-only capability
+}
+
+// NewHarness creates a temporary pair environment
+func NewHarness(pairBinary string, verbose bool) (*Harness, error) {
+	root, err := os.MkdirTemp("", "pair-e2e-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp directory: %v", err)
+	}
+
+	h := &Harness{
+		root:       root,
+		scraperDir: filepath.Join(root, "data", "pair", "scrapers"),
+		pairBinary: pairBinary,
+		verbose:    verbose,
+
+		metadataOnly: map[string]bool{},
+	}
+
+	for _, dir := range []string{h.scraperDir, filepath.Join(root, "config", "pair"), filepath.Join(root, "home")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating %s: %v", dir, err)
+		}
+	}
+	return h, nil
+}
+
+// env returns the environment pointing pair at the temporary config
+func (h *Harness) env() []string {
+	return append(os.Environ(),
+		"HOME="+filepath.Join(h.root, "home"),
+		"XDG_CONFIG_HOME="+filepath.Join(h.root, "config"),
+		"XDG_DATA_HOME="+filepath.Join(h.root, "data"),
+		"XDG_CACHE_HOME="+filepath.Join(h.root, "cache"),
+		"PAIR_CONFIG_DIR="+filepath.Join(h.root, "config", "pair"),
+		"PAIR_DATA_DIR="+filepath.Join(h.root, "data", "pair"),
+		"PAIR_CACHE_DIR="+filepath.Join(h.root, "cache", "pair"),
+	)
+}
+
+// step runs fn and records its result
+func (h *Harness) step(extension, name string, fn func() (string, error)) bool {
+	start := time.Now()
+	details, err := fn()
+	result := StepResult{
+		Extension: extension,
+		Step:      name,
+		Passed:    err == nil,
+		Details:   details,
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+	}
+	if err != nil {
+		result.Details = err.Error()
+	}
+	h.results = append(h.results, result)
+
+	if h.verbose {
+		status := "✅"
+		if !result.Passed {
+			status = "❌"
+		}
+		fmt.Printf("  %s %s: %s\n", status, name, result.Details)
+	}
+	return result.Passed
+}
+
+// Install builds an extension and installs it into the temporary pair
+// scrapers directory together with its manifest, as pair's repo handler would
+func (h *Harness) Install(extensionDir string) (string, scraper.ExtensionInfo, error) {
+	var info scraper.ExtensionInfo
+
+	name := filepath.Base(extensionDir)
+	binaryName := name
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+
+	installDir := filepath.Join(h.scraperDir, name)
+	if err := os.MkdirAll(installDir, 0o755); err != nil {
+		return "", info, err
+	}
+	binaryPath := filepath.Join(installDir, binaryName)
+
+	absExtensionDir, err := filepath.Abs(extensionDir)
+	if err != nil {
+		return "", info, err
+	}
+
+	build := exec.Command("go", "build", "-tags", "nsfw", "-o", binaryPath, ".")
+	build.Dir = absExtensionDir
+	if output, err := build.CombinedOutput(); err != nil {
+		return "", info, fmt.Errorf("build failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	// Read the manifest directly; the pair client is exercised separately
+	stdout, err := exec.Command(binaryPath, "extension-info").Output()
+	if err != nil {
+		return "", info, fmt.Errorf("extension-info failed: %v", err)
+	}
+	var envelope struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(stdout, &envelope); err != nil || envelope.Status != "success" {
+		return "", info, fmt.Errorf("extension-info does not print the success envelope: %s", strings.TrimSpace(string(stdout)))
+	}
+	output := []byte(envelope.Data)
+	if err := json.Unmarshal(output, &info); err != nil {
+		return "", info, fmt.Errorf("invalid extension-info output: %v", err)
+	}
+	var capabilities struct {
+		Sources []struct {
+			ID           string   `json:"id"`
+			Capabilities []string `json:"capabilities"`
+		} `json:"sources"`
+	}
+	json.Unmarshal(output, &capabilities)
+	for _, source := range capabilities.Sources {
+		for _, capability := range source.Capabilities {
+			if capability == "metadata-only" {
+				h.metadataOnly[source.ID] = true
+			}
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(installDir, "manifest.json"), output, 0o644); err != nil {
+		return "", info, err
+	}
+	return binaryPath, info, nil
+}
+
+// RunClientFlow drives an installed extension through pair's own CLIScraper
+// client, following the first result for query to its streams. Without a
+// query only the extension and source info are checked.
+func (h *Harness) RunClientFlow(extension, binaryPath, query string, info scraper.ExtensionInfo) {
+	probe := scraper.NewCLIScraper(binaryPath, "")
+	h.step(extension, "client: extension-info", func() (string, error) {
+		clientInfo, err := probe.GetExtensionInfo()
+		if err != nil {
+			return "", err
+		}
+		if clientInfo.Package != info.Package {
+			return "", fmt.Errorf("package mismatch: client saw %q, manifest has %q", clientInfo.Package, info.Package)
+		}
+		return fmt.Sprintf("%d sources", len(clientInfo.Sources)), nil
+	})
+
+	for _, source := range info.Sources {
+		client := scraper.NewCLIScraper(binaryPath, source.ID)
+		prefix := fmt.Sprintf("client[%s]", source.Name)
+
+		h.step(extension, prefix+": source-info", func() (string, error) {
+			sourceInfo, err := client.GetSourceInfo()
+			if err != nil {
+				return "", err
+			}
+			if sourceInfo.ID != source.ID {
+				return "", fmt.Errorf("source ID mismatch: got %q, want %q", sourceInfo.ID, source.ID)
+			}
+			return sourceInfo.Name, nil
+		})
+
+		if query == "" {
+			continue
+		}
+
+		var animes []scraper.Anime
+		if !h.step(extension, prefix+": search", func() (string, error) {
+			var err error
+			animes, err = client.SearchAnime(query, 1, "")
+			if err == nil && len(animes) == 0 {
+				err = fmt.Errorf("no results for %q", query)
+			}
+			return fmt.Sprintf("%d results", len(animes)), err
+		}) {
+			continue
+		}
+
+		// Metadata-only sources describe anime without episodes or streams
+		if h.metadataOnly[source.ID] {
+			h.step(extension, prefix+": details", func() (string, error) {
+				anime, err := client.GetAnimeDetails(animes[0].ID)
+				if err == nil && anime.Title == "" {
+					err = fmt.Errorf("no title for %q", animes[0].ID)
+				}
+				return anime.Title, err
+			})
+			continue
+		}
+
+		var episodes []scraper.Episode
+		if !h.step(extension, prefix+": episodes", func() (string, error) {
+			var err error
+			episodes, err = client.GetEpisodeList(animes[0].ID)
+			if err == nil && len(episodes) == 0 {
+				err = fmt.Errorf("no episodes for %q", animes[0].ID)
+			}
+			return fmt.Sprintf("%d episodes", len(episodes)), err
+		}) {
+			continue
+		}
+
+		h.step(extension, prefix+": stream-url", func() (string, error) {
+			videos, err := client.GetVideoList(animes[0].ID, episodes[0].EpisodeNumber)
+			if err == nil && len(videos.Streams) == 0 {
+				// Torrent sources without a sidecar only list releases, which
+				// pair reaches through the magnet link instead
+				if magnet, magnetErr := client.GetMagnetLink(animes[0].ID, episodes[0].EpisodeNumber); magnetErr == nil && magnet != "" {
+					return "magnet link", nil
+				}
+				err = fmt.Errorf("no streams for episode %g", episodes[0].EpisodeNumber)
+			}
+			return fmt.Sprintf("%d streams", len(videos.Streams)), err
+		})
+	}
+}
+
+// RunAppFlow drives pair's non-interactive commands against the installed extensions
+func (h *Harness) RunAppFlow(extension, query string, info scraper.ExtensionInfo) {
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(h.pairBinary, args...)
+		cmd.Env = h.env()
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("pair %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+		return string(output), nil
+	}
+
+	h.step(extension, "app: extension list", func() (string, error) {
+		output, err := run("extension", "list")
+		if err != nil {
+			return "", err
+		}
+		if !strings.Contains(output, info.Package) && !strings.Contains(output, info.Name) {
+			return "", fmt.Errorf("installed extension %q not listed", info.Package)
+		}
+		return "extension listed", nil
+	})
+
+	h.step(extension, "app: scraper search", func() (string, error) {
+		if query == "" {
+			return "", fmt.Errorf("no search query for %s", extension)
+		}
+		output, err := run("scraper", "search", query)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(output) == "" {
+			return "", fmt.Errorf("empty search output")
+		}
+		return "search returned output", nil
+	})
+}
+
+// canaryQuery returns the query of the extension's first canary that follows
+// its result to streams, or of its first canary, as cmd/monitor runs them
+// against the fixtures. It is empty when the extension has no canaries.
+func canaryQuery(extensionDir string) string {
+	data, err := os.ReadFile(filepath.Join(extensionDir, "canaries.json"))
+	if err != nil {
+		return ""
+	}
+	var canaries []struct {
+		Query  string `json:"query"`
+		Stream bool   `json:"stream"`
+	}
+	if json.Unmarshal(data, &canaries) != nil || len(canaries) == 0 {
+		return ""
+	}
+	for _, canary := range canaries {
+		if canary.Stream {
+			return canary.Query
+		}
+	}
+	return canaries[0].Query
+}
+
+// Cleanup removes the temporary pair environment
+func (h *Harness) Cleanup() {
+	os.RemoveAll(h.root)
+}
+
+func main() {
+	var (
+		extensions = flag.String("extensions", "", "Comma-separated extension directories (default: all directories in src/)")
+		pairBinary = flag.String("pair", "", "Path to the pair binary; app-level checks are skipped when empty")
+		query      = flag.String("query", "naruto", "Search query used for the flow (with -mock, each extension's canary query)")
+		keep       = flag.Bool("keep", false, "Keep the temporary pair environment for inspection")
+		verbose    = flag.Bool("verbose", false, "Print every step as it runs")
+		jsonOutput = flag.Bool("json", false, "Print results as JSON")
+		mock       = flag.Bool("mock", false, "Serve the extensions' requests from their fixtures (via $PAIR_MOCK) instead of the live sites")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "E2E harness - Run extensions through the pair app's extension client\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s -extensions ./src/allanime -verbose\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -pair $(which pair)\n", os.Args[0])
+	}
+	flag.Parse()
+
+	dirs := []string{}
+	if *extensions != "" {
+		dirs = strings.Split(*extensions, ",")
+	} else {
+		entries, err := os.ReadDir("src")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading src directory: %v\n", err)
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, filepath.Join("src", entry.Name()))
+			}
+		}
+	}
+
+	h, err := NewHarness(*pairBinary, *verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *keep {
+		fmt.Fprintf(os.Stderr, "Keeping pair environment at %s\n", h.root)
+	} else {
+		defer h.Cleanup()
+	}
+
+	for _, dir := range dirs {
+		extension := filepath.Base(dir)
+		if *verbose {
+			fmt.Printf("🔌 %s\n", extension)
+		}
+
+		var binaryPath string
+		var info scraper.ExtensionInfo
+		if !h.step(extension, "install", func() (string, error) {
+			var err error
+			binaryPath, info, err = h.Install(dir)
+			return binaryPath, err
+		}) {
+			continue
+		}
+
+		// pair's client passes no flags, so the fixtures are named in the
+		// environment it inherits, and only the canary queries have fixtures
+		extensionQuery := *query
+		if *mock {
+			fixtures, _ := filepath.Abs(filepath.Join(dir, "fixtures"))
+			os.Setenv("PAIR_MOCK", fixtures)
+			extensionQuery = canaryQuery(dir)
+		}
+		h.RunClientFlow(extension, binaryPath, extensionQuery, info)
+		if *pairBinary != "" {
+			h.RunAppFlow(extension, extensionQuery, info)
+		}
+	}
+
+	failed := 0
+	for _, result := range h.results {
+		if !result.Passed {
+			failed++
+		}
+	}
+
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(h.results, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("\n🎯 E2E Summary: %d steps, %d passed, %d failed\n", len(h.results), len(h.results)-failed, failed)
+		for _, result := range h.results {
+			if !result.Passed {
+				fmt.Printf("  ❌ %s / %s: %s\n", result.Extension, result.Step, result.Details)
+			}
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
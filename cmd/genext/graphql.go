@@ -0,0 +1,404 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// introspection is the result of the standard GraphQL introspection query,
+// with or without the {"data": ...} envelope
+type introspection struct {
+	Data *struct {
+		Schema gqlSchema `json:"__schema"`
+	} `json:"data"`
+	Schema *gqlSchema `json:"__schema"`
+}
+
+type gqlSchema struct {
+	QueryType *struct {
+		Name string `json:"name"`
+	} `json:"queryType"`
+	Types []gqlType `json:"types"`
+}
+
+type gqlType struct {
+	Kind        string     `json:"kind"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Fields      []gqlField `json:"fields"`
+	InputFields []gqlInput `json:"inputFields"`
+	EnumValues  []struct {
+		Name string `json:"name"`
+	} `json:"enumValues"`
+}
+
+type gqlField struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Args        []gqlInput `json:"args"`
+	Type        gqlTypeRef `json:"type"`
+}
+
+type gqlInput struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Type        gqlTypeRef `json:"type"`
+}
+
+// gqlTypeRef is a possibly wrapped (NON_NULL, LIST) reference to a named type
+type gqlTypeRef struct {
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	OfType *gqlTypeRef `json:"ofType"`
+}
+
+// named returns the innermost named type
+func (r gqlTypeRef) named() gqlTypeRef {
+	for r.OfType != nil && r.Name == "" {
+		r = *r.OfType
+	}
+	return r
+}
+
+// String renders the reference in GraphQL syntax, e.g. "[Show!]!"
+func (r gqlTypeRef) String() string {
+	switch r.Kind {
+	case "NON_NULL":
+		return r.OfType.String() + "!"
+	case "LIST":
+		return "[" + r.OfType.String() + "]"
+	}
+	return r.Name
+}
+
+// builtinScalars maps the GraphQL built-in scalars to Go types; custom
+// scalars (JSON, Object, DateTime, ...) are kept as raw JSON
+var builtinScalars = map[string]string{
+	"String":  "string",
+	"ID":      "string",
+	"Int":     "int",
+	"Float":   "float64",
+	"Boolean": "bool",
+}
+
+// graphQLGenerator turns an introspected GraphQL schema into Go source
+type graphQLGenerator struct {
+	types map[string]gqlType
+	out   *strings.Builder
+}
+
+// generateGraphQL returns the Go source of a typed client for the schema in
+// data and the names of the generated methods. Only query fields are
+// generated: extensions read from their sources and never mutate them.
+func generateGraphQL(data []byte, source string) (string, []string, error) {
+	var doc introspection
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", nil, fmt.Errorf("error parsing introspection result: %v", err)
+	}
+	schema := doc.Schema
+	if doc.Data != nil {
+		schema = &doc.Data.Schema
+	}
+	if schema == nil || len(schema.Types) == 0 {
+		return "", nil, fmt.Errorf("introspection result has no __schema types")
+	}
+
+	g := &graphQLGenerator{types: map[string]gqlType{}, out: &strings.Builder{}}
+	for _, t := range schema.Types {
+		g.types[t.Name] = t
+	}
+
+	queryName := "Query"
+	if schema.QueryType != nil && schema.QueryType.Name != "" {
+		queryName = schema.QueryType.Name
+	}
+	query, ok := g.types[queryName]
+	if !ok {
+		return "", nil, fmt.Errorf("introspection result has no %s type", queryName)
+	}
+
+	fmt.Fprintf(g.out, "// Code generated by genext from %s; DO NOT EDIT.\n\n", source)
+	g.out.WriteString("package main\n\n")
+	g.out.WriteString(graphQLClientCode)
+
+	methods := []string{}
+	for _, field := range query.Fields {
+		methods = append(methods, g.queryMethod(field))
+	}
+
+	names := make([]string, 0, len(g.types))
+	for name, t := range g.types {
+		if strings.HasPrefix(name, "__") || name == queryName || t.Kind == "SCALAR" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.typeDecl(g.types[name])
+	}
+
+	return g.out.String(), methods, nil
+}
+
+// goType returns the Go type for a GraphQL type reference. Nullable input
+// objects become pointers so optional arguments can be left out.
+func (g *graphQLGenerator) goType(ref gqlTypeRef, input bool) string {
+	switch ref.Kind {
+	case "NON_NULL":
+		return g.goType(*ref.OfType, false)
+	case "LIST":
+		return "[]" + g.goType(*ref.OfType, false)
+	case "SCALAR":
+		if goType, ok := builtinScalars[ref.Name]; ok {
+			return goType
+		}
+		return "json.RawMessage"
+	case "UNION":
+		return "json.RawMessage"
+	case "INPUT_OBJECT":
+		if input {
+			return "*" + typeName(ref.Name)
+		}
+	}
+	return typeName(ref.Name)
+}
+
+// typeDecl writes the Go declaration of an object, interface, input object or enum
+func (g *graphQLGenerator) typeDecl(t gqlType) {
+	name := typeName(t.Name)
+	if t.Description != "" {
+		fmt.Fprintf(g.out, "// %s: %s\n", name, comment(t.Description))
+	}
+
+	switch t.Kind {
+	case "ENUM":
+		fmt.Fprintf(g.out, "type %s string\n\n", name)
+		g.out.WriteString("const (\n")
+		for _, value := range t.EnumValues {
+			fmt.Fprintf(g.out, "\t%s%s %s = %q\n", name, goName(value.Name), name, value.Name)
+		}
+		g.out.WriteString(")\n\n")
+		return
+	case "UNION":
+		fmt.Fprintf(g.out, "type %s = json.RawMessage\n\n", name)
+		return
+	}
+
+	fmt.Fprintf(g.out, "type %s struct {\n", name)
+	if t.Kind == "INPUT_OBJECT" {
+		for _, field := range t.InputFields {
+			g.structField(field.Name, field.Description, field.Type, true)
+		}
+	} else {
+		for _, field := range t.Fields {
+			g.structField(field.Name, field.Description, field.Type, false)
+		}
+	}
+	g.out.WriteString("}\n\n")
+}
+
+// structField writes one struct field. Every field is omitempty: object
+// fields are only present when selected, and nullable inputs may be left out.
+func (g *graphQLGenerator) structField(name, description string, ref gqlTypeRef, input bool) {
+	fieldType := g.goType(ref, input)
+	// Singular objects are pointers, so types may refer to themselves
+	kind := ref.named().Kind
+	if (kind == "OBJECT" || kind == "INTERFACE" || kind == "INPUT_OBJECT") &&
+		!strings.HasPrefix(fieldType, "*") && !strings.HasPrefix(fieldType, "[]") {
+		fieldType = "*" + fieldType
+	}
+	line := fmt.Sprintf("\t%s %s `json:\"%s,omitempty\"`", goName(name), fieldType, name)
+	if description != "" {
+		line += " // " + comment(description)
+	}
+	g.out.WriteString(line + "\n")
+}
+
+// selection returns the default selection set of an object type: its scalar
+// and enum fields without arguments. Nested objects are left to the caller.
+func (g *graphQLGenerator) selection(ref gqlTypeRef) string {
+	t, ok := g.types[ref.named().Name]
+	if !ok || (t.Kind != "OBJECT" && t.Kind != "INTERFACE") {
+		return ""
+	}
+	fields := []string{}
+	for _, field := range t.Fields {
+		kind := field.Type.named().Kind
+		if len(field.Args) == 0 && (kind == "SCALAR" || kind == "ENUM") {
+			fields = append(fields, field.Name)
+		}
+	}
+	if len(fields) == 0 {
+		// A selection set cannot be empty
+		fields = append(fields, "__typename")
+	}
+	return strings.Join(fields, " ")
+}
+
+// queryMethod writes a client method for one query field and returns its name
+func (g *graphQLGenerator) queryMethod(field gqlField) string {
+	name := goName(field.Name)
+	resultType := g.goType(field.Type, false)
+	selection := g.selection(field.Type)
+
+	// Arguments are passed in a struct so nullable ones can be left out
+	argsType := ""
+	if len(field.Args) > 0 {
+		argsType = name + "Args"
+		fmt.Fprintf(g.out, "// %s holds the arguments of the %s query\n", argsType, field.Name)
+		fmt.Fprintf(g.out, "type %s struct {\n", argsType)
+		for _, arg := range field.Args {
+			line := fmt.Sprintf("\t%s %s", goName(arg.Name), g.goType(arg.Type, true))
+			doc := comment(arg.Description)
+			if arg.Type.Kind == "NON_NULL" {
+				doc = strings.TrimSuffix("Required. "+doc, " ")
+			}
+			if doc != "" {
+				line += " // " + doc
+			}
+			g.out.WriteString(line + "\n")
+		}
+		g.out.WriteString("}\n\n")
+	}
+
+	if selection != "" {
+		fmt.Fprintf(g.out, "// %sSelection is the default selection set of the %s query\n", paramName(field.Name), field.Name)
+		fmt.Fprintf(g.out, "const %sSelection = %q\n\n", paramName(field.Name), selection)
+	}
+
+	fmt.Fprintf(g.out, "// %s runs the %s query", name, field.Name)
+	if doc := comment(field.Description); doc != "" {
+		g.out.WriteString(": " + strings.TrimSuffix(doc, "."))
+	}
+	params := []string{"ctx context.Context"}
+	if argsType != "" {
+		params = append(params, "args "+argsType)
+	}
+	if selection != "" {
+		g.out.WriteString(". An empty selection requests the scalar fields of the result.")
+		params = append(params, "selection string")
+	}
+	g.out.WriteString("\n")
+	fmt.Fprintf(g.out, "func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(params, ", "), resultType)
+
+	// Build the operation from the declared argument types
+	declarations := []string{}
+	arguments := []string{}
+	for _, arg := range field.Args {
+		declarations = append(declarations, "$"+arg.Name+": "+arg.Type.String())
+		arguments = append(arguments, arg.Name+": $"+arg.Name)
+	}
+	operation := "query"
+	call := field.Name
+	if len(field.Args) > 0 {
+		operation += "(" + strings.Join(declarations, ", ") + ")"
+		call += "(" + strings.Join(arguments, ", ") + ")"
+	}
+
+	if selection != "" {
+		fmt.Fprintf(g.out, "\tif selection == \"\" {\n\t\tselection = %sSelection\n\t}\n", paramName(field.Name))
+		fmt.Fprintf(g.out, "\tquery := %q + selection + \" } }\"\n", operation+" { "+call+" { ")
+	} else {
+		fmt.Fprintf(g.out, "\tquery := %q\n", operation+" { "+call+" }")
+	}
+
+	g.out.WriteString("\tvariables := map[string]interface{}{}\n")
+	for _, arg := range field.Args {
+		fmt.Fprintf(g.out, "\tsetVariable(variables, %q, args.%s, %t)\n", arg.Name, goName(arg.Name), arg.Type.Kind == "NON_NULL")
+	}
+
+	fmt.Fprintf(g.out, "\tvar data struct {\n\t\tResult %s `json:%q`\n\t}\n", resultType, field.Name)
+	g.out.WriteString("\terr := c.query(ctx, query, variables, &data)\n")
+	g.out.WriteString("\treturn data.Result, err\n}\n\n")
+	return name
+}
+
+// graphQLClientCode is the fixed part of a generated GraphQL client
+const graphQLClientCode = `import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Client sends GraphQL queries to the API endpoint
+type Client struct {
+	Endpoint string
+	HTTP     *httpclient.Client
+	Headers  map[string]string // Sent with every request, e.g. User-Agent or an API token
+	Retry    httpclient.RetryPolicy
+}
+
+// NewClient creates a client for the GraphQL endpoint using the shared HTTP client
+func NewClient(endpoint string, client *httpclient.Client) *Client {
+	return &Client{Endpoint: endpoint, HTTP: client, Headers: map[string]string{}, Retry: httpclient.DefaultRetryPolicy}
+}
+
+// query posts a GraphQL query and decodes its data into v
+func (c *Client) query(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("error encoding query: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.HTTP.DoRetry(req, c.Retry)
+	if err != nil {
+		return exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+
+	var result struct {
+		Data   json.RawMessage ` + "`json:\"data\"`" + `
+		Errors []struct {
+			Message string ` + "`json:\"message\"`" + `
+		} ` + "`json:\"errors\"`" + `
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = e.Message
+		}
+		return exterr.New(exterr.Upstream, "error from API: %s", strings.Join(messages, "; "))
+	}
+	if err := json.Unmarshal(result.Data, v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response: %w", err)
+	}
+	return nil
+}
+
+// setVariable adds a query variable, leaving out nullable arguments with zero values
+func setVariable(variables map[string]interface{}, name string, value interface{}, required bool) {
+	v := reflect.ValueOf(value)
+	if !required && (!v.IsValid() || v.IsZero()) {
+		return
+	}
+	variables[name] = value
+}
+
+`
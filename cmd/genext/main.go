@@ -0,0 +1,351 @@
+// Command genext generates an extension for a source with a documented API.
+// Given an OpenAPI 3 document or a GraphQL introspection result it writes:
+//
+//   - client.go: typed models and one method per API operation (or query
+//     field), sending requests through the shared httpclient so -debug,
+//     -mock, retries and rate limiting work as in hand-written extensions.
+//     It is regenerated on every run.
+//   - main.go: a scraper skeleton wired to the extension CLI (flags, JSON
+//     output, source ID checks, permissions) whose search, episodes and
+//     stream-url methods are left to fill in with calls to the client.
+//     It is only written when missing, or with -force.
+//   - fixtures/routes.json: an empty mock route table.
+//
+// Usage:
+//
+//	go run ./cmd/genext -name anilibria -display AniLibria -lang ru -openapi anilibria.json
+//	go run ./cmd/genext -name animeonsen -graphql schema.json -base-url https://api.example.com/graphql
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"go/format"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// packageName is the form extension package names take: lowercase letters and digits
+var packageName = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+func main() {
+	var (
+		name    = flag.String("name", "", "Extension package name, e.g. anilibria (required)")
+		display = flag.String("display", "", "Display name (defaults to the package name, capitalized)")
+		lang    = flag.String("lang", "en", "Source language code")
+		baseURL = flag.String("base-url", "", "API base URL, or the GraphQL endpoint (defaults to the first OpenAPI server)")
+		site    = flag.String("site", "", "Website URL shown as the source base URL (defaults to the API host)")
+		openAPI = flag.String("openapi", "", "OpenAPI 3 document (JSON)")
+		graphQL = flag.String("graphql", "", "GraphQL introspection result (JSON)")
+		outDir  = flag.String("out", "", "Output directory (default src/<name>)")
+		force   = flag.Bool("force", false, "Overwrite an existing main.go")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -name NAME (-openapi SPEC | -graphql SCHEMA) [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Generate a typed API client and an extension skeleton for an API-backed source.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if !packageName.MatchString(*name) {
+		fmt.Fprintf(os.Stderr, "Error: -name must be lowercase letters and digits, got %q\n", *name)
+		os.Exit(1)
+	}
+	if (*openAPI == "") == (*graphQL == "") {
+		fmt.Fprintf(os.Stderr, "Error: exactly one of -openapi and -graphql is required\n")
+		os.Exit(1)
+	}
+	if *display == "" {
+		*display = strings.ToUpper((*name)[:1]) + (*name)[1:]
+	}
+	if *outDir == "" {
+		*outDir = filepath.Join("src", *name)
+	}
+
+	var (
+		client  string
+		methods []string
+		kind    = "OpenAPI"
+		err     error
+	)
+	if *openAPI != "" {
+		data, readErr := os.ReadFile(*openAPI)
+		if readErr != nil {
+			fmt.Fprintf(os.Stderr, "Error reading OpenAPI document: %v\n", readErr)
+			os.Exit(1)
+		}
+		var server string
+		client, methods, server, err = generateOpenAPI(data, filepath.Base(*openAPI))
+		if *baseURL == "" {
+			*baseURL = server
+		}
+	} else {
+		kind = "GraphQL"
+		data, readErr := os.ReadFile(*graphQL)
+		if readErr != nil {
+			fmt.Fprintf(os.Stderr, "Error reading introspection result: %v\n", readErr)
+			os.Exit(1)
+		}
+		client, methods, err = generateGraphQL(data, filepath.Base(*graphQL))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	api, parseErr := url.Parse(*baseURL)
+	if *baseURL == "" || parseErr != nil || api.Host == "" {
+		fmt.Fprintf(os.Stderr, "Error: -base-url must be an absolute URL (the document does not name a server)\n")
+		os.Exit(1)
+	}
+	if *site == "" {
+		*site = api.Scheme + "://" + api.Host
+	}
+
+	skeleton := skeletonData{
+		Name:     *name,
+		Display:  *display,
+		Lang:     *lang,
+		SourceID: sourceID(*name, *lang),
+		BaseURL:  strings.TrimRight(*baseURL, "/"),
+		Site:     *site,
+		Host:     api.Hostname(),
+		Kind:     kind,
+		Methods:  methods,
+	}
+
+	if err := os.MkdirAll(filepath.Join(*outDir, "fixtures"), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := writeGo(filepath.Join(*outDir, "client.go"), []byte(client)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d %s operations)\n", filepath.Join(*outDir, "client.go"), len(methods), kind)
+
+	mainPath := filepath.Join(*outDir, "main.go")
+	if _, statErr := os.Stat(mainPath); statErr == nil && !*force {
+		fmt.Fprintf(os.Stderr, "Kept %s (use -force to regenerate it)\n", mainPath)
+	} else {
+		var source bytes.Buffer
+		if err := skeletonTemplate.Execute(&source, skeleton); err != nil {
+			fmt.Fprintf(os.Stderr, "Error rendering skeleton: %v\n", err)
+			os.Exit(1)
+		}
+		if err := writeGo(mainPath, source.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", mainPath)
+	}
+
+	routesPath := filepath.Join(*outDir, "fixtures", "routes.json")
+	if _, statErr := os.Stat(routesPath); statErr != nil {
+		if err := os.WriteFile(routesPath, []byte("[]\n"), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", routesPath, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", routesPath)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nNext: implement SearchAnime, GetEpisodeList and GetVideoList in %s, then run\n", mainPath)
+	fmt.Fprintf(os.Stderr, "  make test EXTENSION_PATH=./%s\n", filepath.ToSlash(*outDir))
+}
+
+// writeGo formats Go source and writes it to path. Unformattable source is
+// still written, so the compiler can point at the problem.
+func writeGo(path string, source []byte) error {
+	formatted, err := format.Source(source)
+	if err != nil {
+		os.WriteFile(path, source, 0o644)
+		return fmt.Errorf("error formatting %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, formatted, 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return nil
+}
+
+// sourceID derives a stable source ID from the package name and language,
+// the way Tachiyomi-style extensions do: the first 8 bytes of an MD5 digest
+// as a positive 64-bit integer
+func sourceID(name, lang string) string {
+	sum := md5.Sum([]byte(strings.ToLower(name) + "/" + lang + "/1"))
+	return fmt.Sprint(binary.BigEndian.Uint64(sum[:8]) & math.MaxInt64)
+}
+
+// skeletonData fills skeletonTemplate
+type skeletonData struct {
+	Name     string
+	Display  string
+	Lang     string
+	SourceID string
+	BaseURL  string // API base URL or GraphQL endpoint
+	Site     string // Website URL
+	Host     string // API host, declared as a network permission
+	Kind     string // "OpenAPI" or "GraphQL"
+	Methods  []string
+}
+
+var skeletonTemplate = template.Must(template.New("main.go").Parse(`package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// sourceID identifies the {{.Display}} source
+const sourceID = "{{.SourceID}}"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// errNotImplemented is returned by the commands still to be written
+var errNotImplemented = exterr.New(exterr.Unsupported, "not implemented")
+
+type Scraper struct {
+	client *httpclient.Client
+	api    *Client // Generated from the {{.Kind}} schema, see client.go
+}
+
+// NewScraper creates a new instance of the {{.Name}} scraper
+func NewScraper() *Scraper {
+	client := httpclient.New()
+	return &Scraper{
+		client: client,
+		api:    NewClient("{{.BaseURL}}", client),
+	}
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Permissions permissions.Permissions ` + "`json:\"permissions\"`" + `
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, _ := s.GetSourceInfo()
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "{{.Display}}",
+			Package: "{{.Name}}",
+			Lang:    "{{.Lang}}",
+			Version: version,
+			Sources: []scraper.SourceInfo{source},
+		},
+		Permissions: permissions.Permissions{
+			// List the hosts streams are served from here as concrete domains, with
+			// permissions.Domains for CDN subdomains and hosters.NetworkDomains for
+			// shared video hosts
+			Network:    []string{"{{.Host}}"},
+			// doctor checks each storage directory by creating and removing a probe file
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions (write, doctor)",
+				"$PAIR_DATA_DIR/extensions/{{.Name}} (write, doctor)",
+				"$PAIR_CACHE_DIR/extensions/{{.Name}} (write, doctor)",
+			},
+			Binaries:   []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (scraper.SourceInfo, error) {
+	return scraper.SourceInfo{
+		ID:             sourceID,
+		Name:           "{{.Display}}",
+		BaseURL:        "{{.Site}}",
+		Language:       "{{.Lang}}",
+		SupportsSearch: true,
+	}, nil
+}
+
+// The commands below are left to implement with the generated API calls:
+//
+{{- range .Methods}}
+//	s.api.{{.}}
+{{- end}}
+
+// SearchAnime searches the source for anime matching query
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	return nil, errNotImplemented
+}
+
+// GetEpisodeList returns the episodes of an anime
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	return nil, errNotImplemented
+}
+
+// GetVideoList returns the streams of an episode
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (scraper.VideoResponse, error) {
+	return scraper.VideoResponse{}, errNotImplemented
+}
+
+// domains returns the hosts doctor checks
+func (s *Scraper) domains() []string {
+	return []string{"{{.Host}}"}
+}
+
+func main() {
+	var (
+		query    = flag.String("query", "", "Search query")
+		page     = flag.Int("page", 1, "Page number")
+		animeURL = flag.String("anime", "", "Anime URL")
+		episode  = flag.Float64("episode", 0, "Episode number")
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:  "{{.Name}}",
+		SourceID: sourceID,
+		Version:  version,
+		Client:   s.client,
+		ExtensionInfo: func() (interface{}, error) {
+			return s.GetExtensionInfo()
+		},
+		SourceInfo: func() (interface{}, error) {
+			return s.GetSourceInfo()
+		},
+		Domains: s.domains,
+	}
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime on a source.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "episodes", Description: "Get the list of episodes for an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetEpisodeList(ctx, *animeURL)
+		}},
+		{Name: "stream-url", Description: "Get the direct video stream URL for an anime episode.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			return s.GetVideoList(ctx, *animeURL, *episode)
+		}},
+	}
+	app.Main()
+}
+`))
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// initialisms are written in upper case in Go names, following Go conventions
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "API": true, "HTTP": true, "JSON": true,
+	"HTML": true, "UUID": true, "IP": true, "SQL": true, "TV": true,
+}
+
+// reservedNames are identifiers the generated files declare themselves
+var reservedNames = map[string]bool{
+	"Client": true, "NewClient": true, "Scraper": true, "NewScraper": true,
+	"ExtensionInfo": true, "VideoResponse": true,
+}
+
+// goName converts a schema name such as "title_list", "episode-info" or
+// "animeId" into an exported Go identifier ("TitleList", "EpisodeInfo", "AnimeID")
+func goName(name string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) > 0 &&
+			(unicode.IsLower(word[len(word)-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			// A new word starts at "aB" and at the last capital of "ABc"
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		if w == strings.ToUpper(w) {
+			// SCREAMING_CASE enum values read better as "NotYetReleased"
+			w = strings.ToLower(w)
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+
+	result := b.String()
+	if result == "" {
+		return "Value"
+	}
+	if unicode.IsDigit([]rune(result)[0]) {
+		result = "N" + result
+	}
+	return result
+}
+
+// typeName returns the Go name of a schema type, renaming names the generated files already use
+func typeName(name string) string {
+	result := goName(name)
+	if reservedNames[result] {
+		result += "Type"
+	}
+	return result
+}
+
+// paramName returns an unexported Go identifier for a parameter or variable
+func paramName(name string) string {
+	exported := goName(name)
+	for i, r := range exported {
+		if !unicode.IsUpper(r) {
+			if i > 1 {
+				// Keep the last capital of a leading initialism: "IDList" → "idList"
+				i--
+			}
+			return strings.ToLower(exported[:i]) + exported[i:]
+		}
+	}
+	return strings.ToLower(exported)
+}
+
+// comment turns schema documentation into a single-line Go comment body
+func comment(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// openAPISpec is the subset of an OpenAPI 3 document the generator reads
+type openAPISpec struct {
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schema   `json:"schemas"`
+		Parameters map[string]parameter `json:"parameters"`
+		Responses  map[string]response  `json:"responses"`
+	} `json:"components"`
+}
+
+// schema is an OpenAPI schema object
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 interface{}        `json:"type"` // A string, or a list in OpenAPI 3.1
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Enum                 []interface{}      `json:"enum"`
+	AllOf                []*schema          `json:"allOf"`
+	OneOf                []*schema          `json:"oneOf"`
+	AnyOf                []*schema          `json:"anyOf"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type mediaTypes map[string]struct {
+	Schema *schema `json:"schema"`
+}
+
+type response struct {
+	Ref     string     `json:"$ref"`
+	Content mediaTypes `json:"content"`
+}
+
+type openAPIOperation struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Description string      `json:"description"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content mediaTypes `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]response `json:"responses"`
+}
+
+// httpMethods are the path item keys that describe operations
+var httpMethods = []string{"get", "post", "put", "patch", "delete"}
+
+// openAPIGenerator turns an OpenAPI document into Go source
+type openAPIGenerator struct {
+	spec  openAPISpec
+	types map[string]bool // Go types already emitted
+	out   *strings.Builder
+	decls []string // Type declarations, emitted after the client
+}
+
+// generateOpenAPI returns the Go source of a typed client for the API in data
+// and the names of the generated methods
+func generateOpenAPI(data []byte, source string) (string, []string, string, error) {
+	g := &openAPIGenerator{types: map[string]bool{}, out: &strings.Builder{}}
+	if err := json.Unmarshal(data, &g.spec); err != nil {
+		return "", nil, "", fmt.Errorf("error parsing OpenAPI document: %v", err)
+	}
+	if len(g.spec.Paths) == 0 {
+		return "", nil, "", fmt.Errorf("OpenAPI document has no paths (only JSON OpenAPI 3 documents are supported)")
+	}
+
+	baseURL := ""
+	if len(g.spec.Servers) > 0 {
+		baseURL = g.spec.Servers[0].URL
+	}
+
+	names := make([]string, 0, len(g.spec.Components.Schemas))
+	for name := range g.spec.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.namedType(typeName(name), g.spec.Components.Schemas[name])
+	}
+
+	fmt.Fprintf(g.out, "// Code generated by genext from %s; DO NOT EDIT.\n\n", source)
+	g.out.WriteString("package main\n\n")
+	g.out.WriteString(openAPIClientCode(g.spec.Info.Title, comment(g.spec.Info.Description)))
+
+	paths := make([]string, 0, len(g.spec.Paths))
+	for path := range g.spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	methods := []string{}
+	for _, path := range paths {
+		for _, method := range httpMethods {
+			raw, ok := g.spec.Paths[path][method]
+			if !ok {
+				continue
+			}
+			var op openAPIOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return "", nil, "", fmt.Errorf("error parsing %s %s: %v", strings.ToUpper(method), path, err)
+			}
+			// Parameters shared by every operation of the path
+			var shared []parameter
+			if rawShared, ok := g.spec.Paths[path]["parameters"]; ok {
+				json.Unmarshal(rawShared, &shared)
+			}
+			op.Parameters = append(shared, op.Parameters...)
+
+			methods = append(methods, g.operation(method, path, op))
+		}
+	}
+
+	for _, decl := range g.decls {
+		g.out.WriteString(decl)
+	}
+	return g.out.String(), methods, baseURL, nil
+}
+
+// resolveSchema follows a local $ref to its component schema
+func (g *openAPIGenerator) resolveSchema(s *schema) (string, *schema) {
+	if s == nil || s.Ref == "" {
+		return "", s
+	}
+	name := s.Ref[strings.LastIndex(s.Ref, "/")+1:]
+	return name, g.spec.Components.Schemas[name]
+}
+
+// schemaType returns the Go type for s, declaring a named type called hint for inline objects
+func (g *openAPIGenerator) schemaType(s *schema, hint string) string {
+	if s == nil {
+		return "json.RawMessage"
+	}
+	if s.Ref != "" {
+		name, _ := g.resolveSchema(s)
+		return typeName(name)
+	}
+	if len(s.AllOf) == 1 {
+		return g.schemaType(s.AllOf[0], hint)
+	}
+	if len(s.AllOf) > 1 {
+		// Merge the members into one object
+		merged := &schema{Type: "object", Properties: map[string]*schema{}, Description: s.Description}
+		for _, member := range s.AllOf {
+			_, resolved := g.resolveSchema(member)
+			if resolved == nil {
+				continue
+			}
+			for name, property := range resolved.Properties {
+				merged.Properties[name] = property
+			}
+			merged.Required = append(merged.Required, resolved.Required...)
+		}
+		return g.namedType(hint, merged)
+	}
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return "json.RawMessage"
+	}
+
+	switch schemaKind(s) {
+	case "string":
+		return "string"
+	case "integer":
+		if s.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.schemaType(s.Items, hint+"Item")
+	case "object":
+		if len(s.Properties) > 0 {
+			return g.namedType(hint, s)
+		}
+		var additional schema
+		if json.Unmarshal(s.AdditionalProperties, &additional) == nil && (additional.Ref != "" || additional.Type != nil) {
+			return "map[string]" + g.schemaType(&additional, hint+"Value")
+		}
+	}
+	return "json.RawMessage"
+}
+
+// isStruct reports whether s is declared as a Go struct
+func (g *openAPIGenerator) isStruct(s *schema) bool {
+	if s == nil {
+		return false
+	}
+	if s.Ref != "" {
+		_, resolved := g.resolveSchema(s)
+		return resolved != nil && resolved.Ref == "" && g.isStruct(resolved)
+	}
+	if len(s.AllOf) == 1 {
+		return g.isStruct(s.AllOf[0])
+	}
+	return len(s.AllOf) > 1 || (len(s.OneOf) == 0 && len(s.AnyOf) == 0 && len(s.Properties) > 0)
+}
+
+// schemaKind returns the JSON type of s, taking the first non-null type of an OpenAPI 3.1 type list
+func schemaKind(s *schema) string {
+	switch kind := s.Type.(type) {
+	case string:
+		return kind
+	case []interface{}:
+		for _, entry := range kind {
+			if name, ok := entry.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	if len(s.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// namedType declares a Go type called name for s and returns name
+func (g *openAPIGenerator) namedType(name string, s *schema) string {
+	if g.types[name] {
+		return name
+	}
+	g.types[name] = true
+
+	var b strings.Builder
+	if s.Description != "" {
+		fmt.Fprintf(&b, "// %s: %s\n", name, comment(s.Description))
+	}
+
+	kind := schemaKind(s)
+	switch {
+	case s.Ref != "" || len(s.AllOf) > 0 || (kind != "object" && kind != ""):
+		// Aliases of other schemas, arrays and scalars
+		fmt.Fprintf(&b, "type %s %s\n\n", name, g.schemaType(s, name+"Value"))
+		if kind == "string" && len(s.Enum) > 0 {
+			b.WriteString("const (\n")
+			for _, value := range s.Enum {
+				if text, ok := value.(string); ok {
+					fmt.Fprintf(&b, "\t%s%s %s = %s\n", name, goName(text), name, strconv.Quote(text))
+				}
+			}
+			b.WriteString(")\n\n")
+		}
+	case len(s.Properties) == 0:
+		fmt.Fprintf(&b, "type %s = json.RawMessage\n\n", name)
+	default:
+		required := map[string]bool{}
+		for _, field := range s.Required {
+			required[field] = true
+		}
+		properties := make([]string, 0, len(s.Properties))
+		for property := range s.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+
+		fmt.Fprintf(&b, "type %s struct {\n", name)
+		for _, property := range properties {
+			field := s.Properties[property]
+			tag := property
+			if !required[property] {
+				tag += ",omitempty"
+			}
+			fieldType := g.schemaType(field, name+goName(property))
+			if g.isStruct(field) {
+				// Singular objects are pointers, so schemas may refer to themselves
+				fieldType = "*" + fieldType
+			}
+			line := fmt.Sprintf("\t%s %s `json:%q`", goName(property), fieldType, tag)
+			if field.Description != "" {
+				line += " // " + comment(field.Description)
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	g.decls = append(g.decls, b.String())
+	return name
+}
+
+// resolveParameter follows a local $ref to its component parameter
+func (g *openAPIGenerator) resolveParameter(p parameter) parameter {
+	if p.Ref == "" {
+		return p
+	}
+	return g.spec.Components.Parameters[p.Ref[strings.LastIndex(p.Ref, "/")+1:]]
+}
+
+// jsonSchema returns the schema of the JSON media type in content
+func jsonSchema(content mediaTypes) *schema {
+	for mediaType, media := range content {
+		if strings.Contains(mediaType, "json") {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+// operation writes a client method for one operation and returns its name
+func (g *openAPIGenerator) operation(method, path string, op openAPIOperation) string {
+	name := goName(op.OperationID)
+	if op.OperationID == "" {
+		name = goName(method + " " + strings.NewReplacer("{", "by ", "}", "").Replace(path))
+	}
+
+	var pathParams, queryParams []parameter
+	for _, p := range op.Parameters {
+		p = g.resolveParameter(p)
+		switch p.In {
+		case "path":
+			pathParams = append(pathParams, p)
+		case "query":
+			queryParams = append(queryParams, p)
+		}
+	}
+
+	// The request body, if any
+	bodyType := ""
+	if op.RequestBody != nil {
+		if s := jsonSchema(op.RequestBody.Content); s != nil {
+			bodyType = g.schemaType(s, name+"Body")
+		}
+	}
+
+	// The first successful JSON response
+	resultType := ""
+	for _, code := range []string{"200", "201", "202", "default"} {
+		resp, ok := op.Responses[code]
+		if !ok {
+			continue
+		}
+		if resp.Ref != "" {
+			resp = g.spec.Components.Responses[resp.Ref[strings.LastIndex(resp.Ref, "/")+1:]]
+		}
+		if s := jsonSchema(resp.Content); s != nil {
+			resultType = g.schemaType(s, name+"Result")
+		}
+		break
+	}
+	if resultType == "" {
+		resultType = "json.RawMessage"
+	}
+
+	// Parameters are passed in a struct so optional ones can be left out
+	paramsType := ""
+	if len(pathParams)+len(queryParams) > 0 {
+		paramsType = name + "Params"
+		var b strings.Builder
+		fmt.Fprintf(&b, "// %s holds the parameters of %s\n", paramsType, name)
+		fmt.Fprintf(&b, "type %s struct {\n", paramsType)
+		for _, p := range append(append([]parameter{}, pathParams...), queryParams...) {
+			line := fmt.Sprintf("\t%s %s", goName(p.Name), g.schemaType(p.Schema, paramsType+goName(p.Name)))
+			doc := comment(p.Description)
+			if p.Required && p.In == "query" {
+				doc = strings.TrimSuffix("Required. "+doc, " ")
+			}
+			if doc != "" {
+				line += " // " + doc
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("}\n\n")
+		g.decls = append(g.decls, b.String())
+	}
+
+	doc := comment(op.Summary)
+	if doc == "" {
+		doc = comment(op.Description)
+	}
+	fmt.Fprintf(g.out, "// %s calls %s %s", name, strings.ToUpper(method), path)
+	if doc != "" {
+		g.out.WriteString(": " + doc)
+	}
+	g.out.WriteString("\n")
+
+	args := []string{"ctx context.Context"}
+	if paramsType != "" {
+		args = append(args, "params "+paramsType)
+	}
+	if bodyType != "" {
+		args = append(args, "body "+bodyType)
+	}
+	fmt.Fprintf(g.out, "func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), resultType)
+
+	// Path parameters are substituted, query parameters left out when zero unless required
+	pathExpr := strconv.Quote(path)
+	for _, p := range pathParams {
+		pathExpr = fmt.Sprintf("strings.ReplaceAll(%s, %q, url.PathEscape(fmt.Sprint(params.%s)))", pathExpr, "{"+p.Name+"}", goName(p.Name))
+	}
+	fmt.Fprintf(g.out, "\tpath := %s\n", pathExpr)
+	g.out.WriteString("\tquery := url.Values{}\n")
+	for _, p := range queryParams {
+		field := "params." + goName(p.Name)
+		if p.Required {
+			fmt.Fprintf(g.out, "\tsetQuery(query, %q, %s, true)\n", p.Name, field)
+		} else {
+			fmt.Fprintf(g.out, "\tsetQuery(query, %q, %s, false)\n", p.Name, field)
+		}
+	}
+
+	body := "nil"
+	if bodyType != "" {
+		body = "body"
+	}
+	fmt.Fprintf(g.out, "\tvar result %s\n", resultType)
+	fmt.Fprintf(g.out, "\terr := c.do(ctx, %q, path, query, %s, &result)\n", strings.ToUpper(method), body)
+	g.out.WriteString("\treturn result, err\n}\n\n")
+	return name
+}
+
+// openAPIClientCode is the fixed part of a generated REST client
+func openAPIClientCode(title, description string) string {
+	doc := "the API"
+	if title != "" {
+		doc = "the " + title
+	}
+	if description != "" {
+		doc += ": " + description
+	}
+	return `import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Client calls ` + doc + `
+type Client struct {
+	BaseURL string
+	HTTP    *httpclient.Client
+	Headers map[string]string // Sent with every request, e.g. User-Agent or an API token
+	Retry   httpclient.RetryPolicy
+}
+
+// NewClient creates a client for the API at baseURL using the shared HTTP client
+func NewClient(baseURL string, client *httpclient.Client) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTP: client, Headers: map[string]string{}, Retry: httpclient.DefaultRetryPolicy}
+}
+
+// do sends a request and decodes the JSON response into v
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, v interface{}) error {
+	reqURL := c.BaseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error encoding request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reader)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.HTTP.DoRetry(req, c.Retry)
+	if err != nil {
+		return exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response: %w", err)
+	}
+	return nil
+}
+
+// setQuery adds a query parameter, leaving out optional parameters with zero values
+func setQuery(query url.Values, name string, value interface{}, required bool) {
+	v := reflect.ValueOf(value)
+	if !required && (!v.IsValid() || v.IsZero()) {
+		return
+	}
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			query.Add(name, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return
+	}
+	query.Set(name, fmt.Sprint(value))
+}
+
+`
+}
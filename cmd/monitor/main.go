@@ -0,0 +1,475 @@
+// Command monitor runs each extension's canary queries against the live sites
+// and records the outcome in a history file. Meant to run on a schedule, it
+// prints a machine-readable diff against the previous run so maintainers hear
+// about upstream breakage (site redesigns, new encodings, dead domains)
+// before users do. It exits with status 1 when a canary newly breaks.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// canaryFile is the per-extension file listing its canary queries
+const canaryFile = "canaries.json"
+
+// Canary is one query expected to keep working against a live source
+type Canary struct {
+	Source  string `json:"source"`            // Source ID; empty runs the canary against every source
+	Query   string `json:"query"`             // Search query expected to return results
+	Stream  bool   `json:"stream"`            // Also resolve episodes and streams for the first result
+	Episode string `json:"episode,omitempty"` // Episode number to resolve; defaults to the first listed
+}
+
+// Result is the outcome of one canary step
+type Result struct {
+	Extension string `json:"extension"`
+	Source    string `json:"source"`
+	Query     string `json:"query"`
+	Step      string `json:"step"` // search, episodes or stream-url
+	Passed    bool   `json:"passed"`
+	Details   string `json:"details,omitempty"`
+	Duration  string `json:"duration"`
+}
+
+// Key identifies a step across runs
+func (r Result) Key() string {
+	return strings.Join([]string{r.Extension, r.Source, r.Query, r.Step}, "/")
+}
+
+// Run is one monitor run as stored in the history
+type Run struct {
+	Time    time.Time `json:"time"`
+	Results []Result  `json:"results"`
+}
+
+// History is the stored list of runs, oldest first
+type History struct {
+	Runs []Run `json:"runs"`
+}
+
+// Diff compares a run against the previous one
+type Diff struct {
+	Time        time.Time  `json:"time"`
+	Previous    *time.Time `json:"previous,omitempty"`
+	Passed      int        `json:"passed"`
+	Failed      int        `json:"failed"`
+	NewlyBroken []Result   `json:"newly_broken"` // Passed last run, failing now; these need attention
+	StillBroken []Result   `json:"still_broken"`
+	Recovered   []Result   `json:"recovered"`
+}
+
+// loadHistory reads the history file. A missing file is an empty history.
+func loadHistory(path string) (History, error) {
+	var history History
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return history, fmt.Errorf("error reading history: %v", err)
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return history, fmt.Errorf("error parsing history %s: %v", path, err)
+	}
+	return history, nil
+}
+
+// save writes the history, keeping only the last keep runs
+func (h History) save(path string, keep int) error {
+	if keep > 0 && len(h.Runs) > keep {
+		h.Runs = h.Runs[len(h.Runs)-keep:]
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding history: %v", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("error creating history directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing history: %v", err)
+	}
+	return nil
+}
+
+// diff compares run with the most recent run in history. Steps without a
+// previous result count as broken only when they fail for the first time.
+func diff(history History, run Run) Diff {
+	d := Diff{Time: run.Time, NewlyBroken: []Result{}, StillBroken: []Result{}, Recovered: []Result{}}
+
+	previous := map[string]bool{}
+	if len(history.Runs) > 0 {
+		last := history.Runs[len(history.Runs)-1]
+		d.Previous = &last.Time
+		for _, result := range last.Results {
+			previous[result.Key()] = result.Passed
+		}
+	}
+
+	for _, result := range run.Results {
+		passedBefore, known := previous[result.Key()]
+		switch {
+		case result.Passed:
+			d.Passed++
+			if known && !passedBefore {
+				d.Recovered = append(d.Recovered, result)
+			}
+		case known && !passedBefore:
+			d.Failed++
+			d.StillBroken = append(d.StillBroken, result)
+		default:
+			d.Failed++
+			d.NewlyBroken = append(d.NewlyBroken, result)
+		}
+	}
+	return d
+}
+
+// Monitor builds extensions and runs their canaries
+type Monitor struct {
+	binDir       string
+	defaultQuery string
+	mock         bool // Run against each extension's fixtures instead of the live sites
+	verbose      bool
+	results      []Result
+}
+
+// loadCanaries reads the canaries of an extension, falling back to the default
+// query against every source
+func (m *Monitor) loadCanaries(extensionDir string) ([]Canary, error) {
+	data, err := os.ReadFile(filepath.Join(extensionDir, canaryFile))
+	if os.IsNotExist(err) {
+		return []Canary{{Query: m.defaultQuery, Stream: true}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", canaryFile, err)
+	}
+
+	var canaries []Canary
+	if err := json.Unmarshal(data, &canaries); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", canaryFile, err)
+	}
+	return canaries, nil
+}
+
+// build compiles an extension into the monitor's binary directory
+func (m *Monitor) build(extensionDir string) (string, error) {
+	name := filepath.Base(extensionDir)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	binaryPath := filepath.Join(m.binDir, name)
+
+	absExtensionDir, err := filepath.Abs(extensionDir)
+	if err != nil {
+		return "", err
+	}
+
+	build := exec.Command("go", "build", "-tags", "nsfw", "-o", binaryPath, ".")
+	build.Dir = absExtensionDir
+	if output, err := build.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("build failed: %s", strings.TrimSpace(string(output)))
+	}
+	return binaryPath, nil
+}
+
+// record runs fn as one step and stores its result
+func (m *Monitor) record(extension, source, query, step string, fn func() (string, error)) bool {
+	start := time.Now()
+	details, err := fn()
+	result := Result{
+		Extension: extension,
+		Source:    source,
+		Query:     query,
+		Step:      step,
+		Passed:    err == nil,
+		Details:   details,
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+	}
+	if err != nil {
+		result.Details = err.Error()
+	}
+	m.results = append(m.results, result)
+
+	if m.verbose {
+		status := "✅"
+		if !result.Passed {
+			status = "❌"
+		}
+		fmt.Fprintf(os.Stderr, "  %s %s [%s] %q %s: %s\n", status, extension, source, query, step, result.Details)
+	}
+	return result.Passed
+}
+
+// RunExtension builds an extension and runs each of its canaries
+func (m *Monitor) RunExtension(extensionDir string) {
+	extension := filepath.Base(extensionDir)
+
+	var binaryPath string
+	var info scraper.ExtensionInfo
+	var lifecycle struct {
+		Sources []struct {
+			ID           string   `json:"id"`
+			Status       string   `json:"status"`
+			Capabilities []string `json:"capabilities"`
+		} `json:"sources"`
+	}
+	var canaries []Canary
+	if !m.record(extension, "", "", "setup", func() (string, error) {
+		var err error
+		if canaries, err = m.loadCanaries(extensionDir); err != nil {
+			return "", err
+		}
+		if binaryPath, err = m.build(extensionDir); err != nil {
+			return "", err
+		}
+		// Read the manifest directly, as cmd/e2e does
+		var output json.RawMessage
+		if err := runJSON(binaryPath, &output, "extension-info"); err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(output, &info); err != nil {
+			return "", fmt.Errorf("invalid extension-info output: %v", err)
+		}
+		json.Unmarshal(output, &lifecycle)
+		return fmt.Sprintf("%d canaries", len(canaries)), nil
+	}) {
+		return
+	}
+
+	// Discontinued sources are expected to fail and are not monitored, and
+	// metadata-only sources have no episodes or streams to follow
+	discontinued := map[string]bool{}
+	metadataOnly := map[string]bool{}
+	for _, source := range lifecycle.Sources {
+		discontinued[source.ID] = source.Status == "discontinued"
+		metadataOnly[source.ID] = slices.Contains(source.Capabilities, "metadata-only")
+	}
+
+	for _, canary := range canaries {
+		for _, source := range info.Sources {
+			if (canary.Source != "" && canary.Source != source.ID) || discontinued[source.ID] {
+				continue
+			}
+			if metadataOnly[source.ID] {
+				canary.Stream = false
+			}
+			m.runCanary(extension, binaryPath, source.ID, canary, m.mockArgs(extensionDir))
+		}
+	}
+}
+
+// searchResults decodes search output, which is either a bare array of anime
+// or an object holding them in results alongside pagination metadata
+type searchResults []scraper.Anime
+
+func (r *searchResults) UnmarshalJSON(data []byte) error {
+	var animes []scraper.Anime
+	if err := json.Unmarshal(data, &animes); err == nil {
+		*r = animes
+		return nil
+	}
+
+	var page struct {
+		Results []scraper.Anime `json:"results"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return err
+	}
+	*r = page.Results
+	return nil
+}
+
+// runCanary searches for the canary query and, when asked, follows the first
+// result through to its streams. The extension is invoked directly rather than
+// through pair's client so that protocol drift, which cmd/e2e covers, is not
+// reported as upstream breakage.
+func (m *Monitor) runCanary(extension, binaryPath, sourceID string, canary Canary, extra []string) {
+	var animes []scraper.Anime
+	if !m.record(extension, sourceID, canary.Query, "search", func() (string, error) {
+		var results searchResults
+		if err := runJSON(binaryPath, &results, append([]string{"search", "--query", canary.Query, "--page", "1", "--source", sourceID}, extra...)...); err != nil {
+			return "", err
+		}
+		animes = results
+		if len(animes) == 0 {
+			return "", fmt.Errorf("no results for %q", canary.Query)
+		}
+		return fmt.Sprintf("%d results", len(animes)), nil
+	}) || !canary.Stream {
+		return
+	}
+
+	var episodeNumber float64
+	if !m.record(extension, sourceID, canary.Query, "episodes", func() (string, error) {
+		var episodes []scraper.Episode
+		if err := runJSON(binaryPath, &episodes, append([]string{"episodes", "--anime", animes[0].ID, "--source", sourceID}, extra...)...); err != nil {
+			return "", err
+		}
+		if len(episodes) == 0 {
+			return "", fmt.Errorf("no episodes for %q", animes[0].ID)
+		}
+
+		episodeNumber = episodes[0].EpisodeNumber
+		if canary.Episode != "" {
+			found := false
+			for _, episode := range episodes {
+				if fmt.Sprint(episode.EpisodeNumber) == canary.Episode {
+					episodeNumber, found = episode.EpisodeNumber, true
+					break
+				}
+			}
+			if !found {
+				return "", fmt.Errorf("episode %s of %q not listed", canary.Episode, animes[0].ID)
+			}
+		}
+		return fmt.Sprintf("%d episodes", len(episodes)), nil
+	}) {
+		return
+	}
+
+	m.record(extension, sourceID, canary.Query, "stream-url", func() (string, error) {
+		var videos struct {
+			Streams  []json.RawMessage `json:"streams"`
+			Torrents []json.RawMessage `json:"torrents"` // Releases of torrent sources without a sidecar
+		}
+		if err := runJSON(binaryPath, &videos, append([]string{"stream-url", "--anime", animes[0].ID, "--episode", fmt.Sprint(episodeNumber), "--source", sourceID}, extra...)...); err != nil {
+			return "", err
+		}
+		if len(videos.Streams) == 0 && len(videos.Torrents) > 0 {
+			return fmt.Sprintf("%d torrents", len(videos.Torrents)), nil
+		}
+		if len(videos.Streams) == 0 {
+			return "", fmt.Errorf("no streams for episode %g", episodeNumber)
+		}
+		return fmt.Sprintf("%d streams", len(videos.Streams)), nil
+	})
+}
+
+// mockArgs returns the flags pointing an extension at its fixtures in mock mode
+func (m *Monitor) mockArgs(extensionDir string) []string {
+	if !m.mock {
+		return nil
+	}
+	fixtures, _ := filepath.Abs(filepath.Join(extensionDir, "fixtures"))
+	return []string{"-mock", fixtures}
+}
+
+// runJSON runs an extension command and decodes the data of its
+// {"status": "success", "data": ...} envelope into v
+func runJSON(binaryPath string, v interface{}, args ...string) error {
+	cmd := exec.Command(binaryPath, args...)
+	// A cache directory of its own keeps responses cached by the user's
+	// extensions from hiding breakage on the live site
+	cmd.Env = append(os.Environ(), "PAIR_CACHE_DIR="+filepath.Join(filepath.Dir(binaryPath), "cache"))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("%s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	var envelope struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(output, &envelope); err != nil {
+		return fmt.Errorf("invalid %s output: %v", args[0], err)
+	}
+	if envelope.Status != "success" {
+		return fmt.Errorf("invalid %s output: status %q instead of success", args[0], envelope.Status)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		return fmt.Errorf("invalid %s output: %v", args[0], err)
+	}
+	return nil
+}
+
+func main() {
+	var (
+		extensions  = flag.String("extensions", "", "Comma-separated extension directories (default: all directories in src/)")
+		historyPath = flag.String("history", "monitor-history.json", "History file the runs are recorded in")
+		keepRuns    = flag.Int("keep", 100, "Number of runs kept in the history file")
+		query       = flag.String("query", "naruto", "Canary query for extensions without a "+canaryFile)
+		mock        = flag.Bool("mock", false, "Run the canaries against each extension's fixtures instead of the live sites")
+		verbose     = flag.Bool("verbose", false, "Print every step to stderr as it runs")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Source monitor - Run canary queries against live sites and report new breakage\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nCanaries are read from %s in each extension directory, e.g.\n", canaryFile)
+		fmt.Fprintf(os.Stderr, "  [{\"source\": \"<source id>\", \"query\": \"one piece\", \"stream\": true}]\n")
+		fmt.Fprintf(os.Stderr, "\nExit status is 1 when a canary step that passed in the previous run fails.\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s -history monitor/history.json > monitor/diff.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -extensions ./src/allanime -verbose\n", os.Args[0])
+	}
+	flag.Parse()
+
+	dirs := []string{}
+	if *extensions != "" {
+		dirs = strings.Split(*extensions, ",")
+	} else {
+		entries, err := os.ReadDir("src")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading src directory: %v\n", err)
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, filepath.Join("src", entry.Name()))
+			}
+		}
+	}
+	sort.Strings(dirs)
+
+	history, err := loadHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	binDir, err := os.MkdirTemp("", "pair-monitor-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating temp directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	m := &Monitor{binDir: binDir, defaultQuery: *query, mock: *mock, verbose: *verbose}
+	run := Run{Time: time.Now().UTC().Truncate(time.Second)}
+	for _, dir := range dirs {
+		m.RunExtension(dir)
+	}
+	run.Results = m.results
+	os.RemoveAll(binDir)
+
+	d := diff(history, run)
+	history.Runs = append(history.Runs, run)
+	if err := history.save(*historyPath, *keepRuns); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, _ := json.MarshalIndent(d, "", "  ")
+	fmt.Println(string(data))
+
+	if len(d.NewlyBroken) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,284 @@
+// Command torrentstream is the sidecar behind pkg/torrentstream. It downloads
+// torrents on demand and serves their files over HTTP on the loopback
+// interface, so torrent extensions can hand players plain stream URLs.
+//
+// GET /stream/{infohash}?magnet=...&episode=N adds the torrent, waits for its
+// metadata and serves the file holding episode N, or the largest video file
+// without an episode. Pieces are fetched in the order the player reads them,
+// starting wherever it seeks to. Completed downloads are not seeded.
+//
+// Extensions start the sidecar when they need it; it exits after -idle
+// without streams, so it does not linger once the user stops watching.
+//
+// Build it as pair-torrentstream, the name extensions look up on PATH:
+//
+//	go build -o pair-torrentstream ./cmd/torrentstream
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+	"github.com/wraient/pair-extensions/pkg/torrentmap"
+	"github.com/wraient/pair-extensions/pkg/torrentstream"
+)
+
+// readahead is how far ahead of the player's position pieces are requested,
+// enough to ride out slow peers without fetching the whole file up front
+const readahead = 32 << 20
+
+// metadataTimeout bounds how long a request waits for a magnet's metadata
+// from peers before giving up
+const metadataTimeout = 2 * time.Minute
+
+// Server serves the files of torrents over HTTP
+type Server struct {
+	client *torrent.Client
+	idle   time.Duration // Exit after this long without streams; 0 never exits
+	done   chan struct{} // Closed when the server has been idle for too long
+	logger *log.Logger
+
+	mu         sync.Mutex
+	streams    int       // Streams being served
+	lastActive time.Time // End of the last stream, or start when there was none
+}
+
+// NewServer creates a server downloading into dataDir
+func NewServer(dataDir string, idle time.Duration, logger *log.Logger) (*Server, error) {
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = dataDir
+	cfg.Seed = false
+	// Any free port, so a running BitTorrent client does not keep the sidecar from starting
+	cfg.ListenPort = 0
+	// The client's own warnings, e.g. about unreachable DHT nodes, go where the sidecar logs
+	cfg.Slogger = slog.New(slog.NewTextHandler(logger.Writer(), &slog.HandlerOptions{Level: slog.LevelWarn}))
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error starting BitTorrent client: %v", err)
+	}
+	return &Server{client: client, idle: idle, done: make(chan struct{}), logger: logger, lastActive: time.Now()}, nil
+}
+
+// Handler returns the server's HTTP routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /stream/{infohash}", s.handleStream)
+	return mux
+}
+
+// handleHealth answers the check extensions run before starting a sidecar
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	streams := s.streams
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "streams": streams})
+}
+
+// handleStream serves a file of a torrent, adding the torrent first
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	magnet := r.URL.Query().Get("magnet")
+	hash, err := torrentstream.InfoHash(magnet)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !strings.EqualFold(hash, r.PathValue("infohash")) {
+		http.Error(w, "info hash does not match the magnet link", http.StatusBadRequest)
+		return
+	}
+	var episode float64
+	if raw := r.URL.Query().Get("episode"); raw != "" {
+		if episode, err = strconv.ParseFloat(raw, 64); err != nil {
+			http.Error(w, fmt.Sprintf("invalid episode %q", raw), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.begin()
+	defer s.end()
+
+	t, err := s.client.AddMagnet(magnet)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	select {
+	case <-t.GotInfo():
+	case <-r.Context().Done():
+		return
+	case <-time.After(metadataTimeout):
+		http.Error(w, "no peer sent the torrent's metadata in time", http.StatusGatewayTimeout)
+		return
+	}
+
+	file, err := pickFile(t, episode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.logger.Printf("streaming %s from %s", file.DisplayPath(), t.Name())
+
+	reader := file.NewReader()
+	defer reader.Close()
+	reader.SetContext(r.Context())
+	// Hand the player chunks as they arrive rather than whole verified pieces
+	reader.SetResponsive()
+	reader.SetReadahead(readahead)
+	http.ServeContent(w, r, path.Base(file.DisplayPath()), time.Time{}, reader)
+}
+
+// pickFile returns the file of a torrent holding episode, mapped from the file
+// names as for batch releases, or its largest video file for episode 0 and
+// for single-episode releases whose file name has no recognizable number
+func pickFile(t *torrent.Torrent, episode float64) (*torrent.File, error) {
+	files := t.Files()
+	mapped := &torrentmap.Torrent{Name: t.Name()}
+	for i, file := range files {
+		mapped.Files = append(mapped.Files, torrentmap.File{Index: i, Path: file.DisplayPath(), Length: file.Length()})
+	}
+
+	if episode != 0 {
+		if found, err := mapped.FileForEpisode(episode); err == nil {
+			return files[found.Index], nil
+		} else if len(mapped.MapEpisodes()) > 1 {
+			return nil, err
+		}
+	}
+	main, err := mapped.MainFile()
+	if err != nil {
+		return nil, err
+	}
+	return files[main.Index], nil
+}
+
+// begin records the start of a stream
+func (s *Server) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams++
+}
+
+// end records the end of a stream
+func (s *Server) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams--
+	s.lastActive = time.Now()
+}
+
+// watchIdle closes done once no stream has been served for s.idle
+func (s *Server) watchIdle() {
+	if s.idle <= 0 {
+		return
+	}
+	tick := time.NewTicker(min(s.idle, time.Minute))
+	defer tick.Stop()
+	for range tick.C {
+		s.mu.Lock()
+		idle := s.streams == 0 && time.Since(s.lastActive) >= s.idle
+		s.mu.Unlock()
+		if idle {
+			close(s.done)
+			return
+		}
+	}
+}
+
+// Close stops the BitTorrent client
+func (s *Server) Close() {
+	for _, err := range s.client.Close() {
+		s.logger.Printf("error closing BitTorrent client: %v", err)
+	}
+}
+
+// defaultDataDir is where downloads go unless -dir says otherwise
+func defaultDataDir() string {
+	dir, err := extconfig.CacheDir("torrentstream")
+	if err != nil {
+		return os.TempDir()
+	}
+	return dir
+}
+
+func main() {
+	var (
+		addr    = flag.String("addr", torrentstream.DefaultAddr, "Address to serve streams on; must be on the loopback interface")
+		dataDir = flag.String("dir", "", "Directory to download torrents into (defaults to the pair cache directory)")
+		idle    = flag.Duration("idle", 30*time.Minute, "Exit after this long without streams (0 keeps running)")
+		verbose = flag.Bool("verbose", false, "Log streams to stderr")
+	)
+	flag.Parse()
+
+	logger := log.New(os.Stderr, "torrentstream: ", log.LstdFlags)
+	if !*verbose {
+		logger.SetOutput(io.Discard)
+	}
+
+	// Streams are unauthenticated, so they must not be reachable from the network
+	host, _, err := net.SplitHostPort(*addr)
+	if ip := net.ParseIP(host); err != nil || (host != "localhost" && (ip == nil || !ip.IsLoopback())) {
+		fmt.Fprintf(os.Stderr, "Error: -addr %q is not on the loopback interface\n", *addr)
+		os.Exit(2)
+	}
+	if *dataDir == "" {
+		*dataDir = defaultDataDir()
+	}
+	if err := os.MkdirAll(*dataDir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	server, err := NewServer(*dataDir, *idle, logger)
+	if err != nil {
+		listener.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer server.Close()
+
+	httpServer := &http.Server{Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("error serving: %v", err)
+		}
+	}()
+	go server.watchIdle()
+	logger.Printf("serving streams on %s, downloading into %s", *addr, *dataDir)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-signals:
+	case <-server.done:
+		logger.Printf("idle for %s, exiting", *idle)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	httpServer.Shutdown(ctx)
+}
@@ -0,0 +1,52 @@
+{
+  description = "Pair extensions";
+
+  inputs = {
+    nixpkgs.url = "github:NixOS/nixpkgs/nixos-25.05";
+    flake-utils.url = "github:numtide/flake-utils";
+  };
+
+  outputs = { self, nixpkgs, flake-utils }:
+    flake-utils.lib.eachDefaultSystem (system:
+      let
+        pkgs = nixpkgs.legacyPackages.${system};
+        go = pkgs.go_1_24;
+
+        # Every directory in src/ is an extension
+        extensions = builtins.attrNames
+          (pkgs.lib.filterAttrs (_: type: type == "directory") (builtins.readDir ./src));
+
+        buildExtension = name: (pkgs.buildGoModule.override { inherit go; }) {
+          pname = name;
+          version = self.shortRev or "dirty";
+          src = ./.;
+          subPackages = [ "src/${name}" ];
+
+          # Update when go.mod or go.sum change; nix prints the expected hash on mismatch
+          vendorHash = "sha256-FvEVMDo4xSKUb6NxpbG8mTHmvcFI7z+vG4tZF0nywL0=";
+
+          # Same flags as the CI release builder and `make release`
+          env.CGO_ENABLED = 0;
+          tags = [ "nsfw" ];
+          ldflags = [ "-s" "-w" "-buildid=" ];
+          doCheck = false;
+        };
+      in
+      {
+        packages = pkgs.lib.genAttrs extensions buildExtension // {
+          # Sidecar torrent extensions serve streams through, see pkg/torrentstream
+          pair-torrentstream = (buildExtension "pair-torrentstream").overrideAttrs (_: {
+            subPackages = [ "cmd/torrentstream" ];
+            postInstall = "mv $out/bin/torrentstream $out/bin/pair-torrentstream";
+          });
+        };
+
+        # Rebuilding a published binary bit for bit needs the Go version recorded in
+        # the manifest's build.go and a clean checkout of build.commit:
+        #   nix develop -c make release EXTENSION_PATH=./src/<name>
+        devShells.default = pkgs.mkShell {
+          packages = [ go pkgs.gnumake pkgs.jq pkgs.upx pkgs.git ];
+          CGO_ENABLED = 0;
+        };
+      });
+}
@@ -0,0 +1,239 @@
+// Package auth implements the login, logout and whoami commands shared by
+// account-based extensions, so every extension asks for credentials and keeps
+// sessions the same way.
+//
+// Credentials come from flags, then environment variables, then interactive
+// prompts when stdin is a terminal. Secret fields are read without echo.
+// Only the session values returned by the extension's Authenticator are
+// stored, in the extension's keystore.
+package auth
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/keystore"
+	"golang.org/x/term"
+)
+
+// Command names handled by Commands.Run
+const (
+	CommandLogin  = "login"
+	CommandLogout = "logout"
+	CommandWhoAmI = "whoami"
+)
+
+// Field describes one credential asked for at login
+type Field struct {
+	Name   string // Flag name, e.g. "username"
+	Prompt string // Prompt shown on a terminal, e.g. "Username"
+	Env    string // Environment variable read when the flag is not given
+	Secret bool   // Read without echo; prefer the environment variable over the flag
+}
+
+// Account identifies the logged-in user
+type Account struct {
+	Username string            `json:"username"`
+	Name     string            `json:"name,omitempty"`
+	Extra    map[string]string `json:"extra,omitempty"` // Extension-specific details, e.g. plan or server
+}
+
+// Authenticator is implemented by extensions with accounts
+type Authenticator interface {
+	// Login exchanges credentials, keyed by field name, for the session values
+	// (tokens, cookies) to keep in the keystore
+	Login(credentials map[string]string) (map[string]string, error)
+	// WhoAmI returns the account a stored session belongs to, or an error when
+	// the session is no longer valid
+	WhoAmI(session map[string]string) (Account, error)
+}
+
+// Revoker is implemented by authenticators that can end a session server-side
+type Revoker interface {
+	Logout(session map[string]string) error
+}
+
+// LoginResult is the output of the login command
+type LoginResult struct {
+	LoggedIn bool    `json:"logged_in"`
+	Account  Account `json:"account"`
+}
+
+// LogoutResult is the output of the logout command
+type LogoutResult struct {
+	LoggedOut bool `json:"logged_out"`
+}
+
+// Commands runs the login, logout and whoami commands of an extension
+type Commands struct {
+	Fields []Field
+	Auth   Authenticator
+	Store  *keystore.Store
+
+	In     *os.File  // Defaults to os.Stdin
+	Prompt io.Writer // Where prompts go; defaults to os.Stderr so stdout stays JSON
+
+	flags  map[string]*string
+	reader *bufio.Reader // Shared by prompts so buffered input is not lost between fields
+}
+
+// IsCommand reports whether name is one of the account commands
+func IsCommand(name string) bool {
+	switch name {
+	case CommandLogin, CommandLogout, CommandWhoAmI:
+		return true
+	}
+	return false
+}
+
+// RegisterFlags adds a flag for every credential field to fs
+func (c *Commands) RegisterFlags(fs *flag.FlagSet) {
+	c.flags = map[string]*string{}
+	for _, field := range c.Fields {
+		usage := fmt.Sprintf("With login: %s", field.Prompt)
+		if field.Env != "" {
+			usage += fmt.Sprintf(" (or $%s)", field.Env)
+		}
+		c.flags[field.Name] = fs.String(field.Name, "", usage)
+	}
+}
+
+// Run executes an account command and returns its JSON-ready result
+func (c *Commands) Run(command string) (interface{}, error) {
+	switch command {
+	case CommandLogin:
+		return c.login()
+	case CommandLogout:
+		return c.logout()
+	case CommandWhoAmI:
+		return c.whoAmI()
+	}
+	return nil, fmt.Errorf("unknown account command %q", command)
+}
+
+// Session returns the stored session values, or false when not logged in
+func (c *Commands) Session() (map[string]string, bool) {
+	keys := c.Store.Keys()
+	if len(keys) == 0 {
+		return nil, false
+	}
+
+	session := make(map[string]string, len(keys))
+	for _, key := range keys {
+		session[key], _ = c.Store.Get(key)
+	}
+	return session, true
+}
+
+func (c *Commands) login() (LoginResult, error) {
+	credentials := map[string]string{}
+	for _, field := range c.Fields {
+		value, err := c.credential(field)
+		if err != nil {
+			return LoginResult{}, err
+		}
+		credentials[field.Name] = value
+	}
+
+	session, err := c.Auth.Login(credentials)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("error logging in: %v", err)
+	}
+
+	account, err := c.Auth.WhoAmI(session)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("error verifying login: %v", err)
+	}
+
+	// Replace any previous session instead of merging into it
+	if err := c.Store.Clear(); err != nil {
+		return LoginResult{}, err
+	}
+	if err := c.Store.SetAll(session); err != nil {
+		return LoginResult{}, err
+	}
+	return LoginResult{LoggedIn: true, Account: account}, nil
+}
+
+func (c *Commands) logout() (LogoutResult, error) {
+	if session, ok := c.Session(); ok {
+		if revoker, ok := c.Auth.(Revoker); ok {
+			// The local session is removed even when the server is unreachable
+			if err := revoker.Logout(session); err != nil {
+				fmt.Fprintf(c.promptWriter(), "Warning: error ending session: %v\n", err)
+			}
+		}
+	}
+
+	if err := c.Store.Clear(); err != nil {
+		return LogoutResult{}, err
+	}
+	return LogoutResult{LoggedOut: true}, nil
+}
+
+func (c *Commands) whoAmI() (Account, error) {
+	session, ok := c.Session()
+	if !ok {
+		return Account{}, fmt.Errorf("not logged in (run the login command)")
+	}
+
+	account, err := c.Auth.WhoAmI(session)
+	if err != nil {
+		return Account{}, fmt.Errorf("session is no longer valid, log in again: %v", err)
+	}
+	return account, nil
+}
+
+// credential returns the value of a field from its flag, its environment
+// variable or, on a terminal, a prompt
+func (c *Commands) credential(field Field) (string, error) {
+	if value, ok := c.flags[field.Name]; ok && *value != "" {
+		return *value, nil
+	}
+	if field.Env != "" {
+		if value := os.Getenv(field.Env); value != "" {
+			return value, nil
+		}
+	}
+
+	in := c.In
+	if in == nil {
+		in = os.Stdin
+	}
+	if !term.IsTerminal(int(in.Fd())) {
+		if field.Env != "" {
+			return "", fmt.Errorf("missing --%s (or $%s)", field.Name, field.Env)
+		}
+		return "", fmt.Errorf("missing --%s", field.Name)
+	}
+
+	fmt.Fprintf(c.promptWriter(), "%s: ", field.Prompt)
+	if field.Secret {
+		value, err := term.ReadPassword(int(in.Fd()))
+		fmt.Fprintln(c.promptWriter())
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %v", field.Name, err)
+		}
+		return string(value), nil
+	}
+
+	if c.reader == nil {
+		c.reader = bufio.NewReader(in)
+	}
+	value, err := c.reader.ReadString('\n')
+	if err != nil && value == "" {
+		return "", fmt.Errorf("error reading %s: %v", field.Name, err)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+func (c *Commands) promptWriter() io.Writer {
+	if c.Prompt != nil {
+		return c.Prompt
+	}
+	return os.Stderr
+}
@@ -0,0 +1,399 @@
+// Package cli is the command layer every extension binary shares: the common
+// flags, config defaults, HTTP client setup, usage message, the commands every
+// extension answers the same way, and the JSON output and error reporting.
+//
+// Extensions speak pair's extension protocol: pair runs
+//
+//	<binary> COMMAND [SOURCE_ID] [OPTIONS]
+//
+// and reads {"status": "success", "data": ...} from stdout, or the error
+// envelope of pkg/exterr. An extension describes its own flags and commands
+// and calls Main:
+//
+//	app := &cli.App{Package: "jkanime", SourceID: sourceID, Client: s.client, ...}
+//	app.Main()
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/doctor"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/extlog"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Config holds the settings every extension's config file accepts. Extension
+// configs embed it so its keys sit next to their own.
+type Config struct {
+	Proxy     string `json:"proxy,omitempty"`      // Default for -proxy, e.g. socks5://127.0.0.1:1080
+	UserAgent string `json:"user_agent,omitempty"` // Default for -user-agent
+}
+
+// HeaderFlags are the -user-agent and -header flags, which set headers sent
+// with every request, to the source and to its stream hosts alike
+type HeaderFlags struct {
+	userAgent *string
+	headers   http.Header
+}
+
+// RegisterHeaderFlags defines the -user-agent and the repeatable -header flags on fs
+func RegisterHeaderFlags(fs *flag.FlagSet) *HeaderFlags {
+	h := &HeaderFlags{headers: http.Header{}}
+	h.userAgent = fs.String("user-agent", "", "Send this User-Agent with every request instead of the extension's own")
+	fs.Func("header", "Send this header with every request, as \"Name: value\" (repeatable)", func(header string) error {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return errors.New(`expected "Name: value"`)
+		}
+		h.headers.Add(name, strings.TrimSpace(value))
+		return nil
+	})
+	return h
+}
+
+// Apply sets the headers on client. The config's user agent fills in when
+// -user-agent is not given; headers an extension's config sets for its own
+// API stay with the extension.
+func (h *HeaderFlags) Apply(client *httpclient.Client, cfg Config) {
+	for name, values := range h.headers {
+		client.Header[name] = append([]string(nil), values...)
+	}
+	userAgent := *h.userAgent
+	if userAgent == "" {
+		userAgent = cfg.UserAgent
+	}
+	if userAgent != "" {
+		client.Header.Set("User-Agent", userAgent)
+	}
+}
+
+// VersionInfo is the output of the version command
+type VersionInfo struct {
+	Package string `json:"pkg"`
+	Version string `json:"version"`
+}
+
+// Command is a command of an extension
+type Command struct {
+	Name        string
+	Description string // Line in the usage message; commands without one are left out
+	NoDeadline  bool   // Run without the -timeout deadline, e.g. a login waiting for the user
+
+	// Run runs the command. Its result is printed as JSON unless it is nil,
+	// for commands that write their own output.
+	Run func(ctx context.Context) (interface{}, error)
+}
+
+// App is the command layer of an extension binary
+type App struct {
+	Package  string // Extension package, e.g. "jkanime"
+	SourceID string // ID of the extension's source; -source must match it
+	Version  string
+	Summary  string // Line describing the tool in the usage message; defaults to a video source's
+
+	// MetadataOnly words the built-in commands for a source without streams
+	MetadataOnly bool
+
+	Client *httpclient.Client
+
+	// ExtensionInfo and SourceInfo answer extension-info, list-sources and source-info
+	ExtensionInfo func() (interface{}, error)
+	SourceInfo    func() (interface{}, error)
+
+	// Domains lists the hosts doctor checks
+	Domains func() []string
+
+	// Configure loads the config file at path, the default location when
+	// empty, applies it to the scraper and to the extension's flags not given
+	// on the command line (see IsFlagSet), and returns the common settings.
+	// Optional for extensions without a config file.
+	Configure func(path string) (Config, error)
+
+	// Setup runs once the flags and the client are configured, before the
+	// command, e.g. to validate flag values. Optional.
+	Setup func() error
+
+	// LimitRate throttles requests to the source. It is not called with -mock.
+	// Optional.
+	LimitRate func()
+
+	Commands []Command
+
+	mock    *string
+	timeout *time.Duration
+	proxy   *url.URL
+}
+
+// Mocking reports whether requests are served from fixtures (-mock)
+func (a *App) Mocking() bool {
+	return a.mock != nil && *a.mock != ""
+}
+
+// IsFlagSet reports whether a flag was given on the command line
+func IsFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// envelope is the successful output of pair's extension protocol
+type envelope struct {
+	Status string      `json:"status"` // Always "success"
+	Data   interface{} `json:"data"`
+}
+
+// Print writes result to stdout in the success envelope
+func Print(result interface{}) error {
+	data, err := json.MarshalIndent(envelope{Status: "success", Data: result}, "", "  ")
+	if err != nil {
+		return exterr.New(exterr.Internal, "error marshalling result to JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// SplitSourceID splits the source ID pair passes between the command and its
+// flags off args, the arguments after the command. The ID is empty when the
+// flags follow the command directly.
+func SplitSourceID(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+// Found returns the results of a search, or a NotFound error when there are
+// none, so a search matching nothing exits with exterr.ExitNoResults:
+//
+//	return cli.Found(s.SearchAnime(ctx, *query, *page))
+func Found[T any](results []T, err error) (interface{}, error) {
+	if err == nil && len(results) == 0 {
+		return nil, exterr.New(exterr.NotFound, "no results found")
+	}
+	return results, err
+}
+
+// builtins returns the commands every extension answers the same way
+func (a *App) builtins() []Command {
+	kind := "anime video source"
+	if a.MetadataOnly {
+		kind = "anime source"
+	}
+	return []Command{
+		{Name: "extension-info", Description: "Get information about a specific extension.", Run: func(ctx context.Context) (interface{}, error) {
+			return a.ExtensionInfo()
+		}},
+		{Name: "version", Description: "Print the extension version.", Run: func(ctx context.Context) (interface{}, error) {
+			return VersionInfo{Package: a.Package, Version: a.Version}, nil
+		}},
+		{Name: "list-sources", Description: "List all available " + kind + "s.", Run: func(ctx context.Context) (interface{}, error) {
+			source, err := a.SourceInfo()
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{source}, nil
+		}},
+		{Name: "source-info", Description: "Get information about a specific " + kind + ".", Run: func(ctx context.Context) (interface{}, error) {
+			return a.SourceInfo()
+		}},
+		{Name: "doctor", Description: "Diagnose DNS, TLS, proxy, clock and storage problems.", Run: func(ctx context.Context) (interface{}, error) {
+			report := doctor.Run(doctor.Options{Package: a.Package, Domains: a.Domains(), Proxy: a.proxy})
+			// Human-readable text goes to stderr so stdout stays JSON
+			fmt.Fprint(os.Stderr, report.Text())
+			return report, nil
+		}},
+	}
+}
+
+// commands returns the built-in and extension commands by name
+func (a *App) commands() map[string]Command {
+	commands := map[string]Command{}
+	for _, c := range append(a.builtins(), a.Commands...) {
+		commands[c.Name] = c
+	}
+	return commands
+}
+
+// usage prints the usage message
+func (a *App) usage() {
+	summary := a.Summary
+	if summary == "" {
+		summary = "A command-line tool for interacting with anime video sources."
+	}
+	fmt.Fprintf(os.Stderr, "Usage: %s COMMAND [SOURCE_ID] [OPTIONS]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "%s\n\n", summary)
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nCommands:\n")
+
+	var names []string
+	commands := a.commands()
+	for name, c := range commands {
+		if c.Description != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commands[name].Description)
+	}
+}
+
+// Main parses the command line, runs the command and prints its result in the
+// success envelope. Failures are printed in the error envelope, see pkg/exterr.
+func (a *App) Main() {
+	var (
+		help     = flag.Bool("h", false, "Show help message")
+		source   = flag.String("source", a.SourceID, "Source ID (optional, defaults to "+a.Package+")")
+		config   = flag.String("config", "", "Path to the extension config file (defaults to the pair config directory)")
+		debug    = flag.Bool("debug", false, "Print request statistics and slow-request warnings to stderr")
+		logLevel = flag.String("log-level", "", "Write JSON log lines about requests and retries to stderr at this level: debug, info, warn or error (off by default)")
+		proxy    = flag.String("proxy", "", "Route all requests through this proxy, e.g. http://host:8080 or socks5://host:1080 (defaults to HTTPS_PROXY/HTTP_PROXY)")
+	)
+	a.mock = flag.String("mock", os.Getenv("PAIR_MOCK"), "Serve all requests from the fixtures in this directory (offline mode; defaults to $PAIR_MOCK)")
+	a.timeout = flag.Duration("timeout", 60*time.Second, "Give up on the command after this long, cancelling outstanding requests (0 disables the deadline)")
+	headers := RegisterHeaderFlags(flag.CommandLine)
+
+	flag.Usage = a.usage
+
+	// exterr.Exit skips deferred calls, so fail runs the cleanups itself
+	var cleanups []func()
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+		cleanups = nil
+	}
+	defer cleanup()
+	fail := func(e *exterr.Error) {
+		cleanup()
+		e.Source = *source
+		exterr.Exit(e)
+	}
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+
+	// Parse flags after the command and the source ID
+	args := os.Args[1:]
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	name := args[0]
+	sourceArg, args := SplitSourceID(args[1:])
+	if err := flag.CommandLine.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fail(exterr.New(exterr.InvalidArgument, "%w", err))
+	}
+	if flag.NArg() > 0 {
+		fail(exterr.New(exterr.InvalidArgument, "unexpected argument %q", flag.Arg(0)))
+	}
+	if sourceArg != "" {
+		*source = sourceArg
+	}
+
+	if *help {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	// If a specific source ID is provided, verify it matches our source
+	if *source != "" && *source != a.SourceID {
+		fail(exterr.New(exterr.InvalidArgument, "invalid source ID %q", *source))
+	}
+
+	var cfg Config
+	if a.Configure != nil {
+		var err error
+		if cfg, err = a.Configure(*config); err != nil {
+			fail(exterr.New(exterr.Config, "error loading config: %w", err))
+		}
+	}
+
+	// Config defaults for flags not given on the command line
+	if cfg.Proxy != "" && !IsFlagSet("proxy") {
+		*proxy = cfg.Proxy
+	}
+
+	headers.Apply(a.Client, cfg)
+
+	if *debug {
+		a.Client.Debug = true
+	}
+	logger, err := extlog.New(os.Stderr, *logLevel)
+	if err != nil {
+		fail(exterr.New(exterr.InvalidArgument, "%w", err))
+	}
+	a.Client.Logger = logger
+	if *proxy != "" {
+		if a.proxy, err = httpclient.ParseProxy(*proxy); err != nil {
+			fail(exterr.New(exterr.InvalidArgument, "%w", err))
+		}
+		a.Client.SetProxy(a.proxy)
+	}
+	if a.Mocking() {
+		stopMock, err := a.Client.UseMock(*a.mock)
+		if err != nil {
+			fail(exterr.New(exterr.Internal, "error starting mock server: %w", err))
+		}
+		cleanups = append(cleanups, func() { stopMock() })
+	} else if a.LimitRate != nil {
+		a.LimitRate()
+	}
+
+	if a.Setup != nil {
+		if err := a.Setup(); err != nil {
+			fail(exterr.From(err, exterr.Internal))
+		}
+	}
+
+	command, ok := a.commands()[name]
+	if !ok {
+		flag.Usage()
+		fail(exterr.New(exterr.UnknownCommand, "unknown command %q", name))
+	}
+
+	// Every request of the command shares one deadline
+	if *a.timeout < 0 {
+		fail(exterr.New(exterr.InvalidArgument, "-timeout must not be negative"))
+	}
+	ctx := context.Background()
+	if *a.timeout > 0 && !command.NoDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *a.timeout)
+		cleanups = append(cleanups, cancel)
+	}
+
+	result, err := command.Run(ctx)
+
+	a.Client.PrintSummary()
+
+	if err != nil {
+		fail(exterr.From(err, exterr.Internal))
+	}
+	if result == nil {
+		return
+	}
+
+	if err := Print(result); err != nil {
+		fail(exterr.From(err, exterr.Internal))
+	}
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// parseHeaderFlags registers the header flags on a fresh flag set and parses args
+func parseHeaderFlags(t *testing.T, args ...string) (*HeaderFlags, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	h := RegisterHeaderFlags(fs)
+	return h, fs.Parse(args)
+}
+
+func TestHeaderFlags(t *testing.T) {
+	h, err := parseHeaderFlags(t, "-user-agent", "custom/1.0", "-header", "X-Extra: a", "-header", "X-Extra:b", "-header", "Cookie: k=v; x=y")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	client := httpclient.New()
+	h.Apply(client, Config{UserAgent: "config/1.0"})
+
+	if got := client.Header.Get("User-Agent"); got != "custom/1.0" {
+		t.Errorf("User-Agent = %q, want the flag's %q over the config's", got, "custom/1.0")
+	}
+	if got := client.Header.Values("X-Extra"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("X-Extra = %q, want %q", got, []string{"a", "b"})
+	}
+	if got := client.Header.Get("Cookie"); got != "k=v; x=y" {
+		t.Errorf("Cookie = %q, want %q", got, "k=v; x=y")
+	}
+
+	// The client gets copies, so changing them leaves the flag values alone
+	client.Header["X-Extra"][0] = "changed"
+	if got := h.headers.Values("X-Extra"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("flag X-Extra = %q after changing the client's, want %q", got, []string{"a", "b"})
+	}
+}
+
+func TestHeaderFlagsConfigUserAgent(t *testing.T) {
+	h, err := parseHeaderFlags(t)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	client := httpclient.New()
+	h.Apply(client, Config{UserAgent: "config/1.0"})
+	if got := client.Header.Get("User-Agent"); got != "config/1.0" {
+		t.Errorf("User-Agent = %q, want the config's %q", got, "config/1.0")
+	}
+}
+
+func TestHeaderFlagsInvalid(t *testing.T) {
+	for _, header := range []string{"no-colon", ": value", "Bad Name: value"} {
+		if _, err := parseHeaderFlags(t, "-header", header); err == nil {
+			t.Errorf("-header %q: want an error", header)
+		}
+	}
+}
+
+func TestSplitSourceID(t *testing.T) {
+	tests := []struct {
+		args   []string
+		source string
+		rest   []string
+	}{
+		{[]string{"3160569130087668532", "--query", "naruto"}, "3160569130087668532", []string{"--query", "naruto"}},
+		{[]string{"--query", "naruto"}, "", []string{"--query", "naruto"}},
+		{[]string{"-source", "3160569130087668532"}, "", []string{"-source", "3160569130087668532"}},
+		{[]string{"3160569130087668532"}, "3160569130087668532", []string{}},
+		{nil, "", nil},
+	}
+	for _, tt := range tests {
+		source, rest := SplitSourceID(tt.args)
+		if source != tt.source || len(rest) != len(tt.rest) || (len(rest) > 0 && !reflect.DeepEqual(rest, tt.rest)) {
+			t.Errorf("SplitSourceID(%q) = %q, %q, want %q, %q", tt.args, source, rest, tt.source, tt.rest)
+		}
+	}
+}
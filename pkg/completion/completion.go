@@ -0,0 +1,194 @@
+// Package completion generates bash, zsh and fish completion scripts for an
+// extension binary from its commands and flag set, so commands, flags and
+// fixed flag values such as qualities tab-complete in interactive use:
+//
+//	source <(allanime completion bash)
+//	allanime completion zsh > "${fpath[1]}/_allanime"
+//	allanime completion fish > ~/.config/fish/completions/allanime.fish
+package completion
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Shells lists the shells Write generates scripts for
+var Shells = []string{"bash", "zsh", "fish"}
+
+// Command is a command of the binary, with its description
+type Command struct {
+	Name        string // Command name, optionally followed by a subcommand, e.g. "providers stats"
+	Description string
+}
+
+// Spec describes what a binary accepts
+type Spec struct {
+	Program  string // Binary name the completions are registered for
+	Commands []Command
+	Flags    *flag.FlagSet
+	Values   map[string][]string // Values completed for a flag, by flag name
+	Files    []string            // Flags taking a file or directory path
+}
+
+// Write writes the completion script for shell
+func Write(w io.Writer, shell string, spec Spec) error {
+	switch shell {
+	case "bash":
+		return writeBash(w, spec)
+	case "zsh":
+		return writeZsh(w, spec)
+	case "fish":
+		return writeFish(w, spec)
+	}
+	return fmt.Errorf("unsupported shell %q (valid: %s)", shell, strings.Join(Shells, ", "))
+}
+
+// topLevel returns the commands without their subcommands, each listed once
+func (spec Spec) topLevel() []Command {
+	var commands []Command
+	seen := map[string]bool{}
+	for _, command := range spec.Commands {
+		name := strings.Fields(command.Name)[0]
+		if !seen[name] {
+			seen[name] = true
+			commands = append(commands, Command{Name: name, Description: command.Description})
+		}
+	}
+	return commands
+}
+
+// takesFile reports whether the flag takes a path
+func (spec Spec) takesFile(name string) bool {
+	for _, file := range spec.Files {
+		if file == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isBool reports whether the flag is a switch without a value
+func isBool(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// funcName turns the program name into a shell function name
+func funcName(program string) string {
+	return "_" + regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(program, "_")
+}
+
+func writeBash(w io.Writer, spec Spec) error {
+	var b strings.Builder
+	fn := funcName(spec.Program)
+	fmt.Fprintf(&b, "# bash completion for %s\n", spec.Program)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	var names []string
+	for _, command := range spec.topLevel() {
+		names = append(names, command.Name)
+	}
+	fmt.Fprintf(&b, "\tif [ \"$COMP_CWORD\" -eq 1 ]; then\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\tfi\n", strings.Join(names, " "))
+
+	var flags []string
+	b.WriteString("\tcase \"$prev\" in\n")
+	spec.Flags.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, "-"+f.Name)
+		if isBool(f) {
+			return
+		}
+		pattern := fmt.Sprintf("-%s|--%s", f.Name, f.Name)
+		switch {
+		case len(spec.Values[f.Name]) > 0:
+			fmt.Fprintf(&b, "\t%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\t\t;;\n", pattern, strings.Join(spec.Values[f.Name], " "))
+		case spec.takesFile(f.Name):
+			fmt.Fprintf(&b, "\t%s)\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn\n\t\t;;\n", pattern)
+		default:
+			// Free-form value, nothing to suggest
+			fmt.Fprintf(&b, "\t%s)\n\t\treturn\n\t\t;;\n", pattern)
+		}
+	})
+	b.WriteString("\tesac\n")
+	fmt.Fprintf(&b, "\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(flags, " "))
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, spec.Program)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeZsh(w io.Writer, spec Spec) error {
+	var b strings.Builder
+	fn := funcName(spec.Program)
+	fmt.Fprintf(&b, "#compdef %s\n\n", spec.Program)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal -a commands\n\tcommands=(\n")
+	for _, command := range spec.topLevel() {
+		fmt.Fprintf(&b, "\t\t%s\n", zshQuote(command.Name+":"+command.Description))
+	}
+	b.WriteString("\t)\n\n\t_arguments \\\n")
+
+	spec.Flags.VisitAll(func(f *flag.Flag) {
+		option := fmt.Sprintf("-%s[%s]", f.Name, zshEscape(f.Usage))
+		switch {
+		case isBool(f):
+		case len(spec.Values[f.Name]) > 0:
+			option += fmt.Sprintf(":%s:(%s)", f.Name, strings.Join(spec.Values[f.Name], " "))
+		case spec.takesFile(f.Name):
+			option += fmt.Sprintf(":%s:_files", f.Name)
+		default:
+			option += fmt.Sprintf(":%s: ", f.Name)
+		}
+		fmt.Fprintf(&b, "\t\t%s \\\n", zshQuote(option))
+	})
+	b.WriteString("\t\t'1:command:->command' \\\n\t\t'*::argument:_default'\n\n")
+	b.WriteString("\tif [[ $state == command ]]; then\n\t\t_describe command commands\n\tfi\n}\n\n")
+	fmt.Fprintf(&b, "%s \"$@\"\n", fn)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// zshEscape escapes the characters _arguments treats specially in descriptions
+func zshEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+// zshQuote single-quotes s for zsh
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeFish(w io.Writer, spec Spec) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s\n", spec.Program)
+	fmt.Fprintf(&b, "complete -c %s -f\n", spec.Program)
+	for _, command := range spec.topLevel() {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", spec.Program, command.Name, fishQuote(command.Description))
+	}
+
+	spec.Flags.VisitAll(func(f *flag.Flag) {
+		line := fmt.Sprintf("complete -c %s -o %s -d %s", spec.Program, f.Name, fishQuote(f.Usage))
+		switch {
+		case isBool(f):
+		case len(spec.Values[f.Name]) > 0:
+			line += " -x -a " + fishQuote(strings.Join(spec.Values[f.Name], " "))
+		case spec.takesFile(f.Name):
+			line += " -r -F"
+		default:
+			line += " -x"
+		}
+		b.WriteString(line + "\n")
+	})
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fishQuote single-quotes s for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
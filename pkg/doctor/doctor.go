@@ -0,0 +1,297 @@
+// Package doctor diagnoses the user's environment for an extension: DNS,
+// TLS, proxy settings, clock skew and the permissions of the storage
+// directories. Each check reports what it found and, on failure, what to do
+// about it, so vague "it doesn't work" reports become diagnosable.
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Timeout bounds each network check
+const Timeout = 10 * time.Second
+
+// maxClockSkew is the clock difference above which TLS and signed URLs start failing
+const maxClockSkew = 2 * time.Minute
+
+// Check is the outcome of one diagnostic
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"` // What to do when the check did not pass
+}
+
+// Report collects the checks of one doctor run
+type Report struct {
+	Package string  `json:"pkg"`
+	OK      bool    `json:"ok"` // Whether no check failed; warnings are allowed
+	Checks  []Check `json:"checks"`
+}
+
+// Options configures a doctor run
+type Options struct {
+	Package string   // Extension package, used to locate its storage directories
+	Domains []string // Hosts the extension's sources contact
+	Proxy   *url.URL // Proxy the extension was told to use; nil means the environment's
+}
+
+// Run performs every check and returns the report
+func Run(opts Options) Report {
+	report := Report{Package: opts.Package}
+
+	for _, domain := range opts.Domains {
+		report.add(checkDNS(domain))
+	}
+	report.add(checkProxy(opts.Domains, opts.Proxy))
+	for _, domain := range opts.Domains {
+		report.add(checkTLS(domain))
+	}
+	if len(opts.Domains) > 0 {
+		report.add(checkClock(opts.Domains[0], opts.Proxy))
+	}
+	for _, check := range checkStorage(opts.Package) {
+		report.add(check)
+	}
+
+	report.OK = true
+	for _, check := range report.Checks {
+		if check.Status == StatusFail {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+func (r *Report) add(check Check) {
+	r.Checks = append(r.Checks, check)
+}
+
+// Text renders the report for humans, one line per check with hints indented below
+func (r Report) Text() string {
+	icons := map[string]string{StatusOK: "✅", StatusWarn: "⚠️ ", StatusFail: "❌"}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🩺 Doctor report for %s\n", r.Package)
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "  %s %s: %s\n", icons[check.Status], check.Name, check.Message)
+		if check.Hint != "" && check.Status != StatusOK {
+			fmt.Fprintf(&b, "     💡 %s\n", check.Hint)
+		}
+	}
+	if r.OK {
+		b.WriteString("🏆 No problems found\n")
+	} else {
+		b.WriteString("❌ Problems found; include this report when asking for help\n")
+	}
+	return b.String()
+}
+
+// checkDNS resolves domain
+func checkDNS(domain string) Check {
+	check := Check{Name: "dns " + domain}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, domain)
+	if err != nil {
+		check.Status = StatusFail
+		check.Message = err.Error()
+		check.Hint = "Check your internet connection and DNS server; some ISPs block anime sites, in which case try another DNS resolver (e.g. 1.1.1.1) or a VPN."
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("resolves to %s", strings.Join(addrs, ", "))
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && (ip.IsLoopback() || ip.IsUnspecified() || ip.IsPrivate()) {
+			check.Status = StatusWarn
+			check.Hint = "The domain resolves to a local or private address, which usually means it is blocked by DNS (hosts file, Pi-hole, ISP filter)."
+		}
+	}
+	return check
+}
+
+// checkTLS completes a TLS handshake with domain on port 443
+func checkTLS(domain string) Check {
+	check := Check{Name: "tls " + domain}
+
+	dialer := &net.Dialer{Timeout: Timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(domain, "443"), &tls.Config{ServerName: domain})
+	if err != nil {
+		check.Status = StatusFail
+		check.Message = err.Error()
+		check.Hint = "A failed handshake points at a firewall, an intercepting proxy or antivirus, or a wrong system clock. Direct connections are checked here; see the proxy check if you use one."
+		return check
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("%s, certificate issued by %s", tls.VersionName(state.Version), state.PeerCertificates[0].Issuer.CommonName)
+	return check
+}
+
+// checkProxy reports the proxy used for the domains and whether it is reachable.
+// An explicit proxy takes precedence over the environment.
+func checkProxy(domains []string, proxy *url.URL) Check {
+	check := Check{Name: "proxy"}
+
+	proxyURL := proxy
+	if proxyURL == nil {
+		target := "https://example.com"
+		if len(domains) > 0 {
+			target = "https://" + domains[0]
+		}
+		req, _ := http.NewRequest("GET", target, nil)
+		var err error
+		proxyURL, err = http.ProxyFromEnvironment(req)
+		if err != nil {
+			check.Status = StatusFail
+			check.Message = fmt.Sprintf("invalid proxy setting: %v", err)
+			check.Hint = "Fix or unset HTTPS_PROXY/HTTP_PROXY; they must be URLs such as http://host:port."
+			return check
+		}
+	}
+	if proxyURL == nil {
+		check.Status = StatusOK
+		check.Message = "no proxy configured"
+		return check
+	}
+
+	address := proxyURL.Host
+	if proxyURL.Port() == "" {
+		address = net.JoinHostPort(proxyURL.Hostname(), defaultPort(proxyURL))
+	}
+	conn, err := net.DialTimeout("tcp", address, Timeout)
+	if err != nil {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("proxy %s unreachable: %v", proxyURL.Redacted(), err)
+		check.Hint = "Start the proxy, or fix the --proxy flag or HTTPS_PROXY/HTTP_PROXY."
+		return check
+	}
+	conn.Close()
+
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("using proxy %s", proxyURL.Redacted())
+	return check
+}
+
+func defaultPort(u *url.URL) string {
+	switch u.Scheme {
+	case "https":
+		return "443"
+	case "socks5", "socks5h":
+		return "1080"
+	}
+	return "80"
+}
+
+// checkClock compares the local clock with the Date header of domain, read
+// through proxy when one is given
+func checkClock(domain string, proxy *url.URL) Check {
+	check := Check{Name: "clock"}
+
+	client := &http.Client{Timeout: Timeout}
+	if proxy != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxy)
+		client.Transport = transport
+	}
+	resp, err := client.Head("https://" + domain)
+	if err != nil {
+		check.Status = StatusWarn
+		check.Message = fmt.Sprintf("could not read server time: %v", err)
+		return check
+	}
+	resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		check.Status = StatusWarn
+		check.Message = "server sent no usable Date header"
+		return check
+	}
+
+	skew := time.Since(serverTime).Round(time.Second)
+	if skew.Abs() > maxClockSkew {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("local clock is off by %s", skew)
+		check.Hint = "Enable automatic time synchronisation (NTP); a wrong clock breaks TLS and expiring stream links."
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("local clock within %s of server time", skew.Abs())
+	return check
+}
+
+// checkStorage verifies the extension can create and remove files in its
+// config, data and cache directories
+func checkStorage(pkg string) []Check {
+	locate := []struct {
+		name string
+		dir  func() (string, error)
+	}{
+		{"config dir", extconfig.Dir},
+		{"data dir", func() (string, error) { return extconfig.DataDir(pkg) }},
+		{"cache dir", func() (string, error) { return extconfig.CacheDir(pkg) }},
+	}
+
+	checks := []Check{}
+	for _, entry := range locate {
+		check := Check{Name: entry.name}
+		dir, err := entry.dir()
+		if err != nil {
+			check.Status = StatusFail
+			check.Message = err.Error()
+			check.Hint = "Set HOME, or PAIR_CONFIG_DIR/PAIR_DATA_DIR/PAIR_CACHE_DIR."
+			checks = append(checks, check)
+			continue
+		}
+
+		if err := writable(dir); err != nil {
+			check.Status = StatusFail
+			check.Message = fmt.Sprintf("%s is not writable: %v", dir, err)
+			check.Hint = fmt.Sprintf("Fix the ownership or permissions of %s (e.g. chown -R $USER).", dir)
+		} else {
+			check.Status = StatusOK
+			check.Message = fmt.Sprintf("%s is writable", dir)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// writable creates dir if needed and writes and removes a probe file in it
+func writable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	file.Close()
+	return os.Remove(filepath.Clean(name))
+}
@@ -0,0 +1,92 @@
+// Package extconfig locates and loads per-extension JSON config files so users
+// can adjust extension behaviour without waiting for a release.
+//
+// Config files live at $PAIR_CONFIG_DIR/extensions/<pkg>.json, falling back to
+// $XDG_CONFIG_HOME/pair/extensions/<pkg>.json and ~/.config/pair/extensions/<pkg>.json.
+// Data an extension persists for the user lives in DataDir, disposable data in CacheDir.
+package extconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory holding extension config files
+func Dir() (string, error) {
+	if dir := os.Getenv("PAIR_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "extensions"), nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "pair", "extensions"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating config directory: %v", err)
+	}
+	return filepath.Join(home, ".config", "pair", "extensions"), nil
+}
+
+// DataDir returns the directory holding an extension package's persistent
+// data: $PAIR_DATA_DIR/extensions/<pkg>, falling back to
+// $XDG_DATA_HOME/pair/extensions/<pkg> and ~/.local/share/pair/extensions/<pkg>
+func DataDir(pkg string) (string, error) {
+	if dir := os.Getenv("PAIR_DATA_DIR"); dir != "" {
+		return filepath.Join(dir, "extensions", pkg), nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "pair", "extensions", pkg), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating data directory: %v", err)
+	}
+	return filepath.Join(home, ".local", "share", "pair", "extensions", pkg), nil
+}
+
+// CacheDir returns the directory holding an extension package's disposable
+// cache: $PAIR_CACHE_DIR/extensions/<pkg>, falling back to
+// $XDG_CACHE_HOME/pair/extensions/<pkg> and ~/.cache/pair/extensions/<pkg>
+func CacheDir(pkg string) (string, error) {
+	if dir := os.Getenv("PAIR_CACHE_DIR"); dir != "" {
+		return filepath.Join(dir, "extensions", pkg), nil
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "pair", "extensions", pkg), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating cache directory: %v", err)
+	}
+	return filepath.Join(home, ".cache", "pair", "extensions", pkg), nil
+}
+
+// Path returns the default config file path for an extension package
+func Path(pkg string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, pkg+".json"), nil
+}
+
+// Load reads the JSON config file at path into v. A missing file leaves v
+// untouched and is not an error.
+func Load(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+	return nil
+}
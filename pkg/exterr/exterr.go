@@ -0,0 +1,160 @@
+// Package exterr reports command failures as a JSON object on stdout, so host
+// apps can tell a mistyped flag from a site that is down and decide whether to
+// retry without parsing free text. A failing command prints the error envelope
+// of pair's extension protocol, with the classification alongside
+//
+//	{"status": "error", "error": "...", "code": "network", "retryable": true, "source": "..."}
+//
+// exits with the status for its code (see ExitStatus) and, for humans, repeats
+// the message on stderr.
+package exterr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// Error codes
+const (
+	InvalidArgument = "invalid_argument" // A flag is missing or malformed
+	UnknownCommand  = "unknown_command"  // The command or subcommand does not exist
+	Config          = "config"           // The config file cannot be read
+	NotFound        = "not_found"        // The anime, episode or streams do not exist
+	Unsupported     = "unsupported"      // The feature is not available in this build
+	Network         = "network"          // The source could not be reached
+	Timeout         = "timeout"          // The command ran out of time
+	RateLimited     = "rate_limited"     // The source asked to slow down
+	Unavailable     = "unavailable"      // The source is temporarily down (502, 503, 504)
+	Upstream        = "upstream"         // The source answered with an error or an unexpected response
+	Parse           = "parse"            // The source's response could not be parsed or decrypted
+	GeoBlocked      = "geo_blocked"      // The source refuses requests from this region (451)
+	Internal        = "internal"         // Anything else, e.g. an unwritable data directory
+)
+
+// Exit statuses, so supervising processes can branch on the kind of failure
+// without reading the error object. 2 matches the flag package's status for
+// unparseable flags.
+const (
+	ExitFailure    = 1 // Internal and unsupported, or any code without its own status
+	ExitUsage      = 2 // Invalid arguments, unknown commands and unreadable config
+	ExitNetwork    = 3 // The source could not be reached or answered with an error
+	ExitParse      = 4 // The source's response could not be parsed
+	ExitGeoBlocked = 5 // The source is not available from this region
+	ExitNoResults  = 6 // The anime, episode or streams do not exist
+)
+
+// exitStatuses maps error codes to exit statuses
+var exitStatuses = map[string]int{
+	InvalidArgument: ExitUsage,
+	UnknownCommand:  ExitUsage,
+	Config:          ExitUsage,
+	Network:         ExitNetwork,
+	Timeout:         ExitNetwork,
+	RateLimited:     ExitNetwork,
+	Unavailable:     ExitNetwork,
+	Upstream:        ExitNetwork,
+	Parse:           ExitParse,
+	GeoBlocked:      ExitGeoBlocked,
+	NotFound:        ExitNoResults,
+}
+
+// ExitStatus returns the process exit status for an error code
+func ExitStatus(code string) int {
+	if status, ok := exitStatuses[code]; ok {
+		return status
+	}
+	return ExitFailure
+}
+
+// retryable lists the codes of failures that may go away on their own
+var retryable = map[string]bool{Network: true, Timeout: true, RateLimited: true, Unavailable: true}
+
+// Error is a classified command failure
+type Error struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`        // Whether running the command again later may succeed
+	Source    string `json:"source,omitempty"` // ID of the source the command ran against
+
+	err error
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.err }
+
+// New creates an error with a message formatted like fmt.Errorf, so %w keeps
+// the cause available to errors.Is and errors.As
+func New(code, format string, args ...interface{}) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), Retryable: retryable[code], err: errors.Unwrap(err)}
+}
+
+// From classifies err: errors made with New keep their code, with the message
+// of the outermost error; expired deadlines become Timeout and network
+// failures Network. Anything else gets the fallback code.
+func From(err error, fallback string) *Error {
+	var classified *Error
+	if errors.As(err, &classified) {
+		e := *classified
+		e.Message = err.Error()
+		e.err = err
+		return &e
+	}
+
+	code := fallback
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		code = Timeout
+	case errors.As(err, &netErr):
+		code = Network
+	}
+	return &Error{Code: code, Message: err.Error(), Retryable: retryable[code], err: err}
+}
+
+// StatusCode returns the code for a failed response with the given HTTP status
+func StatusCode(status int) string {
+	switch status {
+	case http.StatusTooManyRequests:
+		return RateLimited
+	case http.StatusUnavailableForLegalReasons:
+		return GeoBlocked
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return Unavailable
+	default:
+		return Upstream
+	}
+}
+
+// envelope is the error output of pair's extension protocol, whose client
+// reads status and error and ignores the rest
+type envelope struct {
+	Status    string `json:"status"` // Always "error"
+	Error     string `json:"error"`  // The message
+	Code      string `json:"code"`
+	Retryable bool   `json:"retryable"`
+	Source    string `json:"source,omitempty"`
+}
+
+// Print writes e to stdout in the error envelope and its message to stderr
+func Print(e *Error) {
+	out := envelope{Status: "error", Error: e.Message, Code: e.Code, Retryable: e.Retryable, Source: e.Source}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err == nil {
+		fmt.Println(string(data))
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s\n", e.Message)
+}
+
+// Exit prints e like Print, then exits with the status for its code.
+// Deferred calls do not run, so release resources such as mock servers first.
+func Exit(e *Error) {
+	Print(e)
+	os.Exit(ExitStatus(e.Code))
+}
@@ -0,0 +1,115 @@
+package exterr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	cause := io.ErrUnexpectedEOF
+	e := New(Network, "error making request: %w", cause)
+
+	if e.Code != Network || !e.Retryable {
+		t.Errorf("New(Network) = %+v, want a retryable network error", e)
+	}
+	if e.Error() != "error making request: unexpected EOF" {
+		t.Errorf("New().Error() = %q", e.Error())
+	}
+	if !errors.Is(e, cause) {
+		t.Error("New() with %w does not unwrap to its cause")
+	}
+	if New(NotFound, "no episode %d", 3).Retryable {
+		t.Error("New(NotFound) is retryable")
+	}
+}
+
+func TestFrom(t *testing.T) {
+	classified := New(RateLimited, "slow down")
+
+	tests := []struct {
+		name          string
+		err           error
+		fallback      string
+		wantCode      string
+		wantMessage   string
+		wantRetryable bool
+	}{
+		{name: "classified", err: classified, fallback: Internal, wantCode: RateLimited, wantMessage: "slow down", wantRetryable: true},
+		{name: "wrapped classified", err: fmt.Errorf("search: %w", classified), fallback: Internal, wantCode: RateLimited, wantMessage: "search: slow down", wantRetryable: true},
+		{name: "deadline", err: fmt.Errorf("request: %w", context.DeadlineExceeded), fallback: Internal, wantCode: Timeout, wantMessage: "request: context deadline exceeded", wantRetryable: true},
+		{name: "network", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, fallback: Upstream, wantCode: Network, wantMessage: "dial tcp: connection refused", wantRetryable: true},
+		{name: "fallback", err: errors.New("bad json"), fallback: Upstream, wantCode: Upstream, wantMessage: "bad json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := From(tt.err, tt.fallback)
+			if e.Code != tt.wantCode || e.Message != tt.wantMessage || e.Retryable != tt.wantRetryable {
+				t.Errorf("From() = {%s %q %v}, want {%s %q %v}", e.Code, e.Message, e.Retryable, tt.wantCode, tt.wantMessage, tt.wantRetryable)
+			}
+			if !errors.Is(e, tt.err) {
+				t.Error("From() does not unwrap to the original error")
+			}
+		})
+	}
+
+	// From copies classified errors instead of modifying them
+	From(fmt.Errorf("outer: %w", classified), Internal)
+	if classified.Message != "slow down" {
+		t.Errorf("From() changed the wrapped error's message to %q", classified.Message)
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: http.StatusTooManyRequests, want: RateLimited},
+		{status: http.StatusUnavailableForLegalReasons, want: GeoBlocked},
+		{status: http.StatusBadGateway, want: Unavailable},
+		{status: http.StatusServiceUnavailable, want: Unavailable},
+		{status: http.StatusGatewayTimeout, want: Unavailable},
+		{status: http.StatusNotFound, want: Upstream},
+		{status: http.StatusInternalServerError, want: Upstream},
+	}
+
+	for _, tt := range tests {
+		if got := StatusCode(tt.status); got != tt.want {
+			t.Errorf("StatusCode(%d) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		code string
+		want int
+	}{
+		{code: InvalidArgument, want: ExitUsage},
+		{code: UnknownCommand, want: ExitUsage},
+		{code: Config, want: ExitUsage},
+		{code: Network, want: ExitNetwork},
+		{code: Timeout, want: ExitNetwork},
+		{code: RateLimited, want: ExitNetwork},
+		{code: Unavailable, want: ExitNetwork},
+		{code: Upstream, want: ExitNetwork},
+		{code: Parse, want: ExitParse},
+		{code: GeoBlocked, want: ExitGeoBlocked},
+		{code: NotFound, want: ExitNoResults},
+		{code: Unsupported, want: ExitFailure},
+		{code: Internal, want: ExitFailure},
+		{code: "something_new", want: ExitFailure},
+	}
+
+	for _, tt := range tests {
+		if got := ExitStatus(tt.code); got != tt.want {
+			t.Errorf("ExitStatus(%q) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
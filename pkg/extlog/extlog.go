@@ -0,0 +1,37 @@
+// Package extlog creates the structured logger behind an extension's
+// -log-level flag. Log lines are JSON objects written to stderr, one per
+// line, so they can be collected and filtered by host apps while stdout stays
+// reserved for the command's JSON result:
+//
+//	{"time":"...","level":"DEBUG","msg":"request","method":"GET","url":"https://...","status":200,"duration_ms":182}
+package extlog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Levels lists the values accepted by New besides "" and "off"
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// New creates a logger writing JSON lines at level and above to w. An empty
+// level or "off" disables logging with a logger that discards everything.
+func New(w io.Writer, level string) (*slog.Logger, error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" || level == "off" {
+		return Discard(), nil
+	}
+
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q (valid: off, %s)", level, strings.Join(Levels, ", "))
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl})), nil
+}
+
+// Discard returns a logger that drops every record
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
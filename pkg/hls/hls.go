@@ -0,0 +1,193 @@
+// Package hls reads HLS playlists to find the duration of a video without
+// starting playback.
+package hls
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Doer sends HTTP requests; *http.Client and *httpclient.Client satisfy it
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Playlist holds the parts of an HLS playlist needed to work out its duration
+// and the qualities it offers
+type Playlist struct {
+	Variants       []Variant // Variant streams of a master playlist
+	Segments       int
+	Duration       float64 // Sum of the #EXTINF segment durations in seconds
+	TargetDuration float64
+	Ended          bool // Whether the playlist has #EXT-X-ENDLIST, i.e. is not a live stream
+}
+
+// Variant is one rendition listed in a master playlist
+type Variant struct {
+	URL        string // Variant playlist URL, resolved against the master playlist URL
+	Bandwidth  int    // Peak bits per second
+	Resolution string // e.g. "1920x1080", empty when not given
+	Height     int    // Vertical resolution, 0 when not given
+}
+
+// Quality returns a label such as "1080p", falling back to the bandwidth in kbps
+func (v Variant) Quality() string {
+	if v.Height > 0 {
+		return fmt.Sprintf("%dp", v.Height)
+	}
+	if v.Bandwidth > 0 {
+		return fmt.Sprintf("%dkbps", v.Bandwidth/1000)
+	}
+	return ""
+}
+
+// parseAttributes reads an attribute list such as
+// BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.64001f,mp4a.40.2"
+func parseAttributes(list string) map[string]string {
+	attributes := map[string]string{}
+	for list != "" {
+		key, rest, ok := strings.Cut(list, "=")
+		if !ok {
+			break
+		}
+
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			// Quoted values may contain commas
+			end := strings.Index(rest[1:], "\"")
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+			rest = strings.TrimPrefix(rest, ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		attributes[strings.TrimSpace(key)] = value
+		list = rest
+	}
+	return attributes
+}
+
+// Parse reads a master or media playlist. Relative variant URLs are resolved against base.
+func Parse(r io.Reader, base *url.URL) (*Playlist, error) {
+	playlist := &Playlist{}
+	scanner := bufio.NewScanner(r)
+
+	first := true
+	var variant *Variant
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if first {
+			if line != "#EXTM3U" {
+				return nil, fmt.Errorf("not an HLS playlist")
+			}
+			first = false
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF"):
+			attributes := parseAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+			variant = &Variant{Resolution: attributes["RESOLUTION"]}
+			variant.Bandwidth, _ = strconv.Atoi(attributes["BANDWIDTH"])
+			if _, height, ok := strings.Cut(variant.Resolution, "x"); ok {
+				variant.Height, _ = strconv.Atoi(height)
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+				playlist.Duration += seconds
+			}
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			if seconds, err := strconv.ParseFloat(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"), 64); err == nil {
+				playlist.TargetDuration = seconds
+			}
+		case line == "#EXT-X-ENDLIST":
+			playlist.Ended = true
+		case strings.HasPrefix(line, "#"):
+		case variant != nil:
+			variantURL, err := base.Parse(line)
+			if err == nil {
+				variant.URL = variantURL.String()
+				playlist.Variants = append(playlist.Variants, *variant)
+			}
+			variant = nil
+		default:
+			playlist.Segments++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading playlist: %v", err)
+	}
+	if first {
+		return nil, fmt.Errorf("empty playlist")
+	}
+	return playlist, nil
+}
+
+// Fetch downloads and parses the playlist at playlistURL
+func Fetch(ctx context.Context, client Doer, playlistURL string, headers map[string]string) (*Playlist, error) {
+	base, err := url.Parse(playlistURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid playlist URL: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", playlistURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return Parse(resp.Body, base)
+}
+
+// Duration returns the length in seconds of the video behind an HLS playlist.
+// Master playlists are followed to their first variant. When the segments carry
+// no #EXTINF durations, each counts as the target duration. Live playlists are
+// rejected because their length is not known yet.
+func Duration(ctx context.Context, client Doer, playlistURL string, headers map[string]string) (float64, error) {
+	playlist, err := Fetch(ctx, client, playlistURL, headers)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(playlist.Variants) > 0 {
+		playlist, err = Fetch(ctx, client, playlist.Variants[0].URL, headers)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if !playlist.Ended {
+		return 0, fmt.Errorf("playlist is live, duration unknown")
+	}
+	if playlist.Duration == 0 {
+		return playlist.TargetDuration * float64(playlist.Segments), nil
+	}
+	return playlist.Duration, nil
+}
@@ -0,0 +1,160 @@
+package hls
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	masterPlaylist = "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080\n1080/index.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nhttps://cdn.example.com/360.m3u8\n"
+	mediaPlaylist  = "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:9.5,\nseg0.ts\n#EXTINF:10.0,\nseg1.ts\n#EXTINF:4.25,title\nseg2.ts\n#EXT-X-ENDLIST\n"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/video/master.m3u8")
+
+	tests := []struct {
+		name    string
+		data    string
+		want    *Playlist
+		wantErr bool
+	}{
+		{
+			name: "master",
+			data: masterPlaylist,
+			want: &Playlist{Variants: []Variant{
+				{URL: "https://example.com/video/1080/index.m3u8", Bandwidth: 5000000, Resolution: "1920x1080", Height: 1080},
+				{URL: "https://cdn.example.com/360.m3u8", Bandwidth: 800000},
+			}},
+		},
+		{
+			name: "media",
+			data: mediaPlaylist,
+			want: &Playlist{Segments: 3, Duration: 23.75, TargetDuration: 10, Ended: true},
+		},
+		{
+			name: "live without durations",
+			data: "#EXTM3U\r\n\r\n#EXT-X-TARGETDURATION:6\r\na.ts\r\nb.ts\r\n",
+			want: &Playlist{Segments: 2, TargetDuration: 6},
+		},
+		{name: "not a playlist", data: "<html></html>", wantErr: true},
+		{name: "empty", data: "\n\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.data), base)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Referer") != "https://example.com/" {
+			http.Error(w, "missing referer", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\nmedia.m3u8\n"))
+		case "/media.m3u8":
+			w.Write([]byte(mediaPlaylist))
+		case "/untimed.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:6\na.ts\nb.ts\nc.ts\n#EXT-X-ENDLIST\n"))
+		case "/live.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXTINF:6,\na.ts\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	headers := map[string]string{"Referer": "https://example.com/"}
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		want    float64
+		wantErr bool
+	}{
+		{name: "master follows first variant", path: "/master.m3u8", headers: headers, want: 23.75},
+		{name: "media", path: "/media.m3u8", headers: headers, want: 23.75},
+		{name: "segments times target duration", path: "/untimed.m3u8", headers: headers, want: 18},
+		{name: "live", path: "/live.m3u8", headers: headers, wantErr: true},
+		{name: "not found", path: "/missing.m3u8", headers: headers, wantErr: true},
+		{name: "headers are sent", path: "/media.m3u8", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Duration(context.Background(), server.Client(), server.URL+tt.path, tt.headers)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Duration() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Duration() returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Duration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	tests := []struct {
+		list string
+		want map[string]string
+	}{
+		{list: "BANDWIDTH=5000000,RESOLUTION=1920x1080", want: map[string]string{"BANDWIDTH": "5000000", "RESOLUTION": "1920x1080"}},
+		{list: `BANDWIDTH=1,CODECS="avc1.64001f,mp4a.40.2",RESOLUTION=640x360`, want: map[string]string{"BANDWIDTH": "1", "CODECS": "avc1.64001f,mp4a.40.2", "RESOLUTION": "640x360"}},
+		{list: `NAME="unterminated`, want: map[string]string{"NAME": "unterminated"}},
+		{list: "BANDWIDTH=1,garbage", want: map[string]string{"BANDWIDTH": "1"}},
+		{list: "", want: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			if got := parseAttributes(tt.list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAttributes(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariantQuality(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		want    string
+	}{
+		{variant: Variant{Height: 720, Bandwidth: 2500000}, want: "720p"},
+		{variant: Variant{Bandwidth: 800000}, want: "800kbps"},
+		{variant: Variant{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.variant.Quality(); got != tt.want {
+			t.Errorf("%+v.Quality() = %q, want %q", tt.variant, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package hosters
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Desustream resolves the players of Desustream, the video host of Otakudesu,
+// e.g. https://desustream.info/dstream/ondesu/hd/v5/index.php?id=..., into
+// their MP4 file or HLS playlist. Desustream runs several players, such as
+// ondesu, desudrive and updesu; each configures a JW Player or a <video>
+// element with the file.
+type Desustream struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the Desustream hosts
+func (d *Desustream) Domains() []string {
+	return []string{"desustream.info", "desustream.me"}
+}
+
+// StreamDomains lists the hosts the players take files from: Desustream's own
+// storage and Blogger's video servers
+func (d *Desustream) StreamDomains() []string {
+	return []string{"desustream.com", "googlevideo.com"}
+}
+
+var (
+	// desustreamFile finds the file of a JW Player setup: 'file':'https://...'
+	desustreamFile = regexp.MustCompile(`['"]?file['"]?\s*:\s*['"](https?://[^'"]+)['"]`)
+	// desustreamSource finds the file of a <video> element: <source src="https://...">
+	desustreamSource = regexp.MustCompile(`<source[^>]+src=['"](https?://[^'"]+)['"]`)
+)
+
+// Extract resolves embedURL, which the page at referer links to
+func (d *Desustream) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, d.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+
+	match := desustreamFile.FindSubmatch(page)
+	if match == nil {
+		match = desustreamSource.FindSubmatch(page)
+	}
+	if match == nil {
+		return Result{}, exterr.New(exterr.Parse, "no video file on the Desustream page %s", embedURL)
+	}
+	file := string(match[1])
+	headers := map[string]string{"User-Agent": d.Client.UserAgent(UserAgent), "Referer": origin(embedURL) + "/"}
+	stream := Stream{URL: file, Quality: "default", Headers: headers}
+	if strings.Contains(file, ".m3u8") {
+		stream.Quality, stream.HLS = "auto", true
+	}
+	return Result{Streams: []Stream{stream}}, nil
+}
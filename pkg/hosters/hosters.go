@@ -0,0 +1,143 @@
+// Package hosters resolves the embed pages of third-party video hosts into
+// stream URLs a player can open. Sites link their episodes to players on these
+// hosts rather than to video files, and several sites share the same hosts, so
+// the extractors live here instead of in each extension.
+//
+// Every extractor takes the embed URL and the page that linked to it, sends its
+// requests through the extension's httpclient.Client and reports failures as
+// exterr errors: NotFound when the host says the video is gone, Parse when the
+// page no longer looks the way the extractor expects.
+package hosters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// UserAgent is sent to hosts that serve a different page, or none, to clients
+// that do not look like a browser, unless the client's Header sets another
+const UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0"
+
+// Stream is a playable URL resolved from an embed page
+type Stream struct {
+	URL     string            // Video file or HLS playlist
+	Quality string            // e.g. "1080p", or "auto" for an adaptive HLS playlist
+	HLS     bool              // Whether URL is an HLS playlist
+	Headers map[string]string // Headers the host requires when fetching the stream
+}
+
+// Track is an external subtitle track served alongside the streams
+type Track struct {
+	URL     string
+	Label   string // Display name, e.g. "English"
+	Default bool   // Whether the player enables the track by default
+}
+
+// Segment is a time range of the episode in seconds, such as the opening
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Result is what an extractor found on an embed page
+type Result struct {
+	Streams []Stream
+	Tracks  []Track
+	Intro   *Segment // Opening, when the host marks it
+	Outro   *Segment // Ending, when the host marks it
+}
+
+// Extractor resolves the embed pages of one video host
+type Extractor interface {
+	// Domains lists the hosts the extractor handles; subdomains match too
+	Domains() []string
+	// StreamDomains lists the other hosts its streams, tracks and lookups
+	// are served from, such as the host's CDN; subdomains count too
+	StreamDomains() []string
+	// Extract resolves embedURL, which the page at referer links to
+	Extract(ctx context.Context, embedURL, referer string) (Result, error)
+}
+
+// For returns the extractor handling embedURL, or nil when none of extractors does
+func For(extractors []Extractor, embedURL string) Extractor {
+	u, err := url.Parse(embedURL)
+	if err != nil {
+		return nil
+	}
+	for _, extractor := range extractors {
+		if hostMatches(u.Hostname(), extractor.Domains()) {
+			return extractor
+		}
+	}
+	return nil
+}
+
+// NetworkDomains returns every host extractors contact or hand to the player,
+// for an extension's network permissions
+func NetworkDomains(extractors []Extractor) []string {
+	var domains []string
+	for _, extractor := range extractors {
+		domains = append(domains, extractor.Domains()...)
+		domains = append(domains, extractor.StreamDomains()...)
+	}
+	return domains
+}
+
+// fetch sends a GET request with headers and returns the body, classifying
+// failures the way the extensions do
+func fetch(ctx context.Context, client *httpclient.Client, rawURL string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.DoRetry(req, httpclient.DefaultRetryPolicy)
+	if err != nil {
+		return nil, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, exterr.New(exterr.NotFound, "video not found on %s", req.URL.Host)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, exterr.From(fmt.Errorf("error reading response: %w", err), exterr.Network)
+	}
+	return body, nil
+}
+
+// origin returns the scheme and host of rawURL, e.g. https://megacloud.blog
+func origin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// hostMatches reports whether host is one of domains or a subdomain of one
+func hostMatches(host string, domains []string) bool {
+	host = strings.ToLower(strings.TrimPrefix(host, "www."))
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package hosters
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// MegaCloudDomains lists the hosts of the MegaCloud player, including the
+// RapidCloud deployment older HiAnime servers link to
+var MegaCloudDomains = []string{"megacloud.tv", "megacloud.blog", "megacloud.club", "rapid-cloud.co", "rabbitstream.net"}
+
+// MegaCloudStreamDomains lists the CDNs MegaCloud playlists and subtitles are
+// served from, and the host of DefaultMegaCloudKeyURL
+var MegaCloudStreamDomains = []string{"dotstream.buzz", "netmagcdn.com", "megastatics.com", "raw.githubusercontent.com"}
+
+// DefaultMegaCloudKeyURL serves the current MegaCloud decryption keys as a JSON
+// object ({"mega": "...", "rabbit": "..."}). The player rotates its keys every
+// few days, so they are fetched instead of built in.
+const DefaultMegaCloudKeyURL = "https://raw.githubusercontent.com/yogesh-hacker/MegacloudKeys/refs/heads/main/keys.json"
+
+// MegaCloud resolves MegaCloud and RapidCloud embeds, e.g.
+// https://megacloud.blog/embed-2/v3/e-1/AbCdEf?k=1, into their HLS playlist,
+// subtitle tracks and opening and ending times
+type MegaCloud struct {
+	Client *httpclient.Client
+	KeyURL string // Key document, DefaultMegaCloudKeyURL when empty
+
+	mu   sync.Mutex
+	keys map[string]string // Fetched once per process
+}
+
+// Domains lists the MegaCloud and RapidCloud hosts
+func (m *MegaCloud) Domains() []string {
+	return MegaCloudDomains
+}
+
+// StreamDomains lists the CDNs serving the playlists and subtitles, and the host
+// of DefaultMegaCloudKeyURL
+func (m *MegaCloud) StreamDomains() []string {
+	return MegaCloudStreamDomains
+}
+
+// IsMegaCloud reports whether embedURL is a MegaCloud or RapidCloud player
+func IsMegaCloud(embedURL string) bool {
+	u, err := url.Parse(embedURL)
+	return err == nil && hostMatches(u.Hostname(), MegaCloudDomains)
+}
+
+// megaCloudSources is the player's getSources response
+type megaCloudSources struct {
+	Sources   json.RawMessage `json:"sources"` // A list of files, or that list encrypted into a string when Encrypted is set
+	Encrypted bool            `json:"encrypted"`
+	Tracks    []struct {
+		File    string `json:"file"`
+		Label   string `json:"label"`
+		Kind    string `json:"kind"` // captions, or thumbnails for the seek bar previews
+		Default bool   `json:"default"`
+	} `json:"tracks"`
+	Intro Segment `json:"intro"`
+	Outro Segment `json:"outro"`
+}
+
+// megaCloudFile is a stream in the sources list
+type megaCloudFile struct {
+	File string `json:"file"`
+	Type string `json:"type"` // hls or mp4
+}
+
+// clientKeyPatterns find the per-page key the current player sends along with
+// getSources: a 48 character token, or the same token split into three parts
+var clientKeyPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`<meta name="_gg_fb" content="([A-Za-z0-9]{48})"`),
+	regexp.MustCompile(`x:\s*"([A-Za-z0-9]{16})",\s*y:\s*"([A-Za-z0-9]{16})",\s*z:\s*"([A-Za-z0-9]{16})"`),
+	regexp.MustCompile(`\b([A-Za-z0-9]{48})\b`),
+}
+
+// Extract resolves embedURL, which the page at referer links to
+func (m *MegaCloud) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	u, err := url.Parse(embedURL)
+	if err != nil {
+		return Result{}, exterr.New(exterr.Parse, "invalid MegaCloud URL %q: %w", embedURL, err)
+	}
+	sourcesURL, versioned, err := megaCloudSourcesURL(u)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// The versioned players refuse getSources without the key of the embed page
+	if versioned {
+		page, err := fetch(ctx, m.Client, embedURL, map[string]string{"Referer": referer})
+		if err != nil {
+			return Result{}, err
+		}
+		if key := clientKey(page); key != "" {
+			sourcesURL += "&_k=" + url.QueryEscape(key)
+		}
+	}
+
+	body, err := fetch(ctx, m.Client, sourcesURL, map[string]string{
+		"Referer":          embedURL,
+		"X-Requested-With": "XMLHttpRequest",
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	var response megaCloudSources
+	if err := json.Unmarshal(body, &response); err != nil {
+		return Result{}, exterr.New(exterr.Parse, "error parsing MegaCloud sources: %w", err)
+	}
+	files, err := m.files(ctx, u.Hostname(), response)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(files) == 0 {
+		return Result{}, exterr.New(exterr.NotFound, "no streams on %s", u.Host)
+	}
+
+	// Playlists and segments are only served to the player's origin
+	playerOrigin := origin(embedURL)
+	headers := map[string]string{"User-Agent": m.Client.UserAgent(UserAgent), "Referer": playerOrigin + "/", "Origin": playerOrigin}
+
+	var result Result
+	for _, file := range files {
+		hls := file.Type == "hls" || strings.Contains(file.File, ".m3u8")
+		quality := "auto"
+		if !hls {
+			quality = "default"
+		}
+		result.Streams = append(result.Streams, Stream{URL: file.File, Quality: quality, HLS: hls, Headers: headers})
+	}
+	for _, track := range response.Tracks {
+		if track.File == "" || track.Kind == "thumbnails" {
+			continue
+		}
+		result.Tracks = append(result.Tracks, Track{URL: track.File, Label: track.Label, Default: track.Default})
+	}
+	if response.Intro.End > 0 {
+		intro := response.Intro
+		result.Intro = &intro
+	}
+	if response.Outro.End > 0 {
+		outro := response.Outro
+		result.Outro = &outro
+	}
+	return result, nil
+}
+
+// versionSegment matches the player version in embed paths, e.g. v3
+var versionSegment = regexp.MustCompile(`^v\d+$`)
+
+// megaCloudSourcesURL returns the getSources endpoint for an embed URL and
+// whether the player is one of the versioned ones:
+//
+//	/embed-2/v3/e-1/ID  ->  /embed-2/v3/e-1/getSources?id=ID
+//	/embed-2/e-1/ID     ->  /embed-2/ajax/e-1/getSources?id=ID
+func megaCloudSourcesURL(u *url.URL) (string, bool, error) {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 {
+		return "", false, exterr.New(exterr.Parse, "unexpected MegaCloud URL %q", u.String())
+	}
+	id := segments[len(segments)-1]
+	prefix := segments[:len(segments)-1]
+
+	versioned := false
+	for _, segment := range prefix {
+		if versionSegment.MatchString(segment) {
+			versioned = true
+		}
+	}
+	if !versioned {
+		prefix = append([]string{prefix[0], "ajax"}, prefix[1:]...)
+	}
+
+	sources := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/" + strings.Join(append(prefix, "getSources"), "/")}
+	return sources.String() + "?id=" + url.QueryEscape(id), versioned, nil
+}
+
+// clientKey finds the key the player page hands to getSources
+func clientKey(page []byte) string {
+	for _, pattern := range clientKeyPatterns {
+		if match := pattern.FindSubmatch(page); match != nil {
+			return string(bytes.Join(match[1:], nil))
+		}
+	}
+	return ""
+}
+
+// files reads the stream list, decrypting it when the player encrypted it
+func (m *MegaCloud) files(ctx context.Context, host string, response megaCloudSources) ([]megaCloudFile, error) {
+	var files []megaCloudFile
+	if !response.Encrypted {
+		if err := json.Unmarshal(response.Sources, &files); err != nil {
+			return nil, exterr.New(exterr.Parse, "error parsing MegaCloud sources: %w", err)
+		}
+		return files, nil
+	}
+
+	var encrypted string
+	if err := json.Unmarshal(response.Sources, &encrypted); err != nil {
+		return nil, exterr.New(exterr.Parse, "error parsing MegaCloud sources: %w", err)
+	}
+	key, err := m.key(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := decryptOpenSSL(encrypted, key)
+	if err != nil {
+		return nil, exterr.New(exterr.Parse, "error decrypting MegaCloud sources, the key may have rotated: %w", err)
+	}
+	if err := json.Unmarshal(plain, &files); err != nil {
+		return nil, exterr.New(exterr.Parse, "error decrypting MegaCloud sources, the key may have rotated: %w", err)
+	}
+	return files, nil
+}
+
+// key returns the decryption key for the player on host
+func (m *MegaCloud) key(ctx context.Context, host string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.keys == nil {
+		keyURL := m.KeyURL
+		if keyURL == "" {
+			keyURL = DefaultMegaCloudKeyURL
+		}
+		body, err := fetch(ctx, m.Client, keyURL, nil)
+		if err != nil {
+			return "", err
+		}
+		keys := map[string]string{}
+		if err := json.Unmarshal(body, &keys); err != nil {
+			return "", exterr.New(exterr.Parse, "error parsing MegaCloud keys from %s: %w", keyURL, err)
+		}
+		m.keys = keys
+	}
+
+	name := "mega"
+	if hostMatches(host, []string{"rapid-cloud.co", "rabbitstream.net"}) {
+		name = "rabbit"
+	}
+	key := m.keys[name]
+	if key == "" {
+		return "", exterr.New(exterr.Parse, "no %q key in the MegaCloud key document", name)
+	}
+	return key, nil
+}
+
+// decryptOpenSSL decrypts base64 text in OpenSSL's salted format, as CryptoJS
+// writes it for AES with a passphrase: "Salted__", an 8 byte salt, then
+// AES-256-CBC with the key and IV derived from passphrase and salt by
+// EVP_BytesToKey with MD5
+func decryptOpenSSL(text, passphrase string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 16 || string(data[:8]) != "Salted__" {
+		return nil, fmt.Errorf("missing OpenSSL salt header")
+	}
+	salt, data := data[8:16], data[16:]
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("ciphertext is not a whole number of blocks")
+	}
+
+	// EVP_BytesToKey: chain MD5 digests until there are enough bytes for key and IV
+	var derived, previous []byte
+	for len(derived) < 48 {
+		sum := md5.Sum(append(append(previous, passphrase...), salt...))
+		previous = sum[:]
+		derived = append(derived, previous...)
+	}
+
+	block, err := aes.NewCipher(derived[:32])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, derived[32:48]).CryptBlocks(plain, data)
+
+	// Strip PKCS#7 padding
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(plain) || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("bad padding")
+	}
+	return plain[:len(plain)-padding], nil
+}
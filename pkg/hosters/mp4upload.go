@@ -0,0 +1,46 @@
+package hosters
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Mp4Upload resolves Mp4Upload embeds, e.g.
+// https://www.mp4upload.com/embed-a1b2c3d4e5f6.html, into their MP4 file
+type Mp4Upload struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the Mp4Upload hosts
+func (m *Mp4Upload) Domains() []string {
+	return []string{"mp4upload.com"}
+}
+
+// StreamDomains lists no other hosts, the files are served from subdomains such
+// as a4.mp4upload.com
+func (m *Mp4Upload) StreamDomains() []string {
+	return nil
+}
+
+// mp4UploadFile finds the video the embed page hands to its player:
+// player.src({ type: "video/mp4", src: "https://a4.mp4upload.com:183/d/.../video.mp4" })
+var mp4UploadFile = regexp.MustCompile(`player\.src\(\{[^}]*?src:\s*"([^"]+)"`)
+
+// Extract resolves embedURL, which the page at referer links to
+func (m *Mp4Upload) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, m.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+
+	match := mp4UploadFile.FindSubmatch(page)
+	if match == nil {
+		return Result{}, exterr.New(exterr.Parse, "no video file on the Mp4Upload page %s", embedURL)
+	}
+	// The file server refuses requests without the embed's site as referer
+	headers := map[string]string{"User-Agent": m.Client.UserAgent(UserAgent), "Referer": "https://www.mp4upload.com/"}
+	return Result{Streams: []Stream{{URL: string(match[1]), Quality: "default", Headers: headers}}}, nil
+}
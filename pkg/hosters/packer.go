@@ -0,0 +1,50 @@
+package hosters
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// packedScript finds the arguments of a script packed with Dean Edwards'
+// P.A.C.K.E.R., which several hosts wrap their player setup in:
+//
+//	eval(function(p,a,c,k,e,d){...}('0 1=\'2\'',3,3,'var|file|https'.split('|'),0,{}))
+var packedScript = regexp.MustCompile(`(?s)\}\('(.*?)',\s*(\d+),\s*(\d+),\s*'(.*?)'\.split\('\|'\)`)
+
+// packedWord finds the encoded words of a packed payload
+var packedWord = regexp.MustCompile(`\b\w+\b`)
+
+// packedDigits are the digits of the bases up to 62 the packer encodes words in
+const packedDigits = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// unpack returns the source of the first packed script in page, or false when
+// page has none. Each word of the payload is a number in the packer's base
+// indexing the keyword list; words whose keyword is empty stand for themselves.
+func unpack(page []byte) (string, bool) {
+	match := packedScript.FindSubmatch(page)
+	if match == nil {
+		return "", false
+	}
+	base, err := strconv.Atoi(string(match[2]))
+	if err != nil || base < 2 || base > len(packedDigits) {
+		return "", false
+	}
+	payload := strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(string(match[1]))
+	keywords := strings.Split(string(match[4]), "|")
+
+	return packedWord.ReplaceAllStringFunc(payload, func(word string) string {
+		index := 0
+		for _, r := range word {
+			digit := strings.IndexRune(packedDigits[:base], r)
+			if digit < 0 {
+				return word
+			}
+			index = index*base + digit
+		}
+		if index < len(keywords) && keywords[index] != "" {
+			return keywords[index]
+		}
+		return word
+	}), true
+}
@@ -0,0 +1,49 @@
+package hosters
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Sibnet resolves Sibnet embeds, e.g.
+// https://video.sibnet.ru/shell.php?videoid=1234567, into their MP4 file
+type Sibnet struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the Sibnet hosts
+func (s *Sibnet) Domains() []string {
+	return []string{"video.sibnet.ru"}
+}
+
+// StreamDomains lists the CDN the files redirect to, e.g. dv98.sibnet.ru
+func (s *Sibnet) StreamDomains() []string {
+	return []string{"sibnet.ru"}
+}
+
+// sibnetFile finds the video the player page hands to its player, a path
+// relative to the player's host
+var sibnetFile = regexp.MustCompile(`player\.src\(\[\{\s*src:\s*"([^"]+)"`)
+
+// Extract resolves embedURL, which the page at referer links to
+func (s *Sibnet) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, s.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+
+	match := sibnetFile.FindSubmatch(page)
+	if match == nil {
+		return Result{}, exterr.New(exterr.Parse, "no video file on the Sibnet page %s", embedURL)
+	}
+	file := string(match[1])
+	if file[0] == '/' {
+		file = origin(embedURL) + file
+	}
+	// The file redirects to a CDN that checks the player page as referer
+	headers := map[string]string{"User-Agent": s.Client.UserAgent(UserAgent), "Referer": embedURL}
+	return Result{Streams: []Stream{{URL: file, Quality: "default", Headers: headers}}}, nil
+}
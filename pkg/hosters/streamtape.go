@@ -0,0 +1,58 @@
+package hosters
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Streamtape resolves Streamtape embeds, e.g. https://streamtape.com/e/AbCdEf,
+// into their MP4 file
+type Streamtape struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the Streamtape hosts
+func (s *Streamtape) Domains() []string {
+	return []string{"streamtape.com", "streamtape.net", "streamtape.to", "streamta.pe", "strtape.tech", "strtpe.link"}
+}
+
+// StreamDomains lists the file servers the video links redirect to
+func (s *Streamtape) StreamDomains() []string {
+	return []string{"tapecontent.net"}
+}
+
+// streamtapeLink finds the script assembling the video link: a quoted prefix
+// joined with a quoted token the script cuts with substring calls, e.g.
+//
+//	document.getElementById('robotlink').innerHTML = '//streamtape.com/get_video?id=x&expires=1&ip=y&token=' + ('xcdz').substring(1).substring(2);
+var streamtapeLink = regexp.MustCompile(`getElementById\('robotlink'\)\.innerHTML\s*=\s*'([^']+)'\s*\+\s*\('([^']+)'\)((?:\.substring\(\d+\))*)`)
+
+// substringCall finds the offsets of a substring chain
+var substringCall = regexp.MustCompile(`\.substring\((\d+)\)`)
+
+// Extract resolves embedURL, which the page at referer links to
+func (s *Streamtape) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, s.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+
+	match := streamtapeLink.FindStringSubmatch(string(page))
+	if match == nil {
+		return Result{}, exterr.New(exterr.Parse, "no video link on the Streamtape page %s", embedURL)
+	}
+	token := match[2]
+	for _, call := range substringCall.FindAllStringSubmatch(match[3], -1) {
+		offset, _ := strconv.Atoi(call[1])
+		token = token[min(offset, len(token)):]
+	}
+
+	// The link redirects to the file server; stream=1 asks for playback instead of a download
+	link := "https:" + match[1] + token + "&stream=1"
+	headers := map[string]string{"User-Agent": s.Client.UserAgent(UserAgent), "Referer": origin(embedURL) + "/"}
+	return Result{Streams: []Stream{{URL: link, Quality: "default", Headers: headers}}}, nil
+}
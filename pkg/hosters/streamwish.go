@@ -0,0 +1,63 @@
+package hosters
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// StreamWish resolves StreamWish embeds, e.g. https://streamwish.to/e/AbCdEf,
+// into their HLS playlist and subtitle tracks. StreamWish serves its player
+// under many domains and usually packs the player setup with P.A.C.K.E.R.
+type StreamWish struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the StreamWish hosts
+func (s *StreamWish) Domains() []string {
+	return []string{
+		"streamwish.to", "streamwish.com", "strwish.com", "wishembed.pro", "embedwish.com",
+		"playerwish.com", "awish.pro", "dwish.pro", "mwish.pro", "sfastwish.com", "swdyu.com",
+	}
+}
+
+// StreamDomains lists no other hosts, the playlists are served from
+// subdomains of the player domains such as cdn112.swdyu.com
+func (s *StreamWish) StreamDomains() []string {
+	return nil
+}
+
+var (
+	// streamWishFile finds the playlist of the JW Player setup:
+	// sources:[{file:"https://.../master.m3u8?t=..."}]
+	streamWishFile = regexp.MustCompile(`sources\s*:\s*\[\s*\{\s*file\s*:\s*"([^"]+)"`)
+	// streamWishTrack finds the subtitle tracks of the setup:
+	// {file:"https://.../eng.vtt",label:"English",kind:"captions"}
+	streamWishTrack = regexp.MustCompile(`\{\s*file\s*:\s*"([^"]+\.vtt[^"]*)"\s*,\s*label\s*:\s*"([^"]*)"\s*,\s*kind\s*:\s*"captions"(\s*,\s*"?default"?\s*:\s*true)?`)
+)
+
+// Extract resolves embedURL, which the page at referer links to
+func (s *StreamWish) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, s.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+
+	script := string(page)
+	if unpacked, ok := unpack(page); ok {
+		script = unpacked
+	}
+	match := streamWishFile.FindStringSubmatch(script)
+	if match == nil {
+		return Result{}, exterr.New(exterr.Parse, "no playlist on the StreamWish page %s", embedURL)
+	}
+
+	headers := map[string]string{"User-Agent": s.Client.UserAgent(UserAgent), "Referer": origin(embedURL) + "/"}
+	result := Result{Streams: []Stream{{URL: match[1], Quality: "auto", HLS: true, Headers: headers}}}
+	for _, track := range streamWishTrack.FindAllStringSubmatch(script, -1) {
+		result.Tracks = append(result.Tracks, Track{URL: track[1], Label: track[2], Default: track[3] != ""})
+	}
+	return result, nil
+}
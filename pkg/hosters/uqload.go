@@ -0,0 +1,46 @@
+package hosters
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Uqload resolves Uqload embeds, e.g. https://uqload.io/embed-a1b2c3d4e5f6.html,
+// into their MP4 file
+type Uqload struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the Uqload hosts
+func (u *Uqload) Domains() []string {
+	return []string{"uqload.io", "uqload.com", "uqload.co", "uqload.to", "uqload.net"}
+}
+
+// StreamDomains lists no other hosts, the files are served from subdomains such
+// as m180.uqload.io
+func (u *Uqload) StreamDomains() []string {
+	return nil
+}
+
+// uqloadFile finds the video of the embed's Clappr player setup:
+// sources: ["https://m180.uqload.io/3rfkx2ggfjrjwrhvacckxy3skhmfm/v.mp4"]
+var uqloadFile = regexp.MustCompile(`sources:\s*\[\s*"(https?://[^"]+)"`)
+
+// Extract resolves embedURL, which the page at referer links to
+func (u *Uqload) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, u.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+
+	match := uqloadFile.FindSubmatch(page)
+	if match == nil {
+		return Result{}, exterr.New(exterr.Parse, "no video file on the Uqload page %s", embedURL)
+	}
+	// The file servers refuse requests without the embed's site as referer
+	headers := map[string]string{"User-Agent": u.Client.UserAgent(UserAgent), "Referer": origin(embedURL) + "/"}
+	return Result{Streams: []Stream{{URL: string(match[1]), Quality: "default", Headers: headers}}}, nil
+}
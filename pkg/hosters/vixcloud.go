@@ -0,0 +1,80 @@
+package hosters
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// VixCloud resolves VixCloud embeds, e.g.
+// https://vixcloud.co/embed/123456?token=...&expires=..., into their HLS
+// playlist and, when the embed offers a download, the MP4 file
+type VixCloud struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the VixCloud hosts
+func (v *VixCloud) Domains() []string {
+	return []string{"vixcloud.co"}
+}
+
+// StreamDomains lists the CDN serving the downloads
+func (v *VixCloud) StreamDomains() []string {
+	return []string{"scws-content.net"}
+}
+
+var (
+	// vixMasterPlaylist finds the playlist object the player is configured with:
+	// window.masterPlaylist = { params: { 'token': '...', ... }, url: '...' }
+	vixMasterPlaylist = regexp.MustCompile(`(?s)window\.masterPlaylist\s*=\s*\{\s*params:\s*\{(.*?)\},\s*url:\s*'([^']+)'`)
+	// vixParam matches one 'name': 'value' entry of the playlist parameters
+	vixParam = regexp.MustCompile(`'(\w+)':\s*'([^']*)'`)
+	// vixFHD is set when the player may request the 1080p renditions
+	vixFHD = regexp.MustCompile(`window\.canPlayFHD\s*=\s*true`)
+	// vixDownload finds the MP4 file the download button links to
+	vixDownload = regexp.MustCompile(`window\.downloadUrl\s*=\s*'([^']+)'`)
+	// vixQuality finds the height of the video's best rendition in window.video
+	vixQuality = regexp.MustCompile(`"quality":\s*(\d+)`)
+)
+
+// Extract resolves embedURL, which the page at referer links to
+func (v *VixCloud) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, v.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+
+	match := vixMasterPlaylist.FindSubmatch(page)
+	if match == nil {
+		return Result{}, exterr.New(exterr.Parse, "no playlist on the VixCloud page %s", embedURL)
+	}
+	playlist, err := url.Parse(string(match[2]))
+	if err != nil {
+		return Result{}, exterr.New(exterr.Parse, "invalid VixCloud playlist URL: %w", err)
+	}
+	// The playlist is signed with the parameters of the embed page
+	query := playlist.Query()
+	for _, param := range vixParam.FindAllSubmatch(match[1], -1) {
+		if len(param[2]) > 0 {
+			query.Set(string(param[1]), string(param[2]))
+		}
+	}
+	if vixFHD.Match(page) {
+		query.Set("h", "1")
+	}
+	playlist.RawQuery = query.Encode()
+
+	headers := map[string]string{"User-Agent": v.Client.UserAgent(UserAgent), "Referer": origin(embedURL) + "/"}
+	streams := []Stream{{URL: playlist.String(), Quality: "auto", HLS: true, Headers: headers}}
+	if download := vixDownload.FindSubmatch(page); download != nil {
+		quality := "default"
+		if height := vixQuality.FindSubmatch(page); height != nil {
+			quality = string(height[1]) + "p"
+		}
+		streams = append(streams, Stream{URL: string(download[1]), Quality: quality, Headers: headers})
+	}
+	return Result{Streams: streams}, nil
+}
@@ -0,0 +1,142 @@
+package hosters
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// VOE resolves VOE embeds, e.g. https://voe.sx/e/AbCdEf, into their HLS
+// playlist or MP4 file. VOE bounces embeds to rotating mirror domains, which
+// the extractor follows.
+type VOE struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the VOE hosts embeds link to; the mirrors they bounce to vary
+func (v *VOE) Domains() []string {
+	return []string{"voe.sx"}
+}
+
+// StreamDomains lists the mirrors VOE has been seen bouncing embeds to, which
+// also serve the streams; new mirrors have to be added here
+func (v *VOE) StreamDomains() []string {
+	return VOEMirrors
+}
+
+// VOEMirrors lists the rotating domains VOE has been seen moving embeds to
+var VOEMirrors = []string{
+	"jilliandescribecompany.com", "christopheruntilpoint.com", "walterprettytheir.com",
+	"crystaltreatmenteast.com", "lauradaydo.com", "sandratableother.com", "jonathansociallike.com",
+}
+
+var (
+	// voeRedirect finds the script moving the embed to the current mirror
+	voeRedirect = regexp.MustCompile(`window\.location\.href\s*=\s*'(https?://[^']+)'`)
+	// voePayload finds the obfuscated source document of the current player
+	voePayload = regexp.MustCompile(`<script type="application/json">\s*\["([^"]+)"\]\s*</script>`)
+	// voeLegacySource finds the sources of the older player, plain or base64
+	voeLegacySource = regexp.MustCompile(`'(hls|mp4)'\s*:\s*'([^']+)'`)
+)
+
+// voeJunk lists the markers VOE scatters through its payload
+var voeJunk = []string{"@$", "^^", "~@", "%?", "*~", "!!", "#&"}
+
+// Extract resolves embedURL, which the page at referer links to
+func (v *VOE) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, v.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+	if match := voeRedirect.FindSubmatch(page); match != nil && voePayload.Find(page) == nil {
+		embedURL = string(match[1])
+		if page, err = fetch(ctx, v.Client, embedURL, map[string]string{"Referer": referer}); err != nil {
+			return Result{}, err
+		}
+	}
+
+	headers := map[string]string{"User-Agent": v.Client.UserAgent(UserAgent), "Referer": origin(embedURL) + "/"}
+	var result Result
+	add := func(file string, hls bool) {
+		quality := "default"
+		if hls {
+			quality = "auto"
+		}
+		result.Streams = append(result.Streams, Stream{URL: file, Quality: quality, HLS: hls, Headers: headers})
+	}
+
+	if match := voePayload.FindSubmatch(page); match != nil {
+		sources, err := voeDecode(string(match[1]))
+		if err != nil {
+			return Result{}, exterr.New(exterr.Parse, "error decoding VOE sources, the player may have changed: %w", err)
+		}
+		if sources.Source != "" {
+			add(sources.Source, strings.Contains(sources.Source, ".m3u8"))
+		}
+		if sources.DirectAccessURL != "" {
+			add(sources.DirectAccessURL, false)
+		}
+	} else {
+		for _, match := range voeLegacySource.FindAllSubmatch(page, -1) {
+			file := string(match[2])
+			if decoded, err := base64.StdEncoding.DecodeString(file); err == nil && strings.HasPrefix(string(decoded), "http") {
+				file = string(decoded)
+			}
+			add(file, string(match[1]) == "hls")
+		}
+	}
+
+	if len(result.Streams) == 0 {
+		return Result{}, exterr.New(exterr.Parse, "no video on the VOE page %s", embedURL)
+	}
+	return result, nil
+}
+
+// voeSources is the decoded source document of the VOE player
+type voeSources struct {
+	Source          string `json:"source"`            // HLS playlist
+	DirectAccessURL string `json:"direct_access_url"` // MP4 file, when downloads are enabled
+}
+
+// voeDecode reverses the player's obfuscation of its source document: ROT13,
+// junk markers, base64, every character shifted by 3, the string reversed,
+// then base64 again
+func voeDecode(payload string) (voeSources, error) {
+	var sources voeSources
+
+	rot13 := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return 'a' + (r-'a'+13)%26
+		case r >= 'A' && r <= 'Z':
+			return 'A' + (r-'A'+13)%26
+		}
+		return r
+	}, payload)
+	for _, junk := range voeJunk {
+		rot13 = strings.ReplaceAll(rot13, junk, "")
+	}
+
+	shifted, err := base64.StdEncoding.DecodeString(rot13)
+	if err != nil {
+		return sources, err
+	}
+	reversed := make([]byte, len(shifted))
+	for i, b := range shifted {
+		reversed[len(shifted)-1-i] = b - 3
+	}
+	document, err := base64.StdEncoding.DecodeString(string(reversed))
+	if err != nil {
+		return sources, err
+	}
+	if err := json.Unmarshal(document, &sources); err != nil {
+		return sources, fmt.Errorf("error parsing source document: %w", err)
+	}
+	return sources, nil
+}
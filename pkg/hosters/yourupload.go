@@ -0,0 +1,49 @@
+package hosters
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// YourUpload resolves YourUpload embeds, e.g.
+// https://www.yourupload.com/embed/AbCdEf, into their MP4 file
+type YourUpload struct {
+	Client *httpclient.Client
+}
+
+// Domains lists the YourUpload hosts
+func (y *YourUpload) Domains() []string {
+	return []string{"yourupload.com"}
+}
+
+// StreamDomains lists the CDN serving the files
+func (y *YourUpload) StreamDomains() []string {
+	return []string{"vidcache.net"}
+}
+
+// yourUploadFile finds the video in the player options or, failing that, the
+// page's Open Graph tags
+var yourUploadFile = []*regexp.Regexp{
+	regexp.MustCompile(`file:\s*'([^']+)'`),
+	regexp.MustCompile(`<meta property="og:video" content="([^"]+)"`),
+}
+
+// Extract resolves embedURL, which the page at referer links to
+func (y *YourUpload) Extract(ctx context.Context, embedURL, referer string) (Result, error) {
+	page, err := fetch(ctx, y.Client, embedURL, map[string]string{"Referer": referer})
+	if err != nil {
+		return Result{}, err
+	}
+
+	for _, pattern := range yourUploadFile {
+		if match := pattern.FindSubmatch(page); match != nil {
+			// The file host only serves requests coming from the player
+			headers := map[string]string{"User-Agent": y.Client.UserAgent(UserAgent), "Referer": origin(embedURL) + "/"}
+			return Result{Streams: []Stream{{URL: string(match[1]), Quality: "default", Headers: headers}}}, nil
+		}
+	}
+	return Result{}, exterr.New(exterr.Parse, "no video file on the YourUpload page %s", embedURL)
+}
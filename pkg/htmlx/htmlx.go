@@ -0,0 +1,84 @@
+// Package htmlx wraps goquery with helpers for scraping HTML pages that change
+// their markup over time: selector fallbacks, attribute lookups with defaults
+// and text normalization.
+package htmlx
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Parse reads an HTML document
+func Parse(r io.Reader) (*goquery.Document, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HTML: %v", err)
+	}
+	return doc, nil
+}
+
+// ParseString parses an HTML document held in a string
+func ParseString(s string) (*goquery.Document, error) {
+	return Parse(strings.NewReader(s))
+}
+
+// Find returns the matches of the first selector that matches anything under
+// sel, so scrapers can list the current markup first and older layouts after it.
+// The returned selection is empty when no selector matches.
+func Find(sel *goquery.Selection, selectors ...string) *goquery.Selection {
+	for _, selector := range selectors {
+		if found := sel.Find(selector); found.Length() > 0 {
+			return found
+		}
+	}
+	return sel.Find(":not(*)")
+}
+
+// First returns the first element matched by the first matching selector
+func First(sel *goquery.Selection, selectors ...string) *goquery.Selection {
+	return Find(sel, selectors...).First()
+}
+
+// Attr returns the trimmed value of an attribute of the first element in sel,
+// or def when the attribute is missing or empty
+func Attr(sel *goquery.Selection, name, def string) string {
+	if value, ok := sel.Attr(name); ok {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return def
+}
+
+// AttrAny returns the first non-empty attribute out of names, which covers lazy
+// loading images that move src into data-src or data-lazy-src
+func AttrAny(sel *goquery.Selection, def string, names ...string) string {
+	for _, name := range names {
+		if value := Attr(sel, name, ""); value != "" {
+			return value
+		}
+	}
+	return def
+}
+
+// Text returns the normalized text of sel
+func Text(sel *goquery.Selection) string {
+	return NormalizeText(sel.Text())
+}
+
+// TextFirst returns the normalized text of the first element matched by the
+// first matching selector, or an empty string when nothing matches
+func TextFirst(sel *goquery.Selection, selectors ...string) string {
+	return Text(First(sel, selectors...))
+}
+
+// NormalizeText decodes HTML entities, replaces non-breaking and other Unicode
+// spaces with plain spaces, collapses runs of whitespace and trims the result
+func NormalizeText(s string) string {
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, " ", " ")), " ")
+}
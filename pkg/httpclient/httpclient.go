@@ -0,0 +1,335 @@
+// Package httpclient provides the HTTP client shared by extensions. DoRetry
+// retries transient failures with backoff, SetProxy routes requests
+// through a proxy other than the one in the environment, and
+// SetRedirectPolicy decides per host how redirect chains are followed. In
+// debug mode the client records per-host request statistics, including
+// retries and the bytes downloaded, and warns about slow requests. Requests
+// and retries are also logged to Logger as structured records. Header is
+// sent with every request, so a User-Agent or cookie the user configured
+// reaches the source, its stream hosts and the probes alike.
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/mockserver"
+)
+
+// DefaultSlowThreshold is the request duration above which a warning is printed in debug mode
+const DefaultSlowThreshold = 3 * time.Second
+
+// Client wraps http.Client with debug instrumentation
+type Client struct {
+	HTTPClient    *http.Client
+	Debug         bool
+	SlowThreshold time.Duration
+	Output        io.Writer    // Destination for debug output, defaults to stderr
+	Logger        *slog.Logger // Structured log of requests, discarding everything by default
+
+	// Header is set on every request, replacing headers of the same name the
+	// extension set, e.g. the user's -user-agent and -header flags
+	Header http.Header
+
+	limiter      Limiter
+	limitedHosts map[string]bool
+
+	mu        sync.Mutex
+	stats     map[string]*hostStats
+	redirects map[string]RedirectPolicy // Keyed by host or "*.example.com" pattern
+}
+
+// Limiter throttles requests; *ratelimit.Limiter satisfies it
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// hostStats holds the collected statistics for a single host
+type hostStats struct {
+	requests  int
+	errors    int
+	retries   int
+	throttled time.Duration // Time spent waiting for the rate limiter
+	cacheHits int
+	bytes     int64 // Response body bytes read, after transparent decompression
+	latencies []time.Duration
+}
+
+// New creates a new shared client. Debug mode is enabled when PAIR_DEBUG is set.
+func New() *Client {
+	c := &Client{
+		HTTPClient:    &http.Client{},
+		Debug:         os.Getenv("PAIR_DEBUG") != "",
+		SlowThreshold: DefaultSlowThreshold,
+		Output:        os.Stderr,
+		Logger:        slog.New(slog.DiscardHandler),
+		Header:        http.Header{},
+		stats:         map[string]*hostStats{},
+	}
+	c.HTTPClient.CheckRedirect = c.checkRedirect
+	return c
+}
+
+// Limit throttles every request to one of hosts through limiter, typically the
+// rate the source declares. Requests to other hosts, such as stream CDNs, are
+// not throttled.
+func (c *Client) Limit(hosts []string, limiter Limiter) {
+	c.limiter = limiter
+	c.limitedHosts = map[string]bool{}
+	for _, host := range hosts {
+		c.limitedHosts[strings.ToLower(host)] = true
+	}
+}
+
+// UserAgent returns the User-Agent requests are sent with: the one in Header,
+// or fallback, the extension's own. Stream headers handed to players use it so
+// they identify as the client that resolved the stream.
+func (c *Client) UserAgent(fallback string) string {
+	if agent := c.Header.Get("User-Agent"); agent != "" {
+		return agent
+	}
+	return fallback
+}
+
+// Do sends an HTTP request with Header set, waiting for the rate limiter first
+// when the host is limited, and records its latency when debug mode is enabled
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if len(c.Header) > 0 && req.Header == nil {
+		req.Header = http.Header{}
+	}
+	for key, values := range c.Header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+
+	if c.limiter != nil && c.limitedHosts[strings.ToLower(req.URL.Hostname())] {
+		waitStart := time.Now()
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		if waited := time.Since(waitStart); c.Debug && waited > time.Millisecond {
+			c.mu.Lock()
+			c.hostStats(req.URL.Host).throttled += waited
+			c.mu.Unlock()
+		}
+	}
+
+	start := time.Now()
+	resp, err := c.HTTPClient.Do(req)
+	elapsed := time.Since(start)
+	c.logRequest(req, resp, err, elapsed)
+
+	if !c.Debug {
+		return resp, err
+	}
+
+	c.mu.Lock()
+	stats := c.hostStats(req.URL.Host)
+	stats.requests++
+	stats.latencies = append(stats.latencies, elapsed)
+	if err != nil {
+		stats.errors++
+	}
+	c.mu.Unlock()
+
+	if resp != nil && resp.Body != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, client: c, host: req.URL.Host}
+	}
+
+	if resp != nil && resp.Request != nil && resp.Request.URL.String() != req.URL.String() {
+		fmt.Fprintf(c.Output, "[debug] redirected: %s %s resolved to %s\n", req.Method, req.URL.Redacted(), resp.Request.URL.Redacted())
+	}
+
+	if elapsed > c.SlowThreshold {
+		fmt.Fprintf(c.Output, "[debug] slow request: %s %s took %s\n", req.Method, req.URL.Redacted(), elapsed.Round(time.Millisecond))
+	}
+
+	return resp, err
+}
+
+// logRequest logs the outcome of a request: failures as warnings, everything
+// else at debug level
+func (c *Client) logRequest(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.Redacted()),
+		slog.Int64("duration_ms", elapsed.Milliseconds()),
+	}
+	level := slog.LevelDebug
+	switch {
+	case err != nil:
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	default:
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			level = slog.LevelWarn
+		}
+		if resp.Request != nil && resp.Request.URL.String() != req.URL.String() {
+			attrs = append(attrs, slog.String("final_url", resp.Request.URL.Redacted()))
+		}
+	}
+	c.Logger.LogAttrs(req.Context(), level, "request", attrs...)
+}
+
+// countingBody adds the bytes read from a response body to its host's statistics
+type countingBody struct {
+	io.ReadCloser
+	client *Client
+	host   string
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.client.mu.Lock()
+		b.client.hostStats(b.host).bytes += int64(n)
+		b.client.mu.Unlock()
+	}
+	return n, err
+}
+
+// BandwidthBytes returns the response body bytes read so far, i.e. the
+// bandwidth used by the current command. It is always 0 unless debug mode is enabled.
+func (c *Client) BandwidthBytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int64
+	for _, stats := range c.stats {
+		total += stats.bytes
+	}
+	return total
+}
+
+// RecordCacheHit records a request to host that was served from a cache
+func (c *Client) RecordCacheHit(host string) {
+	if !c.Debug {
+		return
+	}
+
+	c.mu.Lock()
+	c.hostStats(host).cacheHits++
+	c.mu.Unlock()
+}
+
+// PrintSummary writes per-host request counts, retries, cache hits, bytes downloaded and
+// latencies, followed by the command's total bandwidthBytes. It is a no-op
+// unless debug mode is enabled.
+func (c *Client) PrintSummary() {
+	if !c.Debug {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.stats) == 0 {
+		return
+	}
+
+	hosts := make([]string, 0, len(c.stats))
+	for host := range c.stats {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var total int64
+	fmt.Fprintf(c.Output, "[debug] request summary:\n")
+	for _, host := range hosts {
+		stats := c.stats[host]
+		total += stats.bytes
+		fmt.Fprintf(c.Output, "[debug]   %s: %d requests, %d errors, %d retries, %s rate limited, %d cache hits, %s downloaded, p50 %s, p95 %s, max %s\n",
+			host, stats.requests, stats.errors, stats.retries, stats.throttled.Round(time.Millisecond), stats.cacheHits, FormatBytes(stats.bytes),
+			percentile(stats.latencies, 50), percentile(stats.latencies, 95), percentile(stats.latencies, 100))
+	}
+	fmt.Fprintf(c.Output, "[debug] bandwidthBytes: %d (%s)\n", total, FormatBytes(total))
+}
+
+// FormatBytes renders a byte count with a binary unit, e.g. "1.5 MiB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// hostStats returns the statistics for host, creating them if needed. The caller must hold c.mu.
+func (c *Client) hostStats(host string) *hostStats {
+	stats, ok := c.stats[host]
+	if !ok {
+		stats = &hostStats{}
+		c.stats[host] = stats
+	}
+	return stats
+}
+
+// percentile returns the p-th percentile of the given durations using the nearest-rank method
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(time.Millisecond)
+}
+
+// UseMock starts a mock server for the fixtures in fixtureDir and redirects
+// every request made through the client to it. The returned function stops the server.
+func (c *Client) UseMock(fixtureDir string) (func() error, error) {
+	server, err := mockserver.Start(fixtureDir)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		server.Close()
+		return nil, fmt.Errorf("error parsing mock server URL: %v", err)
+	}
+
+	c.HTTPClient.Transport = &redirectTransport{target: target, next: http.DefaultTransport}
+	return server.Close, nil
+}
+
+// redirectTransport rewrites every request to the target server, preserving
+// the original host in a header so fixtures can be matched per upstream.
+// Responses carry the original request, so callers reading the final URL of
+// a redirect chain see the upstream URL as they would online.
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redirected := req.Clone(req.Context())
+	redirected.Header.Set(mockserver.OriginalHostHeader, req.URL.Host)
+	redirected.URL.Scheme = t.target.Scheme
+	redirected.URL.Host = t.target.Host
+	redirected.Host = t.target.Host
+	resp, err := t.next.RoundTrip(redirected)
+	if resp != nil {
+		resp.Request = req
+	}
+	return resp, err
+}
@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDoSetsHeader(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	c := New()
+	c.Header.Set("User-Agent", "custom/1.0")
+	c.Header.Add("X-Extra", "a")
+	c.Header.Add("X-Extra", "b")
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "extension/1.0")
+	req.Header.Set("Referer", "https://example.com/")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+
+	if ua := got.Get("User-Agent"); ua != "custom/1.0" {
+		t.Errorf("User-Agent = %q, want the client's %q", ua, "custom/1.0")
+	}
+	if extra := got.Values("X-Extra"); !reflect.DeepEqual(extra, []string{"a", "b"}) {
+		t.Errorf("X-Extra = %q, want %q", extra, []string{"a", "b"})
+	}
+	if ref := got.Get("Referer"); ref != "https://example.com/" {
+		t.Errorf("Referer = %q, want the extension's header kept", ref)
+	}
+
+	// The request gets copies, so changing it leaves the client's headers alone
+	req.Header["X-Extra"][0] = "changed"
+	if extra := c.Header.Values("X-Extra"); !reflect.DeepEqual(extra, []string{"a", "b"}) {
+		t.Errorf("client X-Extra = %q after changing the request, want %q", extra, []string{"a", "b"})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	c := New()
+	if got := c.UserAgent("fallback/1.0"); got != "fallback/1.0" {
+		t.Errorf("UserAgent() = %q without a header, want the fallback", got)
+	}
+	c.Header.Set("User-Agent", "custom/1.0")
+	if got := c.UserAgent("fallback/1.0"); got != "custom/1.0" {
+		t.Errorf("UserAgent() = %q, want %q", got, "custom/1.0")
+	}
+}
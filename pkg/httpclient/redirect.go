@@ -0,0 +1,188 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RedirectPolicy controls how redirects are followed for requests to a host
+type RedirectPolicy struct {
+	MaxHops int // Redirects followed before giving up; 0 uses DefaultMaxHops
+
+	// PreserveQuery lists query parameters carried over to a redirect target
+	// that drops them, e.g. signed tokens; "*" carries over every parameter
+	PreserveQuery []string
+
+	// Interstitials lists hosts of ad or landing pages redirects bounce
+	// through ("*.example.com" covers subdomains). A redirect to one is
+	// followed straight to the URL it carries in its query string, and fails
+	// when there is none.
+	Interstitials []string
+}
+
+// DefaultMaxHops matches the redirect limit of net/http
+const DefaultMaxHops = 10
+
+// DefaultRedirectPolicy applies to hosts without a policy of their own
+var DefaultRedirectPolicy = RedirectPolicy{MaxHops: DefaultMaxHops}
+
+// SetRedirectPolicy sets the policy for redirects of requests sent to host,
+// which may be a "*.example.com" pattern. The policy is chosen by the host of
+// the original request, so it covers the whole chain.
+func (c *Client) SetRedirectPolicy(host string, policy RedirectPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.redirects == nil {
+		c.redirects = map[string]RedirectPolicy{}
+	}
+	c.redirects[strings.ToLower(host)] = policy
+}
+
+// redirectPolicy returns the policy for requests to host. The caller must hold c.mu.
+func (c *Client) redirectPolicy(host string) RedirectPolicy {
+	host = strings.ToLower(host)
+	if policy, ok := c.redirects[host]; ok {
+		return policy
+	}
+	for pattern, policy := range c.redirects {
+		if matchHost(pattern, host) {
+			return policy
+		}
+	}
+	return DefaultRedirectPolicy
+}
+
+// checkRedirect is the CheckRedirect hook of the client's HTTP client. It
+// enforces the hop limit, restores dropped query parameters and skips
+// interstitial pages before req, the next request of the chain, is sent.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	origin := via[0].URL.Hostname()
+	c.mu.Lock()
+	policy := c.redirectPolicy(origin)
+	c.mu.Unlock()
+
+	maxHops := policy.MaxHops
+	if maxHops <= 0 {
+		maxHops = DefaultMaxHops
+	}
+	if len(via) > maxHops {
+		return fmt.Errorf("stopped after %d redirects", maxHops)
+	}
+
+	for _, pattern := range policy.Interstitials {
+		if !matchHost(pattern, req.URL.Hostname()) {
+			continue
+		}
+		target := embeddedURL(req.URL)
+		if target == nil {
+			return fmt.Errorf("redirected to interstitial page %s", req.URL.Redacted())
+		}
+		if target.Host != req.URL.Host {
+			// net/http decided which credentials to forward for the interstitial's host
+			for _, header := range []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"} {
+				req.Header.Del(header)
+			}
+		}
+		req.URL = target
+		req.Host = ""
+		break
+	}
+
+	preserveQuery(req.URL, via, policy.PreserveQuery)
+	return nil
+}
+
+// preserveQuery copies the listed parameters missing from target from the
+// latest request of the chain that had them
+func preserveQuery(target *url.URL, via []*http.Request, params []string) {
+	if len(params) == 0 {
+		return
+	}
+	query := target.Query()
+	changed := false
+	for i := len(via) - 1; i >= 0; i-- {
+		for name, values := range via[i].URL.Query() {
+			if query.Has(name) || !(containsFold(params, "*") || containsFold(params, name)) {
+				continue
+			}
+			query[name] = values
+			changed = true
+		}
+	}
+	if changed {
+		target.RawQuery = query.Encode()
+	}
+}
+
+// embeddedURL returns the absolute http(s) URL an interstitial page carries
+// in its query string, e.g. ?url=https://...
+func embeddedURL(page *url.URL) *url.URL {
+	for _, values := range page.Query() {
+		for _, value := range values {
+			target, err := url.Parse(value)
+			if err == nil && (target.Scheme == "https" || target.Scheme == "http") && target.Host != "" {
+				return target
+			}
+		}
+	}
+	return nil
+}
+
+// NormalizeURL cleans up a URL taken from a page or API response: scheme-less
+// URLs ("//host/path" or "host/path") get https, the host is lowercased,
+// default ports and the fragment are dropped
+func NormalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	} else if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %v", raw, err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid URL %q: no host", raw)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := u.Hostname(), u.Port()
+	if (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		port = ""
+	}
+	host = strings.ToLower(host)
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String(), nil
+}
+
+// matchHost reports whether host matches pattern, where "*.example.com"
+// covers example.com and its subdomains
+func matchHost(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+	return pattern == host
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
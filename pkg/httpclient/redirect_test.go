@@ -0,0 +1,196 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "https://Example.COM/Path?q=1#frag", want: "https://example.com/Path?q=1"},
+		{raw: "//cdn.example.com/v.mp4", want: "https://cdn.example.com/v.mp4"},
+		{raw: "cdn.example.com/v.mp4", want: "https://cdn.example.com/v.mp4"},
+		{raw: " HTTP://example.com:80/a ", want: "http://example.com/a"},
+		{raw: "https://example.com:443/a", want: "https://example.com/a"},
+		{raw: "https://example.com:8443/a", want: "https://example.com:8443/a"},
+		{raw: "http://[::1]:80/a", want: "http://[::1]/a"},
+		{raw: "http://[::1]:8080/a", want: "http://[::1]:8080/a"},
+		{raw: "https:///path", wantErr: true},
+		{raw: "https://exa mple.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := NormalizeURL(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizeURL(%q) = %q, want an error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeURL(%q) returned error: %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{pattern: "example.com", host: "example.com", want: true},
+		{pattern: "example.com", host: "EXAMPLE.com", want: true},
+		{pattern: "example.com", host: "www.example.com", want: false},
+		{pattern: "*.example.com", host: "example.com", want: true},
+		{pattern: "*.example.com", host: "a.b.example.com", want: true},
+		{pattern: "*.example.com", host: "badexample.com", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := matchHost(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("matchHost(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestEmbeddedURL(t *testing.T) {
+	tests := []struct {
+		page string
+		want string
+	}{
+		{page: "https://ads.example/go?url=https%3A%2F%2Fcdn.example%2Fv.mp4", want: "https://cdn.example/v.mp4"},
+		{page: "https://ads.example/go?id=1&to=http://cdn.example/a", want: "http://cdn.example/a"},
+		{page: "https://ads.example/go?next=/relative", want: ""},
+		{page: "https://ads.example/go?u=javascript:alert(1)", want: ""},
+		{page: "https://ads.example/go", want: ""},
+	}
+
+	for _, tt := range tests {
+		page, _ := url.Parse(tt.page)
+		got := ""
+		if target := embeddedURL(page); target != nil {
+			got = target.String()
+		}
+		if got != tt.want {
+			t.Errorf("embeddedURL(%q) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
+
+// redirectServer redirects /hop/N to /hop/N-1 and answers /hop/0 with its
+// query string; /drop redirects to /hop/0 without the query
+func redirectServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/hop/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+			if n == 0 {
+				fmt.Fprint(w, r.URL.RawQuery)
+				return
+			}
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d?%s", n-1, r.URL.RawQuery), http.StatusFound)
+		case r.URL.Path == "/drop":
+			http.Redirect(w, r, "/hop/0?keep=1", http.StatusFound)
+		case r.URL.Path == "/ad":
+			// The port differs from the real target's, so the interstitial
+			// page is only reachable when the client fails to skip it
+			http.Redirect(w, r, "http://localhost"+strings.TrimPrefix(r.Host, "127.0.0.1")+"/landing?to=http://"+r.Host+"/hop/0?ok=1", http.StatusFound)
+		case r.URL.Path == "/landing":
+			http.Error(w, "interstitial", http.StatusTeapot)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	server := redirectServer(t)
+
+	tests := []struct {
+		name     string
+		policy   *RedirectPolicy
+		path     string
+		want     string
+		wantErr  bool
+		wantCode int
+	}{
+		{name: "default hops", path: "/hop/10", want: ""},
+		{name: "default hop limit", path: "/hop/11", wantErr: true},
+		{name: "custom hop limit", policy: &RedirectPolicy{MaxHops: 2}, path: "/hop/3", wantErr: true},
+		{name: "within custom limit", policy: &RedirectPolicy{MaxHops: 2}, path: "/hop/2?a=1", want: "a=1"},
+		{name: "dropped query lost", path: "/drop?token=abc", want: "keep=1"},
+		{name: "dropped query preserved", policy: &RedirectPolicy{PreserveQuery: []string{"token"}}, path: "/drop?token=abc&other=x", want: "keep=1&token=abc"},
+		{name: "every parameter preserved", policy: &RedirectPolicy{PreserveQuery: []string{"*"}}, path: "/drop?token=abc&other=x", want: "keep=1&other=x&token=abc"},
+		{name: "interstitial followed", path: "/ad", wantCode: http.StatusTeapot},
+		{name: "interstitial skipped", policy: &RedirectPolicy{Interstitials: []string{"localhost"}}, path: "/ad", want: "ok=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if tt.policy != nil {
+				c.SetRedirectPolicy("127.0.0.1", *tt.policy)
+			}
+
+			req, _ := http.NewRequest("GET", server.URL+tt.path, nil)
+			resp, err := c.Do(req)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("Do(%s) succeeded, want a redirect error", tt.path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Do(%s) returned error: %v", tt.path, err)
+			}
+			defer resp.Body.Close()
+
+			wantCode := tt.wantCode
+			if wantCode == 0 {
+				wantCode = http.StatusOK
+			}
+			if resp.StatusCode != wantCode {
+				t.Fatalf("Do(%s) status = %d, want %d", tt.path, resp.StatusCode, wantCode)
+			}
+			if wantCode == http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				if got := string(body); got != tt.want {
+					t.Errorf("Do(%s) reached query %q, want %q", tt.path, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInterstitialWithoutTarget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://ads.invalid/landing", http.StatusFound)
+	}))
+	defer server.Close()
+
+	c := New()
+	c.SetRedirectPolicy("*.0.0.1", RedirectPolicy{Interstitials: []string{"*.invalid"}})
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	if resp, err := c.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("Do() followed an interstitial without a target URL, want an error")
+	}
+}
@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how DoRetry retries transient failures
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt; 0 disables retrying
+	BaseDelay  time.Duration // Delay before the first retry, doubled for each further retry
+	MaxDelay   time.Duration // Upper bound for a single delay, including Retry-After
+}
+
+// DefaultRetryPolicy retries twice, after roughly 0.5s and 1s
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   5 * time.Second,
+}
+
+// retryableStatus lists the response statuses worth retrying: rate limiting
+// and gateway errors that usually clear up within seconds
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// IsRetryableStatus reports whether a response status is a transient failure
+func IsRetryableStatus(code int) bool {
+	return retryableStatus[code]
+}
+
+// DoRetry sends req like Do, retrying network errors and retryable statuses
+// with exponential backoff and jitter. A Retry-After header on the response
+// replaces the computed delay, capped at MaxDelay. Requests with a body are
+// only retried when it can be replayed through GetBody. When every attempt
+// fails, the last response or error is returned.
+func (c *Client) DoRetry(req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.Do(req)
+
+		retryable := (err != nil && isTransientError(err)) || (err == nil && IsRetryableStatus(resp.StatusCode))
+		if !retryable || attempt >= policy.MaxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay := policy.delay(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(retryAfter, policy.MaxDelay)
+			}
+			resp.Body.Close()
+		}
+
+		c.Logger.LogAttrs(req.Context(), slog.LevelInfo, "retry",
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.Redacted()),
+			slog.String("reason", reason),
+			slog.Int("attempt", attempt+1),
+			slog.Int("max_retries", policy.MaxRetries),
+			slog.Int64("delay_ms", delay.Milliseconds()))
+		if c.Debug {
+			c.mu.Lock()
+			c.hostStats(req.URL.Host).retries++
+			c.mu.Unlock()
+			fmt.Fprintf(c.Output, "[debug] retrying %s %s after %s (retry %d/%d in %s)\n",
+				req.Method, req.URL.Redacted(), reason, attempt+1, policy.MaxRetries, delay.Round(time.Millisecond))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// delay returns the backoff before retry number attempt+1: BaseDelay doubled
+// per attempt, capped at MaxDelay, with half of it randomised so that clients
+// failing together do not retry in lockstep
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// isTransientError reports whether a request error is a network failure that
+// may succeed when retried, as opposed to e.g. an invalid URL or a cancelled request
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// A host that does not exist will not exist a second later either
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
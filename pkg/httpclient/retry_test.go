@@ -0,0 +1,210 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		min, max time.Duration
+	}{
+		{name: "first retry", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, attempt: 0, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "doubles", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, attempt: 2, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "capped", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, attempt: 10, min: 500 * time.Millisecond, max: time.Second},
+		{name: "shift overflow capped", policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 2 * time.Second}, attempt: 70, min: time.Second, max: 2 * time.Second},
+		{name: "no cap", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond}, attempt: 3, min: 400 * time.Millisecond, max: 800 * time.Millisecond},
+		{name: "zero", policy: RetryPolicy{}, attempt: 0, min: 0, max: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				if got := tt.policy.delay(tt.attempt); got < tt.min || got > tt.max {
+					t.Fatalf("delay(%d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "0", want: 0, wantOK: true},
+		{value: "120", want: 2 * time.Minute, wantOK: true},
+		{value: "-5", wantOK: false},
+		{value: "soon", wantOK: false},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	// A date in the future yields the time left until then
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(future); !ok || got < 58*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %v, %v, want about an hour", future, got, ok)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "timeout", err: fmt.Errorf("request: %w", &net.DNSError{Err: "timeout", IsTimeout: true}), want: true},
+		{name: "unknown host", err: &net.DNSError{Err: "no such host", IsNotFound: true}, want: false},
+		{name: "unexpected eof", err: fmt.Errorf("read: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "eof", err: io.EOF, want: true},
+		{name: "cancelled", err: fmt.Errorf("request: %w", context.Canceled), want: false},
+		{name: "other", err: errors.New("unsupported protocol scheme"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientError(tt.err); got != tt.want {
+				t.Errorf("isTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRetry(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	tests := []struct {
+		name         string
+		statuses     []int // Status per attempt; the last one repeats
+		method       string
+		body         string
+		noGetBody    bool
+		wantStatus   int
+		wantAttempts int32
+	}{
+		{name: "success", statuses: []int{200}, wantStatus: 200, wantAttempts: 1},
+		{name: "recovers", statuses: []int{503, 502, 200}, wantStatus: 200, wantAttempts: 3},
+		{name: "gives up", statuses: []int{429}, wantStatus: 429, wantAttempts: 3},
+		{name: "not retryable", statuses: []int{404, 200}, wantStatus: 404, wantAttempts: 1},
+		{name: "replays body", statuses: []int{504, 200}, method: "POST", body: "payload", wantStatus: 200, wantAttempts: 2},
+		{name: "body that cannot be replayed", statuses: []int{503, 200}, method: "POST", body: "payload", noGetBody: true, wantStatus: 503, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1)) - 1
+				if body, _ := io.ReadAll(r.Body); string(body) != tt.body {
+					t.Errorf("attempt %d sent body %q, want %q", n+1, body, tt.body)
+				}
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses)-1)])
+			}))
+			defer server.Close()
+
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(method, server.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.noGetBody {
+				req.GetBody = nil
+			}
+
+			resp, err := New().DoRetry(req, policy)
+			if err != nil {
+				t.Fatalf("DoRetry() returned error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("DoRetry() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("DoRetry() made %d attempts, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestDoRetryHonorsRetryAfter(t *testing.T) {
+	var attempts atomic.Int32
+	var first time.Time
+	var gap time.Duration
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		gap = time.Since(first)
+	}))
+	defer server.Close()
+
+	// Retry-After replaces the tiny backoff but is capped at MaxDelay
+	policy := RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: 200 * time.Millisecond}
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := New().DoRetry(req, policy)
+	if err != nil {
+		t.Fatalf("DoRetry() returned error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || attempts.Load() != 2 {
+		t.Fatalf("DoRetry() = %d after %d attempts, want 200 after 2", resp.StatusCode, attempts.Load())
+	}
+	if gap < 150*time.Millisecond || gap > 900*time.Millisecond {
+		t.Errorf("retry came %v after the 429, want about MaxDelay (200ms)", gap)
+	}
+}
+
+func TestDoRetryStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+	start := time.Now()
+	_, err := New().DoRetry(req, RetryPolicy{MaxRetries: 5, BaseDelay: time.Minute, MaxDelay: time.Minute})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DoRetry() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("DoRetry() waited %v despite the cancelled context", elapsed)
+	}
+}
@@ -0,0 +1,143 @@
+// Package keystore persists an extension's account credentials and session
+// tokens for the current user.
+//
+// Entries live in credentials.json inside the extension's data directory (see
+// extconfig.DataDir), readable only by the user.
+package keystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Store holds key/value entries backed by a JSON file
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+// Path returns the default keystore file path for an extension package
+func Path(pkg string) (string, error) {
+	dir, err := extconfig.DataDir(pkg)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials.json"), nil
+}
+
+// Open loads the keystore of an extension package from its default path
+func Open(pkg string) (*Store, error) {
+	path, err := Path(pkg)
+	if err != nil {
+		return nil, err
+	}
+	return Load(path)
+}
+
+// Load reads the keystore at path. A missing file is an empty keystore.
+func Load(path string) (*Store, error) {
+	store := &Store{path: path, entries: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading keystore: %v", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("error parsing keystore %s: %v", path, err)
+	}
+	return store, nil
+}
+
+// Get returns the value stored under key
+func (s *Store) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.entries[key]
+	return value, ok
+}
+
+// Keys returns the stored keys in sorted order
+func (s *Store) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Set stores value under key and saves the keystore
+func (s *Store) Set(key, value string) error {
+	s.mu.Lock()
+	s.entries[key] = value
+	s.mu.Unlock()
+	return s.save()
+}
+
+// SetAll stores every entry of values and saves the keystore once
+func (s *Store) SetAll(values map[string]string) error {
+	s.mu.Lock()
+	for key, value := range values {
+		s.entries[key] = value
+	}
+	s.mu.Unlock()
+	return s.save()
+}
+
+// Delete removes key and saves the keystore
+func (s *Store) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return s.save()
+}
+
+// Clear removes every entry along with the keystore file
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	s.entries = map[string]string{}
+	s.mu.Unlock()
+
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing keystore: %v", err)
+	}
+	return nil
+}
+
+// save writes the entries to the keystore file, readable only by the user
+func (s *Store) save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding keystore: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("error creating data directory: %v", err)
+	}
+
+	// Write to a temp file first so a crash never leaves half-written credentials
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("error writing keystore: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing keystore: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package mockserver serves recorded API responses from a fixture directory so
+// extensions can be run fully offline for manual testing and demos.
+//
+// A fixture directory contains a routes.json manifest and the response bodies
+// it references:
+//
+//	[
+//	  {"host": "api.allanime.day", "path": "/api", "contains": ["shows("], "file": "search.json"},
+//	  {"path": "/apivtwo/clock.json", "file": "clock.json"}
+//	]
+//
+// Routes are matched in order. Host and method are optional, and every string
+// in contains must appear in the decoded query string or request body.
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OriginalHostHeader carries the host a redirected request was originally sent to
+const OriginalHostHeader = "X-Mock-Original-Host"
+
+// Route maps a request to a fixture file
+type Route struct {
+	Method   string            `json:"method,omitempty"`   // HTTP method, any when empty
+	Host     string            `json:"host,omitempty"`     // Original request host, any when empty
+	Path     string            `json:"path"`               // Request path
+	Contains []string          `json:"contains,omitempty"` // Substrings required in the query or body
+	Status   int               `json:"status,omitempty"`   // Response status, defaults to 200
+	Headers  map[string]string `json:"headers,omitempty"`  // Extra response headers
+	File     string            `json:"file"`               // Response body, relative to the fixture directory
+}
+
+// Server serves fixtures over HTTP on a local port
+type Server struct {
+	URL    string // Base URL of the server, e.g. http://127.0.0.1:41234
+	dir    string
+	routes []Route
+	server *http.Server
+}
+
+// LoadRoutes reads the routes.json manifest from a fixture directory
+func LoadRoutes(dir string) ([]Route, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "routes.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading fixture manifest: %v", err)
+	}
+
+	var routes []Route
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("error parsing fixture manifest: %v", err)
+	}
+	return routes, nil
+}
+
+// Start serves the fixtures in dir on a random local port
+func Start(dir string) (*Server, error) {
+	routes, err := LoadRoutes(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error starting mock server: %v", err)
+	}
+
+	s := &Server{
+		URL:    "http://" + listener.Addr().String(),
+		dir:    dir,
+		routes: routes,
+	}
+	s.server = &http.Server{Handler: s}
+
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// ServeHTTP responds with the fixture of the first matching route
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	query, _ := url.QueryUnescape(r.URL.RawQuery)
+	payload := query + "\n" + string(body)
+
+	host := r.Header.Get(OriginalHostHeader)
+	if host == "" {
+		host = r.Host
+	}
+
+	for _, route := range s.routes {
+		if !route.matches(r.Method, host, r.URL.Path, payload) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, route.File))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error reading fixture %s: %v", route.File, err), http.StatusInternalServerError)
+			return
+		}
+
+		for key, value := range route.Headers {
+			w.Header().Set(key, value)
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		w.Write(data)
+		return
+	}
+
+	http.Error(w, fmt.Sprintf("no fixture for %s %s%s", r.Method, host, r.URL.Path), http.StatusNotFound)
+}
+
+func (route Route) matches(method, host, path, payload string) bool {
+	if route.Method != "" && !strings.EqualFold(route.Method, method) {
+		return false
+	}
+	if route.Host != "" && !strings.EqualFold(route.Host, host) {
+		return false
+	}
+	if route.Path != path {
+		return false
+	}
+	for _, needle := range route.Contains {
+		if !strings.Contains(payload, needle) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+// Package pagination implements the optional --page/--limit protocol for list
+// commands whose full result is too long to return at once, such as the
+// episode list of a long-running show. Given --limit, a command returns one
+// page of the list in an object together with Info; without it, the command
+// returns the plain list as before, so frontends that do not paginate are
+// unaffected. Clients paging by position use --offset instead of --page.
+package pagination
+
+import "fmt"
+
+// Info describes one page of a list
+type Info struct {
+	Page        int  `json:"page"`        // 1-based page number
+	Offset      int  `json:"offset"`      // 0-based index of the first item on the page
+	Limit       int  `json:"limit"`       // Maximum number of items per page
+	Total       int  `json:"total"`       // Number of items across all pages
+	HasNextPage bool `json:"hasNextPage"` // Whether page+1 has items, as in scraper.AnimePage
+}
+
+// Slice returns the items on page (1-based) when the list is split into pages
+// of limit items. A page past the end is empty rather than an error, so
+// frontends can stop on an empty page as well as on HasNextPage.
+func Slice[T any](items []T, page, limit int) ([]T, Info, error) {
+	if page < 1 {
+		return nil, Info{}, fmt.Errorf("invalid page %d: pages start at 1", page)
+	}
+	if limit < 1 {
+		return nil, Info{}, fmt.Errorf("invalid limit %d: must be at least 1", limit)
+	}
+	return SliceOffset(items, (page-1)*limit, limit)
+}
+
+// SliceOffset returns up to limit items starting at the 0-based offset. Page
+// in the returned Info is the page the first item falls on. An offset past
+// the end gives an empty page, as in Slice.
+func SliceOffset[T any](items []T, offset, limit int) ([]T, Info, error) {
+	if offset < 0 {
+		return nil, Info{}, fmt.Errorf("invalid offset %d: must not be negative", offset)
+	}
+	if limit < 1 {
+		return nil, Info{}, fmt.Errorf("invalid limit %d: must be at least 1", limit)
+	}
+
+	start := min(offset, len(items))
+	end := min(start+limit, len(items))
+	pageItems := make([]T, end-start)
+	copy(pageItems, items[start:end])
+
+	return pageItems, Info{
+		Page:        offset/limit + 1,
+		Offset:      offset,
+		Limit:       limit,
+		Total:       len(items),
+		HasNextPage: end < len(items),
+	}, nil
+}
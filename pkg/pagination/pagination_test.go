@@ -0,0 +1,92 @@
+package pagination
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name      string
+		page      int
+		limit     int
+		want      []int
+		wantInfo  Info
+		wantError bool
+	}{
+		{name: "first page", page: 1, limit: 2, want: []int{1, 2}, wantInfo: Info{Page: 1, Limit: 2, Total: 5, HasNextPage: true}},
+		{name: "middle page", page: 2, limit: 2, want: []int{3, 4}, wantInfo: Info{Page: 2, Offset: 2, Limit: 2, Total: 5, HasNextPage: true}},
+		{name: "last partial page", page: 3, limit: 2, want: []int{5}, wantInfo: Info{Page: 3, Offset: 4, Limit: 2, Total: 5}},
+		{name: "exact fit", page: 1, limit: 5, want: []int{1, 2, 3, 4, 5}, wantInfo: Info{Page: 1, Limit: 5, Total: 5}},
+		{name: "past the end", page: 9, limit: 2, want: []int{}, wantInfo: Info{Page: 9, Offset: 16, Limit: 2, Total: 5}},
+		{name: "page zero", page: 0, limit: 2, wantError: true},
+		{name: "limit zero", page: 1, limit: 0, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, info, err := Slice(items, tt.page, tt.limit)
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("Slice(%d, %d) = %v, want an error", tt.page, tt.limit, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Slice(%d, %d) returned error: %v", tt.page, tt.limit, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || info != tt.wantInfo {
+				t.Errorf("Slice(%d, %d) = %v, %+v, want %v, %+v", tt.page, tt.limit, got, info, tt.want, tt.wantInfo)
+			}
+		})
+	}
+}
+
+func TestSliceOffset(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		name      string
+		offset    int
+		limit     int
+		want      []string
+		wantInfo  Info
+		wantError bool
+	}{
+		{name: "start", offset: 0, limit: 2, want: []string{"a", "b"}, wantInfo: Info{Page: 1, Offset: 0, Limit: 2, Total: 5, HasNextPage: true}},
+		{name: "unaligned", offset: 3, limit: 2, want: []string{"d", "e"}, wantInfo: Info{Page: 2, Offset: 3, Limit: 2, Total: 5}},
+		{name: "aligned", offset: 2, limit: 2, want: []string{"c", "d"}, wantInfo: Info{Page: 2, Offset: 2, Limit: 2, Total: 5, HasNextPage: true}},
+		{name: "past the end", offset: 10, limit: 3, want: []string{}, wantInfo: Info{Page: 4, Offset: 10, Limit: 3, Total: 5}},
+		{name: "negative offset", offset: -1, limit: 2, wantError: true},
+		{name: "limit zero", offset: 0, limit: 0, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, info, err := SliceOffset(items, tt.offset, tt.limit)
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("SliceOffset(%d, %d) = %v, want an error", tt.offset, tt.limit, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SliceOffset(%d, %d) returned error: %v", tt.offset, tt.limit, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || info != tt.wantInfo {
+				t.Errorf("SliceOffset(%d, %d) = %v, %+v, want %v, %+v", tt.offset, tt.limit, got, info, tt.want, tt.wantInfo)
+			}
+		})
+	}
+}
+
+func TestSliceCopies(t *testing.T) {
+	items := []int{1, 2, 3}
+	page, _, _ := Slice(items, 1, 2)
+	page[0] = 99
+	if items[0] != 1 {
+		t.Error("modifying a page changed the underlying list")
+	}
+}
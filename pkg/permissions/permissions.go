@@ -0,0 +1,49 @@
+// Package permissions describes what an extension declares it needs at
+// runtime, so the pair app can show a permissions summary before install.
+package permissions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permissions is the permission set declared in an extension's manifest
+type Permissions struct {
+	Network    []string `json:"network"`    // Hosts the extension contacts, exact or "*.example.com" for subdomains
+	Filesystem []string `json:"filesystem"` // Paths read or written, using $PAIR_CONFIG_DIR style placeholders
+	Binaries   []string `json:"binaries"`   // External programs the extension executes
+}
+
+// Summary returns a one-line, human-readable description of the permission set
+func (p Permissions) Summary() string {
+	parts := []string{}
+	if len(p.Network) > 0 {
+		parts = append(parts, fmt.Sprintf("network: %s", strings.Join(p.Network, ", ")))
+	}
+	if len(p.Filesystem) > 0 {
+		parts = append(parts, fmt.Sprintf("filesystem: %s", strings.Join(p.Filesystem, ", ")))
+	}
+	if len(p.Binaries) > 0 {
+		parts = append(parts, fmt.Sprintf("binaries: %s", strings.Join(p.Binaries, ", ")))
+	}
+	if len(parts) == 0 {
+		return "no permissions"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Domains returns the network entries covering each of domains and all of
+// their subdomains, e.g. "mp4upload.com" and "*.mp4upload.com"
+func Domains(domains ...string) []string {
+	entries := []string{}
+	seen := map[string]bool{}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "*."))
+		if domain == "" || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		entries = append(entries, domain, "*."+domain)
+	}
+	return entries
+}
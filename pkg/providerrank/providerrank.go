@@ -0,0 +1,386 @@
+// Package providerrank orders stream URLs by provider, combining a static
+// preference list with success rates and latencies learned from the stream
+// requests an extension makes anyway, e.g. for HLS playlists. Providers
+// without an outcome from the last StaleAfter get an availability probe. What
+// it learns is persisted per user so the ranking improves across runs.
+//
+// Each extension keeps its own stats and only learns from its own requests;
+// a host one extension ranks low is still tried first by another. The
+// extensions that resolve embeds through pkg/hosters do not rank them.
+//
+// Stats live at $PAIR_DATA_DIR/extensions/<pkg>/providers.json, falling back
+// to $XDG_DATA_HOME/pair/extensions/<pkg>/providers.json and
+// ~/.local/share/pair/extensions/<pkg>/providers.json.
+package providerrank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Weights of the score components; each component is in [0, 1]
+const (
+	staticWeight  = 0.5
+	successWeight = 0.35
+	latencyWeight = 0.15
+)
+
+// latencyScale is the probe latency at which the latency component halves
+const latencyScale = time.Second
+
+// ProbeTimeout bounds a single availability probe
+const ProbeTimeout = 5 * time.Second
+
+// StaleAfter is how long a provider's last outcome stays current. ProbeStale
+// leaves providers with a newer one alone, so most lookups send no probes.
+const StaleAfter = 6 * time.Hour
+
+// Doer sends HTTP requests; *http.Client and *httpclient.Client satisfy it
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Record holds what has been learned about one provider
+type Record struct {
+	Successes    int       `json:"successes"`
+	Failures     int       `json:"failures"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"` // Mean latency of successful requests
+	LastProbed   time.Time `json:"last_probed"`    // Time of the last outcome, from a probe or a real fetch
+}
+
+// Stats describes a provider's current ranking, as shown by `providers stats`
+type Stats struct {
+	Provider     string     `json:"provider"`
+	Preference   int        `json:"preference"` // Position in the static list starting at 1, 0 when not listed
+	Successes    int        `json:"successes"`
+	Failures     int        `json:"failures"`
+	SuccessRate  float64    `json:"success_rate"`
+	AvgLatencyMs float64    `json:"avg_latency_ms"`
+	Score        float64    `json:"score"`
+	LastProbed   *time.Time `json:"last_probed,omitempty"`
+}
+
+// Ranker scores stream URLs by provider
+type Ranker struct {
+	preferences []string
+	path        string
+
+	mu      sync.Mutex
+	records map[string]*Record
+	dirty   bool // Records changed since they were loaded or saved
+
+	saveMu sync.Mutex // Serializes writes of the stats file
+}
+
+// Path returns the default stats file path for an extension package
+func Path(pkg string) (string, error) {
+	dir, err := extconfig.DataDir(pkg)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "providers.json"), nil
+}
+
+// New creates a ranker preferring the given domains in order and loads the
+// stats stored at path. A missing file starts with no learned data; an empty
+// path keeps the ranking in memory only.
+func New(preferences []string, path string) (*Ranker, error) {
+	r := &Ranker{
+		preferences: preferences,
+		path:        path,
+		records:     map[string]*Record{},
+	}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading provider stats: %v", err)
+	}
+	if err := json.Unmarshal(data, &r.records); err != nil {
+		return nil, fmt.Errorf("error parsing provider stats %s: %v", path, err)
+	}
+	return r, nil
+}
+
+// Provider returns the provider a URL belongs to: the matching preferred
+// domain, or the URL's host otherwise
+func (r *Ranker) Provider(rawURL string) string {
+	for _, domain := range r.preferences {
+		if strings.Contains(rawURL, domain) {
+			return domain
+		}
+	}
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return rawURL
+}
+
+// preference returns the 1-based position of provider in the static list, or 0
+func (r *Ranker) preference(provider string) int {
+	for i, domain := range r.preferences {
+		if domain == provider {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Score rates a URL between 0 and 1, higher meaning more likely to play
+func (r *Ranker) Score(rawURL string) float64 {
+	provider := r.Provider(rawURL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.score(provider)
+}
+
+func (r *Ranker) score(provider string) float64 {
+	static := 0.0
+	if p := r.preference(provider); p > 0 {
+		static = float64(len(r.preferences)-p+1) / float64(len(r.preferences))
+	}
+
+	// Unprobed providers get neutral success and latency components so the
+	// static list decides until there is data
+	success, latency := 0.5, 0.5
+	if record, ok := r.records[provider]; ok {
+		// Laplace smoothing keeps a single probe from dominating
+		success = float64(record.Successes+1) / float64(record.Successes+record.Failures+2)
+		if record.Successes > 0 {
+			latency = 1 / (1 + record.AvgLatencyMs/float64(latencyScale.Milliseconds()))
+		}
+	}
+
+	return staticWeight*static + successWeight*success + latencyWeight*latency
+}
+
+// Record adds the outcome of one probe of or request for a URL
+func (r *Ranker) Record(rawURL string, ok bool, latency time.Duration) {
+	provider := r.Provider(rawURL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dirty = true
+	record, found := r.records[provider]
+	if !found {
+		record = &Record{}
+		r.records[provider] = record
+	}
+	record.LastProbed = time.Now().UTC()
+	if !ok {
+		record.Failures++
+		return
+	}
+
+	ms := float64(latency.Milliseconds())
+	record.AvgLatencyMs = (record.AvgLatencyMs*float64(record.Successes) + ms) / float64(record.Successes+1)
+	record.Successes++
+}
+
+// RecordFetch records the outcome of a real request for a stream URL, such as
+// fetching its HLS playlist, so the ranking learns without extra requests.
+// Requests cut short by ctx are not recorded.
+func (r *Ranker) RecordFetch(ctx context.Context, rawURL string, err error, latency time.Duration) {
+	if ctx.Err() != nil {
+		return
+	}
+	r.Record(rawURL, err == nil, latency)
+}
+
+// Probe checks whether a URL is reachable with a HEAD request and records the
+// outcome. Servers rejecting HEAD are retried with a one-byte ranged GET.
+// Probes cut short by ctx are not recorded, since they say nothing about the provider.
+func (r *Ranker) Probe(ctx context.Context, client Doer, rawURL string, headers map[string]string) bool {
+	start := time.Now()
+	ok := probe(ctx, client, http.MethodHead, rawURL, headers)
+	if !ok {
+		ok = probe(ctx, client, http.MethodGet, rawURL, headers)
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	r.Record(rawURL, ok, time.Since(start))
+	return ok
+}
+
+func probe(ctx context.Context, client Doer, method, rawURL string, headers map[string]string) bool {
+	ctx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return false
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < 400
+}
+
+// stale reports whether provider has no outcome from the last StaleAfter
+func (r *Ranker) stale(provider string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[provider]
+	return !ok || time.Since(record.LastProbed) > StaleAfter
+}
+
+// ProbeStale probes the URLs concurrently, one probe per provider whose stats
+// are missing or stale, and records the outcomes. Providers with a current
+// outcome cost no request.
+func (r *Ranker) ProbeStale(ctx context.Context, client Doer, urls []string, headers map[string]string) {
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		provider := r.Provider(u)
+		if seen[provider] || !r.stale(provider) {
+			continue
+		}
+		seen[provider] = true
+
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			r.Probe(ctx, client, u, headers)
+		}(u)
+	}
+	wg.Wait()
+}
+
+// Sort orders URLs by score, highest first, keeping the order of equal scores
+func (r *Ranker) Sort(urls []string) {
+	scores := make(map[string]float64, len(urls))
+	for _, u := range urls {
+		scores[u] = r.Score(u)
+	}
+	sort.SliceStable(urls, func(i, j int) bool {
+		return scores[urls[i]] > scores[urls[j]]
+	})
+}
+
+// Stats returns every preferred or probed provider, best first
+func (r *Ranker) Stats() []Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	providers := append([]string{}, r.preferences...)
+	for provider := range r.records {
+		if r.preference(provider) == 0 {
+			providers = append(providers, provider)
+		}
+	}
+
+	stats := make([]Stats, 0, len(providers))
+	for _, provider := range providers {
+		entry := Stats{
+			Provider:   provider,
+			Preference: r.preference(provider),
+			Score:      math.Round(r.score(provider)*1000) / 1000,
+		}
+		if record, ok := r.records[provider]; ok {
+			entry.Successes = record.Successes
+			entry.Failures = record.Failures
+			entry.AvgLatencyMs = record.AvgLatencyMs
+			entry.LastProbed = &record.LastProbed
+			if total := record.Successes + record.Failures; total > 0 {
+				entry.SuccessRate = float64(record.Successes) / float64(total)
+			}
+		}
+		stats = append(stats, entry)
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Score > stats[j].Score
+	})
+	return stats
+}
+
+// Save writes the learned stats to the ranker's path when anything was
+// recorded since they were loaded or last saved. It is safe to call from
+// concurrent lookups, e.g. of several episodes at once. The stats are written
+// to a temporary file first, so another run reading them, or a run killed
+// halfway, never leaves half a file.
+func (r *Ranker) Save() error {
+	if r.path == "" {
+		return nil
+	}
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(r.records, "", "  ")
+	r.dirty = false
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding provider stats: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("error creating data directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".providers-*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing provider stats: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing provider stats: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing provider stats: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing provider stats: %v", err)
+	}
+	return nil
+}
+
+// Reset forgets everything learned, keeping only the static preferences
+func (r *Ranker) Reset() error {
+	r.mu.Lock()
+	r.records = map[string]*Record{}
+	r.mu.Unlock()
+
+	if r.path == "" {
+		return nil
+	}
+	if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing provider stats: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package providerrank
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProvider(t *testing.T) {
+	r, _ := New([]string{"sharepoint.com", "wixmp.com"}, "")
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://tenant.sharepoint.com/video.mp4", want: "sharepoint.com"},
+		{url: "https://repackager.wixmp.com/a.m3u8", want: "wixmp.com"},
+		{url: "https://CDN.Example.com:8443/a.mp4", want: "cdn.example.com"},
+		{url: "not a url", want: "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := r.Provider(tt.url); got != tt.want {
+			t.Errorf("Provider(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	preferences := []string{"a.com", "b.com", "c.com", "d.com"}
+
+	tests := []struct {
+		name    string
+		url     string
+		records map[string]*Record
+		want    float64
+	}{
+		{name: "first preference unprobed", url: "https://a.com/x", want: staticWeight*1 + successWeight*0.5 + latencyWeight*0.5},
+		{name: "last preference unprobed", url: "https://d.com/x", want: staticWeight*0.25 + successWeight*0.5 + latencyWeight*0.5},
+		{name: "unlisted unprobed", url: "https://e.com/x", want: successWeight*0.5 + latencyWeight*0.5},
+		{
+			name:    "smoothed success rate",
+			url:     "https://b.com/x",
+			records: map[string]*Record{"b.com": {Successes: 3, Failures: 1, AvgLatencyMs: 1000}},
+			want:    staticWeight*0.75 + successWeight*(4.0/6) + latencyWeight*0.5,
+		},
+		{
+			name:    "fast provider",
+			url:     "https://e.com/x",
+			records: map[string]*Record{"e.com": {Successes: 1, AvgLatencyMs: 0}},
+			want:    successWeight*(2.0/3) + latencyWeight*1,
+		},
+		{
+			name:    "only failures keep neutral latency",
+			url:     "https://c.com/x",
+			records: map[string]*Record{"c.com": {Failures: 2, AvgLatencyMs: 5000}},
+			want:    staticWeight*0.5 + successWeight*0.25 + latencyWeight*0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := New(preferences, "")
+			if tt.records != nil {
+				r.records = tt.records
+			}
+			if got := r.Score(tt.url); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	r, _ := New(nil, "")
+	r.Record("https://a.com/1", true, 100*time.Millisecond)
+	r.Record("https://a.com/2", true, 300*time.Millisecond)
+	r.Record("https://a.com/3", false, time.Hour)
+
+	record := r.records["a.com"]
+	if record.Successes != 2 || record.Failures != 1 || record.AvgLatencyMs != 200 {
+		t.Errorf("record = %+v, want 2 successes, 1 failure and 200ms average latency", record)
+	}
+	if record.LastProbed.IsZero() {
+		t.Error("LastProbed was not set")
+	}
+}
+
+func TestSort(t *testing.T) {
+	r, _ := New([]string{"a.com", "b.com"}, "")
+	urls := []string{"https://x.com/1", "https://b.com/1", "https://x.com/2", "https://a.com/1"}
+	r.Sort(urls)
+
+	want := []string{"https://a.com/1", "https://b.com/1", "https://x.com/1", "https://x.com/2"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("Sort() = %v, want %v", urls, want)
+	}
+
+	// Learned reliability reorders the preferred providers
+	for i := 0; i < 20; i++ {
+		r.Record("https://a.com/1", false, 0)
+		r.Record("https://b.com/1", true, 0)
+	}
+	r.Sort(urls)
+	want = []string{"https://b.com/1", "https://a.com/1", "https://x.com/1", "https://x.com/2"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("Sort() after probes = %v, want %v", urls, want)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			if r.Header.Get("Referer") != "https://example.com/" {
+				w.WriteHeader(http.StatusForbidden)
+			}
+		case "/no-head":
+			if r.Method == http.MethodHead || r.Header.Get("Range") != "bytes=0-0" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusPartialContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	headers := map[string]string{"Referer": "https://example.com/"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/ok", want: true},
+		{path: "/no-head", want: true},
+		{path: "/missing", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			r, _ := New(nil, "")
+			if got := r.Probe(context.Background(), server.Client(), server.URL+tt.path, headers); got != tt.want {
+				t.Errorf("Probe(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+			record := r.records[r.Provider(server.URL)]
+			if record == nil || record.Successes+record.Failures != 1 {
+				t.Errorf("Probe(%s) recorded %+v, want exactly one outcome", tt.path, record)
+			}
+		})
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "providers.json")
+
+	r, err := New([]string{"a.com"}, path)
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	r.Record("https://a.com/x", true, 50*time.Millisecond)
+	r.Record("https://b.com/x", false, 0)
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	loaded, err := New([]string{"a.com"}, path)
+	if err != nil {
+		t.Fatalf("New() of saved stats returned error: %v", err)
+	}
+	stats := loaded.Stats()
+	if len(stats) != 2 || stats[0].Provider != "a.com" || stats[0].Preference != 1 || stats[0].Successes != 1 || stats[0].AvgLatencyMs != 50 {
+		t.Fatalf("Stats() after reload = %+v", stats)
+	}
+	if stats[1].Provider != "b.com" || stats[1].Failures != 1 || stats[1].SuccessRate != 0 {
+		t.Errorf("Stats()[1] after reload = %+v", stats[1])
+	}
+
+	if err := loaded.Reset(); err != nil {
+		t.Fatalf("Reset() returned error: %v", err)
+	}
+	reset, err := New(nil, path)
+	if err != nil {
+		t.Fatalf("New() after Reset returned error: %v", err)
+	}
+	if len(reset.Stats()) != 0 {
+		t.Errorf("Stats() after Reset = %+v, want none", reset.Stats())
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("PAIR_DATA_DIR", "/data")
+	got, err := Path("allanime")
+	if err != nil {
+		t.Fatalf("Path() returned error: %v", err)
+	}
+	if want := filepath.Join("/data", "extensions", "allanime", "providers.json"); got != want {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+}
+
+func TestProbeStale(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	r, _ := New(nil, "")
+	urls := []string{server.URL + "/1", server.URL + "/2"}
+	r.ProbeStale(context.Background(), server.Client(), urls, nil)
+	if requests != 1 {
+		t.Fatalf("first ProbeStale() sent %d requests, want 1 for the single provider", requests)
+	}
+
+	// A current outcome, from a probe or a real fetch, saves the probe
+	r.ProbeStale(context.Background(), server.Client(), urls, nil)
+	if requests != 1 {
+		t.Errorf("ProbeStale() with current stats sent %d more requests, want none", requests-1)
+	}
+
+	r.records[r.Provider(server.URL)].LastProbed = time.Now().Add(-StaleAfter - time.Minute)
+	r.ProbeStale(context.Background(), server.Client(), urls, nil)
+	if requests != 2 {
+		t.Errorf("ProbeStale() with stale stats sent %d more requests, want 1", requests-1)
+	}
+}
+
+func TestRecordFetch(t *testing.T) {
+	r, _ := New(nil, "")
+	r.RecordFetch(context.Background(), "https://a.com/master.m3u8", nil, 100*time.Millisecond)
+	r.RecordFetch(context.Background(), "https://a.com/master.m3u8", http.ErrHandlerTimeout, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.RecordFetch(ctx, "https://a.com/master.m3u8", ctx.Err(), 0)
+
+	record := r.records["a.com"]
+	if record.Successes != 1 || record.Failures != 1 {
+		t.Errorf("record = %+v, want 1 success and 1 failure, the cancelled fetch left out", record)
+	}
+}
+
+func TestSaveOnlyChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "providers.json")
+	r, _ := New(nil, path)
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Save() without changes wrote %s", path)
+	}
+
+	r.Record("https://a.com/x", true, 0)
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Save() after Record did not write the stats: %v", err)
+	}
+}
+
+func TestSaveReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "providers.json")
+
+	r, _ := New(nil, path)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Record(fmt.Sprintf("https://host%d.com/x", i), true, 0)
+			if err := r.Save(); err != nil {
+				t.Errorf("Save() returned error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "providers.json" {
+		t.Errorf("data directory holds %v, want only providers.json", entries)
+	}
+	loaded, err := New(nil, path)
+	if err != nil {
+		t.Fatalf("New() of saved stats returned error: %v", err)
+	}
+	if got := len(loaded.Stats()); got != 8 {
+		t.Errorf("reloaded %d providers, want 8", got)
+	}
+}
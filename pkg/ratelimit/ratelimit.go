@@ -0,0 +1,180 @@
+// Package ratelimit throttles an extension's requests to its source with a
+// token bucket, so the rate a source declares in SourceInfo.RateLimit is
+// actually respected.
+//
+// Every extension command runs as its own process, so a bucket kept in memory
+// would start full on each invocation and a frontend or tester running
+// commands back to back could still hammer the site. The bucket can therefore
+// be persisted in a small state file, shared by every invocation through a
+// lock file.
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// lockTimeout bounds how long a process waits for the state file lock before
+// going ahead without it; a lock older than this is left over from a crash
+const lockTimeout = 2 * time.Second
+
+// Limiter is a token bucket refilled at a fixed rate per minute
+type Limiter struct {
+	perSecond float64
+	burst     float64
+	path      string // State file; empty keeps the bucket in memory only
+
+	mu      sync.Mutex
+	tokens  float64
+	updated time.Time
+}
+
+// state is the persisted bucket
+type state struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// Path returns the default state file path for an extension package
+func Path(pkg string) (string, error) {
+	dir, err := extconfig.CacheDir(pkg)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ratelimit.json"), nil
+}
+
+// New creates a limiter allowing perMinute requests per minute with bursts of
+// up to burst requests, persisting its bucket at path when it is not empty
+func New(perMinute, burst int, path string) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		perSecond: float64(perMinute) / 60,
+		burst:     float64(burst),
+		path:      path,
+		tokens:    float64(burst),
+		updated:   time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done. Tokens are reserved
+// before sleeping, so concurrent callers queue up instead of all waking at once.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l.perSecond <= 0 {
+		return nil
+	}
+
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a token, letting the bucket go negative, and returns how long
+// the caller must wait for its token to become available
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path != "" {
+		if unlock, err := lock(l.path + ".lock"); err == nil {
+			defer unlock()
+		}
+		l.load()
+	}
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.updated).Seconds()*l.perSecond)
+	l.updated = now
+	l.tokens--
+
+	if l.path != "" {
+		l.save()
+	}
+
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.perSecond * float64(time.Second))
+}
+
+// load replaces the in-memory bucket with the persisted one, if any. A missing
+// or unreadable state file leaves the bucket as it is.
+func (l *Limiter) load() {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return
+	}
+	var persisted state
+	if json.Unmarshal(data, &persisted) != nil || persisted.Updated.IsZero() {
+		return
+	}
+	l.tokens = min(persisted.Tokens, l.burst)
+	l.updated = persisted.Updated
+}
+
+// save persists the bucket. Failures are ignored: the limiter keeps working in
+// memory, it just stops coordinating with other processes.
+func (l *Limiter) save() {
+	data, err := json.Marshal(state{Tokens: l.tokens, Updated: l.updated})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return
+	}
+	tmp := l.path + ".tmp"
+	if os.WriteFile(tmp, data, 0o644) != nil {
+		return
+	}
+	if os.Rename(tmp, l.path) != nil {
+		os.Remove(tmp)
+	}
+}
+
+// lock creates path exclusively, waiting for other processes to remove it.
+// It works the same on every platform, unlike flock.
+func lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > lockTimeout {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
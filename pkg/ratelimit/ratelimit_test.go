@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		burst     int
+		calls     int
+		want      []time.Duration // Wait returned by each call, rounded to 100ms
+	}{
+		{name: "burst is free", perMinute: 60, burst: 3, calls: 3, want: []time.Duration{0, 0, 0}},
+		{name: "then one per interval", perMinute: 60, burst: 2, calls: 5, want: []time.Duration{0, 0, time.Second, 2 * time.Second, 3 * time.Second}},
+		{name: "faster rate", perMinute: 600, burst: 1, calls: 3, want: []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond}},
+		{name: "burst below one", perMinute: 60, burst: 0, calls: 2, want: []time.Duration{0, time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.perMinute, tt.burst, "")
+			for i := 0; i < tt.calls; i++ {
+				if got := l.reserve().Round(100 * time.Millisecond); got != tt.want[i] {
+					t.Errorf("reserve() call %d = %v, want %v", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRefill(t *testing.T) {
+	l := New(60, 2, "")
+	l.reserve()
+	l.reserve()
+
+	// Pretend 1.5 seconds passed: 1.5 tokens refilled, capped at the burst
+	l.updated = l.updated.Add(-1500 * time.Millisecond)
+	if got := l.reserve(); got != 0 {
+		t.Errorf("reserve() after refill = %v, want 0", got)
+	}
+	if got := l.reserve().Round(100 * time.Millisecond); got != 500*time.Millisecond {
+		t.Errorf("reserve() with half a token left = %v, want 500ms", got)
+	}
+
+	l.updated = l.updated.Add(-time.Hour)
+	l.reserve()
+	if l.tokens > 1 {
+		t.Errorf("tokens after a long pause = %v, want at most burst-1", l.tokens)
+	}
+}
+
+func TestWait(t *testing.T) {
+	if err := New(0, 1, "").Wait(context.Background()); err != nil {
+		t.Errorf("Wait() without a rate limit returned %v", err)
+	}
+
+	l := New(1, 1, "")
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait() returned %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() on an empty bucket = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestPersistedBucket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "ratelimit.json")
+
+	// Two limiters stand in for two invocations sharing the state file
+	first := New(60, 2, path)
+	second := New(60, 2, path)
+
+	if got := first.reserve(); got != 0 {
+		t.Fatalf("first reserve() = %v, want 0", got)
+	}
+	if got := second.reserve(); got != 0 {
+		t.Fatalf("second reserve() = %v, want 0", got)
+	}
+	if got := first.reserve().Round(100 * time.Millisecond); got != time.Second {
+		t.Errorf("third reserve() across limiters = %v, want 1s", got)
+	}
+
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary state file left behind: %v", err)
+	}
+}
+
+func TestCorruptStateIgnored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := New(60, 1, path).reserve(); got != 0 {
+		t.Errorf("reserve() with a corrupt state file = %v, want 0", got)
+	}
+}
+
+func TestStaleLockRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json.lock")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * lockTimeout)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lock(path)
+	if err != nil {
+		t.Fatalf("lock() over a stale lock returned %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("unlock() left the lock file: %v", err)
+	}
+}
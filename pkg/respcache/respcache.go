@@ -0,0 +1,118 @@
+// Package respcache keeps API responses on disk so repeated invocations of an
+// extension command (a frontend re-running search while the user types, or
+// listing episodes it just listed) are answered without hitting the source.
+//
+// Entries are raw response bodies named after a hash of their request key.
+// Freshness is judged by the file's modification time against a TTL chosen by
+// the caller on every read, so each endpoint can use its own TTL.
+package respcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Cache is a directory of cached responses
+type Cache struct {
+	dir string
+}
+
+// Path returns the default cache directory for an extension package
+func Path(pkg string) (string, error) {
+	dir, err := extconfig.CacheDir(pkg)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "responses"), nil
+}
+
+// New creates a cache stored in dir, which is created on the first write
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// file returns the entry path for key
+func (c *Cache) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the entry for key when it was stored less than ttl ago.
+// Expired entries are removed.
+func (c *Cache) Get(key string, ttl time.Duration) ([]byte, bool) {
+	if ttl <= 0 {
+		return nil, false
+	}
+	path := c.file(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(info.ModTime()) >= ttl {
+		os.Remove(path)
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores data for key, replacing any previous entry. The entry is written
+// to a temporary file first, so concurrent readers never see half an entry.
+func (c *Cache) Put(key string, data []byte) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.file(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Prune removes entries older than maxAge, typically the longest TTL in use,
+// along with temporary files left behind by interrupted writes
+func (c *Cache) Prune(maxAge time.Duration) error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".tmp-")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) >= maxAge {
+			os.Remove(filepath.Join(c.dir, name))
+		}
+	}
+	return nil
+}
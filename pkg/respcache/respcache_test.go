@@ -0,0 +1,105 @@
+package respcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// age backdates the entry for key by d
+func age(t *testing.T, c *Cache, key string, d time.Duration) {
+	t.Helper()
+	when := time.Now().Add(-d)
+	if err := os.Chtimes(c.file(key), when, when); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name     string
+		age      time.Duration
+		ttl      time.Duration
+		wantHit  bool
+		wantKept bool
+	}{
+		{name: "fresh", age: time.Minute, ttl: time.Hour, wantHit: true, wantKept: true},
+		{name: "expired", age: 2 * time.Hour, ttl: time.Hour, wantHit: false, wantKept: false},
+		{name: "exactly at ttl", age: time.Hour, ttl: time.Hour, wantHit: false, wantKept: false},
+		{name: "zero ttl disables caching", age: 0, ttl: 0, wantHit: false, wantKept: true},
+		{name: "negative ttl", age: 0, ttl: -time.Second, wantHit: false, wantKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(filepath.Join(t.TempDir(), "responses"))
+			if err := c.Put("search naruto", []byte(`{"data":1}`)); err != nil {
+				t.Fatalf("Put() returned error: %v", err)
+			}
+			age(t, c, "search naruto", tt.age)
+
+			data, ok := c.Get("search naruto", tt.ttl)
+			if ok != tt.wantHit {
+				t.Fatalf("Get() hit = %v, want %v", ok, tt.wantHit)
+			}
+			if ok && string(data) != `{"data":1}` {
+				t.Errorf("Get() = %q, want the stored body", data)
+			}
+			if _, err := os.Stat(c.file("search naruto")); (err == nil) != tt.wantKept {
+				t.Errorf("entry kept = %v, want %v", err == nil, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestPutReplacesAndSeparatesKeys(t *testing.T) {
+	c := New(t.TempDir())
+	c.Put("a", []byte("first"))
+	c.Put("a", []byte("second"))
+	c.Put("b", []byte("other"))
+
+	if data, ok := c.Get("a", time.Hour); !ok || string(data) != "second" {
+		t.Errorf("Get(a) = %q, %v, want the replaced body", data, ok)
+	}
+	if data, ok := c.Get("b", time.Hour); !ok || string(data) != "other" {
+		t.Errorf("Get(b) = %q, %v, want its own body", data, ok)
+	}
+	if _, ok := c.Get("missing", time.Hour); ok {
+		t.Error("Get() of a missing key hit")
+	}
+
+	entries, _ := os.ReadDir(c.dir)
+	if len(entries) != 2 {
+		t.Errorf("cache holds %d files, want 2 with no temporary files left", len(entries))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := New(t.TempDir())
+	c.Put("old", []byte("1"))
+	c.Put("new", []byte("2"))
+	age(t, c, "old", 2*time.Hour)
+
+	stale := filepath.Join(c.dir, ".tmp-123")
+	unrelated := filepath.Join(c.dir, "notes.txt")
+	for _, path := range []string{stale, unrelated} {
+		os.WriteFile(path, nil, 0o644)
+		when := time.Now().Add(-2 * time.Hour)
+		os.Chtimes(path, when, when)
+	}
+
+	if err := c.Prune(time.Hour); err != nil {
+		t.Fatalf("Prune() returned error: %v", err)
+	}
+
+	for path, want := range map[string]bool{c.file("old"): false, c.file("new"): true, stale: false, unrelated: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s kept = %v, want %v", filepath.Base(path), err == nil, want)
+		}
+	}
+
+	if err := New(filepath.Join(c.dir, "missing")).Prune(time.Hour); err != nil {
+		t.Errorf("Prune() of a missing directory returned %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+// Package subs detects, parses, converts and time-shifts subtitle files so
+// pair can hand players a consistent format regardless of the extension.
+package subs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format identifies a subtitle file format
+type Format string
+
+// Supported subtitle formats
+const (
+	FormatUnknown Format = ""
+	FormatVTT     Format = "vtt"
+	FormatSRT     Format = "srt"
+	FormatASS     Format = "ass"
+)
+
+// Cue represents a single timed subtitle line
+type Cue struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Text  string        `json:"text"` // Plain text, lines separated by \n
+}
+
+var (
+	srtTimingRe = regexp.MustCompile(`^(\d+:\d{2}:\d{2}[,.]\d{1,3})\s*-->\s*(\d+:\d{2}:\d{2}[,.]\d{1,3})`)
+	vttTimingRe = regexp.MustCompile(`^((?:\d+:)?\d{2}:\d{2}\.\d{1,3})\s*-->\s*((?:\d+:)?\d{2}:\d{2}\.\d{1,3})`)
+	tagRe       = regexp.MustCompile(`<[^>]*>`)
+	assTagRe    = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// Detect guesses the format of subtitle data
+func Detect(data []byte) Format {
+	text := strings.TrimSpace(decodeText(data))
+
+	switch {
+	case strings.HasPrefix(text, "WEBVTT"):
+		return FormatVTT
+	case strings.HasPrefix(text, "[Script Info]") || strings.Contains(text, "\n[Events]"):
+		return FormatASS
+	}
+
+	for _, line := range strings.SplitN(text, "\n", 10) {
+		if srtTimingRe.MatchString(strings.TrimSpace(line)) {
+			return FormatSRT
+		}
+	}
+
+	return FormatUnknown
+}
+
+// Parse detects the format of subtitle data and decodes its cues
+func Parse(data []byte) ([]Cue, Format, error) {
+	format := Detect(data)
+	text := decodeText(data)
+
+	var cues []Cue
+	var err error
+	switch format {
+	case FormatVTT:
+		cues, err = parseBlocks(text, vttTimingRe)
+	case FormatSRT:
+		cues, err = parseBlocks(text, srtTimingRe)
+	case FormatASS:
+		cues, err = parseASS(text)
+	default:
+		return nil, format, fmt.Errorf("unrecognized subtitle format")
+	}
+
+	return cues, format, err
+}
+
+// Convert parses subtitle data, applies the given time offset and writes it in the target format
+func Convert(data []byte, target Format, offset time.Duration) ([]byte, error) {
+	cues, _, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := Write(Shift(cues, offset), target)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// Shift moves every cue by offset, dropping cues that end before zero
+func Shift(cues []Cue, offset time.Duration) []Cue {
+	shifted := make([]Cue, 0, len(cues))
+	for _, cue := range cues {
+		cue.Start += offset
+		cue.End += offset
+		if cue.End <= 0 {
+			continue
+		}
+		if cue.Start < 0 {
+			cue.Start = 0
+		}
+		shifted = append(shifted, cue)
+	}
+	return shifted
+}
+
+// Write renders cues in the given format
+func Write(cues []Cue, format Format) (string, error) {
+	var b strings.Builder
+
+	switch format {
+	case FormatVTT:
+		b.WriteString("WEBVTT\n\n")
+		for _, cue := range cues {
+			fmt.Fprintf(&b, "%s --> %s\n%s\n\n", formatTimestamp(cue.Start, "."), formatTimestamp(cue.End, "."), cue.Text)
+		}
+	case FormatSRT:
+		for i, cue := range cues {
+			fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, formatTimestamp(cue.Start, ","), formatTimestamp(cue.End, ","), cue.Text)
+		}
+	case FormatASS:
+		b.WriteString("[Script Info]\nScriptType: v4.00+\n\n")
+		b.WriteString("[V4+ Styles]\nFormat: Name, Fontname, Fontsize, PrimaryColour, Bold, Italic, Alignment, MarginL, MarginR, MarginV\n")
+		b.WriteString("Style: Default,Arial,20,&H00FFFFFF,0,0,2,10,10,10\n\n")
+		b.WriteString("[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+		for _, cue := range cues {
+			fmt.Fprintf(&b, "Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n", formatASSTimestamp(cue.Start), formatASSTimestamp(cue.End), strings.ReplaceAll(cue.Text, "\n", `\N`))
+		}
+	default:
+		return "", fmt.Errorf("unsupported target format %q", format)
+	}
+
+	return b.String(), nil
+}
+
+// parseBlocks parses the blank-line separated cue blocks shared by SRT and VTT
+func parseBlocks(text string, timingRe *regexp.Regexp) ([]Cue, error) {
+	var cues []Cue
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		for i, line := range lines {
+			match := timingRe.FindStringSubmatch(strings.TrimSpace(line))
+			if match == nil {
+				continue
+			}
+
+			start, err := parseTimestamp(match[1])
+			if err != nil {
+				return nil, err
+			}
+			end, err := parseTimestamp(match[2])
+			if err != nil {
+				return nil, err
+			}
+
+			cues = append(cues, Cue{
+				Start: start,
+				End:   end,
+				Text:  tagRe.ReplaceAllString(strings.Join(lines[i+1:], "\n"), ""),
+			})
+			break
+		}
+	}
+	return cues, nil
+}
+
+// parseASS parses the Dialogue lines of an ASS/SSA [Events] section
+func parseASS(text string) ([]Cue, error) {
+	var cues []Cue
+	fields := []string{}
+	inEvents := false
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+
+		if strings.HasPrefix(line, "Format:") {
+			fields = strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "Format:")), ",")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "Dialogue:") || len(fields) == 0 {
+			continue
+		}
+
+		// Text is always the last field and may itself contain commas
+		values := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "Dialogue:")), ",", len(fields))
+		if len(values) != len(fields) {
+			continue
+		}
+
+		var cue Cue
+		for i, field := range fields {
+			var err error
+			switch field {
+			case "Start":
+				cue.Start, err = parseTimestamp(values[i])
+			case "End":
+				cue.End, err = parseTimestamp(values[i])
+			case "Text":
+				cue.Text = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(assTagRe.ReplaceAllString(values[i], ""))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		cues = append(cues, cue)
+	}
+
+	return cues, nil
+}
+
+// parseTimestamp parses SRT (00:00:01,000), VTT (00:01.000) and ASS (0:00:01.00) timestamps
+func parseTimestamp(value string) (time.Duration, error) {
+	value = strings.Replace(strings.TrimSpace(value), ",", ".", 1)
+
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var hours, minutes int
+	var seconds float64
+	var err error
+	if len(parts) == 3 {
+		if hours, err = strconv.Atoi(parts[0]); err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+		parts = parts[1:]
+	}
+	if minutes, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+	if seconds, err = strconv.ParseFloat(parts[1], 64); err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second)).Round(time.Millisecond), nil
+}
+
+// formatTimestamp renders HH:MM:SS<sep>mmm as used by SRT and VTT
+func formatTimestamp(d time.Duration, separator string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
+
+// formatASSTimestamp renders H:MM:SS.cc as used by ASS
+func formatASSTimestamp(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
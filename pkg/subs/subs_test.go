@@ -0,0 +1,232 @@
+package subs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	sampleSRT = "1\r\n00:00:01,000 --> 00:00:02,500\r\n<i>Hello</i>\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nWorld\r\nagain\r\n"
+	sampleVTT = "WEBVTT\n\n00:01.000 --> 00:02.500\nHello\n\n00:00:03.000 --> 00:00:04.000\n<c.yellow>World</c>\nagain\n"
+	sampleASS = "[Script Info]\nScriptType: v4.00+\n\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.00,0:00:02.50,Default,,0,0,0,,{\\i1}Hello{\\i0}\nDialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,World\\Nagain, with commas\n"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{name: "vtt", data: []byte(sampleVTT), want: FormatVTT},
+		{name: "srt", data: []byte(sampleSRT), want: FormatSRT},
+		{name: "ass", data: []byte(sampleASS), want: FormatASS},
+		{name: "srt with utf-8 bom", data: append([]byte{0xEF, 0xBB, 0xBF}, sampleSRT...), want: FormatSRT},
+		{name: "vtt in utf-16le", data: utf16LE("WEBVTT\n\n00:01.000 --> 00:02.000\nHi\n"), want: FormatVTT},
+		{name: "plain text", data: []byte("just some text"), want: FormatUnknown},
+		{name: "empty", data: nil, want: FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.data); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	want := []Cue{
+		{Start: time.Second, End: 2500 * time.Millisecond, Text: "Hello"},
+		{Start: 3 * time.Second, End: 4 * time.Second, Text: "World\nagain"},
+	}
+
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+		want   []Cue
+	}{
+		{name: "srt", data: sampleSRT, format: FormatSRT, want: want},
+		{name: "vtt", data: sampleVTT, format: FormatVTT, want: want},
+		{
+			name:   "ass",
+			data:   sampleASS,
+			format: FormatASS,
+			want: []Cue{
+				{Start: time.Second, End: 2500 * time.Millisecond, Text: "Hello"},
+				{Start: 3 * time.Second, End: 4 * time.Second, Text: "World\nagain, with commas"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cues, format, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse() returned error: %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("Parse() format = %q, want %q", format, tt.format)
+			}
+			if !reflect.DeepEqual(cues, tt.want) {
+				t.Errorf("Parse() = %#v, want %#v", cues, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := Parse([]byte("not a subtitle")); err == nil {
+		t.Error("Parse() of unknown data returned no error")
+	}
+}
+
+func TestShift(t *testing.T) {
+	cues := []Cue{
+		{Start: time.Second, End: 2 * time.Second, Text: "a"},
+		{Start: 3 * time.Second, End: 5 * time.Second, Text: "b"},
+	}
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   []Cue
+	}{
+		{name: "zero", offset: 0, want: cues},
+		{
+			name:   "forward",
+			offset: 1500 * time.Millisecond,
+			want: []Cue{
+				{Start: 2500 * time.Millisecond, End: 3500 * time.Millisecond, Text: "a"},
+				{Start: 4500 * time.Millisecond, End: 6500 * time.Millisecond, Text: "b"},
+			},
+		},
+		{
+			name:   "backward drops and clamps",
+			offset: -4 * time.Second,
+			want:   []Cue{{Start: 0, End: time.Second, Text: "b"}},
+		},
+		{name: "everything before zero", offset: -10 * time.Second, want: []Cue{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Shift(cues, tt.offset); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Shift(%v) = %#v, want %#v", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name   string
+		target Format
+		offset time.Duration
+		want   string
+	}{
+		{
+			name:   "srt to vtt",
+			target: FormatVTT,
+			want:   "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n\n00:00:03.000 --> 00:00:04.000\nWorld\nagain\n\n",
+		},
+		{
+			name:   "srt to srt shifted",
+			target: FormatSRT,
+			offset: time.Hour,
+			want:   "1\n01:00:01,000 --> 01:00:02,500\nHello\n\n2\n01:00:03,000 --> 01:00:04,000\nWorld\nagain\n\n",
+		},
+		{
+			name:   "srt to ass",
+			target: FormatASS,
+			want: "[Script Info]\nScriptType: v4.00+\n\n" +
+				"[V4+ Styles]\nFormat: Name, Fontname, Fontsize, PrimaryColour, Bold, Italic, Alignment, MarginL, MarginR, MarginV\n" +
+				"Style: Default,Arial,20,&H00FFFFFF,0,0,2,10,10,10\n\n" +
+				"[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+				"Dialogue: 0,0:00:01.00,0:00:02.50,Default,,0,0,0,,Hello\n" +
+				"Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,World\\Nagain\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert([]byte(sampleSRT), tt.target, tt.offset)
+			if err != nil {
+				t.Fatalf("Convert() returned error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Convert() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Convert([]byte(sampleSRT), Format("sub"), 0); err == nil {
+		t.Error("Convert() to an unsupported format returned no error")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "00:00:01,000", want: time.Second},
+		{value: "01:02:03.456", want: time.Hour + 2*time.Minute + 3456*time.Millisecond},
+		{value: "02:03.5", want: 2*time.Minute + 3500*time.Millisecond},
+		{value: "0:00:01.25", want: 1250 * time.Millisecond},
+		{value: "12", wantErr: true},
+		{value: "1:2:3:4", wantErr: true},
+		{value: "aa:00:01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTimestamp(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTimestamp(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTimestamp(%q) returned error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseTimestamp(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "utf-8", data: []byte("café\r\n"), want: "café\n"},
+		{name: "utf-8 bom", data: []byte("\xEF\xBB\xBFhi"), want: "hi"},
+		{name: "utf-16le", data: utf16LE("hé\r\nx"), want: "hé\nx"},
+		{name: "utf-16be", data: []byte{0xFE, 0xFF, 0x00, 'o', 0x00, 'k'}, want: "ok"},
+		{name: "windows-1252", data: []byte("caf\xe9 \x93quoted\x94 \x80"), want: "café “quoted” €"},
+		{name: "old mac line endings", data: []byte("a\rb"), want: "a\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeText(tt.data); got != tt.want {
+				t.Errorf("decodeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// utf16LE encodes ASCII/Latin-1 text as UTF-16LE with a byte order mark
+func utf16LE(s string) []byte {
+	data := []byte{0xFF, 0xFE}
+	for _, r := range s {
+		data = append(data, byte(r), byte(r>>8))
+	}
+	return data
+}
@@ -0,0 +1,118 @@
+// Package titlematch scores how well anime titles match a search query so
+// extensions can rank results consistently.
+package titlematch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Score weights, highest first. A title's score is the best of its primary
+// and alternative titles; alternative title matches rank slightly lower.
+const (
+	ScoreExact         = 100.0
+	ScoreExactAlt      = 90.0
+	ScorePrefix        = 75.0
+	ScorePrefixAlt     = 65.0
+	ScoreContains      = 50.0
+	ScoreContainsAlt   = 45.0
+	ScoreFuzzyMax      = 40.0 // Fuzzy matches scale from 0 up to this value
+	altTitleFuzzyRatio = 0.9
+)
+
+// Normalize lowercases a title and collapses punctuation and whitespace so that
+// "Naruto: Shippuuden" and "naruto shippuuden" compare equal
+func Normalize(title string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// Score returns the relevance of a title and its alternative titles to query, from 0 to 100
+func Score(query, title string, altTitles []string) float64 {
+	q := Normalize(query)
+	if q == "" {
+		return 0
+	}
+
+	best := scoreOne(q, Normalize(title), false)
+	for _, alt := range altTitles {
+		if score := scoreOne(q, Normalize(alt), true); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+func scoreOne(query, title string, alt bool) float64 {
+	if title == "" {
+		return 0
+	}
+
+	switch {
+	case title == query:
+		return pick(alt, ScoreExactAlt, ScoreExact)
+	case strings.HasPrefix(title, query):
+		return pick(alt, ScorePrefixAlt, ScorePrefix)
+	case strings.Contains(title, query):
+		return pick(alt, ScoreContainsAlt, ScoreContains)
+	}
+
+	score := Similarity(query, title) * ScoreFuzzyMax
+	if alt {
+		score *= altTitleFuzzyRatio
+	}
+	return score
+}
+
+func pick(alt bool, altScore, score float64) float64 {
+	if alt {
+		return altScore
+	}
+	return score
+}
+
+// Similarity returns a value between 0 and 1 based on the Levenshtein distance of a and b
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,88 @@
+package titlematch
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "Naruto: Shippuuden", want: "naruto shippuuden"},
+		{title: "  naruto   shippuuden  ", want: "naruto shippuuden"},
+		{title: "Re:Zero - Starting Life in Another World", want: "re zero starting life in another world"},
+		{title: "Steins;Gate 0", want: "steins gate 0"},
+		{title: "Kaguya-sama wa Kokurasetai!?", want: "kaguya sama wa kokurasetai"},
+		{title: "Pokémon", want: "pokémon"},
+		{title: "進撃の巨人", want: "進撃の巨人"},
+		{title: "!!!", want: ""},
+		{title: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := Normalize(tt.title); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		title     string
+		altTitles []string
+		want      float64
+	}{
+		{name: "exact", query: "naruto", title: "Naruto", want: ScoreExact},
+		{name: "exact after normalizing", query: "naruto shippuuden", title: "Naruto: Shippuuden", want: ScoreExact},
+		{name: "prefix", query: "naruto", title: "Naruto: Shippuuden", want: ScorePrefix},
+		{name: "contains", query: "shippuuden", title: "Naruto: Shippuuden", want: ScoreContains},
+		{name: "exact alt", query: "attack on titan", title: "Shingeki no Kyojin", altTitles: []string{"Attack on Titan"}, want: ScoreExactAlt},
+		{name: "prefix alt", query: "attack", title: "Shingeki no Kyojin", altTitles: []string{"Attack on Titan"}, want: ScorePrefixAlt},
+		{name: "contains alt", query: "titan", title: "Shingeki no Kyojin", altTitles: []string{"Attack on Titan"}, want: ScoreContainsAlt},
+		{name: "primary beats alt", query: "naruto", title: "Naruto", altTitles: []string{"Naruto"}, want: ScoreExact},
+		{name: "best alt wins", query: "titan", title: "x", altTitles: []string{"", "Attack on Titan", "Titan"}, want: ScoreExactAlt},
+		{name: "fuzzy", query: "narutp", title: "Naruto", want: ScoreFuzzyMax * 5 / 6},
+		{name: "fuzzy alt", query: "narutp", title: "", altTitles: []string{"Naruto"}, want: ScoreFuzzyMax * 5 / 6 * altTitleFuzzyRatio},
+		{name: "empty query", query: "?!", title: "Naruto", want: 0},
+		{name: "empty title", query: "naruto", title: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Score(tt.query, tt.title, tt.altTitles); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score(%q, %q, %q) = %v, want %v", tt.query, tt.title, tt.altTitles, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "", b: "", want: 1},
+		{a: "abc", b: "abc", want: 1},
+		{a: "abc", b: "", want: 0},
+		{a: "kitten", b: "sitting", want: 1 - 3.0/7},
+		{a: "flaw", b: "lawn", want: 0.5},
+		{a: "ポケモン", b: "ポケモソ", want: 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := Similarity(tt.b, tt.a); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package torrentmap
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// decoder is a minimal bencode decoder sufficient for reading .torrent files
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// decodeBencode decodes a single bencoded value. Dictionaries are returned as
+// map[string]interface{}, lists as []interface{}, integers as int64 and
+// strings as string.
+func decodeBencode(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+	return d.decode()
+}
+
+func (d *decoder) decode() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("unexpected end of data at offset %d", d.pos)
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.decodeInt()
+	case c == 'l':
+		return d.decodeList()
+	case c == 'd':
+		return d.decodeDict()
+	case c >= '0' && c <= '9':
+		return d.decodeString()
+	default:
+		return nil, fmt.Errorf("invalid bencode type %q at offset %d", c, d.pos)
+	}
+}
+
+func (d *decoder) decodeInt() (int64, error) {
+	d.pos++ // skip 'i'
+	end := d.indexFrom('e')
+	if end < 0 {
+		return 0, fmt.Errorf("unterminated integer at offset %d", d.pos)
+	}
+
+	value, err := strconv.ParseInt(string(d.data[d.pos:end]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer at offset %d: %v", d.pos, err)
+	}
+
+	d.pos = end + 1
+	return value, nil
+}
+
+func (d *decoder) decodeString() (string, error) {
+	colon := d.indexFrom(':')
+	if colon < 0 {
+		return "", fmt.Errorf("invalid string length at offset %d", d.pos)
+	}
+
+	length, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("invalid string length at offset %d", d.pos)
+	}
+
+	// Compare against the bytes left rather than computing start+length,
+	// which overflows for lengths near the maximum int
+	start := colon + 1
+	if length > len(d.data)-start {
+		return "", fmt.Errorf("string at offset %d exceeds data length", d.pos)
+	}
+
+	d.pos = start + length
+	return string(d.data[start:d.pos]), nil
+}
+
+func (d *decoder) decodeList() ([]interface{}, error) {
+	d.pos++ // skip 'l'
+	list := []interface{}{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("unterminated list")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return list, nil
+		}
+
+		value, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+}
+
+func (d *decoder) decodeDict() (map[string]interface{}, error) {
+	d.pos++ // skip 'd'
+	dict := map[string]interface{}{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("unterminated dictionary")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return dict, nil
+		}
+
+		key, err := d.decodeString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+}
+
+// indexFrom returns the index of the next occurrence of c at or after the current position
+func (d *decoder) indexFrom(c byte) int {
+	for i := d.pos; i < len(d.data); i++ {
+		if d.data[i] == c {
+			return i
+		}
+	}
+	return -1
+}
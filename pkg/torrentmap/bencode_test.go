@@ -0,0 +1,50 @@
+package torrentmap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeBencode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "string", data: "4:spam", want: "spam"},
+		{name: "empty string", data: "0:", want: ""},
+		{name: "integer", data: "i-42e", want: int64(-42)},
+		{name: "list", data: "l4:spami7ee", want: []interface{}{"spam", int64(7)}},
+		{name: "dictionary", data: "d3:cow3:moo4:spami1ee", want: map[string]interface{}{"cow": "moo", "spam": int64(1)}},
+		{name: "string past end", data: "5:spam", wantErr: true},
+		{name: "maximum length", data: "9223372036854775807:abc", wantErr: true},
+		{name: "length overflowing int", data: "99999999999999999999:abc", wantErr: true},
+		{name: "negative length", data: "-1:a", wantErr: true},
+		{name: "missing colon", data: "4spam", wantErr: true},
+		{name: "unterminated integer", data: "i42", wantErr: true},
+		{name: "unterminated list", data: "l4:spam", wantErr: true},
+		{name: "unterminated dictionary", data: "d3:cow3:moo", wantErr: true},
+		{name: "dictionary with long key", data: "d9223372036854775807:ae", wantErr: true},
+		{name: "invalid type", data: "x", wantErr: true},
+		{name: "empty", data: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeBencode([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decodeBencode(%q) = %v, want an error", tt.data, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeBencode(%q) returned error: %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeBencode(%q) = %#v, want %#v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Package torrentmap maps the files inside a batch torrent to episode numbers
+// so torrent extensions can point stream-url at a specific file index.
+//
+// Metadata is read from .torrent files. Extensions that only have a magnet link
+// should resolve the matching .torrent URL (most trackers publish both) since
+// fetching metadata over DHT is not supported.
+package torrentmap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// File represents a single file inside a torrent
+type File struct {
+	Index  int    `json:"index"`  // Position of the file in the torrent's file list
+	Path   string `json:"path"`   // Slash-separated path relative to the torrent root
+	Length int64  `json:"length"` // File size in bytes
+}
+
+// Torrent represents the metadata of a .torrent file
+type Torrent struct {
+	Name  string `json:"name"`  // Suggested name of the root file or directory
+	Files []File `json:"files"` // Files contained in the torrent
+}
+
+// EpisodeFile maps an episode number to a file inside a torrent
+type EpisodeFile struct {
+	EpisodeNumber float64 `json:"episode_number"`
+	File          File    `json:"file"`
+}
+
+// videoExtensions lists file extensions considered playable episodes
+var videoExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".avi": true, ".webm": true, ".m4v": true, ".ts": true,
+}
+
+// episodePatterns match episode numbers in common release file names, most specific first
+var episodePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)S\d{1,2}E(\d{1,4}(?:\.\d)?)(?:\D|$)`),
+	regexp.MustCompile(`(?i)\b(?:EP?|Episode)[ ._-]?(\d{1,4}(?:\.\d)?)(?:\D|$)`),
+	regexp.MustCompile(`\s-\s(\d{1,4}(?:\.\d)?)(?:v\d)?[\s\[(.]`),
+	regexp.MustCompile(`[\[_ ](\d{2,4}(?:\.\d)?)(?:v\d)?[\]_ ]`),
+}
+
+// Doer sends HTTP requests; *http.Client and *httpclient.Client satisfy it
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Fetch downloads and parses a .torrent file. Extensions pass their
+// httpclient.Client so the request shares its proxy, rate limit and logging.
+func Fetch(ctx context.Context, client Doer, torrentURL string, headers map[string]string) (*Torrent, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", torrentURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+
+	return Parse(body)
+}
+
+// Parse decodes the contents of a .torrent file
+func Parse(data []byte) (*Torrent, error) {
+	value, err := decodeBencode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding torrent: %v", err)
+	}
+
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("torrent root is not a dictionary")
+	}
+
+	info, ok := root["info"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("torrent is missing the info dictionary")
+	}
+
+	name, _ := info["name"].(string)
+	torrent := &Torrent{Name: name}
+
+	// Single-file torrents only carry a length
+	if length, ok := info["length"].(int64); ok {
+		torrent.Files = []File{{Index: 0, Path: name, Length: length}}
+		return torrent, nil
+	}
+
+	files, ok := info["files"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("torrent has neither length nor files")
+	}
+
+	for i, f := range files {
+		entry, ok := f.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid file entry at index %d", i)
+		}
+
+		length, _ := entry["length"].(int64)
+		parts, _ := entry["path"].([]interface{})
+		segments := []string{}
+		for _, part := range parts {
+			if segment, ok := part.(string); ok {
+				segments = append(segments, segment)
+			}
+		}
+
+		torrent.Files = append(torrent.Files, File{
+			Index:  i,
+			Path:   strings.Join(segments, "/"),
+			Length: length,
+		})
+	}
+
+	return torrent, nil
+}
+
+// ParseEpisodeNumber extracts an episode number from a release file name
+func ParseEpisodeNumber(fileName string) (float64, bool) {
+	base := path.Base(fileName)
+	base = strings.TrimSuffix(base, path.Ext(base))
+
+	for _, pattern := range episodePatterns {
+		// Pad with a trailing space so patterns anchored on a separator match at the end
+		if match := pattern.FindStringSubmatch(base + " "); match != nil {
+			if number, err := strconv.ParseFloat(match[1], 64); err == nil {
+				return number, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// MapEpisodes maps the video files of a torrent to episode numbers, sorted by
+// episode. When several files resolve to the same episode the largest wins.
+func (t *Torrent) MapEpisodes() []EpisodeFile {
+	byEpisode := map[float64]File{}
+	for _, file := range t.Files {
+		if !videoExtensions[strings.ToLower(path.Ext(file.Path))] {
+			continue
+		}
+
+		number, ok := ParseEpisodeNumber(file.Path)
+		if !ok {
+			continue
+		}
+
+		if existing, ok := byEpisode[number]; !ok || file.Length > existing.Length {
+			byEpisode[number] = file
+		}
+	}
+
+	var mapped []EpisodeFile
+	for number, file := range byEpisode {
+		mapped = append(mapped, EpisodeFile{EpisodeNumber: number, File: file})
+	}
+
+	sort.Slice(mapped, func(i, j int) bool {
+		return mapped[i].EpisodeNumber < mapped[j].EpisodeNumber
+	})
+
+	return mapped
+}
+
+// FileForEpisode returns the file holding the given episode
+func (t *Torrent) FileForEpisode(episodeNumber float64) (File, error) {
+	for _, mapped := range t.MapEpisodes() {
+		if mapped.EpisodeNumber == episodeNumber {
+			return mapped.File, nil
+		}
+	}
+	return File{}, fmt.Errorf("episode %v not found in torrent %q", episodeNumber, t.Name)
+}
+
+// MainFile returns the largest video file of a torrent, the episode or movie
+// of a release that ships a single one alongside extras such as fonts
+func (t *Torrent) MainFile() (File, error) {
+	var main File
+	found := false
+	for _, file := range t.Files {
+		if !videoExtensions[strings.ToLower(path.Ext(file.Path))] {
+			continue
+		}
+		if !found || file.Length > main.Length {
+			main = file
+			found = true
+		}
+	}
+	if !found {
+		return File{}, fmt.Errorf("no video file in torrent %q", t.Name)
+	}
+	return main, nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package torrentstream
+
+import "syscall"
+
+// detached puts the sidecar in a session of its own, so signals sent to the
+// extension's process group do not stop it
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package torrentstream
+
+import "syscall"
+
+// detachedProcess is DETACHED_PROCESS: the sidecar gets no console of its own
+const detachedProcess = 0x00000008
+
+// detached starts the sidecar without the extension's console, so closing it
+// does not stop the sidecar
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,186 @@
+// Package torrentstream lets torrent extensions return playable HTTP URLs the
+// way every other extension does.
+//
+// An extension process exits as soon as it prints its JSON, so it cannot
+// download a torrent while the player watches it. A long-running sidecar,
+// cmd/torrentstream, downloads torrents on demand and serves their files over
+// HTTP on the loopback interface, fetching pieces in the order the player
+// reads them. Extensions only build URLs pointing at the sidecar and start it
+// when it is not running; the BitTorrent client is linked into the sidecar
+// alone, which keeps extension binaries small.
+package torrentstream
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAddr is where the sidecar listens unless told otherwise
+const DefaultAddr = "127.0.0.1:8689"
+
+// Binary is the name of the sidecar executable looked up on PATH
+const Binary = "pair-torrentstream"
+
+// startTimeout bounds how long Resolve waits for a sidecar it started to answer
+const startTimeout = 5 * time.Second
+
+// Options locate the sidecar
+type Options struct {
+	Addr   string // host:port the sidecar listens on; DefaultAddr when empty
+	Binary string // Sidecar executable; Binary on PATH when empty
+}
+
+func (o Options) addr() string {
+	if o.Addr == "" {
+		return DefaultAddr
+	}
+	return o.Addr
+}
+
+func (o Options) binary() string {
+	if o.Binary == "" {
+		return Binary
+	}
+	return o.Binary
+}
+
+// InfoHash returns the info hash of a magnet link as 40 lowercase hex digits.
+// Magnet links carry it in hex or, in older clients' links, in base32.
+func InfoHash(magnet string) (string, error) {
+	u, err := url.Parse(magnet)
+	if err != nil || u.Scheme != "magnet" {
+		return "", fmt.Errorf("not a magnet link: %q", magnet)
+	}
+	for _, xt := range u.Query()["xt"] {
+		hash, ok := strings.CutPrefix(xt, "urn:btih:")
+		if !ok {
+			continue
+		}
+		switch len(hash) {
+		case 40:
+			if _, err := hex.DecodeString(hash); err == nil {
+				return strings.ToLower(hash), nil
+			}
+		case 32:
+			if decoded, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash)); err == nil {
+				return hex.EncodeToString(decoded), nil
+			}
+		}
+		return "", fmt.Errorf("invalid info hash %q in magnet link", hash)
+	}
+	return "", fmt.Errorf("magnet link has no BitTorrent info hash")
+}
+
+// StreamURL returns the URL the sidecar at addr serves an episode of a
+// torrent at. The sidecar picks the file holding the episode from the
+// torrent's file names; episode 0 selects the largest video file, for
+// single-episode releases and movies.
+func StreamURL(addr, magnet string, episode float64) (string, error) {
+	hash, err := InfoHash(magnet)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{"magnet": {magnet}}
+	if episode != 0 {
+		query.Set("episode", strconv.FormatFloat(episode, 'f', -1, 64))
+	}
+	return "http://" + addr + "/stream/" + hash + "?" + query.Encode(), nil
+}
+
+// Resolve returns the stream URL of an episode of a torrent, starting the
+// sidecar first when it is not running. The download starts once the player
+// requests the URL.
+func Resolve(ctx context.Context, opts Options, magnet string, episode float64) (string, error) {
+	streamURL, err := StreamURL(opts.addr(), magnet, episode)
+	if err != nil {
+		return "", err
+	}
+	if running(ctx, opts.addr()) {
+		return streamURL, nil
+	}
+	if err := start(ctx, opts); err != nil {
+		return "", err
+	}
+	return streamURL, nil
+}
+
+// running reports whether a sidecar answers at addr
+func running(ctx context.Context, addr string) bool {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/health", nil)
+	if err != nil {
+		return false
+	}
+	// Straight to the loopback interface, whatever proxy the environment sets
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// start launches the sidecar detached from the extension, so it outlives the
+// command, and waits until it answers
+func start(ctx context.Context, opts Options) error {
+	if host, _, err := net.SplitHostPort(opts.addr()); err != nil || !isLoopback(host) {
+		return fmt.Errorf("torrent streaming address %q is not on the loopback interface", opts.addr())
+	}
+	binary, err := exec.LookPath(opts.binary())
+	if err != nil {
+		return fmt.Errorf("torrent streaming needs %s: %v", opts.binary(), err)
+	}
+
+	// No stdio: the frontend reads the extension's stdout until every
+	// process holding it exits
+	cmd := exec.Command(binary, "-addr", opts.addr())
+	cmd.SysProcAttr = detached()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting %s: %v", binary, err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.NewTimer(startTimeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-exited:
+			// Another invocation may have started one first and taken the port
+			if running(ctx, opts.addr()) {
+				return nil
+			}
+			return fmt.Errorf("%s exited: %v", binary, err)
+		case <-deadline.C:
+			return fmt.Errorf("%s did not answer on %s within %s", binary, opts.addr(), startTimeout)
+		case <-tick.C:
+			if running(ctx, opts.addr()) {
+				return nil
+			}
+		}
+	}
+}
+
+// isLoopback reports whether host names or is a loopback address
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,6 @@
+//go:build nsfw
+
+package main
+
+// adultContentBuild reports whether this build may return adult results with --allow-adult
+const adultContentBuild = true
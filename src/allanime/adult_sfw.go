@@ -0,0 +1,8 @@
+//go:build !nsfw
+
+package main
+
+// adultContentBuild reports whether this build may return adult results with
+// --allow-adult. Builds without the nsfw tag never do, so distributions that
+// exclude adult sources cannot enable them at runtime.
+const adultContentBuild = false
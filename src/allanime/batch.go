@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// batchConcurrency is how many episodes stream-url --episodes resolves at once.
+// Requests to AllAnime's own hosts are still paced by the rate limiter.
+const batchConcurrency = 4
+
+// EpisodeStreams is the result for one episode of stream-url --episodes:
+// the episode's streams, or the error that kept them from being resolved
+type EpisodeStreams struct {
+	EpisodeNumber float64 `json:"episode_number"`
+	*VideoResponse
+	Error *exterr.Error `json:"error,omitempty"`
+}
+
+// GetVideoLists retrieves the streams of every episode between start and end,
+// in episode order, with quality applied as by SelectQuality. An episode that
+// fails carries its error instead of streams; the batch only fails when no
+// episode could be resolved.
+func (s *AllanimeScaper) GetVideoLists(ctx context.Context, animeID string, start, end float64, quality string) ([]EpisodeStreams, error) {
+	episodes, err := s.listEpisodes(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []float64
+	for _, episode := range episodes {
+		if episode.EpisodeNumber >= start && episode.EpisodeNumber <= end {
+			numbers = append(numbers, episode.EpisodeNumber)
+		}
+	}
+	if len(numbers) == 0 {
+		return nil, exterr.New(exterr.NotFound, "no episodes between %g and %g", start, end)
+	}
+	sort.Float64s(numbers)
+
+	results := make([]EpisodeStreams, len(numbers))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, number := range numbers {
+		wg.Add(1)
+		go func(i int, number float64) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i].EpisodeNumber = number
+			videos, err := s.GetVideoList(ctx, animeID, number)
+			if err == nil {
+				videos.Streams, err = SelectQuality(videos.Streams, quality)
+			}
+			if err != nil {
+				results[i].Error = exterr.From(err, exterr.Internal)
+				return
+			}
+			results[i].VideoResponse = &videos
+		}(i, number)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Error == nil {
+			return results, nil
+		}
+	}
+	return nil, results[0].Error
+}
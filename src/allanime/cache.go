@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/respcache"
+)
+
+// defaultCacheTTLs is how long responses stay fresh per endpoint. Listings and
+// episode lists change as episodes air, show metadata rarely changes, and
+// provider payloads carry signed links that expire. The cache_ttl config
+// setting overrides them.
+var defaultCacheTTLs = map[string]time.Duration{
+	"search":        time.Hour,
+	"latest":        10 * time.Minute,
+	"popular":       time.Hour,
+	"details":       24 * time.Hour,
+	"related":       24 * time.Hour,
+	"episodes":      30 * time.Minute,
+	"episodes-meta": 6 * time.Hour,
+	"fillers":       24 * time.Hour,   // MyAnimeList episode lists
+	"stream":        30 * time.Minute, // Episode sources
+	"provider":      10 * time.Minute, // Decoded provider payloads with the stream links
+}
+
+// EnableCache answers repeated requests from the response cache in the
+// extension's cache directory, dropping entries older than the longest TTL
+func (s *AllanimeScaper) EnableCache() error {
+	dir, err := respcache.Path("allanime")
+	if err != nil {
+		return err
+	}
+	s.cache = respcache.New(dir)
+
+	var longest time.Duration
+	for _, ttl := range s.cacheTTL {
+		longest = max(longest, ttl)
+	}
+	return s.cache.Prune(longest)
+}
+
+// cacheable reports whether a response body may be cached: GraphQL reports
+// errors, including rate limiting, with a 200 status and an errors field
+func cacheable(body []byte) bool {
+	var response struct {
+		Errors json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+	return len(response.Errors) == 0 || string(response.Errors) == "null"
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// Config holds user overrides read from the extension config file, letting
+// users hotfix access when AllAnime moves its API or starts requiring headers,
+// and set defaults for flags they would otherwise repeat on every invocation.
+// Flags given on the command line win over the file.
+type Config struct {
+	cli.Config
+	Translation     string   `json:"translation,omitempty"`      // Default for -translation: sub, dub, raw or all
+	Quality         string   `json:"quality,omitempty"`          // Default for -quality: best, worst or a resolution such as 1080p
+	TimeoutSeconds  *int     `json:"timeout_seconds,omitempty"`  // Default for -timeout in seconds; 0 disables the deadline
+	PriorityDomains []string `json:"priority_domains,omitempty"` // Stream provider domains to prefer, best first, ahead of the built-in order
+
+	APIURL         string            `json:"api_url,omitempty"`          // GraphQL endpoint, e.g. https://api.allanime.day/api
+	BaseHost       string            `json:"base_host,omitempty"`        // Host serving provider links, e.g. allanime.day
+	Mirrors        []string          `json:"mirrors,omitempty"`          // Fallback base hosts tried in order when the API fails or serves a Cloudflare challenge, e.g. allanime.to
+	Referer        string            `json:"referer,omitempty"`          // Referer sent with every request
+	Headers        map[string]string `json:"headers,omitempty"`          // Extra headers sent with every API request
+	APIToken       string            `json:"api_token,omitempty"`        // Optional API token
+	APITokenHeader string            `json:"api_token_header,omitempty"` // Header carrying the token, defaults to Authorization: Bearer
+	Retries        *int              `json:"retries,omitempty"`          // Retries for transient API failures (429, 502, 503, 504, network errors); 0 disables
+	RetryDelayMs   int               `json:"retry_delay_ms,omitempty"`   // Delay before the first retry in milliseconds, doubled for each further retry
+	Cache          *bool             `json:"cache,omitempty"`            // Whether to cache API responses on disk; defaults to true
+	CacheTTL       map[string]int    `json:"cache_ttl,omitempty"`        // Seconds responses stay fresh per endpoint (search, latest, popular, details, related, episodes, episodes-meta, fillers, stream, provider); 0 disables
+
+	Prefetch           bool `json:"prefetch,omitempty"`             // In the REPL, resolve the next episode's streams in the background after stream-url
+	PrefetchTTLSeconds int  `json:"prefetch_ttl_seconds,omitempty"` // Seconds prefetched streams are served, defaults to 600
+
+	// How redirects are followed per host ("*.example.com" covers subdomains)
+	Redirects map[string]RedirectConfig `json:"redirects,omitempty"`
+}
+
+// RedirectConfig is the redirect policy for one host, see httpclient.RedirectPolicy
+type RedirectConfig struct {
+	MaxHops       int      `json:"max_hops,omitempty"`       // Redirects followed before giving up
+	PreserveQuery []string `json:"preserve_query,omitempty"` // Query parameters carried over when a redirect drops them; "*" for all
+	Interstitials []string `json:"interstitials,omitempty"`  // Ad or landing page hosts to skip to the URL they carry
+}
+
+// LoadConfig reads the config file at path, or the default location when path is empty
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path == "" {
+		defaultPath, err := extconfig.Path("allanime")
+		if err != nil {
+			return cfg, err
+		}
+		path = defaultPath
+	}
+
+	err := extconfig.Load(path, &cfg)
+	return cfg, err
+}
+
+// ApplyConfig overrides the scraper defaults with the non-empty values of cfg
+func (s *AllanimeScaper) ApplyConfig(cfg Config) {
+	if cfg.BaseHost != "" {
+		s.allanimeBase = cfg.BaseHost
+		s.allanimeAPI = "https://api." + cfg.BaseHost + "/api"
+	}
+	if cfg.APIURL != "" {
+		s.allanimeAPI = cfg.APIURL
+	}
+	s.setMirrors(cfg.Mirrors)
+	if cfg.Referer != "" {
+		s.allanimeRef = cfg.Referer
+	}
+	if len(cfg.PriorityDomains) > 0 {
+		priorities := []string{}
+		for _, domain := range append(cfg.PriorityDomains, LinkPriorities...) {
+			domain = strings.ToLower(strings.TrimSpace(domain))
+			if domain != "" && !slices.Contains(priorities, domain) {
+				priorities = append(priorities, domain)
+			}
+		}
+		s.priorities = priorities
+		s.ranker = newRanker(s.priorities, "")
+	}
+	if cfg.Retries != nil && *cfg.Retries >= 0 {
+		s.retry.MaxRetries = *cfg.Retries
+	}
+	if cfg.RetryDelayMs > 0 {
+		s.retry.BaseDelay = time.Duration(cfg.RetryDelayMs) * time.Millisecond
+	}
+	for endpoint, seconds := range cfg.CacheTTL {
+		if seconds >= 0 {
+			s.cacheTTL[endpoint] = time.Duration(seconds) * time.Second
+		}
+	}
+
+	for host, redirect := range cfg.Redirects {
+		s.client.SetRedirectPolicy(host, httpclient.RedirectPolicy{
+			MaxHops:       redirect.MaxHops,
+			PreserveQuery: redirect.PreserveQuery,
+			Interstitials: redirect.Interstitials,
+		})
+	}
+
+	s.headers = map[string]string{}
+	for key, value := range cfg.Headers {
+		s.headers[key] = value
+	}
+	if cfg.APIToken != "" {
+		if cfg.APITokenHeader != "" {
+			s.headers[cfg.APITokenHeader] = cfg.APIToken
+		} else {
+			s.headers["Authorization"] = "Bearer " + cfg.APIToken
+		}
+	}
+}
+
+// setHeaders sets the headers every AllAnime request needs
+func (s *AllanimeScaper) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", s.agent)
+	req.Header.Set("Referer", s.allanimeRef)
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+}
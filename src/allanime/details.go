@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends Anime with the metadata a detail screen needs
+type AnimeDetails struct {
+	Anime
+	Studios []string `json:"studios,omitempty"`
+	Score   float64  `json:"score,omitempty"`  // Average score out of 10
+	Season  string   `json:"season,omitempty"` // Airing season, e.g. "Fall 2002"
+	Type    string   `json:"type,omitempty"`   // TV, Movie, OVA, ...
+}
+
+// GetAnimeDetails retrieves description, genres, studios, score, images, season and airing status for an anime
+func (s *AllanimeScaper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	detailsGql := `query ($showId: String!) { show( _id: $showId ) { _id name englishName nativeName altNames description genres tags studios score thumbnail banner season status type availableEpisodes airedStart aniListId malId } }`
+
+	variables := map[string]interface{}{
+		"showId": animeID,
+	}
+
+	var response struct {
+		Data struct {
+			Show *struct {
+				showCard
+				NativeName  string   `json:"nativeName"`
+				AltNames    []string `json:"altNames"`
+				Description string   `json:"description"`
+				Genres      []string `json:"genres"`
+				Tags        []string `json:"tags"`
+				Studios     []string `json:"studios"`
+				Score       float64  `json:"score"`
+				Season      struct {
+					Quarter string `json:"quarter"`
+					Year    int    `json:"year"`
+				} `json:"season"`
+				AiredStart struct {
+					Year int `json:"year"`
+				} `json:"airedStart"`
+			} `json:"show"`
+		} `json:"data"`
+	}
+
+	if err := s.queryAPI(ctx, "details", detailsGql, variables, &response); err != nil {
+		return AnimeDetails{}, err
+	}
+
+	show := response.Data.Show
+	if show == nil || show.ID == "" {
+		return AnimeDetails{}, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+
+	anime := s.toAnime(show.showCard)
+	anime.Description = plainDescription(show.Description)
+	anime.Genre = strings.Join(show.Genres, ", ")
+	anime.Tags = show.Tags
+	anime.Artist = strings.Join(show.Studios, ", ")
+	anime.Status = airingStatus(show.Status)
+	for _, name := range append([]string{show.NativeName}, show.AltNames...) {
+		if name != "" && !containsTitle(anime.AlternativeTitles, name) {
+			anime.AlternativeTitles = append(anime.AlternativeTitles, name)
+		}
+	}
+
+	anime.ReleaseYear = show.AiredStart.Year
+	if anime.ReleaseYear == 0 {
+		anime.ReleaseYear = show.Season.Year
+	}
+
+	details := AnimeDetails{
+		Anime:   anime,
+		Studios: show.Studios,
+		Score:   show.Score,
+		Type:    show.Type,
+	}
+	if show.Season.Quarter != "" && show.Season.Year != 0 {
+		details.Season = fmt.Sprintf("%s %d", show.Season.Quarter, show.Season.Year)
+	}
+
+	return details, nil
+}
+
+// plainDescription strips the HTML tags and entities AllAnime descriptions contain
+func plainDescription(description string) string {
+	lineBreaks := strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n")
+	doc, err := htmlx.ParseString(lineBreaks.Replace(description))
+	if err != nil {
+		return description
+	}
+	return strings.TrimSpace(doc.Text())
+}
+
+// airingStatus maps AllAnime's status strings onto the scraper status constants
+func airingStatus(status string) string {
+	switch status {
+	case "Releasing":
+		return scraper.StatusOngoing
+	case "Finished":
+		return scraper.StatusCompleted
+	case "Cancelled":
+		return scraper.StatusCancelled
+	case "Hiatus":
+		return scraper.StatusOnHiatus
+	}
+	return scraper.StatusUnknown
+}
+
+// containsTitle reports whether titles already holds title, ignoring case
+func containsTitle(titles []string, title string) bool {
+	for _, existing := range titles {
+		if strings.EqualFold(existing, title) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// DownloadPlan is the output of the download command: the selected stream
+// and ready-to-run command lines that save it with the headers it requires
+type DownloadPlan struct {
+	AnimeID       string            `json:"anime_id"`
+	EpisodeNumber float64           `json:"episode_number"`
+	Quality       string            `json:"quality,omitempty"`
+	URL           string            `json:"url"`
+	Format        string            `json:"format"` // "hls" for playlists, "file" for direct video files
+	Headers       map[string]string `json:"headers"`
+	Output        string            `json:"output"` // File the commands write to
+
+	FFmpeg      []string `json:"ffmpeg"`       // ffmpeg argv, run by download -run
+	FFmpegShell string   `json:"ffmpeg_shell"` // The same, quoted for sh-compatible shells such as bash and zsh
+	YtDlp       []string `json:"yt_dlp"`       // Alternative yt-dlp argv
+	YtDlpShell  string   `json:"yt_dlp_shell"`
+
+	Downloaded bool `json:"downloaded"` // Whether download -run saved the episode
+}
+
+// PlanDownload selects the stream of an episode to archive, the best quality
+// unless quality says otherwise, and builds the commands that download it to out
+func (s *AllanimeScaper) PlanDownload(ctx context.Context, animeID string, episodeNumber float64, quality, out string) (DownloadPlan, error) {
+	if quality == "" {
+		quality = QualityBest
+	}
+	videos, err := s.GetVideoList(ctx, animeID, episodeNumber)
+	if err != nil {
+		return DownloadPlan{}, err
+	}
+	streams, err := SelectQuality(videos.Streams, quality)
+	if err != nil {
+		return DownloadPlan{}, err
+	}
+	if len(streams) == 0 {
+		return DownloadPlan{}, exterr.New(exterr.NotFound, "no streams found for episode %g", episodeNumber)
+	}
+	stream := streams[0]
+
+	if out == "" {
+		out = fmt.Sprintf("%s-%s.mp4", animeID, strconv.FormatFloat(episodeNumber, 'f', -1, 64))
+	}
+	plan := DownloadPlan{
+		AnimeID:       animeID,
+		EpisodeNumber: episodeNumber,
+		Quality:       stream.Quality,
+		URL:           stream.VideoURL,
+		Format:        "file",
+		Headers:       stream.Headers,
+		Output:        out,
+	}
+	if strings.Contains(stream.VideoURL, ".m3u8") {
+		plan.Format = "hls"
+	}
+
+	// Header order is fixed so the commands are reproducible
+	names := make([]string, 0, len(plan.Headers))
+	for name := range plan.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ffmpegHeaders strings.Builder
+	plan.YtDlp = []string{"yt-dlp"}
+	for _, name := range names {
+		fmt.Fprintf(&ffmpegHeaders, "%s: %s\r\n", name, plan.Headers[name])
+		plan.YtDlp = append(plan.YtDlp, "--add-header", name+":"+plan.Headers[name])
+	}
+	plan.YtDlp = append(plan.YtDlp, "-o", out, plan.URL)
+
+	plan.FFmpeg = []string{"ffmpeg", "-hide_banner", "-loglevel", "warning", "-stats"}
+	if ffmpegHeaders.Len() > 0 {
+		plan.FFmpeg = append(plan.FFmpeg, "-headers", ffmpegHeaders.String())
+	}
+	plan.FFmpeg = append(plan.FFmpeg, "-i", plan.URL, "-c", "copy")
+	if plan.Format == "hls" {
+		// HLS carries AAC in ADTS framing, which MP4 does not accept
+		plan.FFmpeg = append(plan.FFmpeg, "-bsf:a", "aac_adtstoasc")
+	}
+	plan.FFmpeg = append(plan.FFmpeg, out)
+
+	plan.FFmpegShell = shellJoin(plan.FFmpeg)
+	plan.YtDlpShell = shellJoin(plan.YtDlp)
+	return plan, nil
+}
+
+// RunDownload runs the plan's ffmpeg command. ffmpeg's progress goes to
+// stderr so stdout keeps only the JSON result.
+func RunDownload(ctx context.Context, plan *DownloadPlan) error {
+	path, err := exec.LookPath(plan.FFmpeg[0])
+	if err != nil {
+		return exterr.New(exterr.Unsupported, "ffmpeg is required to download, install it or run the printed command with yt-dlp")
+	}
+	cmd := exec.CommandContext(ctx, path, plan.FFmpeg[1:]...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return exterr.From(fmt.Errorf("error running ffmpeg: %w", err), exterr.Internal)
+	}
+	plan.Downloaded = true
+	return nil
+}
+
+// shellJoin quotes args for a POSIX shell. Arguments with control characters,
+// such as the CRLF-separated ffmpeg headers, use $'...' quoting.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,+@%") == "" {
+		return arg
+	}
+	if strings.ContainsAny(arg, "\r\n\t") {
+		replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\r", `\r`, "\n", `\n`, "\t", `\t`)
+		return "$'" + replacer.Replace(arg) + "'"
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// EpisodeMeta holds display metadata for a single episode
+type EpisodeMeta struct {
+	EpisodeNumber float64 `json:"episode_number"`
+	Title         string  `json:"title,omitempty"`
+	Description   string  `json:"description,omitempty"`
+	Thumbnail     string  `json:"thumbnail_url,omitempty"`
+	Duration      int     `json:"duration,omitempty"` // Duration in seconds
+	AirDate       int64   `json:"air_date,omitempty"` // Unix timestamp of the first upload
+	// Unix timestamps of the upload in each translation type
+	UploadDates map[string]int64 `json:"upload_dates,omitempty"`
+}
+
+// ParseEpisodeRange parses an episode range such as "1-24" or "5"
+func ParseEpisodeRange(value string) (float64, float64, error) {
+	startStr, endStr, isRange := strings.Cut(value, "-")
+	start, err := strconv.ParseFloat(strings.TrimSpace(startStr), 64)
+	if err != nil {
+		return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q", value)
+	}
+
+	end := start
+	if isRange {
+		end, err = strconv.ParseFloat(strings.TrimSpace(endStr), 64)
+		if err != nil {
+			return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q", value)
+		}
+	}
+
+	if end < start {
+		return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q: end is before start", value)
+	}
+	return start, end, nil
+}
+
+// GetEpisodesMeta retrieves titles, thumbnails, durations and air dates for a range of episodes in one request
+func (s *AllanimeScaper) GetEpisodesMeta(ctx context.Context, animeID string, start, end float64) ([]EpisodeMeta, error) {
+	episodeInfosGql := `query ($showId: String!, $episodeNumStart: Float!, $episodeNumEnd: Float!) { episodeInfos(showId: $showId, episodeNumStart: $episodeNumStart, episodeNumEnd: $episodeNumEnd) { episodeIdNum notes description thumbnails uploadDates vidInforssub } }`
+
+	variables := map[string]interface{}{
+		"showId":          animeID,
+		"episodeNumStart": start,
+		"episodeNumEnd":   end,
+	}
+
+	var response struct {
+		Data struct {
+			EpisodeInfos []struct {
+				EpisodeIdNum float64                `json:"episodeIdNum"`
+				Notes        string                 `json:"notes"`
+				Description  string                 `json:"description"`
+				Thumbnails   []string               `json:"thumbnails"`
+				UploadDates  map[string]interface{} `json:"uploadDates"`
+				VidInforssub map[string]interface{} `json:"vidInforssub"`
+			} `json:"episodeInfos"`
+		} `json:"data"`
+	}
+
+	if err := s.queryAPI(ctx, "episodes-meta", episodeInfosGql, variables, &response); err != nil {
+		return nil, err
+	}
+
+	var metas []EpisodeMeta
+	for _, info := range response.Data.EpisodeInfos {
+		meta := EpisodeMeta{
+			EpisodeNumber: info.EpisodeIdNum,
+			Title:         info.Notes,
+			Description:   info.Description,
+			UploadDates:   parseUploadDates(info.UploadDates),
+		}
+		for _, date := range meta.UploadDates {
+			if meta.AirDate == 0 || date < meta.AirDate {
+				meta.AirDate = date
+			}
+		}
+
+		for _, thumbnail := range info.Thumbnails {
+			if strings.HasPrefix(thumbnail, "http") {
+				meta.Thumbnail = thumbnail
+				break
+			}
+		}
+
+		if duration, ok := info.VidInforssub["vidDuration"].(float64); ok {
+			meta.Duration = int(duration)
+		}
+
+		metas = append(metas, meta)
+	}
+
+	return metas, nil
+}
+
+// uploadDate returns the earliest upload date among the translation types,
+// or 0 when none of them was uploaded
+func (m EpisodeMeta) uploadDate(translationTypes []string) int64 {
+	var earliest int64
+	for _, translationType := range translationTypes {
+		if date, ok := m.UploadDates[translationType]; ok && (earliest == 0 || date < earliest) {
+			earliest = date
+		}
+	}
+	return earliest
+}
+
+// parseUploadDates converts the RFC 3339 upload dates per translation type into Unix timestamps
+func parseUploadDates(uploadDates map[string]interface{}) map[string]int64 {
+	dates := map[string]int64{}
+	for translationType, value := range uploadDates {
+		dateStr, ok := value.(string)
+		if !ok {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, dateStr)
+		if err != nil {
+			continue
+		}
+		dates[translationType] = date.Unix()
+	}
+	return dates
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestEpisodeMetaUploadDate(t *testing.T) {
+	meta := EpisodeMeta{UploadDates: map[string]int64{"sub": 1700000000, "dub": 1690000000}}
+	tests := []struct {
+		name             string
+		translationTypes []string
+		want             int64
+	}{
+		{name: "sub", translationTypes: []string{"sub"}, want: 1700000000},
+		{name: "dub", translationTypes: []string{"dub"}, want: 1690000000},
+		{name: "all takes the earliest", translationTypes: []string{"sub", "dub"}, want: 1690000000},
+		{name: "missing translation", translationTypes: []string{"raw"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := meta.uploadDate(tt.translationTypes); got != tt.want {
+				t.Errorf("uploadDate(%v) = %d, want %d", tt.translationTypes, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// jikanAPI mirrors MyAnimeList, whose episode lists flag filler and recap
+// episodes, see https://docs.api.jikan.moe
+const jikanAPI = "https://api.jikan.moe/v4"
+
+// jikanPageSize is how many episodes Jikan returns per page
+const jikanPageSize = 100
+
+// attachFillers marks filler and recap episodes from MyAnimeList's episode
+// list. Like the other metadata it is optional, so a failed lookup leaves the
+// episodes unmarked.
+func (s *AllanimeScaper) attachFillers(ctx context.Context, animeID string, episodes []Episode) {
+	if len(episodes) == 0 {
+		return
+	}
+	malID, err := s.malID(ctx, animeID)
+	if err != nil {
+		return
+	}
+
+	// Jikan numbers episodes from 1, so only the pages covering the listed
+	// episodes are fetched. Episodes are sorted newest first.
+	firstPage := max(1, pageOf(episodes[len(episodes)-1].EpisodeNumber))
+	lastPage := max(firstPage, pageOf(episodes[0].EpisodeNumber))
+
+	filler := map[int]bool{}
+	recap := map[int]bool{}
+	for page := firstPage; page <= lastPage; page++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/anime/%d/episodes?page=%d", jikanAPI, malID, page), nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("User-Agent", s.agent)
+
+		var response struct {
+			Data []struct {
+				Number int  `json:"mal_id"` // Episode number within the show
+				Filler bool `json:"filler"`
+				Recap  bool `json:"recap"`
+			} `json:"data"`
+			Pagination struct {
+				HasNextPage bool `json:"has_next_page"`
+			} `json:"pagination"`
+		}
+		if err := s.getJSON(ctx, "fillers", req, &response); err != nil {
+			return
+		}
+		for _, episode := range response.Data {
+			filler[episode.Number] = episode.Filler
+			recap[episode.Number] = episode.Recap
+		}
+		if !response.Pagination.HasNextPage {
+			break
+		}
+	}
+
+	for i := range episodes {
+		number := episodes[i].EpisodeNumber
+		// Half episodes such as 12.5 are specials MyAnimeList does not list
+		if number != math.Trunc(number) {
+			continue
+		}
+		episodes[i].Filler = filler[int(number)]
+		episodes[i].Recap = recap[int(number)]
+	}
+}
+
+// pageOf returns the Jikan episode page holding an episode number
+func pageOf(number float64) int {
+	return (int(number)-1)/jikanPageSize + 1
+}
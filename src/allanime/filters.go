@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// SearchFilters is the JSON object accepted by --filters, e.g.
+//
+//	{"genres": ["Action", "Comedy"], "year": 2023, "season": "Fall", "type": "TV", "status": "ongoing", "sortBy": "top"}
+//
+// Every field is optional. status takes the scraper status values (ongoing,
+// completed, cancelled, on_hiatus) and is applied to the results, as AllAnime
+// cannot filter on it; sortBy is one of recent, top, name_asc or name_desc.
+type SearchFilters struct {
+	Genres []string `json:"genres,omitempty"`
+	Year   int      `json:"year,omitempty"`
+	Season string   `json:"season,omitempty"`
+	Type   string   `json:"type,omitempty"`
+	Status string   `json:"status,omitempty"`
+	SortBy string   `json:"sortBy,omitempty"`
+}
+
+// Seasons accepted by the season filter
+var Seasons = []string{"Winter", "Spring", "Summer", "Fall"}
+
+// Genres lists the genres AllAnime's search page offers. AllAnime has no query
+// listing them, so this mirrors the site.
+var Genres = []string{
+	"Action", "Adventure", "Cars", "Comedy", "Dementia", "Demons", "Drama",
+	"Ecchi", "Fantasy", "Game", "Harem", "Historical", "Horror", "Isekai",
+	"Josei", "Kids", "Magic", "Martial Arts", "Mecha", "Military", "Music",
+	"Mystery", "Parody", "Police", "Psychological", "Romance", "Samurai",
+	"School", "Sci-Fi", "Seinen", "Shoujo", "Shoujo Ai", "Shounen",
+	"Shounen Ai", "Slice of Life", "Space", "Sports", "Super Power",
+	"Supernatural", "Thriller", "Vampire", "Yaoi", "Yuri",
+}
+
+// adultGenres are only offered when adult results are allowed
+var adultGenres = []string{"Hentai"}
+
+// ShowTypes lists the show types accepted by the type filter
+var ShowTypes = []string{"TV", "Movie", "OVA", "ONA", "Special"}
+
+// sortByValues maps the sortBy filter values onto AllAnime's sort orders
+var sortByValues = map[string]string{
+	"recent":    "Recent",
+	"top":       "Top",
+	"name_asc":  "Name_ASC",
+	"name_desc": "Name_DESC",
+}
+
+// FilterOption is one value of a filter, with the ID to put in the filters JSON
+type FilterOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FilterOptions is the output of the genres command: the values each field of
+// the filters JSON accepts, for front-ends building filter pickers
+type FilterOptions struct {
+	Genres   []FilterOption `json:"genres"`
+	Types    []FilterOption `json:"types"`
+	Seasons  []FilterOption `json:"seasons"`
+	Statuses []FilterOption `json:"statuses"`
+	SortBy   []FilterOption `json:"sortBy"`
+}
+
+// GetFilterOptions lists the values the filters JSON accepts
+func (s *AllanimeScaper) GetFilterOptions() FilterOptions {
+	genres := Genres
+	if s.allowAdult {
+		genres = append(slices.Clone(Genres), adultGenres...)
+		sort.Strings(genres)
+	}
+
+	return FilterOptions{
+		Genres:  namedOptions(genres),
+		Types:   namedOptions(ShowTypes),
+		Seasons: namedOptions(Seasons),
+		Statuses: []FilterOption{
+			{ID: scraper.StatusOngoing, Name: "Ongoing"},
+			{ID: scraper.StatusCompleted, Name: "Completed"},
+			{ID: scraper.StatusCancelled, Name: "Cancelled"},
+			{ID: scraper.StatusOnHiatus, Name: "On hiatus"},
+		},
+		SortBy: []FilterOption{
+			{ID: "recent", Name: "Recently updated"},
+			{ID: "top", Name: "Top rated"},
+			{ID: "name_asc", Name: "Name (A-Z)"},
+			{ID: "name_desc", Name: "Name (Z-A)"},
+		},
+	}
+}
+
+// namedOptions makes options whose ID is their name
+func namedOptions(names []string) []FilterOption {
+	options := make([]FilterOption, len(names))
+	for i, name := range names {
+		options[i] = FilterOption{ID: name, Name: name}
+	}
+	return options
+}
+
+// ParseSearchFilters parses and validates the --filters JSON object. An empty string means no filters.
+func ParseSearchFilters(filters string) (SearchFilters, error) {
+	var f SearchFilters
+	if strings.TrimSpace(filters) == "" {
+		return f, nil
+	}
+
+	if err := json.Unmarshal([]byte(filters), &f); err != nil {
+		return f, exterr.New(exterr.InvalidArgument, "invalid filters: %w", err)
+	}
+
+	if f.Season != "" {
+		season := ""
+		for _, valid := range Seasons {
+			if strings.EqualFold(f.Season, valid) {
+				season = valid
+			}
+		}
+		if season == "" {
+			return f, exterr.New(exterr.InvalidArgument, "invalid season %q (valid: %s)", f.Season, strings.Join(Seasons, ", "))
+		}
+		f.Season = season
+	}
+
+	if f.SortBy != "" {
+		if _, ok := sortByValues[strings.ToLower(f.SortBy)]; !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid sortBy %q (valid: recent, top, name_asc, name_desc)", f.SortBy)
+		}
+		f.SortBy = strings.ToLower(f.SortBy)
+	}
+
+	f.Status = strings.ToLower(f.Status)
+	return f, nil
+}
+
+// apply adds the filters AllAnime supports to a SearchInput
+func (f SearchFilters) apply(search map[string]interface{}) {
+	if len(f.Genres) > 0 {
+		search["genres"] = f.Genres
+	}
+	if f.Year != 0 {
+		search["year"] = f.Year
+	}
+	if f.Season != "" {
+		search["season"] = f.Season
+	}
+	if f.Type != "" {
+		search["types"] = []string{f.Type}
+	}
+	if f.SortBy != "" {
+		search["sortBy"] = sortByValues[f.SortBy]
+	}
+}
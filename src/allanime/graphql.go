@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+)
+
+// queryAPI runs a GraphQL query against the AllAnime API and decodes the
+// response into v. Queries are POSTed as persisted queries like the site does:
+// only the query's hash is sent first, and the full text when the server does
+// not know the hash yet. Servers rejecting POST get the full query in a GET
+// URL instead. The body is decoded as it is read instead of being buffered
+// whole first, which matters for long-running shows whose episode and search
+// responses get large. endpoint selects the cache TTL.
+func (s *AllanimeScaper) queryAPI(ctx context.Context, endpoint, query string, variables map[string]interface{}, v interface{}) error {
+	variablesJSON, err := json.Marshal(variables)
+	if err != nil {
+		return fmt.Errorf("error encoding variables: %v", err)
+	}
+	hash := sha256.Sum256([]byte(query))
+	queryHash := hex.EncodeToString(hash[:])
+
+	// Another mirror is tried when the current one is down or challenging requests
+	return s.withFailover(ctx, func(m mirror) error {
+		if !s.postRejected(m) {
+			err := s.postQuery(ctx, m, endpoint, "", queryHash, variablesJSON, v)
+			if errors.Is(err, errPersistedQueryNotFound) {
+				err = s.postQuery(ctx, m, endpoint, query, queryHash, variablesJSON, v)
+			}
+			if !errors.Is(err, errPostRejected) {
+				return err
+			}
+			s.log.Info("GraphQL POST rejected, falling back to GET", "host", m.base, "error", err)
+		}
+
+		reqURL := fmt.Sprintf("%s?variables=%s&query=%s", m.api, url.QueryEscape(string(variablesJSON)), url.QueryEscape(query))
+
+		req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+		if err != nil {
+			return fmt.Errorf("error creating request: %v", err)
+		}
+		s.setHeaders(req)
+
+		return s.getJSON(ctx, endpoint, req, v)
+	})
+}
+
+var (
+	// errPersistedQueryNotFound means the server does not know a query hash yet
+	errPersistedQueryNotFound = errors.New("persisted query not found")
+	// errPostRejected means the server does not take GraphQL queries by POST
+	errPostRejected = errors.New("GraphQL POST rejected")
+)
+
+// postQuery POSTs a persisted GraphQL query, with its full text unless query
+// is empty. Both forms share a cache entry, keyed by the hash and variables.
+func (s *AllanimeScaper) postQuery(ctx context.Context, m mirror, endpoint, query, queryHash string, variablesJSON []byte, v interface{}) error {
+	key := "POST " + m.api + " " + queryHash + " " + string(variablesJSON)
+	if s.cachedJSON(endpoint, key, v) {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"variables":  json.RawMessage(variablesJSON),
+		"extensions": map[string]interface{}{"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": queryHash}},
+	}
+	if query != "" {
+		payload["query"] = query
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.api, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	s.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusUnsupportedMediaType:
+		// The mirror only answers GET, so stop trying POST on it for this run
+		s.rejectPost(m)
+		return fmt.Errorf("%w: %s from %s", errPostRejected, resp.Status, req.URL.Host)
+	case http.StatusNotFound:
+		// Could be a transient routing error, so only this query falls back
+		return fmt.Errorf("%w: %s from %s", errPostRejected, resp.Status, req.URL.Host)
+	case http.StatusBadRequest:
+		// Some servers answer unknown hashes with 400 rather than a GraphQL error
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if bytes.Contains(data, []byte("PersistedQueryNotFound")) || bytes.Contains(data, []byte("PERSISTED_QUERY_NOT_FOUND")) {
+			return errPersistedQueryNotFound
+		}
+		return fmt.Errorf("%w: %s from %s", errPostRejected, resp.Status, req.URL.Host)
+	}
+	if err := checkStatus(req, resp, s.retry); err != nil {
+		return err
+	}
+
+	response := graphQLResponse{data: v}
+	if err := s.decodeJSON(endpoint, key, resp, &response); err != nil {
+		return err
+	}
+	if response.persistedQueryNotFound {
+		return errPersistedQueryNotFound
+	}
+	return nil
+}
+
+// graphQLResponse decodes a GraphQL response into data while noting whether
+// the server asked for the full text of a persisted query
+type graphQLResponse struct {
+	data                   interface{}
+	persistedQueryNotFound bool
+}
+
+func (r *graphQLResponse) UnmarshalJSON(b []byte) error {
+	var envelope struct {
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(b, &envelope); err != nil {
+		return err
+	}
+	for _, gqlErr := range envelope.Errors {
+		if gqlErr.Message == "PersistedQueryNotFound" || gqlErr.Extensions.Code == "PERSISTED_QUERY_NOT_FOUND" {
+			r.persistedQueryNotFound = true
+			return nil
+		}
+	}
+	return json.Unmarshal(b, r.data)
+}
+
+// getJSON sends req, retrying transient failures, and decodes the JSON
+// response body into v. With the cache enabled, a response stored less than
+// the endpoint's TTL ago is used instead, and successful responses are stored.
+func (s *AllanimeScaper) getJSON(ctx context.Context, endpoint string, req *http.Request, v interface{}) error {
+	key := req.Method + " " + req.URL.String()
+	if s.cachedJSON(endpoint, key, v) {
+		return nil
+	}
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(req, resp, s.retry); err != nil {
+		return err
+	}
+	return s.decodeJSON(endpoint, key, resp, v)
+}
+
+// cachedJSON decodes the response cached under key into v, reporting whether
+// one was stored less than the endpoint's TTL ago
+func (s *AllanimeScaper) cachedJSON(endpoint, key string, v interface{}) bool {
+	ttl := s.cacheTTL[endpoint]
+	if s.cache == nil || ttl <= 0 {
+		return false
+	}
+	data, ok := s.cache.Get(key, ttl)
+	if !ok || json.Unmarshal(data, v) != nil {
+		return false
+	}
+	s.log.Debug("cache hit", "endpoint", endpoint, "key", key)
+	if u, err := url.Parse(strings.Fields(key)[1]); err == nil {
+		s.client.RecordCacheHit(u.Host)
+	}
+	return true
+}
+
+// checkStatus turns Cloudflare challenges and any status outside 2xx into
+// errors, so error pages and unfollowed redirects never reach the decoder
+func checkStatus(req *http.Request, resp *http.Response, retry httpclient.RetryPolicy) error {
+	if isChallenge(resp) {
+		return exterr.New(exterr.Unavailable, "error making request: %s is serving a Cloudflare challenge", req.URL.Host)
+	}
+	if httpclient.IsRetryableStatus(resp.StatusCode) {
+		return exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s after %d retries", resp.Status, req.URL.Host, retry.MaxRetries)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	return nil
+}
+
+// decodeJSON decodes the response body into v, storing successful responses
+// under key when the endpoint is cached
+func (s *AllanimeScaper) decodeJSON(endpoint, key string, resp *http.Response, v interface{}) error {
+	caching := s.cache != nil && s.cacheTTL[endpoint] > 0
+
+	// Keep a copy of the body while decoding it for the cache
+	var body bytes.Buffer
+	reader := io.Reader(resp.Body)
+	if caching {
+		reader = io.TeeReader(resp.Body, &body)
+	}
+	if err := json.NewDecoder(reader).Decode(v); err != nil {
+		return exterr.From(fmt.Errorf("error parsing response: %w", err), exterr.Parse)
+	}
+
+	if caching && resp.StatusCode == http.StatusOK && cacheable(body.Bytes()) {
+		// A failed write only costs a request next time
+		s.cache.Put(key, body.Bytes())
+	}
+	return nil
+}
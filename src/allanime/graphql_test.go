@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newBenchScraper returns a scraper whose API is a local server answering
+// every request with body
+func newBenchScraper(b *testing.B, body []byte) *AllanimeScaper {
+	b.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	b.Cleanup(server.Close)
+
+	s := NewAllanimeScaper()
+	s.allanimeAPI = server.URL + "/api"
+	return s
+}
+
+// episodesResponse builds an availableEpisodesDetail response listing count
+// sub episodes and half as many dub ones, the size of a long-running show
+func episodesResponse(b *testing.B, count int) []byte {
+	b.Helper()
+	sub := make([]string, 0, count)
+	for i := count; i >= 1; i-- {
+		sub = append(sub, fmt.Sprint(i))
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"show": map[string]interface{}{
+				"_id":                     "ReooPAxPMsHM4KPMY",
+				"availableEpisodesDetail": map[string]interface{}{"sub": sub, "dub": sub[count/2:], "raw": []string{}},
+			},
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return body
+}
+
+func TestParseEpisodeDetails(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []episodeDetail
+	}{
+		{
+			name:  "array of strings",
+			value: `["3","2","1"]`,
+			want:  []episodeDetail{{episodeString: "3"}, {episodeString: "2"}, {episodeString: "1"}},
+		},
+		{
+			name:  "array of numbers",
+			value: `[12.5, 12, 1]`,
+			want:  []episodeDetail{{episodeString: "12.5"}, {episodeString: "12"}, {episodeString: "1"}},
+		},
+		{
+			name:  "object sorted newest first",
+			value: `{"1":{"title":"One","thumbnails":["https://example.com/1.jpg"]},"10":{"name":"Ten"},"2":null}`,
+			want: []episodeDetail{
+				{episodeString: "10", title: "Ten"},
+				{episodeString: "2"},
+				{episodeString: "1", title: "One", thumbnail: "https://example.com/1.jpg"},
+			},
+		},
+		{name: "empty array", value: `[]`, want: []episodeDetail{}},
+		{name: "missing", value: ``, want: nil},
+		{name: "unexpected type", value: `"1,2,3"`, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseEpisodeDetails(json.RawMessage(tt.value)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEpisodeDetails(%s) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkGetEpisodeList(b *testing.B) {
+	for _, count := range []int{24, 1100} {
+		b.Run(fmt.Sprint(count), func(b *testing.B) {
+			body := episodesResponse(b, count)
+			s := newBenchScraper(b, body)
+
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := s.GetEpisodeList(context.Background(), "ReooPAxPMsHM4KPMY", AllEpisodes); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkQueryAPIEpisode(b *testing.B) {
+	body, err := os.ReadFile(filepath.Join("fixtures", "episode.json"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := newBenchScraper(b, body)
+	variables := map[string]interface{}{"showId": "ReooPAxPMsHM4KPMY", "translationType": "sub", "episodeString": "1"}
+
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for b.Loop() {
+		var response struct {
+			Data struct {
+				Episode struct {
+					SourceUrls []struct {
+						SourceUrl  string  `json:"sourceUrl"`
+						Priority   float64 `json:"priority"`
+						SourceName string  `json:"sourceName"`
+						Type       string  `json:"type"`
+					} `json:"sourceUrls"`
+				} `json:"episode"`
+			} `json:"data"`
+		}
+		if err := s.queryAPI(context.Background(), "stream", "query { episode { sourceUrls } }", variables, &response); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseEpisodeDetails(b *testing.B) {
+	list := make([]string, 0, 1100)
+	details := map[string]interface{}{}
+	for i := 1100; i >= 1; i-- {
+		list = append(list, fmt.Sprint(i))
+		details[fmt.Sprint(i)] = map[string]interface{}{"title": fmt.Sprint("Episode ", i), "thumbnail": "https://example.com/thumb.jpg"}
+	}
+	shapes := map[string]interface{}{"array": list, "object": details}
+
+	for _, name := range []string{"array", "object"} {
+		b.Run(name, func(b *testing.B) {
+			value, err := json.Marshal(shapes[name])
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(value)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if got := parseEpisodeDetails(value); len(got) != 1100 {
+					b.Fatalf("parsed %d episodes, want 1100", len(got))
+				}
+			}
+		})
+	}
+}
+
+func TestGetJSONRejectsNon2xx(t *testing.T) {
+	for _, status := range []int{http.StatusNotModified, http.StatusNotFound, http.StatusForbidden} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				w.Write([]byte(`{"links":[]}`))
+			}))
+			defer server.Close()
+
+			s := NewAllanimeScaper()
+			req, err := http.NewRequest("GET", server.URL+"/apivtwo/clock.json", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v map[string]interface{}
+			if err := s.getJSON(context.Background(), "provider", req, &v); err == nil {
+				t.Fatalf("getJSON accepted status %d and decoded %v", status, v)
+			}
+		})
+	}
+}
+
+func TestQueryAPIPostRejection(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantPosts int // POSTs seen over two queries
+	}{
+		// 405 means the mirror never takes POST, so the second query skips it
+		{http.StatusMethodNotAllowed, 1},
+		// 404 only makes the query at hand fall back to GET
+		{http.StatusNotFound, 2},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			var posts, gets int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					posts++
+					w.WriteHeader(tt.status)
+					return
+				}
+				gets++
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"data":{"ok":true}}`))
+			}))
+			defer server.Close()
+
+			s := NewAllanimeScaper()
+			s.allanimeAPI = server.URL + "/api"
+			s.setMirrors(nil)
+			for i := 0; i < 2; i++ {
+				var v struct {
+					Data struct {
+						OK bool `json:"ok"`
+					} `json:"data"`
+				}
+				if err := s.queryAPI(context.Background(), "search", "query { ok }", nil, &v); err != nil {
+					t.Fatalf("queryAPI: %v", err)
+				}
+				if !v.Data.OK {
+					t.Fatalf("queryAPI decoded %+v, want the GET response", v)
+				}
+			}
+			if posts != tt.wantPosts || gets != 2 {
+				t.Errorf("got %d POSTs and %d GETs, want %d and 2", posts, gets, tt.wantPosts)
+			}
+		})
+	}
+}
@@ -1,19 +1,38 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
+	"log/slog"
+	"maps"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
-	"regexp"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/completion"
+	"github.com/wraient/pair-extensions/pkg/doctor"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/extlog"
+	"github.com/wraient/pair-extensions/pkg/hls"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/pagination"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/providerrank"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair-extensions/pkg/respcache"
+	"github.com/wraient/pair-extensions/pkg/subs"
+	"github.com/wraient/pair-extensions/pkg/titlematch"
 	"github.com/wraient/pair/pkg/scraper"
 )
 
@@ -22,6 +41,24 @@ type AllanimeScaper struct {
 	allanimeRef  string
 	allanimeBase string
 	allanimeAPI  string
+	headers      map[string]string
+	translation  string // Translation type used for search, episodes and streams
+	allowAdult   bool   // Whether search and latest include adult shows
+	client       *httpclient.Client
+	retry        httpclient.RetryPolicy   // Retries for search, episode and provider extraction requests
+	priorities   []string                 // Preferred provider domains, best first; LinkPriorities unless configured
+	ranker       *providerrank.Ranker     // Orders stream URLs by provider
+	rawSources   bool                     // Whether stream-url also returns the undecoded API sources
+	provider     string                   // Only return streams from this provider when set
+	validate     bool                     // Whether stream-url drops stream URLs that do not respond
+	cache        *respcache.Cache         // Response cache; nil disables caching
+	cacheTTL     map[string]time.Duration // Freshness per endpoint, see defaultCacheTTLs
+	log          *slog.Logger             // Structured log set by -log-level, shared with the HTTP client
+	mirrors      []mirror                 // Primary deployment followed by fallback mirrors, see setMirrors
+	mirrorIdx    int                      // Index of the mirror requests are sent to
+	mirrorMu     sync.Mutex
+	getOnly      map[string]bool // API URLs of mirrors that rejected GraphQL POSTs, see queryAPI
+	prefetch     *prefetcher     // Streams of the next episodes resolved in the background; nil disables prefetching
 }
 
 // NewAllanimeScaper creates a new instance of the allanime scraper
@@ -38,107 +75,370 @@ func NewAllanimeScaper() *AllanimeScaper {
 		allanimeRef:  allanimeRef,
 		allanimeBase: allanimeBase,
 		allanimeAPI:  allanimeAPI,
+		translation:  "sub",
+		client:       httpclient.New(),
+		retry:        httpclient.DefaultRetryPolicy,
+		priorities:   LinkPriorities,
+		ranker:       newRanker(LinkPriorities, ""),
+		cacheTTL:     maps.Clone(defaultCacheTTLs),
+		log:          extlog.Discard(),
 	}
 }
 
+// newRanker creates a provider ranker preferring the priorities domains, with
+// learned stats stored at path (in memory only when empty)
+func newRanker(priorities []string, path string) *providerrank.Ranker {
+	ranker, err := providerrank.New(priorities, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, starting with an empty provider ranking\n", err)
+		ranker, _ = providerrank.New(priorities, "")
+	}
+	return ranker
+}
+
+// LoadProviderStats switches to the provider ranking persisted for the user
+func (s *AllanimeScaper) LoadProviderStats() error {
+	path, err := providerrank.Path("allanime")
+	if err != nil {
+		return err
+	}
+	s.ranker = newRanker(s.priorities, path)
+	return nil
+}
+
+// Requests per minute AllAnime tolerates, as declared in SourceInfo.RateLimit.
+// rateBurst requests may go out back to back, enough for one stream-url command.
+const (
+	rateLimit = 50
+	rateBurst = 10
+)
+
+// LimitRate throttles requests to AllAnime's own hosts to rateLimit, sharing
+// the budget with every other invocation through a state file in the cache
+// directory. Stream hosts are not AllAnime's and are left unthrottled.
+func (s *AllanimeScaper) LimitRate() {
+	path, err := ratelimit.Path("allanime")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains(), ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// version is the release version reported by extension-info and version.
+// Release builds stamp it with -ldflags "-X main.version=X.Y.Z" so the binary
+// agrees with its manifest and the allanime-vX.Y.Z tag.
+var version = "0.1.0"
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Sources     []SourceInfo            `json:"sources"` // Replaces the embedded sources to carry their lifecycle
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// SourceDiscontinued marks a source whose site is gone for good. Discontinued
+// sources stay listed, with a successor where there is one, so clients can
+// migrate their library instead of the source silently disappearing.
+const SourceDiscontinued = "discontinued"
+
+// SourceInfo extends scraper.SourceInfo with the source's lifecycle
+type SourceInfo struct {
+	scraper.SourceInfo
+	Status    string `json:"status,omitempty"`    // SourceDiscontinued for retired sources, empty while active
+	Successor string `json:"successor,omitempty"` // ID of the source replacing a discontinued one
+}
+
 // GetExtensionInfo returns metadata about this scraper implementation
-func (s *AllanimeScaper) GetExtensionInfo() (scraper.ExtensionInfo, error) {
-	return scraper.ExtensionInfo{
+func (s *AllanimeScaper) GetExtensionInfo() (ExtensionInfo, error) {
+	info := scraper.ExtensionInfo{
 		Name:    "AllAnime",
 		Package: "allanime",
 		Lang:    "en",
-		Version: "0.1.0",
-		NSFW:    false,
-		Sources: []scraper.SourceInfo{
-			{
-				ID:                   "3160569130087668532",
-				Name:                 "AllAnime",
-				BaseURL:              "https://allanime.to",
-				Language:             "en",
-				NSFW:                 false,
-				RateLimit:            50,
-				SupportsLatest:       false,
-				SupportsSearch:       true,
-				SupportsRelatedAnime: false,
+		Version: version,
+		NSFW:    s.allowAdult,
+	}
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: info,
+		Sources:       []SourceInfo{source},
+		Permissions: permissions.Permissions{
+			// The API hosts of every configured mirror, AniSkip for skip-times, Jikan for
+			// filler and recap flags, plus the stream providers, which are probed for
+			// availability and read for HLS playlist durations
+			Network: append(append(s.mirrorHosts(), "api.aniskip.com", "api.jikan.moe"), permissions.Domains(StreamDomains...)...),
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/allanime.json (read)",
+				"$PAIR_DATA_DIR/extensions/allanime/providers.json (read/write)",
+				"$PAIR_CACHE_DIR/extensions/allanime/ratelimit.json (read/write)",
+				"$PAIR_CACHE_DIR/extensions/allanime/responses (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/allanime (write, doctor)",
+				// download -run writes the episode where -out points
+				"-out file (write, download -run)",
 			},
+			Binaries: []string{"ffmpeg (download -run)"},
 		},
 	}, nil
 }
 
 // GetSourceInfo retrieves metadata about a specific source
-func (s *AllanimeScaper) GetSourceInfo() (scraper.SourceInfo, error) {
-	return scraper.SourceInfo{
-		ID:                   "3160569130087668532",
-		Name:                 "AllAnime",
-		BaseURL:              "https://allanime.to",
-		Language:             "en",
-		NSFW:                 false,
-		RateLimit:            50,
-		SupportsLatest:       false,
-		SupportsSearch:       true,
-		SupportsRelatedAnime: false,
+func (s *AllanimeScaper) GetSourceInfo() (SourceInfo, error) {
+	return SourceInfo{
+		SourceInfo: scraper.SourceInfo{
+			ID:                   "3160569130087668532",
+			Name:                 "AllAnime",
+			BaseURL:              "https://allanime.to",
+			Language:             "en",
+			NSFW:                 s.allowAdult,
+			RateLimit:            rateLimit,
+			SupportsLatest:       true,
+			SupportsSearch:       true,
+			SupportsRelatedAnime: true,
+		},
 	}, nil
 }
 
-// decodeProviderID decodes the encoded provider ID to get the actual URL
+// providerIDKey is the byte AllAnime XORs every character of a provider ID with
+const providerIDKey = 0x38
+
+// decodeProviderID decodes the encoded provider ID to get the actual URL. The
+// ID is hex-encoded bytes each XORed with providerIDKey. A pair that is not
+// valid hex is kept as-is so a format change degrades visibly in the URL
+// instead of dropping characters.
 func (s *AllanimeScaper) decodeProviderID(encoded string) string {
-	re := regexp.MustCompile("..")
-	pairs := re.FindAllString(encoded, -1)
+	var result strings.Builder
+	result.Grow(len(encoded) / 2)
 
-	replacements := map[string]string{
-		"01": "9", "08": "0", "05": "=", "0a": "2", "0b": "3", "0c": "4", "07": "?",
-		"00": "8", "5c": "d", "0f": "7", "5e": "f", "17": "/", "54": "l", "09": "1",
-		"48": "p", "4f": "w", "0e": "6", "5b": "c", "5d": "e", "0d": "5", "53": "k",
-		"1e": "&", "5a": "b", "59": "a", "4a": "r", "4c": "t", "4e": "v", "57": "o",
-		"51": "i",
+	for i := 0; i+1 < len(encoded); i += 2 {
+		pair := encoded[i : i+2]
+		b, err := strconv.ParseUint(pair, 16, 8)
+		if err != nil {
+			result.WriteString(pair)
+			continue
+		}
+		result.WriteByte(byte(b) ^ providerIDKey)
 	}
 
-	for i, pair := range pairs {
-		if val, exists := replacements[pair]; exists {
-			pairs[i] = val
+	return strings.ReplaceAll(result.String(), "/clock", "/clock.json")
+}
+
+// extractLinks retrieves the actual stream links from the provider. It also
+// returns the mirror host that answered, or the last one tried on failure,
+// which differs from the configured base host after a failover.
+func (s *AllanimeScaper) extractLinks(ctx context.Context, provider_id string) (map[string]interface{}, string, error) {
+	var videoData map[string]interface{}
+	var host string
+	err := s.withFailover(ctx, func(m mirror) error {
+		host = m.base
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://"+m.base+provider_id, nil)
+		if err != nil {
+			return fmt.Errorf("error creating request: %v", err)
 		}
+		s.setHeaders(req)
+
+		return s.getJSON(ctx, "provider", req, &videoData)
+	})
+	if err != nil {
+		return nil, host, err
 	}
 
-	result := strings.Join(pairs, "")
-	result = strings.ReplaceAll(result, "/clock", "/clock.json")
-	return result
+	return videoData, host, nil
 }
 
-// extractLinks retrieves the actual stream links from the provider
-func (s *AllanimeScaper) extractLinks(provider_id string) (map[string]interface{}, error) {
-	url := "https://" + s.allanimeBase + provider_id
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
+// showsPageSize is how many shows the shows query returns per page
+const showsPageSize = 40
+
+// SearchPage is one page of search results with pagination metadata derived
+// from the shows query. The search command prints it with --page-info, and
+// the plain results otherwise, which is what pair and the workflow decode.
+type SearchPage struct {
+	Results     []Anime `json:"results"`
+	Page        int     `json:"page"`
+	HasNextPage bool    `json:"hasNextPage"`
+	// Shows matching the search across all pages, the larger of the sub and
+	// dub counts with --translation all. A status filter is applied to each
+	// page afterwards, so it counts shows the filter drops as well.
+	TotalResults int `json:"totalResults"`
+}
 
+// SearchAnime searches for anime with the given query and filters
+func (s *AllanimeScaper) SearchAnime(ctx context.Context, query string, page int, filters string) (SearchPage, error) {
+	searchFilters, err := ParseSearchFilters(filters)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+		return SearchPage{}, err
 	}
 
-	req.Header.Set("Referer", s.allanimeRef)
-	req.Header.Set("User-Agent", s.agent)
+	search := map[string]interface{}{
+		"allowAdult":   s.allowAdult,
+		"allowUnknown": false,
+	}
+	if query != "" {
+		search["query"] = query
+	}
+	searchFilters.apply(search)
 
-	resp, err := client.Do(req)
+	shows, err := s.queryShows(ctx, "search", search, page)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
+		return SearchPage{}, err
+	}
+	result := SearchPage{
+		Results:      shows.animes,
+		Page:         page,
+		HasNextPage:  shows.hasNextPage,
+		TotalResults: shows.total,
+	}
+	if result.Results == nil {
+		result.Results = []Anime{}
+	}
+	if searchFilters.Status == "" {
+		return result, nil
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response: %v", err)
+	// AllAnime's SearchInput has no status field, so filter the results instead
+	filtered := []Anime{}
+	for _, anime := range result.Results {
+		if airingStatus(anime.Status) == searchFilters.Status {
+			filtered = append(filtered, anime)
+		}
 	}
+	result.Results = filtered
+	return result, nil
+}
 
-	var videoData map[string]interface{}
-	err = json.Unmarshal(body, &videoData)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing JSON: %v", err)
+// GetLatestUpdates retrieves the most recently updated anime
+func (s *AllanimeScaper) GetLatestUpdates(ctx context.Context, page int) ([]Anime, error) {
+	shows, err := s.queryShows(ctx, "latest", map[string]interface{}{
+		"allowAdult":   s.allowAdult,
+		"allowUnknown": false,
+		"sortBy":       "Recent",
+	}, page)
+	return shows.animes, err
+}
+
+// GetPopularAnime retrieves the currently trending anime from AllAnime's popularity ranking
+func (s *AllanimeScaper) GetPopularAnime(ctx context.Context, page int) ([]Anime, error) {
+	popularGql := `query($type: VaildPopularTypeEnumType!, $size: Int!, $page: Int, $dateRange: Int) {
+		queryPopular(type: $type, size: $size, page: $page, dateRange: $dateRange) {
+			recommendations {
+				anyCard {
+					_id
+					name
+					englishName
+					availableEpisodes
+					status
+					type
+					aniListId
+					malId
+					thumbnail
+					banner
+				}
+			}
+		}
+	}`
+
+	variables := map[string]interface{}{
+		"type":      "anime",
+		"size":      40,
+		"page":      page,
+		"dateRange": 7, // Trending over the last week
 	}
 
-	return videoData, nil
+	var response struct {
+		Data struct {
+			QueryPopular struct {
+				Recommendations []struct {
+					AnyCard *showCard `json:"anyCard"`
+				} `json:"recommendations"`
+			} `json:"queryPopular"`
+		} `json:"data"`
+	}
+
+	if err := s.queryAPI(ctx, "popular", popularGql, variables, &response); err != nil {
+		return nil, err
+	}
+
+	var animes []Anime
+	for _, recommendation := range response.Data.QueryPopular.Recommendations {
+		// Recommendations can point at manga cards, which have no anime card
+		if recommendation.AnyCard == nil || recommendation.AnyCard.ID == "" {
+			continue
+		}
+		animes = append(animes, s.toAnime(*recommendation.AnyCard))
+	}
+
+	return animes, nil
 }
 
-// SearchAnime searches for anime with the given query and filters
-func (s *AllanimeScaper) SearchAnime(query string, page int, filters string) ([]scraper.Anime, error) {
+// showsPage is one page of the shows query
+type showsPage struct {
+	animes      []Anime
+	total       int // Shows across all pages, the most of any translation type queried
+	hasNextPage bool
+}
+
+// queryShows runs the shows query with the given search input and converts the
+// results. With --translation all, the sub and dub queries run concurrently and
+// their results are merged. endpoint selects the cache TTL.
+func (s *AllanimeScaper) queryShows(ctx context.Context, endpoint string, search map[string]interface{}, page int) (showsPage, error) {
+	translations := s.translationTypes()
+	results := make([]showsResponse, len(translations))
+	errs := make([]error, len(translations))
+
+	var wg sync.WaitGroup
+	for i, translation := range translations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.queryShowsFor(ctx, endpoint, search, page, translation)
+		}()
+	}
+	wg.Wait()
+
+	var shows showsPage
+	seen := map[string]bool{}
+	failed := 0
+	for i := range translations {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+		for _, show := range results[i].Edges {
+			if !seen[show.ID] {
+				seen[show.ID] = true
+				shows.animes = append(shows.animes, s.toAnime(show))
+			}
+		}
+
+		total := results[i].PageInfo.Total
+		shows.total = max(shows.total, total)
+		// Without a total, a full page is the only hint that another follows
+		if page*showsPageSize < total || (total == 0 && len(results[i].Edges) == showsPageSize) {
+			shows.hasNextPage = true
+		}
+	}
+
+	// A single failed translation still leaves usable results
+	if failed == len(translations) {
+		return showsPage{}, errs[0]
+	}
+	return shows, nil
+}
+
+// showsResponse is the shows field of the shows query
+type showsResponse struct {
+	Edges    []showCard `json:"edges"`
+	PageInfo struct {
+		Total int `json:"total"`
+	} `json:"pageInfo"`
+}
+
+// queryShowsFor runs the shows query for a single translation type
+func (s *AllanimeScaper) queryShowsFor(ctx context.Context, endpoint string, search map[string]interface{}, page int, translation string) (showsResponse, error) {
 	searchGql := `query($search: SearchInput, $limit: Int, $page: Int, $translationType: VaildTranslationTypeEnumType, $countryOrigin: VaildCountryOriginEnumType) {
 		shows(search: $search, limit: $limit, page: $page, translationType: $translationType, countryOrigin: $countryOrigin) {
 			edges {
@@ -148,164 +448,569 @@ func (s *AllanimeScaper) SearchAnime(query string, page int, filters string) ([]
 				availableEpisodes
 				status
 				type
+				aniListId
+				malId
+				thumbnail
+				banner
+			}
+			pageInfo {
+				total
 			}
 		}
 	}`
 
 	// Prepare the GraphQL variables
 	variables := map[string]interface{}{
-		"search": map[string]interface{}{
-			"allowAdult":   false,
-			"allowUnknown": false,
-			"query":        query,
-		},
-		"limit":           40,
+		"search":          search,
+		"limit":           showsPageSize,
 		"page":            page,
-		"translationType": "sub", // Default to sub
+		"translationType": translation,
 		"countryOrigin":   "ALL",
 	}
 
-	variablesJSON, err := json.Marshal(variables)
-	if err != nil {
-		return nil, fmt.Errorf("error encoding variables: %v", err)
-	}
-
-	reqURL := fmt.Sprintf("%s?variables=%s&query=%s", s.allanimeAPI, url.QueryEscape(string(variablesJSON)), url.QueryEscape(searchGql))
-
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+	var response struct {
+		Data struct {
+			Shows showsResponse `json:"shows"`
+		} `json:"data"`
 	}
-	req.Header.Set("User-Agent", s.agent)
-	req.Header.Set("Referer", s.allanimeRef)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
+	if err := s.queryAPI(ctx, endpoint, searchGql, variables, &response); err != nil {
+		return showsResponse{}, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response: %v", err)
-	}
+	return response.Data.Shows, nil
+}
 
-	// Debug: Uncomment the line below for API debugging
-	// fmt.Fprintf(os.Stderr, "API Response: %s\n", string(body))
+// showCard is the show summary AllAnime returns from list queries
+type showCard struct {
+	ID                string      `json:"_id"`
+	Name              string      `json:"name"`
+	EnglishName       string      `json:"englishName"`
+	AvailableEpisodes interface{} `json:"availableEpisodes"`
+	Status            string      `json:"status"`
+	Type              string      `json:"type"`
+	AniListID         interface{} `json:"aniListId"` // A string or a number, depending on the show
+	MalID             interface{} `json:"malId"`
+	Thumbnail         string      `json:"thumbnail"` // Cover image, a URL or a path on AllAnime's image host
+	Banner            string      `json:"banner"`
+}
 
-	var response struct {
-		Data struct {
-			Shows struct {
-				Edges []struct {
-					ID                string      `json:"_id"`
-					Name              string      `json:"name"`
-					EnglishName       string      `json:"englishName"`
-					AvailableEpisodes interface{} `json:"availableEpisodes"`
-					Status            string      `json:"status"`
-					Type              string      `json:"type"`
-				} `json:"edges"`
-			} `json:"shows"`
-		} `json:"data"`
-	}
+// Anime extends scraper.Anime with the show's IDs on tracking sites, so host
+// apps can sync progress and fetch metadata without matching titles
+type Anime struct {
+	scraper.Anime
+	AniListID  int      `json:"anilist_id,omitempty"`
+	MalID      int      `json:"mal_id,omitempty"`
+	BannerURL  string   `json:"banner_url,omitempty"`    // Wide banner image
+	Duplicates []string `json:"duplicate_ids,omitempty"` // Other AllAnime entries for the same show, folded into this one
+}
 
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing response: %v", err)
+// externalID reads a tracking site ID AllAnime reports as a string or a number
+func externalID(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		id, _ := strconv.Atoi(strings.TrimSpace(v))
+		return id
 	}
+	return 0
+}
 
-	var animes []scraper.Anime
-	for _, show := range response.Data.Shows.Edges {
-		var episodes int
-		if eps, ok := show.AvailableEpisodes.(map[string]interface{}); ok {
-			if subEps, ok := eps["sub"].(float64); ok {
-				episodes = int(subEps)
+// toAnime converts a show summary into an Anime for the selected translation type
+func (s *AllanimeScaper) toAnime(show showCard) Anime {
+	counts := map[string]int{}
+	if eps, ok := show.AvailableEpisodes.(map[string]interface{}); ok {
+		for translation, value := range eps {
+			if count, ok := value.(float64); ok {
+				counts[translation] = int(count)
 			}
 		}
+	}
 
-		alternativeTitles := []string{}
-		if show.EnglishName != "" {
-			alternativeTitles = append(alternativeTitles, show.EnglishName)
-		}
+	episodes := counts[s.translation]
+	subDub := s.translation
+	if s.translation == TranslationAll {
+		// Annotate availability per translation type from the episode counts
+		episodes = max(counts["sub"], counts["dub"])
+		subDub = subDubMarker(availableTranslations(counts))
+	}
+
+	alternativeTitles := []string{}
+	if show.EnglishName != "" {
+		alternativeTitles = append(alternativeTitles, show.EnglishName)
+	}
 
-		animes = append(animes, scraper.Anime{
+	return Anime{
+		Anime: scraper.Anime{
 			ID:                show.ID,
 			Title:             show.Name,
 			AlternativeTitles: alternativeTitles,
 			Status:            show.Status,
 			Episodes:          episodes,
-			SubDub:            "sub",
+			SubDub:            subDub,
+			ThumbnailURL:      imageURL(show.Thumbnail),
+		},
+		AniListID: externalID(show.AniListID),
+		MalID:     externalID(show.MalID),
+		BannerURL: imageURL(show.Banner),
+	}
+}
+
+// imageHost serves the images AllAnime references by path instead of URL
+const imageHost = "https://wp.youtube-anime.com/aln.youtube-anime.com/"
+
+// imageURL resolves a show image to a URL
+func imageURL(image string) string {
+	if image == "" || strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") {
+		return image
+	}
+	return imageHost + strings.TrimPrefix(image, "/")
+}
+
+// availableTranslations lists the translation types with at least one episode
+func availableTranslations(counts map[string]int) []string {
+	var translations []string
+	for _, translation := range TranslationTypes {
+		if counts[translation] > 0 {
+			translations = append(translations, translation)
+		}
+	}
+	return translations
+}
+
+// Search result orderings accepted by --sort
+const (
+	SortRelevance    = "relevance"
+	SortPopularity   = "popularity"
+	SortAlphabetical = "alphabetical"
+)
+
+// SortAnime orders search results in place. Popularity keeps AllAnime's own ordering.
+func SortAnime(animes []Anime, query, mode string) error {
+	switch mode {
+	case SortRelevance:
+		scores := make(map[string]float64, len(animes))
+		for _, anime := range animes {
+			scores[anime.ID] = titlematch.Score(query, anime.Title, anime.AlternativeTitles)
+		}
+		sort.SliceStable(animes, func(i, j int) bool {
+			if scores[animes[i].ID] != scores[animes[j].ID] {
+				return scores[animes[i].ID] > scores[animes[j].ID]
+			}
+			// Equally named entries are usually a show and its specials
+			return animes[i].Episodes > animes[j].Episodes
 		})
+	case SortAlphabetical:
+		sort.SliceStable(animes, func(i, j int) bool {
+			return strings.ToLower(animes[i].Title) < strings.ToLower(animes[j].Title)
+		})
+	case SortPopularity:
+	default:
+		return exterr.New(exterr.InvalidArgument, "invalid sort order %q (valid: relevance, popularity, alphabetical)", mode)
 	}
+	return nil
+}
 
-	return animes, nil
+// CollapseDuplicates folds entries AllAnime lists more than once for the same
+// show into one, identified by their MyAnimeList or AniList ID, or by their
+// titles when they have neither. The entry with the most episodes is kept, at
+// the position of the highest ranked one, listing the others in Duplicates.
+func CollapseDuplicates(animes []Anime) []Anime {
+	groups := map[string]int{} // Show key to index in collapsed
+	collapsed := []Anime{}
+	for _, anime := range animes {
+		key := duplicateKey(anime)
+		i, ok := groups[key]
+		if !ok {
+			groups[key] = len(collapsed)
+			collapsed = append(collapsed, anime)
+			continue
+		}
+
+		kept := &collapsed[i]
+		if anime.Episodes > kept.Episodes {
+			anime.Duplicates, kept.Duplicates = kept.Duplicates, nil
+			*kept, anime = anime, *kept
+		}
+		kept.Duplicates = append(kept.Duplicates, anime.ID)
+		kept.Duplicates = append(kept.Duplicates, anime.Duplicates...)
+	}
+	return collapsed
 }
 
-// GetEpisodeList retrieves the list of episodes for an anime
-func (s *AllanimeScaper) GetEpisodeList(animeID string) ([]scraper.Episode, error) {
-	episodesListGql := `query ($showId: String!) { show( _id: $showId ) { _id availableEpisodesDetail }}`
+// duplicateKey identifies the show an entry belongs to
+func duplicateKey(anime Anime) string {
+	switch {
+	case anime.MalID != 0:
+		return fmt.Sprintf("mal:%d", anime.MalID)
+	case anime.AniListID != 0:
+		return fmt.Sprintf("anilist:%d", anime.AniListID)
+	}
+	key := "title:" + titlematch.Normalize(anime.Title)
+	for _, title := range anime.AlternativeTitles {
+		key += "|" + titlematch.Normalize(title)
+	}
+	return key
+}
 
-	variables := map[string]interface{}{
-		"showId": animeID,
+// commands lists the commands with their descriptions, for the usage message
+// and shell completion
+var commands = []completion.Command{
+	{Name: "completion", Description: "Print a bash, zsh or fish completion script, e.g. source <(allanime completion bash)."},
+	{Name: "doctor", Description: "Diagnose DNS, TLS, proxy, clock and storage problems."},
+	{Name: "download", Description: "Print ffmpeg and yt-dlp commands that save an episode (-run runs ffmpeg)."},
+	{Name: "details", Description: "Get description, genres, studios, score and images for an anime."},
+	{Name: "episodes", Description: "Get the list of episodes for an anime (-limit splits it into pages)."},
+	{Name: "episodes-meta", Description: "Get titles, thumbnails, durations and air dates for a range of episodes."},
+	{Name: "extension-info", Description: "Get information about a specific extension."},
+	{Name: "genres", Description: "List the genres, types, seasons, statuses and sort orders the -filters JSON accepts."},
+	{Name: "latest", Description: "Get the most recently updated anime."},
+	{Name: "list-sources", Description: "List all available anime video sources."},
+	{Name: "popular", Description: "Get the currently trending anime."},
+	{Name: "related", Description: "Get sequels, prequels, side stories and other related anime."},
+	{Name: "providers stats", Description: "Show the learned stream provider ranking (-reset clears it)."},
+	{Name: "repl", Description: "Read commands from stdin, one per line, reusing connections and caches between them."},
+	{Name: "search", Description: "Search for anime on a source."},
+	{Name: "skip-times", Description: "Get the opening, ending and recap timestamps of an episode from AniSkip."},
+	{Name: "source-info", Description: "Get information about a specific anime video source."},
+	{Name: "stream-url", Description: "Get the direct video stream URL for an anime episode (-episodes for a range)."},
+	{Name: "version", Description: "Print the extension version."},
+}
+
+// splitCommand splits command-line arguments into the command, its
+// subcommand if it takes one, and the flags that follow. A source ID between
+// the command and its flags, as pair passes it, is turned into -source.
+func splitCommand(args []string) (string, string, []string) {
+	command, args := args[0], args[1:]
+	// Some commands take a subcommand before their flags
+	subcommand := ""
+	if command == "providers" || command == "completion" {
+		subcommand, args = cli.SplitSourceID(args)
+		return command, subcommand, args
+	}
+	if sourceID, flagArgs := cli.SplitSourceID(args); sourceID != "" {
+		args = append([]string{"-source=" + sourceID}, flagArgs...)
 	}
+	return command, subcommand, args
+}
 
-	variablesJSON, err := json.Marshal(variables)
-	if err != nil {
-		return nil, fmt.Errorf("error encoding variables: %v", err)
+// TranslationTypes lists the translation types AllAnime reports per episode
+var TranslationTypes = []string{"sub", "dub", "raw"}
+
+// TranslationAll selects sub and dub at once: search merges both, and streams
+// fall back to dub when an episode has no sub
+const TranslationAll = "all"
+
+// SetTranslation selects the translation type used for search, episodes and streams
+func (s *AllanimeScaper) SetTranslation(translation string) error {
+	switch translation {
+	case "sub", "dub", "raw", TranslationAll:
+		s.translation = translation
+		return nil
+	default:
+		return exterr.New(exterr.InvalidArgument, "invalid translation type %q (valid: sub, dub, raw, all)", translation)
+	}
+}
+
+// SetAllowAdult opts into adult results, which only builds with the nsfw tag permit
+func (s *AllanimeScaper) SetAllowAdult(allow bool) error {
+	if allow && !adultContentBuild {
+		return exterr.New(exterr.Unsupported, "adult content is not available in this build (rebuild with -tags nsfw)")
+	}
+	s.allowAdult = allow
+	return nil
+}
+
+// translationTypes returns the translation types to query for the selected translation
+func (s *AllanimeScaper) translationTypes() []string {
+	if s.translation == TranslationAll {
+		return []string{"sub", "dub"}
 	}
+	return []string{s.translation}
+}
+
+// Episode extends scraper.Episode with the translation types the episode is available in
+// and the display metadata from AllAnime's episode info query
+type Episode struct {
+	scraper.Episode
+	Thumbnail string   `json:"thumbnail_url,omitempty"` // Episode thumbnail when AllAnime provides one
+	Languages []string `json:"languages,omitempty"`     // Any of "sub", "dub" or "raw"
+	SubDub    string   `json:"sub_dub,omitempty"`       // "sub", "dub", or "both"
+
+	Description     string `json:"description,omitempty"`
+	AirDate         int64  `json:"air_date,omitempty"`        // Unix timestamp of the first upload in any translation
+	DurationSeconds int    `json:"durationSeconds,omitempty"` // Episode length from AllAnime's episode metadata
 
-	reqURL := fmt.Sprintf("%s?variables=%s&query=%s", s.allanimeAPI, url.QueryEscape(string(variablesJSON)), url.QueryEscape(episodesListGql))
+	Filler bool `json:"filler"` // Anime-original episode outside the source material, per MyAnimeList
+	Recap  bool `json:"recap"`  // Episode recapping earlier ones, per MyAnimeList
+}
+
+// EpisodeRange bounds an episode list by episode number, both ends inclusive
+type EpisodeRange struct {
+	From float64
+	To   float64
+}
+
+// AllEpisodes is the EpisodeRange covering every episode
+var AllEpisodes = EpisodeRange{From: math.Inf(-1), To: math.Inf(1)}
+
+// filter returns the episodes within the range, keeping their order
+func (r EpisodeRange) filter(episodes []Episode) []Episode {
+	filtered := []Episode{}
+	for _, episode := range episodes {
+		if episode.EpisodeNumber >= r.From && episode.EpisodeNumber <= r.To {
+			filtered = append(filtered, episode)
+		}
+	}
+	return filtered
+}
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+// GetEpisodeList retrieves the list of episodes for an anime within r
+func (s *AllanimeScaper) GetEpisodeList(ctx context.Context, animeID string, r EpisodeRange) ([]Episode, error) {
+	episodes, err := s.listEpisodes(ctx, animeID)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+		return nil, err
 	}
-	req.Header.Set("User-Agent", s.agent)
-	req.Header.Set("Referer", s.allanimeRef)
+	episodes = r.filter(episodes)
+	s.attachEpisodeMeta(ctx, animeID, episodes)
+	s.attachFillers(ctx, animeID, episodes)
+	return episodes, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// EpisodePage is one page of an anime's episodes, newest first
+type EpisodePage struct {
+	Episodes []Episode `json:"episodes"`
+	pagination.Info
+}
+
+// GetEpisodePage retrieves up to limit of the episodes of an anime within r,
+// starting at offset. Episode metadata is only looked up for the episodes on
+// the page, which keeps long lists cheap to page through.
+func (s *AllanimeScaper) GetEpisodePage(ctx context.Context, animeID string, r EpisodeRange, offset, limit int) (EpisodePage, error) {
+	episodes, err := s.listEpisodes(ctx, animeID)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
+		return EpisodePage{}, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	pageEpisodes, info, err := pagination.SliceOffset(r.filter(episodes), offset, limit)
 	if err != nil {
-		return nil, fmt.Errorf("error reading response: %v", err)
+		return EpisodePage{}, exterr.From(err, exterr.InvalidArgument)
+	}
+	s.attachEpisodeMeta(ctx, animeID, pageEpisodes)
+	s.attachFillers(ctx, animeID, pageEpisodes)
+	return EpisodePage{Episodes: pageEpisodes, Info: info}, nil
+}
+
+// listEpisodes retrieves the episodes of an anime without their metadata
+func (s *AllanimeScaper) listEpisodes(ctx context.Context, animeID string) ([]Episode, error) {
+	episodesListGql := `query ($showId: String!) { show( _id: $showId ) { _id availableEpisodesDetail }}`
+
+	variables := map[string]interface{}{
+		"showId": animeID,
 	}
 
 	var response struct {
 		Data struct {
 			Show struct {
-				ID                      string                 `json:"_id"`
-				AvailableEpisodesDetail map[string]interface{} `json:"availableEpisodesDetail"`
+				ID                      string                     `json:"_id"`
+				AvailableEpisodesDetail map[string]json.RawMessage `json:"availableEpisodesDetail"`
 			} `json:"show"`
 		} `json:"data"`
 	}
 
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing response: %v", err)
+	if err := s.queryAPI(ctx, "episodes", episodesListGql, variables, &response); err != nil {
+		return nil, err
+	}
+
+	// Record which translation types each episode is available in
+	details := map[string][]episodeDetail{}
+	languages := map[string][]string{}
+	for _, translationType := range TranslationTypes {
+		details[translationType] = parseEpisodeDetails(response.Data.Show.AvailableEpisodesDetail[translationType])
+		for _, detail := range details[translationType] {
+			languages[detail.episodeString] = append(languages[detail.episodeString], translationType)
+		}
 	}
 
-	var episodes []scraper.Episode
-	if eps, ok := response.Data.Show.AvailableEpisodesDetail["sub"].([]interface{}); ok {
-		for _, ep := range eps {
-			if epNum, err := strconv.ParseFloat(fmt.Sprintf("%v", ep), 64); err == nil {
-				episodes = append(episodes, scraper.Episode{
+	// Merge sub and dub episodes, preferring the metadata of the selected
+	// translation; raw episodes are only listed when raw is selected
+	merged := map[string]episodeDetail{}
+	for _, translationType := range []string{s.translation, "sub", "dub"} {
+		for _, detail := range details[translationType] {
+			if _, ok := merged[detail.episodeString]; !ok {
+				merged[detail.episodeString] = detail
+			}
+		}
+	}
+
+	var episodes []Episode
+	for episodeString, detail := range merged {
+		if epNum, err := strconv.ParseFloat(episodeString, 64); err == nil {
+			episodes = append(episodes, Episode{
+				Episode: scraper.Episode{
 					ID:            animeID,
+					Name:          detail.title,
 					EpisodeNumber: epNum,
-					DateUpload:    time.Now().Unix(), // We don't have actual upload dates
-				})
-			}
+				},
+				Thumbnail: detail.thumbnail,
+				Languages: languages[episodeString],
+				SubDub:    subDubMarker(languages[episodeString]),
+			})
 		}
 	}
 
+	// Keep the newest-first order AllAnime returns
+	sort.Slice(episodes, func(i, j int) bool {
+		return episodes[i].EpisodeNumber > episodes[j].EpisodeNumber
+	})
+
 	return episodes, nil
 }
 
-// LinkPriorities defines the priority order for video sources
+// attachEpisodeMeta fills in metadata from AllAnime's episode info query. The
+// metadata is optional, so a failed lookup leaves the episodes unchanged.
+func (s *AllanimeScaper) attachEpisodeMeta(ctx context.Context, animeID string, episodes []Episode) {
+	if len(episodes) == 0 {
+		return
+	}
+
+	// Episodes are sorted newest first
+	metas, err := s.GetEpisodesMeta(ctx, animeID, episodes[len(episodes)-1].EpisodeNumber, episodes[0].EpisodeNumber)
+	if err != nil {
+		return
+	}
+
+	byNumber := make(map[float64]EpisodeMeta, len(metas))
+	for _, meta := range metas {
+		byNumber[meta.EpisodeNumber] = meta
+	}
+	for i := range episodes {
+		meta, ok := byNumber[episodes[i].EpisodeNumber]
+		if !ok {
+			continue
+		}
+		if episodes[i].Name == "" {
+			episodes[i].Name = meta.Title
+		}
+		if episodes[i].Thumbnail == "" {
+			episodes[i].Thumbnail = meta.Thumbnail
+		}
+		episodes[i].Description = meta.Description
+		episodes[i].AirDate = meta.AirDate
+		// With sub and dub selected, the episode is out once either is
+		episodes[i].DateUpload = meta.uploadDate(s.translationTypes())
+		if episodes[i].DateUpload == 0 {
+			episodes[i].DateUpload = meta.AirDate
+		}
+		episodes[i].DurationSeconds = meta.Duration
+	}
+}
+
+// subDubMarker summarizes the translation types of an episode as "sub", "dub" or "both"
+func subDubMarker(languages []string) string {
+	hasSub := slices.Contains(languages, "sub")
+	hasDub := slices.Contains(languages, "dub")
+	switch {
+	case hasSub && hasDub:
+		return "both"
+	case hasDub:
+		return "dub"
+	case hasSub:
+		return "sub"
+	}
+	return ""
+}
+
+// episodeDetail holds an episode string and any metadata AllAnime sent with it
+type episodeDetail struct {
+	episodeString string
+	title         string
+	thumbnail     string
+}
+
+// episodeKey is an episode number the API sends as either a JSON string or number
+type episodeKey string
+
+func (e *episodeKey) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*e = episodeKey(value)
+		return nil
+	}
+	*e = episodeKey(data)
+	return nil
+}
+
+// parseEpisodeDetails reads one translation type of availableEpisodesDetail, which is
+// either an array of episode strings or an object mapping episode strings to metadata.
+// The common array shape is decoded into typed values, which allocates far less than
+// decoding long episode lists into interface{} values.
+func parseEpisodeDetails(value json.RawMessage) []episodeDetail {
+	var details []episodeDetail
+
+	var list []episodeKey
+	var eps map[string]interface{}
+	switch {
+	case json.Unmarshal(value, &list) == nil:
+		details = make([]episodeDetail, 0, len(list))
+		for _, ep := range list {
+			details = append(details, episodeDetail{episodeString: string(ep)})
+		}
+
+	case json.Unmarshal(value, &eps) == nil:
+		for episodeString, meta := range eps {
+			detail := episodeDetail{episodeString: episodeString}
+			if fields, ok := meta.(map[string]interface{}); ok {
+				detail.title = firstString(fields, "title", "name", "notes")
+				detail.thumbnail = firstString(fields, "thumbnail", "thumbnails")
+			}
+			details = append(details, detail)
+		}
+
+		// Map iteration order is random; keep the newest-first order used by the array shape
+		sort.Slice(details, func(i, j int) bool {
+			a, _ := strconv.ParseFloat(details[i].episodeString, 64)
+			b, _ := strconv.ParseFloat(details[j].episodeString, 64)
+			return a > b
+		})
+	}
+
+	return details
+}
+
+// firstString returns the first non-empty string found under the given keys,
+// taking the first element when the value is an array of strings
+func firstString(fields map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch value := fields[key].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case []interface{}:
+			for _, item := range value {
+				if str, ok := item.(string); ok && str != "" {
+					return str
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// StreamDomains lists the hosts the stream providers serve files and
+// playlists from
+var StreamDomains = []string{
+	"sharepoint.com", "wixmp.com", "dropbox.com", "dropboxusercontent.com",
+	"wetransfer.com", "gogoanime.com", "fast4speed.rsvp",
+}
+
+// LinkPriorities defines the static preference order of stream providers; the
+// provider ranker combines it with learned success rates and latencies
 var LinkPriorities = []string{
 	"sharepoint.com",
 	"wixmp.com",
@@ -314,43 +1019,78 @@ var LinkPriorities = []string{
 	"gogoanime.com",
 }
 
-func (s *AllanimeScaper) GetVideoList(animeID string, episodeNumber float64) (scraper.VideoResponse, error) {
-	query := `query($showId:String!,$translationType:VaildTranslationTypeEnumType!,$episodeString:String!){episode(showId:$showId,translationType:$translationType,episodeString:$episodeString){episodeString sourceUrls}}`
+// Video extends scraper.Video with mirror URLs serving the same stream from other servers
+type Video struct {
+	scraper.Video
+	Mirrors         []string `json:"mirrors,omitempty"`         // Fallback URLs in priority order
+	DurationSeconds int      `json:"durationSeconds,omitempty"` // Episode length read from the HLS playlist
+	Bandwidth       int      `json:"bandwidth,omitempty"`       // Peak bits per second of an HLS variant
+	Provider        string   `json:"provider,omitempty"`        // Host serving the stream, e.g. "wixmp" or "sharepoint"
+	Source          string   `json:"source,omitempty"`          // AllAnime source name, e.g. "Default" or "Yt-mp4"
+}
 
-	variables := map[string]interface{}{
-		"showId":          animeID,
-		"translationType": "sub",
-		"episodeString":   fmt.Sprintf("%v", episodeNumber),
-	}
+// streamInfo is a resolved stream URL before streams are grouped into Videos
+type streamInfo struct {
+	url       string
+	quality   string
+	source    string // AllAnime source name the stream was extracted from, e.g. "Yt-mp4"
+	priority  float64
+	bandwidth int
+}
 
-	variablesJSON, err := json.Marshal(variables)
-	if err != nil {
-		return scraper.VideoResponse{}, fmt.Errorf("error encoding variables: %v", err)
-	}
+// VideoResponse mirrors scraper.VideoResponse using the extended Video type
+type VideoResponse struct {
+	Streams    []Video     `json:"streams"`
+	Subtitles  []Subtitle  `json:"subtitles"`
+	RawSources []RawSource `json:"raw_sources,omitempty"` // Only with --raw-sources
+	Warnings   []Warning   `json:"warnings"`              // Sources that failed while others succeeded
+}
 
-	values := url.Values{}
-	values.Set("query", query)
-	values.Set("variables", string(variablesJSON))
+// Warning reports an episode source whose streams could not be extracted, so
+// frontends can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // AllAnime source name, e.g. "Default" or "S-mp4"
+	Provider string `json:"provider,omitempty"` // Host the links were requested from
+	Reason   string `json:"reason"`
+}
 
-	reqURL := fmt.Sprintf("%s?%s", s.allanimeAPI, values.Encode())
+// Subtitle extends scraper.Track with what a player needs to pick and load an external track
+type Subtitle struct {
+	scraper.Track
+	Label   string      `json:"label,omitempty"`   // Display name, e.g. "English"
+	Format  subs.Format `json:"format,omitempty"`  // vtt, srt or ass, guessed from the URL
+	Default bool        `json:"default,omitempty"` // Whether the provider marks the track as default
+}
 
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return scraper.VideoResponse{}, fmt.Errorf("error creating request: %v", err)
-	}
-	req.Header.Set("User-Agent", s.agent)
-	req.Header.Set("Referer", s.allanimeRef)
+// RawSource is an episode source as the API returns it, before provider extraction
+type RawSource struct {
+	SourceURL  string  `json:"sourceUrl"`
+	SourceName string  `json:"sourceName"`
+	Priority   float64 `json:"priority"`
+	Type       string  `json:"type"`
+	Decoded    string  `json:"decoded,omitempty"` // Provider URL of "--" prefixed sources
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return scraper.VideoResponse{}, fmt.Errorf("error making request: %v", err)
+func (s *AllanimeScaper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	var err error
+	for _, translation := range s.translationTypes() {
+		var videos VideoResponse
+		videos, err = s.getVideoList(ctx, animeID, episodeNumber, translation)
+		if err == nil {
+			return videos, nil
+		}
 	}
-	defer resp.Body.Close()
+	return VideoResponse{}, err
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return scraper.VideoResponse{}, fmt.Errorf("error reading response: %v", err)
+// getVideoList retrieves the streams of an episode in a single translation type
+func (s *AllanimeScaper) getVideoList(ctx context.Context, animeID string, episodeNumber float64, translation string) (VideoResponse, error) {
+	query := `query($showId:String!,$translationType:VaildTranslationTypeEnumType!,$episodeString:String!){episode(showId:$showId,translationType:$translationType,episodeString:$episodeString){episodeString sourceUrls}}`
+
+	variables := map[string]interface{}{
+		"showId":          animeID,
+		"translationType": translation,
+		"episodeString":   fmt.Sprintf("%v", episodeNumber),
 	}
 
 	var response struct {
@@ -366,132 +1106,456 @@ func (s *AllanimeScaper) GetVideoList(animeID string, episodeNumber float64) (sc
 		} `json:"data"`
 	}
 
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return scraper.VideoResponse{}, fmt.Errorf("error parsing response: %v", err)
-	}
-
-	type streamInfo struct {
-		url      string
-		quality  string
-		priority int
+	if err := s.queryAPI(ctx, "stream", query, variables, &response); err != nil {
+		return VideoResponse{}, err
 	}
 
 	var streams []streamInfo
+	var rawSources []RawSource
+	warnings := []Warning{}
+	subtitles := []Subtitle{}
+	seenSubtitles := map[string]bool{}
 
 	// Process all sources
 	for _, source := range response.Data.Episode.SourceUrls {
+		if s.rawSources {
+			raw := RawSource{
+				SourceURL:  source.SourceUrl,
+				SourceName: source.SourceName,
+				Priority:   source.Priority,
+				Type:       source.Type,
+			}
+			if strings.HasPrefix(source.SourceUrl, "--") {
+				raw.Decoded = s.decodeProviderID(source.SourceUrl[2:])
+			}
+			rawSources = append(rawSources, raw)
+		}
+
 		if strings.HasPrefix(source.SourceUrl, "--") {
 			decodedProviderID := s.decodeProviderID(source.SourceUrl[2:])
-			extractedLinks, err := s.extractLinks(decodedProviderID)
+			extractedLinks, host, err := s.extractLinks(ctx, decodedProviderID)
 			if err != nil {
+				s.log.Warn("provider failed", "source", source.SourceName, "host", host, "provider_path", decodedProviderID, "error", err.Error())
+				warnings = append(warnings, Warning{Source: source.SourceName, Provider: host, Reason: err.Error()})
 				continue
 			}
 
+			linkCount := len(streams)
 			if linksInterface, ok := extractedLinks["links"].([]interface{}); ok {
 				for _, linkInterface := range linksInterface {
 					if linkMap, ok := linkInterface.(map[string]interface{}); ok {
+						for _, subtitle := range parseSubtitles(linkMap["subtitles"]) {
+							if !seenSubtitles[subtitle.URL] {
+								seenSubtitles[subtitle.URL] = true
+								subtitles = append(subtitles, subtitle)
+							}
+						}
+
 						if link, ok := linkMap["link"].(string); ok {
 							quality, _ := linkMap["resolutionStr"].(string)
-							var finalURL string
 							if strings.HasPrefix(link, "--") {
-								decodedLink := s.decodeProviderID(link[2:])
-								if !strings.HasPrefix(decodedLink, "http") {
-									finalURL = "https://" + decodedLink
-								} else {
-									finalURL = decodedLink
-								}
-							} else {
-								finalURL = link
+								link = s.decodeProviderID(link[2:])
 							}
-
-							// Check priority based on domain
-							priority := -1
-							for i, domain := range LinkPriorities {
-								if strings.Contains(finalURL, domain) {
-									priority = len(LinkPriorities) - i
-									break
-								}
+							// Decoded links often come without a scheme
+							finalURL, err := httpclient.NormalizeURL(link)
+							if err != nil {
+								continue
 							}
 
 							streams = append(streams, streamInfo{
-								url:      finalURL,
-								quality:  quality,
-								priority: priority,
+								url:     finalURL,
+								quality: quality,
+								source:  source.SourceName,
 							})
 						}
 					}
 				}
 			}
-		} else if strings.HasPrefix(source.SourceUrl, "https://") {
-			// Check priority based on domain
-			priority := -1
-			for i, domain := range LinkPriorities {
-				if strings.Contains(source.SourceUrl, domain) {
-					priority = len(LinkPriorities) - i
-					break
-				}
+			s.log.Debug("provider decoded", "source", source.SourceName, "host", host, "provider_path", decodedProviderID, "links", len(streams)-linkCount)
+			if len(streams) == linkCount {
+				warnings = append(warnings, Warning{Source: source.SourceName, Provider: host, Reason: "provider returned no stream links"})
 			}
-
+		} else if strings.HasPrefix(source.SourceUrl, "https://") {
 			streams = append(streams, streamInfo{
-				url:      source.SourceUrl,
-				quality:  source.SourceName,
-				priority: priority,
+				url:     source.SourceUrl,
+				quality: source.SourceName,
+				source:  source.SourceName,
 			})
 		}
 	}
 
-	// Sort streams by priority (highest first)
-	sort.Slice(streams, func(i, j int) bool {
+	// Probe one stream per provider the ranking has no recent outcome for,
+	// then sort by the combined score (highest first). The master playlist
+	// fetches below record outcomes too; everything learned is saved once at
+	// the end.
+	urls := make([]string, len(streams))
+	for i, stream := range streams {
+		urls[i] = stream.url
+	}
+	s.ranker.ProbeStale(ctx, s.client, urls, s.streamHeaders())
+	defer s.saveRanking()
+	for i := range streams {
+		streams[i].priority = s.ranker.Score(streams[i].url)
+	}
+	sort.SliceStable(streams, func(i, j int) bool {
 		return streams[i].priority > streams[j].priority
 	})
 
-	// Convert to scraper.Video format
-	var result []scraper.Video
+	if s.provider != "" {
+		var selected []streamInfo
+		available := []string{}
+		for _, stream := range streams {
+			if s.matchesProvider(stream) {
+				selected = append(selected, stream)
+			} else if name := s.providerName(stream.url); !slices.Contains(available, name) {
+				available = append(available, name)
+			}
+		}
+		if len(selected) == 0 {
+			return VideoResponse{}, exterr.New(exterr.NotFound, "no streams from provider %q (available: %s)", s.provider, strings.Join(available, ", "))
+		}
+		streams = selected
+	}
+
+	if s.validate {
+		var dropped []Warning
+		streams, dropped = s.validateStreams(ctx, streams)
+		warnings = append(warnings, dropped...)
+	}
+
+	// Master playlists become one stream per variant so each quality can be picked
+	var expanded []streamInfo
 	for _, stream := range streams {
-		result = append(result, scraper.Video{
-			ID:       animeID,
-			Quality:  stream.quality,
-			VideoURL: stream.url,
+		expanded = append(expanded, s.hlsVariants(ctx, stream)...)
+	}
+	streams = expanded
+
+	// Convert to Video format, grouping streams of the same quality as mirrors
+	// of the highest priority one. HLS variants stay separate from direct files
+	// so their bandwidth is kept.
+	var result []Video
+	byQuality := map[string]int{}
+	for _, stream := range streams {
+		key := strings.ToLower(strings.TrimSpace(stream.quality))
+		if key != "" && strings.Contains(stream.url, ".m3u8") {
+			key += " hls"
+		}
+		if i, ok := byQuality[key]; ok && key != "" {
+			result[i].Mirrors = append(result[i].Mirrors, stream.url)
+			continue
+		}
+
+		byQuality[key] = len(result)
+		result = append(result, Video{
+			Video: scraper.Video{
+				ID:       animeID,
+				Quality:  stream.quality,
+				VideoURL: stream.url,
+				Headers:  s.streamHeaders(),
+			},
+			Bandwidth: stream.bandwidth,
+			Provider:  s.providerName(stream.url),
+			Source:    stream.source,
 		})
 	}
 
 	if len(result) == 0 {
-		return scraper.VideoResponse{}, fmt.Errorf("no valid streams found")
+		// The raw sources are what is needed to debug a failed extraction
+		if len(rawSources) > 0 {
+			return VideoResponse{Streams: []Video{}, Subtitles: subtitles, RawSources: rawSources, Warnings: warnings}, nil
+		}
+		if len(warnings) > 0 {
+			reasons := make([]string, len(warnings))
+			for i, warning := range warnings {
+				reasons[i] = fmt.Sprintf("%s: %s", warning.Source, warning.Reason)
+			}
+			return VideoResponse{}, exterr.New(exterr.Upstream, "no valid streams found (%s)", strings.Join(reasons, "; "))
+		}
+		return VideoResponse{}, exterr.New(exterr.NotFound, "no valid streams found")
+	}
+
+	// Every stream is the same episode, so one HLS playlist gives the length for all of them
+	duration := s.streamDuration(ctx, result)
+	for i := range result {
+		result[i].DurationSeconds = duration
 	}
 
-	return scraper.VideoResponse{
-		Streams: result,
+	return VideoResponse{
+		Streams:    result,
+		Subtitles:  subtitles,
+		RawSources: rawSources,
+		Warnings:   warnings,
 	}, nil
 }
 
+// saveRanking writes what the provider ranking learned, warning on stderr
+// when it cannot
+func (s *AllanimeScaper) saveRanking() {
+	if err := s.ranker.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// validateStreams probes every stream URL concurrently and returns the ones
+// that respond, in their original order, with a warning for each dead link.
+// The outcomes also feed the provider ranking.
+func (s *AllanimeScaper) validateStreams(ctx context.Context, streams []streamInfo) ([]streamInfo, []Warning) {
+	alive := make([]bool, len(streams))
+	var wg sync.WaitGroup
+	for i, stream := range streams {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			alive[i] = s.ranker.Probe(ctx, s.client, u, s.streamHeaders())
+		}(i, stream.url)
+	}
+	wg.Wait()
+
+	var valid []streamInfo
+	var warnings []Warning
+	for i, stream := range streams {
+		if alive[i] {
+			valid = append(valid, stream)
+			continue
+		}
+		host := stream.url
+		if u, err := url.Parse(stream.url); err == nil {
+			host = u.Host
+		}
+		warnings = append(warnings, Warning{Source: stream.source, Provider: host, Reason: "stream URL did not respond, dropped: " + stream.url})
+	}
+	return valid, warnings
+}
+
+// parseSubtitles reads the subtitle tracks a provider lists next to a stream link
+func parseSubtitles(value interface{}) []Subtitle {
+	entries, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var subtitles []Subtitle
+	for _, entry := range entries {
+		track, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		src, _ := track["src"].(string)
+		if src == "" {
+			src, _ = track["url"].(string)
+		}
+		if src == "" {
+			continue
+		}
+		if strings.HasPrefix(src, "//") {
+			src = "https:" + src
+		}
+
+		lang, _ := track["lang"].(string)
+		label, _ := track["label"].(string)
+		subtitle := Subtitle{
+			Track:  scraper.Track{URL: src, Lang: lang},
+			Label:  label,
+			Format: subtitleFormat(src),
+		}
+		switch isDefault := track["default"].(type) {
+		case bool:
+			subtitle.Default = isDefault
+		case string:
+			subtitle.Default = isDefault == "default" || isDefault == "true"
+		}
+		subtitles = append(subtitles, subtitle)
+	}
+	return subtitles
+}
+
+// subtitleFormat guesses a subtitle format from the file extension in its URL
+func subtitleFormat(rawURL string) subs.Format {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".vtt":
+		return subs.FormatVTT
+	case ".srt":
+		return subs.FormatSRT
+	case ".ass", ".ssa":
+		return subs.FormatASS
+	}
+	return subs.FormatUnknown
+}
+
+// providerName returns the short name of the provider serving a stream URL:
+// the preferred domain or host without its top-level domain, e.g. "wixmp"
+func (s *AllanimeScaper) providerName(streamURL string) string {
+	labels := strings.Split(s.ranker.Provider(streamURL), ".")
+	if len(labels) >= 2 {
+		return labels[len(labels)-2]
+	}
+	return labels[0]
+}
+
+// matchesProvider reports whether a stream comes from the provider selected
+// with --provider, matched against the provider name, the AllAnime source name
+// (so "yt" selects "Yt-mp4") and the stream host
+func (s *AllanimeScaper) matchesProvider(stream streamInfo) bool {
+	want := strings.ToLower(s.provider)
+	if s.providerName(stream.url) == want || strings.HasPrefix(strings.ToLower(stream.source), want) {
+		return true
+	}
+	if u, err := url.Parse(stream.url); err == nil {
+		return strings.Contains(strings.ToLower(u.Hostname()), want)
+	}
+	return false
+}
+
+// hlsVariants expands a stream pointing at an HLS master playlist into one
+// stream per variant, highest bandwidth first. Other streams, and master
+// playlists that cannot be fetched, are returned unchanged.
+func (s *AllanimeScaper) hlsVariants(ctx context.Context, stream streamInfo) []streamInfo {
+	if !strings.Contains(stream.url, ".m3u8") {
+		return []streamInfo{stream}
+	}
+
+	start := time.Now()
+	playlist, err := hls.Fetch(ctx, s.client, stream.url, s.streamHeaders())
+	s.ranker.RecordFetch(ctx, stream.url, err, time.Since(start))
+	if err != nil || len(playlist.Variants) == 0 {
+		return []streamInfo{stream}
+	}
+
+	variants := append([]hls.Variant{}, playlist.Variants...)
+	sort.SliceStable(variants, func(i, j int) bool {
+		return variants[i].Bandwidth > variants[j].Bandwidth
+	})
+
+	streams := make([]streamInfo, 0, len(variants))
+	for _, variant := range variants {
+		quality := variant.Quality()
+		if quality == "" {
+			quality = stream.quality
+		}
+		streams = append(streams, streamInfo{
+			url:       variant.URL,
+			quality:   quality,
+			source:    stream.source,
+			priority:  stream.priority,
+			bandwidth: variant.Bandwidth,
+		})
+	}
+	return streams
+}
+
+// domains returns the hosts the source contacts for search, episodes and
+// stream extraction, following the configured base host, API URL and mirrors
+func (s *AllanimeScaper) domains() []string {
+	mirrors := s.mirrors
+	if len(mirrors) == 0 {
+		mirrors = []mirror{{base: s.allanimeBase, api: s.allanimeAPI}}
+	}
+	domains := []string{}
+	for _, m := range mirrors {
+		domains = append(domains, m.base)
+		if u, err := url.Parse(m.api); err == nil && u.Hostname() != "" && u.Hostname() != m.base {
+			domains = append(domains, u.Hostname())
+		}
+	}
+	return domains
+}
+
+// streamHeaders returns the headers stream hosts require, which players must
+// send as well (e.g. mpv --http-header-fields). Config headers are meant for
+// the API and are not passed on to third-party hosts.
+func (s *AllanimeScaper) streamHeaders() map[string]string {
+	return map[string]string{"User-Agent": s.client.UserAgent(s.agent), "Referer": s.allanimeRef}
+}
+
+// streamDuration returns the length in seconds of the first HLS stream, or 0 when there is none
+func (s *AllanimeScaper) streamDuration(ctx context.Context, videos []Video) int {
+	for _, video := range videos {
+		if !strings.Contains(video.VideoURL, ".m3u8") {
+			continue
+		}
+		duration, err := hls.Duration(ctx, s.client, video.VideoURL, s.streamHeaders())
+		if err != nil {
+			return 0
+		}
+		return int(math.Round(duration))
+	}
+	return 0
+}
+
 func main() {
 	// Define command-line flags
 	var (
-		help     = flag.Bool("h", false, "Show help message")
-		query    = flag.String("query", "", "Search query")
-		page     = flag.Int("page", 1, "Page number")
-		filters  = flag.String("filters", "", "JSON filters")
-		animeURL = flag.String("anime", "", "Anime URL")
-		episode  = flag.Float64("episode", 0, "Episode number")
-		sourceID = flag.String("source", "3160569130087668532", "Source ID (optional, defaults to allanime)")
+		help        = flag.Bool("h", false, "Show help message")
+		query       = flag.String("query", "", "Search query")
+		page        = flag.Int("page", 1, "Page number")
+		limit       = flag.Int("limit", 0, "With episodes: return at most this many episodes per --page, with pagination metadata")
+		offset      = flag.Int("offset", 0, "With episodes and --limit: skip this many episodes instead of paging with --page")
+		from        = flag.Float64("from", 0, "With episodes: only list episodes numbered from this one on")
+		to          = flag.Float64("to", 0, "With episodes: only list episodes numbered up to this one")
+		filters     = flag.String("filters", "", `JSON filters, e.g. {"genres":["Action"],"year":2023,"season":"Fall","type":"TV","status":"ongoing","sortBy":"top"}`)
+		animeURL    = flag.String("anime", "", "Anime URL")
+		episode     = flag.Float64("episode", 0, "Episode number")
+		sourceID    = flag.String("source", "3160569130087668532", "Source ID (optional, defaults to allanime)")
+		debug       = flag.Bool("debug", false, "Print request statistics and slow-request warnings to stderr")
+		logLevel    = flag.String("log-level", "", "Write JSON log lines about requests, retries and provider decoding to stderr at this level: debug, info, warn or error (off by default)")
+		mock        = flag.String("mock", os.Getenv("PAIR_MOCK"), "Serve all requests from the fixtures in this directory (offline mode; defaults to $PAIR_MOCK)")
+		output      = flag.String("output", OutputJSON, "Output format: json, or ndjson to print search results and episodes one JSON object per line as they are ready")
+		pageInfo    = flag.Bool("page-info", false, "With search: wrap the results in {results, page, hasNextPage, totalResults} instead of printing the plain array")
+		keepDups    = flag.Bool("keep-duplicates", false, "With search: list every AllAnime entry of a show instead of folding duplicates into the one with the most episodes")
+		sortBy      = flag.String("sort", SortRelevance, "Search result order: relevance, popularity, alphabetical")
+		epRange     = flag.String("episodes", "", "Episode range, e.g. 1-24 (episodes-meta, or stream-url for every episode in the range)")
+		translation = flag.String("translation", "sub", "Translation type: sub, dub, raw (untranslated), all (search merges sub and dub results)")
+		allowAdult  = flag.Bool("allow-adult", false, "Include adult shows in search and latest results (requires a build with -tags nsfw)")
+		config      = flag.String("config", "", "Path to the extension config file (defaults to the pair config directory)")
+		reset       = flag.Bool("reset", false, "With providers stats: forget the learned provider ranking")
+		quality     = flag.String("quality", "", "With stream-url: best, worst or a resolution such as 1080p to filter and order streams by (download defaults to best)")
+		out         = flag.String("out", "", "With download: file to save the episode to (defaults to <anime>-<episode>.mp4)")
+		run         = flag.Bool("run", false, "With download: run ffmpeg instead of only printing the download commands")
+		provider    = flag.String("provider", "", "With stream-url: only return streams from this provider, e.g. wixmp, sharepoint, gogoanime, yt")
+		validate    = flag.Bool("validate", false, "With stream-url: check every stream URL with a HEAD or ranged GET request and drop the ones that do not respond")
+		rawSources  = flag.Bool("raw-sources", false, "With stream-url: also return the undecoded API sources with their provider names and priorities")
+		noCache     = flag.Bool("no-cache", false, "Always fetch from the API instead of the response cache")
+		timeout     = flag.Duration("timeout", 60*time.Second, "Give up on the command after this long, cancelling outstanding requests (0 disables the deadline)")
+		proxy       = flag.String("proxy", "", "Route all requests through this proxy, e.g. http://host:8080 or socks5://host:1080 (defaults to HTTPS_PROXY/HTTP_PROXY)")
 	)
+	headers := cli.RegisterHeaderFlags(flag.CommandLine)
 
 	// Custom usage message
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] COMMAND [ARGS]...\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s COMMAND [SOURCE_ID] [OPTIONS]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "A command-line tool for interacting with anime video sources.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nCommands:\n")
-		fmt.Fprintf(os.Stderr, "  episodes        Get the list of episodes for an anime.\n")
-		fmt.Fprintf(os.Stderr, "  extension-info  Get information about a specific extension.\n")
-		fmt.Fprintf(os.Stderr, "  list-sources    List all available anime video sources.\n")
-		fmt.Fprintf(os.Stderr, "  search          Search for anime on a source.\n")
-		fmt.Fprintf(os.Stderr, "  source-info     Get information about a specific anime video source.\n")
-		fmt.Fprintf(os.Stderr, "  stream-url      Get the direct video stream URL for an anime episode.\n")
+		for _, c := range commands {
+			fmt.Fprintf(os.Stderr, "  %-15s %s\n", c.Name, c.Description)
+		}
 	}
 
+	// Failures are printed as a JSON error object on stdout so host apps can
+	// tell bad input from an unreachable site
+	withSource := func(e *exterr.Error) *exterr.Error {
+		e.Source = *sourceID
+		if e.Source == "" {
+			e.Source = "3160569130087668532"
+		}
+		return e
+	}
+	// exterr.Exit skips deferred calls, so fail stops the mock server itself
+	stopMock := func() error { return nil }
+	defer func() { stopMock() }()
+	fail := func(e *exterr.Error) {
+		stopMock()
+		exterr.Exit(withSource(e))
+	}
+	// Flag errors are reported like any other failure instead of exiting with status 2
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+
 	// Parse flags after the command
 	args := os.Args[1:]
 	if len(args) == 0 {
@@ -499,8 +1563,16 @@ func main() {
 		os.Exit(0)
 	}
 
-	command := args[0]
-	flag.CommandLine.Parse(args[1:])
+	command, subcommand, args := splitCommand(args)
+	if err := flag.CommandLine.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fail(exterr.New(exterr.InvalidArgument, "%w", err))
+	}
+	if flag.NArg() > 0 {
+		fail(exterr.New(exterr.InvalidArgument, "unexpected argument %q", flag.Arg(0)))
+	}
 
 	if *help {
 		flag.Usage()
@@ -509,82 +1581,381 @@ func main() {
 
 	s := NewAllanimeScaper()
 
-	var result interface{}
-	var err error
+	cfg, cfgErr := LoadConfig(*config)
+	if cfgErr != nil {
+		fail(exterr.New(exterr.Config, "error loading config: %w", cfgErr))
+	}
+	s.ApplyConfig(cfg)
+
+	// Config defaults for flags not given on the command line
+	if cfg.Translation != "" && !cli.IsFlagSet("translation") {
+		*translation = cfg.Translation
+	}
+	if cfg.Quality != "" && !cli.IsFlagSet("quality") {
+		*quality = cfg.Quality
+	}
+	if cfg.Proxy != "" && !cli.IsFlagSet("proxy") {
+		*proxy = cfg.Proxy
+	}
+	if cfg.TimeoutSeconds != nil && !cli.IsFlagSet("timeout") {
+		*timeout = time.Duration(*cfg.TimeoutSeconds) * time.Second
+	}
 
-	switch command {
-	case "extension-info":
-		result, err = s.GetExtensionInfo()
+	headers.Apply(s.client, cfg.Config)
+
+	if err := s.SetAllowAdult(*allowAdult); err != nil {
+		fail(exterr.From(err, exterr.Unsupported))
+	}
 
-	case "list-sources":
-		// Get extension info and return just the sources
-		info, err := s.GetExtensionInfo()
+	if *debug {
+		s.client.Debug = true
+	}
+	logger, logErr := extlog.New(os.Stderr, *logLevel)
+	if logErr != nil {
+		fail(exterr.New(exterr.InvalidArgument, "%w", logErr))
+	}
+	s.log = logger
+	s.client.Logger = logger
+	var proxyURL *url.URL
+	if *proxy != "" {
+		var proxyErr error
+		if proxyURL, proxyErr = httpclient.ParseProxy(*proxy); proxyErr != nil {
+			fail(exterr.New(exterr.InvalidArgument, "%w", proxyErr))
+		}
+		s.client.SetProxy(proxyURL)
+	}
+	if *mock != "" {
+		stop, err := s.client.UseMock(*mock)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting extension info: %v\n", err)
-			os.Exit(1)
+			fail(exterr.New(exterr.Internal, "error starting mock server: %w", err))
 		}
-		result = info.Sources
-
-	case "source-info":
-		// If a specific source ID is provided, verify it matches our source
-		if *sourceID != "" && *sourceID != "3160569130087668532" {
-			fmt.Fprintf(os.Stderr, "Error: invalid source ID %q\n", *sourceID)
-			os.Exit(1)
+		stopMock = stop
+	} else {
+		if err := s.LoadProviderStats(); err != nil {
+			// Without a data directory the ranking is learned for this run only
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		s.LimitRate()
+		if !*noCache && (cfg.Cache == nil || *cfg.Cache) {
+			if err := s.EnableCache(); err != nil {
+				// Without a cache directory every request goes to the API
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
 		}
-		result, err = s.GetSourceInfo()
+	}
 
-	case "search":
-		if *query == "" {
-			fmt.Fprintf(os.Stderr, "Error: search query is required\n")
-			os.Exit(1)
+	// runCommand runs one command with the current flag values, returning its failure
+	// instead of exiting so the REPL can carry on after it
+	runCommand := func(command, subcommand string) *exterr.Error {
+		s.waitPrefetch()
+		if err := s.SetTranslation(*translation); err != nil {
+			return exterr.From(err, exterr.InvalidArgument)
+		}
+		s.rawSources = *rawSources
+		s.validate = *validate
+		s.provider = strings.TrimSpace(*provider)
+
+		// Every request of the command shares one deadline, so a hanging provider
+		// cannot stall it forever
+		if *timeout < 0 {
+			return exterr.New(exterr.InvalidArgument, "-timeout must not be negative")
 		}
-		// If a specific source ID is provided, verify it matches our source
-		if *sourceID != "" && *sourceID != "3160569130087668532" {
-			fmt.Fprintf(os.Stderr, "Error: invalid source ID %q\n", *sourceID)
-			os.Exit(1)
+		ctx := context.Background()
+		if *timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, *timeout)
+			defer cancel()
 		}
-		result, err = s.SearchAnime(*query, *page, *filters)
 
-	case "episodes":
-		if *animeURL == "" {
-			fmt.Fprintf(os.Stderr, "Error: anime URL is required\n")
-			os.Exit(1)
+		switch {
+		case *output != OutputJSON && *output != OutputNDJSON:
+			return exterr.New(exterr.InvalidArgument, "invalid output format %q (valid: json, ndjson)", *output)
+		case *output == OutputNDJSON && command != "search" && command != "episodes":
+			return exterr.New(exterr.InvalidArgument, "--output ndjson is only supported by search and episodes")
 		}
-		// If a specific source ID is provided, verify it matches our source
-		if *sourceID != "" && *sourceID != "3160569130087668532" {
-			fmt.Fprintf(os.Stderr, "Error: invalid source ID %q\n", *sourceID)
-			os.Exit(1)
+
+		var result interface{}
+		var err error
+		streamed := false // Whether the command already wrote its output
+
+		switch command {
+		case "extension-info":
+			result, err = s.GetExtensionInfo()
+
+		case "version":
+			result = cli.VersionInfo{Package: "allanime", Version: version}
+
+		case "list-sources":
+			// Get extension info and return just the sources
+			info, err := s.GetExtensionInfo()
+			if err != nil {
+				return exterr.New(exterr.Internal, "error getting extension info: %w", err)
+			}
+			result = info.Sources
+
+		case "source-info":
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			result, err = s.GetSourceInfo()
+
+		case "search":
+			if *query == "" && *filters == "" {
+				return exterr.New(exterr.InvalidArgument, "search query or filters are required")
+			}
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			var searchPage SearchPage
+			searchPage, err = s.SearchAnime(ctx, *query, *page, *filters)
+			if err == nil {
+				// A sortBy filter already ordered the results unless --sort was given explicitly
+				sortMode := *sortBy
+				if searchFilters, _ := ParseSearchFilters(*filters); searchFilters.SortBy != "" && !cli.IsFlagSet("sort") {
+					sortMode = SortPopularity
+				}
+				err = SortAnime(searchPage.Results, *query, sortMode)
+			}
+			if !*keepDups {
+				searchPage.Results = CollapseDuplicates(searchPage.Results)
+			}
+			if err == nil && len(searchPage.Results) == 0 {
+				// A search matching nothing exits with exterr.ExitNoResults
+				err = exterr.New(exterr.NotFound, "no results found")
+			}
+			if err == nil && *output == OutputNDJSON {
+				err = writeNDJSON(os.Stdout, searchPage.Results)
+				streamed = true
+			}
+			if *pageInfo {
+				result = searchPage
+			} else {
+				result = searchPage.Results
+			}
+
+		case "latest":
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			result, err = s.GetLatestUpdates(ctx, *page)
+
+		case "popular":
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			result, err = s.GetPopularAnime(ctx, *page)
+
+		case "completion":
+			spec := completion.Spec{
+				Program:  "allanime",
+				Commands: commands,
+				Flags:    flag.CommandLine,
+				Values: map[string][]string{
+					"quality":     {QualityBest, QualityWorst, "2160p", "1080p", "720p", "480p", "360p"},
+					"translation": append(slices.Clone(TranslationTypes), TranslationAll),
+					"sort":        {SortRelevance, SortPopularity, SortAlphabetical},
+					"output":      {OutputJSON, OutputNDJSON},
+					"log-level":   append([]string{"off"}, extlog.Levels...),
+				},
+				Files: []string{"config", "mock", "out"},
+			}
+			if err := completion.Write(os.Stdout, subcommand, spec); err != nil {
+				return exterr.New(exterr.InvalidArgument, "%w", err)
+			}
+			streamed = true
+
+		case "genres":
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			result = s.GetFilterOptions()
+
+		case "details":
+			if *animeURL == "" {
+				return exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			result, err = s.GetAnimeDetails(ctx, *animeURL)
+
+		case "episodes":
+			if *animeURL == "" {
+				return exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			if *limit < 0 {
+				return exterr.New(exterr.InvalidArgument, "--limit must be positive")
+			}
+			episodeRange := AllEpisodes
+			if cli.IsFlagSet("from") {
+				episodeRange.From = *from
+			}
+			if cli.IsFlagSet("to") {
+				episodeRange.To = *to
+			}
+			if episodeRange.To < episodeRange.From {
+				return exterr.New(exterr.InvalidArgument, "--to %g is before --from %g", *to, *from)
+			}
+			if *offset < 0 {
+				return exterr.New(exterr.InvalidArgument, "--offset must not be negative")
+			}
+			if cli.IsFlagSet("offset") && *limit == 0 {
+				return exterr.New(exterr.InvalidArgument, "--offset requires --limit")
+			}
+			pageOffset := *offset
+			if *limit > 0 && !cli.IsFlagSet("offset") {
+				if *page < 1 {
+					return exterr.New(exterr.InvalidArgument, "invalid page %d: pages start at 1", *page)
+				}
+				pageOffset = (*page - 1) * *limit
+			}
+			switch {
+			case *output == OutputNDJSON:
+				err = s.StreamEpisodes(ctx, os.Stdout, *animeURL, episodeRange, pageOffset, *limit)
+				streamed = true
+			case *limit > 0:
+				result, err = s.GetEpisodePage(ctx, *animeURL, episodeRange, pageOffset, *limit)
+			default:
+				result, err = s.GetEpisodeList(ctx, *animeURL, episodeRange)
+			}
+
+		case "episodes-meta":
+			if *animeURL == "" || *epRange == "" {
+				return exterr.New(exterr.InvalidArgument, "anime URL and episode range are required")
+			}
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			start, end, rangeErr := ParseEpisodeRange(*epRange)
+			if rangeErr != nil {
+				return exterr.From(rangeErr, exterr.InvalidArgument)
+			}
+			result, err = s.GetEpisodesMeta(ctx, *animeURL, start, end)
+
+		case "related":
+			if *animeURL == "" {
+				return exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			result, err = s.GetRelatedAnime(ctx, *animeURL, *page)
+
+		case "stream-url":
+			if *animeURL == "" || (*episode == 0 && *epRange == "") {
+				return exterr.New(exterr.InvalidArgument, "anime URL and episode number or range are required")
+			}
+			if *episode != 0 && *epRange != "" {
+				return exterr.New(exterr.InvalidArgument, "-episode and -episodes cannot be combined")
+			}
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			if *epRange != "" {
+				start, end, rangeErr := ParseEpisodeRange(*epRange)
+				if rangeErr != nil {
+					return exterr.From(rangeErr, exterr.InvalidArgument)
+				}
+				result, err = s.GetVideoLists(ctx, *animeURL, start, end, *quality)
+				break
+			}
+			var videos VideoResponse
+			videos, err = s.videoList(ctx, *animeURL, *episode, *timeout)
+			if err == nil {
+				videos.Streams, err = SelectQuality(videos.Streams, *quality)
+			}
+			result = videos
+
+		case "download":
+			if *animeURL == "" || *episode == 0 {
+				return exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			var plan DownloadPlan
+			plan, err = s.PlanDownload(ctx, *animeURL, *episode, *quality, *out)
+			if err == nil && *run {
+				err = RunDownload(ctx, &plan)
+			}
+			result = plan
+
+		case "skip-times":
+			if *animeURL == "" || *episode == 0 {
+				return exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			// If a specific source ID is provided, verify it matches our source
+			if *sourceID != "" && *sourceID != "3160569130087668532" {
+				return exterr.New(exterr.InvalidArgument, "invalid source ID %q", *sourceID)
+			}
+			result, err = s.GetSkipTimes(ctx, *animeURL, *episode)
+
+		case "doctor":
+			report := doctor.Run(doctor.Options{Package: "allanime", Domains: s.domains(), Proxy: proxyURL})
+			// Human-readable text goes to stderr so stdout stays JSON
+			fmt.Fprint(os.Stderr, report.Text())
+			result = report
+
+		case "providers":
+			if subcommand != "stats" {
+				return exterr.New(exterr.UnknownCommand, "unknown providers subcommand %q (expected stats)", subcommand)
+			}
+			if *reset {
+				err = s.ranker.Reset()
+			}
+			result = s.ranker.Stats()
+
+		default:
+			flag.Usage()
+			return exterr.New(exterr.UnknownCommand, "unknown command %q", command)
 		}
-		result, err = s.GetEpisodeList(*animeURL)
 
-	case "stream-url":
-		if *animeURL == "" || *episode == 0 {
-			fmt.Fprintf(os.Stderr, "Error: anime URL and episode number are required\n")
-			os.Exit(1)
+		s.client.PrintSummary()
+
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				// Requests cut off by the deadline fail with less helpful errors
+				return exterr.New(exterr.Timeout, "timed out after %s (raise it with -timeout)", *timeout)
+			}
+			return exterr.From(err, exterr.Internal)
 		}
-		// If a specific source ID is provided, verify it matches our source
-		if *sourceID != "" && *sourceID != "3160569130087668532" {
-			fmt.Fprintf(os.Stderr, "Error: invalid source ID %q\n", *sourceID)
-			os.Exit(1)
+
+		if streamed {
+			return nil
 		}
-		result, err = s.GetVideoList(*animeURL, *episode)
 
-	default:
-		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", command)
-		flag.Usage()
-		os.Exit(1)
+		if err := cli.Print(result); err != nil {
+			return exterr.From(err, exterr.Internal)
+		}
+		return nil
 	}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if command == "repl" {
+		if cfg.Prefetch {
+			ttl := defaultPrefetchTTL
+			if cfg.PrefetchTTLSeconds > 0 {
+				ttl = time.Duration(cfg.PrefetchTTLSeconds) * time.Second
+			}
+			s.EnablePrefetch(ttl)
+		}
+		runREPL(os.Stdin, runCommand, func(e *exterr.Error) { exterr.Print(withSource(e)) })
+		return
 	}
-
-	// Output the result as JSON
-	jsonOutput, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshalling result to JSON: %v\n", err)
-		os.Exit(1)
+	if e := runCommand(command, subcommand); e != nil {
+		fail(e)
 	}
-	fmt.Println(string(jsonOutput))
 }
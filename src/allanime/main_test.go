@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGetEpisodeListObjectMap(t *testing.T) {
+	s := NewAllanimeScaper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock() returned error: %v", err)
+	}
+	defer stop()
+
+	episodes, err := s.GetEpisodeList(context.Background(), "MapShapeShow", AllEpisodes)
+	if err != nil {
+		t.Fatalf("GetEpisodeList() returned error: %v", err)
+	}
+
+	want := []struct {
+		number    float64
+		name      string
+		thumbnail string
+		languages []string
+	}{
+		{number: 10, name: "The Forest of Chakra", languages: []string{"sub"}},
+		{number: 2, name: "My Name is Konohamaru!", thumbnail: "https://example.com/thumbs/2.jpg", languages: []string{"sub"}},
+		{number: 1, name: "Enter: Naruto Uzumaki!", thumbnail: "https://example.com/thumbs/1.jpg", languages: []string{"sub", "dub"}},
+	}
+	if len(episodes) != len(want) {
+		t.Fatalf("GetEpisodeList() returned %d episodes, want %d", len(episodes), len(want))
+	}
+	for i, w := range want {
+		ep := episodes[i]
+		if ep.EpisodeNumber != w.number || ep.Name != w.name || ep.Thumbnail != w.thumbnail || !reflect.DeepEqual(ep.Languages, w.languages) {
+			t.Errorf("episode %d = {%v %q %q %v}, want {%v %q %q %v}", i, ep.EpisodeNumber, ep.Name, ep.Thumbnail, ep.Languages, w.number, w.name, w.thumbnail, w.languages)
+		}
+	}
+}
+
+func TestFirstString(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		keys   []string
+		want   string
+	}{
+		{name: "first key", fields: map[string]interface{}{"title": "a", "name": "b"}, keys: []string{"title", "name"}, want: "a"},
+		{name: "skips empty", fields: map[string]interface{}{"title": "", "name": "b"}, keys: []string{"title", "name"}, want: "b"},
+		{name: "array", fields: map[string]interface{}{"thumbnails": []interface{}{"", 3.0, "c"}}, keys: []string{"thumbnails"}, want: "c"},
+		{name: "wrong type", fields: map[string]interface{}{"title": 1.0}, keys: []string{"title"}, want: ""},
+		{name: "missing", fields: map[string]interface{}{}, keys: []string{"title"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstString(tt.fields, tt.keys...); got != tt.want {
+				t.Errorf("firstString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeProviderID(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		want    string
+	}{
+		{
+			name:    "clock endpoint",
+			encoded: "175948514e4c4f57175b54575b5307515c05595a5b090a0b",
+			want:    "/apivtwo/clock.json?id=abc123",
+		},
+		{
+			name:    "uppercase and punctuation",
+			encoded: "175948514e4c4f571755514b4b51565f07515c05795a675b1609",
+			want:    "/apivtwo/missing?id=Ab_c.1",
+		},
+		{
+			name:    "escaped query",
+			encoded: "175948514e4c4f57175b54575b5307515c05795a675b1509160a1d0b7c",
+			want:    "/apivtwo/clock.json?id=Ab_c-1.2%3D",
+		},
+		{
+			name:    "uppercase hex digits",
+			encoded: "175948514E4C4F57",
+			want:    "/apivtwo",
+		},
+		{name: "empty", encoded: "", want: ""},
+		{name: "invalid pair kept", encoded: "17zz59", want: "/zza"},
+		{name: "trailing nibble dropped", encoded: "175", want: "/"},
+		{name: "single character", encoded: "1", want: ""},
+		{name: "not hex at all", encoded: "https://", want: "https://"},
+	}
+
+	s := &AllanimeScaper{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.decodeProviderID(tt.encoded); got != tt.want {
+				t.Errorf("decodeProviderID(%q) = %q, want %q", tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+)
+
+// mirror is an AllAnime deployment: the host serving provider links and its
+// GraphQL endpoint
+type mirror struct {
+	base string
+	api  string
+}
+
+// setMirrors makes the configured base host and API URL the primary mirror,
+// followed by the fallback base hosts in order
+func (s *AllanimeScaper) setMirrors(fallbacks []string) {
+	s.mirrorMu.Lock()
+	defer s.mirrorMu.Unlock()
+
+	s.mirrors = []mirror{{base: s.allanimeBase, api: s.allanimeAPI}}
+	seen := map[string]bool{s.allanimeBase: true}
+	for _, host := range fallbacks {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		s.mirrors = append(s.mirrors, mirror{base: host, api: "https://api." + host + "/api"})
+	}
+	s.mirrorIdx = 0
+}
+
+// mirrorHosts returns the network entries covering every mirror's base host
+// with its subdomains, and its API host when it lives elsewhere
+func (s *AllanimeScaper) mirrorHosts() []string {
+	s.mirrorMu.Lock()
+	defer s.mirrorMu.Unlock()
+
+	var bases, apis []string
+	for _, m := range s.mirrors {
+		bases = append(bases, m.base)
+		if u, err := url.Parse(m.api); err == nil && u.Hostname() != m.base && !strings.HasSuffix(u.Hostname(), "."+m.base) {
+			apis = append(apis, u.Hostname())
+		}
+	}
+	return append(permissions.Domains(bases...), apis...)
+}
+
+// currentMirror returns the mirror requests are sent to, which moves on to the
+// next one whenever a query fails over
+func (s *AllanimeScaper) currentMirror() (mirror, int) {
+	s.mirrorMu.Lock()
+	defer s.mirrorMu.Unlock()
+
+	if len(s.mirrors) == 0 {
+		return mirror{base: s.allanimeBase, api: s.allanimeAPI}, 0
+	}
+	return s.mirrors[s.mirrorIdx], s.mirrorIdx
+}
+
+// rotateMirror switches from the mirror at failed to the next one. Concurrent
+// queries failing on the same mirror only rotate once.
+func (s *AllanimeScaper) rotateMirror(failed int) (mirror, int) {
+	s.mirrorMu.Lock()
+	defer s.mirrorMu.Unlock()
+
+	if s.mirrorIdx == failed {
+		s.mirrorIdx = (failed + 1) % len(s.mirrors)
+	}
+	return s.mirrors[s.mirrorIdx], s.mirrorIdx
+}
+
+// postRejected reports whether m answered a GraphQL POST with 405 or 415
+// earlier in this run
+func (s *AllanimeScaper) postRejected(m mirror) bool {
+	s.mirrorMu.Lock()
+	defer s.mirrorMu.Unlock()
+
+	return s.getOnly[m.api]
+}
+
+// rejectPost makes later queries to m skip POST and go straight to GET
+func (s *AllanimeScaper) rejectPost(m mirror) {
+	s.mirrorMu.Lock()
+	defer s.mirrorMu.Unlock()
+
+	if s.getOnly == nil {
+		s.getOnly = make(map[string]bool)
+	}
+	s.getOnly[m.api] = true
+}
+
+// withFailover runs query against the current mirror, moving on to the next
+// mirror while it fails with an error another mirror may not have, until
+// every mirror was tried once
+func (s *AllanimeScaper) withFailover(ctx context.Context, query func(mirror) error) error {
+	m, idx := s.currentMirror()
+	err := query(m)
+	for tried := 1; err != nil && tried < len(s.mirrors) && shouldFailover(ctx, err); tried++ {
+		next, nextIdx := s.rotateMirror(idx)
+		s.log.Warn("mirror failed, switching", "host", m.base, "next", next.base, "error", err)
+		m, idx = next, nextIdx
+		err = query(m)
+	}
+	return err
+}
+
+// shouldFailover reports whether err means the mirror itself is unreachable,
+// overloaded or challenging requests, rather than the query being at fault
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var extErr *exterr.Error
+	if !errors.As(err, &extErr) {
+		return false
+	}
+	return extErr.Retryable
+}
+
+// isChallenge reports whether resp is a Cloudflare challenge page instead of
+// the API response
+func isChallenge(resp *http.Response) bool {
+	if resp.Header.Get("cf-mitigated") == "challenge" {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return strings.EqualFold(resp.Header.Get("Server"), "cloudflare") && mediaType == "text/html"
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// Output formats accepted by --output
+const (
+	OutputJSON   = "json"
+	OutputNDJSON = "ndjson" // One JSON object per line, written as soon as it is ready
+)
+
+// streamChunkSize is how many episodes StreamEpisodes completes with metadata
+// before writing them, matching a page of Jikan's episode list
+const streamChunkSize = jikanPageSize
+
+// writeNDJSON writes each record as a JSON object on a line of its own
+func writeNDJSON[T any](w io.Writer, records []T) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return exterr.New(exterr.Internal, "error writing output: %w", err)
+		}
+	}
+	return nil
+}
+
+// StreamEpisodes writes up to limit of the episodes of an anime within r,
+// starting at offset, as NDJSON (all of them when limit is 0). Metadata is
+// looked up a chunk at a time and each chunk is written as soon as it is
+// complete, so host apps can show the first episodes of long-running shows
+// while the rest are still being fetched.
+func (s *AllanimeScaper) StreamEpisodes(ctx context.Context, w io.Writer, animeID string, r EpisodeRange, offset, limit int) error {
+	episodes, err := s.listEpisodes(ctx, animeID)
+	if err != nil {
+		return err
+	}
+	episodes = r.filter(episodes)
+	episodes = episodes[min(offset, len(episodes)):]
+	if limit > 0 {
+		episodes = episodes[:min(limit, len(episodes))]
+	}
+
+	for start := 0; start < len(episodes); start += streamChunkSize {
+		chunk := episodes[start:min(start+streamChunkSize, len(episodes))]
+		s.attachEpisodeMeta(ctx, animeID, chunk)
+		s.attachFillers(ctx, animeID, chunk)
+		if err := writeNDJSON(w, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// defaultPrefetchTTL is how long prefetched streams are served, matching the
+// provider cache TTL since both carry signed links that expire
+const defaultPrefetchTTL = 10 * time.Minute
+
+// prefetcher holds the streams of episodes resolved in the background, so the
+// REPL answers stream-url for the next episode without waiting on providers.
+// Each entry is served once.
+type prefetcher struct {
+	ttl     time.Duration
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	entries map[prefetchKey]prefetched
+}
+
+// prefetchKey identifies an episode's streams together with the settings that
+// shape them, so a prefetch is not served after they changed
+type prefetchKey struct {
+	animeID     string
+	episode     float64
+	translation string
+	provider    string
+	validate    bool
+	rawSources  bool
+}
+
+type prefetched struct {
+	videos  VideoResponse
+	fetched time.Time
+}
+
+func newPrefetcher(ttl time.Duration) *prefetcher {
+	return &prefetcher{ttl: ttl, entries: map[prefetchKey]prefetched{}}
+}
+
+// start runs fetch in the background and keeps the streams it resolves.
+// Failures are dropped, leaving the episode to be resolved when asked for.
+func (p *prefetcher) start(fetch func() (prefetchKey, VideoResponse, error)) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		key, videos, err := fetch()
+		if err != nil {
+			return
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for k, entry := range p.entries {
+			if time.Since(entry.fetched) >= p.ttl {
+				delete(p.entries, k)
+			}
+		}
+		p.entries[key] = prefetched{videos: videos, fetched: time.Now()}
+	}()
+}
+
+// take returns and forgets the streams prefetched for key while they are fresh
+func (p *prefetcher) take(key prefetchKey) (VideoResponse, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[key]
+	delete(p.entries, key)
+	if !ok || time.Since(entry.fetched) >= p.ttl {
+		return VideoResponse{}, false
+	}
+	return entry.videos, true
+}
+
+// wait blocks until the running prefetches finished
+func (p *prefetcher) wait() {
+	p.wg.Wait()
+}
+
+// EnablePrefetch makes stream-url resolve the episode after the requested one
+// in the background, serving it for ttl. It only pays off in a process that
+// outlives the command, i.e. the REPL.
+func (s *AllanimeScaper) EnablePrefetch(ttl time.Duration) {
+	s.prefetch = newPrefetcher(ttl)
+}
+
+// waitPrefetch blocks until the running prefetches finished, so settings can
+// be changed without racing them
+func (s *AllanimeScaper) waitPrefetch() {
+	if s.prefetch != nil {
+		s.prefetch.wait()
+	}
+}
+
+// streamKey returns the prefetch key for an episode under the current settings
+func (s *AllanimeScaper) streamKey(animeID string, episode float64) prefetchKey {
+	return prefetchKey{
+		animeID:     animeID,
+		episode:     episode,
+		translation: s.translation,
+		provider:    s.provider,
+		validate:    s.validate,
+		rawSources:  s.rawSources,
+	}
+}
+
+// videoList is GetVideoList for stream-url. With prefetching enabled it
+// serves a prefetched episode from memory and prefetches the one after the
+// episode it returns, giving that up after timeout unless it is 0.
+func (s *AllanimeScaper) videoList(ctx context.Context, animeID string, episode float64, timeout time.Duration) (VideoResponse, error) {
+	if s.prefetch == nil {
+		return s.GetVideoList(ctx, animeID, episode)
+	}
+
+	videos, ok := s.prefetch.take(s.streamKey(animeID, episode))
+	if ok {
+		s.log.Debug("serving prefetched streams", "anime", animeID, "episode", episode)
+	} else {
+		var err error
+		if videos, err = s.GetVideoList(ctx, animeID, episode); err != nil {
+			return VideoResponse{}, err
+		}
+	}
+
+	s.prefetch.start(func() (prefetchKey, VideoResponse, error) {
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		next, err := s.nextEpisode(ctx, animeID, episode)
+		if err != nil {
+			s.log.Debug("no episode to prefetch", "anime", animeID, "after", episode, "error", err)
+			return prefetchKey{}, VideoResponse{}, err
+		}
+		videos, err := s.GetVideoList(ctx, animeID, next)
+		if err != nil {
+			s.log.Debug("prefetch failed", "anime", animeID, "episode", next, "error", err)
+		} else {
+			s.log.Debug("prefetched streams", "anime", animeID, "episode", next, "streams", len(videos.Streams))
+		}
+		return s.streamKey(animeID, next), videos, err
+	})
+	return videos, nil
+}
+
+// nextEpisode returns the number of the episode following episode, which is
+// not always episode+1 for shows with recaps such as 12.5
+func (s *AllanimeScaper) nextEpisode(ctx context.Context, animeID string, episode float64) (float64, error) {
+	episodes, err := s.listEpisodes(ctx, animeID)
+	if err != nil {
+		return 0, err
+	}
+
+	next, found := 0.0, false
+	for _, e := range episodes {
+		if e.EpisodeNumber > episode && (!found || e.EpisodeNumber < next) {
+			next, found = e.EpisodeNumber, true
+		}
+	}
+	if !found {
+		return 0, exterr.New(exterr.NotFound, "no episode after %g", episode)
+	}
+	return next, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPrefetcher(t *testing.T) {
+	key := prefetchKey{animeID: "show", episode: 2, translation: "sub"}
+	videos := VideoResponse{Streams: []Video{{Provider: "wixmp"}}}
+
+	p := newPrefetcher(time.Minute)
+	p.start(func() (prefetchKey, VideoResponse, error) { return key, videos, nil })
+	p.wait()
+
+	dub := key
+	dub.translation = "dub"
+	if _, ok := p.take(dub); ok {
+		t.Error("take() served streams prefetched under another translation")
+	}
+	got, ok := p.take(key)
+	if !ok || len(got.Streams) != 1 || got.Streams[0].Provider != "wixmp" {
+		t.Fatalf("take() = %+v, %v, want the prefetched streams", got, ok)
+	}
+	if _, ok := p.take(key); ok {
+		t.Error("take() served the same prefetch twice")
+	}
+
+	// Failed prefetches are not kept
+	p.start(func() (prefetchKey, VideoResponse, error) { return key, videos, errors.New("provider down") })
+	p.wait()
+	if _, ok := p.take(key); ok {
+		t.Error("take() served a failed prefetch")
+	}
+
+	// Nor are expired ones served
+	p.start(func() (prefetchKey, VideoResponse, error) { return key, videos, nil })
+	p.wait()
+	p.ttl = 0
+	if _, ok := p.take(key); ok {
+		t.Error("take() served an expired prefetch")
+	}
+}
+
+func TestNextEpisode(t *testing.T) {
+	s := NewAllanimeScaper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock() returned error: %v", err)
+	}
+	defer stop()
+
+	// MapShapeShow has episodes 1, 2 and 10
+	next, err := s.nextEpisode(context.Background(), "MapShapeShow", 2)
+	if err != nil || next != 10 {
+		t.Errorf("nextEpisode(2) = %v, %v, want 10", next, err)
+	}
+	if next, err := s.nextEpisode(context.Background(), "MapShapeShow", 10); err == nil {
+		t.Errorf("nextEpisode(10) = %v, want an error after the last episode", next)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// Quality selections accepted by --quality besides a resolution such as "720p"
+const (
+	QualityBest  = "best"
+	QualityWorst = "worst"
+)
+
+var (
+	qualityFlagRe  = regexp.MustCompile(`^(\d{3,4})p$`)
+	resolutionRe   = regexp.MustCompile(`(?i)\b(\d{3,4})p\b`)
+	dimensionsRe   = regexp.MustCompile(`\b\d{3,4}x(\d{3,4})\b`)
+	qualityAliases = map[string]int{"4k": 2160, "uhd": 2160, "fhd": 1080, "hd": 720, "sd": 480}
+)
+
+// streamResolution returns the vertical resolution in a quality label such as
+// "1080p", "1920x1080" or "FHD", or 0 when the label names none (e.g. "Hls")
+func streamResolution(quality string) int {
+	if match := resolutionRe.FindStringSubmatch(quality); match != nil {
+		resolution, _ := strconv.Atoi(match[1])
+		return resolution
+	}
+	if match := dimensionsRe.FindStringSubmatch(quality); match != nil {
+		resolution, _ := strconv.Atoi(match[1])
+		return resolution
+	}
+	return qualityAliases[strings.ToLower(strings.TrimSpace(quality))]
+}
+
+// SelectQuality filters and orders streams by resolution. best and worst sort
+// the streams from highest or lowest resolution; a resolution such as 720p
+// keeps only the matching streams, or, when there are none, orders the rest by
+// closeness, preferring lower resolutions. Streams without a resolution in
+// their label come last, and streams of equal resolution keep their provider
+// ranking order. An empty quality leaves the streams unchanged.
+func SelectQuality(videos []Video, quality string) ([]Video, error) {
+	quality = strings.ToLower(strings.TrimSpace(quality))
+
+	var rank func(resolution int) int
+	switch {
+	case quality == "":
+		return videos, nil
+	case quality == QualityBest:
+		rank = func(resolution int) int { return -resolution }
+	case quality == QualityWorst:
+		rank = func(resolution int) int { return resolution }
+	case qualityFlagRe.MatchString(quality):
+		target := streamResolution(quality)
+		matching := []Video{}
+		for _, video := range videos {
+			if streamResolution(video.Quality) == target {
+				matching = append(matching, video)
+			}
+		}
+		if len(matching) > 0 {
+			return matching, nil
+		}
+
+		rank = func(resolution int) int {
+			if resolution <= target {
+				return target - resolution
+			}
+			// Anything above the target ranks after everything below it
+			return target + resolution
+		}
+	default:
+		return nil, exterr.New(exterr.InvalidArgument, "invalid quality %q (valid: best, worst or a resolution such as 1080p)", quality)
+	}
+
+	sorted := append([]Video{}, videos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := streamResolution(sorted[i].Quality), streamResolution(sorted[j].Quality)
+		if ri == 0 || rj == 0 {
+			return ri != 0 && rj == 0
+		}
+		return rank(ri) < rank(rj)
+	})
+	return sorted, nil
+}
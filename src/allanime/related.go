@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// RelatedAnime extends Anime with how the show relates to the requested one
+type RelatedAnime struct {
+	Anime
+	Relation string `json:"relation"` // prequel, sequel, side_story, spin_off, ...
+}
+
+// relationOrder lists the relations shown first, in order; others follow alphabetically
+var relationOrder = []string{"prequel", "sequel", "parent", "side_story", "spin_off", "alternative", "summary", "other"}
+
+// GetRelatedAnime retrieves the sequels, prequels, side stories and other shows AllAnime relates to an anime
+func (s *AllanimeScaper) GetRelatedAnime(ctx context.Context, animeID string, page int) ([]RelatedAnime, error) {
+	related := []RelatedAnime{}
+	// All relations fit on the first page
+	if page > 1 {
+		return related, nil
+	}
+
+	relationsGql := `query ($showId: String!) { show( _id: $showId ) { _id relatedShows } }`
+
+	var relationsResponse struct {
+		Data struct {
+			Show *struct {
+				ID           string `json:"_id"`
+				RelatedShows []struct {
+					Relation string `json:"relation"`
+					ShowID   string `json:"showId"`
+				} `json:"relatedShows"`
+			} `json:"show"`
+		} `json:"data"`
+	}
+	if err := s.queryAPI(ctx, "related", relationsGql, map[string]interface{}{"showId": animeID}, &relationsResponse); err != nil {
+		return nil, err
+	}
+
+	show := relationsResponse.Data.Show
+	if show == nil || show.ID == "" {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	if len(show.RelatedShows) == 0 {
+		return related, nil
+	}
+
+	relations := map[string]string{}
+	ids := []string{}
+	for _, relatedShow := range show.RelatedShows {
+		if relatedShow.ShowID == "" || relatedShow.ShowID == animeID {
+			continue
+		}
+		if _, seen := relations[relatedShow.ShowID]; seen {
+			continue
+		}
+		relations[relatedShow.ShowID] = strings.ToLower(strings.ReplaceAll(relatedShow.Relation, " ", "_"))
+		ids = append(ids, relatedShow.ShowID)
+	}
+
+	showsGql := `query ($ids: [String!]!) { showsWithIds( ids: $ids ) { _id name englishName availableEpisodes status type aniListId malId thumbnail banner } }`
+
+	var showsResponse struct {
+		Data struct {
+			ShowsWithIds []showCard `json:"showsWithIds"`
+		} `json:"data"`
+	}
+	if err := s.queryAPI(ctx, "related", showsGql, map[string]interface{}{"ids": ids}, &showsResponse); err != nil {
+		return nil, err
+	}
+
+	for _, card := range showsResponse.Data.ShowsWithIds {
+		relation, ok := relations[card.ID]
+		if !ok {
+			continue
+		}
+		related = append(related, RelatedAnime{
+			Anime:    s.toAnime(card),
+			Relation: relation,
+		})
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		ri, rj := relationRank(related[i].Relation), relationRank(related[j].Relation)
+		if ri != rj {
+			return ri < rj
+		}
+		return related[i].Relation < related[j].Relation
+	})
+
+	return related, nil
+}
+
+// relationRank returns the position of relation in relationOrder, placing unknown relations last
+func relationRank(relation string) int {
+	for i, known := range relationOrder {
+		if relation == known {
+			return i
+		}
+	}
+	return len(relationOrder)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// replSetupFlags configure the client shared by every REPL command, so they
+// only take effect when given to repl itself
+var replSetupFlags = []string{"allow-adult", "config", "debug", "log-level", "mock", "no-cache", "proxy"}
+
+// runREPL reads commands from in, one per line as they would be given on the
+// command line (e.g. search -query "one piece"), and runs them in this
+// process, so HTTP connections, the response cache and the provider ranking
+// carry over from one command to the next; with the prefetch config setting,
+// stream-url also resolves the following episode in the background. Flags are reset to the values repl
+// was started with before each command. Failures are reported with report and
+// the REPL carries on; it ends with exit, quit or end of input.
+func runREPL(in io.Reader, runCommand func(command, subcommand string) *exterr.Error, report func(*exterr.Error)) {
+	defaults := map[string]string{}
+	flag.VisitAll(func(f *flag.Flag) {
+		defaults[f.Name] = f.Value.String()
+	})
+
+	fmt.Fprintf(os.Stderr, "allanime %s REPL: enter commands such as search -query naruto, help or exit\n", version)
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(os.Stderr, "allanime> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(os.Stderr)
+			return
+		}
+
+		args, err := splitLine(scanner.Text())
+		if err != nil {
+			report(exterr.New(exterr.InvalidArgument, "%w", err))
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "exit", "quit":
+			return
+		case "help":
+			flag.Usage()
+			continue
+		case "repl":
+			report(exterr.New(exterr.InvalidArgument, "already in the REPL"))
+			continue
+		}
+
+		// Each command gets a flag set of its own, sharing the flag values, so
+		// cli.IsFlagSet only sees the flags given on this line
+		command, subcommand, flagArgs := splitCommand(args)
+		lineFlags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		flag.VisitAll(func(f *flag.Flag) {
+			f.Value.Set(defaults[f.Name])
+			lineFlags.Var(f.Value, f.Name, f.Usage)
+		})
+		lineFlags.Usage = flag.Usage
+		flag.CommandLine = lineFlags
+		if err := lineFlags.Parse(flagArgs); err != nil {
+			if err != flag.ErrHelp {
+				report(exterr.New(exterr.InvalidArgument, "%w", err))
+			}
+			continue
+		}
+		if lineFlags.NArg() > 0 {
+			report(exterr.New(exterr.InvalidArgument, "unexpected argument %q", lineFlags.Arg(0)))
+			continue
+		}
+		for _, name := range replSetupFlags {
+			if cli.IsFlagSet(name) {
+				fmt.Fprintf(os.Stderr, "Warning: -%s only applies when starting the REPL, ignoring it\n", name)
+			}
+		}
+
+		if e := runCommand(command, subcommand); e != nil {
+			report(e)
+		}
+	}
+}
+
+// splitLine splits a REPL line into arguments the way a POSIX shell splits
+// words, honouring single and double quotes and backslash escapes
+func splitLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	escaped := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// aniskipAPI serves community-submitted opening and ending timestamps keyed
+// by MyAnimeList ID, see https://api.aniskip.com
+const aniskipAPI = "https://api.aniskip.com/v2"
+
+// skipTypes are the interval types requested from AniSkip
+var skipTypes = []string{"op", "ed", "mixed-op", "mixed-ed", "recap"}
+
+// SkipInterval is a part of an episode a player can skip
+type SkipInterval struct {
+	Type  string  `json:"type"`  // "op", "ed", "mixed-op", "mixed-ed" (opening or ending over story content) or "recap"
+	Start float64 `json:"start"` // Seconds from the start of the episode
+	End   float64 `json:"end"`
+	// Length of the episode the interval was submitted for, in seconds.
+	// Versions of different lengths shift the timestamps.
+	EpisodeLength float64 `json:"episode_length,omitempty"`
+}
+
+// SkipTimes is the output of the skip-times command
+type SkipTimes struct {
+	MalID         int            `json:"mal_id"`
+	EpisodeNumber float64        `json:"episode_number"`
+	Intervals     []SkipInterval `json:"intervals"` // Empty when nobody submitted timestamps yet
+}
+
+// malID looks up the MyAnimeList ID of a show, which AniSkip and Jikan are keyed by
+func (s *AllanimeScaper) malID(ctx context.Context, animeID string) (int, error) {
+	idsGql := `query ($showId: String!) { show( _id: $showId ) { _id malId } }`
+
+	var response struct {
+		Data struct {
+			Show *struct {
+				ID    string      `json:"_id"`
+				MalID interface{} `json:"malId"`
+			} `json:"show"`
+		} `json:"data"`
+	}
+	if err := s.queryAPI(ctx, "details", idsGql, map[string]interface{}{"showId": animeID}, &response); err != nil {
+		return 0, err
+	}
+
+	show := response.Data.Show
+	if show == nil || show.ID == "" {
+		return 0, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	malID := externalID(show.MalID)
+	if malID == 0 {
+		return 0, exterr.New(exterr.NotFound, "anime %q has no MyAnimeList ID", animeID)
+	}
+	return malID, nil
+}
+
+// GetSkipTimes looks up the opening, ending and recap timestamps of an
+// episode on AniSkip through the show's MyAnimeList ID
+func (s *AllanimeScaper) GetSkipTimes(ctx context.Context, animeID string, episodeNumber float64) (SkipTimes, error) {
+	malID, err := s.malID(ctx, animeID)
+	if err != nil {
+		return SkipTimes{}, err
+	}
+
+	query := url.Values{"episodeLength": {"0"}}
+	for _, skipType := range skipTypes {
+		query.Add("types[]", skipType)
+	}
+	reqURL := fmt.Sprintf("%s/skip-times/%d/%s?%s", aniskipAPI, malID, strconv.FormatFloat(episodeNumber, 'f', -1, 64), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return SkipTimes{}, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("User-Agent", s.agent)
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return SkipTimes{}, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	result := SkipTimes{MalID: malID, EpisodeNumber: episodeNumber, Intervals: []SkipInterval{}}
+	// AniSkip answers 404 for episodes without submissions
+	if resp.StatusCode == http.StatusNotFound {
+		return result, nil
+	}
+	if resp.StatusCode >= 400 {
+		return SkipTimes{}, exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+
+	var skipResponse struct {
+		Results []struct {
+			Interval struct {
+				StartTime float64 `json:"startTime"`
+				EndTime   float64 `json:"endTime"`
+			} `json:"interval"`
+			SkipType      string  `json:"skipType"`
+			EpisodeLength float64 `json:"episodeLength"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&skipResponse); err != nil {
+		return SkipTimes{}, exterr.New(exterr.Parse, "error parsing response: %w", err)
+	}
+
+	for _, skip := range skipResponse.Results {
+		result.Intervals = append(result.Intervals, SkipInterval{
+			Type:          skip.SkipType,
+			Start:         skip.Interval.StartTime,
+			End:           skip.Interval.EndTime,
+			EpisodeLength: skip.EpisodeLength,
+		})
+	}
+	sort.SliceStable(result.Intervals, func(i, j int) bool {
+		return result.Intervals[i].Start < result.Intervals[j].Start
+	})
+	return result, nil
+}
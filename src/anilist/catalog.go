@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// perPage is the number of anime per page of search, popular and schedule
+const perPage = 25
+
+// mediaFields are the fields of a Media read for lists; details adds more
+const mediaFields = `id idMal title { romaji english native } synonyms format status episodes season seasonYear averageScore genres coverImage { extraLarge large } bannerImage studios(isMain: true) { nodes { name } }`
+
+// media is an anime as the API returns it
+type media struct {
+	ID    int `json:"id"`
+	IDMal int `json:"idMal"`
+	Title struct {
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+		Native  string `json:"native"`
+	} `json:"title"`
+	Synonyms     []string `json:"synonyms"`
+	Type         string   `json:"type"`   // ANIME or MANGA; only read for relations
+	Format       string   `json:"format"` // TV, TV_SHORT, MOVIE, SPECIAL, OVA, ONA, MUSIC, ...
+	Status       string   `json:"status"` // FINISHED, RELEASING, NOT_YET_RELEASED, CANCELLED, HIATUS
+	Episodes     int      `json:"episodes"`
+	Duration     int      `json:"duration"` // Minutes per episode
+	Season       string   `json:"season"`   // WINTER, SPRING, SUMMER, FALL
+	SeasonYear   int      `json:"seasonYear"`
+	AverageScore int      `json:"averageScore"`
+	Genres       []string `json:"genres"`
+	Description  string   `json:"description"`
+	CoverImage   struct {
+		ExtraLarge string `json:"extraLarge"`
+		Large      string `json:"large"`
+	} `json:"coverImage"`
+	BannerImage string `json:"bannerImage"`
+	Studios     struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"studios"`
+	Tags []struct {
+		Name             string `json:"name"`
+		IsGeneralSpoiler bool   `json:"isGeneralSpoiler"`
+		IsMediaSpoiler   bool   `json:"isMediaSpoiler"`
+	} `json:"tags"`
+	NextAiringEpisode *struct {
+		Episode  int   `json:"episode"`
+		AiringAt int64 `json:"airingAt"`
+	} `json:"nextAiringEpisode"`
+}
+
+// Airing is an upcoming episode
+type Airing struct {
+	Episode  int   `json:"episode"`
+	AiringAt int64 `json:"airing_at"` // Unix time
+}
+
+// Anime extends scraper.Anime with the IDs other sources and trackers use
+// and the details AniList has on top of the common fields
+type Anime struct {
+	scraper.Anime
+	AniListID   int     `json:"anilist_id"`
+	MalID       int     `json:"mal_id,omitempty"`
+	Format      string  `json:"format,omitempty"`       // e.g. "TV", "MOVIE", "ONA"
+	Season      string  `json:"season,omitempty"`       // e.g. "FALL 2023"
+	Score       int     `json:"score,omitempty"`        // Average score out of 100
+	Duration    int     `json:"duration,omitempty"`     // Minutes per episode
+	BannerURL   string  `json:"banner_url,omitempty"`   // Wide banner image
+	NextEpisode *Airing `json:"next_episode,omitempty"` // Next episode to air, while the show airs
+}
+
+// toAnime converts a Media, titled in the selected language
+func (s *Scraper) toAnime(m media) Anime {
+	title := m.Title.Romaji
+	switch s.title {
+	case "english":
+		if m.Title.English != "" {
+			title = m.Title.English
+		}
+	case "native":
+		if m.Title.Native != "" {
+			title = m.Title.Native
+		}
+	}
+	var alternatives []string
+	for _, alt := range append([]string{m.Title.Romaji, m.Title.English, m.Title.Native}, m.Synonyms...) {
+		if alt != "" && alt != title && !containsTitle(alternatives, alt) {
+			alternatives = append(alternatives, alt)
+		}
+	}
+	var studios []string
+	for _, studio := range m.Studios.Nodes {
+		studios = append(studios, studio.Name)
+	}
+	var tags []string
+	for _, tag := range m.Tags {
+		if !tag.IsGeneralSpoiler && !tag.IsMediaSpoiler {
+			tags = append(tags, tag.Name)
+		}
+	}
+	thumbnail := m.CoverImage.ExtraLarge
+	if thumbnail == "" {
+		thumbnail = m.CoverImage.Large
+	}
+
+	anime := Anime{
+		Anime: scraper.Anime{
+			ID:                strconv.Itoa(m.ID),
+			Title:             title,
+			Artist:            strings.Join(studios, ", "),
+			Description:       plainDescription(m.Description),
+			Genre:             strings.Join(m.Genres, ", "),
+			ThumbnailURL:      thumbnail,
+			Status:            airingStatus(m.Status),
+			AlternativeTitles: alternatives,
+			Episodes:          m.Episodes,
+			Tags:              tags,
+			ReleaseYear:       m.SeasonYear,
+		},
+		AniListID: m.ID,
+		MalID:     m.IDMal,
+		Format:    m.Format,
+		Score:     m.AverageScore,
+		Duration:  m.Duration,
+		BannerURL: m.BannerImage,
+	}
+	if next := m.NextAiringEpisode; next != nil {
+		anime.NextEpisode = &Airing{Episode: next.Episode, AiringAt: next.AiringAt}
+	}
+	if m.Season != "" && m.SeasonYear != 0 {
+		anime.Season = fmt.Sprintf("%s %d", m.Season, m.SeasonYear)
+	}
+	return anime
+}
+
+// containsTitle reports whether titles already holds title, ignoring case
+func containsTitle(titles []string, title string) bool {
+	for _, existing := range titles {
+		if strings.EqualFold(existing, title) {
+			return true
+		}
+	}
+	return false
+}
+
+// airingStatus maps AniList's MediaStatus onto the scraper status constants
+func airingStatus(status string) string {
+	switch status {
+	case "RELEASING":
+		return scraper.StatusOngoing
+	case "FINISHED":
+		return scraper.StatusCompleted
+	case "CANCELLED":
+		return scraper.StatusCancelled
+	case "HIATUS":
+		return scraper.StatusOnHiatus
+	}
+	return scraper.StatusUnknown
+}
+
+// blankLines matches the runs of empty lines <br><br> leaves behind
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// plainDescription strips the HTML tags and entities AniList descriptions
+// contain even when not requested as HTML
+func plainDescription(description string) string {
+	lineBreaks := strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n")
+	doc, err := htmlx.ParseString(lineBreaks.Replace(description))
+	if err != nil {
+		return description
+	}
+	return blankLines.ReplaceAllString(strings.TrimSpace(doc.Text()), "\n\n")
+}
+
+// mediaID extracts the AniList ID from an anime ID, either the ID itself or
+// a URL such as https://anilist.co/anime/154587/Sousou-no-Frieren/
+func mediaID(animeID string) (int, error) {
+	path := animeID
+	if u, err := url.Parse(animeID); err == nil {
+		path = u.Path
+	}
+	path = strings.Trim(path, "/")
+	if _, rest, found := strings.Cut(path, "anime/"); found {
+		path, _, _ = strings.Cut(rest, "/")
+	}
+	id, err := strconv.Atoi(path)
+	if err != nil || id <= 0 {
+		return 0, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected an AniList ID, e.g. 154587)", animeID)
+	}
+	return id, nil
+}
+
+// mediaPage lists a page of anime sorted by sort, matching search when set.
+// Adult entries are left out.
+func (s *Scraper) mediaPage(ctx context.Context, search, sort string, page int) ([]Anime, error) {
+	if page < 1 {
+		page = 1
+	}
+	query := `query ($page: Int, $perPage: Int, $search: String, $sort: [MediaSort]) {
+  Page(page: $page, perPage: $perPage) {
+    media(type: ANIME, isAdult: false, search: $search, sort: $sort) { ` + mediaFields + ` }
+  }
+}`
+	variables := map[string]interface{}{"page": page, "perPage": perPage, "sort": []string{sort}}
+	if search != "" {
+		variables["search"] = search
+	}
+
+	var data struct {
+		Page struct {
+			Media []media `json:"media"`
+		} `json:"Page"`
+	}
+	if err := s.queryAPI(ctx, query, variables, &data); err != nil {
+		return nil, err
+	}
+	animes := []Anime{}
+	for _, m := range data.Page.Media {
+		animes = append(animes, s.toAnime(m))
+	}
+	return animes, nil
+}
+
+// SearchAnime searches for anime by title, best matches first
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]Anime, error) {
+	return s.mediaPage(ctx, query, "SEARCH_MATCH", page)
+}
+
+// GetPopularAnime lists the anime trending on AniList
+func (s *Scraper) GetPopularAnime(ctx context.Context, page int) ([]Anime, error) {
+	return s.mediaPage(ctx, "", "TRENDING_DESC", page)
+}
+
+// GetAnimeDetails retrieves the description, genres, tags, studios, score and
+// next episode of an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (Anime, error) {
+	id, err := mediaID(animeID)
+	if err != nil {
+		return Anime{}, err
+	}
+	query := `query ($id: Int) {
+  Media(id: $id, type: ANIME) {
+    ` + mediaFields + ` duration description(asHtml: false) tags { name isGeneralSpoiler isMediaSpoiler } nextAiringEpisode { episode airingAt }
+  }
+}`
+	var data struct {
+		Media *media `json:"Media"`
+	}
+	if err := s.queryAPI(ctx, query, map[string]interface{}{"id": id}, &data); err != nil {
+		var extErr *exterr.Error
+		if errors.As(err, &extErr) && extErr.Code == exterr.NotFound {
+			return Anime{}, exterr.New(exterr.NotFound, "anime %d not found on AniList", id)
+		}
+		return Anime{}, err
+	}
+	if data.Media == nil {
+		return Anime{}, exterr.New(exterr.NotFound, "anime %d not found on AniList", id)
+	}
+	return s.toAnime(*data.Media), nil
+}
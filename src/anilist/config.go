@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Config holds user overrides read from the extension config file. Flags
+// given on the command line win over the file.
+type Config struct {
+	cli.Config
+	Title string `json:"title,omitempty"` // Default for -title, e.g. english
+
+	APIURL string `json:"api_url,omitempty"` // GraphQL endpoint, e.g. https://graphql.anilist.co
+}
+
+// LoadConfig reads the config file at path, or the default location when path is empty
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path == "" {
+		defaultPath, err := extconfig.Path("anilist")
+		if err != nil {
+			return cfg, err
+		}
+		path = defaultPath
+	}
+
+	err := extconfig.Load(path, &cfg)
+	return cfg, err
+}
+
+// ApplyConfig overrides the scraper defaults with the non-empty values of cfg
+func (s *Scraper) ApplyConfig(cfg Config) {
+	if cfg.APIURL != "" {
+		s.apiURL = strings.TrimRight(cfg.APIURL, "/")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// graphqlError is an error AniList reports alongside, or instead of, data
+type graphqlError struct {
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+}
+
+// graphqlResponse is the envelope of every API response
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphqlError  `json:"errors"`
+}
+
+// queryAPI POSTs a GraphQL query and decodes its data into v. AniList answers
+// errors with their HTTP status in the body as well, so the body is read
+// whatever the status; a missing Media is a 404 with "Not Found." as message.
+func (s *Scraper) queryAPI(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return exterr.New(exterr.Internal, "error encoding query: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	var response graphqlResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&response)
+	if len(response.Errors) > 0 {
+		messages := make([]string, len(response.Errors))
+		for i, e := range response.Errors {
+			messages[i] = e.Message
+		}
+		message := strings.Join(messages, "; ")
+		switch status := response.Errors[0].Status; {
+		case status == http.StatusNotFound || resp.StatusCode == http.StatusNotFound:
+			return exterr.New(exterr.NotFound, "not found on AniList")
+		case status == http.StatusBadRequest:
+			return exterr.New(exterr.InvalidArgument, "AniList rejected the query: %s", message)
+		case resp.StatusCode >= 400:
+			return exterr.New(exterr.StatusCode(resp.StatusCode), "error from AniList: %s", message)
+		}
+		return exterr.New(exterr.Upstream, "error from AniList: %s", message)
+	}
+	if resp.StatusCode >= 400 {
+		return exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	if decodeErr != nil {
+		return exterr.New(exterr.Parse, "error parsing response: %w", decodeErr)
+	}
+	if err := json.Unmarshal(response.Data, v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response: %w", err)
+	}
+	return nil
+}
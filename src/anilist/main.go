@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// sourceID identifies the AniList source
+const sourceID = "297628545340942341"
+
+// defaultAPIURL is AniList's GraphQL API. api_url in the config file points
+// the extension elsewhere, e.g. at a caching proxy.
+const defaultAPIURL = "https://graphql.anilist.co"
+
+// CapabilityMetadataOnly marks a source that describes anime but has no
+// episodes or streams. Clients use it to enrich entries from video sources and
+// for discovery, and testers skip the stream pipeline for it.
+const CapabilityMetadataOnly = "metadata-only"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// TitleLanguages lists the values of -title
+var TitleLanguages = []string{"romaji", "english", "native"}
+
+type Scraper struct {
+	apiURL string
+	title  string // Title language anime are listed under
+	client *httpclient.Client
+	retry  httpclient.RetryPolicy
+}
+
+// NewScraper creates a new instance of the anilist scraper
+func NewScraper() *Scraper {
+	return &Scraper{
+		apiURL: defaultAPIURL,
+		title:  "romaji",
+		client: httpclient.New(),
+		retry:  httpclient.DefaultRetryPolicy,
+	}
+}
+
+// Requests per minute AniList allows before answering 429, as declared in
+// SourceInfo.RateLimit. The API's documented limit is 90, lowered to 30 while
+// it runs degraded, which has been the case for a long time. Every command
+// needs a single request.
+const (
+	rateLimit = 30
+	rateBurst = 5
+)
+
+// LimitRate throttles requests to AniList to rateLimit, sharing the budget
+// with every other invocation through a state file in the cache directory
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("anilist")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains(), ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetTitleLanguage selects the language anime titles are listed in
+func (s *Scraper) SetTitleLanguage(language string) error {
+	for _, valid := range TitleLanguages {
+		if language == valid {
+			s.title = language
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid title language %q (valid: %s)", language, strings.Join(TitleLanguages, ", "))
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Sources     []SourceInfo            `json:"sources"`
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// SourceInfo extends scraper.SourceInfo with what the source can do beyond
+// the supports* flags
+type SourceInfo struct {
+	scraper.SourceInfo
+	Capabilities []string `json:"capabilities,omitempty"` // e.g. ["metadata-only"]
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "AniList",
+			Package: "anilist",
+			Lang:    "en",
+			Version: version,
+		},
+		Sources: []SourceInfo{source},
+		Permissions: permissions.Permissions{
+			// The API; api_url in the config file can point elsewhere
+			Network: []string{"graphql.anilist.co"},
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/anilist.json (read)",
+				"$PAIR_CACHE_DIR/extensions/anilist/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/anilist (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (SourceInfo, error) {
+	return SourceInfo{
+		SourceInfo: scraper.SourceInfo{
+			ID:                   sourceID,
+			Name:                 "AniList",
+			BaseURL:              "https://anilist.co",
+			Language:             "en",
+			RateLimit:            rateLimit,
+			SupportsSearch:       true,
+			SupportsRelatedAnime: true,
+		},
+		Capabilities: []string{CapabilityMetadataOnly},
+	}, nil
+}
+
+// domains returns the hosts doctor checks: the API
+func (s *Scraper) domains() []string {
+	host := "graphql.anilist.co"
+	if u, err := url.Parse(s.apiURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return []string{host}
+}
+
+func main() {
+	var (
+		query    = flag.String("query", "", "Search query")
+		page     = flag.Int("page", 1, "Page number")
+		animeURL = flag.String("anime", "", "AniList ID or URL, e.g. 154587 or https://anilist.co/anime/154587")
+		days     = flag.Int("days", 7, "With schedule: list the episodes airing within this many days from now")
+		title    = flag.String("title", "romaji", "Language of anime titles: romaji, english or native (falls back to romaji when missing)")
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:       "anilist",
+		SourceID:      sourceID,
+		Version:       version,
+		Summary:       "A command-line tool for looking up anime metadata on AniList. It lists no episodes or streams.",
+		MetadataOnly:  true,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Title != "" && !cli.IsFlagSet("title") {
+				*title = cfg.Title
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetTitleLanguage(*title); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			return nil
+		},
+	}
+
+	// Clients that ignore the capability get a clear error instead of an unknown command
+	unsupported := func(name string) cli.Command {
+		return cli.Command{Name: name, Run: func(ctx context.Context) (interface{}, error) {
+			return nil, exterr.New(exterr.Unsupported, "AniList is a metadata-only source and has no episodes or streams; use a video source for %s", name)
+		}}
+	}
+
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime by title.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "popular", Description: "Get the currently trending anime.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetPopularAnime(ctx, *page)
+		}},
+		{Name: "details", Description: "Get the description, genres, studios, score and next episode of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "related", Description: "Get sequels, prequels, side stories and other related anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetRelatedAnime(ctx, *animeURL, *page)
+		}},
+		{Name: "schedule", Description: "Get the episodes airing in the next -days days.", Run: func(ctx context.Context) (interface{}, error) {
+			if *days < 1 || *days > 31 {
+				return nil, exterr.New(exterr.InvalidArgument, "-days must be between 1 and 31")
+			}
+			return s.GetSchedule(ctx, time.Now(), *days, *page)
+		}},
+		unsupported("episodes"),
+		unsupported("stream-url"),
+	}
+	app.Main()
+}
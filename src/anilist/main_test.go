@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "frieren", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].AniListID != 154587 {
+		t.Fatalf("SearchAnime results = %+v, want AniList ID 154587 first", results)
+	}
+
+	details, err := s.GetAnimeDetails(ctx, "https://anilist.co/anime/154587/Sousou-no-Frieren/")
+	if err != nil {
+		t.Fatalf("GetAnimeDetails: %v", err)
+	}
+	if details.AniListID != 154587 || details.Title == "" {
+		t.Errorf("GetAnimeDetails = %+v, want the details of 154587", details)
+	}
+
+	related, err := s.GetRelatedAnime(ctx, "154587", 1)
+	if err != nil {
+		t.Fatalf("GetRelatedAnime: %v", err)
+	}
+	for i := 1; i < len(related); i++ {
+		if relationRank(related[i-1].Relation) > relationRank(related[i].Relation) {
+			t.Errorf("GetRelatedAnime lists %s before %s", related[i-1].Relation, related[i].Relation)
+		}
+	}
+
+	_, err = s.GetAnimeDetails(ctx, "1")
+	if e := exterr.From(err, exterr.Internal); e.Code != exterr.NotFound {
+		t.Errorf("GetAnimeDetails of a missing ID error code = %q, want %q", e.Code, exterr.NotFound)
+	}
+}
+
+func TestGetSchedule(t *testing.T) {
+	s := newMockScraper(t)
+	entries, err := s.GetSchedule(context.Background(), time.Unix(1700000000, 0), 7, 1)
+	if err != nil {
+		t.Fatalf("GetSchedule: %v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("GetSchedule returned no entries")
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i-1].AiringAt > entries[i].AiringAt {
+			t.Errorf("GetSchedule entry %d airs before entry %d", i, i-1)
+		}
+	}
+}
+
+func TestMediaID(t *testing.T) {
+	tests := []struct {
+		animeID string
+		want    int
+		wantErr bool
+	}{
+		{animeID: "154587", want: 154587},
+		{animeID: "https://anilist.co/anime/154587/Sousou-no-Frieren/", want: 154587},
+		{animeID: "/anime/21", want: 21},
+		{animeID: "0", wantErr: true},
+		{animeID: "frieren", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := mediaID(tt.animeID)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("mediaID(%q) = %d, %v, want %d (error %v)", tt.animeID, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAiringStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"RELEASING", scraper.StatusOngoing},
+		{"FINISHED", scraper.StatusCompleted},
+		{"CANCELLED", scraper.StatusCancelled},
+		{"HIATUS", scraper.StatusOnHiatus},
+		{"NOT_YET_RELEASED", scraper.StatusUnknown},
+	}
+	for _, tt := range tests {
+		if got := airingStatus(tt.status); got != tt.want {
+			t.Errorf("airingStatus(%q) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestPlainDescription(t *testing.T) {
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{"The adventure is over.<br><br><br>But life goes on. <i>(Source: Crunchyroll)</i>", "The adventure is over.\n\nBut life goes on. (Source: Crunchyroll)"},
+		{"Tom &amp; Jerry", "Tom & Jerry"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := plainDescription(tt.description); got != tt.want {
+			t.Errorf("plainDescription(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
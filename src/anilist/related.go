@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// RelatedAnime extends Anime with how the show relates to the requested one
+type RelatedAnime struct {
+	Anime
+	Relation string `json:"relation"` // prequel, sequel, side_story, spin_off, ...
+}
+
+// relationOrder lists the relations shown first, in order; others follow alphabetically
+var relationOrder = []string{"prequel", "sequel", "parent", "side_story", "spin_off", "alternative", "summary", "other"}
+
+// GetRelatedAnime retrieves the sequels, prequels, side stories and other
+// anime AniList relates to an anime. The manga and novels it adapts are left out.
+func (s *Scraper) GetRelatedAnime(ctx context.Context, animeID string, page int) ([]RelatedAnime, error) {
+	related := []RelatedAnime{}
+	// All relations fit on the first page
+	if page > 1 {
+		return related, nil
+	}
+	id, err := mediaID(animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `query ($id: Int) {
+  Media(id: $id, type: ANIME) {
+    id relations { edges { relationType(version: 2) node { type isAdult ` + mediaFields + ` } } }
+  }
+}`
+	var data struct {
+		Media *struct {
+			Relations struct {
+				Edges []struct {
+					RelationType string `json:"relationType"`
+					Node         struct {
+						media
+						IsAdult bool `json:"isAdult"`
+					} `json:"node"`
+				} `json:"edges"`
+			} `json:"relations"`
+		} `json:"Media"`
+	}
+	if err := s.queryAPI(ctx, query, map[string]interface{}{"id": id}, &data); err != nil {
+		var extErr *exterr.Error
+		if errors.As(err, &extErr) && extErr.Code == exterr.NotFound {
+			return nil, exterr.New(exterr.NotFound, "anime %d not found on AniList", id)
+		}
+		return nil, err
+	}
+	if data.Media == nil {
+		return nil, exterr.New(exterr.NotFound, "anime %d not found on AniList", id)
+	}
+
+	for _, edge := range data.Media.Relations.Edges {
+		if edge.Node.Type != "ANIME" || edge.Node.IsAdult {
+			continue
+		}
+		related = append(related, RelatedAnime{
+			Anime:    s.toAnime(edge.Node.media),
+			Relation: strings.ToLower(edge.RelationType),
+		})
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		ri, rj := relationRank(related[i].Relation), relationRank(related[j].Relation)
+		if ri != rj {
+			return ri < rj
+		}
+		return related[i].Relation < related[j].Relation
+	})
+	return related, nil
+}
+
+// relationRank returns the position of relation in relationOrder, placing unknown relations last
+func relationRank(relation string) int {
+	for i, known := range relationOrder {
+		if relation == known {
+			return i
+		}
+	}
+	return len(relationOrder)
+}
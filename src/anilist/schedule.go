@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// ScheduleEntry is an episode airing within the requested window
+type ScheduleEntry struct {
+	Anime    Anime `json:"anime"`
+	Episode  int   `json:"episode"`
+	AiringAt int64 `json:"airing_at"` // Unix time
+}
+
+// GetSchedule lists the episodes airing between now and days later, soonest
+// first. Adult shows are left out.
+func (s *Scraper) GetSchedule(ctx context.Context, now time.Time, days, page int) ([]ScheduleEntry, error) {
+	if page < 1 {
+		page = 1
+	}
+	query := `query ($page: Int, $perPage: Int, $from: Int, $to: Int) {
+  Page(page: $page, perPage: $perPage) {
+    airingSchedules(airingAt_greater: $from, airingAt_lesser: $to, sort: TIME) {
+      episode airingAt media { isAdult ` + mediaFields + ` }
+    }
+  }
+}`
+	variables := map[string]interface{}{
+		"page":    page,
+		"perPage": perPage,
+		"from":    now.Unix(),
+		"to":      now.Add(time.Duration(days) * 24 * time.Hour).Unix(),
+	}
+
+	var data struct {
+		Page struct {
+			AiringSchedules []struct {
+				Episode  int   `json:"episode"`
+				AiringAt int64 `json:"airingAt"`
+				Media    *struct {
+					media
+					IsAdult bool `json:"isAdult"`
+				} `json:"media"`
+			} `json:"airingSchedules"`
+		} `json:"Page"`
+	}
+	if err := s.queryAPI(ctx, query, variables, &data); err != nil {
+		return nil, err
+	}
+
+	schedule := []ScheduleEntry{}
+	for _, airing := range data.Page.AiringSchedules {
+		if airing.Media == nil || airing.Media.IsAdult {
+			continue
+		}
+		schedule = append(schedule, ScheduleEntry{
+			Anime:    s.toAnime(airing.Media.media),
+			Episode:  airing.Episode,
+			AiringAt: airing.AiringAt,
+		})
+	}
+	return schedule, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the metadata a detail screen needs
+type AnimeDetails struct {
+	scraper.Anime
+	Score float64 `json:"score,omitempty"` // Rating out of 10
+	Year  int     `json:"year,omitempty"`  // Year the show started airing
+}
+
+// The site keeps a show's dub apart from its subtitled release, under the
+// same slug with dubSuffix. Anime IDs are the slug of the subtitled release;
+// the translation selects which of the two pages is read.
+const (
+	dubSuffix     = "-dublado"
+	listingSuffix = "-todos-os-episodios" // Suffix of anime page slugs: "all episodes"
+)
+
+// dubTitle matches the marker the site appends to the titles of dubs
+var dubTitle = regexp.MustCompile(`(?i)\s*\(dublado\)\s*$`)
+
+// animeIDFromHref extracts the anime ID from a link to the show or one of its
+// episodes, such as /animes/one-piece-todos-os-episodios or
+// /animes/one-piece-dublado/12, and reports whether the link is to the dub.
+// Full URLs and bare IDs are accepted as well.
+func animeIDFromHref(href string) (id string, dub bool) {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	href = strings.TrimPrefix(strings.Trim(href, "/"), "animes/")
+	id, _, _ = strings.Cut(href, "/")
+	id = strings.TrimSuffix(id, listingSuffix)
+	if strings.HasSuffix(id, dubSuffix) {
+		return strings.TrimSuffix(id, dubSuffix), true
+	}
+	return id, false
+}
+
+// slug returns the site's slug of an anime in the selected translation
+func (s *Scraper) slug(animeID string) (string, error) {
+	id, _ := animeIDFromHref(animeID)
+	if id == "" {
+		return "", exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece)", animeID)
+	}
+	if s.translation == "dub" {
+		return id + dubSuffix, nil
+	}
+	return id, nil
+}
+
+// listing fetches a page of show cards, as search and the lists render them,
+// keeping the shows of the selected translation
+func (s *Scraper) listing(ctx context.Context, path string, page int) ([]scraper.Anime, error) {
+	if page > 1 {
+		path += "/" + strconv.Itoa(page)
+	}
+	doc, err := s.getPage(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	doc.Find("div.divCardUltimosEps").Each(func(_ int, card *goquery.Selection) {
+		id, dub := animeIDFromHref(htmlx.Attr(card.Find("a[href*='/animes/']").First(), "href", ""))
+		if id == "" || dub != (s.translation == "dub") {
+			return
+		}
+		animes = append(animes, scraper.Anime{
+			ID:           id,
+			Title:        dubTitle.ReplaceAllString(htmlx.TextFirst(card, "h3.animeTitle"), ""),
+			ThumbnailURL: htmlx.AttrAny(card.Find("img.imgAnimes").First(), "", "data-src", "src"),
+			Status:       scraper.StatusUnknown,
+		})
+	})
+	return animes, nil
+}
+
+// searchSlug turns a query into the path segment of the search page, which
+// takes words joined by dashes
+func searchSlug(query string) string {
+	return url.PathEscape(strings.Join(strings.Fields(strings.ToLower(query)), "-"))
+}
+
+// SearchAnime searches for anime by title
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	return s.listing(ctx, "/pesquisar/"+searchSlug(query), page)
+}
+
+// GetPopularAnime retrieves the highest rated shows
+func (s *Scraper) GetPopularAnime(ctx context.Context, page int) ([]scraper.Anime, error) {
+	return s.listing(ctx, "/top-animes", page)
+}
+
+// GetLatestUpdates retrieves the airing shows
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]scraper.Anime, error) {
+	animes, err := s.listing(ctx, "/em-lancamento", page)
+	for i := range animes {
+		animes[i].Status = scraper.StatusOngoing
+	}
+	return animes, err
+}
+
+// animePage fetches the page of a show in the selected translation
+func (s *Scraper) animePage(ctx context.Context, animeID string) (*goquery.Document, error) {
+	slug, err := s.slug(animeID)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.getPage(ctx, "/animes/"+slug+listingSuffix, nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find("div.div_anime_names").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found (translation %s)", animeID, s.translation)
+	}
+	return doc, nil
+}
+
+// airingStatus maps AnimeFire's airing statuses onto the scraper statuses
+func airingStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "em lançamento":
+		return scraper.StatusOngoing
+	case "completo":
+		return scraper.StatusCompleted
+	}
+	return scraper.StatusUnknown
+}
+
+// GetAnimeDetails retrieves description, genres, score and airing status for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	var genres []string
+	doc.Find("a.spanGeneros").Each(func(_ int, link *goquery.Selection) {
+		genres = append(genres, htmlx.Text(link))
+	})
+	var titles []string
+	if alt := htmlx.TextFirst(doc.Selection, "div.div_anime_names h6"); alt != "" {
+		titles = append(titles, alt)
+	}
+	id, _ := animeIDFromHref(animeID)
+	details := AnimeDetails{
+		Anime: scraper.Anime{
+			ID:                id,
+			Title:             dubTitle.ReplaceAllString(htmlx.TextFirst(doc.Selection, "div.div_anime_names h1"), ""),
+			Description:       htmlx.TextFirst(doc.Selection, "div.divSinopse span.spanAnimeInfo"),
+			Genre:             strings.Join(genres, ", "),
+			ThumbnailURL:      htmlx.AttrAny(doc.Find("div.sub_animepage_img img").First(), "", "data-src", "src"),
+			Status:            scraper.StatusUnknown,
+			AlternativeTitles: titles,
+		},
+	}
+	details.Score, _ = strconv.ParseFloat(htmlx.TextFirst(doc.Selection, "#anime_score"), 64)
+
+	// Info lines pair a bold label with a value, e.g. <b>Ano:</b> <span>1999</span>
+	doc.Find("div.animeInfo").Each(func(_ int, info *goquery.Selection) {
+		value := htmlx.TextFirst(info, "span.spanAnimeInfo")
+		switch strings.TrimSuffix(htmlx.TextFirst(info, "b"), ":") {
+		case "Status do Anime":
+			details.Status = airingStatus(value)
+		case "Ano":
+			details.Year, _ = strconv.Atoi(value)
+		case "Episódios":
+			details.Episodes, _ = strconv.Atoi(value)
+		case "Estúdios":
+			details.Artist = value
+		}
+	})
+	if details.Episodes == 0 {
+		details.Episodes = len(pageEpisodes(doc))
+	}
+	return details, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// episodeLink is an entry of the episode list of an anime page
+type episodeLink struct {
+	number float64
+	title  string
+}
+
+// pageEpisodes reads the episode list of an anime page, oldest first. Episode
+// links end in the episode number, e.g. /animes/one-piece/12.
+func pageEpisodes(doc *goquery.Document) []episodeLink {
+	episodes := []episodeLink{}
+	seen := map[float64]bool{}
+	doc.Find("div.div_video_list a[href*='/animes/']").Each(func(_ int, link *goquery.Selection) {
+		href := htmlx.Attr(link, "href", "")
+		if u, err := url.Parse(href); err == nil {
+			href = u.Path
+		}
+		number, err := strconv.ParseFloat(path.Base(href), 64)
+		if err != nil || seen[number] {
+			return
+		}
+		seen[number] = true
+		episodes = append(episodes, episodeLink{number: number, title: htmlx.Text(link)})
+	})
+	sort.Slice(episodes, func(i, j int) bool { return episodes[i].number < episodes[j].number })
+	return episodes
+}
+
+// GetEpisodeList returns the episodes of an anime in the selected translation
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+	slug, _ := s.slug(animeID)
+
+	episodes := []scraper.Episode{}
+	for _, link := range pageEpisodes(doc) {
+		number := strconv.FormatFloat(link.number, 'f', -1, 64)
+		name := link.title
+		if name == "" {
+			name = "Episódio " + number
+		}
+		episodes = append(episodes, scraper.Episode{
+			ID:            s.baseURL + "/animes/" + slug + "/" + number,
+			Name:          name,
+			EpisodeNumber: link.number,
+		})
+	}
+	return episodes, nil
+}
+
+// VideoResponse lists the direct links of an episode, best quality first
+type VideoResponse struct {
+	Streams []scraper.Video `json:"streams"`
+}
+
+// videoLinks is the response of /video/{slug}/{episode}, which the episode
+// page's player loads its sources from
+type videoLinks struct {
+	Data []struct {
+		Src   string `json:"src"`
+		Label string `json:"label"` // Quality, e.g. "360p", "720p" or "1080p"
+	} `json:"data"`
+}
+
+// qualityHeight returns the height of a quality such as "720p", for sorting
+func qualityHeight(quality string) int {
+	height, _ := strconv.Atoi(strings.TrimSuffix(strings.ToLower(quality), "p"))
+	return height
+}
+
+// GetVideoList returns the direct links of an episode in the selected
+// translation, one per quality the site encoded
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	slug, err := s.slug(animeID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	number := strconv.FormatFloat(episodeNumber, 'f', -1, 64)
+
+	var links videoLinks
+	if err := s.getJSON(ctx, "/video/"+slug+"/"+number, nil, &links); err != nil {
+		return VideoResponse{}, err
+	}
+	if len(links.Data) == 0 {
+		// Episodes without direct links play from an embedded Blogger player only
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %s of %s has no direct links", number, slug)
+	}
+
+	id, _ := animeIDFromHref(animeID)
+	response := VideoResponse{Streams: []scraper.Video{}}
+	for _, link := range links.Data {
+		if link.Src == "" {
+			continue
+		}
+		response.Streams = append(response.Streams, scraper.Video{
+			ID:       id,
+			Quality:  link.Label,
+			VideoURL: link.Src,
+			// The file servers refuse requests without the site as referer
+			Headers: map[string]string{"User-Agent": s.client.UserAgent(hosters.UserAgent), "Referer": s.baseURL + "/"},
+		})
+	}
+	sort.SliceStable(response.Streams, func(i, j int) bool {
+		return qualityHeight(response.Streams[i].Quality) > qualityHeight(response.Streams[j].Quality)
+	})
+	return response, nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair/pkg/scraper"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// sourceID identifies the AnimeFire source
+const sourceID = "461114847418340066"
+
+// defaultBaseURL is AnimeFire's site. base_url in the config file points the
+// extension at a mirror when the site moves.
+const defaultBaseURL = "https://animefire.plus"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// TranslationTypes lists the values of -translation: Japanese audio with
+// Brazilian Portuguese subtitles, or the Brazilian Portuguese dub
+var TranslationTypes = []string{"sub", "dub"}
+
+type Scraper struct {
+	baseURL     string
+	translation string // Translation type used for streams
+	client      *httpclient.Client
+	retry       httpclient.RetryPolicy
+}
+
+// NewScraper creates a new instance of the animefire scraper
+func NewScraper() *Scraper {
+	client := httpclient.New()
+	return &Scraper{
+		baseURL:     defaultBaseURL,
+		translation: "sub",
+		client:      client,
+		retry:       httpclient.DefaultRetryPolicy,
+	}
+}
+
+// Requests per minute AnimeFire tolerates before its Cloudflare front answers
+// 429, as declared in SourceInfo.RateLimit. A stream-url command needs one
+// request to the site.
+const (
+	rateLimit = 30
+	rateBurst = 5
+)
+
+// LimitRate throttles requests to AnimeFire to rateLimit, sharing the budget
+// with every other invocation through a state file in the cache directory. The
+// file servers are left unthrottled.
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("animefire")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains()[:1], ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetTranslation selects sub or dub
+func (s *Scraper) SetTranslation(translation string) error {
+	for _, valid := range TranslationTypes {
+		if translation == valid {
+			s.translation = translation
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid translation type %q (valid: %s)", translation, strings.Join(TranslationTypes, ", "))
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "AnimeFire",
+			Package: "animefire",
+			Lang:    "pt-BR",
+			Version: version,
+			Sources: []scraper.SourceInfo{source},
+		},
+		Permissions: permissions.Permissions{
+			// The site (base_url in the config file can point elsewhere) and the
+			// file server of its direct links
+			Network: permissions.Domains("animefire.plus", "lightspeedst.net"),
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/animefire.json (read)",
+				"$PAIR_CACHE_DIR/extensions/animefire/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/animefire (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (scraper.SourceInfo, error) {
+	return scraper.SourceInfo{
+		ID:             sourceID,
+		Name:           "AnimeFire",
+		BaseURL:        s.baseURL,
+		Language:       "pt-BR",
+		RateLimit:      rateLimit,
+		SupportsLatest: true,
+		SupportsSearch: true,
+	}, nil
+}
+
+// getPage fetches a page of the site and parses it as HTML
+func (s *Scraper) getPage(ctx context.Context, path string, query url.Values) (*goquery.Document, error) {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := htmlx.Parse(resp.Body)
+	if err != nil {
+		return nil, exterr.New(exterr.Parse, "%w", err)
+	}
+	return doc, nil
+}
+
+// getJSON calls one of the site's JSON endpoints and decodes its response into v
+func (s *Scraper) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// get sends a GET request for path on the site, turning error statuses into errors
+func (s *Scraper) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	rawURL := s.baseURL + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", hosters.UserAgent)
+	req.Header.Set("Referer", s.baseURL+"/")
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return nil, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, exterr.New(exterr.NotFound, "%s not found", path)
+		}
+		return nil, exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	return resp, nil
+}
+
+// domains returns the hosts doctor checks: the site
+func (s *Scraper) domains() []string {
+	host := "animefire.plus"
+	if u, err := url.Parse(s.baseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return []string{host}
+}
+
+func main() {
+	var (
+		query       = flag.String("query", "", "Search query")
+		page        = flag.Int("page", 1, "Page number")
+		animeURL    = flag.String("anime", "", "Anime URL or ID, e.g. one-piece")
+		episode     = flag.Float64("episode", 0, "Episode number")
+		translation = flag.String("translation", "sub", "Translation type: sub (Japanese audio, Portuguese subtitles) or dub (Brazilian Portuguese)")
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:       "animefire",
+		SourceID:      sourceID,
+		Version:       version,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Translation != "" && !cli.IsFlagSet("translation") {
+				*translation = cfg.Translation
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetTranslation(*translation); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			return nil
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime on a source.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "popular", Description: "Get the highest rated anime.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetPopularAnime(ctx, *page)
+		}},
+		{Name: "latest", Description: "Get the airing anime.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetLatestUpdates(ctx, *page)
+		}},
+		{Name: "details", Description: "Get the description, genres, score and airing status of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "episodes", Description: "Get the list of episodes for an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetEpisodeList(ctx, *animeURL)
+		}},
+		{Name: "stream-url", Description: "Get the direct links of an anime episode, one per quality.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			return s.GetVideoList(ctx, *animeURL, *episode)
+		}},
+	}
+	app.Main()
+}
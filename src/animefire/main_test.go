@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "one-piece" {
+		t.Fatalf("SearchAnime results = %+v, want one-piece first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 3", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, 1)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	var qualities []string
+	for _, v := range videos.Streams {
+		qualities = append(qualities, v.Quality)
+	}
+	if want := []string{"1080p", "720p", "360p"}; !reflect.DeepEqual(qualities, want) {
+		t.Errorf("GetVideoList qualities = %q, want %q", qualities, want)
+	}
+}
+
+func TestAnimeIDFromHref(t *testing.T) {
+	tests := []struct {
+		href    string
+		wantID  string
+		wantDub bool
+	}{
+		{"/animes/one-piece-todos-os-episodios", "one-piece", false},
+		{"/animes/one-piece-dublado/12", "one-piece", true},
+		{"https://animefire.plus/animes/one-piece-dublado-todos-os-episodios", "one-piece", true},
+		{"one-piece", "one-piece", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		id, dub := animeIDFromHref(tt.href)
+		if id != tt.wantID || dub != tt.wantDub {
+			t.Errorf("animeIDFromHref(%q) = %q, %v, want %q, %v", tt.href, id, dub, tt.wantID, tt.wantDub)
+		}
+	}
+}
+
+func TestSearchSlug(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"One Piece", "one-piece"},
+		{"  sousou  no frieren ", "sousou-no-frieren"},
+		{"re:zero", "re:zero"},
+		{"fate/zero", "fate%2Fzero"},
+	}
+	for _, tt := range tests {
+		if got := searchSlug(tt.query); got != tt.want {
+			t.Errorf("searchSlug(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
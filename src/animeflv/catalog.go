@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the metadata a detail screen needs
+type AnimeDetails struct {
+	scraper.Anime
+	Score       float64 `json:"score,omitempty"`       // Site rating out of 5
+	Type        string  `json:"type,omitempty"`        // Anime, Película, Especial or OVA
+	NextEpisode string  `json:"nextEpisode,omitempty"` // Date the next episode airs, e.g. "2025-06-14", for airing shows
+}
+
+// animeInfoPattern finds the show info the anime page embeds for its episode
+// list script: ID, title, slug and, while airing, the next episode's date
+var animeInfoPattern = regexp.MustCompile(`var anime_info = (\[.*?\]);`)
+
+// animeIDFromHref extracts the anime ID, the slug, from a link to the show
+// such as /anime/one-piece-tv. Full URLs and bare slugs are accepted as well.
+func animeIDFromHref(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	href = strings.Trim(href, "/")
+	href = strings.TrimPrefix(href, "anime/")
+	if href == "" || strings.Contains(href, "/") {
+		return ""
+	}
+	return href
+}
+
+// absoluteURL resolves a link of the site, which uses relative and absolute
+// image URLs alike
+func (s *Scraper) absoluteURL(href string) string {
+	if href == "" || strings.HasPrefix(href, "http") {
+		return href
+	}
+	return s.baseURL + "/" + strings.TrimPrefix(href, "/")
+}
+
+// parseCard reads a show from a card of the browse page
+func (s *Scraper) parseCard(card *goquery.Selection) (scraper.Anime, bool) {
+	link := card.Find("a[href*='/anime/']").First()
+	id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+	if id == "" {
+		return scraper.Anime{}, false
+	}
+
+	// The last paragraph of the hover description is the synopsis; the first
+	// holds the type and rating
+	description := ""
+	if paragraphs := card.Find(".Description p"); paragraphs.Length() > 1 {
+		description = htmlx.Text(paragraphs.Last())
+	}
+	return scraper.Anime{
+		ID:           id,
+		Title:        htmlx.TextFirst(card, "h3.Title", ".Title strong"),
+		Description:  description,
+		ThumbnailURL: s.absoluteURL(htmlx.AttrAny(card.Find(".Image img").First(), "", "src", "data-cfsrc")),
+		Status:       scraper.StatusUnknown,
+	}, true
+}
+
+// browse fetches a page of the browse page, which serves search, filters and
+// the catalog orders alike
+func (s *Scraper) browse(ctx context.Context, query url.Values, page int) ([]scraper.Anime, error) {
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	doc, err := s.getPage(ctx, "/browse", query)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	htmlx.Find(doc.Selection, "ul.ListAnimes article.Anime", "article.Anime").Each(func(_ int, card *goquery.Selection) {
+		if anime, ok := s.parseCard(card); ok {
+			animes = append(animes, anime)
+		}
+	})
+	return animes, nil
+}
+
+// SearchAnime searches for anime by title, narrowed down by filters
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int, filters SearchFilters) ([]scraper.Anime, error) {
+	values := url.Values{}
+	filters.apply(values)
+	if query != "" {
+		values.Set("q", query)
+	}
+	return s.browse(ctx, values, page)
+}
+
+// GetPopularAnime retrieves AnimeFLV's highest rated shows
+func (s *Scraper) GetPopularAnime(ctx context.Context, page int) ([]scraper.Anime, error) {
+	return s.browse(ctx, url.Values{"order": {"rating"}}, page)
+}
+
+// GetLatestUpdates retrieves the shows with the most recently released episodes
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]scraper.Anime, error) {
+	return s.browse(ctx, url.Values{"order": {"updated"}}, page)
+}
+
+// animePage fetches the page of a show
+func (s *Scraper) animePage(ctx context.Context, animeID string) (*goquery.Document, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece-tv)", animeID)
+	}
+	doc, err := s.getPage(ctx, "/anime/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find(".Ficha .Title").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	return doc, nil
+}
+
+// GetAnimeDetails retrieves description, genres, score and airing status for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	var genres []string
+	doc.Find("nav.Nvgnrs a").Each(func(_ int, link *goquery.Selection) {
+		genres = append(genres, htmlx.Text(link))
+	})
+	var titles []string
+	doc.Find(".Ficha .TxtAlt").Each(func(_ int, alt *goquery.Selection) {
+		titles = append(titles, htmlx.Text(alt))
+	})
+
+	details := AnimeDetails{
+		Anime: scraper.Anime{
+			ID:                animeIDFromHref(animeID),
+			Title:             htmlx.TextFirst(doc.Selection, ".Ficha h1.Title", ".Ficha .Title"),
+			Description:       htmlx.TextFirst(doc.Selection, ".Description p", ".Description"),
+			Genre:             strings.Join(genres, ", "),
+			ThumbnailURL:      s.absoluteURL(htmlx.Attr(doc.Find(".AnimeCover img").First(), "src", "")),
+			Status:            airingStatus(htmlx.TextFirst(doc.Selection, "p.AnmStts span", ".AnmStts")),
+			AlternativeTitles: titles,
+		},
+		Type: htmlx.TextFirst(doc.Selection, ".Ficha span.Type"),
+	}
+	details.Score, _ = strconv.ParseFloat(htmlx.TextFirst(doc.Selection, "#votes_prmd", ".vtprmd"), 64)
+
+	if episodes, err := pageEpisodes(doc); err == nil {
+		details.Episodes = len(episodes)
+	}
+	if match := animeInfoPattern.FindStringSubmatch(doc.Text()); match != nil {
+		var info []string
+		if json.Unmarshal([]byte(match[1]), &info) == nil && len(info) > 3 && details.Status == scraper.StatusOngoing {
+			details.NextEpisode = info[3]
+		}
+	}
+	return details, nil
+}
+
+// airingStatus maps AnimeFLV's airing statuses onto the scraper statuses
+func airingStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "en emision", "en emisión":
+		return scraper.StatusOngoing
+	case "finalizado":
+		return scraper.StatusCompleted
+	}
+	return scraper.StatusUnknown
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Config holds user overrides read from the extension config file, letting
+// users follow AnimeFLV to a new domain without waiting for a release. Flags
+// given on the command line win over the file.
+type Config struct {
+	cli.Config
+	Translation string `json:"translation,omitempty"` // Default for -translation: sub or dub
+	Server      string `json:"server,omitempty"`      // Default for -server, e.g. YourUpload
+
+	BaseURL string `json:"base_url,omitempty"` // Site URL, e.g. https://www3.animeflv.net
+}
+
+// LoadConfig reads the config file at path, or the default location when path is empty
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path == "" {
+		defaultPath, err := extconfig.Path("animeflv")
+		if err != nil {
+			return cfg, err
+		}
+		path = defaultPath
+	}
+
+	err := extconfig.Load(path, &cfg)
+	return cfg, err
+}
+
+// ApplyConfig overrides the scraper defaults with the non-empty values of cfg
+func (s *Scraper) ApplyConfig(cfg Config) {
+	if cfg.BaseURL != "" {
+		s.baseURL = strings.TrimRight(cfg.BaseURL, "/")
+	}
+}
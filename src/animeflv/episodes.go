@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// episodesPattern finds the episode list the anime page embeds as a script:
+// [[number, id], ...], newest first
+var episodesPattern = regexp.MustCompile(`var episodes = (\[.*?\]);`)
+
+// videosPattern finds the servers the episode page embeds as a script, keyed
+// by translation: {"SUB": [...], "LAT": [...]}
+var videosPattern = regexp.MustCompile(`var videos = (\{.*?\});`)
+
+// pageEpisodes reads the episode numbers from an anime page, in order
+func pageEpisodes(doc *goquery.Document) ([]float64, error) {
+	match := episodesPattern.FindStringSubmatch(doc.Text())
+	if match == nil {
+		return nil, exterr.New(exterr.Parse, "no episode list on the anime page")
+	}
+	var entries [][]float64
+	if err := json.Unmarshal([]byte(match[1]), &entries); err != nil {
+		return nil, exterr.New(exterr.Parse, "error parsing episode list: %w", err)
+	}
+
+	numbers := []float64{}
+	for _, entry := range entries {
+		if len(entry) > 0 {
+			numbers = append(numbers, entry[0])
+		}
+	}
+	sort.Float64s(numbers)
+	return numbers, nil
+}
+
+// episodePath returns the path of an episode's page, e.g. /ver/one-piece-tv-1
+func episodePath(animeID string, number float64) string {
+	return "/ver/" + animeIDFromHref(animeID) + "-" + strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// GetEpisodeList returns the episodes of an anime. The site lists one set of
+// episodes whatever the translation.
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := pageEpisodes(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := []scraper.Episode{}
+	for _, number := range numbers {
+		episodes = append(episodes, scraper.Episode{
+			ID:            s.baseURL + episodePath(animeID, number),
+			Name:          "Episodio " + strconv.FormatFloat(number, 'f', -1, 64),
+			EpisodeNumber: number,
+		})
+	}
+	return episodes, nil
+}
+
+// Video extends scraper.Video with the AnimeFLV server the stream was resolved from
+type Video struct {
+	scraper.Video
+	Server string `json:"server,omitempty"` // Server name as the site shows it, e.g. "YourUpload"
+}
+
+// Server is a video host an episode is embedded from
+type Server struct {
+	Name      string `json:"name"`      // e.g. "YourUpload" or "SW"
+	EmbedURL  string `json:"embedUrl"`  // Player page, for front-ends that open embeds themselves
+	Supported bool   `json:"supported"` // Whether the extension resolves the server into streams
+}
+
+// Warning reports a supported server whose streams could not be extracted, so
+// frontends can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // Server name, e.g. "YourUpload"
+	Provider string `json:"provider,omitempty"` // Host of the player the server embeds
+	Reason   string `json:"reason"`
+}
+
+// VideoResponse lists the streams resolved from an episode's servers along
+// with every server the episode page offers, including those the extension
+// cannot resolve
+type VideoResponse struct {
+	Streams  []Video   `json:"streams"`
+	Servers  []Server  `json:"servers"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// videoServer is an entry of the episode page's server list
+type videoServer struct {
+	Server string `json:"server"` // Short ID, e.g. "yu"
+	Title  string `json:"title"`  // e.g. "YourUpload"
+	Code   string `json:"code"`   // Embed URL
+}
+
+// translationKeys maps the translations to the server groups of the episode page
+var translationKeys = map[string]string{"sub": "SUB", "dub": "LAT"}
+
+// GetVideoList returns every server of an episode in the selected translation,
+// the preferred server first, with the streams of the servers a video host
+// extractor resolves
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	if animeIDFromHref(animeID) == "" {
+		return VideoResponse{}, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece-tv)", animeID)
+	}
+	path := episodePath(animeID, episodeNumber)
+	doc, err := s.getPage(ctx, path, nil)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+
+	match := videosPattern.FindStringSubmatch(doc.Text())
+	if match == nil {
+		return VideoResponse{}, exterr.New(exterr.Parse, "no server list on %s", path)
+	}
+	var groups map[string][]videoServer
+	if err := json.Unmarshal([]byte(match[1]), &groups); err != nil {
+		return VideoResponse{}, exterr.New(exterr.Parse, "error parsing server list: %w", err)
+	}
+	servers := groups[translationKeys[s.translation]]
+	if len(servers) == 0 {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g has no %s servers", episodeNumber, s.translation)
+	}
+	for i, srv := range servers {
+		if s.server != "" && (strings.EqualFold(srv.Title, s.server) || strings.EqualFold(srv.Server, s.server)) {
+			servers = append(append([]videoServer{srv}, servers[:i]...), servers[i+1:]...)
+			break
+		}
+	}
+
+	response := VideoResponse{Streams: []Video{}, Servers: []Server{}, Warnings: []Warning{}}
+	for _, srv := range servers {
+		extractor := hosters.For(s.extractors, srv.Code)
+		response.Servers = append(response.Servers, Server{Name: srv.Title, EmbedURL: srv.Code, Supported: extractor != nil})
+		if extractor == nil {
+			continue
+		}
+
+		result, err := extractor.Extract(ctx, srv.Code, s.baseURL+path)
+		if err != nil {
+			response.Warnings = append(response.Warnings, Warning{Source: srv.Title, Provider: extractor.Domains()[0], Reason: err.Error()})
+			continue
+		}
+		for _, stream := range result.Streams {
+			response.Streams = append(response.Streams, Video{
+				Video: scraper.Video{
+					ID:       animeIDFromHref(animeID),
+					Quality:  stream.Quality,
+					VideoURL: stream.URL,
+					Headers:  stream.Headers,
+				},
+				Server: srv.Title,
+			})
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// SearchFilters is the JSON object accepted by --filters, e.g.
+//
+//	{"genres": ["accion", "comedia"], "year": 2023, "type": "tv", "status": "ongoing", "sortBy": "rating"}
+//
+// Every field is optional. Genres and types take the IDs the genres command
+// lists or their Spanish names; status takes the scraper status values ongoing
+// and completed, or upcoming.
+type SearchFilters struct {
+	Genres []string `json:"genres,omitempty"`
+	Year   int      `json:"year,omitempty"`
+	Type   string   `json:"type,omitempty"`
+	Status string   `json:"status,omitempty"`
+	SortBy string   `json:"sortBy,omitempty"`
+}
+
+// genres maps the genre IDs of AnimeFLV's browse page to their names
+var genres = map[string]string{
+	"accion": "Acción", "artes-marciales": "Artes Marciales", "aventura": "Aventuras",
+	"carreras": "Carreras", "ciencia-ficcion": "Ciencia Ficción", "comedia": "Comedia",
+	"demencia": "Demencia", "demonios": "Demonios", "deportes": "Deportes", "drama": "Drama",
+	"ecchi": "Ecchi", "escolares": "Escolares", "espacial": "Espacial", "fantasia": "Fantasía",
+	"harem": "Harem", "historico": "Histórico", "infantil": "Infantil", "josei": "Josei",
+	"juegos": "Juegos", "magia": "Magia", "mecha": "Mecha", "militar": "Militar",
+	"misterio": "Misterio", "musica": "Música", "parodia": "Parodia", "policia": "Policía",
+	"psicologico": "Psicológico", "recuentos-de-la-vida": "Recuentos de la vida",
+	"romance": "Romance", "samurai": "Samurai", "seinen": "Seinen", "shoujo": "Shoujo",
+	"shounen": "Shounen", "sobrenatural": "Sobrenatural", "superpoderes": "Superpoderes",
+	"suspenso": "Suspenso", "terror": "Terror", "vampiros": "Vampiros", "yaoi": "Yaoi",
+	"yuri": "Yuri",
+}
+
+// showTypes lists the show types of the browse page, by ID
+var showTypes = []FilterOption{
+	{ID: "tv", Name: "Anime"},
+	{ID: "movie", Name: "Película"},
+	{ID: "special", Name: "Especial"},
+	{ID: "ova", Name: "OVA"},
+}
+
+// statusIDs maps the statuses accepted by the status filter to the airing
+// statuses the browse page uses
+var statusIDs = map[string]int{scraper.StatusOngoing: 1, scraper.StatusCompleted: 2, "upcoming": 3}
+
+// sortBy lists the sort orders of the browse page
+var sortBy = []FilterOption{
+	{ID: "default", Name: "Por defecto"},
+	{ID: "updated", Name: "Recientemente actualizados"},
+	{ID: "added", Name: "Recientemente agregados"},
+	{ID: "title", Name: "Nombre A-Z"},
+	{ID: "rating", Name: "Calificación"},
+}
+
+// FilterOption is one value of a filter, with the ID to put in the filters JSON
+type FilterOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FilterOptions is the output of the genres command: the values each field of
+// the filters JSON accepts, for front-ends building filter pickers
+type FilterOptions struct {
+	Genres   []FilterOption `json:"genres"`
+	Types    []FilterOption `json:"types"`
+	Statuses []FilterOption `json:"statuses"`
+	SortBy   []FilterOption `json:"sortBy"`
+}
+
+// GetFilterOptions lists the values the filters JSON accepts
+func GetFilterOptions() FilterOptions {
+	ids := make([]string, 0, len(genres))
+	for id := range genres {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	options := FilterOptions{
+		Types: showTypes,
+		Statuses: []FilterOption{
+			{ID: scraper.StatusOngoing, Name: "En emisión"},
+			{ID: scraper.StatusCompleted, Name: "Finalizado"},
+			{ID: "upcoming", Name: "Próximamente"},
+		},
+		SortBy: sortBy,
+	}
+	for _, id := range ids {
+		options.Genres = append(options.Genres, FilterOption{ID: id, Name: genres[id]})
+	}
+	return options
+}
+
+// ParseSearchFilters parses and validates the --filters JSON object. An empty string means no filters.
+func ParseSearchFilters(filters string) (SearchFilters, error) {
+	var f SearchFilters
+	if strings.TrimSpace(filters) == "" {
+		return f, nil
+	}
+
+	if err := json.Unmarshal([]byte(filters), &f); err != nil {
+		return f, exterr.New(exterr.InvalidArgument, "invalid filters: %w", err)
+	}
+
+	for i, genre := range f.Genres {
+		id, ok := genreID(genre)
+		if !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid genre %q (see the genres command)", genre)
+		}
+		f.Genres[i] = id
+	}
+	if f.Type != "" {
+		id, ok := optionID(f.Type, showTypes)
+		if !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid type %q (valid: %s)", f.Type, optionIDs(showTypes))
+		}
+		f.Type = id
+	}
+	if f.Status != "" {
+		f.Status = strings.ToLower(f.Status)
+		if _, ok := statusIDs[f.Status]; !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid status %q (valid: %s, %s, upcoming)", f.Status, scraper.StatusOngoing, scraper.StatusCompleted)
+		}
+	}
+	if f.SortBy != "" {
+		id, ok := optionID(f.SortBy, sortBy)
+		if !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid sortBy %q (valid: %s)", f.SortBy, optionIDs(sortBy))
+		}
+		f.SortBy = id
+	}
+	return f, nil
+}
+
+// empty reports whether no filter is set
+func (f SearchFilters) empty() bool {
+	return len(f.Genres) == 0 && f.Year == 0 && f.Type == "" && f.Status == "" && f.SortBy == ""
+}
+
+// apply adds the filters to the query of the browse page
+func (f SearchFilters) apply(query url.Values) {
+	for _, genre := range f.Genres {
+		query.Add("genre[]", genre)
+	}
+	if f.Year != 0 {
+		query.Set("year[]", strconv.Itoa(f.Year))
+	}
+	if f.Type != "" {
+		query.Set("type[]", f.Type)
+	}
+	if f.Status != "" {
+		query.Set("status[]", strconv.Itoa(statusIDs[f.Status]))
+	}
+	if f.SortBy != "" {
+		query.Set("order", f.SortBy)
+	}
+}
+
+// genreID finds a genre by ID or name, ignoring case
+func genreID(genre string) (string, bool) {
+	genre = strings.TrimSpace(genre)
+	for id, name := range genres {
+		if strings.EqualFold(id, genre) || strings.EqualFold(name, genre) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// optionID finds an option by ID or name, ignoring case
+func optionID(value string, options []FilterOption) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, option := range options {
+		if strings.EqualFold(option.ID, value) || strings.EqualFold(option.Name, value) {
+			return option.ID, true
+		}
+	}
+	return "", false
+}
+
+// optionIDs joins the IDs of options for error messages
+func optionIDs(options []FilterOption) string {
+	ids := make([]string, len(options))
+	for i, option := range options {
+		ids[i] = option.ID
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair/pkg/scraper"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// sourceID identifies the AnimeFLV source
+const sourceID = "93702975723668971"
+
+// defaultBaseURL is AnimeFLV's site. base_url in the config file points the
+// extension at a mirror when the site moves.
+const defaultBaseURL = "https://www3.animeflv.net"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// TranslationTypes lists the values of -translation: Japanese audio with
+// Spanish subtitles, or the Latin American Spanish dub
+var TranslationTypes = []string{"sub", "dub"}
+
+type Scraper struct {
+	baseURL     string
+	translation string // Translation type used for streams
+	server      string // Preferred server, e.g. "YourUpload"; tried first when set
+	client      *httpclient.Client
+	retry       httpclient.RetryPolicy
+	extractors  []hosters.Extractor // Video hosts streams are resolved from
+}
+
+// NewScraper creates a new instance of the animeflv scraper
+func NewScraper() *Scraper {
+	client := httpclient.New()
+	return &Scraper{
+		baseURL:     defaultBaseURL,
+		translation: "sub",
+		client:      client,
+		retry:       httpclient.DefaultRetryPolicy,
+		extractors:  []hosters.Extractor{&hosters.YourUpload{Client: client}},
+	}
+}
+
+// Requests per minute AnimeFLV tolerates before its Cloudflare front answers
+// 429, as declared in SourceInfo.RateLimit. A stream-url command needs two
+// requests to the site.
+const (
+	rateLimit = 30
+	rateBurst = 5
+)
+
+// LimitRate throttles requests to AnimeFLV to rateLimit, sharing the budget
+// with every other invocation through a state file in the cache directory. The
+// video hosts are left unthrottled.
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("animeflv")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains()[:1], ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetTranslation selects sub or dub
+func (s *Scraper) SetTranslation(translation string) error {
+	for _, valid := range TranslationTypes {
+		if translation == valid {
+			s.translation = translation
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid translation type %q (valid: %s)", translation, strings.Join(TranslationTypes, ", "))
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "AnimeFLV",
+			Package: "animeflv",
+			Lang:    "es",
+			Version: version,
+			Sources: []scraper.SourceInfo{source},
+		},
+		Permissions: permissions.Permissions{
+			// The site (base_url in the config file can point elsewhere), the video
+			// hosts, and the mirrors and file servers they serve streams from
+			Network: permissions.Domains(append([]string{"animeflv.net"}, hosters.NetworkDomains(s.extractors)...)...),
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/animeflv.json (read)",
+				"$PAIR_CACHE_DIR/extensions/animeflv/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/animeflv (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (scraper.SourceInfo, error) {
+	return scraper.SourceInfo{
+		ID:             sourceID,
+		Name:           "AnimeFLV",
+		BaseURL:        s.baseURL,
+		Language:       "es",
+		RateLimit:      rateLimit,
+		SupportsLatest: true,
+		SupportsSearch: true,
+	}, nil
+}
+
+// getPage fetches a page of the site and parses it as HTML
+func (s *Scraper) getPage(ctx context.Context, path string, query url.Values) (*goquery.Document, error) {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := htmlx.Parse(resp.Body)
+	if err != nil {
+		return nil, exterr.New(exterr.Parse, "%w", err)
+	}
+	return doc, nil
+}
+
+// get sends a GET request for path on the site, turning error statuses into errors
+func (s *Scraper) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	rawURL := s.baseURL + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", hosters.UserAgent)
+	req.Header.Set("Referer", s.baseURL+"/")
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return nil, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, exterr.New(exterr.NotFound, "%s not found", path)
+		}
+		return nil, exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	return resp, nil
+}
+
+// domains returns the hosts doctor checks: the site, followed by the video hosts
+func (s *Scraper) domains() []string {
+	host := "www3.animeflv.net"
+	if u, err := url.Parse(s.baseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return append([]string{host}, s.hostDomains()...)
+}
+
+// hostDomains lists the domains of the video hosts streams are resolved from
+func (s *Scraper) hostDomains() []string {
+	var domains []string
+	for _, extractor := range s.extractors {
+		domains = append(domains, extractor.Domains()...)
+	}
+	return domains
+}
+
+func main() {
+	var (
+		query       = flag.String("query", "", "Search query")
+		page        = flag.Int("page", 1, "Page number")
+		filters     = flag.String("filters", "", `JSON filters, e.g. {"genres":["accion"],"year":2023,"type":"tv","status":"ongoing","sortBy":"rating"}`)
+		animeURL    = flag.String("anime", "", "Anime URL")
+		episode     = flag.Float64("episode", 0, "Episode number")
+		translation = flag.String("translation", "sub", "Translation type: sub (Japanese audio, Spanish subtitles) or dub (Latin American Spanish)")
+		server      = flag.String("server", "", "With stream-url: try this server first, e.g. YourUpload")
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:       "animeflv",
+		SourceID:      sourceID,
+		Version:       version,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Translation != "" && !cli.IsFlagSet("translation") {
+				*translation = cfg.Translation
+			}
+			if cfg.Server != "" && !cli.IsFlagSet("server") {
+				*server = cfg.Server
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetTranslation(*translation); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			s.server = strings.TrimSpace(*server)
+			return nil
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime on a source.", Run: func(ctx context.Context) (interface{}, error) {
+			searchFilters, filterErr := ParseSearchFilters(*filters)
+			if filterErr != nil {
+				return nil, exterr.From(filterErr, exterr.InvalidArgument)
+			}
+			if *query == "" && searchFilters.empty() {
+				return nil, exterr.New(exterr.InvalidArgument, "search query or filters are required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page, searchFilters))
+		}},
+		{Name: "popular", Description: "Get the highest rated anime.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetPopularAnime(ctx, *page)
+		}},
+		{Name: "latest", Description: "Get recently updated anime.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetLatestUpdates(ctx, *page)
+		}},
+		{Name: "genres", Description: "List the genres, types, statuses and sort orders -filters accepts.", Run: func(ctx context.Context) (interface{}, error) {
+			return GetFilterOptions(), nil
+		}},
+		{Name: "details", Description: "Get the description, genres, score and airing status of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "episodes", Description: "Get the list of episodes for an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetEpisodeList(ctx, *animeURL)
+		}},
+		{Name: "stream-url", Description: "Get the video servers of an anime episode and the streams resolved from them.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			return s.GetVideoList(ctx, *animeURL, *episode)
+		}},
+	}
+	app.Main()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece", 1, SearchFilters{})
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "one-piece-tv" {
+		t.Fatalf("SearchAnime results = %+v, want one-piece-tv first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 3", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, episodes[0].EpisodeNumber)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	if len(videos.Streams) == 0 || videos.Streams[0].Server != "YourUpload" {
+		t.Errorf("GetVideoList streams = %+v, want a YourUpload stream", videos.Streams)
+	}
+	supported := map[string]bool{}
+	for _, srv := range videos.Servers {
+		supported[srv.Name] = srv.Supported
+	}
+	if !supported["YourUpload"] {
+		t.Errorf("GetVideoList servers = %+v, want YourUpload supported", videos.Servers)
+	}
+	if _, ok := supported["SW"]; !ok {
+		t.Errorf("GetVideoList servers = %+v, want the unsupported SW server listed", videos.Servers)
+	}
+}
+
+func TestAnimeIDFromHref(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"/anime/one-piece-tv", "one-piece-tv"},
+		{"https://www3.animeflv.net/anime/one-piece-tv", "one-piece-tv"},
+		{"one-piece-tv", "one-piece-tv"},
+		{"/ver/one-piece-tv-1", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := animeIDFromHref(tt.href); got != tt.want {
+			t.Errorf("animeIDFromHref(%q) = %q, want %q", tt.href, got, tt.want)
+		}
+	}
+}
+
+func TestEpisodePath(t *testing.T) {
+	tests := []struct {
+		animeID string
+		number  float64
+		want    string
+	}{
+		{"one-piece-tv", 1, "/ver/one-piece-tv-1"},
+		{"/anime/one-piece-tv", 12.5, "/ver/one-piece-tv-12.5"},
+	}
+	for _, tt := range tests {
+		if got := episodePath(tt.animeID, tt.number); got != tt.want {
+			t.Errorf("episodePath(%q, %v) = %q, want %q", tt.animeID, tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestAiringStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"En emision", scraper.StatusOngoing},
+		{"En emisión", scraper.StatusOngoing},
+		{"Finalizado", scraper.StatusCompleted},
+		{"Próximamente", scraper.StatusUnknown},
+	}
+	for _, tt := range tests {
+		if got := airingStatus(tt.status); got != tt.want {
+			t.Errorf("airingStatus(%q) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the seasons of the show
+type AnimeDetails struct {
+	scraper.Anime
+	Season  string   `json:"season,omitempty"`  // Season the ID selects, e.g. "Saison 1"
+	Seasons []Season `json:"seasons,omitempty"` // Every season, film and special, in the site's order
+}
+
+// Season is a part of a show with its own episode list: a season, the films
+// or a special
+type Season struct {
+	ID   string `json:"id"`   // Anime ID selecting the season, e.g. one-piece/saison2
+	Name string `json:"name"` // e.g. "Saison 2" or "Film"
+}
+
+// seasonPattern finds the seasons the show page lists through calls such as
+// panneauAnime("Saison 1", "saison1/vostfr");
+var seasonPattern = regexp.MustCompile(`panneauAnime\("([^"]+)",\s*"([^"]+)"\)`)
+
+// commentPattern matches the JavaScript comments the show page hides
+// placeholder and retired seasons in
+var commentPattern = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+
+// parseAnimeID splits an anime ID into the show's slug and the season path,
+// which is empty when the ID names the show only. Accepted forms:
+//
+//	one-piece
+//	one-piece/saison2
+//	https://anime-sama.fr/catalogue/one-piece/saison2/vostfr/
+func parseAnimeID(animeID string) (slug, season string, err error) {
+	path := animeID
+	if u, parseErr := url.Parse(animeID); parseErr == nil {
+		path = u.Path
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(strings.Trim(path, "/"), "catalogue"), "/"), "/")
+	if parts[0] == "" {
+		return "", "", exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece or one-piece/saison2)", animeID)
+	}
+	if len(parts) > 1 {
+		season = parts[1]
+	}
+	return parts[0], season, nil
+}
+
+// SearchAnime searches the catalog for shows by title. Entries that only
+// have scans are left out.
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	values := url.Values{"search": {query}, "type[]": {"Anime"}}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	doc, err := s.getPage(ctx, "/catalogue/", values)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	htmlx.Find(doc.Selection, ".catalog-card", "#list_catalog > div").Each(func(_ int, card *goquery.Selection) {
+		link := card.Find("a[href*='/catalogue/']").First()
+		slug, _, err := parseAnimeID(htmlx.Attr(link, "href", ""))
+		if err != nil {
+			return
+		}
+
+		// Cards list their details as label and value rows
+		info := map[string]string{}
+		card.Find(".info-row").Each(func(_ int, row *goquery.Selection) {
+			info[htmlx.TextFirst(row, ".info-label")] = htmlx.TextFirst(row, ".info-value")
+		})
+		if types, ok := info["Types"]; ok && !strings.Contains(types, "Anime") {
+			return
+		}
+
+		anime := scraper.Anime{
+			ID:           slug,
+			Title:        htmlx.TextFirst(card, ".card-title", "h1", "h2"),
+			Genre:        info["Genres"],
+			ThumbnailURL: htmlx.AttrAny(card.Find("img").First(), "", "src", "data-src"),
+			Status:       scraper.StatusUnknown,
+		}
+		if alternates := htmlx.TextFirst(card, ".alternate-titles", "p"); alternates != "" && alternates != anime.Title {
+			anime.AlternativeTitles = splitTitles(alternates)
+		}
+		animes = append(animes, anime)
+	})
+	return animes, nil
+}
+
+// splitTitles splits a comma separated list of titles
+func splitTitles(titles string) []string {
+	var split []string
+	for _, title := range strings.Split(titles, ",") {
+		if title = strings.TrimSpace(title); title != "" {
+			split = append(split, title)
+		}
+	}
+	return split
+}
+
+// showPage fetches the page of a show
+func (s *Scraper) showPage(ctx context.Context, slug string) (*goquery.Document, error) {
+	doc, err := s.getPage(ctx, "/catalogue/"+slug+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find("#titreOeuvre").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", slug)
+	}
+	return doc, nil
+}
+
+// showSeasons reads the seasons a show page lists, skipping the ones the
+// page comments out
+func showSeasons(doc *goquery.Document, slug string) []Season {
+	var seasons []Season
+	seen := map[string]bool{}
+	doc.Find("script").Each(func(_ int, script *goquery.Selection) {
+		code := commentPattern.ReplaceAllString(script.Text(), "")
+		for _, match := range seasonPattern.FindAllStringSubmatch(code, -1) {
+			// The path names the catalog too, e.g. saison1/vostfr
+			path := strings.Split(strings.Trim(match[2], "/"), "/")[0]
+			if path == "" || seen[path] {
+				continue
+			}
+			seen[path] = true
+			seasons = append(seasons, Season{ID: slug + "/" + path, Name: match[1]})
+		}
+	})
+	return seasons
+}
+
+// resolveSeason returns the season path an anime ID selects: the one it
+// names, or the show's first season
+func (s *Scraper) resolveSeason(ctx context.Context, animeID string) (slug, season string, err error) {
+	slug, season, err = parseAnimeID(animeID)
+	if err != nil || season != "" {
+		return slug, season, err
+	}
+	doc, err := s.showPage(ctx, slug)
+	if err != nil {
+		return "", "", err
+	}
+	seasons := showSeasons(doc, slug)
+	if len(seasons) == 0 {
+		return "", "", exterr.New(exterr.NotFound, "anime %q has no episodes", slug)
+	}
+	_, season, _ = parseAnimeID(seasons[0].ID)
+	return slug, season, nil
+}
+
+// GetAnimeDetails retrieves description, genres and seasons for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	slug, season, err := parseAnimeID(animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+	doc, err := s.showPage(ctx, slug)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	details := AnimeDetails{
+		Anime: scraper.Anime{
+			ID:           strings.TrimSuffix(slug+"/"+season, "/"),
+			Title:        htmlx.Text(doc.Find("#titreOeuvre")),
+			ThumbnailURL: htmlx.Attr(doc.Find("#coverOeuvre"), "src", ""),
+			Status:       scraper.StatusUnknown,
+		},
+		Seasons: showSeasons(doc, slug),
+	}
+	if alternates := htmlx.Text(doc.Find("#titreAlter")); alternates != "" {
+		details.AlternativeTitles = splitTitles(alternates)
+	}
+
+	// Synopsis and genres each follow a heading
+	doc.Find("h2").Each(func(_ int, heading *goquery.Selection) {
+		switch strings.ToLower(htmlx.Text(heading)) {
+		case "synopsis":
+			details.Description = htmlx.Text(heading.NextFiltered("p"))
+		case "genres":
+			details.Genre = htmlx.Text(heading.Next())
+		}
+	})
+
+	// A bare show ID selects the first season
+	for i, entry := range details.Seasons {
+		if entry.ID == details.ID || (season == "" && i == 0) {
+			details.Season = entry.Name
+		}
+	}
+	return details, nil
+}
+
+// RelatedAnime extends scraper.Anime with how the entry relates to the requested one
+type RelatedAnime struct {
+	scraper.Anime
+	Relation string `json:"relation"` // Always season: the other parts of the show
+}
+
+// GetRelatedAnime lists the other seasons, films and specials of a show, as
+// anime IDs episodes and stream-url accept
+func (s *Scraper) GetRelatedAnime(ctx context.Context, animeID string, page int) ([]RelatedAnime, error) {
+	related := []RelatedAnime{}
+	// Everything is on the show page
+	if page > 1 {
+		return related, nil
+	}
+
+	slug, season, err := parseAnimeID(animeID)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.showPage(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	seasons := showSeasons(doc, slug)
+	// A bare show ID selects the first season
+	current := slug + "/" + season
+	if season == "" && len(seasons) > 0 {
+		current = seasons[0].ID
+	}
+
+	title := htmlx.Text(doc.Find("#titreOeuvre"))
+	for _, other := range seasons {
+		if other.ID == current {
+			continue
+		}
+		related = append(related, RelatedAnime{
+			Anime: scraper.Anime{
+				ID:           other.ID,
+				Title:        title + " - " + other.Name,
+				ThumbnailURL: htmlx.Attr(doc.Find("#coverOeuvre"), "src", ""),
+				Status:       scraper.StatusUnknown,
+			},
+			Relation: "season",
+		})
+	}
+	return related, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// playerPattern finds the players of a season's episodes.js, one array of
+// embed URLs per player, indexed by episode: var eps1 = ['https://...', ...];
+var playerPattern = regexp.MustCompile(`(?s)var\s+eps(\d+)\s*=\s*\[(.*?)\]`)
+
+// embedPattern finds the quoted URLs in a player array, including the empty
+// strings standing in for missing episodes
+var embedPattern = regexp.MustCompile(`'([^']*)'|"([^"]*)"`)
+
+// player is one of a season's players: the embed URL of every episode on one
+// video host, or an empty string for episodes it lacks
+type player struct {
+	number int
+	embeds []string
+}
+
+// name returns the player's name as the site shows it
+func (p player) name() string {
+	return "Lecteur " + strconv.Itoa(p.number)
+}
+
+// seasonPlayers fetches the players of a season in the selected catalog, in order
+func (s *Scraper) seasonPlayers(ctx context.Context, slug, season string) ([]player, error) {
+	path := "/catalogue/" + slug + "/" + season + "/" + s.translation + "/episodes.js"
+	resp, err := s.get(ctx, path, nil)
+	if err != nil {
+		var extErr *exterr.Error
+		if errors.As(err, &extErr) && extErr.Code == exterr.NotFound {
+			// Shows without a French dub have no vf catalog
+			return nil, exterr.New(exterr.NotFound, "%s/%s has no %s episodes", slug, season, s.translation)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, exterr.New(exterr.Network, "error reading response: %w", err)
+	}
+
+	var players []player
+	for _, match := range playerPattern.FindAllStringSubmatch(string(body), -1) {
+		number, _ := strconv.Atoi(match[1])
+		p := player{number: number}
+		for _, embed := range embedPattern.FindAllStringSubmatch(match[2], -1) {
+			p.embeds = append(p.embeds, strings.TrimSpace(embed[1]+embed[2]))
+		}
+		players = append(players, p)
+	}
+	if len(players) == 0 {
+		return nil, exterr.New(exterr.Parse, "no players in %s", path)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].number < players[j].number })
+	return players, nil
+}
+
+// GetEpisodeList returns the episodes of a season in the selected catalog.
+// Players list the episodes they host in order, so the last episode any
+// player hosts ends the list.
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	slug, season, err := s.resolveSeason(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+	players, err := s.seasonPlayers(ctx, slug, season)
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for _, p := range players {
+		for i, embed := range p.embeds {
+			if embed != "" {
+				count = max(count, i+1)
+			}
+		}
+	}
+	episodes := []scraper.Episode{}
+	for number := 1; number <= count; number++ {
+		episodes = append(episodes, scraper.Episode{
+			ID:            slug + "/" + season + "/" + s.translation + "/" + strconv.Itoa(number),
+			Name:          "Épisode " + strconv.Itoa(number),
+			EpisodeNumber: float64(number),
+		})
+	}
+	return episodes, nil
+}
+
+// Video extends scraper.Video with the Anime-Sama player the stream was resolved from
+type Video struct {
+	scraper.Video
+	Server string `json:"server,omitempty"` // Player name as the site shows it, e.g. "Lecteur 1"
+}
+
+// Server is a player an episode is embedded from
+type Server struct {
+	Name      string `json:"name"`      // e.g. "Lecteur 1"
+	EmbedURL  string `json:"embedUrl"`  // Player page, for front-ends that open embeds themselves
+	Supported bool   `json:"supported"` // Whether the extension resolves the player into streams
+}
+
+// Warning reports a supported player whose streams could not be extracted, so
+// frontends can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // Player name, e.g. "Lecteur 1"
+	Provider string `json:"provider,omitempty"` // Host of the embed
+	Reason   string `json:"reason"`
+}
+
+// VideoResponse lists the streams resolved from an episode's players along
+// with every player hosting the episode, including those the extension
+// cannot resolve
+type VideoResponse struct {
+	Streams  []Video   `json:"streams"`
+	Servers  []Server  `json:"servers"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// GetVideoList returns every player hosting an episode in the selected
+// catalog, the preferred player first, with the streams of the players a video
+// host extractor resolves
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	slug, season, err := s.resolveSeason(ctx, animeID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	players, err := s.seasonPlayers(ctx, slug, season)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+
+	index := int(episodeNumber) - 1
+	var hosting []player
+	for _, p := range players {
+		if index >= 0 && float64(index+1) == episodeNumber && index < len(p.embeds) && p.embeds[index] != "" {
+			hosting = append(hosting, p)
+		}
+	}
+	if len(hosting) == 0 {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g not found", episodeNumber)
+	}
+	for i, p := range hosting {
+		if s.server != "" && (strings.EqualFold(p.name(), s.server) || s.server == strconv.Itoa(p.number)) {
+			hosting = append(append([]player{p}, hosting[:i]...), hosting[i+1:]...)
+			break
+		}
+	}
+
+	referer := s.baseURL + "/catalogue/" + slug + "/" + season + "/" + s.translation + "/"
+	response := VideoResponse{Streams: []Video{}, Servers: []Server{}, Warnings: []Warning{}}
+	for _, p := range hosting {
+		embed := p.embeds[index]
+		extractor := hosters.For(s.extractors, embed)
+		response.Servers = append(response.Servers, Server{Name: p.name(), EmbedURL: embed, Supported: extractor != nil})
+		if extractor == nil {
+			continue
+		}
+
+		result, err := extractor.Extract(ctx, embed, referer)
+		if err != nil {
+			response.Warnings = append(response.Warnings, Warning{Source: p.name(), Provider: extractor.Domains()[0], Reason: err.Error()})
+			continue
+		}
+		for _, stream := range result.Streams {
+			response.Streams = append(response.Streams, Video{
+				Video: scraper.Video{
+					ID:       slug + "/" + season,
+					Quality:  stream.Quality,
+					VideoURL: stream.URL,
+					Headers:  stream.Headers,
+				},
+				Server: p.name(),
+			})
+		}
+	}
+	return response, nil
+}
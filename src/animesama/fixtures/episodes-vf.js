@@ -0,0 +1,5 @@
+var eps1 = [
+'https://video.sibnet.ru/shell.php?videoid=4391120',
+'',
+"https://video.sibnet.ru/shell.php?videoid=4391122",
+''];
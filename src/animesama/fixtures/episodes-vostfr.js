@@ -0,0 +1,9 @@
+var eps1 = [
+'https://video.sibnet.ru/shell.php?videoid=4286514',
+'https://video.sibnet.ru/shell.php?videoid=4286515',
+'https://video.sibnet.ru/shell.php?videoid=4286516',
+];
+var eps2 = [
+'https://vidmoly.to/embed-a1b2c3d4e5f6.html',
+'https://vidmoly.to/embed-b2c3d4e5f6a1.html',
+];
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair/pkg/scraper"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// sourceID identifies the Anime-Sama source
+const sourceID = "5104384223103778991"
+
+// defaultBaseURL is Anime-Sama's site. It moves between domains often;
+// base_url in the config file points the extension at the current one.
+const defaultBaseURL = "https://anime-sama.fr"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// TranslationTypes lists the values of -translation, the site's two catalogs:
+// vostfr (Japanese audio, French subtitles) and vf (French dub)
+var TranslationTypes = []string{"vostfr", "vf"}
+
+// translationAliases maps the translation names other extensions use onto the catalogs
+var translationAliases = map[string]string{"sub": "vostfr", "dub": "vf"}
+
+type Scraper struct {
+	baseURL     string
+	translation string // Catalog episodes and streams are read from: vostfr or vf
+	server      string // Preferred player, e.g. "Lecteur 2"; tried first when set
+	client      *httpclient.Client
+	retry       httpclient.RetryPolicy
+	extractors  []hosters.Extractor // Video hosts streams are resolved from
+}
+
+// NewScraper creates a new instance of the animesama scraper
+func NewScraper() *Scraper {
+	client := httpclient.New()
+	return &Scraper{
+		baseURL:     defaultBaseURL,
+		translation: "vostfr",
+		client:      client,
+		retry:       httpclient.DefaultRetryPolicy,
+		extractors:  []hosters.Extractor{&hosters.Sibnet{Client: client}},
+	}
+}
+
+// Requests per minute Anime-Sama tolerates before its Cloudflare front answers
+// 429, as declared in SourceInfo.RateLimit. A stream-url command needs two
+// requests to the site.
+const (
+	rateLimit = 30
+	rateBurst = 5
+)
+
+// LimitRate throttles requests to Anime-Sama to rateLimit, sharing the budget
+// with every other invocation through a state file in the cache directory. The
+// video hosts are left unthrottled.
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("animesama")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains()[:1], ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetTranslation selects vostfr or vf; sub and dub are accepted as aliases
+func (s *Scraper) SetTranslation(translation string) error {
+	translation = strings.ToLower(translation)
+	if alias, ok := translationAliases[translation]; ok {
+		translation = alias
+	}
+	for _, valid := range TranslationTypes {
+		if translation == valid {
+			s.translation = translation
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid translation type %q (valid: %s)", translation, strings.Join(TranslationTypes, ", "))
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "Anime-Sama",
+			Package: "animesama",
+			Lang:    "fr",
+			Version: version,
+			Sources: []scraper.SourceInfo{source},
+		},
+		Permissions: permissions.Permissions{
+			// The site (base_url in the config file can point elsewhere), the video
+			// hosts, and the mirrors and file servers they serve streams from
+			Network: permissions.Domains(append([]string{"anime-sama.fr"}, hosters.NetworkDomains(s.extractors)...)...),
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/animesama.json (read)",
+				"$PAIR_CACHE_DIR/extensions/animesama/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/animesama (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (scraper.SourceInfo, error) {
+	return scraper.SourceInfo{
+		ID:                   sourceID,
+		Name:                 "Anime-Sama",
+		BaseURL:              s.baseURL,
+		Language:             "fr",
+		RateLimit:            rateLimit,
+		SupportsSearch:       true,
+		SupportsRelatedAnime: true,
+	}, nil
+}
+
+// getPage fetches a page of the site and parses it as HTML
+func (s *Scraper) getPage(ctx context.Context, path string, query url.Values) (*goquery.Document, error) {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := htmlx.Parse(resp.Body)
+	if err != nil {
+		return nil, exterr.New(exterr.Parse, "%w", err)
+	}
+	return doc, nil
+}
+
+// get sends a GET request for path on the site, turning error statuses into errors
+func (s *Scraper) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	rawURL := s.baseURL + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", hosters.UserAgent)
+	req.Header.Set("Referer", s.baseURL+"/")
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return nil, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, exterr.New(exterr.NotFound, "%s not found", path)
+		}
+		return nil, exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	return resp, nil
+}
+
+// domains returns the hosts doctor checks: the site, followed by the video hosts
+func (s *Scraper) domains() []string {
+	host := "anime-sama.fr"
+	if u, err := url.Parse(s.baseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return append([]string{host}, s.hostDomains()...)
+}
+
+// hostDomains lists the domains of the video hosts streams are resolved from
+func (s *Scraper) hostDomains() []string {
+	var domains []string
+	for _, extractor := range s.extractors {
+		domains = append(domains, extractor.Domains()...)
+	}
+	return domains
+}
+
+func main() {
+	var (
+		query       = flag.String("query", "", "Search query")
+		page        = flag.Int("page", 1, "Page number")
+		animeURL    = flag.String("anime", "", "Anime URL or ID, optionally with the season, e.g. one-piece/saison2")
+		episode     = flag.Float64("episode", 0, "Episode number")
+		translation = flag.String("translation", "vostfr", "Catalog: vostfr (French subtitles) or vf (French dub); sub and dub are aliases")
+		server      = flag.String("server", "", `With stream-url: try this player first, e.g. "Lecteur 2"`)
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:       "animesama",
+		SourceID:      sourceID,
+		Version:       version,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Translation != "" && !cli.IsFlagSet("translation") {
+				*translation = cfg.Translation
+			}
+			if cfg.Server != "" && !cli.IsFlagSet("server") {
+				*server = cfg.Server
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetTranslation(*translation); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			s.server = strings.TrimSpace(*server)
+			return nil
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime on a source.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "details", Description: "Get the description, genres and seasons of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "related", Description: "Get the other seasons, films and specials of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetRelatedAnime(ctx, *animeURL, *page)
+		}},
+		{Name: "episodes", Description: "Get the list of episodes for an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetEpisodeList(ctx, *animeURL)
+		}},
+		{Name: "stream-url", Description: "Get the players of an anime episode and the streams resolved from them.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			return s.GetVideoList(ctx, *animeURL, *episode)
+		}},
+	}
+	app.Main()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "one-piece" {
+		t.Fatalf("SearchAnime results = %+v, want one-piece first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 3", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, episodes[0].EpisodeNumber)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	if len(videos.Streams) == 0 || !strings.Contains(videos.Streams[0].VideoURL, "sibnet") {
+		t.Errorf("GetVideoList streams = %+v, want a Sibnet stream", videos.Streams)
+	}
+}
+
+func TestParseAnimeID(t *testing.T) {
+	tests := []struct {
+		animeID    string
+		wantSlug   string
+		wantSeason string
+		wantErr    bool
+	}{
+		{animeID: "one-piece", wantSlug: "one-piece"},
+		{animeID: "one-piece/saison2", wantSlug: "one-piece", wantSeason: "saison2"},
+		{animeID: "https://anime-sama.fr/catalogue/one-piece/saison2/vostfr/", wantSlug: "one-piece", wantSeason: "saison2"},
+		{animeID: "/catalogue/", wantErr: true},
+		{animeID: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		slug, season, err := parseAnimeID(tt.animeID)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAnimeID(%q) error = %v, wantErr %v", tt.animeID, err, tt.wantErr)
+			continue
+		}
+		if slug != tt.wantSlug || season != tt.wantSeason {
+			t.Errorf("parseAnimeID(%q) = %q, %q, want %q, %q", tt.animeID, slug, season, tt.wantSlug, tt.wantSeason)
+		}
+	}
+}
+
+func TestSplitTitles(t *testing.T) {
+	tests := []struct {
+		titles string
+		want   []string
+	}{
+		{"One Piece, ワンピース", []string{"One Piece", "ワンピース"}},
+		{" One Piece ,, ", []string{"One Piece"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitTitles(tt.titles); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitTitles(%q) = %q, want %q", tt.titles, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the metadata a detail screen needs
+// and the IDs trackers know the show by
+type AnimeDetails struct {
+	scraper.Anime
+	Type          string `json:"type,omitempty"`          // TV, TV Short, OVA, ONA, Special or Movie
+	Season        string `json:"season,omitempty"`        // Airing season as the site names it, e.g. "Autunno"
+	EpisodeLength int    `json:"episodeLength,omitempty"` // Minutes per episode
+	AniListID     int    `json:"anilistId,omitempty"`
+	MALID         int    `json:"malId,omitempty"`
+}
+
+// record is a show as the site's JSON endpoints and page components describe it
+type record struct {
+	ID             int     `json:"id"`
+	Slug           string  `json:"slug"`
+	Title          string  `json:"title"`
+	TitleEng       string  `json:"title_eng"`
+	TitleIt        string  `json:"title_it"`
+	Plot           string  `json:"plot"`
+	ImageURL       string  `json:"imageurl"`
+	Type           string  `json:"type"`
+	Status         string  `json:"status"` // Italian, e.g. "In Corso"
+	Date           string  `json:"date"`   // Year the show started airing
+	Season         string  `json:"season"` // Italian, e.g. "Autunno"
+	Dub            int     `json:"dub"`    // 1 for the Italian dub's entry
+	Studio         string  `json:"studio"`
+	Genres         []genre `json:"genres"`
+	EpisodesCount  int     `json:"episodes_count"`
+	EpisodesLength int     `json:"episodes_length"`
+	AniListID      int     `json:"anilist_id"`
+	MALID          int     `json:"mal_id"`
+}
+
+// genre is a genre as the archive filters by it
+type genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// recordStatuses maps the airing statuses of the site to scraper statuses
+var recordStatuses = map[string]string{
+	"In Corso":  scraper.StatusOngoing,
+	"Terminato": scraper.StatusCompleted,
+	"Droppato":  scraper.StatusCancelled,
+}
+
+// anime converts a record into a scraper.Anime
+func (r record) anime() scraper.Anime {
+	title := r.Title
+	if title == "" {
+		title = r.TitleEng
+	}
+	var alternatives []string
+	for _, alt := range []string{r.TitleEng, r.TitleIt} {
+		if alt != "" && alt != title {
+			alternatives = append(alternatives, alt)
+		}
+	}
+	var genres []string
+	for _, g := range r.Genres {
+		genres = append(genres, g.Name)
+	}
+	status, ok := recordStatuses[r.Status]
+	if !ok {
+		status = scraper.StatusUnknown
+	}
+	subDub := "sub"
+	if r.Dub == 1 {
+		subDub = "dub"
+	}
+	year, _ := strconv.Atoi(r.Date)
+
+	return scraper.Anime{
+		ID:                strconv.Itoa(r.ID) + "-" + r.Slug,
+		Title:             title,
+		Artist:            r.Studio,
+		Description:       strings.TrimSpace(r.Plot),
+		Genre:             strings.Join(genres, ", "),
+		ThumbnailURL:      r.ImageURL,
+		Status:            status,
+		AlternativeTitles: alternatives,
+		Episodes:          r.EpisodesCount,
+		SubDub:            subDub,
+		ReleaseYear:       year,
+	}
+}
+
+// matchesTranslation reports whether a record is in the selected translation
+func (s *Scraper) matchesTranslation(r record) bool {
+	return (r.Dub == 1) == (s.translation == "dub")
+}
+
+// animeIDPattern matches the ID part of a show's URL: the numeric ID and the slug
+var animeIDPattern = regexp.MustCompile(`^(\d+)-[^/]+$`)
+
+// parseAnimeID splits an anime ID into the site's numeric ID and the path
+// segment of the show's page. Accepted forms:
+//
+//	12-one-piece
+//	/anime/12-one-piece
+//	https://www.animeunity.so/anime/12-one-piece/3456
+func parseAnimeID(animeID string) (id, segment string, err error) {
+	path := animeID
+	if u, parseErr := url.Parse(animeID); parseErr == nil {
+		path = u.Path
+	}
+	path = strings.TrimPrefix(strings.Trim(path, "/"), "anime/")
+	segment, _, _ = strings.Cut(path, "/")
+	match := animeIDPattern.FindStringSubmatch(segment)
+	if match == nil {
+		return "", "", exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. 12-one-piece)", animeID)
+	}
+	return match[1], segment, nil
+}
+
+// session holds what the archive page hands its search component
+type session struct {
+	token  string  // CSRF token the archive endpoint expects with the session cookie
+	genres []genre // Genres the archive filters by
+}
+
+// getSession fetches the archive page once per command, which sets the
+// session cookie and carries the matching CSRF token and the genre list
+func (s *Scraper) getSession(ctx context.Context) (*session, error) {
+	if s.session != nil {
+		return s.session, nil
+	}
+	doc, err := s.getPage(ctx, "/archivio", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token := htmlx.Attr(doc.Find("meta[name=csrf-token]"), "content", "")
+	if token == "" {
+		return nil, exterr.New(exterr.Parse, "no CSRF token on the archive page")
+	}
+	genres := []genre{}
+	if raw := htmlx.Attr(doc.Find("archivio"), "all_genres", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &genres); err != nil {
+			return nil, exterr.New(exterr.Parse, "error parsing genre list: %w", err)
+		}
+	}
+	s.session = &session{token: token, genres: genres}
+	return s.session, nil
+}
+
+// archivePageSize is the number of records the archive returns per request
+const archivePageSize = 30
+
+// archiveQuery is the body of an archive request. Filters left unset are
+// false, as the archive's own search component sends them.
+type archiveQuery map[string]interface{}
+
+// newArchiveQuery returns an unfiltered query for a page of the archive
+func (s *Scraper) newArchiveQuery(page int) archiveQuery {
+	return archiveQuery{
+		"title":  false,
+		"type":   false,
+		"year":   false,
+		"order":  false,
+		"status": false,
+		"genres": false,
+		"season": false,
+		"dubbed": s.translation == "dub",
+		"offset": (max(page, 1) - 1) * archivePageSize,
+	}
+}
+
+// archive fetches a page of the archive, which serves search, filters and the
+// catalog orders alike. With dubbed unset the archive lists both translations,
+// so subbed pages can hold fewer records than archivePageSize.
+func (s *Scraper) archive(ctx context.Context, query archiveQuery) ([]scraper.Anime, error) {
+	var response struct {
+		Records []record `json:"records"`
+	}
+	if err := s.postJSON(ctx, "/archivio/get-animes", query, &response); err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	for _, r := range response.Records {
+		if s.matchesTranslation(r) {
+			animes = append(animes, r.anime())
+		}
+	}
+	return animes, nil
+}
+
+// SearchAnime searches the archive by title, narrowed down by filters
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int, filters SearchFilters) ([]scraper.Anime, error) {
+	session, err := s.getSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	body := s.newArchiveQuery(page)
+	if err := filters.apply(body, session.genres); err != nil {
+		return nil, err
+	}
+	if query != "" {
+		body["title"] = query
+	}
+	return s.archive(ctx, body)
+}
+
+// GetPopularAnime retrieves the archive's most popular shows
+func (s *Scraper) GetPopularAnime(ctx context.Context, page int) ([]scraper.Anime, error) {
+	body := s.newArchiveQuery(page)
+	body["order"] = "Popolarità"
+	return s.archive(ctx, body)
+}
+
+// GetLatestUpdates retrieves the shows of the latest episodes the home page
+// lists, newest first
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]scraper.Anime, error) {
+	query := url.Values{}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	doc, err := s.getPage(ctx, "/", query)
+	if err != nil {
+		return nil, err
+	}
+
+	// The home page hands its episode grid a page of episodes, each with its show
+	raw := htmlx.Attr(doc.Find("layout-items"), "items-json", "")
+	if raw == "" {
+		return nil, exterr.New(exterr.Parse, "no episode list on the home page")
+	}
+	var items struct {
+		Data []struct {
+			Anime record `json:"anime"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		return nil, exterr.New(exterr.Parse, "error parsing episode list: %w", err)
+	}
+
+	animes := []scraper.Anime{}
+	seen := map[int]bool{}
+	for _, item := range items.Data {
+		if seen[item.Anime.ID] || !s.matchesTranslation(item.Anime) {
+			continue
+		}
+		seen[item.Anime.ID] = true
+		animes = append(animes, item.Anime.anime())
+	}
+	return animes, nil
+}
+
+// animePage is what a show's page hands its video player
+type animePage struct {
+	segment  string         // Path segment of the page, e.g. 12-one-piece
+	record   record         // The show
+	episodes []episodeEntry // The first episodes, up to episodeRangeSize
+	count    int            // Number of episodes of the show
+}
+
+// getAnimePage fetches the page of a show and reads its player's attributes
+func (s *Scraper) getAnimePage(ctx context.Context, animeID string) (animePage, error) {
+	_, segment, err := parseAnimeID(animeID)
+	if err != nil {
+		return animePage{}, err
+	}
+	doc, err := s.getPage(ctx, "/anime/"+segment, nil)
+	if err != nil {
+		return animePage{}, err
+	}
+
+	player := doc.Find("video-player").First()
+	if player.Length() == 0 {
+		return animePage{}, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	page := animePage{segment: segment}
+	if err := unmarshalAttr(player, "anime", &page.record); err != nil {
+		return animePage{}, err
+	}
+	if err := unmarshalAttr(player, "episodes", &page.episodes); err != nil {
+		return animePage{}, err
+	}
+	page.count, err = strconv.Atoi(htmlx.Attr(player, "episodes_count", "0"))
+	if err != nil {
+		return animePage{}, exterr.New(exterr.Parse, "invalid episode count: %w", err)
+	}
+	return page, nil
+}
+
+// unmarshalAttr decodes the JSON a page component receives as attribute name
+func unmarshalAttr(sel *goquery.Selection, name string, v interface{}) error {
+	raw := htmlx.Attr(sel, name, "")
+	if raw == "" {
+		return exterr.New(exterr.Parse, "no %s attribute on the anime page", name)
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing %s attribute: %w", name, err)
+	}
+	return nil
+}
+
+// GetAnimeDetails retrieves description, genres, airing status and tracker
+// IDs for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	page, err := s.getAnimePage(ctx, animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	anime := page.record.anime()
+	anime.Episodes = page.count
+	return AnimeDetails{
+		Anime:         anime,
+		Type:          page.record.Type,
+		Season:        page.record.Season,
+		EpisodeLength: page.record.EpisodesLength,
+		AniListID:     page.record.AniListID,
+		MALID:         page.record.MALID,
+	}, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// episodeRangeSize is the number of episodes the anime page and each request
+// to the episode endpoint list
+const episodeRangeSize = 120
+
+// episodeEntry is an episode as the anime page and the episode endpoint list it
+type episodeEntry struct {
+	ID        int    `json:"id"`         // ID the player's embed URL is requested with
+	Number    string `json:"number"`     // e.g. "12" or "12.5"
+	CreatedAt string `json:"created_at"` // Upload time, e.g. "2023-10-01 18:30:00"
+}
+
+// listEpisodes returns every episode of a show, fetching the ranges past the
+// ones the anime page lists from the episode endpoint
+func (s *Scraper) listEpisodes(ctx context.Context, animeID string) (string, []episodeEntry, error) {
+	page, err := s.getAnimePage(ctx, animeID)
+	if err != nil {
+		return "", nil, err
+	}
+	id := strconv.Itoa(page.record.ID)
+
+	entries := page.episodes
+	for start := len(entries) + 1; start <= page.count; start += episodeRangeSize {
+		var response struct {
+			Episodes []episodeEntry `json:"episodes"`
+		}
+		query := url.Values{
+			"start_range": {strconv.Itoa(start)},
+			"end_range":   {strconv.Itoa(min(start+episodeRangeSize-1, page.count))},
+		}
+		if err := s.getJSON(ctx, "/info_api/"+id+"/1", query, &response); err != nil {
+			return "", nil, err
+		}
+		if len(response.Episodes) == 0 {
+			break
+		}
+		entries = append(entries, response.Episodes...)
+	}
+	return page.segment, entries, nil
+}
+
+// GetEpisodeList returns the episodes of an anime. Each entry of the site has
+// a single translation, so the list is the same whatever -translation says.
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	segment, entries, err := s.listEpisodes(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := []scraper.Episode{}
+	for _, entry := range entries {
+		number, err := strconv.ParseFloat(entry.Number, 64)
+		if err != nil {
+			continue
+		}
+		var uploaded int64
+		if t, err := time.Parse(time.DateTime, entry.CreatedAt); err == nil {
+			uploaded = t.Unix()
+		}
+		episodes = append(episodes, scraper.Episode{
+			ID:            s.baseURL + "/anime/" + segment + "/" + strconv.Itoa(entry.ID),
+			Name:          "Episodio " + entry.Number,
+			DateUpload:    uploaded,
+			EpisodeNumber: number,
+		})
+	}
+	return episodes, nil
+}
+
+// Video extends scraper.Video with the kind of stream
+type Video struct {
+	scraper.Video
+	HLS bool `json:"hls"` // Whether the URL is an HLS playlist rather than an MP4 file
+}
+
+// VideoResponse lists the streams of an episode: the HLS playlist of its
+// player and, when the player offers a download, the MP4 file
+type VideoResponse struct {
+	Streams   []Video         `json:"streams"`
+	Subtitles []scraper.Track `json:"subtitles"`
+}
+
+// GetVideoList returns the streams of an episode, resolved from the VixCloud
+// player the site embeds
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	segment, entries, err := s.listEpisodes(ctx, animeID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	var episode *episodeEntry
+	for i := range entries {
+		if number, err := strconv.ParseFloat(entries[i].Number, 64); err == nil && number == episodeNumber {
+			episode = &entries[i]
+		}
+	}
+	if episode == nil {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g not found", episodeNumber)
+	}
+
+	embedURL, err := s.embedURL(ctx, episode.ID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	extractor := hosters.For(s.extractors, embedURL)
+	if extractor == nil {
+		return VideoResponse{}, exterr.New(exterr.Unsupported, "episode %g uses an unsupported player: %s", episodeNumber, embedURL)
+	}
+	result, err := extractor.Extract(ctx, embedURL, s.baseURL+"/anime/"+segment+"/"+strconv.Itoa(episode.ID))
+	if err != nil {
+		return VideoResponse{}, err
+	}
+
+	response := VideoResponse{Streams: []Video{}, Subtitles: []scraper.Track{}}
+	for _, stream := range result.Streams {
+		response.Streams = append(response.Streams, Video{
+			Video: scraper.Video{
+				ID:       segment,
+				Quality:  stream.Quality,
+				VideoURL: stream.URL,
+				Headers:  stream.Headers,
+			},
+			HLS: stream.HLS,
+		})
+	}
+	return response, nil
+}
+
+// embedURL asks the site for the player URL of an episode, which it answers
+// as plain text
+func (s *Scraper) embedURL(ctx context.Context, episodeID int) (string, error) {
+	resp, err := s.get(ctx, "/embed-url/"+strconv.Itoa(episodeID), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", exterr.New(exterr.Network, "error reading player URL: %w", err)
+	}
+	embedURL := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(embedURL, "http") {
+		return "", exterr.New(exterr.Parse, "invalid player URL %q", embedURL)
+	}
+	return embedURL, nil
+}
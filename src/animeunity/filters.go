@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// SearchFilters is the JSON object accepted by --filters, e.g.
+//
+//	{"genres": ["Action", "Comedy"], "year": 2023, "season": "fall", "type": "TV", "status": "ongoing", "sortBy": "popularity"}
+//
+// Every field is optional. Genres take the names the genres command lists,
+// which the site serves with its archive page; the other fields take the IDs
+// or the Italian names of their options.
+type SearchFilters struct {
+	Genres []string `json:"genres,omitempty"`
+	Year   int      `json:"year,omitempty"`
+	Season string   `json:"season,omitempty"`
+	Type   string   `json:"type,omitempty"`
+	Status string   `json:"status,omitempty"`
+	SortBy string   `json:"sortBy,omitempty"`
+}
+
+// showTypes lists the show types of the archive, whose IDs are the values it filters by
+var showTypes = []FilterOption{
+	{ID: "TV", Name: "TV"},
+	{ID: "TV Short", Name: "TV Short"},
+	{ID: "OVA", Name: "OVA"},
+	{ID: "ONA", Name: "ONA"},
+	{ID: "Special", Name: "Special"},
+	{ID: "Movie", Name: "Movie"},
+}
+
+// seasons lists the airing seasons; the archive filters by the Italian name
+var seasons = []FilterOption{
+	{ID: "winter", Name: "Inverno"},
+	{ID: "spring", Name: "Primavera"},
+	{ID: "summer", Name: "Estate"},
+	{ID: "fall", Name: "Autunno"},
+}
+
+// statuses lists the airing statuses by scraper status; the archive filters
+// by the Italian name
+var statuses = []FilterOption{
+	{ID: scraper.StatusOngoing, Name: "In Corso"},
+	{ID: scraper.StatusCompleted, Name: "Terminato"},
+	{ID: "upcoming", Name: "In Uscita"},
+	{ID: scraper.StatusCancelled, Name: "Droppato"},
+}
+
+// sortBy lists the sort orders of the archive, which sorts by the Italian name
+var sortBy = []FilterOption{
+	{ID: "az", Name: "Lista A-Z"},
+	{ID: "za", Name: "Lista Z-A"},
+	{ID: "popularity", Name: "Popolarità"},
+	{ID: "rating", Name: "Valutazione"},
+}
+
+// FilterOption is one value of a filter, with the ID to put in the filters JSON
+type FilterOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FilterOptions is the output of the genres command: the values each field of
+// the filters JSON accepts, for front-ends building filter pickers
+type FilterOptions struct {
+	Genres   []FilterOption `json:"genres"`
+	Types    []FilterOption `json:"types"`
+	Seasons  []FilterOption `json:"seasons"`
+	Statuses []FilterOption `json:"statuses"`
+	SortBy   []FilterOption `json:"sortBy"`
+}
+
+// GetFilterOptions lists the values the filters JSON accepts. The genres
+// come from the archive page, so they follow the site's list.
+func (s *Scraper) GetFilterOptions(ctx context.Context) (FilterOptions, error) {
+	session, err := s.getSession(ctx)
+	if err != nil {
+		return FilterOptions{}, err
+	}
+
+	options := FilterOptions{
+		Genres:   []FilterOption{},
+		Types:    showTypes,
+		Seasons:  seasons,
+		Statuses: statuses,
+		SortBy:   sortBy,
+	}
+	for _, genre := range session.genres {
+		options.Genres = append(options.Genres, FilterOption{ID: genre.Name, Name: genre.Name})
+	}
+	return options, nil
+}
+
+// ParseSearchFilters parses and validates the --filters JSON object. An empty
+// string means no filters. Genres are checked when the search runs, against
+// the archive's genre list.
+func ParseSearchFilters(filters string) (SearchFilters, error) {
+	var f SearchFilters
+	if strings.TrimSpace(filters) == "" {
+		return f, nil
+	}
+
+	if err := json.Unmarshal([]byte(filters), &f); err != nil {
+		return f, exterr.New(exterr.InvalidArgument, "invalid filters: %w", err)
+	}
+
+	for _, field := range []struct {
+		name    string
+		value   *string
+		options []FilterOption
+	}{
+		{"season", &f.Season, seasons},
+		{"type", &f.Type, showTypes},
+		{"status", &f.Status, statuses},
+		{"sortBy", &f.SortBy, sortBy},
+	} {
+		if *field.value == "" {
+			continue
+		}
+		id, ok := optionID(*field.value, field.options)
+		if !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid %s %q (valid: %s)", field.name, *field.value, optionIDs(field.options))
+		}
+		*field.value = id
+	}
+	return f, nil
+}
+
+// empty reports whether no filter is set
+func (f SearchFilters) empty() bool {
+	return len(f.Genres) == 0 && f.Year == 0 && f.Season == "" && f.Type == "" && f.Status == "" && f.SortBy == ""
+}
+
+// apply sets the filters in the body of an archive request, resolving the
+// genre names to the genres of the archive page
+func (f SearchFilters) apply(query archiveQuery, genres []genre) error {
+	if len(f.Genres) > 0 {
+		selected := []genre{}
+		for _, name := range f.Genres {
+			match, ok := findGenre(name, genres)
+			if !ok {
+				return exterr.New(exterr.InvalidArgument, "invalid genre %q (see the genres command)", name)
+			}
+			selected = append(selected, match)
+		}
+		query["genres"] = selected
+	}
+	if f.Year != 0 {
+		query["year"] = strconv.Itoa(f.Year)
+	}
+	for key, field := range map[string]struct {
+		value   string
+		options []FilterOption
+	}{
+		"season": {f.Season, seasons},
+		"type":   {f.Type, showTypes},
+		"status": {f.Status, statuses},
+		"order":  {f.SortBy, sortBy},
+	} {
+		for _, option := range field.options {
+			if option.ID == field.value {
+				query[key] = option.Name
+			}
+		}
+	}
+	return nil
+}
+
+// findGenre finds a genre by name, ignoring case
+func findGenre(name string, genres []genre) (genre, bool) {
+	for _, g := range genres {
+		if strings.EqualFold(g.Name, strings.TrimSpace(name)) {
+			return g, true
+		}
+	}
+	return genre{}, false
+}
+
+// optionID finds an option by ID or name, ignoring case
+func optionID(value string, options []FilterOption) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, option := range options {
+		if strings.EqualFold(option.ID, value) || strings.EqualFold(option.Name, value) {
+			return option.ID, true
+		}
+	}
+	return "", false
+}
+
+// optionIDs joins the IDs of options for error messages
+func optionIDs(options []FilterOption) string {
+	ids := make([]string, len(options))
+	for i, option := range options {
+		ids[i] = option.ID
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece", 1, SearchFilters{})
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "12-one-piece" {
+		t.Fatalf("SearchAnime results = %+v, want 12-one-piece first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 3", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, episodes[0].EpisodeNumber)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	var qualities []string
+	for _, v := range videos.Streams {
+		qualities = append(qualities, v.Quality)
+	}
+	if want := []string{"auto", "1080p"}; !reflect.DeepEqual(qualities, want) {
+		t.Errorf("GetVideoList qualities = %q, want %q", qualities, want)
+	}
+	if len(videos.Streams) > 0 && !videos.Streams[0].HLS {
+		t.Errorf("GetVideoList first stream = %+v, want the HLS playlist", videos.Streams[0])
+	}
+}
+
+func TestParseAnimeID(t *testing.T) {
+	tests := []struct {
+		animeID     string
+		wantID      string
+		wantSegment string
+		wantErr     bool
+	}{
+		{animeID: "12-one-piece", wantID: "12", wantSegment: "12-one-piece"},
+		{animeID: "/anime/12-one-piece", wantID: "12", wantSegment: "12-one-piece"},
+		{animeID: "https://www.animeunity.so/anime/12-one-piece/3456", wantID: "12", wantSegment: "12-one-piece"},
+		{animeID: "one-piece", wantErr: true},
+		{animeID: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		id, segment, err := parseAnimeID(tt.animeID)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAnimeID(%q) error = %v, wantErr %v", tt.animeID, err, tt.wantErr)
+			continue
+		}
+		if id != tt.wantID || segment != tt.wantSegment {
+			t.Errorf("parseAnimeID(%q) = %q, %q, want %q, %q", tt.animeID, id, segment, tt.wantID, tt.wantSegment)
+		}
+	}
+}
+
+func TestRecordAnime(t *testing.T) {
+	r := record{
+		ID:       12,
+		Slug:     "one-piece",
+		TitleEng: "One Piece",
+		TitleIt:  "One Piece (ITA)",
+		Status:   "In Corso",
+		Date:     "1999",
+		Dub:      1,
+		Genres:   []genre{{ID: 1, Name: "Azione"}, {ID: 2, Name: "Avventura"}},
+	}
+	got := r.anime()
+	want := scraper.Anime{
+		ID:                "12-one-piece",
+		Title:             "One Piece",
+		Genre:             "Azione, Avventura",
+		Status:            scraper.StatusOngoing,
+		AlternativeTitles: []string{"One Piece (ITA)"},
+		SubDub:            "dub",
+		ReleaseYear:       1999,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("record.anime() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the metadata a detail screen needs
+// and the seasons of the show
+type AnimeDetails struct {
+	scraper.Anime
+	Studios []string `json:"studios,omitempty"`
+	Rating  string   `json:"rating,omitempty"`  // German age rating, e.g. "FSK 12"
+	Season  string   `json:"season,omitempty"`  // Season the ID selects, e.g. "Staffel 1"
+	Seasons []Season `json:"seasons,omitempty"` // Every season and the films, in the site's order
+}
+
+// Season is a part of a show with its own episode list: a season or the films
+type Season struct {
+	ID   string `json:"id"`   // Anime ID selecting the season, e.g. naruto/staffel-2
+	Name string `json:"name"` // e.g. "Staffel 2" or "Filme"
+}
+
+// seasonLinkPattern matches the season links of a show page and captures the
+// season path, e.g. staffel-2 in /anime/stream/naruto/staffel-2
+var seasonLinkPattern = regexp.MustCompile(`^/anime/stream/[^/]+/(staffel-\d+|filme)/?$`)
+
+// yearPattern finds the first year in production spans such as "(2002 - 2007)"
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+// parseAnimeID splits an anime ID into the show's slug and the season path,
+// which is empty when the ID names the show only. Accepted forms:
+//
+//	naruto
+//	naruto/staffel-2
+//	https://aniworld.to/anime/stream/naruto/staffel-2
+func parseAnimeID(animeID string) (slug, season string, err error) {
+	path := animeID
+	if u, parseErr := url.Parse(animeID); parseErr == nil {
+		path = u.Path
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(strings.Trim(path, "/"), "anime/stream"), "/"), "/")
+	if parts[0] == "" {
+		return "", "", exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. naruto or naruto/staffel-2)", animeID)
+	}
+	if len(parts) > 1 {
+		season = parts[1]
+	}
+	return parts[0], season, nil
+}
+
+// absoluteURL resolves a link of the site, which uses relative image URLs
+func (s *Scraper) absoluteURL(href string) string {
+	if href == "" || strings.HasPrefix(href, "http") {
+		return href
+	}
+	return s.baseURL + "/" + strings.TrimPrefix(href, "/")
+}
+
+// searchResult is an entry of the series search endpoint
+type searchResult struct {
+	Name           string `json:"name"`
+	Link           string `json:"link"` // Slug of the show
+	Description    string `json:"description"`
+	Cover          string `json:"cover"`
+	ProductionYear string `json:"productionYear"` // e.g. "(2002 - 2007)"
+}
+
+// SearchAnime searches for shows by title. The endpoint returns every match
+// at once, so pages after the first are empty.
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	animes := []scraper.Anime{}
+	if page > 1 {
+		return animes, nil
+	}
+
+	var results []searchResult
+	if err := s.getJSON(ctx, "/ajax/seriesSearch", url.Values{"keyword": {query}}, &results); err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Link == "" {
+			continue
+		}
+		anime := scraper.Anime{
+			ID:           result.Link,
+			Title:        result.Name,
+			Description:  result.Description,
+			ThumbnailURL: s.absoluteURL(result.Cover),
+			Status:       scraper.StatusUnknown,
+		}
+		anime.ReleaseYear, _ = strconv.Atoi(yearPattern.FindString(result.ProductionYear))
+		animes = append(animes, anime)
+	}
+	return animes, nil
+}
+
+// showPage fetches the page of a show
+func (s *Scraper) showPage(ctx context.Context, slug string) (*goquery.Document, error) {
+	doc, err := s.getPage(ctx, "/anime/stream/"+slug, nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find(".series-title").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", slug)
+	}
+	return doc, nil
+}
+
+// showSeasons reads the seasons a show page links to
+func showSeasons(doc *goquery.Document, slug string) []Season {
+	var seasons []Season
+	seen := map[string]bool{}
+	doc.Find("#stream ul li a").Each(func(_ int, link *goquery.Selection) {
+		match := seasonLinkPattern.FindStringSubmatch(htmlx.Attr(link, "href", ""))
+		if match == nil || seen[match[1]] {
+			return
+		}
+		seen[match[1]] = true
+
+		name := "Filme"
+		if number, ok := strings.CutPrefix(match[1], "staffel-"); ok {
+			name = "Staffel " + number
+		}
+		seasons = append(seasons, Season{ID: slug + "/" + match[1], Name: name})
+	})
+	return seasons
+}
+
+// firstSeason returns the season a bare show ID selects: the first numbered
+// season, or the films for shows that only have films
+func firstSeason(seasons []Season) (Season, bool) {
+	for _, season := range seasons {
+		if !strings.HasSuffix(season.ID, "/filme") {
+			return season, true
+		}
+	}
+	if len(seasons) > 0 {
+		return seasons[0], true
+	}
+	return Season{}, false
+}
+
+// resolveSeason returns the season path an anime ID selects: the one it
+// names, or the show's first season
+func (s *Scraper) resolveSeason(ctx context.Context, animeID string) (slug, season string, err error) {
+	slug, season, err = parseAnimeID(animeID)
+	if err != nil || season != "" {
+		return slug, season, err
+	}
+	doc, err := s.showPage(ctx, slug)
+	if err != nil {
+		return "", "", err
+	}
+	first, ok := firstSeason(showSeasons(doc, slug))
+	if !ok {
+		return "", "", exterr.New(exterr.NotFound, "anime %q has no episodes", slug)
+	}
+	_, season, _ = parseAnimeID(first.ID)
+	return slug, season, nil
+}
+
+// GetAnimeDetails retrieves description, genres, studios, airing years and
+// seasons for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	slug, season, err := parseAnimeID(animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+	doc, err := s.showPage(ctx, slug)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	heading := doc.Find(".series-title h1").First()
+	description := doc.Find("p.seri_des").First()
+	var genres []string
+	doc.Find(".genres li a").Each(func(_ int, link *goquery.Selection) {
+		genres = append(genres, htmlx.Text(link))
+	})
+	var studios []string
+	doc.Find("li[itemprop='creator'] [itemprop='name']").Each(func(_ int, name *goquery.Selection) {
+		studios = append(studios, htmlx.Text(name))
+	})
+
+	details := AnimeDetails{
+		Anime: scraper.Anime{
+			ID:           strings.TrimSuffix(slug+"/"+season, "/"),
+			Title:        htmlx.TextFirst(heading, "span"),
+			Artist:       strings.Join(studios, ", "),
+			Description:  htmlx.Attr(description, "data-full-description", htmlx.Text(description)),
+			Genre:        strings.Join(genres, ", "),
+			ThumbnailURL: s.absoluteURL(htmlx.AttrAny(doc.Find(".seriesCoverBox img").First(), "", "data-src", "src")),
+			Status:       scraper.StatusUnknown,
+		},
+		Studios: studios,
+		Seasons: showSeasons(doc, slug),
+	}
+	if details.Title == "" {
+		details.Title = htmlx.Text(heading)
+	}
+	for _, title := range strings.Split(htmlx.Attr(heading, "data-alternativetitles", ""), ",") {
+		if title = strings.TrimSpace(title); title != "" && title != details.Title {
+			details.AlternativeTitles = append(details.AlternativeTitles, title)
+		}
+	}
+	if fsk := htmlx.Attr(doc.Find("[data-fsk]").First(), "data-fsk", ""); fsk != "" {
+		details.Rating = "FSK " + fsk
+	}
+
+	details.ReleaseYear, _ = strconv.Atoi(htmlx.TextFirst(doc.Selection, "span[itemprop='startDate']"))
+	if end := htmlx.TextFirst(doc.Selection, "span[itemprop='endDate']"); end != "" {
+		// Shows still airing end "Heute", today
+		details.Status = scraper.StatusCompleted
+		if strings.EqualFold(end, "heute") {
+			details.Status = scraper.StatusOngoing
+		}
+	}
+
+	current := details.ID
+	if season == "" {
+		if first, ok := firstSeason(details.Seasons); ok {
+			current = first.ID
+		}
+	}
+	for _, entry := range details.Seasons {
+		if entry.ID == current {
+			details.Season = entry.Name
+		}
+	}
+	return details, nil
+}
+
+// RelatedAnime extends scraper.Anime with how the entry relates to the requested one
+type RelatedAnime struct {
+	scraper.Anime
+	Relation string `json:"relation"` // Always season: the other parts of the show
+}
+
+// GetRelatedAnime lists the other seasons and the films of a show, as anime
+// IDs episodes and stream-url accept
+func (s *Scraper) GetRelatedAnime(ctx context.Context, animeID string, page int) ([]RelatedAnime, error) {
+	related := []RelatedAnime{}
+	// Everything is on the show page
+	if page > 1 {
+		return related, nil
+	}
+
+	slug, season, err := parseAnimeID(animeID)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.showPage(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	seasons := showSeasons(doc, slug)
+	current := slug + "/" + season
+	if first, ok := firstSeason(seasons); ok && season == "" {
+		current = first.ID
+	}
+
+	title := htmlx.TextFirst(doc.Selection, ".series-title h1 span", ".series-title h1")
+	cover := s.absoluteURL(htmlx.AttrAny(doc.Find(".seriesCoverBox img").First(), "", "data-src", "src"))
+	for _, other := range seasons {
+		if other.ID == current {
+			continue
+		}
+		related = append(related, RelatedAnime{
+			Anime: scraper.Anime{
+				ID:           other.ID,
+				Title:        title + " - " + other.Name,
+				ThumbnailURL: cover,
+				Status:       scraper.StatusUnknown,
+			},
+			Relation: "season",
+		})
+	}
+	return related, nil
+}
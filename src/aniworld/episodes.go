@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// languageKeys maps the translations to the data-lang-key of the hosters an
+// episode page lists
+var languageKeys = map[string]string{"dub": "1", "en-sub": "2", "sub": "3"}
+
+// languageFlags maps the translations to the flag images the episode list
+// marks the available languages with
+var languageFlags = map[string]string{"dub": "german.svg", "en-sub": "japanese-english.svg", "sub": "japanese-german.svg"}
+
+// episodePath returns the path of an episode's page. Films are numbered like
+// episodes within the films season.
+func episodePath(slug, season string, number float64) string {
+	kind := "episode-"
+	if season == "filme" {
+		kind = "film-"
+	}
+	return "/anime/stream/" + slug + "/" + season + "/" + kind + strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// GetEpisodeList returns the episodes of a season available in the selected language
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	slug, season, err := s.resolveSeason(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.getPage(ctx, "/anime/stream/"+slug+"/"+season, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := doc.Find("table.seasonEpisodesList tbody tr")
+	if rows.Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "%s/%s has no episodes", slug, season)
+	}
+	episodes := []scraper.Episode{}
+	rows.Each(func(_ int, row *goquery.Selection) {
+		number, err := strconv.ParseFloat(htmlx.Attr(row.Find("meta[itemprop='episodeNumber']"), "content", ""), 64)
+		if err != nil || !hasLanguage(row, s.translation) {
+			return
+		}
+		name := htmlx.TextFirst(row, ".seasonEpisodeTitle strong", ".seasonEpisodeTitle span")
+		if name == "" {
+			name = "Episode " + strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		episodes = append(episodes, scraper.Episode{
+			ID:            s.baseURL + episodePath(slug, season, number),
+			Name:          name,
+			EpisodeNumber: number,
+		})
+	})
+	return episodes, nil
+}
+
+// hasLanguage reports whether an episode list row offers the translation.
+// Rows without flags are assumed to offer every language.
+func hasLanguage(row *goquery.Selection, translation string) bool {
+	flags := row.Find("img.flag")
+	if flags.Length() == 0 {
+		return true
+	}
+	found := false
+	flags.Each(func(_ int, flag *goquery.Selection) {
+		if path.Base(htmlx.Attr(flag, "src", "")) == languageFlags[translation] {
+			found = true
+		}
+	})
+	return found
+}
+
+// Video extends scraper.Video with the AniWorld hoster the stream was resolved from
+type Video struct {
+	scraper.Video
+	Server string `json:"server,omitempty"` // Hoster name as the site shows it, e.g. "VOE"
+}
+
+// Server is a hoster an episode is embedded from
+type Server struct {
+	Name      string `json:"name"`      // e.g. "VOE" or "Doodstream"
+	EmbedURL  string `json:"embedUrl"`  // Site link redirecting to the player, or the player once resolved
+	Supported bool   `json:"supported"` // Whether the extension resolves the hoster into streams
+}
+
+// Warning reports a supported hoster whose streams could not be extracted, so
+// frontends can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // Hoster name, e.g. "VOE"
+	Provider string `json:"provider,omitempty"` // Host of the player
+	Reason   string `json:"reason"`
+}
+
+// VideoResponse lists the streams resolved from an episode's hosters along
+// with every hoster offering the episode, including those the extension
+// cannot resolve
+type VideoResponse struct {
+	Streams  []Video   `json:"streams"`
+	Servers  []Server  `json:"servers"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// hoster is an entry of an episode page's hoster list
+type hoster struct {
+	name     string
+	redirect string // Site path redirecting to the player, e.g. /redirect/1234567
+}
+
+// GetVideoList returns every hoster offering an episode in the selected
+// language, the preferred hoster first, with the streams of the VOE and
+// Streamtape hosters
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	slug, season, err := s.resolveSeason(ctx, animeID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	pagePath := episodePath(slug, season, episodeNumber)
+	doc, err := s.getPage(ctx, pagePath, nil)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+
+	items := doc.Find("li[data-lang-key][data-link-target]")
+	if items.Length() == 0 {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g not found", episodeNumber)
+	}
+	var hosts []hoster
+	items.Each(func(_ int, item *goquery.Selection) {
+		if htmlx.Attr(item, "data-lang-key", "") == languageKeys[s.translation] {
+			hosts = append(hosts, hoster{name: htmlx.TextFirst(item, "h4"), redirect: htmlx.Attr(item, "data-link-target", "")})
+		}
+	})
+	if len(hosts) == 0 {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g has no %s hosters", episodeNumber, s.translation)
+	}
+	for i, h := range hosts {
+		if s.server != "" && strings.EqualFold(h.name, s.server) {
+			hosts = append(append([]hoster{h}, hosts[:i]...), hosts[i+1:]...)
+			break
+		}
+	}
+
+	response := VideoResponse{Streams: []Video{}, Servers: []Server{}, Warnings: []Warning{}}
+	for _, h := range hosts {
+		extractor := s.extractors[h.name]
+		if extractor == nil {
+			response.Servers = append(response.Servers, Server{Name: h.name, EmbedURL: s.absoluteURL(h.redirect)})
+			continue
+		}
+
+		embedURL, err := s.redirectTarget(ctx, h.redirect)
+		if err != nil {
+			response.Servers = append(response.Servers, Server{Name: h.name, EmbedURL: s.absoluteURL(h.redirect), Supported: true})
+			response.Warnings = append(response.Warnings, Warning{Source: h.name, Reason: err.Error()})
+			continue
+		}
+		response.Servers = append(response.Servers, Server{Name: h.name, EmbedURL: embedURL, Supported: true})
+
+		result, err := extractor.Extract(ctx, embedURL, s.baseURL+pagePath)
+		if err != nil {
+			response.Warnings = append(response.Warnings, Warning{Source: h.name, Provider: hostOf(embedURL), Reason: err.Error()})
+			continue
+		}
+		for _, stream := range result.Streams {
+			response.Streams = append(response.Streams, Video{
+				Video: scraper.Video{
+					ID:       slug + "/" + season,
+					Quality:  stream.Quality,
+					VideoURL: stream.URL,
+					Headers:  stream.Headers,
+				},
+				Server: h.name,
+			})
+		}
+	}
+	return response, nil
+}
+
+// redirectTarget follows one of the site's hoster redirects and returns the
+// player URL it lands on
+func (s *Scraper) redirectTarget(ctx context.Context, redirect string) (string, error) {
+	resp, err := s.get(ctx, redirect, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Request.URL.String(), nil
+}
+
+// hostOf returns the host of rawURL
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Hostname()
+	}
+	return ""
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair/pkg/scraper"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// sourceID identifies the AniWorld source
+const sourceID = "1483214262415580537"
+
+// defaultBaseURL is AniWorld's site. base_url in the config file points the
+// extension at a mirror when the site moves.
+const defaultBaseURL = "https://aniworld.to"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// TranslationTypes lists the values of -translation, the languages AniWorld
+// offers episodes in: sub (Japanese audio, German subtitles), dub (German
+// audio) and en-sub (Japanese audio, English subtitles)
+var TranslationTypes = []string{"sub", "dub", "en-sub"}
+
+type Scraper struct {
+	baseURL     string
+	translation string // Language episodes and streams are listed in
+	server      string // Preferred hoster, e.g. "Streamtape"; tried first when set
+	client      *httpclient.Client
+	retry       httpclient.RetryPolicy
+	extractors  map[string]hosters.Extractor // Video hosts streams are resolved from, by the hoster name the site shows
+}
+
+// NewScraper creates a new instance of the aniworld scraper
+func NewScraper() *Scraper {
+	client := httpclient.New()
+	return &Scraper{
+		baseURL:     defaultBaseURL,
+		translation: "sub",
+		client:      client,
+		retry:       httpclient.DefaultRetryPolicy,
+		extractors: map[string]hosters.Extractor{
+			"VOE":        &hosters.VOE{Client: client},
+			"Streamtape": &hosters.Streamtape{Client: client},
+		},
+	}
+}
+
+// Requests per minute AniWorld tolerates before answering 429, as declared in
+// SourceInfo.RateLimit. A stream-url command needs one request to the site
+// and one redirect per hoster.
+const (
+	rateLimit = 30
+	rateBurst = 5
+)
+
+// LimitRate throttles requests to AniWorld to rateLimit, sharing the budget
+// with every other invocation through a state file in the cache directory. The
+// video hosts are left unthrottled.
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("aniworld")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains()[:1], ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetTranslation selects sub, dub or en-sub
+func (s *Scraper) SetTranslation(translation string) error {
+	for _, valid := range TranslationTypes {
+		if translation == valid {
+			s.translation = translation
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid translation type %q (valid: %s)", translation, strings.Join(TranslationTypes, ", "))
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "AniWorld",
+			Package: "aniworld",
+			Lang:    "de",
+			Version: version,
+			Sources: []scraper.SourceInfo{source},
+		},
+		Permissions: permissions.Permissions{
+			// The site (base_url in the config file can point elsewhere), the video
+			// hosts, and the mirrors and file servers they serve streams from
+			Network: permissions.Domains(append([]string{"aniworld.to"}, hosters.NetworkDomains(s.sortedExtractors())...)...),
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/aniworld.json (read)",
+				"$PAIR_CACHE_DIR/extensions/aniworld/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/aniworld (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (scraper.SourceInfo, error) {
+	return scraper.SourceInfo{
+		ID:                   sourceID,
+		Name:                 "AniWorld",
+		BaseURL:              s.baseURL,
+		Language:             "de",
+		RateLimit:            rateLimit,
+		SupportsSearch:       true,
+		SupportsRelatedAnime: true,
+	}, nil
+}
+
+// getPage fetches a page of the site and parses it as HTML
+func (s *Scraper) getPage(ctx context.Context, path string, query url.Values) (*goquery.Document, error) {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := htmlx.Parse(resp.Body)
+	if err != nil {
+		return nil, exterr.New(exterr.Parse, "%w", err)
+	}
+	return doc, nil
+}
+
+// getJSON calls one of the site's AJAX endpoints and decodes its JSON response into v
+func (s *Scraper) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// get sends a GET request for path on the site, turning error statuses into errors
+func (s *Scraper) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	rawURL := s.baseURL + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", hosters.UserAgent)
+	req.Header.Set("Referer", s.baseURL+"/")
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return nil, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, exterr.New(exterr.NotFound, "%s not found", path)
+		}
+		return nil, exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	return resp, nil
+}
+
+// domains returns the hosts doctor checks: the site, followed by the video hosts
+func (s *Scraper) domains() []string {
+	host := "aniworld.to"
+	if u, err := url.Parse(s.baseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return append([]string{host}, s.hostDomains()...)
+}
+
+// hostDomains lists the domains of the video hosts streams are resolved from
+func (s *Scraper) hostDomains() []string {
+	var domains []string
+	for _, extractor := range s.sortedExtractors() {
+		domains = append(domains, extractor.Domains()...)
+	}
+	return domains
+}
+
+// sortedExtractors returns the extractors ordered by hoster name
+func (s *Scraper) sortedExtractors() []hosters.Extractor {
+	names := make([]string, 0, len(s.extractors))
+	for name := range s.extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	extractors := make([]hosters.Extractor, 0, len(names))
+	for _, name := range names {
+		extractors = append(extractors, s.extractors[name])
+	}
+	return extractors
+}
+
+func main() {
+	var (
+		query       = flag.String("query", "", "Search query")
+		page        = flag.Int("page", 1, "Page number")
+		animeURL    = flag.String("anime", "", "Anime URL or ID, optionally with the season, e.g. one-piece/staffel-2")
+		episode     = flag.Float64("episode", 0, "Episode number")
+		translation = flag.String("translation", "sub", "Language: sub (German subtitles), dub (German audio) or en-sub (English subtitles)")
+		server      = flag.String("server", "", "With stream-url: try this hoster first, e.g. Streamtape")
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:       "aniworld",
+		SourceID:      sourceID,
+		Version:       version,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Translation != "" && !cli.IsFlagSet("translation") {
+				*translation = cfg.Translation
+			}
+			if cfg.Server != "" && !cli.IsFlagSet("server") {
+				*server = cfg.Server
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetTranslation(*translation); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			s.server = strings.TrimSpace(*server)
+			return nil
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime on a source.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "details", Description: "Get the description, genres, studios and seasons of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "related", Description: "Get the other seasons and the films of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetRelatedAnime(ctx, *animeURL, *page)
+		}},
+		{Name: "episodes", Description: "Get the list of episodes for an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetEpisodeList(ctx, *animeURL)
+		}},
+		{Name: "stream-url", Description: "Get the hosters of an anime episode and the streams resolved from them.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			return s.GetVideoList(ctx, *animeURL, *episode)
+		}},
+	}
+	app.Main()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "naruto", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "naruto" {
+		t.Fatalf("SearchAnime results = %+v, want naruto first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 2", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, episodes[0].EpisodeNumber)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	servers := map[string]bool{}
+	for _, v := range videos.Streams {
+		servers[v.Server] = true
+	}
+	for _, want := range []string{"VOE", "Streamtape"} {
+		if !servers[want] {
+			t.Errorf("GetVideoList streams = %+v, missing server %s", videos.Streams, want)
+		}
+	}
+}
+
+func TestParseAnimeID(t *testing.T) {
+	tests := []struct {
+		animeID    string
+		wantSlug   string
+		wantSeason string
+		wantErr    bool
+	}{
+		{animeID: "naruto", wantSlug: "naruto"},
+		{animeID: "naruto/staffel-2", wantSlug: "naruto", wantSeason: "staffel-2"},
+		{animeID: "https://aniworld.to/anime/stream/naruto/staffel-2", wantSlug: "naruto", wantSeason: "staffel-2"},
+		{animeID: "/anime/stream/", wantErr: true},
+		{animeID: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		slug, season, err := parseAnimeID(tt.animeID)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAnimeID(%q) error = %v, wantErr %v", tt.animeID, err, tt.wantErr)
+			continue
+		}
+		if slug != tt.wantSlug || season != tt.wantSeason {
+			t.Errorf("parseAnimeID(%q) = %q, %q, want %q, %q", tt.animeID, slug, season, tt.wantSlug, tt.wantSeason)
+		}
+	}
+}
+
+func TestEpisodePath(t *testing.T) {
+	tests := []struct {
+		season string
+		number float64
+		want   string
+	}{
+		{"staffel-1", 3, "/anime/stream/naruto/staffel-1/episode-3"},
+		{"filme", 1, "/anime/stream/naruto/filme/film-1"},
+	}
+	for _, tt := range tests {
+		if got := episodePath("naruto", tt.season, tt.number); got != tt.want {
+			t.Errorf("episodePath(%q, %q, %v) = %q, want %q", "naruto", tt.season, tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestFirstSeason(t *testing.T) {
+	tests := []struct {
+		name    string
+		seasons []Season
+		want    string
+		wantOK  bool
+	}{
+		{"numbered season first", []Season{{ID: "naruto/filme"}, {ID: "naruto/staffel-1"}}, "naruto/staffel-1", true},
+		{"films only", []Season{{ID: "naruto/filme"}}, "naruto/filme", true},
+		{"no seasons", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := firstSeason(tt.seasons)
+			if got.ID != tt.want || ok != tt.wantOK {
+				t.Errorf("firstSeason() = %q, %v, want %q, %v", got.ID, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// animeIDFromHref extracts the anime ID, the slug, from a link to the page of
+// a show, such as /anime-list/sousou-no-frieren/. Full URLs and bare slugs are
+// accepted as well.
+func animeIDFromHref(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	href = strings.Trim(href, "/")
+	if _, rest, found := strings.Cut(href, "anime-list/"); found {
+		href = rest
+	}
+	id, _, _ := strings.Cut(href, "/")
+	return id
+}
+
+// listing fetches a page of posts, as search and the release list render them,
+// and returns the shows they link to
+func (s *Scraper) listing(ctx context.Context, path string, query url.Values, page int) ([]scraper.Anime, error) {
+	if page > 1 {
+		path += "page/" + strconv.Itoa(page) + "/"
+	}
+	doc, err := s.getPage(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	seen := map[string]bool{}
+	doc.Find("article").Each(func(_ int, post *goquery.Selection) {
+		// The heading links the show by title; thumbnails link it too, without text
+		link := post.Find("h2.entry-title a[href*='/anime-list/']").First()
+		id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		animes = append(animes, scraper.Anime{
+			ID:           id,
+			Title:        htmlx.Text(link),
+			ThumbnailURL: htmlx.AttrAny(post.Find("img").First(), "", "data-src", "src"),
+			Status:       scraper.StatusUnknown,
+		})
+	})
+	return animes, nil
+}
+
+// SearchAnime searches for anime by title
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	return s.listing(ctx, "/", url.Values{"s": {query}, "post_type": {"anime-list"}}, page)
+}
+
+// GetLatestUpdates retrieves the shows of the newest releases
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]scraper.Anime, error) {
+	animes, err := s.listing(ctx, "/episodes/", nil, page)
+	for i := range animes {
+		animes[i].Status = scraper.StatusOngoing
+	}
+	return animes, err
+}
+
+// animePage fetches the page of a show
+func (s *Scraper) animePage(ctx context.Context, animeID string) (*goquery.Document, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. sousou-no-frieren)", animeID)
+	}
+	doc, err := s.getPage(ctx, "/anime-list/"+id+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find("h1.entry-title").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	return doc, nil
+}
+
+// GetAnimeDetails retrieves the description and genres of an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (scraper.Anime, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return scraper.Anime{}, err
+	}
+
+	var genres []string
+	doc.Find("span.tags-links a").Each(func(_ int, link *goquery.Selection) {
+		genres = append(genres, htmlx.Text(link))
+	})
+	var titles []string
+	if alt := htmlx.TextFirst(doc.Selection, "h2.alternative-title"); alt != "" {
+		titles = append(titles, alt)
+	}
+	return scraper.Anime{
+		ID:                animeIDFromHref(animeID),
+		Title:             htmlx.TextFirst(doc.Selection, "h1.entry-title"),
+		Description:       htmlx.TextFirst(doc.Selection, "div.entry-content > p"),
+		Genre:             strings.Join(genres, ", "),
+		ThumbnailURL:      htmlx.Attr(doc.Find("meta[property='og:image']").First(), "content", ""),
+		Status:            scraper.StatusUnknown,
+		AlternativeTitles: titles,
+		SubDub:            "sub",
+	}, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Config holds user overrides read from the extension config file, letting
+// users follow Erai-raws to a new domain without waiting for a release. Flags
+// given on the command line win over the file.
+type Config struct {
+	cli.Config
+	Translation string `json:"translation,omitempty"`  // Default for -translation: sub
+	Resolution  string `json:"resolution,omitempty"`   // Default for -resolution, e.g. 720p
+	Subtitles   string `json:"subtitles,omitempty"`    // Default for -subtitles, e.g. pt-BR,es-419
+	TorrentAddr string `json:"torrent_addr,omitempty"` // Default for -torrent-addr, e.g. 127.0.0.1:8689
+
+	BaseURL       string `json:"base_url,omitempty"`       // Site URL, e.g. https://www.erai-raws.info
+	TorrentBinary string `json:"torrent_binary,omitempty"` // Path of the torrent sidecar when it is not on PATH
+}
+
+// LoadConfig reads the config file at path, or the default location when path is empty
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path == "" {
+		defaultPath, err := extconfig.Path("erairaws")
+		if err != nil {
+			return cfg, err
+		}
+		path = defaultPath
+	}
+
+	err := extconfig.Load(path, &cfg)
+	return cfg, err
+}
+
+// ApplyConfig overrides the scraper defaults with the non-empty values of cfg
+func (s *Scraper) ApplyConfig(cfg Config) {
+	if cfg.BaseURL != "" {
+		s.baseURL = strings.TrimRight(cfg.BaseURL, "/")
+	}
+	if cfg.TorrentBinary != "" {
+		s.streamer.Binary = cfg.TorrentBinary
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/torrentstream"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// feed is an RSS feed of releases. The site's feeds add their own elements
+// to each item, in the erai: namespace.
+type feed struct {
+	Items []feedItem `xml:"channel>item"`
+}
+
+// feedItem is a release in a feed
+type feedItem struct {
+	Title      string `xml:"title"` // e.g. "[1080p] Sousou no Frieren - 28"
+	Link       string `xml:"link"`  // Magnet link, as the feeds are requested with type=magnet
+	PubDate    string `xml:"pubDate"`
+	InfoHash   string `xml:"infohash"`
+	Size       string `xml:"size"`       // e.g. "1.4 GiB"
+	Resolution string `xml:"resolution"` // e.g. "1080p"
+	Subtitles  string `xml:"subtitles"`  // Flags of the subtitle tracks, e.g. "[us][br][mx]"
+}
+
+// releaseTitlePattern matches the title of a single episode's release and
+// captures its number and revision, e.g. "[1080p] Sousou no Frieren - 28v2 [HEVC]".
+// Batches such as "- 01 ~ 28" do not match.
+var releaseTitlePattern = regexp.MustCompile(`\s-\s(\d+(?:\.\d+)?)(?:v(\d+))?(?:\s*\[[^\]]*\])*\s*$`)
+
+// release is a single episode's release read from a feed
+type release struct {
+	number    float64
+	revision  int
+	item      feedItem
+	subtitles []string
+	uploaded  int64 // Unix time, 0 when the feed's date cannot be read
+}
+
+// Torrent is the torrent of a release, with the subtitle languages it ships
+type Torrent struct {
+	Title      string   `json:"title"`
+	MagnetLink string   `json:"magnetLink"`
+	InfoHash   string   `json:"infoHash,omitempty"`
+	Resolution string   `json:"resolution,omitempty"`
+	Size       string   `json:"size,omitempty"`
+	Subtitles  []string `json:"subtitles"` // BCP 47 languages of the subtitle tracks in the file, e.g. ["en", "pt-BR", "es-419"]
+}
+
+// torrent returns the torrent of a release, building its magnet link from the
+// info hash when the feed has none
+func (r release) torrent() Torrent {
+	magnet := r.item.Link
+	if !strings.HasPrefix(magnet, "magnet:") && r.item.InfoHash != "" {
+		magnet = "magnet:?xt=urn:btih:" + r.item.InfoHash + "&dn=" + url.QueryEscape(r.item.Title)
+	}
+	return Torrent{
+		Title:      strings.TrimSpace(r.item.Title),
+		MagnetLink: magnet,
+		InfoHash:   r.item.InfoHash,
+		Resolution: r.item.Resolution,
+		Size:       r.item.Size,
+		Subtitles:  r.subtitles,
+	}
+}
+
+// releases reads the releases of an anime in the selected resolution from its
+// feed, the latest revision of each episode, oldest episode first. Releases
+// without the subtitle languages -subtitles asks for are left out.
+func (s *Scraper) releases(ctx context.Context, animeID string) ([]release, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. sousou-no-frieren)", animeID)
+	}
+	var f feed
+	query := url.Values{"res": {s.resolution}, "type": {"magnet"}}
+	if err := s.getFeed(ctx, "/anime-list/"+id+"/feed/", query, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Items) == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q has no %s releases", animeID, s.resolution)
+	}
+
+	byNumber := map[float64]release{}
+	for _, item := range f.Items {
+		match := releaseTitlePattern.FindStringSubmatch(item.Title)
+		if match == nil {
+			continue
+		}
+		number, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			continue
+		}
+		revision, _ := strconv.Atoi(match[2])
+		r := release{number: number, revision: revision, item: item, subtitles: parseSubtitles(item.Subtitles)}
+		if uploaded, err := time.Parse(time.RFC1123Z, strings.TrimSpace(item.PubDate)); err == nil {
+			r.uploaded = uploaded.Unix()
+		}
+		if !hasSubtitles(r.subtitles, s.subtitles) {
+			continue
+		}
+		if existing, ok := byNumber[number]; !ok || r.revision > existing.revision {
+			byNumber[number] = r
+		}
+	}
+
+	releases := make([]release, 0, len(byNumber))
+	for _, r := range byNumber {
+		releases = append(releases, r)
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].number < releases[j].number })
+	return releases, nil
+}
+
+// release returns the release of an episode
+func (s *Scraper) release(ctx context.Context, animeID string, episodeNumber float64) (release, error) {
+	releases, err := s.releases(ctx, animeID)
+	if err != nil {
+		return release{}, err
+	}
+	for _, r := range releases {
+		if r.number == episodeNumber {
+			return r, nil
+		}
+	}
+	if len(s.subtitles) > 0 {
+		return release{}, exterr.New(exterr.NotFound, "episode %g has no %s release with %s subtitles", episodeNumber, s.resolution, strings.Join(s.subtitles, ", "))
+	}
+	return release{}, exterr.New(exterr.NotFound, "episode %g has no %s release", episodeNumber, s.resolution)
+}
+
+// Episode extends scraper.Episode with what the episode's release contains
+type Episode struct {
+	scraper.Episode
+	Resolution string   `json:"resolution,omitempty"` // e.g. "1080p"
+	Size       string   `json:"size,omitempty"`       // e.g. "1.4 GiB"
+	Subtitles  []string `json:"subtitles"`            // BCP 47 languages of the subtitle tracks, e.g. ["en", "pt-BR"]
+}
+
+// GetEpisodeList returns the released episodes of an anime with the subtitle
+// languages each release ships
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]Episode, error) {
+	releases, err := s.releases(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := []Episode{}
+	for _, r := range releases {
+		torrent := r.torrent()
+		episodes = append(episodes, Episode{
+			Episode: scraper.Episode{
+				ID:            torrent.MagnetLink,
+				Name:          "Episode " + strconv.FormatFloat(r.number, 'f', -1, 64),
+				DateUpload:    r.uploaded,
+				EpisodeNumber: r.number,
+				Scanlator:     "Erai-raws",
+			},
+			Resolution: torrent.Resolution,
+			Size:       torrent.Size,
+			Subtitles:  torrent.Subtitles,
+		})
+	}
+	return episodes, nil
+}
+
+// Warning reports a torrent that could not be turned into a stream, so
+// frontends can fall back to the magnet link instead of failing silently
+type Warning struct {
+	Source string `json:"source"` // Release title
+	Reason string `json:"reason"`
+}
+
+// VideoResponse lists the stream of an episode, served from its torrent by the
+// torrent sidecar, along with the torrent itself for players that stream
+// torrents on their own. The subtitles are tracks of the video file rather
+// than files of their own.
+type VideoResponse struct {
+	Streams  []scraper.Video `json:"streams"`
+	Torrents []Torrent       `json:"torrents"`
+	Warnings []Warning       `json:"warnings"`
+}
+
+// GetVideoList returns the stream of an episode in the selected resolution.
+// The sidecar is started when it is not running; without it, the torrent is
+// still listed with a warning.
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	r, err := s.release(ctx, animeID, episodeNumber)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	torrent := r.torrent()
+	response := VideoResponse{Streams: []scraper.Video{}, Torrents: []Torrent{torrent}, Warnings: []Warning{}}
+
+	// Releases hold a single episode, whose file the sidecar picks without a number
+	streamURL, err := torrentstream.Resolve(ctx, s.streamer, torrent.MagnetLink, 0)
+	if err != nil {
+		response.Warnings = append(response.Warnings, Warning{Source: torrent.Title, Reason: err.Error()})
+		return response, nil
+	}
+	response.Streams = append(response.Streams, scraper.Video{
+		ID:       animeIDFromHref(animeID),
+		Quality:  torrent.Resolution,
+		VideoURL: streamURL,
+	})
+	return response, nil
+}
+
+// GetMagnetLink returns the torrent of an episode in the selected resolution.
+// Its magnetLink field is what pair's magnet-link command expects.
+func (s *Scraper) GetMagnetLink(ctx context.Context, animeID string, episodeNumber float64) (Torrent, error) {
+	r, err := s.release(ctx, animeID, episodeNumber)
+	if err != nil {
+		return Torrent{}, err
+	}
+	return r.torrent(), nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair-extensions/pkg/torrentstream"
+	"github.com/wraient/pair/pkg/scraper"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// sourceID identifies the Erai-raws source
+const sourceID = "1438808252673531234"
+
+// defaultBaseURL is Erai-raws' site. base_url in the config file points the
+// extension at a mirror when the site moves.
+const defaultBaseURL = "https://www.erai-raws.info"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// TranslationTypes lists the values of -translation. Erai-raws releases
+// Japanese audio with subtitles in many languages in the same file.
+var TranslationTypes = []string{"sub"}
+
+// Resolutions lists the values of -resolution, the encodes Erai-raws publishes
+var Resolutions = []string{"1080p", "720p", "540p", "480p"}
+
+type Scraper struct {
+	baseURL     string
+	translation string   // Translation type used for streams
+	resolution  string   // Encode whose releases are listed, e.g. "1080p"
+	subtitles   []string // Languages releases must ship subtitles in, e.g. ["pt-BR"]; empty lists every release
+	client      *httpclient.Client
+	retry       httpclient.RetryPolicy
+	streamer    torrentstream.Options // Sidecar turning magnet links into local stream URLs
+}
+
+// NewScraper creates a new instance of the erairaws scraper
+func NewScraper() *Scraper {
+	return &Scraper{
+		baseURL:     defaultBaseURL,
+		translation: "sub",
+		resolution:  "1080p",
+		client:      httpclient.New(),
+		retry:       httpclient.DefaultRetryPolicy,
+	}
+}
+
+// Requests per minute Erai-raws tolerates before its Cloudflare front answers
+// 429, as declared in SourceInfo.RateLimit. Every command but latest needs
+// one request, a show's feed or page.
+const (
+	rateLimit = 30
+	rateBurst = 5
+)
+
+// LimitRate throttles requests to Erai-raws to rateLimit, sharing the budget
+// with every other invocation through a state file in the cache directory
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("erairaws")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains(), ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetTranslation selects the translation type; only sub exists
+func (s *Scraper) SetTranslation(translation string) error {
+	for _, valid := range TranslationTypes {
+		if translation == valid {
+			s.translation = translation
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid translation type %q (valid: %s)", translation, strings.Join(TranslationTypes, ", "))
+}
+
+// SetResolution selects the encode whose releases are listed
+func (s *Scraper) SetResolution(resolution string) error {
+	for _, valid := range Resolutions {
+		if strings.EqualFold(resolution, valid) {
+			s.resolution = valid
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid resolution %q (valid: %s)", resolution, strings.Join(Resolutions, ", "))
+}
+
+// SetSubtitles restricts releases to those shipping subtitles in every one of
+// languages, given as a comma-separated list such as "pt-BR,es-419"
+func (s *Scraper) SetSubtitles(languages string) error {
+	s.subtitles = nil
+	for _, lang := range strings.Split(languages, ",") {
+		lang = strings.TrimSpace(lang)
+		if lang == "" {
+			continue
+		}
+		canonical, ok := knownLanguage(lang)
+		if !ok {
+			return exterr.New(exterr.InvalidArgument, "unknown subtitle language %q (valid: %s)", lang, strings.Join(subtitleLanguages(), ", "))
+		}
+		s.subtitles = append(s.subtitles, canonical)
+	}
+	return nil
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Sources     []SourceInfo            `json:"sources"`
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// SourceInfo extends scraper.SourceInfo with the kind of stream URLs the source returns
+type SourceInfo struct {
+	scraper.SourceInfo
+	Type string `json:"type,omitempty"` // "local": streams are served by the torrent sidecar on this machine
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "Erai-raws",
+			Package: "erairaws",
+			Lang:    "all",
+			Version: version,
+		},
+		Sources: []SourceInfo{source},
+		Permissions: permissions.Permissions{
+			// The site (base_url in the config file can point elsewhere) and the
+			// torrent sidecar; the sidecar reaches the peers, not the extension
+			Network: []string{"www.erai-raws.info", s.streamerAddr()},
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/erairaws.json (read)",
+				"$PAIR_CACHE_DIR/extensions/erairaws/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/erairaws (write, doctor)",
+			},
+			// Started when stream-url finds it not running
+			Binaries: []string{torrentstream.Binary},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (SourceInfo, error) {
+	return SourceInfo{
+		SourceInfo: scraper.SourceInfo{
+			ID:             sourceID,
+			Name:           "Erai-raws",
+			BaseURL:        s.baseURL,
+			Language:       "all",
+			RateLimit:      rateLimit,
+			SupportsLatest: true,
+			SupportsSearch: true,
+		},
+		Type: "local",
+	}, nil
+}
+
+// streamerAddr returns the address of the torrent sidecar
+func (s *Scraper) streamerAddr() string {
+	if s.streamer.Addr == "" {
+		return torrentstream.DefaultAddr
+	}
+	return s.streamer.Addr
+}
+
+// getPage fetches a page of the site and parses it as HTML
+func (s *Scraper) getPage(ctx context.Context, path string, query url.Values) (*goquery.Document, error) {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := htmlx.Parse(resp.Body)
+	if err != nil {
+		return nil, exterr.New(exterr.Parse, "%w", err)
+	}
+	return doc, nil
+}
+
+// getFeed fetches one of the site's RSS feeds and decodes it into v
+func (s *Scraper) getFeed(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing feed %s: %w", path, err)
+	}
+	return nil
+}
+
+// get sends a GET request for path on the site, turning error statuses into errors
+func (s *Scraper) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	rawURL := s.baseURL + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", hosters.UserAgent)
+	req.Header.Set("Referer", s.baseURL+"/")
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return nil, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, exterr.New(exterr.NotFound, "%s not found", path)
+		}
+		return nil, exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	return resp, nil
+}
+
+// domains returns the hosts doctor checks: the site
+func (s *Scraper) domains() []string {
+	host := "www.erai-raws.info"
+	if u, err := url.Parse(s.baseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return []string{host}
+}
+
+func main() {
+	var (
+		query       = flag.String("query", "", "Search query")
+		page        = flag.Int("page", 1, "Page number")
+		animeURL    = flag.String("anime", "", "Anime URL or ID, e.g. sousou-no-frieren")
+		episode     = flag.Float64("episode", 0, "Episode number")
+		translation = flag.String("translation", "sub", "Translation type: sub (Japanese audio, multi-language subtitles)")
+		resolution  = flag.String("resolution", "1080p", "Encode to list releases of: "+strings.Join(Resolutions, ", "))
+		subtitles   = flag.String("subtitles", "", "Only list releases with subtitles in all of these languages, comma-separated, e.g. pt-BR,es-419")
+		torrentAddr = flag.String("torrent-addr", torrentstream.DefaultAddr, "With stream-url: address of the "+torrentstream.Binary+" sidecar serving torrents as streams")
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:       "erairaws",
+		SourceID:      sourceID,
+		Version:       version,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Translation != "" && !cli.IsFlagSet("translation") {
+				*translation = cfg.Translation
+			}
+			if cfg.Resolution != "" && !cli.IsFlagSet("resolution") {
+				*resolution = cfg.Resolution
+			}
+			if cfg.Subtitles != "" && !cli.IsFlagSet("subtitles") {
+				*subtitles = cfg.Subtitles
+			}
+			if cfg.TorrentAddr != "" && !cli.IsFlagSet("torrent-addr") {
+				*torrentAddr = cfg.TorrentAddr
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetTranslation(*translation); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			if err := s.SetResolution(*resolution); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			if err := s.SetSubtitles(*subtitles); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			s.streamer.Addr = *torrentAddr
+			return nil
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime on a source.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "latest", Description: "Get the anime of the newest releases.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetLatestUpdates(ctx, *page)
+		}},
+		{Name: "details", Description: "Get the description and genres of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "episodes", Description: "Get the released episodes of an anime with their subtitle languages.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetEpisodeList(ctx, *animeURL)
+		}},
+		{Name: "stream-url", Description: "Get the stream of an anime episode, served from its torrent by " + torrentstream.Binary + ".", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			return s.GetVideoList(ctx, *animeURL, *episode)
+		}},
+		{Name: "magnet-link", Description: "Get the magnet link of an anime episode's release.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			return s.GetMagnetLink(ctx, *animeURL, *episode)
+		}},
+	}
+	app.Main()
+}
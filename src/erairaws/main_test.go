@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "frieren", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "sousou-no-frieren" {
+		t.Fatalf("SearchAnime results = %+v, want sousou-no-frieren first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) == 0 {
+		t.Fatal("GetEpisodeList returned no episodes")
+	}
+	for _, episode := range episodes {
+		if len(episode.Subtitles) == 0 {
+			t.Errorf("GetEpisodeList episode %v has no subtitle languages", episode.EpisodeNumber)
+		}
+	}
+
+	torrent, err := s.GetMagnetLink(ctx, results[0].ID, episodes[0].EpisodeNumber)
+	if err != nil {
+		t.Fatalf("GetMagnetLink: %v", err)
+	}
+	if !strings.HasPrefix(torrent.MagnetLink, "magnet:") {
+		t.Errorf("GetMagnetLink magnet link = %q, want a magnet: URI", torrent.MagnetLink)
+	}
+}
+
+func TestSetSubtitles(t *testing.T) {
+	s := NewScraper()
+	if err := s.SetSubtitles(" en, PT-br ,"); err != nil {
+		t.Fatalf("SetSubtitles: %v", err)
+	}
+	if want := []string{"en", "pt-BR"}; !reflect.DeepEqual(s.subtitles, want) {
+		t.Errorf("SetSubtitles selected %q, want %q", s.subtitles, want)
+	}
+	if err := s.SetSubtitles("xx-YY"); err == nil {
+		t.Error("SetSubtitles with an unknown language succeeded, want an error")
+	}
+}
+
+func TestParseSubtitles(t *testing.T) {
+	tests := []struct {
+		flags string
+		want  []string
+	}{
+		{"[us][br][mx]", []string{"en", "pt-BR", "es-419"}},
+		{"[US][us]", []string{"en"}},
+		{"[zz]", []string{"zz"}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := parseSubtitles(tt.flags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSubtitles(%q) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestHasSubtitles(t *testing.T) {
+	languages := []string{"en", "pt-BR", "es-419"}
+	tests := []struct {
+		wanted []string
+		want   bool
+	}{
+		{nil, true},
+		{[]string{"pt-BR"}, true},
+		{[]string{"en", "es-419"}, true},
+		{[]string{"en", "fr"}, false},
+	}
+	for _, tt := range tests {
+		if got := hasSubtitles(languages, tt.wanted); got != tt.want {
+			t.Errorf("hasSubtitles(%q, %q) = %v, want %v", languages, tt.wanted, got, tt.want)
+		}
+	}
+}
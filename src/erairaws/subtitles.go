@@ -0,0 +1,112 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// flagLanguages maps the country flags Erai-raws marks subtitle tracks with
+// onto the languages of the tracks, as BCP 47 tags. The flag is the country
+// the translation is made for, so [mx] is Latin American Spanish and [es] the
+// Spanish of Spain.
+var flagLanguages = map[string]string{
+	"us": "en",
+	"br": "pt-BR",
+	"pt": "pt-PT",
+	"mx": "es-419",
+	"es": "es",
+	"sa": "ar",
+	"fr": "fr",
+	"de": "de",
+	"it": "it",
+	"ru": "ru",
+	"jp": "ja",
+	"pl": "pl",
+	"nl": "nl",
+	"no": "no",
+	"fi": "fi",
+	"tr": "tr",
+	"se": "sv",
+	"gr": "el",
+	"il": "he",
+	"ro": "ro",
+	"id": "id",
+	"th": "th",
+	"kr": "ko",
+	"dk": "da",
+	"cn": "zh-Hans",
+	"tw": "zh-Hant",
+	"bg": "bg",
+	"vn": "vi",
+	"in": "hi",
+	"ua": "uk",
+	"hu": "hu",
+	"cz": "cs",
+	"hr": "hr",
+	"my": "ms",
+	"sk": "sk",
+	"ph": "fil",
+}
+
+// subtitleFlagPattern finds the flags in a release's subtitle list, e.g.
+// "[us][br][mx][es][sa][fr][de][it][ru]"
+var subtitleFlagPattern = regexp.MustCompile(`\[([A-Za-z]{2})\]`)
+
+// parseSubtitles turns a release's subtitle flags into languages, in the order
+// the release lists them. Flags without a known language are kept as they are
+// so no track goes unreported.
+func parseSubtitles(flags string) []string {
+	languages := []string{}
+	seen := map[string]bool{}
+	for _, match := range subtitleFlagPattern.FindAllStringSubmatch(flags, -1) {
+		flag := strings.ToLower(match[1])
+		lang, ok := flagLanguages[flag]
+		if !ok {
+			lang = flag
+		}
+		if !seen[lang] {
+			seen[lang] = true
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// knownLanguage returns the canonical spelling of a subtitle language given
+// as a BCP 47 tag in any case, e.g. "PT-br" for pt-BR
+func knownLanguage(lang string) (string, bool) {
+	for _, known := range flagLanguages {
+		if strings.EqualFold(lang, known) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// subtitleLanguages lists the subtitle languages releases can ship, sorted
+func subtitleLanguages() []string {
+	languages := make([]string, 0, len(flagLanguages))
+	for _, lang := range flagLanguages {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// hasSubtitles reports whether a release ships subtitles in every one of wanted
+func hasSubtitles(languages, wanted []string) bool {
+	for _, want := range wanted {
+		found := false
+		for _, lang := range languages {
+			if lang == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
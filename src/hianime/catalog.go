@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// Anime extends scraper.Anime with the show's IDs on tracking sites, which only
+// the details command reads, so host apps can sync progress without matching titles
+type Anime struct {
+	scraper.Anime
+	AniListID int `json:"anilist_id,omitempty"`
+	MalID     int `json:"mal_id,omitempty"`
+}
+
+// AnimeDetails extends Anime with the metadata a detail screen needs
+type AnimeDetails struct {
+	Anime
+	Studios []string `json:"studios,omitempty"`
+	Score   float64  `json:"score,omitempty"`  // MyAnimeList score out of 10
+	Season  string   `json:"season,omitempty"` // Airing season, e.g. "Fall 1999"
+	Type    string   `json:"type,omitempty"`   // TV, Movie, OVA, ...
+	Rating  string   `json:"rating,omitempty"` // Age rating, e.g. "PG-13"
+}
+
+// animeIDPattern matches the anime ID in site links: the slug ending in the
+// numeric ID, e.g. one-piece-100 in /one-piece-100?ref=search or /watch/one-piece-100
+var animeIDPattern = regexp.MustCompile(`([a-z0-9-]+-\d+)(?:[/?#]|$)`)
+
+// yearPattern finds the year in airing dates such as "Oct 20, 1999 to ?"
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+// backgroundPattern extracts the image of a background-image style
+var backgroundPattern = regexp.MustCompile(`url\(([^)]+)\)`)
+
+// animeIDFromHref extracts the anime ID from a link to the show or its watch page
+func animeIDFromHref(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	href = strings.TrimPrefix(strings.TrimPrefix(href, "/watch"), "/")
+	if match := animeIDPattern.FindStringSubmatch(href); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// numericID returns the number the AJAX endpoints identify a show by, the last
+// part of the anime ID. Full show URLs are accepted as well.
+func numericID(animeID string) (string, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return "", exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece-100)", animeID)
+	}
+	return id[strings.LastIndex(id, "-")+1:], nil
+}
+
+// tickCount reads an episode count badge such as the sub or dub count of a card
+func tickCount(sel *goquery.Selection, class string) int {
+	count, _ := strconv.Atoi(htmlx.TextFirst(sel, ".tick-item."+class, "."+class))
+	return count
+}
+
+// subDub describes the translations with episodes out of the sub and dub counts
+func subDub(sub, dub int) string {
+	switch {
+	case sub > 0 && dub > 0:
+		return "both"
+	case dub > 0:
+		return "dub"
+	case sub > 0:
+		return "sub"
+	}
+	return ""
+}
+
+// episodeCount returns the episodes available in the selected translation;
+// raw episodes are listed with the subbed ones
+func (s *Scraper) episodeCount(sub, dub, total int) int {
+	switch {
+	case s.translation == "dub":
+		return dub
+	case sub > 0:
+		return sub
+	}
+	return total
+}
+
+// parseCard reads a show from a card of the search, catalog and sidebar lists
+func (s *Scraper) parseCard(card *goquery.Selection) (Anime, bool) {
+	link := htmlx.First(card, ".film-name a", ".film-detail a", "a.film-poster-ahref")
+	id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+	if id == "" {
+		return Anime{}, false
+	}
+
+	title := htmlx.Attr(link, "title", htmlx.Text(link))
+	anime := Anime{Anime: scraper.Anime{
+		ID:           id,
+		Title:        title,
+		ThumbnailURL: htmlx.AttrAny(htmlx.First(card, "img.film-poster-img", "img"), "", "data-src", "src"),
+		Status:       scraper.StatusUnknown,
+	}}
+	if jname := htmlx.Attr(link, "data-jname", ""); jname != "" && jname != title {
+		anime.AlternativeTitles = []string{jname}
+	}
+
+	sub, dub := tickCount(card, "tick-sub"), tickCount(card, "tick-dub")
+	anime.Episodes = s.episodeCount(sub, dub, tickCount(card, "tick-eps"))
+	anime.SubDub = subDub(sub, dub)
+	return anime, true
+}
+
+// listAnime fetches a page of shows: search results or one of the catalog pages
+func (s *Scraper) listAnime(ctx context.Context, path string, query url.Values) ([]Anime, error) {
+	doc, err := s.getPage(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []Anime{}
+	htmlx.Find(doc.Selection, ".film_list-wrap .flw-item", ".flw-item").Each(func(_ int, card *goquery.Selection) {
+		if anime, ok := s.parseCard(card); ok {
+			animes = append(animes, anime)
+		}
+	})
+	return animes, nil
+}
+
+// pageQuery returns the query selecting a page of a list
+func pageQuery(page int) url.Values {
+	query := url.Values{}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	return query
+}
+
+// SearchAnime searches for anime by title, narrowed down by filters. Dubbed
+// search only returns shows with dubbed episodes.
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int, filters SearchFilters) ([]Anime, error) {
+	values := pageQuery(page)
+	path := "/search"
+	if !filters.empty() {
+		// The search page ignores filters, the filter page takes a keyword too
+		path = "/filter"
+		filters.apply(values)
+	}
+	if query != "" {
+		values.Set("keyword", query)
+	}
+	if s.translation == "dub" {
+		values.Set("language", "2")
+	}
+	return s.listAnime(ctx, path, values)
+}
+
+// GetPopularAnime retrieves HiAnime's most popular shows
+func (s *Scraper) GetPopularAnime(ctx context.Context, page int) ([]Anime, error) {
+	return s.listAnime(ctx, "/most-popular", pageQuery(page))
+}
+
+// GetLatestUpdates retrieves the shows with the most recently released episodes
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]Anime, error) {
+	return s.listAnime(ctx, "/recently-updated", pageQuery(page))
+}
+
+// syncData is the JSON the show page embeds for the player and trackers
+type syncData struct {
+	AnimeID   string `json:"anime_id"`
+	MalID     string `json:"mal_id"`
+	AniListID string `json:"anilist_id"`
+}
+
+// animePage fetches the page of a show
+func (s *Scraper) animePage(ctx context.Context, animeID string) (*goquery.Document, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece-100)", animeID)
+	}
+	doc, err := s.getPage(ctx, "/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find(".anisc-detail").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	return doc, nil
+}
+
+// GetAnimeDetails retrieves description, genres, studios, score, season, airing
+// status and tracking IDs for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	detail := doc.Find(".anisc-detail")
+	name := detail.Find(".film-name").First()
+	sub, dub := tickCount(detail, "tick-sub"), tickCount(detail, "tick-dub")
+
+	anime := Anime{Anime: scraper.Anime{
+		ID:           animeIDFromHref(animeID),
+		Title:        htmlx.Text(name),
+		Description:  htmlx.TextFirst(detail, ".film-description .text", ".film-description"),
+		ThumbnailURL: htmlx.AttrAny(htmlx.First(doc.Selection, ".anisc-poster img", ".film-poster img"), "", "src", "data-src"),
+		Status:       scraper.StatusUnknown,
+		SubDub:       subDub(sub, dub),
+	}}
+	anime.Episodes = s.episodeCount(sub, dub, tickCount(detail, "tick-eps"))
+	if jname := htmlx.Attr(name, "data-jname", ""); jname != "" && jname != anime.Title {
+		anime.AlternativeTitles = append(anime.AlternativeTitles, jname)
+	}
+
+	var sync syncData
+	if err := json.Unmarshal([]byte(doc.Find("#syncData").Text()), &sync); err == nil {
+		anime.MalID, _ = strconv.Atoi(sync.MalID)
+		anime.AniListID, _ = strconv.Atoi(sync.AniListID)
+	}
+
+	details := AnimeDetails{
+		Anime:  anime,
+		Rating: htmlx.TextFirst(detail, ".tick-item.tick-pg", ".tick-pg"),
+		Type:   htmlx.TextFirst(detail, ".film-stats .item"),
+	}
+
+	// The info panel is a list of "Head: value" items
+	doc.Find(".anisc-info .item").Each(func(_ int, item *goquery.Selection) {
+		head := strings.TrimSuffix(htmlx.TextFirst(item, ".item-head"), ":")
+		value := htmlx.TextFirst(item, ".name", ".text")
+		var links []string
+		item.Find("a").Each(func(_ int, link *goquery.Selection) {
+			links = append(links, htmlx.Text(link))
+		})
+
+		switch head {
+		case "Japanese", "Synonyms":
+			for _, title := range strings.Split(value, ",") {
+				if title = strings.TrimSpace(title); title != "" && title != details.Title {
+					details.AlternativeTitles = append(details.AlternativeTitles, title)
+				}
+			}
+		case "Aired":
+			if match := yearPattern.FindString(value); match != "" {
+				details.ReleaseYear, _ = strconv.Atoi(match)
+			}
+		case "Premiered":
+			details.Season = value
+		case "Status":
+			details.Status = airingStatus(value)
+		case "MAL Score":
+			details.Score, _ = strconv.ParseFloat(value, 64)
+		case "Genres":
+			details.Genre = strings.Join(links, ", ")
+		case "Studios":
+			details.Studios = links
+			details.Artist = strings.Join(links, ", ")
+		case "Overview":
+			if details.Description == "" {
+				details.Description = value
+			}
+		}
+	})
+	return details, nil
+}
+
+// airingStatus maps HiAnime's airing statuses onto the scraper statuses
+func airingStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "currently airing":
+		return scraper.StatusOngoing
+	case "finished airing":
+		return scraper.StatusCompleted
+	}
+	return scraper.StatusUnknown
+}
+
+// RelatedAnime extends Anime with how the show relates to the requested one
+type RelatedAnime struct {
+	Anime
+	Relation string `json:"relation"` // season, related or recommended
+}
+
+// GetRelatedAnime retrieves the other seasons of an anime, the shows HiAnime
+// relates to it and its recommendations, all read from the show page
+func (s *Scraper) GetRelatedAnime(ctx context.Context, animeID string, page int) ([]RelatedAnime, error) {
+	related := []RelatedAnime{}
+	// Everything is on the show page
+	if page > 1 {
+		return related, nil
+	}
+
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+	self := animeIDFromHref(animeID)
+	seen := map[string]bool{self: true}
+	add := func(anime Anime, relation string) {
+		if !seen[anime.ID] {
+			seen[anime.ID] = true
+			related = append(related, RelatedAnime{Anime: anime, Relation: relation})
+		}
+	}
+
+	// Seasons are links with a poster background instead of cards
+	doc.Find(".os-list a.os-item").Each(func(_ int, link *goquery.Selection) {
+		id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+		if id == "" {
+			return
+		}
+		poster := htmlx.Attr(link.Find(".season-poster"), "style", "")
+		if match := backgroundPattern.FindStringSubmatch(poster); match != nil {
+			poster = strings.Trim(match[1], `'"`)
+		}
+		add(Anime{Anime: scraper.Anime{
+			ID:           id,
+			Title:        htmlx.Attr(link, "title", htmlx.TextFirst(link, ".title")),
+			ThumbnailURL: poster,
+			Status:       scraper.StatusUnknown,
+		}}, "season")
+	})
+
+	// The sidebar lists related shows; the recommendations follow the episode list
+	doc.Find("#main-sidebar .block_area").Each(func(_ int, block *goquery.Selection) {
+		if !strings.Contains(strings.ToLower(htmlx.TextFirst(block, ".cat-heading", "h2")), "related") {
+			return
+		}
+		block.Find("li").Each(func(_ int, card *goquery.Selection) {
+			if anime, ok := s.parseCard(card); ok {
+				add(anime, "related")
+			}
+		})
+	})
+	doc.Find("#main-content .block_area_category .flw-item").Each(func(_ int, card *goquery.Selection) {
+		if anime, ok := s.parseCard(card); ok {
+			add(anime, "recommended")
+		}
+	})
+	return related, nil
+}
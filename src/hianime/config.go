@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Config holds user overrides read from the extension config file, letting
+// users follow HiAnime to a new domain or a rotated MegaCloud key document
+// without waiting for a release. Flags given on the command line win over the file.
+type Config struct {
+	cli.Config
+	Translation string `json:"translation,omitempty"` // Default for -translation: sub, dub or raw
+	Server      string `json:"server,omitempty"`      // Default for -server, e.g. HD-2
+
+	BaseURL         string `json:"base_url,omitempty"`          // Site URL, e.g. https://hianime.to
+	MegaCloudKeyURL string `json:"megacloud_key_url,omitempty"` // JSON document with the MegaCloud decryption keys
+}
+
+// LoadConfig reads the config file at path, or the default location when path is empty
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path == "" {
+		defaultPath, err := extconfig.Path("hianime")
+		if err != nil {
+			return cfg, err
+		}
+		path = defaultPath
+	}
+
+	err := extconfig.Load(path, &cfg)
+	return cfg, err
+}
+
+// ApplyConfig overrides the scraper defaults with the non-empty values of cfg
+func (s *Scraper) ApplyConfig(cfg Config) {
+	if cfg.BaseURL != "" {
+		s.baseURL = strings.TrimRight(cfg.BaseURL, "/")
+	}
+	if cfg.MegaCloudKeyURL != "" {
+		s.megacloud.KeyURL = cfg.MegaCloudKeyURL
+	}
+}
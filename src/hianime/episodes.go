@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair-extensions/pkg/subs"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// Episode extends scraper.Episode with HiAnime's filler flag
+type Episode struct {
+	scraper.Episode
+	Filler bool `json:"filler"` // Anime-original episode outside the source material
+}
+
+// episodeItem is an entry of the AJAX episode list
+type episodeItem struct {
+	id     string // Episode ID the server list is requested with
+	number float64
+	title  string
+	filler bool
+}
+
+// listEpisodes fetches every episode of a show, in order
+func (s *Scraper) listEpisodes(ctx context.Context, animeID string) ([]episodeItem, error) {
+	id, err := numericID(animeID)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.getAJAX(ctx, "/ajax/v2/episode/list/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []episodeItem
+	doc.Find("a.ep-item").Each(func(_ int, link *goquery.Selection) {
+		number, err := strconv.ParseFloat(htmlx.Attr(link, "data-number", ""), 64)
+		episodeID := htmlx.Attr(link, "data-id", "")
+		if err != nil || episodeID == "" {
+			return
+		}
+		items = append(items, episodeItem{
+			id:     episodeID,
+			number: number,
+			title:  htmlx.Attr(link, "title", htmlx.TextFirst(link, ".ep-name")),
+			filler: link.HasClass("ssl-item-filler"),
+		})
+	})
+	if len(items) == 0 {
+		return nil, exterr.New(exterr.NotFound, "no episodes found for %q", animeID)
+	}
+	return items, nil
+}
+
+// GetEpisodeList returns the episodes of an anime. The site lists every
+// episode regardless of translation, so dubbed lists stop at the dub count
+// the show page reports.
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]Episode, error) {
+	items, err := s.listEpisodes(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.translation == "dub" {
+		doc, err := s.animePage(ctx, animeID)
+		if err != nil {
+			return nil, err
+		}
+		dubbed := tickCount(doc.Find(".anisc-detail"), "tick-dub")
+		items = items[:min(dubbed, len(items))]
+	}
+
+	id := animeIDFromHref(animeID)
+	episodes := []Episode{}
+	for _, item := range items {
+		episodes = append(episodes, Episode{
+			Episode: scraper.Episode{
+				ID:            id + "?ep=" + item.id,
+				Name:          item.title,
+				EpisodeNumber: item.number,
+			},
+			Filler: item.filler,
+		})
+	}
+	return episodes, nil
+}
+
+// Video extends scraper.Video with the HiAnime server serving the stream
+type Video struct {
+	scraper.Video
+	Server string `json:"server,omitempty"` // Server name as the site shows it, e.g. "HD-1"
+}
+
+// Subtitle extends scraper.Track with what a player needs to pick and load an external track
+type Subtitle struct {
+	scraper.Track
+	Label   string      `json:"label,omitempty"`   // Display name, e.g. "English"
+	Format  subs.Format `json:"format,omitempty"`  // vtt, srt or ass, guessed from the URL
+	Default bool        `json:"default,omitempty"` // Whether the player enables the track by default
+}
+
+// Warning reports a server whose streams could not be extracted, so frontends
+// can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // Server name, e.g. "HD-1"
+	Provider string `json:"provider,omitempty"` // Host of the player the server embeds
+	Reason   string `json:"reason"`
+}
+
+// VideoResponse mirrors scraper.VideoResponse using the extended types, with
+// the opening and ending times the player marks
+type VideoResponse struct {
+	Streams   []Video          `json:"streams"`
+	Subtitles []Subtitle       `json:"subtitles"`
+	Intro     *hosters.Segment `json:"intro,omitempty"`
+	Outro     *hosters.Segment `json:"outro,omitempty"`
+	Warnings  []Warning        `json:"warnings"`
+}
+
+// server is an entry of an episode's server list
+type server struct {
+	id          string // ID the embed link is requested with
+	name        string // e.g. "HD-1"
+	translation string // sub, dub or raw
+}
+
+// episodeServers lists the servers of an episode for the selected translation,
+// the preferred server first. Shows without subtitles only have raw servers,
+// which stand in for subbed ones.
+func (s *Scraper) episodeServers(ctx context.Context, episodeID string) ([]server, error) {
+	doc, err := s.getAJAX(ctx, "/ajax/v2/episode/servers", url.Values{"episodeId": {episodeID}})
+	if err != nil {
+		return nil, err
+	}
+
+	byType := map[string][]server{}
+	doc.Find(".server-item").Each(func(_ int, item *goquery.Selection) {
+		srv := server{
+			id:          htmlx.Attr(item, "data-id", ""),
+			name:        htmlx.Text(item),
+			translation: htmlx.Attr(item, "data-type", ""),
+		}
+		if srv.id != "" {
+			byType[srv.translation] = append(byType[srv.translation], srv)
+		}
+	})
+
+	servers := byType[s.translation]
+	if len(servers) == 0 && s.translation == "sub" {
+		servers = byType["raw"]
+	}
+	for i, srv := range servers {
+		if s.server != "" && strings.EqualFold(srv.name, s.server) {
+			servers = append(append([]server{srv}, servers[:i]...), servers[i+1:]...)
+			break
+		}
+	}
+	return servers, nil
+}
+
+// GetVideoList returns the streams of an episode from every server of the
+// selected translation, with the subtitle tracks of the first server that works
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	items, err := s.listEpisodes(ctx, animeID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	var episode *episodeItem
+	for i := range items {
+		if items[i].number == episodeNumber {
+			episode = &items[i]
+		}
+	}
+	if episode == nil {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g not found", episodeNumber)
+	}
+
+	servers, err := s.episodeServers(ctx, episode.id)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	if len(servers) == 0 {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g has no %s servers", episodeNumber, s.translation)
+	}
+
+	response := VideoResponse{Streams: []Video{}, Subtitles: []Subtitle{}, Warnings: []Warning{}}
+	var firstErr error
+	for _, srv := range servers {
+		result, provider, err := s.extractServer(ctx, srv, animeID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			response.Warnings = append(response.Warnings, Warning{Source: srv.name, Provider: provider, Reason: err.Error()})
+			continue
+		}
+
+		first := len(response.Streams) == 0
+		for _, stream := range result.Streams {
+			response.Streams = append(response.Streams, Video{
+				Video: scraper.Video{
+					ID:       animeID,
+					Quality:  stream.Quality,
+					VideoURL: stream.URL,
+					Headers:  stream.Headers,
+				},
+				Server: srv.name,
+			})
+		}
+		if first {
+			response.Subtitles = subtitles(result.Tracks)
+			response.Intro, response.Outro = result.Intro, result.Outro
+		}
+	}
+
+	if len(response.Streams) == 0 {
+		// Report the first server's failure with its classification, e.g. a rotated key
+		return VideoResponse{}, firstErr
+	}
+	if track := defaultTrack(response.Subtitles); track != nil {
+		for i := range response.Streams {
+			response.Streams[i].SubtitleTrack = track
+		}
+	}
+	return response, nil
+}
+
+// extractServer resolves the player a server embeds into streams, returning
+// the player's host along with them
+func (s *Scraper) extractServer(ctx context.Context, srv server, animeID string) (hosters.Result, string, error) {
+	var embed struct {
+		Type string `json:"type"`
+		Link string `json:"link"`
+	}
+	if err := s.getJSON(ctx, "/ajax/v2/episode/sources", url.Values{"id": {srv.id}}, &embed); err != nil {
+		return hosters.Result{}, "", err
+	}
+	if embed.Link == "" {
+		return hosters.Result{}, "", exterr.New(exterr.NotFound, "server %s has no player", srv.name)
+	}
+	provider := ""
+	if u, err := url.Parse(embed.Link); err == nil {
+		provider = u.Hostname()
+	}
+	if !hosters.IsMegaCloud(embed.Link) {
+		return hosters.Result{}, provider, exterr.New(exterr.Unsupported, "server %s uses an unsupported player: %s", srv.name, embed.Link)
+	}
+	result, err := s.megacloud.Extract(ctx, embed.Link, s.baseURL+"/watch/"+animeIDFromHref(animeID))
+	return result, provider, err
+}
+
+// subtitleLanguages maps the English language names in track labels, e.g.
+// "Portuguese - Português(Brasil)", to language codes
+var subtitleLanguages = map[string]string{
+	"arabic": "ar", "chinese": "zh", "english": "en", "french": "fr",
+	"german": "de", "hindi": "hi", "indonesian": "id", "italian": "it",
+	"japanese": "ja", "korean": "ko", "malay": "ms", "polish": "pl",
+	"portuguese": "pt", "russian": "ru", "spanish": "es", "thai": "th",
+	"turkish": "tr", "vietnamese": "vi",
+}
+
+// subtitles converts the player's tracks into subtitles
+func subtitles(tracks []hosters.Track) []Subtitle {
+	subtitles := []Subtitle{}
+	for _, track := range tracks {
+		name := strings.ToLower(strings.TrimSpace(strings.Split(track.Label, "-")[0]))
+		subtitles = append(subtitles, Subtitle{
+			Track:   scraper.Track{URL: track.URL, Lang: subtitleLanguages[name]},
+			Label:   track.Label,
+			Format:  subtitleFormat(track.URL),
+			Default: track.Default,
+		})
+	}
+	return subtitles
+}
+
+// defaultTrack returns the track the player enables by default, if any
+func defaultTrack(subtitles []Subtitle) *scraper.Track {
+	for _, subtitle := range subtitles {
+		if subtitle.Default {
+			track := subtitle.Track
+			return &track
+		}
+	}
+	return nil
+}
+
+// subtitleFormat guesses a subtitle format from the file extension in its URL
+func subtitleFormat(rawURL string) subs.Format {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".vtt":
+		return subs.FormatVTT
+	case ".srt":
+		return subs.FormatSRT
+	case ".ass", ".ssa":
+		return subs.FormatASS
+	}
+	return subs.FormatUnknown
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// SearchFilters is the JSON object accepted by --filters, e.g.
+//
+//	{"genres": ["Action", "Comedy"], "year": 2023, "season": "Fall", "type": "TV", "status": "ongoing", "sortBy": "score"}
+//
+// Every field is optional. status takes the scraper status values ongoing and
+// completed; sortBy is one of the IDs the genres command lists.
+type SearchFilters struct {
+	Genres []string `json:"genres,omitempty"`
+	Year   int      `json:"year,omitempty"`
+	Season string   `json:"season,omitempty"`
+	Type   string   `json:"type,omitempty"`
+	Status string   `json:"status,omitempty"`
+	SortBy string   `json:"sortBy,omitempty"`
+}
+
+// genreIDs maps the genres of HiAnime's filter page to the IDs it filters by
+var genreIDs = map[string]int{
+	"Action": 1, "Adventure": 2, "Cars": 3, "Comedy": 4, "Dementia": 5,
+	"Demons": 6, "Mystery": 7, "Drama": 8, "Ecchi": 9, "Fantasy": 10,
+	"Game": 11, "Historical": 13, "Horror": 14, "Kids": 15, "Magic": 16,
+	"Martial Arts": 17, "Mecha": 18, "Music": 19, "Parody": 20, "Samurai": 21,
+	"Romance": 22, "School": 23, "Sci-Fi": 24, "Shoujo": 25, "Shoujo Ai": 26,
+	"Shounen": 27, "Shounen Ai": 28, "Space": 29, "Sports": 30, "Super Power": 31,
+	"Vampire": 32, "Harem": 35, "Slice of Life": 36, "Supernatural": 37, "Military": 38,
+	"Police": 39, "Psychological": 40, "Thriller": 41, "Seinen": 42, "Josei": 43,
+	"Isekai": 44,
+}
+
+// typeIDs maps the show types to the IDs the filter page uses
+var typeIDs = map[string]int{"Movie": 1, "TV": 2, "OVA": 3, "ONA": 4, "Special": 5, "Music": 6}
+
+// ShowTypes lists the show types accepted by the type filter
+var ShowTypes = []string{"TV", "Movie", "OVA", "ONA", "Special", "Music"}
+
+// Seasons accepted by the season filter, in the order of their IDs
+var Seasons = []string{"Spring", "Summer", "Fall", "Winter"}
+
+// statusIDs maps the scraper statuses to the airing statuses the filter page uses
+var statusIDs = map[string]int{scraper.StatusCompleted: 1, scraper.StatusOngoing: 2}
+
+// sortBy lists the sort orders of the filter page
+var sortBy = []FilterOption{
+	{ID: "default", Name: "Default"},
+	{ID: "recently_added", Name: "Recently added"},
+	{ID: "recently_updated", Name: "Recently updated"},
+	{ID: "score", Name: "Score"},
+	{ID: "name_az", Name: "Name (A-Z)"},
+	{ID: "released_date", Name: "Release date"},
+	{ID: "most_watched", Name: "Most watched"},
+}
+
+// FilterOption is one value of a filter, with the ID to put in the filters JSON
+type FilterOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FilterOptions is the output of the genres command: the values each field of
+// the filters JSON accepts, for front-ends building filter pickers
+type FilterOptions struct {
+	Genres   []FilterOption `json:"genres"`
+	Types    []FilterOption `json:"types"`
+	Seasons  []FilterOption `json:"seasons"`
+	Statuses []FilterOption `json:"statuses"`
+	SortBy   []FilterOption `json:"sortBy"`
+}
+
+// GetFilterOptions lists the values the filters JSON accepts
+func GetFilterOptions() FilterOptions {
+	genres := make([]string, 0, len(genreIDs))
+	for genre := range genreIDs {
+		genres = append(genres, genre)
+	}
+	sort.Strings(genres)
+
+	return FilterOptions{
+		Genres:  namedOptions(genres),
+		Types:   namedOptions(ShowTypes),
+		Seasons: namedOptions(Seasons),
+		Statuses: []FilterOption{
+			{ID: scraper.StatusOngoing, Name: "Currently airing"},
+			{ID: scraper.StatusCompleted, Name: "Finished airing"},
+		},
+		SortBy: sortBy,
+	}
+}
+
+// namedOptions makes options whose ID is their name
+func namedOptions(names []string) []FilterOption {
+	options := make([]FilterOption, len(names))
+	for i, name := range names {
+		options[i] = FilterOption{ID: name, Name: name}
+	}
+	return options
+}
+
+// ParseSearchFilters parses and validates the --filters JSON object. An empty string means no filters.
+func ParseSearchFilters(filters string) (SearchFilters, error) {
+	var f SearchFilters
+	if strings.TrimSpace(filters) == "" {
+		return f, nil
+	}
+
+	if err := json.Unmarshal([]byte(filters), &f); err != nil {
+		return f, exterr.New(exterr.InvalidArgument, "invalid filters: %w", err)
+	}
+
+	for i, genre := range f.Genres {
+		name, ok := lookupFold(genre, genreIDs)
+		if !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid genre %q (see the genres command)", genre)
+		}
+		f.Genres[i] = name
+	}
+	if f.Type != "" {
+		name, ok := lookupFold(f.Type, typeIDs)
+		if !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid type %q (valid: %s)", f.Type, strings.Join(ShowTypes, ", "))
+		}
+		f.Type = name
+	}
+	if f.Season != "" {
+		season := ""
+		for _, valid := range Seasons {
+			if strings.EqualFold(f.Season, valid) {
+				season = valid
+			}
+		}
+		if season == "" {
+			return f, exterr.New(exterr.InvalidArgument, "invalid season %q (valid: %s)", f.Season, strings.Join(Seasons, ", "))
+		}
+		f.Season = season
+	}
+	if f.Status != "" {
+		f.Status = strings.ToLower(f.Status)
+		if _, ok := statusIDs[f.Status]; !ok {
+			return f, exterr.New(exterr.InvalidArgument, "invalid status %q (valid: %s, %s)", f.Status, scraper.StatusOngoing, scraper.StatusCompleted)
+		}
+	}
+	if f.SortBy != "" {
+		f.SortBy = strings.ToLower(f.SortBy)
+		valid := []string{}
+		for _, option := range sortBy {
+			valid = append(valid, option.ID)
+		}
+		if !slices.Contains(valid, f.SortBy) {
+			return f, exterr.New(exterr.InvalidArgument, "invalid sortBy %q (valid: %s)", f.SortBy, strings.Join(valid, ", "))
+		}
+	}
+	return f, nil
+}
+
+// empty reports whether no filter is set
+func (f SearchFilters) empty() bool {
+	return len(f.Genres) == 0 && f.Year == 0 && f.Season == "" && f.Type == "" && f.Status == "" && f.SortBy == ""
+}
+
+// apply adds the filters to the query of the filter page
+func (f SearchFilters) apply(query url.Values) {
+	if len(f.Genres) > 0 {
+		ids := make([]string, len(f.Genres))
+		for i, genre := range f.Genres {
+			ids[i] = strconv.Itoa(genreIDs[genre])
+		}
+		query.Set("genres", strings.Join(ids, ","))
+	}
+	if f.Year != 0 {
+		// Shows that aired within the year
+		query.Set("sy", strconv.Itoa(f.Year))
+		query.Set("ey", strconv.Itoa(f.Year))
+	}
+	for i, season := range Seasons {
+		if f.Season == season {
+			query.Set("season", strconv.Itoa(i+1))
+		}
+	}
+	if f.Type != "" {
+		query.Set("type", strconv.Itoa(typeIDs[f.Type]))
+	}
+	if f.Status != "" {
+		query.Set("status", strconv.Itoa(statusIDs[f.Status]))
+	}
+	if f.SortBy != "" {
+		query.Set("sort", f.SortBy)
+	}
+}
+
+// lookupFold finds name in ids ignoring case, returning the spelling used as key
+func lookupFold(name string, ids map[string]int) (string, bool) {
+	for key := range ids {
+		if strings.EqualFold(key, strings.TrimSpace(name)) {
+			return key, true
+		}
+	}
+	return "", false
+}
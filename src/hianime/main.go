@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair/pkg/scraper"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// sourceID identifies the HiAnime source
+const sourceID = "4844379015355361939"
+
+// defaultBaseURL is HiAnime's site. It moves between domains now and then;
+// base_url in the config file points the extension at the current one.
+const defaultBaseURL = "https://hianime.to"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// TranslationTypes lists the values of -translation, matching the server
+// groups HiAnime lists for each episode
+var TranslationTypes = []string{"sub", "dub", "raw"}
+
+type Scraper struct {
+	baseURL     string
+	translation string // Translation type used for search, episodes and streams
+	server      string // Preferred server name, e.g. "HD-2"; tried first when set
+	client      *httpclient.Client
+	retry       httpclient.RetryPolicy
+	megacloud   *hosters.MegaCloud
+}
+
+// NewScraper creates a new instance of the hianime scraper
+func NewScraper() *Scraper {
+	client := httpclient.New()
+	return &Scraper{
+		baseURL:     defaultBaseURL,
+		translation: "sub",
+		client:      client,
+		retry:       httpclient.DefaultRetryPolicy,
+		megacloud:   &hosters.MegaCloud{Client: client},
+	}
+}
+
+// Requests per minute HiAnime tolerates before answering 429, as declared in
+// SourceInfo.RateLimit. A stream-url command needs about ten requests.
+const (
+	rateLimit = 60
+	rateBurst = 10
+)
+
+// LimitRate throttles requests to HiAnime to rateLimit, sharing the budget with
+// every other invocation through a state file in the cache directory. The
+// MegaCloud player and stream hosts are left unthrottled.
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("hianime")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains()[:1], ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetTranslation selects sub, dub or raw
+func (s *Scraper) SetTranslation(translation string) error {
+	for _, valid := range TranslationTypes {
+		if translation == valid {
+			s.translation = translation
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid translation type %q (valid: %s)", translation, strings.Join(TranslationTypes, ", "))
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "HiAnime",
+			Package: "hianime",
+			Lang:    "en",
+			Version: version,
+			Sources: []scraper.SourceInfo{source},
+		},
+		Permissions: permissions.Permissions{
+			// The site (base_url in the config file can point elsewhere), the MegaCloud
+			// players, the host of the MegaCloud key document, and the CDNs serving
+			// the playlists and subtitles
+			Network: permissions.Domains(append([]string{"hianime.to", keyHost(s.megacloud.KeyURL)}, hosters.NetworkDomains([]hosters.Extractor{s.megacloud})...)...),
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/hianime.json (read)",
+				"$PAIR_CACHE_DIR/extensions/hianime/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/hianime (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// keyHost returns the host of the configured MegaCloud key document, or an
+// empty string for the default, which MegaCloudStreamDomains covers
+func keyHost(keyURL string) string {
+	u, err := url.Parse(keyURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (scraper.SourceInfo, error) {
+	return scraper.SourceInfo{
+		ID:                   sourceID,
+		Name:                 "HiAnime",
+		BaseURL:              s.baseURL,
+		Language:             "en",
+		RateLimit:            rateLimit,
+		SupportsLatest:       true,
+		SupportsSearch:       true,
+		SupportsRelatedAnime: true,
+	}, nil
+}
+
+// getPage fetches a page of the site and parses it as HTML
+func (s *Scraper) getPage(ctx context.Context, path string, query url.Values) (*goquery.Document, error) {
+	resp, err := s.get(ctx, path, query, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	doc, err := htmlx.Parse(resp.Body)
+	if err != nil {
+		return nil, exterr.New(exterr.Parse, "%w", err)
+	}
+	return doc, nil
+}
+
+// ajaxResponse wraps the HTML fragments the site's AJAX endpoints return
+type ajaxResponse struct {
+	Status bool   `json:"status"`
+	HTML   string `json:"html"`
+}
+
+// getAJAX calls one of the site's AJAX endpoints and parses the HTML fragment it returns
+func (s *Scraper) getAJAX(ctx context.Context, path string, query url.Values) (*goquery.Document, error) {
+	var response ajaxResponse
+	if err := s.getJSON(ctx, path, query, &response); err != nil {
+		return nil, err
+	}
+	doc, err := htmlx.ParseString(response.HTML)
+	if err != nil {
+		return nil, exterr.New(exterr.Parse, "%w", err)
+	}
+	return doc, nil
+}
+
+// getJSON calls one of the site's AJAX endpoints and decodes its JSON response into v
+func (s *Scraper) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := s.get(ctx, path, query, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// get sends a GET request for path on the site, turning error statuses into errors
+func (s *Scraper) get(ctx context.Context, path string, query url.Values, ajax bool) (*http.Response, error) {
+	rawURL := s.baseURL + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", hosters.UserAgent)
+	req.Header.Set("Referer", s.baseURL+"/")
+	if ajax {
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	}
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return nil, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, exterr.New(exterr.NotFound, "%s not found", path)
+		}
+		return nil, exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	return resp, nil
+}
+
+// domains returns the hosts doctor checks: the site, followed by the MegaCloud players
+func (s *Scraper) domains() []string {
+	host := "hianime.to"
+	if u, err := url.Parse(s.baseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return append([]string{host}, hosters.MegaCloudDomains...)
+}
+
+func main() {
+	var (
+		query       = flag.String("query", "", "Search query")
+		page        = flag.Int("page", 1, "Page number")
+		filters     = flag.String("filters", "", `JSON filters, e.g. {"genres":["Action"],"year":2023,"season":"Fall","type":"TV","status":"ongoing","sortBy":"score"}`)
+		animeURL    = flag.String("anime", "", "Anime URL")
+		episode     = flag.Float64("episode", 0, "Episode number")
+		translation = flag.String("translation", "sub", "Translation type: sub, dub or raw")
+		server      = flag.String("server", "", "With stream-url: try this server first, e.g. HD-1 or HD-2")
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:       "hianime",
+		SourceID:      sourceID,
+		Version:       version,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Translation != "" && !cli.IsFlagSet("translation") {
+				*translation = cfg.Translation
+			}
+			if cfg.Server != "" && !cli.IsFlagSet("server") {
+				*server = cfg.Server
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetTranslation(*translation); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			s.server = strings.TrimSpace(*server)
+			return nil
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime on a source.", Run: func(ctx context.Context) (interface{}, error) {
+			searchFilters, filterErr := ParseSearchFilters(*filters)
+			if filterErr != nil {
+				return nil, exterr.From(filterErr, exterr.InvalidArgument)
+			}
+			if *query == "" && searchFilters.empty() {
+				return nil, exterr.New(exterr.InvalidArgument, "search query or filters are required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page, searchFilters))
+		}},
+		{Name: "popular", Description: "Get the most popular anime.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetPopularAnime(ctx, *page)
+		}},
+		{Name: "latest", Description: "Get recently updated anime.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetLatestUpdates(ctx, *page)
+		}},
+		{Name: "genres", Description: "List the genres, types, seasons, statuses and sort orders -filters accepts.", Run: func(ctx context.Context) (interface{}, error) {
+			return GetFilterOptions(), nil
+		}},
+		{Name: "details", Description: "Get the description, genres, studios and tracking IDs of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "related", Description: "Get the seasons, movies and spin-offs related to an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetRelatedAnime(ctx, *animeURL, *page)
+		}},
+		{Name: "episodes", Description: "Get the list of episodes for an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetEpisodeList(ctx, *animeURL)
+		}},
+		{Name: "stream-url", Description: "Get the direct video stream URL for an anime episode.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			return s.GetVideoList(ctx, *animeURL, *episode)
+		}},
+	}
+	app.Main()
+}
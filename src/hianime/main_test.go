@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece", 1, SearchFilters{})
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "one-piece-100" {
+		t.Fatalf("SearchAnime results = %+v, want one-piece-100 first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 3", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, episodes[0].EpisodeNumber)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	servers := map[string]bool{}
+	for _, v := range videos.Streams {
+		servers[v.Server] = true
+	}
+	for _, want := range []string{"HD-1", "HD-2"} {
+		if !servers[want] {
+			t.Errorf("GetVideoList streams = %+v, missing server %s", videos.Streams, want)
+		}
+	}
+	if len(videos.Subtitles) == 0 {
+		t.Error("GetVideoList returned no subtitles")
+	}
+}
+
+func TestAnimeIDFromHref(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"/one-piece-100", "one-piece-100"},
+		{"/watch/one-piece-100?ep=2142", "one-piece-100"},
+		{"https://hianime.to/one-piece-100", "one-piece-100"},
+		{"one-piece-100/", "one-piece-100"},
+		{"/home", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := animeIDFromHref(tt.href); got != tt.want {
+			t.Errorf("animeIDFromHref(%q) = %q, want %q", tt.href, got, tt.want)
+		}
+	}
+}
+
+func TestNumericID(t *testing.T) {
+	if got, err := numericID("one-piece-100"); err != nil || got != "100" {
+		t.Errorf("numericID(%q) = %q, %v, want %q", "one-piece-100", got, err, "100")
+	}
+	if _, err := numericID("one-piece"); err == nil {
+		t.Errorf("numericID(%q) succeeded, want an error", "one-piece")
+	}
+}
+
+func TestSubDub(t *testing.T) {
+	tests := []struct {
+		sub, dub int
+		want     string
+	}{
+		{12, 10, "both"},
+		{0, 3, "dub"},
+		{5, 0, "sub"},
+		{0, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := subDub(tt.sub, tt.dub); got != tt.want {
+			t.Errorf("subDub(%d, %d) = %q, want %q", tt.sub, tt.dub, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearchFilters(t *testing.T) {
+	f, err := ParseSearchFilters(`{"genres":["action"],"type":"tv","season":"fall"}`)
+	if err != nil {
+		t.Fatalf("ParseSearchFilters: %v", err)
+	}
+	if len(f.Genres) != 1 || f.Genres[0] != "Action" || f.Type != "TV" || f.Season != "Fall" {
+		t.Errorf("ParseSearchFilters = %+v, want canonical names", f)
+	}
+
+	for _, filters := range []string{`{"genres":["Nope"]}`, `{"type":"Film"}`, `not json`} {
+		if _, err := ParseSearchFilters(filters); err == nil {
+			t.Errorf("ParseSearchFilters(%q) succeeded, want an error", filters)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the metadata a detail screen needs
+type AnimeDetails struct {
+	scraper.Anime
+	Type  string `json:"type,omitempty"`  // e.g. Serie, Pelicula or OVA
+	Aired string `json:"aired,omitempty"` // Airing dates as the site states them, e.g. "20 de Octubre de 1999 a ?"
+}
+
+// animeIDFromHref extracts the anime ID, the slug, from a link to the show or
+// one of its episodes, such as /one-piece/ or /one-piece/1100/. Full URLs and
+// bare slugs are accepted as well.
+func animeIDFromHref(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	id, _, _ := strings.Cut(strings.Trim(href, "/"), "/")
+	return id
+}
+
+// airingStatus maps Jkanime's airing statuses onto the scraper statuses
+func airingStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "en emision", "en emisión":
+		return scraper.StatusOngoing
+	case "concluido", "finalizado":
+		return scraper.StatusCompleted
+	}
+	return scraper.StatusUnknown
+}
+
+// SearchAnime searches for anime by title
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	doc, err := s.getPage(ctx, "/buscar/"+url.PathEscape(query)+"/"+strconv.Itoa(max(page, 1))+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	doc.Find("div.anime__item").Each(func(_ int, item *goquery.Selection) {
+		link := item.Find("div.anime__item__text h5 a").First()
+		id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+		if id == "" {
+			return
+		}
+		animes = append(animes, scraper.Anime{
+			ID:           id,
+			Title:        htmlx.Text(link),
+			ThumbnailURL: htmlx.Attr(item.Find("div.anime__item__pic").First(), "data-setbg", ""),
+			Status:       airingStatus(htmlx.TextFirst(item, "div.anime__item__text ul li:not(.anime)")),
+		})
+	})
+	return animes, nil
+}
+
+// GetLatestUpdates retrieves the shows of the newest episodes on the home page
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]scraper.Anime, error) {
+	if page > 1 {
+		// The home page is the only list of new episodes
+		return []scraper.Anime{}, nil
+	}
+	doc, err := s.getPage(ctx, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	seen := map[string]bool{}
+	doc.Find("div.listadoanime-home a.bloqq").Each(func(_ int, link *goquery.Selection) {
+		id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		animes = append(animes, scraper.Anime{
+			ID:           id,
+			Title:        htmlx.TextFirst(link, "h5"),
+			ThumbnailURL: htmlx.AttrAny(link.Find("img").First(), "", "data-src", "src"),
+			Status:       scraper.StatusOngoing,
+		})
+	})
+	return animes, nil
+}
+
+// animePage fetches the page of a show
+func (s *Scraper) animePage(ctx context.Context, animeID string) (*goquery.Document, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece)", animeID)
+	}
+	doc, err := s.getPage(ctx, "/"+id+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find("div.anime__details__title").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	return doc, nil
+}
+
+// GetAnimeDetails retrieves description, genres and airing status for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	var titles []string
+	if alt := htmlx.TextFirst(doc.Selection, "div.anime__details__title span"); alt != "" {
+		titles = append(titles, alt)
+	}
+	details := AnimeDetails{
+		Anime: scraper.Anime{
+			ID:                animeIDFromHref(animeID),
+			Title:             htmlx.TextFirst(doc.Selection, "div.anime__details__title h3"),
+			Description:       htmlx.TextFirst(doc.Selection, "p.tab.sinopsis", "div.anime__details__text p"),
+			ThumbnailURL:      htmlx.Attr(doc.Find("div.anime__details__pic").First(), "data-setbg", ""),
+			Status:            scraper.StatusUnknown,
+			AlternativeTitles: titles,
+		},
+	}
+	// Widget lines pair a label with a value, e.g. <span>Estado:</span> Concluido
+	doc.Find("div.anime__details__widget ul li").Each(func(_ int, item *goquery.Selection) {
+		label := strings.TrimSuffix(htmlx.TextFirst(item, "span"), ":")
+		value := strings.TrimSpace(strings.TrimPrefix(htmlx.Text(item), htmlx.TextFirst(item, "span")))
+		switch strings.ToLower(label) {
+		case "tipo":
+			details.Type = value
+		case "genero", "género":
+			var genres []string
+			item.Find("a").Each(func(_ int, link *goquery.Selection) {
+				genres = append(genres, htmlx.Text(link))
+			})
+			details.Genre = strings.Join(genres, ", ")
+		case "estado":
+			details.Status = airingStatus(value)
+		case "episodios":
+			details.Episodes, _ = strconv.Atoi(value)
+		case "emitido":
+			details.Aired = value
+		case "studios", "estudios":
+			details.Artist = value
+		}
+	})
+	if details.Episodes == 0 {
+		details.Episodes = episodeCount(doc)
+	}
+	return details, nil
+}
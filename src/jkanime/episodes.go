@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair-extensions/pkg/pagination"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// episodesPerPage is the size of the pages the site lists episodes in
+const episodesPerPage = 12
+
+// episodeCount reads the number of episodes from the episode list's page
+// links, which are labelled with their ranges, e.g. "1093 - 1100"; 0 when the
+// page has none
+func episodeCount(doc *goquery.Document) int {
+	last := htmlx.Text(doc.Find("div.anime__pagination a.numbers").Last())
+	_, end, _ := strings.Cut(last, "-")
+	count, _ := strconv.Atoi(strings.TrimSpace(end))
+	return count
+}
+
+// listedEpisode is an entry of /ajax/pagination_episodes
+type listedEpisode struct {
+	Number    json.Number `json:"number"` // Sent as a string or a number
+	Title     string      `json:"title"`
+	Timestamp string      `json:"timestamp"` // e.g. "2024-10-20 10:30:00"
+}
+
+// episodeLister fetches the pages of an anime's episode list
+type episodeLister struct {
+	s     *Scraper
+	slug  string
+	id    string // Numeric ID the site's AJAX endpoints take
+	total int    // Number of episodes, 0 when the anime page does not say
+}
+
+// newEpisodeLister reads what listing an anime's episodes takes from its page
+func (s *Scraper) newEpisodeLister(ctx context.Context, animeID string) (*episodeLister, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+	id := htmlx.AttrAny(doc.Find("#guardar-anime").First(), "", "data-anime")
+	if id == "" {
+		return nil, exterr.New(exterr.Parse, "no anime ID on the page of %s", animeID)
+	}
+	return &episodeLister{s: s, slug: animeIDFromHref(animeID), id: id, total: episodeCount(doc)}, nil
+}
+
+// page fetches a page of the episode list, oldest first
+func (l *episodeLister) page(ctx context.Context, page int) ([]scraper.Episode, error) {
+	var listed []listedEpisode
+	if err := l.s.getJSON(ctx, "/ajax/pagination_episodes/"+l.id+"/"+strconv.Itoa(page)+"/", nil, &listed); err != nil {
+		return nil, err
+	}
+
+	episodes := []scraper.Episode{}
+	for _, item := range listed {
+		number, err := item.Number.Float64()
+		if err != nil {
+			continue
+		}
+		formatted := strconv.FormatFloat(number, 'f', -1, 64)
+		name := item.Title
+		if name == "" {
+			name = "Episodio " + formatted
+		}
+		var uploaded int64
+		if t, err := time.Parse(time.DateTime, item.Timestamp); err == nil {
+			uploaded = t.Unix()
+		}
+		episodes = append(episodes, scraper.Episode{
+			ID:            l.s.baseURL + "/" + l.slug + "/" + formatted + "/",
+			Name:          name,
+			DateUpload:    uploaded,
+			EpisodeNumber: number,
+		})
+	}
+	return episodes, nil
+}
+
+// pages fetches the pages of the episode list from first to last, stopping
+// early at an empty page
+func (l *episodeLister) pages(ctx context.Context, first, last int) ([]scraper.Episode, error) {
+	episodes := []scraper.Episode{}
+	for page := first; page <= last; page++ {
+		items, err := l.page(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			break
+		}
+		episodes = append(episodes, items...)
+	}
+	return episodes, nil
+}
+
+// maxEpisodePages bounds the pages fetched for an anime whose page does not
+// state its episode count; the list then ends at the first empty page
+const maxEpisodePages = 500
+
+// GetEpisodeList returns the episodes of an anime, fetching every page of the
+// site's episode list
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	lister, err := s.newEpisodeLister(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+	last := maxEpisodePages
+	if lister.total > 0 {
+		last = (lister.total + episodesPerPage - 1) / episodesPerPage
+	}
+	return lister.pages(ctx, 1, last)
+}
+
+// EpisodePage is one page of an anime's episodes, oldest first
+type EpisodePage struct {
+	Episodes []scraper.Episode `json:"episodes"`
+	pagination.Info
+}
+
+// GetEpisodePage retrieves up to limit of the episodes of an anime starting at
+// offset. Only the pages of the site's episode list the range falls on are
+// fetched, which keeps long shows cheap to page through.
+func (s *Scraper) GetEpisodePage(ctx context.Context, animeID string, offset, limit int) (EpisodePage, error) {
+	lister, err := s.newEpisodeLister(ctx, animeID)
+	if err != nil {
+		return EpisodePage{}, err
+	}
+	if lister.total == 0 {
+		// Without a count the whole list is needed to know where it ends
+		episodes, err := lister.pages(ctx, 1, maxEpisodePages)
+		if err != nil {
+			return EpisodePage{}, err
+		}
+		items, info, err := pagination.SliceOffset(episodes, offset, limit)
+		if err != nil {
+			return EpisodePage{}, exterr.From(err, exterr.InvalidArgument)
+		}
+		return EpisodePage{Episodes: items, Info: info}, nil
+	}
+
+	if offset < 0 || limit < 1 {
+		return EpisodePage{}, exterr.New(exterr.InvalidArgument, "invalid offset %d or limit %d", offset, limit)
+	}
+	info := pagination.Info{
+		Page:        offset/limit + 1,
+		Offset:      offset,
+		Limit:       limit,
+		Total:       lister.total,
+		HasNextPage: offset+limit < lister.total,
+	}
+	if offset >= lister.total {
+		return EpisodePage{Episodes: []scraper.Episode{}, Info: info}, nil
+	}
+
+	first := offset/episodesPerPage + 1
+	last := (min(offset+limit, lister.total)-1)/episodesPerPage + 1
+	episodes, err := lister.pages(ctx, first, last)
+	if err != nil {
+		return EpisodePage{}, err
+	}
+	start := min(offset-(first-1)*episodesPerPage, len(episodes))
+	end := min(start+limit, len(episodes))
+	return EpisodePage{Episodes: episodes[start:end], Info: info}, nil
+}
+
+// Video extends scraper.Video with the server the stream was resolved from
+type Video struct {
+	scraper.Video
+	Server string `json:"server,omitempty"` // Server name as the site shows it, e.g. "Desu"
+}
+
+// Server is a player an episode is embedded from
+type Server struct {
+	Name      string `json:"name"`               // e.g. "Desu" or "Streamwish"
+	EmbedURL  string `json:"embedUrl,omitempty"` // Player page
+	Supported bool   `json:"supported"`          // Whether the extension resolves the server into streams
+}
+
+// Warning reports a supported server whose streams could not be extracted, so
+// frontends can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // Server name, e.g. "Streamwish"
+	Provider string `json:"provider,omitempty"` // Host of the player
+	Reason   string `json:"reason"`
+}
+
+// VideoResponse lists the streams resolved from an episode's servers along
+// with every server the episode page offers, including those the extension
+// cannot resolve
+type VideoResponse struct {
+	Streams   []Video         `json:"streams"`
+	Subtitles []scraper.Track `json:"subtitles"`
+	Servers   []Server        `json:"servers"`
+	Warnings  []Warning       `json:"warnings"`
+}
+
+var (
+	// sitePlayerPattern finds the site's own players in the episode page's
+	// script: video[1] = '<iframe class="player_conte" src="https://jkanime.net/jkplayer/um?e=..."
+	sitePlayerPattern = regexp.MustCompile(`video\[\d+\]\s*=\s*'<iframe[^>]+src="([^"]+)"`)
+	// remoteServersPattern finds the third-party servers in the same script:
+	// var servers = [{"remote":"<base64 embed URL>","server":"Streamwish","lang":1}, ...];
+	remoteServersPattern = regexp.MustCompile(`var servers\s*=\s*(\[.*?\]);`)
+	// desuPlaylistPattern finds the playlist of the Desu player: url: 'https://.../master.m3u8'
+	desuPlaylistPattern = regexp.MustCompile(`url\s*:\s*'(https?://[^']+\.m3u8[^']*)'`)
+)
+
+// sitePlayers names the site's own players by the last element of their path
+var sitePlayers = map[string]string{"um": "Desu"}
+
+// remoteServer is an entry of the episode page's server list
+type remoteServer struct {
+	Remote string `json:"remote"` // Base64 of the embed URL
+	Server string `json:"server"`
+}
+
+// pageServers reads the servers of an episode page: the site's players, then
+// the third-party ones, the preferred server first
+func (s *Scraper) pageServers(page []byte) []Server {
+	servers := []Server{}
+	for _, match := range sitePlayerPattern.FindAllSubmatch(page, -1) {
+		embedURL := string(match[1])
+		u, err := url.Parse(embedURL)
+		if err != nil {
+			continue
+		}
+		name, ok := sitePlayers[u.Path[strings.LastIndex(u.Path, "/")+1:]]
+		if !ok {
+			name = "Jkanime " + u.Path
+		}
+		servers = append(servers, Server{Name: name, EmbedURL: embedURL, Supported: ok})
+	}
+
+	if match := remoteServersPattern.FindSubmatch(page); match != nil {
+		var remotes []remoteServer
+		if err := json.Unmarshal(match[1], &remotes); err == nil {
+			for _, remote := range remotes {
+				decoded, err := base64.StdEncoding.DecodeString(remote.Remote)
+				if err != nil {
+					continue
+				}
+				embedURL := string(decoded)
+				servers = append(servers, Server{
+					Name:      remote.Server,
+					EmbedURL:  embedURL,
+					Supported: hosters.For(s.extractors, embedURL) != nil,
+				})
+			}
+		}
+	}
+
+	preferred := func(server Server) bool { return s.server != "" && strings.EqualFold(server.Name, s.server) }
+	sort.SliceStable(servers, func(i, j int) bool { return preferred(servers[i]) && !preferred(servers[j]) })
+	return servers
+}
+
+// GetVideoList returns every server of an episode with the streams of the
+// servers the extension resolves: the site's Desu player and the third-party
+// hosts a video host extractor handles. The preferred server is tried first.
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	slug := animeIDFromHref(animeID)
+	if slug == "" {
+		return VideoResponse{}, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece)", animeID)
+	}
+	path := "/" + slug + "/" + strconv.FormatFloat(episodeNumber, 'f', -1, 64) + "/"
+	page, err := s.getBody(ctx, path)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	referer := s.baseURL + path
+
+	response := VideoResponse{Streams: []Video{}, Subtitles: []scraper.Track{}, Servers: s.pageServers(page), Warnings: []Warning{}}
+	if len(response.Servers) == 0 {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g has no servers", episodeNumber)
+	}
+	for _, server := range response.Servers {
+		if !server.Supported {
+			continue
+		}
+		var result hosters.Result
+		var provider string
+		if extractor := hosters.For(s.extractors, server.EmbedURL); extractor != nil {
+			provider = extractor.Domains()[0]
+			result, err = extractor.Extract(ctx, server.EmbedURL, referer)
+		} else {
+			provider = "jkanime.net"
+			result, err = s.desuStreams(ctx, server.EmbedURL, referer)
+		}
+		if err != nil {
+			response.Warnings = append(response.Warnings, Warning{Source: server.Name, Provider: provider, Reason: err.Error()})
+			continue
+		}
+		for _, stream := range result.Streams {
+			response.Streams = append(response.Streams, Video{
+				Video: scraper.Video{
+					ID:       slug,
+					Quality:  stream.Quality,
+					VideoURL: stream.URL,
+					Headers:  stream.Headers,
+				},
+				Server: server.Name,
+			})
+		}
+		for _, track := range result.Tracks {
+			response.Subtitles = append(response.Subtitles, scraper.Track{URL: track.URL, Lang: track.Label})
+		}
+	}
+	return response, nil
+}
+
+// desuStreams resolves the site's Desu player into its HLS playlist
+func (s *Scraper) desuStreams(ctx context.Context, embedURL, referer string) (hosters.Result, error) {
+	u, err := url.Parse(embedURL)
+	if err != nil {
+		return hosters.Result{}, exterr.New(exterr.Parse, "invalid player link %q: %w", embedURL, err)
+	}
+	page, err := s.getBody(ctx, u.RequestURI())
+	if err != nil {
+		return hosters.Result{}, err
+	}
+
+	match := desuPlaylistPattern.FindSubmatch(page)
+	if match == nil {
+		return hosters.Result{}, exterr.New(exterr.Parse, "no playlist on the Desu player %s", embedURL)
+	}
+	headers := map[string]string{"User-Agent": s.client.UserAgent(hosters.UserAgent), "Referer": referer}
+	return hosters.Result{Streams: []hosters.Stream{{URL: string(match[1]), Quality: "auto", HLS: true, Headers: headers}}}, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "one-piece" {
+		t.Fatalf("SearchAnime results = %+v, want one-piece first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 27 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 27", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, 1)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	servers := map[string]bool{}
+	for _, v := range videos.Streams {
+		servers[v.Server] = true
+	}
+	for _, want := range []string{"Desu", "Streamwish", "Mp4upload"} {
+		if !servers[want] {
+			t.Errorf("GetVideoList streams = %+v, missing server %s", videos.Streams, want)
+		}
+	}
+}
+
+func TestGetEpisodePage(t *testing.T) {
+	s := newMockScraper(t)
+	tests := []struct {
+		name      string
+		offset    int
+		limit     int
+		wantFirst float64
+		wantLen   int
+		wantNext  bool
+	}{
+		{"first page", 0, 5, 1, 5, true},
+		{"across site pages", 10, 5, 11, 5, true},
+		{"last episodes", 25, 5, 26, 2, false},
+		{"past the end", 30, 5, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := s.GetEpisodePage(context.Background(), "one-piece", tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("GetEpisodePage: %v", err)
+			}
+			if len(page.Episodes) != tt.wantLen || page.HasNextPage != tt.wantNext || page.Total != 27 {
+				t.Fatalf("GetEpisodePage(%d, %d) = %d episodes, next %v, total %d, want %d, %v, 27",
+					tt.offset, tt.limit, len(page.Episodes), page.HasNextPage, page.Total, tt.wantLen, tt.wantNext)
+			}
+			if tt.wantLen > 0 && page.Episodes[0].EpisodeNumber != tt.wantFirst {
+				t.Errorf("GetEpisodePage(%d, %d) starts at episode %v, want %v",
+					tt.offset, tt.limit, page.Episodes[0].EpisodeNumber, tt.wantFirst)
+			}
+		})
+	}
+
+	if _, err := s.GetEpisodePage(context.Background(), "one-piece", -1, 5); err == nil {
+		t.Error("GetEpisodePage with a negative offset succeeded, want an error")
+	}
+}
+
+func TestAnimeIDFromHref(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"/one-piece/", "one-piece"},
+		{"/one-piece/1100/", "one-piece"},
+		{"https://jkanime.net/one-piece/", "one-piece"},
+		{"one-piece", "one-piece"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := animeIDFromHref(tt.href); got != tt.want {
+			t.Errorf("animeIDFromHref(%q) = %q, want %q", tt.href, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// perPage is the number of anime per page of search, the most Kitsu allows
+const perPage = 20
+
+// detailsInclude are the related resources details reads alongside the anime
+const detailsInclude = "categories,mappings"
+
+// titles are the titles of an anime or episode by language; Kitsu keys
+// romanized Japanese as en_jp
+type titles struct {
+	En   string `json:"en"`
+	EnUS string `json:"en_us"`
+	EnJP string `json:"en_jp"`
+	JaJP string `json:"ja_jp"`
+}
+
+// image is a poster, cover or thumbnail in the sizes Kitsu serves
+type image struct {
+	Original string `json:"original"`
+	Large    string `json:"large"`
+}
+
+// attributes are the attributes of an anime resource
+type attributes struct {
+	Slug              string   `json:"slug"`
+	CanonicalTitle    string   `json:"canonicalTitle"`
+	Titles            titles   `json:"titles"`
+	AbbreviatedTitles []string `json:"abbreviatedTitles"`
+	Synopsis          string   `json:"synopsis"`
+	AverageRating     string   `json:"averageRating"` // Percentage as a decimal string, e.g. "90.42"
+	StartDate         string   `json:"startDate"`     // YYYY-MM-DD
+	Status            string   `json:"status"`        // current, finished, tba, unreleased, upcoming
+	Subtype           string   `json:"subtype"`       // TV, movie, OVA, ONA, special, music
+	EpisodeCount      int      `json:"episodeCount"`
+	EpisodeLength     int      `json:"episodeLength"` // Minutes
+	AgeRating         string   `json:"ageRating"`     // G, PG, R, R18
+	PosterImage       *image   `json:"posterImage"`
+	CoverImage        *image   `json:"coverImage"`
+}
+
+// Anime extends scraper.Anime with the IDs other sources and trackers use
+// and the details Kitsu has on top of the common fields
+type Anime struct {
+	scraper.Anime
+	KitsuID   int    `json:"kitsu_id"`
+	Slug      string `json:"slug"`                 // URL name, e.g. "sousou-no-frieren"
+	MalID     int    `json:"mal_id,omitempty"`     // Details only
+	AniListID int    `json:"anilist_id,omitempty"` // Details only
+	Format    string `json:"format,omitempty"`     // e.g. "TV", "movie", "ONA"
+	Score     int    `json:"score,omitempty"`      // Average rating out of 100
+	Duration  int    `json:"duration,omitempty"`   // Minutes per episode
+	AgeRating string `json:"age_rating,omitempty"` // e.g. "PG", "R"
+	BannerURL string `json:"banner_url,omitempty"` // Wide cover image
+}
+
+// titleOf returns the title in the selected language, falling back to the canonical title
+func (s *Scraper) titleOf(canonical string, t titles) string {
+	var title string
+	switch s.title {
+	case "english":
+		title = t.En
+		if title == "" {
+			title = t.EnUS
+		}
+	case "romaji":
+		title = t.EnJP
+	case "native":
+		title = t.JaJP
+	}
+	if title == "" {
+		return canonical
+	}
+	return title
+}
+
+// toAnime converts an anime resource, titled in the selected language
+func (s *Scraper) toAnime(r resource) (Anime, error) {
+	var attrs attributes
+	if err := decodeAttributes(r, &attrs); err != nil {
+		return Anime{}, err
+	}
+	id, _ := strconv.Atoi(r.ID)
+	title := s.titleOf(attrs.CanonicalTitle, attrs.Titles)
+
+	var alternatives []string
+	candidates := []string{attrs.CanonicalTitle, attrs.Titles.En, attrs.Titles.EnUS, attrs.Titles.EnJP, attrs.Titles.JaJP}
+	for _, alt := range append(candidates, attrs.AbbreviatedTitles...) {
+		if alt != "" && alt != title && !containsTitle(alternatives, alt) {
+			alternatives = append(alternatives, alt)
+		}
+	}
+
+	anime := Anime{
+		Anime: scraper.Anime{
+			ID:                r.ID,
+			Title:             title,
+			Description:       strings.TrimSpace(attrs.Synopsis),
+			Status:            airingStatus(attrs.Status),
+			AlternativeTitles: alternatives,
+			Episodes:          attrs.EpisodeCount,
+			ReleaseYear:       releaseYear(attrs.StartDate),
+		},
+		KitsuID:   id,
+		Slug:      attrs.Slug,
+		Format:    attrs.Subtype,
+		Duration:  attrs.EpisodeLength,
+		AgeRating: attrs.AgeRating,
+	}
+	if poster := attrs.PosterImage; poster != nil {
+		anime.ThumbnailURL = poster.Original
+		if anime.ThumbnailURL == "" {
+			anime.ThumbnailURL = poster.Large
+		}
+	}
+	if cover := attrs.CoverImage; cover != nil {
+		anime.BannerURL = cover.Original
+	}
+	if rating, err := strconv.ParseFloat(attrs.AverageRating, 64); err == nil {
+		anime.Score = int(math.Round(rating))
+	}
+	return anime, nil
+}
+
+// containsTitle reports whether titles already holds title, ignoring case
+func containsTitle(titles []string, title string) bool {
+	for _, existing := range titles {
+		if strings.EqualFold(existing, title) {
+			return true
+		}
+	}
+	return false
+}
+
+// airingStatus maps Kitsu's status onto the scraper status constants
+func airingStatus(status string) string {
+	switch status {
+	case "current":
+		return scraper.StatusOngoing
+	case "finished":
+		return scraper.StatusCompleted
+	}
+	return scraper.StatusUnknown
+}
+
+// releaseYear returns the year of a YYYY-MM-DD date, or 0 when it is missing
+func releaseYear(date string) int {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return 0
+	}
+	return t.Year()
+}
+
+// slugPattern matches Kitsu's URL names, e.g. sousou-no-frieren
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// animeRef extracts the Kitsu ID or slug from an anime ID, either of them
+// alone or a URL such as https://kitsu.app/anime/sousou-no-frieren. Exactly
+// one of the results is set.
+func animeRef(animeID string) (id int, slug string, err error) {
+	path := animeID
+	if u, err := url.Parse(animeID); err == nil {
+		path = u.Path
+	}
+	path = strings.Trim(path, "/")
+	if _, rest, found := strings.Cut(path, "anime/"); found {
+		path, _, _ = strings.Cut(rest, "/")
+	}
+	if id, err := strconv.Atoi(path); err == nil && id > 0 {
+		return id, "", nil
+	}
+	if slugPattern.MatchString(path) {
+		return 0, path, nil
+	}
+	return 0, "", exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected a Kitsu ID or slug, e.g. 46474 or sousou-no-frieren)", animeID)
+}
+
+// animeList converts the anime of a document listing them
+func (s *Scraper) animeList(doc document) ([]Anime, error) {
+	resources, err := decodeResources(doc)
+	if err != nil {
+		return nil, err
+	}
+	animes := []Anime{}
+	for _, r := range resources {
+		anime, err := s.toAnime(r)
+		if err != nil {
+			return nil, err
+		}
+		animes = append(animes, anime)
+	}
+	return animes, nil
+}
+
+// SearchAnime searches for anime by title, best matches first
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]Anime, error) {
+	if page < 1 {
+		page = 1
+	}
+	doc, err := s.getAPI(ctx, "/anime", url.Values{
+		"filter[text]": {query},
+		"page[limit]":  {strconv.Itoa(perPage)},
+		"page[offset]": {strconv.Itoa((page - 1) * perPage)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.animeList(doc)
+}
+
+// GetPopularAnime lists the anime trending on Kitsu. The trending list is a
+// single page.
+func (s *Scraper) GetPopularAnime(ctx context.Context, page int) ([]Anime, error) {
+	if page > 1 {
+		return []Anime{}, nil
+	}
+	doc, err := s.getAPI(ctx, "/trending/anime", url.Values{"limit": {strconv.Itoa(perPage)}})
+	if err != nil {
+		return nil, err
+	}
+	return s.animeList(doc)
+}
+
+// findBySlug retrieves the anime with a slug and the document holding it,
+// passing the other parameters of query along
+func (s *Scraper) findBySlug(ctx context.Context, slug string, query url.Values) (resource, document, error) {
+	query.Set("filter[slug]", slug)
+	doc, err := s.getAPI(ctx, "/anime", query)
+	if err != nil {
+		return resource{}, document{}, err
+	}
+	resources, err := decodeResources(doc)
+	if err != nil {
+		return resource{}, document{}, err
+	}
+	if len(resources) == 0 {
+		return resource{}, document{}, exterr.New(exterr.NotFound, "anime %q not found on Kitsu", slug)
+	}
+	return resources[0], doc, nil
+}
+
+// kitsuID resolves an anime ID to its numeric Kitsu ID, looking up slugs
+func (s *Scraper) kitsuID(ctx context.Context, animeID string) (int, error) {
+	id, slug, err := animeRef(animeID)
+	if err != nil || slug == "" {
+		return id, err
+	}
+	r, _, err := s.findBySlug(ctx, slug, url.Values{"fields[anime]": {"slug"}})
+	if err != nil {
+		return 0, err
+	}
+	id, err = strconv.Atoi(r.ID)
+	if err != nil {
+		return 0, exterr.New(exterr.Parse, "unexpected anime ID %q", r.ID)
+	}
+	return id, nil
+}
+
+// GetAnimeDetails retrieves the synopsis, categories, rating and MyAnimeList
+// and AniList IDs of an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (Anime, error) {
+	id, slug, err := animeRef(animeID)
+	if err != nil {
+		return Anime{}, err
+	}
+
+	var r resource
+	var doc document
+	query := url.Values{"include": {detailsInclude}}
+	if slug != "" {
+		r, doc, err = s.findBySlug(ctx, slug, query)
+	} else {
+		doc, err = s.getAPI(ctx, "/anime/"+strconv.Itoa(id), query)
+		if err == nil {
+			if jsonErr := decodeData(doc, &r); jsonErr != nil {
+				err = jsonErr
+			}
+		}
+	}
+	if err != nil {
+		var extErr *exterr.Error
+		if errors.As(err, &extErr) && extErr.Code == exterr.NotFound {
+			return Anime{}, exterr.New(exterr.NotFound, "anime %s not found on Kitsu", strings.TrimSpace(animeID))
+		}
+		return Anime{}, err
+	}
+
+	anime, err := s.toAnime(r)
+	if err != nil {
+		return Anime{}, err
+	}
+	// Only this anime's categories and mappings are included
+	var genres []string
+	for _, included := range doc.Included {
+		switch included.Type {
+		case "categories":
+			var category struct {
+				Title string `json:"title"`
+			}
+			if decodeAttributes(included, &category) == nil && category.Title != "" {
+				genres = append(genres, category.Title)
+			}
+		case "mappings":
+			var mapping struct {
+				ExternalSite string `json:"externalSite"`
+				ExternalID   string `json:"externalId"`
+			}
+			if decodeAttributes(included, &mapping) != nil {
+				continue
+			}
+			externalID, _ := strconv.Atoi(mapping.ExternalID)
+			switch mapping.ExternalSite {
+			case "myanimelist/anime":
+				anime.MalID = externalID
+			case "anilist/anime":
+				anime.AniListID = externalID
+			}
+		}
+	}
+	anime.Genre = strings.Join(genres, ", ")
+	return anime, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// episodesPerRequest is the page size used when walking an episode list, the most Kitsu allows
+const episodesPerRequest = 20
+
+// EpisodeMeta holds display metadata for a single episode, for clients to
+// merge into the episode lists of video sources
+type EpisodeMeta struct {
+	EpisodeNumber float64 `json:"episode_number"`
+	Season        int     `json:"season,omitempty"`         // Season number Kitsu files the episode under
+	SeasonEpisode int     `json:"season_episode,omitempty"` // Episode number within the season
+	Title         string  `json:"title,omitempty"`
+	Description   string  `json:"description,omitempty"`
+	Thumbnail     string  `json:"thumbnail_url,omitempty"`
+	Duration      int     `json:"duration,omitempty"` // Duration in seconds
+	AirDate       int64   `json:"air_date,omitempty"` // Unix timestamp of the first broadcast
+}
+
+// episodeAttributes are the attributes of an episode resource
+type episodeAttributes struct {
+	Number         int    `json:"number"`
+	SeasonNumber   int    `json:"seasonNumber"`
+	RelativeNumber int    `json:"relativeNumber"`
+	CanonicalTitle string `json:"canonicalTitle"`
+	Titles         titles `json:"titles"`
+	Synopsis       string `json:"synopsis"`
+	Airdate        string `json:"airdate"` // YYYY-MM-DD
+	Length         int    `json:"length"`  // Minutes
+	Thumbnail      *image `json:"thumbnail"`
+}
+
+// ParseEpisodeRange parses an episode range such as "1-24" or "5"
+func ParseEpisodeRange(value string) (float64, float64, error) {
+	startStr, endStr, isRange := strings.Cut(value, "-")
+	start, err := strconv.ParseFloat(strings.TrimSpace(startStr), 64)
+	if err != nil {
+		return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q", value)
+	}
+
+	end := start
+	if isRange {
+		end, err = strconv.ParseFloat(strings.TrimSpace(endStr), 64)
+		if err != nil {
+			return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q", value)
+		}
+	}
+
+	if end < start {
+		return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q: end is before start", value)
+	}
+	return start, end, nil
+}
+
+// GetEpisodesMeta retrieves titles, synopses, thumbnails, durations and air
+// dates for a range of episodes. Kitsu numbers episodes from 1 without gaps,
+// so the walk starts at the page holding start rather than the first one.
+func (s *Scraper) GetEpisodesMeta(ctx context.Context, animeID string, start, end float64) ([]EpisodeMeta, error) {
+	id, err := s.kitsuID(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	metas := []EpisodeMeta{}
+	path := "/anime/" + strconv.Itoa(id) + "/episodes"
+	first := max(int(math.Floor(start))-1, 0)
+	for offset := first - first%episodesPerRequest; ; offset += episodesPerRequest {
+		doc, err := s.getAPI(ctx, path, url.Values{
+			"sort":         {"number"},
+			"page[limit]":  {strconv.Itoa(episodesPerRequest)},
+			"page[offset]": {strconv.Itoa(offset)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		resources, err := decodeResources(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastNumber float64
+		for _, r := range resources {
+			var attrs episodeAttributes
+			if err := decodeAttributes(r, &attrs); err != nil {
+				return nil, err
+			}
+			number := float64(attrs.Number)
+			lastNumber = number
+			if number < start || number > end {
+				continue
+			}
+			meta := EpisodeMeta{
+				EpisodeNumber: number,
+				Season:        attrs.SeasonNumber,
+				SeasonEpisode: attrs.RelativeNumber,
+				Title:         s.titleOf(attrs.CanonicalTitle, attrs.Titles),
+				Description:   strings.TrimSpace(attrs.Synopsis),
+				Duration:      attrs.Length * 60,
+			}
+			if thumbnail := attrs.Thumbnail; thumbnail != nil {
+				meta.Thumbnail = thumbnail.Original
+			}
+			if airDate, err := time.Parse(time.DateOnly, attrs.Airdate); err == nil {
+				meta.AirDate = airDate.Unix()
+			}
+			metas = append(metas, meta)
+		}
+
+		if len(resources) < episodesPerRequest || offset+len(resources) >= doc.Meta.Count || lastNumber >= end {
+			break
+		}
+	}
+	return metas, nil
+}
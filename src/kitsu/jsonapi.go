@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// mediaType is the JSON:API content type Kitsu speaks
+const mediaType = "application/vnd.api+json"
+
+// resource is a JSON:API resource object
+type resource struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+// document is a JSON:API top-level document. Data is a single resource or a
+// list depending on the endpoint, so it is decoded by the caller.
+type document struct {
+	Data     json.RawMessage `json:"data"`
+	Included []resource      `json:"included"`
+	Meta     struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+	Errors []struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+		Status string `json:"status"`
+	} `json:"errors"`
+}
+
+// getAPI fetches an API path and returns the decoded document. Kitsu reports
+// errors in the errors array of the body, whose details are more useful than
+// the status line.
+func (s *Scraper) getAPI(ctx context.Context, path string, query url.Values) (document, error) {
+	rawURL := s.apiURL + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return document{}, exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("Accept", mediaType)
+	req.Header.Set("Content-Type", mediaType)
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return document{}, exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	var doc document
+	decodeErr := json.NewDecoder(resp.Body).Decode(&doc)
+	if resp.StatusCode >= 400 {
+		message := resp.Status
+		if len(doc.Errors) > 0 {
+			details := make([]string, len(doc.Errors))
+			for i, e := range doc.Errors {
+				details[i] = e.Detail
+				if details[i] == "" {
+					details[i] = e.Title
+				}
+			}
+			message = strings.Join(details, "; ")
+		}
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return document{}, exterr.New(exterr.NotFound, "not found on Kitsu: %s", message)
+		case http.StatusBadRequest:
+			return document{}, exterr.New(exterr.InvalidArgument, "Kitsu rejected the request: %s", message)
+		}
+		return document{}, exterr.New(exterr.StatusCode(resp.StatusCode), "error from Kitsu: %s", message)
+	}
+	if decodeErr != nil {
+		return document{}, exterr.New(exterr.Parse, "error parsing response from %s: %w", path, decodeErr)
+	}
+	return doc, nil
+}
+
+// decodeResources decodes the data of a document listing resources
+func decodeResources(doc document) ([]resource, error) {
+	var resources []resource
+	if err := json.Unmarshal(doc.Data, &resources); err != nil {
+		return nil, exterr.New(exterr.Parse, "error parsing response: %w", err)
+	}
+	return resources, nil
+}
+
+// decodeAttributes decodes the attributes of a resource into v
+func decodeAttributes(r resource, v interface{}) error {
+	if err := json.Unmarshal(r.Attributes, v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing %s %s: %w", r.Type, r.ID, err)
+	}
+	return nil
+}
+
+// decodeData decodes the data of a document holding a single resource
+func decodeData(doc document, r *resource) error {
+	if err := json.Unmarshal(doc.Data, r); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// sourceID identifies the Kitsu source
+const sourceID = "5536189745410957761"
+
+// defaultAPIURL is the root of Kitsu's JSON:API. api_url in the config file
+// points the extension elsewhere, e.g. at a caching proxy.
+const defaultAPIURL = "https://kitsu.app/api/edge"
+
+// CapabilityMetadataOnly marks a source that describes anime but has no
+// streams. Clients use it to enrich entries from video sources, here with
+// episode thumbnails and synopses, and testers skip the stream pipeline for it.
+const CapabilityMetadataOnly = "metadata-only"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// TitleLanguages lists the values of -title
+var TitleLanguages = []string{"romaji", "english", "native"}
+
+type Scraper struct {
+	apiURL string
+	title  string // Title language anime are listed under
+	client *httpclient.Client
+	retry  httpclient.RetryPolicy
+}
+
+// NewScraper creates a new instance of the kitsu scraper
+func NewScraper() *Scraper {
+	return &Scraper{
+		apiURL: defaultAPIURL,
+		title:  "romaji",
+		client: httpclient.New(),
+		retry:  httpclient.DefaultRetryPolicy,
+	}
+}
+
+// Requests per minute sent to Kitsu, as declared in SourceInfo.RateLimit.
+// Kitsu publishes no limit; this keeps episodes-meta polite while it pages
+// through long shows 20 episodes at a time.
+const (
+	rateLimit = 60
+	rateBurst = 10
+)
+
+// LimitRate throttles requests to Kitsu to rateLimit, sharing the budget
+// with every other invocation through a state file in the cache directory
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("kitsu")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains(), ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetTitleLanguage selects the language anime titles are listed in
+func (s *Scraper) SetTitleLanguage(language string) error {
+	for _, valid := range TitleLanguages {
+		if language == valid {
+			s.title = language
+			return nil
+		}
+	}
+	return exterr.New(exterr.InvalidArgument, "invalid title language %q (valid: %s)", language, strings.Join(TitleLanguages, ", "))
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Sources     []SourceInfo            `json:"sources"`
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// SourceInfo extends scraper.SourceInfo with what the source can do beyond
+// the supports* flags
+type SourceInfo struct {
+	scraper.SourceInfo
+	Capabilities []string `json:"capabilities,omitempty"` // e.g. ["metadata-only"]
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "Kitsu",
+			Package: "kitsu",
+			Lang:    "en",
+			Version: version,
+		},
+		Sources: []SourceInfo{source},
+		Permissions: permissions.Permissions{
+			// The API; api_url in the config file can point elsewhere
+			Network: []string{"kitsu.app"},
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/kitsu.json (read)",
+				"$PAIR_CACHE_DIR/extensions/kitsu/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/kitsu (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (SourceInfo, error) {
+	return SourceInfo{
+		SourceInfo: scraper.SourceInfo{
+			ID:             sourceID,
+			Name:           "Kitsu",
+			BaseURL:        "https://kitsu.app",
+			Language:       "en",
+			RateLimit:      rateLimit,
+			SupportsSearch: true,
+		},
+		Capabilities: []string{CapabilityMetadataOnly},
+	}, nil
+}
+
+// domains returns the hosts doctor checks: the API
+func (s *Scraper) domains() []string {
+	host := "kitsu.app"
+	if u, err := url.Parse(s.apiURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return []string{host}
+}
+
+func main() {
+	var (
+		query    = flag.String("query", "", "Search query")
+		page     = flag.Int("page", 1, "Page number")
+		animeURL = flag.String("anime", "", "Kitsu ID, slug or URL, e.g. 46474 or https://kitsu.app/anime/sousou-no-frieren")
+		epRange  = flag.String("episodes", "", "With episodes-meta: episode range, e.g. 1-24")
+		title    = flag.String("title", "romaji", "Language of anime and episode titles: romaji, english or native (falls back to Kitsu's canonical title when missing)")
+	)
+
+	s := NewScraper()
+	app := &cli.App{
+		Package:       "kitsu",
+		SourceID:      sourceID,
+		Version:       version,
+		Summary:       "A command-line tool for looking up anime and episode metadata on Kitsu. It lists no streams.",
+		MetadataOnly:  true,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Title != "" && !cli.IsFlagSet("title") {
+				*title = cfg.Title
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetTitleLanguage(*title); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+			return nil
+		},
+	}
+
+	// Clients that ignore the capability get a clear error instead of an unknown command
+	unsupported := func(name string) cli.Command {
+		return cli.Command{Name: name, Run: func(ctx context.Context) (interface{}, error) {
+			return nil, exterr.New(exterr.Unsupported, "Kitsu is a metadata-only source and has no streams; use a video source for %s, and episodes-meta for episode metadata", name)
+		}}
+	}
+
+	app.Commands = []cli.Command{
+		{Name: "search", Description: "Search for anime by title.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "popular", Description: "Get the currently trending anime.", Run: func(ctx context.Context) (interface{}, error) {
+			return s.GetPopularAnime(ctx, *page)
+		}},
+		{Name: "details", Description: "Get the synopsis, categories, rating and MyAnimeList and AniList IDs of an anime.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "episodes-meta", Description: "Get titles, synopses, thumbnails, durations and air dates for a range of episodes.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *epRange == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode range are required")
+			}
+			start, end, rangeErr := ParseEpisodeRange(*epRange)
+			if rangeErr != nil {
+				return nil, exterr.From(rangeErr, exterr.InvalidArgument)
+			}
+			return s.GetEpisodesMeta(ctx, *animeURL, start, end)
+		}},
+		unsupported("episodes"),
+		unsupported("stream-url"),
+	}
+	app.Main()
+}
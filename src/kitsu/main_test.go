@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "frieren", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].KitsuID != 46474 {
+		t.Fatalf("SearchAnime results = %+v, want Kitsu ID 46474 first", results)
+	}
+
+	// Slugs are resolved to the Kitsu ID
+	details, err := s.GetAnimeDetails(ctx, "https://kitsu.app/anime/sousou-no-frieren")
+	if err != nil {
+		t.Fatalf("GetAnimeDetails: %v", err)
+	}
+	if details.KitsuID != 46474 || details.Slug != "sousou-no-frieren" {
+		t.Errorf("GetAnimeDetails = %+v, want the details of 46474", details)
+	}
+
+	// The range spans both pages of the fixtures
+	metas, err := s.GetEpisodesMeta(ctx, "46474", 19, 22)
+	if err != nil {
+		t.Fatalf("GetEpisodesMeta: %v", err)
+	}
+	if len(metas) != 4 {
+		t.Fatalf("GetEpisodesMeta returned %d episodes, want 4", len(metas))
+	}
+	for i, meta := range metas {
+		if want := float64(19 + i); meta.EpisodeNumber != want {
+			t.Errorf("GetEpisodesMeta episode %d numbered %v, want %v", i, meta.EpisodeNumber, want)
+		}
+	}
+}
+
+func TestAnimeRef(t *testing.T) {
+	tests := []struct {
+		animeID  string
+		wantID   int
+		wantSlug string
+		wantErr  bool
+	}{
+		{animeID: "46474", wantID: 46474},
+		{animeID: "sousou-no-frieren", wantSlug: "sousou-no-frieren"},
+		{animeID: "https://kitsu.app/anime/sousou-no-frieren", wantSlug: "sousou-no-frieren"},
+		{animeID: "https://kitsu.app/anime/46474/episodes", wantID: 46474},
+		{animeID: "Sousou no Frieren", wantErr: true},
+		{animeID: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		id, slug, err := animeRef(tt.animeID)
+		if (err != nil) != tt.wantErr || id != tt.wantID || slug != tt.wantSlug {
+			t.Errorf("animeRef(%q) = %d, %q, %v, want %d, %q (error %v)", tt.animeID, id, slug, err, tt.wantID, tt.wantSlug, tt.wantErr)
+		}
+	}
+}
+
+func TestParseEpisodeRange(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantStart float64
+		wantEnd   float64
+		wantErr   bool
+	}{
+		{value: "1-24", wantStart: 1, wantEnd: 24},
+		{value: "5", wantStart: 5, wantEnd: 5},
+		{value: " 3 - 4 ", wantStart: 3, wantEnd: 4},
+		{value: "10-2", wantErr: true},
+		{value: "a-b", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		start, end, err := ParseEpisodeRange(tt.value)
+		if (err != nil) != tt.wantErr || start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("ParseEpisodeRange(%q) = %v, %v, %v, want %v, %v (error %v)", tt.value, start, end, err, tt.wantStart, tt.wantEnd, tt.wantErr)
+		}
+	}
+}
+
+func TestReleaseYear(t *testing.T) {
+	tests := []struct {
+		date string
+		want int
+	}{
+		{"2023-09-29", 2023},
+		{"", 0},
+		{"2023", 0},
+	}
+	for _, tt := range tests {
+		if got := releaseYear(tt.date); got != tt.want {
+			t.Errorf("releaseYear(%q) = %d, want %d", tt.date, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the metadata a detail screen needs
+type AnimeDetails struct {
+	scraper.Anime
+	Score    float64 `json:"score,omitempty"`    // Rating out of 10
+	Type     string  `json:"type,omitempty"`     // e.g. TV, Movie or OVA
+	Duration string  `json:"duration,omitempty"` // Episode length as the site states it, e.g. "24 Menit"
+	Aired    string  `json:"aired,omitempty"`    // Release date as the site states it, e.g. "Okt 20, 1999"
+	Batch    bool    `json:"batch"`              // Whether the batch command has download links for the show
+}
+
+// titleSuffix matches what the site appends to show titles: the episode range
+// and the subtitle language, e.g. "(Episode 1 – 1100) Subtitle Indonesia"
+var titleSuffix = regexp.MustCompile(`(?i)\s*(\(Episode[^)]*\))?\s*(Subtitle Indonesia|Sub Indo)\s*$`)
+
+// cleanTitle strips the site's suffixes from a show title
+func cleanTitle(title string) string {
+	return strings.TrimSpace(titleSuffix.ReplaceAllString(title, ""))
+}
+
+// animeIDFromHref extracts the anime ID, the slug, from a link to the show
+// such as /anime/1piece-sub-indo/. Full URLs and bare slugs are accepted as well.
+func animeIDFromHref(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	href = strings.Trim(href, "/")
+	href = strings.TrimPrefix(href, "anime/")
+	if href == "" || strings.Contains(href, "/") {
+		return ""
+	}
+	return href
+}
+
+// airingStatus maps Otakudesu's airing statuses onto the scraper statuses
+func airingStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "ongoing":
+		return scraper.StatusOngoing
+	case "completed":
+		return scraper.StatusCompleted
+	}
+	return scraper.StatusUnknown
+}
+
+// labeledValue splits a "Label : value" line of the site's info boxes
+func labeledValue(text string) (label, value string) {
+	label, value, _ = strings.Cut(htmlx.NormalizeText(text), ":")
+	return strings.ToLower(strings.TrimSpace(label)), strings.TrimSpace(value)
+}
+
+// SearchAnime searches for anime by title. The search page lists every match
+// at once, so there is no page parameter.
+func (s *Scraper) SearchAnime(ctx context.Context, query string) ([]scraper.Anime, error) {
+	doc, err := s.getPage(ctx, "/", url.Values{"s": {query}, "post_type": {"anime"}})
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	doc.Find("ul.chivsrc li").Each(func(_ int, item *goquery.Selection) {
+		link := item.Find("h2 a").First()
+		id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+		if id == "" {
+			return
+		}
+		anime := scraper.Anime{
+			ID:           id,
+			Title:        cleanTitle(htmlx.Text(link)),
+			ThumbnailURL: htmlx.Attr(item.Find("img").First(), "src", ""),
+			Status:       scraper.StatusUnknown,
+		}
+		item.Find("div.set").Each(func(_ int, set *goquery.Selection) {
+			label, value := labeledValue(set.Text())
+			switch label {
+			case "genres":
+				anime.Genre = value
+			case "status":
+				anime.Status = airingStatus(value)
+			}
+		})
+		animes = append(animes, anime)
+	})
+	return animes, nil
+}
+
+// GetLatestUpdates retrieves the airing shows, the one with the newest
+// episode first
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]scraper.Anime, error) {
+	path := "/ongoing-anime/"
+	if page > 1 {
+		path += "page/" + strconv.Itoa(page) + "/"
+	}
+	doc, err := s.getPage(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	doc.Find("div.venz ul li").Each(func(_ int, item *goquery.Selection) {
+		id := animeIDFromHref(htmlx.Attr(item.Find(".thumb a").First(), "href", ""))
+		if id == "" {
+			return
+		}
+		animes = append(animes, scraper.Anime{
+			ID:           id,
+			Title:        cleanTitle(htmlx.TextFirst(item, "h2.jdlflm")),
+			ThumbnailURL: htmlx.Attr(item.Find("img").First(), "src", ""),
+			Status:       scraper.StatusOngoing,
+		})
+	})
+	return animes, nil
+}
+
+// animePage fetches the page of a show
+func (s *Scraper) animePage(ctx context.Context, animeID string) (*goquery.Document, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. 1piece-sub-indo)", animeID)
+	}
+	doc, err := s.getPage(ctx, "/anime/"+id+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find("div.infozingle").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	return doc, nil
+}
+
+// GetAnimeDetails retrieves description, genres, score and airing status for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	var paragraphs []string
+	doc.Find("div.sinopc p").Each(func(_ int, p *goquery.Selection) {
+		if text := htmlx.Text(p); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	})
+	details := AnimeDetails{
+		Anime: scraper.Anime{
+			ID:           animeIDFromHref(animeID),
+			Description:  strings.Join(paragraphs, "\n\n"),
+			ThumbnailURL: htmlx.Attr(doc.Find("div.fotoanime img").First(), "src", ""),
+			Status:       scraper.StatusUnknown,
+		},
+		Batch: batchLink(doc) != "",
+	}
+	doc.Find("div.infozingle p").Each(func(_ int, p *goquery.Selection) {
+		label, value := labeledValue(p.Text())
+		switch label {
+		case "judul":
+			details.Title = cleanTitle(value)
+		case "japanese":
+			if value != "" {
+				details.AlternativeTitles = append(details.AlternativeTitles, value)
+			}
+		case "skor":
+			details.Score, _ = strconv.ParseFloat(value, 64)
+		case "tipe":
+			details.Type = value
+		case "status":
+			details.Status = airingStatus(value)
+		case "total episode":
+			details.Episodes, _ = strconv.Atoi(value)
+		case "durasi":
+			details.Duration = value
+		case "tanggal rilis":
+			details.Aired = value
+		case "studio":
+			details.Artist = value
+		case "genre":
+			details.Genre = value
+		}
+	})
+	if details.Episodes == 0 {
+		details.Episodes = len(pageEpisodes(doc))
+	}
+	return details, nil
+}
+
+// Batch is the output of the batch command: the download links of a finished
+// show's complete release, by quality
+type Batch struct {
+	Title     string     `json:"title"`
+	Downloads []Download `json:"downloads"`
+}
+
+// Download is a file offered in one quality, mirrored on several hosts
+type Download struct {
+	Quality string         `json:"quality"`        // e.g. "Mp4 720p" or "MKV 1080p"
+	Size    string         `json:"size,omitempty"` // e.g. "1.2 GB"
+	Links   []DownloadLink `json:"links"`
+}
+
+// DownloadLink is a download page on a file host
+type DownloadLink struct {
+	Host string `json:"host"` // e.g. "Pdrain" or "Mega"
+	URL  string `json:"url"`
+}
+
+// batchLink returns the link to a show's batch page, which finished shows have
+func batchLink(doc *goquery.Document) string {
+	return htmlx.Attr(doc.Find("div.episodelist a[href*='/batch/']").First(), "href", "")
+}
+
+// GetBatch returns the download links of the batch release of a show
+func (s *Scraper) GetBatch(ctx context.Context, animeID string) (Batch, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return Batch{}, err
+	}
+	link := batchLink(doc)
+	if link == "" {
+		return Batch{}, exterr.New(exterr.NotFound, "anime %q has no batch release", animeID)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return Batch{}, exterr.New(exterr.Parse, "invalid batch link %q: %w", link, err)
+	}
+
+	page, err := s.getPage(ctx, u.Path, nil)
+	if err != nil {
+		return Batch{}, err
+	}
+	box := page.Find("div.batchlink").First()
+	if box.Length() == 0 {
+		return Batch{}, exterr.New(exterr.Parse, "no download links on %s", u.Path)
+	}
+	return Batch{
+		Title:     strings.TrimSpace(strings.TrimSuffix(cleanTitle(htmlx.TextFirst(box, "h4")), "Batch")),
+		Downloads: parseDownloads(box),
+	}, nil
+}
+
+// parseDownloads reads the download lists of batch and episode pages: one
+// item per quality, with the quality in bold, a link per host and the size
+func parseDownloads(box *goquery.Selection) []Download {
+	downloads := []Download{}
+	box.Find("ul li").Each(func(_ int, item *goquery.Selection) {
+		download := Download{
+			Quality: htmlx.TextFirst(item, "strong"),
+			Size:    htmlx.TextFirst(item, "i"),
+			Links:   []DownloadLink{},
+		}
+		item.Find("a[href]").Each(func(_ int, link *goquery.Selection) {
+			download.Links = append(download.Links, DownloadLink{Host: htmlx.Text(link), URL: htmlx.Attr(link, "href", "")})
+		})
+		if download.Quality != "" && len(download.Links) > 0 {
+			downloads = append(downloads, download)
+		}
+	})
+	return downloads
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// episodeLink is an entry of the episode list of an anime page
+type episodeLink struct {
+	url      string // Episode page
+	number   float64
+	title    string
+	uploaded int64 // Unix time, 0 when the date cannot be read
+}
+
+// episodeNumberPattern finds the number in an episode title, e.g.
+// "One Piece Episode 1100 Subtitle Indonesia"
+var episodeNumberPattern = regexp.MustCompile(`(?i)\bEpisode\s+(\d+(?:\.\d+)?)`)
+
+// pageEpisodes reads the episode list of an anime page, oldest first. The
+// site lists the newest first, next to the batch and complete-release links,
+// which are left out.
+func pageEpisodes(doc *goquery.Document) []episodeLink {
+	episodes := []episodeLink{}
+	seen := map[float64]bool{}
+	doc.Find("div.episodelist ul li").Each(func(_ int, item *goquery.Selection) {
+		link := item.Find("a[href*='/episode/']").First()
+		match := episodeNumberPattern.FindStringSubmatch(htmlx.Text(link))
+		if match == nil {
+			return
+		}
+		number, err := strconv.ParseFloat(match[1], 64)
+		if err != nil || seen[number] {
+			return
+		}
+		seen[number] = true
+		episodes = append(episodes, episodeLink{
+			url:      htmlx.Attr(link, "href", ""),
+			number:   number,
+			title:    cleanTitle(htmlx.Text(link)),
+			uploaded: uploadDate(htmlx.TextFirst(item, "span.zeebr")),
+		})
+	})
+	sort.Slice(episodes, func(i, j int) bool { return episodes[i].number < episodes[j].number })
+	return episodes
+}
+
+// indonesianMonths maps the month abbreviations of the episode dates
+var indonesianMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"mei": time.May, "jun": time.June, "jul": time.July, "agu": time.August,
+	"agt": time.August, "ags": time.August, "sep": time.September, "okt": time.October,
+	"nov": time.November, "des": time.December,
+}
+
+// datePattern matches the episode dates of the episode list, e.g. "18 Okt,24"
+var datePattern = regexp.MustCompile(`(\d{1,2})\s+([A-Za-z]{3})[A-Za-z]*\s*,\s*(\d{2,4})`)
+
+// uploadDate converts an episode date into a Unix time, or 0 when it cannot be read
+func uploadDate(text string) int64 {
+	match := datePattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0
+	}
+	month, ok := indonesianMonths[strings.ToLower(match[2])]
+	day, dayErr := strconv.Atoi(match[1])
+	year, yearErr := strconv.Atoi(match[3])
+	if !ok || dayErr != nil || yearErr != nil {
+		return 0
+	}
+	if year < 100 {
+		year += 2000
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix()
+}
+
+// GetEpisodeList returns the episodes of an anime
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := []scraper.Episode{}
+	for _, link := range pageEpisodes(doc) {
+		episodes = append(episodes, scraper.Episode{
+			ID:            link.url,
+			Name:          link.title,
+			DateUpload:    link.uploaded,
+			EpisodeNumber: link.number,
+		})
+	}
+	return episodes, nil
+}
+
+// Video extends scraper.Video with the mirror the stream was resolved from
+type Video struct {
+	scraper.Video
+	Server string `json:"server,omitempty"` // Mirror name as the site shows it, e.g. "mp4upload"
+}
+
+// Server is a mirror an episode is streamed from
+type Server struct {
+	Name      string `json:"name"`               // e.g. "ondesu" or "mp4upload"
+	Quality   string `json:"quality"`            // e.g. "720p"
+	EmbedURL  string `json:"embedUrl,omitempty"` // Player page, when the extension requested it
+	Supported bool   `json:"supported"`          // Whether the extension resolves the mirror into streams
+}
+
+// Warning reports a supported mirror whose streams could not be extracted, so
+// frontends can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // Mirror name and quality, e.g. "ondesu 720p"
+	Provider string `json:"provider,omitempty"` // Host of the player the mirror embeds
+	Reason   string `json:"reason"`
+}
+
+// VideoResponse lists the streams resolved from an episode's mirrors along
+// with every mirror the episode page offers and its download links
+type VideoResponse struct {
+	Streams   []Video    `json:"streams"`
+	Servers   []Server   `json:"servers"`
+	Warnings  []Warning  `json:"warnings"`
+	Downloads []Download `json:"downloads"`
+}
+
+// mirrorActions are the admin-ajax actions behind the mirror buttons of an
+// episode page. They are hashes the site's player script sends, which change
+// when the script is updated; the config file can override them.
+type mirrorActions struct {
+	nonce  string // Returns the nonce the mirror requests are signed with
+	mirror string // Returns the player of a mirror
+}
+
+// defaultMirrorActions are the actions of the site's current player script
+var defaultMirrorActions = mirrorActions{
+	nonce:  "aa1208d27f29ca340c92c66d1926f13f",
+	mirror: "2a3505c93b0035d3f455df82bf976b84",
+}
+
+// mirror is a button of an episode page's mirror list
+type mirror struct {
+	name    string
+	quality string // e.g. "720p", from the list the button is in
+	content string // data-content: base64 of {"id":...,"i":...,"q":"720p"}
+}
+
+// qualityHeight returns the height of a quality such as "720p", for sorting
+func qualityHeight(quality string) int {
+	height, _ := strconv.Atoi(strings.TrimSuffix(quality, "p"))
+	return height
+}
+
+// pageMirrors reads the mirror lists of an episode page: the preferred
+// mirror's buttons first, then the rest by quality, best first
+func (s *Scraper) pageMirrors(doc *goquery.Document) []mirror {
+	var mirrors []mirror
+	doc.Find("div.mirrorstream ul").Each(func(_ int, list *goquery.Selection) {
+		// Lists are classed by quality, e.g. m720p
+		quality := ""
+		for _, class := range strings.Fields(htmlx.Attr(list, "class", "")) {
+			if strings.HasPrefix(class, "m") && strings.HasSuffix(class, "p") {
+				quality = strings.TrimPrefix(class, "m")
+			}
+		}
+		list.Find("li a[data-content]").Each(func(_ int, button *goquery.Selection) {
+			mirrors = append(mirrors, mirror{
+				name:    htmlx.Text(button),
+				quality: quality,
+				content: htmlx.Attr(button, "data-content", ""),
+			})
+		})
+	})
+	preferred := func(m mirror) bool { return s.server != "" && strings.EqualFold(m.name, s.server) }
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		if preferred(mirrors[i]) != preferred(mirrors[j]) {
+			return preferred(mirrors[i])
+		}
+		return qualityHeight(mirrors[i].quality) > qualityHeight(mirrors[j].quality)
+	})
+	return mirrors
+}
+
+// supportedMirror reports whether a mirror plays from a host the extension
+// resolves, going by its name: third-party mirrors are named after their host,
+// the players of Desustream after the player, e.g. "ondesu" or "desudrive"
+func supportedMirror(name string) bool {
+	name = strings.ToLower(name)
+	return name == "mp4upload" || name == "odstream" || strings.Contains(name, "desu")
+}
+
+// GetVideoList returns every mirror of an episode, best quality first, with
+// the streams of the mirrors a video host extractor resolves and the
+// episode's download links. When no mirror yields a stream, the player the
+// episode page opens with is tried as well.
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	var episode *episodeLink
+	for _, link := range pageEpisodes(doc) {
+		if link.number == episodeNumber {
+			episode = &link
+		}
+	}
+	if episode == nil {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g not found", episodeNumber)
+	}
+	u, err := url.Parse(episode.url)
+	if err != nil {
+		return VideoResponse{}, exterr.New(exterr.Parse, "invalid episode link %q: %w", episode.url, err)
+	}
+	page, err := s.getPage(ctx, u.Path, nil)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	referer := s.baseURL + u.Path
+
+	response := VideoResponse{Streams: []Video{}, Servers: []Server{}, Warnings: []Warning{}, Downloads: parseDownloads(page.Find("div.download"))}
+	addStreams := func(name, quality, embedURL string) {
+		extractor := hosters.For(s.extractors, embedURL)
+		if extractor == nil {
+			return
+		}
+		result, err := extractor.Extract(ctx, embedURL, referer)
+		if err != nil {
+			response.Warnings = append(response.Warnings, Warning{Source: strings.TrimSpace(name + " " + quality), Provider: extractor.Domains()[0], Reason: err.Error()})
+			return
+		}
+		for _, stream := range result.Streams {
+			if stream.Quality == "default" && quality != "" {
+				stream.Quality = quality
+			}
+			response.Streams = append(response.Streams, Video{
+				Video: scraper.Video{
+					ID:       animeIDFromHref(animeID),
+					Quality:  stream.Quality,
+					VideoURL: stream.URL,
+					Headers:  stream.Headers,
+				},
+				Server: name,
+			})
+		}
+	}
+
+	nonce := ""
+	var nonceErr error
+	for _, m := range s.pageMirrors(page) {
+		server := Server{Name: m.name, Quality: m.quality}
+		if supportedMirror(m.name) && nonceErr == nil {
+			if nonce == "" {
+				nonce, nonceErr = s.mirrorNonce(ctx, referer)
+			}
+			if nonceErr != nil {
+				// Without a nonce no mirror resolves; a changed action is the usual cause
+				response.Warnings = append(response.Warnings, Warning{Source: "mirrors", Reason: nonceErr.Error()})
+			} else if embedURL, err := s.mirrorEmbed(ctx, m, nonce, referer); err != nil {
+				response.Warnings = append(response.Warnings, Warning{Source: m.name + " " + m.quality, Reason: err.Error()})
+			} else {
+				server.EmbedURL = embedURL
+				server.Supported = hosters.For(s.extractors, embedURL) != nil
+				addStreams(m.name, m.quality, embedURL)
+			}
+		}
+		response.Servers = append(response.Servers, server)
+	}
+
+	if len(response.Streams) == 0 {
+		if embedURL := htmlx.Attr(htmlx.First(page.Selection, "#pembed iframe", "div.responsive-embed-stream iframe"), "src", ""); embedURL != "" {
+			addStreams("default", "", embedURL)
+		}
+	}
+	return response, nil
+}
+
+// mirrorNonce requests the nonce mirror requests are signed with
+func (s *Scraper) mirrorNonce(ctx context.Context, referer string) (string, error) {
+	var response struct {
+		Data string `json:"data"`
+	}
+	if err := s.postAJAX(ctx, url.Values{"action": {s.actions.nonce}}, referer, &response); err != nil {
+		return "", err
+	}
+	if response.Data == "" {
+		return "", exterr.New(exterr.Parse, "no nonce in the admin-ajax response (has the nonce action changed?)")
+	}
+	return response.Data, nil
+}
+
+// iframePattern finds the player a mirror's HTML embeds
+var iframePattern = regexp.MustCompile(`<iframe[^>]+src="([^"]+)"`)
+
+// mirrorEmbed requests the player of a mirror, which admin-ajax returns as
+// base64-encoded HTML with an iframe
+func (s *Scraper) mirrorEmbed(ctx context.Context, m mirror, nonce, referer string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(m.content)
+	if err != nil {
+		return "", exterr.New(exterr.Parse, "invalid mirror data: %w", err)
+	}
+	// Numbers are kept as written; IDs would print in exponent form as floats
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return "", exterr.New(exterr.Parse, "invalid mirror data: %w", err)
+	}
+	form := url.Values{"nonce": {nonce}, "action": {s.actions.mirror}}
+	for key, value := range fields {
+		form.Set(key, fmt.Sprint(value))
+	}
+
+	var response struct {
+		Data string `json:"data"`
+	}
+	if err := s.postAJAX(ctx, form, referer, &response); err != nil {
+		return "", err
+	}
+	player, err := base64.StdEncoding.DecodeString(response.Data)
+	if err != nil {
+		return "", exterr.New(exterr.Parse, "invalid mirror player: %w", err)
+	}
+	match := iframePattern.FindSubmatch(player)
+	if match == nil {
+		return "", exterr.New(exterr.Parse, "no player in the mirror response")
+	}
+	return string(match[1]), nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece")
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "1piece-sub-indo" {
+		t.Fatalf("SearchAnime results = %+v, want 1piece-sub-indo first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 3", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, 1)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	var desu, mp4upload bool
+	for _, v := range videos.Streams {
+		desu = desu || strings.Contains(strings.ToLower(v.Server), "desu")
+		mp4upload = mp4upload || strings.EqualFold(v.Server, "mp4upload")
+	}
+	if !desu || !mp4upload {
+		t.Errorf("GetVideoList streams = %+v, want Desustream and Mp4Upload streams", videos.Streams)
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"One Piece (Episode 1 – 1100) Subtitle Indonesia", "One Piece"},
+		{"One Piece Sub Indo", "One Piece"},
+		{"One Piece", "One Piece"},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.title); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestUploadDate(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"18 Okt,24", time.Date(2024, time.October, 18, 0, 0, 0, 0, time.UTC).Unix()},
+		{"3 Agustus, 2023", time.Date(2023, time.August, 3, 0, 0, 0, 0, time.UTC).Unix()},
+		{"18 Foo,24", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := uploadDate(tt.text); got != tt.want {
+			t.Errorf("uploadDate(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSupportedMirror(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"mp4upload", true},
+		{"ondesu", true},
+		{"DesuDrive", true},
+		{"odstream", true},
+		{"pdrain", false},
+	}
+	for _, tt := range tests {
+		if got := supportedMirror(tt.name); got != tt.want {
+			t.Errorf("supportedMirror(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/auth"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// defaultPlexTV is the plex.tv API, home of accounts and the servers they can reach
+const defaultPlexTV = "https://plex.tv"
+
+// product is the name pair goes by in the account's list of authorized devices
+const product = "pair"
+
+// pinInterval is how often a PIN is checked while waiting for the user to link it
+var pinInterval = 2 * time.Second
+
+// session is what is kept in the keystore after login
+type session struct {
+	Token       string   // Account token, for plex.tv
+	ClientID    string   // X-Plex-Client-Identifier the token was issued to
+	ServerName  string   // e.g. "Living room"
+	ServerURLs  []string // Connections to the server, local ones first
+	ServerToken string   // Access token of the server, which differs from Token on shared servers
+}
+
+// sessionFrom reads a session from its keystore values
+func sessionFrom(values map[string]string) session {
+	return session{
+		Token:       values["token"],
+		ClientID:    values["client_id"],
+		ServerName:  values["server_name"],
+		ServerURLs:  strings.Fields(values["server_urls"]),
+		ServerToken: values["server_token"],
+	}
+}
+
+// values returns the keystore values of the session
+func (s session) values() map[string]string {
+	return map[string]string{
+		"token":        s.Token,
+		"client_id":    s.ClientID,
+		"server_name":  s.ServerName,
+		"server_urls":  strings.Join(s.ServerURLs, " "),
+		"server_token": s.ServerToken,
+	}
+}
+
+// configuredHost returns the host of server_url from the config file, if any
+func (s *Scraper) configuredHost() []string {
+	u, err := url.Parse(s.serverURL)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+	return []string{u.Hostname()}
+}
+
+// serverURLs returns the connections to try, the configured one first
+func (s *Scraper) serverURLs() []string {
+	var urls []string
+	if s.serverURL != "" {
+		urls = append(urls, s.serverURL)
+	}
+	return append(urls, s.session.ServerURLs...)
+}
+
+// serverToken returns the token to send to the server
+func (s *Scraper) serverToken() string {
+	if s.session.ServerToken != "" {
+		return s.session.ServerToken
+	}
+	return s.session.Token
+}
+
+// pin is a code the user links to their account at app.plex.tv
+type pin struct {
+	ID        int64  `json:"id"`
+	Code      string `json:"code"`
+	AuthToken string `json:"authToken"` // Set once the user has linked the PIN
+	ExpiresAt string `json:"expiresAt"` // RFC 3339
+}
+
+// resource is a device of the account, of which only servers are used
+type resource struct {
+	Name        string       `json:"name"`
+	Provides    string       `json:"provides"` // Comma-separated roles, e.g. "server"
+	Owned       bool         `json:"owned"`
+	AccessToken string       `json:"accessToken"`
+	Connections []connection `json:"connections"`
+}
+
+// connection is an address a server can be reached at
+type connection struct {
+	URI   string `json:"uri"`
+	Local bool   `json:"local"`
+	Relay bool   `json:"relay"`
+}
+
+// user is the plex.tv account
+type user struct {
+	Username string `json:"username"`
+	Title    string `json:"title"`
+	Email    string `json:"email"`
+}
+
+// authenticator implements auth.Authenticator against plex.tv
+type authenticator struct {
+	s *Scraper
+}
+
+// Login links pair to the account with a PIN the user enters at app.plex.tv,
+// or takes the token from $PLEX_TOKEN, then picks the server to use
+func (a *authenticator) Login(credentials map[string]string) (map[string]string, error) {
+	ctx := context.Background()
+	sess := session{Token: os.Getenv("PLEX_TOKEN"), ClientID: newClientID()}
+	if sess.Token == "" {
+		token, err := a.s.linkPIN(ctx, sess.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		sess.Token = token
+	}
+
+	server, err := a.s.findServer(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+	sess.ServerName = server.Name
+	sess.ServerToken = server.AccessToken
+	sess.ServerURLs = connectionURLs(server.Connections)
+	return sess.values(), nil
+}
+
+// WhoAmI returns the plex.tv account of a session, with the server in use
+func (a *authenticator) WhoAmI(values map[string]string) (auth.Account, error) {
+	sess := sessionFrom(values)
+	var u user
+	if err := a.s.getPlexTV(context.Background(), sess, "/api/v2/user", nil, &u); err != nil {
+		return auth.Account{}, err
+	}
+	return auth.Account{
+		Username: u.Username,
+		Name:     u.Title,
+		Extra:    map[string]string{"server": sess.ServerName},
+	}, nil
+}
+
+// linkPIN asks the user to link a new PIN to their account and waits until
+// they have, returning the account token
+func (s *Scraper) linkPIN(ctx context.Context, clientID string) (string, error) {
+	sess := session{ClientID: clientID}
+	var p pin
+	if err := s.plexTVRequest(ctx, http.MethodPost, sess, "/api/v2/pins", url.Values{"strong": {"true"}}, &p); err != nil {
+		return "", err
+	}
+
+	link := "https://app.plex.tv/auth#?" + url.Values{
+		"clientID":                 {clientID},
+		"code":                     {p.Code},
+		"context[device][product]": {product},
+	}.Encode()
+	fmt.Fprintf(os.Stderr, "Open this link and sign in to Plex to link pair to your account:\n\n  %s\n\nWaiting for the link...\n", link)
+
+	expires, _ := time.Parse(time.RFC3339, p.ExpiresAt)
+	for {
+		if err := s.getPlexTV(ctx, sess, fmt.Sprintf("/api/v2/pins/%d", p.ID), nil, &p); err != nil {
+			return "", err
+		}
+		if p.AuthToken != "" {
+			return p.AuthToken, nil
+		}
+		if !expires.IsZero() && time.Now().After(expires) {
+			return "", exterr.New(exterr.Timeout, "the PIN expired before it was linked, log in again")
+		}
+		select {
+		case <-ctx.Done():
+			return "", exterr.From(ctx.Err(), exterr.Timeout)
+		case <-time.After(pinInterval):
+		}
+	}
+}
+
+// findServer returns the server named by -server, or the first one the
+// account owns, falling back to the first one shared with it
+func (s *Scraper) findServer(ctx context.Context, sess session) (resource, error) {
+	var resources []resource
+	query := url.Values{"includeHttps": {"1"}, "includeRelay": {"1"}}
+	if err := s.getPlexTV(ctx, sess, "/api/v2/resources", query, &resources); err != nil {
+		return resource{}, err
+	}
+
+	var servers []resource
+	for _, r := range resources {
+		if strings.Contains(r.Provides, "server") {
+			servers = append(servers, r)
+		}
+	}
+	if s.serverPref != "" {
+		var names []string
+		for _, server := range servers {
+			if strings.EqualFold(server.Name, s.serverPref) {
+				return server, nil
+			}
+			names = append(names, server.Name)
+		}
+		return resource{}, exterr.New(exterr.NotFound, "no Plex server named %q (found: %s)", s.serverPref, strings.Join(names, ", "))
+	}
+	for _, server := range servers {
+		if server.Owned {
+			return server, nil
+		}
+	}
+	if len(servers) > 0 {
+		return servers[0], nil
+	}
+	return resource{}, exterr.New(exterr.NotFound, "the Plex account has no servers")
+}
+
+// connectionURLs orders a server's connections local first, then remote,
+// then relayed, which are the slowest
+func connectionURLs(connections []connection) []string {
+	var local, remote, relay []string
+	for _, c := range connections {
+		uri := strings.TrimRight(c.URI, "/")
+		switch {
+		case c.Relay:
+			relay = append(relay, uri)
+		case c.Local:
+			local = append(local, uri)
+		default:
+			remote = append(remote, uri)
+		}
+	}
+	return append(append(local, remote...), relay...)
+}
+
+// newClientID returns a random client identifier for a new login
+func newClientID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// getPlexTV fetches a plex.tv API path and decodes the JSON response into v
+func (s *Scraper) getPlexTV(ctx context.Context, sess session, path string, query url.Values, v interface{}) error {
+	return s.plexTVRequest(ctx, http.MethodGet, sess, path, query, v)
+}
+
+// plexTVRequest sends a request to the plex.tv API, identifying pair and the
+// account, and decodes the JSON response into v
+func (s *Scraper) plexTVRequest(ctx context.Context, method string, sess session, path string, query url.Values, v interface{}) error {
+	rawURL := s.plexTV + path
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Plex-Product", product)
+	req.Header.Set("X-Plex-Version", version)
+	req.Header.Set("X-Plex-Client-Identifier", sess.ClientID)
+	if sess.Token != "" {
+		req.Header.Set("X-Plex-Token", sess.Token)
+	}
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return exterr.New(exterr.Config, "plex.tv rejected the token, log in again")
+	}
+	if resp.StatusCode >= 400 {
+		return exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+	}
+	return decodeJSON(resp.Body, path, v)
+}
+
+// decodeJSON decodes a JSON response body into v
+func decodeJSON(body io.Reader, path string, v interface{}) error {
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response from %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/extconfig"
+)
+
+// Config holds user overrides read from the extension config file. Flags
+// given on the command line win over the file.
+type Config struct {
+	cli.Config
+	Server  string `json:"server,omitempty"`  // Default for -server, e.g. "Living room"
+	Library string `json:"library,omitempty"` // Default for -library, e.g. "Anime"
+
+	// Connection tried before those plex.tv lists, for servers reachable at an
+	// address plex.tv does not know, e.g. http://192.168.1.10:32400
+	ServerURL string `json:"server_url,omitempty"`
+
+	// Put the access token in thumbnail URLs, for hosts that cannot show
+	// posters otherwise. Off by default since it leaves the token wherever the
+	// host keeps thumbnail URLs, e.g. image caches and logs.
+	ThumbnailToken bool `json:"thumbnail_token,omitempty"`
+}
+
+// LoadConfig reads the config file at path, or the default location when path is empty
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path == "" {
+		defaultPath, err := extconfig.Path("plex")
+		if err != nil {
+			return cfg, err
+		}
+		path = defaultPath
+	}
+
+	err := extconfig.Load(path, &cfg)
+	return cfg, err
+}
+
+// ApplyConfig overrides the scraper defaults with the non-empty values of cfg
+func (s *Scraper) ApplyConfig(cfg Config) {
+	if cfg.ServerURL != "" {
+		s.serverURL = strings.TrimRight(cfg.ServerURL, "/")
+	}
+	s.thumbToken = cfg.ThumbnailToken
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// numberedEpisode is an episode of a show with its number counted across seasons
+type numberedEpisode struct {
+	number float64
+	item   metadata
+}
+
+// episodes lists the episodes of a show in season order, numbered from 1
+// across seasons the way pair counts them. Specials, season 0, are left out.
+func (s *Scraper) episodes(ctx context.Context, animeID string) ([]numberedEpisode, error) {
+	key, err := ratingKey(animeID)
+	if err != nil {
+		return nil, err
+	}
+	var c container
+	if err := s.getServer(ctx, "/library/metadata/"+key+"/allLeaves", nil, &c); err != nil {
+		return nil, err
+	}
+
+	var items []metadata
+	for _, m := range c.MediaContainer.Metadata {
+		if m.Type == "episode" && m.ParentIndex > 0 {
+			items = append(items, m)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ParentIndex != items[j].ParentIndex {
+			return items[i].ParentIndex < items[j].ParentIndex
+		}
+		return items[i].Index < items[j].Index
+	})
+
+	episodes := make([]numberedEpisode, len(items))
+	for i, m := range items {
+		episodes[i] = numberedEpisode{number: float64(i + 1), item: m}
+	}
+	return episodes, nil
+}
+
+// GetEpisodeList returns the episodes of a show, named after their season
+// and episode, e.g. "S2E3 - Title"
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	numbered, err := s.episodes(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := []scraper.Episode{}
+	for _, e := range numbered {
+		name := fmt.Sprintf("S%dE%d", e.item.ParentIndex, e.item.Index)
+		if e.item.Title != "" {
+			name += " - " + e.item.Title
+		}
+		episodes = append(episodes, scraper.Episode{
+			ID:            e.item.RatingKey,
+			Name:          name,
+			DateUpload:    uploaded(e.item),
+			EpisodeNumber: e.number,
+		})
+	}
+	return episodes, nil
+}
+
+// uploaded returns when an episode aired, or when it was added to the library
+// when the air date is unknown, as Unix time
+func uploaded(m metadata) int64 {
+	if aired, err := time.Parse("2006-01-02", m.OriginallyAvailableAt); err == nil {
+		return aired.Unix()
+	}
+	return m.AddedAt
+}
+
+// quality returns the label of a media's resolution, e.g. "1080p" or "4K"
+func quality(resolution string) string {
+	switch strings.ToLower(resolution) {
+	case "":
+		return "unknown"
+	case "4k":
+		return "4K"
+	case "sd":
+		return "SD"
+	}
+	return resolution + "p"
+}
+
+// GetVideoList returns the files of an episode, as the server stores them,
+// followed by an HLS stream the server transcodes for players that cannot
+// play them. The URLs carry the access token.
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (scraper.VideoResponse, error) {
+	numbered, err := s.episodes(ctx, animeID)
+	if err != nil {
+		return scraper.VideoResponse{}, err
+	}
+	var key string
+	for _, e := range numbered {
+		if e.number == episodeNumber {
+			key = e.item.RatingKey
+			break
+		}
+	}
+	if key == "" {
+		return scraper.VideoResponse{}, exterr.New(exterr.NotFound, "episode %g not found (the show has %d)", episodeNumber, len(numbered))
+	}
+
+	// allLeaves leaves out the tracks of each file, which the episode lists
+	episode, err := s.item(ctx, key)
+	if err != nil {
+		return scraper.VideoResponse{}, err
+	}
+
+	response := scraper.VideoResponse{Streams: []scraper.Video{}, Subtitles: []scraper.Track{}}
+	seen := map[string]bool{}
+	for _, m := range episode.Media {
+		for _, p := range m.Part {
+			if p.Key == "" {
+				continue
+			}
+			response.Streams = append(response.Streams, scraper.Video{
+				ID:       key,
+				Quality:  quality(m.VideoResolution),
+				VideoURL: s.withToken(p.Key),
+			})
+			for _, st := range p.Stream {
+				// Subtitles embedded in the file have no key and play along with it
+				if st.StreamType != 3 || st.Key == "" || seen[st.Key] {
+					continue
+				}
+				seen[st.Key] = true
+				lang := st.LanguageTag
+				if lang == "" {
+					lang = st.LanguageCode
+				}
+				response.Subtitles = append(response.Subtitles, scraper.Track{URL: s.withToken(st.Key), Lang: lang})
+			}
+		}
+	}
+	if len(response.Streams) == 0 {
+		return scraper.VideoResponse{}, exterr.New(exterr.NotFound, "episode %g has no files", episodeNumber)
+	}
+
+	transcode := url.Values{
+		"path":                     {"/library/metadata/" + key},
+		"protocol":                 {"hls"},
+		"mediaIndex":               {"0"},
+		"partIndex":                {"0"},
+		"directPlay":               {"0"},
+		"directStream":             {"1"},
+		"fastSeek":                 {"1"},
+		"X-Plex-Product":           {product},
+		"X-Plex-Client-Identifier": {s.session.ClientID},
+	}
+	response.Streams = append(response.Streams, scraper.Video{
+		ID:       key,
+		Quality:  "auto (transcoded)",
+		VideoURL: s.withToken("/video/:/transcode/universal/start.m3u8?" + transcode.Encode()),
+	})
+	return response, nil
+}
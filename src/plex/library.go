@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// pageSize is the number of shows per page of browse, search, popular and latest
+const pageSize = 50
+
+// container is the envelope of every server response
+type container struct {
+	MediaContainer struct {
+		Size      int         `json:"size"`
+		TotalSize int         `json:"totalSize"`
+		Metadata  []metadata  `json:"Metadata"`
+		Directory []directory `json:"Directory"`
+	} `json:"MediaContainer"`
+}
+
+// directory is a library section
+type directory struct {
+	Key      string `json:"key"`
+	Title    string `json:"title"`
+	Type     string `json:"type"` // "show" for TV libraries
+	Language string `json:"language"`
+}
+
+// metadata is a show, season or episode
+type metadata struct {
+	RatingKey             string  `json:"ratingKey"`
+	Type                  string  `json:"type"` // "show", "season" or "episode"
+	Title                 string  `json:"title"`
+	OriginalTitle         string  `json:"originalTitle"`
+	Summary               string  `json:"summary"`
+	Thumb                 string  `json:"thumb"`
+	Year                  int     `json:"year"`
+	Studio                string  `json:"studio"`
+	LeafCount             int     `json:"leafCount"`   // Episodes of a show
+	Index                 int     `json:"index"`       // Episode number within its season
+	ParentIndex           int     `json:"parentIndex"` // Season number of an episode, 0 for specials
+	OriginallyAvailableAt string  `json:"originallyAvailableAt"`
+	AddedAt               int64   `json:"addedAt"`
+	Genre                 []tag   `json:"Genre"`
+	Media                 []media `json:"Media"`
+}
+
+type tag struct {
+	Tag string `json:"tag"`
+}
+
+// media is a version of an episode, e.g. a 1080p and a 4K file
+type media struct {
+	VideoResolution string `json:"videoResolution"` // e.g. "1080", "720", "4k", "sd"
+	Part            []part `json:"Part"`
+}
+
+// part is a file of a media
+type part struct {
+	Key    string   `json:"key"` // e.g. /library/parts/4567/1700000000/file.mkv
+	Stream []stream `json:"Stream"`
+}
+
+// stream is a track of a part; episode metadata lists them, allLeaves does not
+type stream struct {
+	StreamType   int    `json:"streamType"` // 1 video, 2 audio, 3 subtitles
+	Key          string `json:"key"`        // Set for subtitles in files of their own
+	Codec        string `json:"codec"`
+	LanguageCode string `json:"languageCode"` // ISO 639-2, e.g. "eng"
+	LanguageTag  string `json:"languageTag"`  // BCP 47, e.g. "en"
+	Title        string `json:"title"`
+}
+
+// getServer fetches a path on the server and decodes the response into v.
+// Each connection is tried in turn until one answers; the first to answer is
+// used for the rest of the command.
+func (s *Scraper) getServer(ctx context.Context, path string, query url.Values, v interface{}) error {
+	urls := s.serverURLs()
+	if len(urls) == 0 {
+		return exterr.New(exterr.Config, "no Plex server connection, log in again or set server_url in the config")
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+
+	var lastErr error
+	for _, serverURL := range urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL+path+"?"+query.Encode(), nil)
+		if err != nil {
+			return exterr.New(exterr.Internal, "error creating request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-Plex-Product", product)
+		req.Header.Set("X-Plex-Client-Identifier", s.session.ClientID)
+		req.Header.Set("X-Plex-Token", s.serverToken())
+
+		resp, err := s.client.DoRetry(req, s.retry)
+		if err != nil {
+			if ctx.Err() != nil {
+				return exterr.From(ctx.Err(), exterr.Timeout)
+			}
+			// Local addresses are unreachable away from home; try the next one
+			lastErr = err
+			continue
+		}
+		defer resp.Body.Close()
+		s.session.ServerURLs = moveFirst(s.session.ServerURLs, serverURL)
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			return exterr.New(exterr.Config, "the Plex server rejected the token, log in again")
+		case resp.StatusCode == http.StatusNotFound:
+			return exterr.New(exterr.NotFound, "%s not found", path)
+		case resp.StatusCode >= 400:
+			return exterr.New(exterr.StatusCode(resp.StatusCode), "error making request: %s from %s", resp.Status, req.URL.Host)
+		}
+		return decodeJSON(resp.Body, path, v)
+	}
+	return exterr.From(fmt.Errorf("error reaching Plex server %q: %w", s.session.ServerName, lastErr), exterr.Network)
+}
+
+// moveFirst returns urls with u first, so later requests go to the connection that answered
+func moveFirst(urls []string, u string) []string {
+	moved := []string{u}
+	for _, other := range urls {
+		if other != u {
+			moved = append(moved, other)
+		}
+	}
+	return moved
+}
+
+// serverBase returns the connection that last answered
+func (s *Scraper) serverBase() string {
+	if len(s.session.ServerURLs) > 0 {
+		return s.session.ServerURLs[0]
+	}
+	return s.serverURL
+}
+
+// withToken returns the server URL of path with the access token, which
+// players need since they cannot send the token as a header
+func (s *Scraper) withToken(path string) string {
+	if path == "" {
+		return ""
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return s.serverBase() + path + sep + "X-Plex-Token=" + url.QueryEscape(s.serverToken())
+}
+
+// thumbnailURL returns the server URL of a poster, without the access token
+// unless the config opts in: a token in thumbnail URLs grants access to the
+// whole server to anything the host hands them to
+func (s *Scraper) thumbnailURL(path string) string {
+	if s.thumbToken {
+		return s.withToken(path)
+	}
+	if path == "" {
+		return ""
+	}
+	return s.serverBase() + path
+}
+
+// Library is a show library of the server
+type Library struct {
+	Key      string `json:"key"`
+	Title    string `json:"title"`
+	Language string `json:"language,omitempty"`
+}
+
+// GetLibraries returns the show libraries of the server
+func (s *Scraper) GetLibraries(ctx context.Context) ([]Library, error) {
+	var c container
+	if err := s.getServer(ctx, "/library/sections", nil, &c); err != nil {
+		return nil, err
+	}
+	libraries := []Library{}
+	for _, d := range c.MediaContainer.Directory {
+		if d.Type == "show" {
+			libraries = append(libraries, Library{Key: d.Key, Title: d.Title, Language: d.Language})
+		}
+	}
+	return libraries, nil
+}
+
+// libraryPath returns the path listing the shows of the library set with
+// -library, or of every library when none is set
+func (s *Scraper) libraryPath(ctx context.Context) (string, error) {
+	if s.library == "" {
+		return "/library/all", nil
+	}
+	libraries, err := s.GetLibraries(ctx)
+	if err != nil {
+		return "", err
+	}
+	var titles []string
+	for _, l := range libraries {
+		if l.Key == s.library || strings.EqualFold(l.Title, s.library) {
+			return "/library/sections/" + l.Key + "/all", nil
+		}
+		titles = append(titles, l.Title)
+	}
+	return "", exterr.New(exterr.NotFound, "no show library named %q (found: %s)", s.library, strings.Join(titles, ", "))
+}
+
+// shows lists a page of shows, filtered and sorted by query
+func (s *Scraper) shows(ctx context.Context, query url.Values, page int) ([]scraper.Anime, error) {
+	path, err := s.libraryPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	query.Set("type", "2") // Shows
+	query.Set("X-Plex-Container-Start", strconv.Itoa((page-1)*pageSize))
+	query.Set("X-Plex-Container-Size", strconv.Itoa(pageSize))
+
+	var c container
+	if err := s.getServer(ctx, path, query, &c); err != nil {
+		return nil, err
+	}
+	animes := []scraper.Anime{}
+	for _, m := range c.MediaContainer.Metadata {
+		animes = append(animes, s.anime(m))
+	}
+	return animes, nil
+}
+
+// anime converts a show to a scraper.Anime
+func (s *Scraper) anime(m metadata) scraper.Anime {
+	var genres []string
+	for _, g := range m.Genre {
+		genres = append(genres, g.Tag)
+	}
+	var titles []string
+	if m.OriginalTitle != "" && m.OriginalTitle != m.Title {
+		titles = append(titles, m.OriginalTitle)
+	}
+	return scraper.Anime{
+		ID:                m.RatingKey,
+		Title:             m.Title,
+		Artist:            m.Studio,
+		Description:       m.Summary,
+		Genre:             strings.Join(genres, ", "),
+		ThumbnailURL:      s.thumbnailURL(m.Thumb),
+		Status:            scraper.StatusUnknown,
+		AlternativeTitles: titles,
+		Episodes:          m.LeafCount,
+		ReleaseYear:       m.Year,
+	}
+}
+
+// BrowseAnime lists the shows of the libraries alphabetically
+func (s *Scraper) BrowseAnime(ctx context.Context, page int) ([]scraper.Anime, error) {
+	return s.shows(ctx, url.Values{"sort": {"titleSort"}}, page)
+}
+
+// SearchAnime searches the libraries for shows by title
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	return s.shows(ctx, url.Values{"title": {query}, "sort": {"titleSort"}}, page)
+}
+
+// GetPopularAnime lists the most watched shows
+func (s *Scraper) GetPopularAnime(ctx context.Context, page int) ([]scraper.Anime, error) {
+	return s.shows(ctx, url.Values{"sort": {"viewCount:desc"}}, page)
+}
+
+// GetLatestUpdates lists the shows with the most recently added episodes
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]scraper.Anime, error) {
+	return s.shows(ctx, url.Values{"sort": {"episode.addedAt:desc"}}, page)
+}
+
+// ratingKey extracts the rating key from an anime ID, either the key itself
+// or a path such as /library/metadata/12345
+func ratingKey(animeID string) (string, error) {
+	key := strings.Trim(animeID, "/")
+	if _, rest, found := strings.Cut(key, "library/metadata/"); found {
+		key, _, _ = strings.Cut(rest, "/")
+	}
+	if _, err := strconv.ParseUint(key, 10, 64); err != nil {
+		return "", exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected a rating key, e.g. 12345)", animeID)
+	}
+	return key, nil
+}
+
+// item fetches the metadata of a show or episode
+func (s *Scraper) item(ctx context.Context, key string) (metadata, error) {
+	var c container
+	err := s.getServer(ctx, "/library/metadata/"+key, nil, &c)
+	var extErr *exterr.Error
+	if errors.As(err, &extErr) && extErr.Code == exterr.NotFound {
+		return metadata{}, exterr.New(exterr.NotFound, "item %s not found", key)
+	}
+	if err != nil {
+		return metadata{}, err
+	}
+	if len(c.MediaContainer.Metadata) == 0 {
+		return metadata{}, exterr.New(exterr.NotFound, "item %s not found", key)
+	}
+	return c.MediaContainer.Metadata[0], nil
+}
+
+// GetAnimeDetails retrieves the description, genres and studio of a show
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (scraper.Anime, error) {
+	key, err := ratingKey(animeID)
+	if err != nil {
+		return scraper.Anime{}, err
+	}
+	m, err := s.item(ctx, key)
+	if err != nil {
+		return scraper.Anime{}, err
+	}
+	if m.Type != "show" {
+		return scraper.Anime{}, exterr.New(exterr.InvalidArgument, "item %s is a %s, not a show", key, m.Type)
+	}
+	return s.anime(m), nil
+}
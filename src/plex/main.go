@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/auth"
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/keystore"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// sourceID identifies the Plex source
+const sourceID = "749679465210685294"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+type Scraper struct {
+	client *httpclient.Client
+	retry  httpclient.RetryPolicy
+
+	plexTV     string // plex.tv API, where accounts and servers are looked up
+	serverPref string // Server picked at login, by name; the first owned server when empty
+	serverURL  string // Connection tried before the discovered ones, e.g. http://192.168.1.10:32400
+	library    string // Library browse and search are restricted to, by title or key; every show library when empty
+	thumbToken bool   // Whether thumbnail URLs carry the access token, see thumbnailURL
+
+	session session // Set from the keystore once logged in
+}
+
+// NewScraper creates a new instance of the plex scraper
+func NewScraper() *Scraper {
+	return &Scraper{
+		client: httpclient.New(),
+		retry:  httpclient.DefaultRetryPolicy,
+		plexTV: defaultPlexTV,
+	}
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Sources     []SourceInfo            `json:"sources"`
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// SourceInfo extends scraper.SourceInfo with the kind of stream URLs the source returns
+type SourceInfo struct {
+	scraper.SourceInfo
+	Type string `json:"type,omitempty"` // "local": streams come from the user's own Plex server
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "Plex",
+			Package: "plex",
+			Lang:    "all",
+			Version: version,
+		},
+		Sources: []SourceInfo{source},
+		Permissions: permissions.Permissions{
+			// plex.tv for the account and its servers, then the server itself:
+			// plex.tv hands out *.plex.direct addresses for local, remote and
+			// relayed connections, while server_url in the config file can name
+			// any address
+			Network: append(permissions.Domains("plex.tv", "plex.direct"), s.configuredHost()...),
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/plex.json (read)",
+				"$PAIR_DATA_DIR/extensions/plex/credentials.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/plex (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (SourceInfo, error) {
+	baseURL := "https://app.plex.tv"
+	if urls := s.serverURLs(); len(urls) > 0 {
+		baseURL = urls[0]
+	}
+	return SourceInfo{
+		SourceInfo: scraper.SourceInfo{
+			ID:             sourceID,
+			Name:           "Plex",
+			BaseURL:        baseURL,
+			Language:       "all",
+			SupportsLatest: true,
+			SupportsSearch: true,
+		},
+		Type: "local",
+	}, nil
+}
+
+// domains returns the hosts doctor checks: plex.tv. The server is left out,
+// as it is usually on the home network, where doctor would take its private
+// address for a DNS block; the libraries command shows whether it answers.
+func (s *Scraper) domains() []string {
+	return []string{"plex.tv"}
+}
+
+func main() {
+	var (
+		query    = flag.String("query", "", "Search query")
+		page     = flag.Int("page", 1, "Page number")
+		animeURL = flag.String("anime", "", "Show rating key, e.g. 12345, or /library/metadata/12345")
+		episode  = flag.Float64("episode", 0, "Episode number, counted across seasons")
+		server   = flag.String("server", "", "With login: name of the Plex server to use (defaults to the first one you own)")
+		library  = flag.String("library", "", "Restrict browse, popular, latest and search to this library, by title or key")
+	)
+
+	s := NewScraper()
+	account := &auth.Commands{Auth: &authenticator{s: s}}
+	app := &cli.App{
+		Package:       "plex",
+		SourceID:      sourceID,
+		Version:       version,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Server != "" && !cli.IsFlagSet("server") {
+				*server = cfg.Server
+			}
+			if cfg.Library != "" && !cli.IsFlagSet("library") {
+				*library = cfg.Library
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			s.serverPref = strings.TrimSpace(*server)
+			s.library = strings.TrimSpace(*library)
+
+			store, err := keystore.Open("plex")
+			if err != nil {
+				return exterr.New(exterr.Config, "error opening keystore: %w", err)
+			}
+			account.Store = store
+			if stored, ok := account.Session(); ok {
+				s.session = sessionFrom(stored)
+			}
+			return nil
+		},
+	}
+
+	// Commands reading the server need a session
+	requireLogin := func() error {
+		if s.session.Token == "" {
+			return exterr.New(exterr.Config, "not logged in to Plex (run the login command)")
+		}
+		return nil
+	}
+
+	app.Commands = []cli.Command{
+		// Login waits for the PIN to be entered, however long that takes
+		{Name: auth.CommandLogin, Description: "Link pair to your Plex account with a PIN (or $PLEX_TOKEN), waiting for it regardless of -timeout.", NoDeadline: true, Run: func(ctx context.Context) (interface{}, error) {
+			return account.Run(auth.CommandLogin)
+		}},
+		{Name: auth.CommandLogout, Description: "Forget the Plex account.", Run: func(ctx context.Context) (interface{}, error) {
+			return account.Run(auth.CommandLogout)
+		}},
+		{Name: auth.CommandWhoAmI, Description: "Show the Plex account and server in use.", Run: func(ctx context.Context) (interface{}, error) {
+			return account.Run(auth.CommandWhoAmI)
+		}},
+		{Name: "libraries", Description: "List the show libraries of your server.", Run: func(ctx context.Context) (interface{}, error) {
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return s.GetLibraries(ctx)
+		}},
+		{Name: "search", Description: "Search your libraries for shows.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "browse", Description: "List the shows of your libraries alphabetically.", Run: func(ctx context.Context) (interface{}, error) {
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return s.BrowseAnime(ctx, *page)
+		}},
+		{Name: "popular", Description: "Get your most watched shows.", Run: func(ctx context.Context) (interface{}, error) {
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return s.GetPopularAnime(ctx, *page)
+		}},
+		{Name: "latest", Description: "Get the shows with recently added episodes.", Run: func(ctx context.Context) (interface{}, error) {
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return s.GetLatestUpdates(ctx, *page)
+		}},
+		{Name: "details", Description: "Get the description, genres and studio of a show.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "episodes", Description: "Get the list of episodes for a show, numbered across seasons.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return s.GetEpisodeList(ctx, *animeURL)
+		}},
+		{Name: "stream-url", Description: "Get the stream URLs of an episode, with the access token.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" || *episode == 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL and episode number are required")
+			}
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return s.GetVideoList(ctx, *animeURL, *episode)
+		}},
+	}
+	app.Main()
+}
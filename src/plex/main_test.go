@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the
+// fixtures, logged in with $PLEX_TOKEN
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+
+	t.Setenv("PLEX_TOKEN", "account-token")
+	values, err := (&authenticator{s: s}).Login(nil)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	s.session = sessionFrom(values)
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	if s.session.ServerToken == "" || len(s.session.ServerURLs) == 0 {
+		t.Fatalf("Login session = %+v, want a server token and connections", s.session)
+	}
+
+	results, err := s.SearchAnime(ctx, "frieren", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("SearchAnime returned no shows")
+	}
+	for _, anime := range results {
+		if strings.Contains(anime.ThumbnailURL, "X-Plex-Token=") {
+			t.Errorf("SearchAnime thumbnail URL %q carries the access token", anime.ThumbnailURL)
+		}
+	}
+	s.ApplyConfig(Config{ThumbnailToken: true})
+	if thumb := s.anime(metadata{Thumb: "/library/metadata/101/thumb"}).ThumbnailURL; !strings.Contains(thumb, "X-Plex-Token=") {
+		t.Errorf("thumbnail URL %q carries no access token with thumbnail_token set", thumb)
+	}
+	s.ApplyConfig(Config{})
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) == 0 {
+		t.Fatal("GetEpisodeList returned no episodes")
+	}
+	for i, episode := range episodes {
+		if episode.EpisodeNumber != float64(i+1) {
+			t.Errorf("GetEpisodeList episode %d numbered %v, want %d", i, episode.EpisodeNumber, i+1)
+		}
+	}
+
+	// The fixtures have the media of one episode only
+	var number float64
+	for _, episode := range episodes {
+		if episode.ID == "1103" {
+			number = episode.EpisodeNumber
+		}
+	}
+	videos, err := s.GetVideoList(ctx, results[0].ID, number)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	if len(videos.Streams) == 0 {
+		t.Fatal("GetVideoList returned no streams")
+	}
+	for _, v := range videos.Streams {
+		if !strings.Contains(v.VideoURL, "X-Plex-Token=") {
+			t.Errorf("GetVideoList stream URL %q carries no access token", v.VideoURL)
+		}
+	}
+}
+
+func TestConnectionURLs(t *testing.T) {
+	connections := []connection{
+		{URI: "https://relay.plex.direct:8443", Relay: true},
+		{URI: "https://remote.plex.direct:32400"},
+		{URI: "https://local.plex.direct:32400", Local: true},
+	}
+	want := []string{"https://local.plex.direct:32400", "https://remote.plex.direct:32400", "https://relay.plex.direct:8443"}
+	if got := connectionURLs(connections); !reflect.DeepEqual(got, want) {
+		t.Errorf("connectionURLs() = %q, want %q", got, want)
+	}
+}
+
+func TestRatingKey(t *testing.T) {
+	tests := []struct {
+		animeID string
+		want    string
+		wantErr bool
+	}{
+		{animeID: "12345", want: "12345"},
+		{animeID: "/library/metadata/12345", want: "12345"},
+		{animeID: "/library/metadata/12345/children", want: "12345"},
+		{animeID: "frieren", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ratingKey(tt.animeID)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ratingKey(%q) = %q, %v, want %q (error %v)", tt.animeID, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQuality(t *testing.T) {
+	tests := []struct {
+		resolution string
+		want       string
+	}{
+		{"1080", "1080p"},
+		{"4k", "4K"},
+		{"sd", "SD"},
+		{"", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := quality(tt.resolution); got != tt.want {
+			t.Errorf("quality(%q) = %q, want %q", tt.resolution, got, tt.want)
+		}
+	}
+}
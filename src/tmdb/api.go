@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// apiError is the body TMDB answers errors with
+type apiError struct {
+	StatusCode    int    `json:"status_code"` // TMDB's own code, e.g. 34 for a missing resource
+	StatusMessage string `json:"status_message"`
+}
+
+// isReadAccessToken reports whether key is an API read access token, a JWT
+// sent as a bearer token, rather than a v3 API key sent as a query parameter
+func isReadAccessToken(key string) bool {
+	return strings.HasPrefix(key, "eyJ")
+}
+
+// getAPI fetches an API path with the stored key and decodes the JSON response into v
+func (s *Scraper) getAPI(ctx context.Context, path string, query url.Values, v interface{}) error {
+	return s.request(ctx, s.apiKey, path, query, v)
+}
+
+// request fetches an API path authenticated with key and decodes the JSON
+// response into v
+func (s *Scraper) request(ctx context.Context, key, path string, query url.Values, v interface{}) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	if !isReadAccessToken(key) {
+		query.Set("api_key", key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.apiURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return exterr.New(exterr.Internal, "error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if isReadAccessToken(key) {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := s.client.DoRetry(req, s.retry)
+	if err != nil {
+		return exterr.From(fmt.Errorf("error making request: %w", err), exterr.Network)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		message := resp.Status
+		var body apiError
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.StatusMessage != "" {
+			message = body.StatusMessage
+		}
+		switch resp.StatusCode {
+		case http.StatusUnauthorized:
+			return exterr.New(exterr.Config, "TMDB rejected the API key, log in again: %s", message)
+		case http.StatusNotFound:
+			return exterr.New(exterr.NotFound, "not found on TMDB: %s", message)
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			return exterr.New(exterr.InvalidArgument, "TMDB rejected the request: %s", message)
+		}
+		return exterr.New(exterr.StatusCode(resp.StatusCode), "error from TMDB: %s", message)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return exterr.New(exterr.Parse, "error parsing response from %s: %w", path, err)
+	}
+	return nil
+}
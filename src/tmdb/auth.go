@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wraient/pair-extensions/pkg/auth"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// keyEntry is the keystore entry holding the API key
+const keyEntry = "api_key"
+
+// loginFields are the credentials asked for at login
+var loginFields = []auth.Field{
+	{Name: "api-key", Prompt: "TMDB API read access token or API key", Env: "TMDB_API_KEY", Secret: true},
+}
+
+// authenticator implements auth.Authenticator by checking the key with TMDB.
+// There is no session beyond the key itself.
+type authenticator struct {
+	s *Scraper
+}
+
+// Login checks the key and keeps it
+func (a *authenticator) Login(credentials map[string]string) (map[string]string, error) {
+	key := strings.TrimSpace(credentials["api-key"])
+	if key == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "API key is required")
+	}
+	if err := a.check(key); err != nil {
+		return nil, err
+	}
+	return map[string]string{keyEntry: key}, nil
+}
+
+// WhoAmI checks the stored key is still accepted. TMDB keys carry no account
+// name, so the key is identified by its kind and last characters.
+func (a *authenticator) WhoAmI(values map[string]string) (auth.Account, error) {
+	key := values[keyEntry]
+	if err := a.check(key); err != nil {
+		return auth.Account{}, err
+	}
+	kind := "API key"
+	if isReadAccessToken(key) {
+		kind = "API read access token"
+	}
+	return auth.Account{
+		Username: "…" + key[max(len(key)-4, 0):],
+		Name:     "TMDB " + kind,
+	}, nil
+}
+
+// check asks TMDB whether it accepts key
+func (a *authenticator) check(key string) error {
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := a.s.request(context.Background(), key, "/3/authentication", nil, &result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return exterr.New(exterr.Config, "TMDB rejected the API key")
+	}
+	return nil
+}
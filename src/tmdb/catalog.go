@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// imageBase is the prefix of TMDB's image paths for the full-size files
+const imageBase = "https://image.tmdb.org/t/p/original"
+
+// show is a TV show as the API returns it; the fields after Genres are only
+// set by details
+type show struct {
+	ID               int      `json:"id"`
+	Name             string   `json:"name"`
+	OriginalName     string   `json:"original_name"`
+	Overview         string   `json:"overview"`
+	PosterPath       string   `json:"poster_path"`
+	BackdropPath     string   `json:"backdrop_path"`
+	FirstAirDate     string   `json:"first_air_date"` // YYYY-MM-DD
+	VoteAverage      float64  `json:"vote_average"`   // Out of 10
+	Genres           []named  `json:"genres"`
+	Status           string   `json:"status"` // Returning Series, Ended, Canceled, In Production, Planned
+	NumberOfEpisodes int      `json:"number_of_episodes"`
+	Companies        []named  `json:"production_companies"`
+	Seasons          []season `json:"seasons"`
+	ExternalIDs      struct {
+		IMDbID string `json:"imdb_id"`
+		TVDBID int    `json:"tvdb_id"`
+	} `json:"external_ids"`
+}
+
+// named is a genre or company
+type named struct {
+	Name string `json:"name"`
+}
+
+// season is a season in a show's details
+type season struct {
+	SeasonNumber int    `json:"season_number"` // 0 holds the specials
+	Name         string `json:"name"`
+	EpisodeCount int    `json:"episode_count"`
+	AirDate      string `json:"air_date"`
+}
+
+// Season describes a season of a show and where it starts in the numbering
+// across seasons that episodes-meta uses without -season
+type Season struct {
+	Number       int    `json:"season"`
+	Name         string `json:"name"`
+	Episodes     int    `json:"episodes"`
+	FirstEpisode int    `json:"first_episode,omitempty"` // Number of its first episode across seasons; unset for specials
+}
+
+// Anime extends scraper.Anime with the IDs other sources and trackers use
+// and the details TMDB has on top of the common fields
+type Anime struct {
+	scraper.Anime
+	TMDBID    int      `json:"tmdb_id"`
+	IMDbID    string   `json:"imdb_id,omitempty"`    // Details only
+	TVDBID    int      `json:"tvdb_id,omitempty"`    // Details only
+	Score     int      `json:"score,omitempty"`      // Average vote out of 100
+	BannerURL string   `json:"banner_url,omitempty"` // Backdrop image
+	Seasons   []Season `json:"seasons,omitempty"`    // Details only
+}
+
+// toAnime converts a show
+func toAnime(sh show) Anime {
+	var alternatives []string
+	if sh.OriginalName != "" && sh.OriginalName != sh.Name {
+		alternatives = []string{sh.OriginalName}
+	}
+	var genres []string
+	for _, genre := range sh.Genres {
+		genres = append(genres, genre.Name)
+	}
+	var companies []string
+	for _, company := range sh.Companies {
+		companies = append(companies, company.Name)
+	}
+
+	anime := Anime{
+		Anime: scraper.Anime{
+			ID:                strconv.Itoa(sh.ID),
+			Title:             sh.Name,
+			Artist:            strings.Join(companies, ", "),
+			Description:       strings.TrimSpace(sh.Overview),
+			Genre:             strings.Join(genres, ", "),
+			ThumbnailURL:      imageURL(sh.PosterPath),
+			Status:            airingStatus(sh.Status),
+			AlternativeTitles: alternatives,
+			Episodes:          sh.NumberOfEpisodes,
+			ReleaseYear:       releaseYear(sh.FirstAirDate),
+		},
+		TMDBID:    sh.ID,
+		IMDbID:    sh.ExternalIDs.IMDbID,
+		TVDBID:    sh.ExternalIDs.TVDBID,
+		Score:     int(math.Round(sh.VoteAverage * 10)),
+		BannerURL: imageURL(sh.BackdropPath),
+	}
+
+	first := 1
+	for _, se := range sh.Seasons {
+		entry := Season{Number: se.SeasonNumber, Name: se.Name, Episodes: se.EpisodeCount}
+		if se.SeasonNumber > 0 {
+			entry.FirstEpisode = first
+			first += se.EpisodeCount
+		}
+		anime.Seasons = append(anime.Seasons, entry)
+	}
+	return anime
+}
+
+// imageURL returns the URL of an image path, or "" when there is none
+func imageURL(path string) string {
+	if path == "" {
+		return ""
+	}
+	return imageBase + path
+}
+
+// airingStatus maps TMDB's show status onto the scraper status constants
+func airingStatus(status string) string {
+	switch status {
+	case "Returning Series":
+		return scraper.StatusOngoing
+	case "Ended":
+		return scraper.StatusCompleted
+	case "Canceled":
+		return scraper.StatusCancelled
+	}
+	return scraper.StatusUnknown
+}
+
+// releaseYear returns the year of a YYYY-MM-DD date, or 0 when it is missing
+func releaseYear(date string) int {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return 0
+	}
+	return t.Year()
+}
+
+// imdbPattern matches IMDb title IDs, e.g. tt22248376
+var imdbPattern = regexp.MustCompile(`^tt\d+$`)
+
+// showRef parses an anime ID: a TMDB ID, optionally prefixed with "tmdb:", a
+// TMDB URL such as https://www.themoviedb.org/tv/209867-frieren, or an
+// external ID, "imdb:tt22248376" (or the bare IMDb ID) or "tvdb:424536". For
+// external IDs the TMDB ID is 0 and source names TMDB's external source.
+func showRef(animeID string) (id int, source, externalID string, err error) {
+	ref := strings.TrimSpace(animeID)
+	prefix, value, prefixed := strings.Cut(ref, ":")
+	switch {
+	case prefixed && prefix == "imdb" && imdbPattern.MatchString(value):
+		return 0, "imdb_id", value, nil
+	case imdbPattern.MatchString(ref):
+		return 0, "imdb_id", ref, nil
+	case prefixed && prefix == "tvdb":
+		if _, err := strconv.Atoi(value); err == nil {
+			return 0, "tvdb_id", value, nil
+		}
+	case prefixed && prefix == "tmdb":
+		ref = value
+	}
+
+	path := ref
+	if u, err := url.Parse(ref); err == nil && u.Host != "" {
+		path = u.Path
+	}
+	path = strings.Trim(path, "/")
+	if _, rest, found := strings.Cut(path, "tv/"); found {
+		path, _, _ = strings.Cut(rest, "/")
+		// URLs append the title to the ID, e.g. 209867-frieren
+		path, _, _ = strings.Cut(path, "-")
+	}
+	if id, err := strconv.Atoi(path); err == nil && id > 0 {
+		return id, "", "", nil
+	}
+	return 0, "", "", exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected a TMDB ID or URL, imdb:<id> or tvdb:<id>)", animeID)
+}
+
+// showID resolves an anime ID to a TMDB ID, looking up external IDs
+func (s *Scraper) showID(ctx context.Context, animeID string) (int, error) {
+	id, source, externalID, err := showRef(animeID)
+	if err != nil || source == "" {
+		return id, err
+	}
+
+	var found struct {
+		TVResults []show `json:"tv_results"`
+	}
+	query := url.Values{"external_source": {source}}
+	if err := s.getAPI(ctx, "/3/find/"+url.PathEscape(externalID), query, &found); err != nil {
+		return 0, err
+	}
+	if len(found.TVResults) == 0 {
+		return 0, exterr.New(exterr.NotFound, "no TMDB show has %s %s", strings.TrimSuffix(source, "_id"), externalID)
+	}
+	return found.TVResults[0].ID, nil
+}
+
+// SearchAnime searches TMDB's TV shows by title. Films are left out, as they
+// have no episodes to describe.
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]Anime, error) {
+	if page < 1 {
+		page = 1
+	}
+	var results struct {
+		Results []show `json:"results"`
+	}
+	err := s.getAPI(ctx, "/3/search/tv", url.Values{
+		"query":         {query},
+		"page":          {strconv.Itoa(page)},
+		"language":      {s.language},
+		"include_adult": {"false"},
+	}, &results)
+	if err != nil {
+		return nil, err
+	}
+	animes := []Anime{}
+	for _, sh := range results.Results {
+		animes = append(animes, toAnime(sh))
+	}
+	return animes, nil
+}
+
+// searchID returns the TMDB ID of the best match for a title
+func (s *Scraper) searchID(ctx context.Context, title string) (int, error) {
+	animes, err := s.SearchAnime(ctx, title, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(animes) == 0 {
+		return 0, exterr.New(exterr.NotFound, "no TMDB show matches %q", title)
+	}
+	return animes[0].TMDBID, nil
+}
+
+// details retrieves a show with its seasons and external IDs
+func (s *Scraper) details(ctx context.Context, id int) (show, error) {
+	var sh show
+	query := url.Values{"language": {s.language}, "append_to_response": {"external_ids"}}
+	if err := s.getAPI(ctx, "/3/tv/"+strconv.Itoa(id), query, &sh); err != nil {
+		var extErr *exterr.Error
+		if errors.As(err, &extErr) && extErr.Code == exterr.NotFound {
+			return show{}, exterr.New(exterr.NotFound, "show %d not found on TMDB", id)
+		}
+		return show{}, err
+	}
+	return sh, nil
+}
+
+// GetAnimeDetails retrieves the overview, genres, companies, seasons and
+// IMDb and TVDB IDs of a show
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (Anime, error) {
+	id, err := s.showID(ctx, animeID)
+	if err != nil {
+		return Anime{}, err
+	}
+	sh, err := s.details(ctx, id)
+	if err != nil {
+		return Anime{}, err
+	}
+	return toAnime(sh), nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair-extensions/pkg/exterr"
+)
+
+// fallbackLanguage fills in the overviews TMDB has no translation for
+const fallbackLanguage = "en-US"
+
+// EpisodeMeta holds display metadata for a single episode, for clients to
+// merge into the episode lists of video sources
+type EpisodeMeta struct {
+	EpisodeNumber float64 `json:"episode_number"`
+	Season        int     `json:"season,omitempty"`         // TMDB season the episode belongs to
+	SeasonEpisode int     `json:"season_episode,omitempty"` // Episode number within the season
+	Title         string  `json:"title,omitempty"`
+	Description   string  `json:"description,omitempty"`
+	Thumbnail     string  `json:"thumbnail_url,omitempty"` // Still from the episode
+	Duration      int     `json:"duration,omitempty"`      // Duration in seconds
+	AirDate       int64   `json:"air_date,omitempty"`      // Unix timestamp of the first broadcast
+}
+
+// episode is an episode in a season's details
+type episode struct {
+	EpisodeNumber int    `json:"episode_number"`
+	Name          string `json:"name"`
+	Overview      string `json:"overview"`
+	StillPath     string `json:"still_path"`
+	Runtime       int    `json:"runtime"`  // Minutes
+	AirDate       string `json:"air_date"` // YYYY-MM-DD
+}
+
+// ParseEpisodeRange parses an episode range such as "1-24" or "5"
+func ParseEpisodeRange(value string) (float64, float64, error) {
+	startStr, endStr, isRange := strings.Cut(value, "-")
+	start, err := strconv.ParseFloat(strings.TrimSpace(startStr), 64)
+	if err != nil {
+		return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q", value)
+	}
+
+	end := start
+	if isRange {
+		end, err = strconv.ParseFloat(strings.TrimSpace(endStr), 64)
+		if err != nil {
+			return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q", value)
+		}
+	}
+
+	if end < start {
+		return 0, 0, exterr.New(exterr.InvalidArgument, "invalid episode range %q: end is before start", value)
+	}
+	return start, end, nil
+}
+
+// seasonEpisodes retrieves the episodes of a season in the selected
+// language, filling in blank overviews from fallbackLanguage
+func (s *Scraper) seasonEpisodes(ctx context.Context, id, seasonNumber int) ([]episode, error) {
+	path := "/3/tv/" + strconv.Itoa(id) + "/season/" + strconv.Itoa(seasonNumber)
+	fetch := func(language string) ([]episode, error) {
+		var result struct {
+			Episodes []episode `json:"episodes"`
+		}
+		if err := s.getAPI(ctx, path, url.Values{"language": {language}}, &result); err != nil {
+			return nil, err
+		}
+		return result.Episodes, nil
+	}
+
+	episodes, err := fetch(s.language)
+	if err != nil || s.language == fallbackLanguage {
+		return episodes, err
+	}
+	untranslated := false
+	for _, ep := range episodes {
+		untranslated = untranslated || ep.Overview == ""
+	}
+	if !untranslated {
+		return episodes, nil
+	}
+
+	fallback, err := fetch(fallbackLanguage)
+	if err != nil {
+		// The translated episodes are still worth returning
+		return episodes, nil
+	}
+	overviews := map[int]string{}
+	for _, ep := range fallback {
+		overviews[ep.EpisodeNumber] = ep.Overview
+	}
+	for i := range episodes {
+		if episodes[i].Overview == "" {
+			episodes[i].Overview = overviews[episodes[i].EpisodeNumber]
+		}
+	}
+	return episodes, nil
+}
+
+// GetEpisodesMeta retrieves titles, overviews, stills, runtimes and air dates
+// for a range of episodes of a show. With seasonNumber 0 episodes are
+// numbered across seasons, specials left out, the way video sources number
+// them; otherwise the range applies within that season. One request is made
+// per season in the range, plus one for untranslated overviews.
+func (s *Scraper) GetEpisodesMeta(ctx context.Context, id, seasonNumber int, start, end float64) ([]EpisodeMeta, error) {
+	sh, err := s.details(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first episode number of each season to fetch
+	firsts := map[int]int{}
+	var order []int
+	if seasonNumber > 0 {
+		for _, se := range sh.Seasons {
+			if se.SeasonNumber == seasonNumber {
+				firsts[seasonNumber] = 1
+				order = append(order, seasonNumber)
+			}
+		}
+		if len(order) == 0 {
+			return nil, exterr.New(exterr.NotFound, "show %d has no season %d on TMDB", id, seasonNumber)
+		}
+	} else {
+		first := 1
+		for _, se := range sh.Seasons {
+			if se.SeasonNumber <= 0 {
+				continue
+			}
+			last := first + se.EpisodeCount - 1
+			if float64(last) >= start && float64(first) <= end {
+				firsts[se.SeasonNumber] = first
+				order = append(order, se.SeasonNumber)
+			}
+			first = last + 1
+		}
+	}
+
+	metas := []EpisodeMeta{}
+	for _, n := range order {
+		episodes, err := s.seasonEpisodes(ctx, id, n)
+		if err != nil {
+			return nil, err
+		}
+		for _, ep := range episodes {
+			number := float64(firsts[n] + ep.EpisodeNumber - 1)
+			if number < start || number > end {
+				continue
+			}
+			meta := EpisodeMeta{
+				EpisodeNumber: number,
+				Season:        n,
+				SeasonEpisode: ep.EpisodeNumber,
+				Title:         ep.Name,
+				Description:   strings.TrimSpace(ep.Overview),
+				Thumbnail:     imageURL(ep.StillPath),
+				Duration:      ep.Runtime * 60,
+			}
+			if airDate, err := time.Parse(time.DateOnly, ep.AirDate); err == nil {
+				meta.AirDate = airDate.Unix()
+			}
+			metas = append(metas, meta)
+		}
+	}
+	return metas, nil
+}
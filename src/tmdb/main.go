@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/url"
+	"regexp"
+
+	"github.com/wraient/pair-extensions/pkg/auth"
+	"github.com/wraient/pair-extensions/pkg/cli"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/httpclient"
+	"github.com/wraient/pair-extensions/pkg/keystore"
+	"github.com/wraient/pair-extensions/pkg/permissions"
+	"github.com/wraient/pair-extensions/pkg/ratelimit"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// sourceID identifies the TMDB source
+const sourceID = "6417946128878102725"
+
+// defaultAPIURL is the root of TMDB's API. api_url in the config file points
+// the extension elsewhere, e.g. at a caching proxy.
+const defaultAPIURL = "https://api.themoviedb.org"
+
+// mockAPIKey is sent in -mock mode when no key is stored; the fixtures
+// answer any key but the rejected one, so offline runs need no login
+const mockAPIKey = "mock-key"
+
+// CapabilityMetadataOnly marks a source that describes anime but has no
+// streams. Clients use it to enrich entries from video sources, here with
+// episode stills, runtimes and localized overviews, and testers skip the
+// stream pipeline for it.
+const CapabilityMetadataOnly = "metadata-only"
+
+// version is stamped at release with -ldflags "-X main.version=X.Y.Z"
+var version = "0.1.0"
+
+// languagePattern matches the ISO 639-1 language codes TMDB translates into,
+// optionally with an ISO 3166-1 region, e.g. ja or pt-BR
+var languagePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+type Scraper struct {
+	apiURL   string
+	apiKey   string // Set from the keystore once logged in
+	language string // Language titles and overviews are translated into
+	client   *httpclient.Client
+	retry    httpclient.RetryPolicy
+}
+
+// NewScraper creates a new instance of the tmdb scraper
+func NewScraper() *Scraper {
+	return &Scraper{
+		apiURL:   defaultAPIURL,
+		language: fallbackLanguage,
+		client:   httpclient.New(),
+		retry:    httpclient.DefaultRetryPolicy,
+	}
+}
+
+// Requests per minute sent to TMDB, as declared in SourceInfo.RateLimit. TMDB
+// answers 429 past about 40 requests a second; episodes-meta needs one or two
+// per season, so a fraction of that is plenty.
+const (
+	rateLimit = 300
+	rateBurst = 20
+)
+
+// LimitRate throttles requests to TMDB to rateLimit, sharing the budget
+// with every other invocation through a state file in the cache directory
+func (s *Scraper) LimitRate() {
+	path, err := ratelimit.Path("tmdb")
+	if err != nil {
+		// Without a cache directory the limit applies to this command only
+		path = ""
+	}
+	s.client.Limit(s.domains(), ratelimit.New(rateLimit, rateBurst, path))
+}
+
+// SetLanguage selects the language titles and overviews are translated into
+func (s *Scraper) SetLanguage(language string) error {
+	if !languagePattern.MatchString(language) {
+		return exterr.New(exterr.InvalidArgument, "invalid language %q (expected a language code, optionally with a region, e.g. ja or pt-BR)", language)
+	}
+	s.language = language
+	return nil
+}
+
+// ExtensionInfo extends scraper.ExtensionInfo with the permissions the extension declares
+type ExtensionInfo struct {
+	scraper.ExtensionInfo
+	Sources     []SourceInfo            `json:"sources"`
+	Permissions permissions.Permissions `json:"permissions"`
+}
+
+// SourceInfo extends scraper.SourceInfo with what the source can do beyond
+// the supports* flags
+type SourceInfo struct {
+	scraper.SourceInfo
+	Capabilities []string `json:"capabilities,omitempty"` // e.g. ["metadata-only"]
+}
+
+// GetExtensionInfo returns metadata about this scraper implementation
+func (s *Scraper) GetExtensionInfo() (ExtensionInfo, error) {
+	source, err := s.GetSourceInfo()
+	if err != nil {
+		return ExtensionInfo{}, err
+	}
+
+	return ExtensionInfo{
+		ExtensionInfo: scraper.ExtensionInfo{
+			Name:    "TMDB",
+			Package: "tmdb",
+			Lang:    "all",
+			Version: version,
+		},
+		Sources: []SourceInfo{source},
+		Permissions: permissions.Permissions{
+			// The API; api_url in the config file can point elsewhere. Stills
+			// are linked on image.tmdb.org but not fetched.
+			Network: []string{"api.themoviedb.org"},
+			Filesystem: []string{
+				"$PAIR_CONFIG_DIR/extensions/tmdb.json (read)",
+				"$PAIR_DATA_DIR/extensions/tmdb/credentials.json (read/write)",
+				"$PAIR_CACHE_DIR/extensions/tmdb/ratelimit.json (read/write)",
+				// doctor checks each storage directory by creating and removing a probe file
+				"$PAIR_CACHE_DIR/extensions/tmdb (write, doctor)",
+			},
+			Binaries: []string{},
+		},
+	}, nil
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (s *Scraper) GetSourceInfo() (SourceInfo, error) {
+	return SourceInfo{
+		SourceInfo: scraper.SourceInfo{
+			ID:             sourceID,
+			Name:           "TMDB",
+			BaseURL:        "https://www.themoviedb.org",
+			Language:       "all",
+			RateLimit:      rateLimit,
+			SupportsSearch: true,
+		},
+		Capabilities: []string{CapabilityMetadataOnly},
+	}, nil
+}
+
+// domains returns the hosts doctor checks: the API
+func (s *Scraper) domains() []string {
+	host := "api.themoviedb.org"
+	if u, err := url.Parse(s.apiURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return []string{host}
+}
+
+func main() {
+	var (
+		query    = flag.String("query", "", "Search query")
+		page     = flag.Int("page", 1, "Page number")
+		animeURL = flag.String("anime", "", "TMDB ID or URL, or an external ID, e.g. 209867, imdb:tt22248376 or tvdb:424536")
+		epRange  = flag.String("episodes", "", "With episodes-meta: episode range, e.g. 1-24")
+		season   = flag.Int("season", 0, "With episodes-meta: number episodes within this season instead of across seasons")
+		language = flag.String("language", fallbackLanguage, "Language of titles and overviews, e.g. ja-JP (overviews missing in it fall back to en-US)")
+	)
+
+	s := NewScraper()
+	account := &auth.Commands{Fields: loginFields, Auth: &authenticator{s: s}}
+	account.RegisterFlags(flag.CommandLine)
+
+	var app *cli.App
+	app = &cli.App{
+		Package:       "tmdb",
+		SourceID:      sourceID,
+		Version:       version,
+		Summary:       "A command-line tool for looking up show and episode metadata on TMDB. It lists no streams.",
+		MetadataOnly:  true,
+		Client:        s.client,
+		ExtensionInfo: func() (interface{}, error) { return s.GetExtensionInfo() },
+		SourceInfo:    func() (interface{}, error) { return s.GetSourceInfo() },
+		Domains:       s.domains,
+		LimitRate:     s.LimitRate,
+		Configure: func(path string) (cli.Config, error) {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return cli.Config{}, err
+			}
+			s.ApplyConfig(cfg)
+			if cfg.Language != "" && !cli.IsFlagSet("language") {
+				*language = cfg.Language
+			}
+			return cfg.Config, nil
+		},
+		Setup: func() error {
+			if err := s.SetLanguage(*language); err != nil {
+				return exterr.From(err, exterr.InvalidArgument)
+			}
+
+			store, err := keystore.Open("tmdb")
+			if err != nil {
+				return exterr.New(exterr.Config, "error opening keystore: %w", err)
+			}
+			account.Store = store
+			if stored, ok := account.Session(); ok {
+				s.apiKey = stored[keyEntry]
+			}
+			if app.Mocking() && s.apiKey == "" {
+				s.apiKey = mockAPIKey
+			}
+			return nil
+		},
+	}
+
+	// Every API request needs the key
+	requireLogin := func() error {
+		if s.apiKey == "" {
+			return exterr.New(exterr.Config, "no TMDB API key (run the login command)")
+		}
+		return nil
+	}
+
+	// Clients that ignore the capability get a clear error instead of an unknown command
+	unsupported := func(name string) cli.Command {
+		return cli.Command{Name: name, Run: func(ctx context.Context) (interface{}, error) {
+			return nil, exterr.New(exterr.Unsupported, "TMDB is a metadata-only source and has no streams; use a video source for %s, and episodes-meta for episode metadata", name)
+		}}
+	}
+
+	app.Commands = []cli.Command{
+		{Name: auth.CommandLogin, Description: "Store your TMDB API read access token or API key (-api-key or $TMDB_API_KEY).", Run: func(ctx context.Context) (interface{}, error) {
+			return account.Run(auth.CommandLogin)
+		}},
+		{Name: auth.CommandLogout, Description: "Forget the TMDB API key.", Run: func(ctx context.Context) (interface{}, error) {
+			return account.Run(auth.CommandLogout)
+		}},
+		{Name: auth.CommandWhoAmI, Description: "Check the stored API key.", Run: func(ctx context.Context) (interface{}, error) {
+			return account.Run(auth.CommandWhoAmI)
+		}},
+		{Name: "search", Description: "Search for TV shows by title.", Run: func(ctx context.Context) (interface{}, error) {
+			if *query == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "search query is required")
+			}
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return cli.Found(s.SearchAnime(ctx, *query, *page))
+		}},
+		{Name: "details", Description: "Get the overview, genres, seasons and IMDb and TVDB IDs of a show.", Run: func(ctx context.Context) (interface{}, error) {
+			if *animeURL == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL is required")
+			}
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			return s.GetAnimeDetails(ctx, *animeURL)
+		}},
+		{Name: "episodes-meta", Description: "Get titles, overviews, stills, runtimes and air dates for a range of episodes (-anime or -query).", Run: func(ctx context.Context) (interface{}, error) {
+			if (*animeURL == "" && *query == "") || *epRange == "" {
+				return nil, exterr.New(exterr.InvalidArgument, "anime URL or search query, and episode range are required")
+			}
+			if *season < 0 {
+				return nil, exterr.New(exterr.InvalidArgument, "-season must not be negative")
+			}
+			start, end, err := ParseEpisodeRange(*epRange)
+			if err != nil {
+				return nil, exterr.From(err, exterr.InvalidArgument)
+			}
+			if err := requireLogin(); err != nil {
+				return nil, err
+			}
+			// A title is looked up and its best match used
+			var id int
+			if *animeURL != "" {
+				id, err = s.showID(ctx, *animeURL)
+			} else {
+				id, err = s.searchID(ctx, *query)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return s.GetEpisodesMeta(ctx, id, *season, start, end)
+		}},
+		unsupported("episodes"),
+		unsupported("stream-url"),
+	}
+	app.Main()
+}
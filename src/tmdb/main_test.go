@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	s.apiKey = "test-key"
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "frieren", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].TMDBID != 209867 {
+		t.Fatalf("SearchAnime results = %+v, want TMDB ID 209867 first", results)
+	}
+
+	// External IDs are looked up
+	details, err := s.GetAnimeDetails(ctx, "imdb:tt22248376")
+	if err != nil {
+		t.Fatalf("GetAnimeDetails: %v", err)
+	}
+	if details.TMDBID != 209867 || len(details.Seasons) == 0 {
+		t.Errorf("GetAnimeDetails = %+v, want the details of 209867 with its seasons", details)
+	}
+}
+
+func TestGetEpisodesMeta(t *testing.T) {
+	s := newMockScraper(t)
+	type numbered struct {
+		number        float64
+		season        int
+		seasonEpisode int
+	}
+	tests := []struct {
+		name   string
+		season int
+		start  float64
+		end    float64
+		want   []numbered
+	}{
+		{"across seasons", 0, 36, 38, []numbered{{36, 2, 11}, {37, 2, 12}, {38, 3, 1}}},
+		{"within a season", 3, 1, 2, []numbered{{1, 3, 1}, {2, 3, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metas, err := s.GetEpisodesMeta(context.Background(), 120089, tt.season, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("GetEpisodesMeta: %v", err)
+			}
+			if len(metas) != len(tt.want) {
+				t.Fatalf("GetEpisodesMeta returned %d episodes, want %d", len(metas), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := numbered{metas[i].EpisodeNumber, metas[i].Season, metas[i].SeasonEpisode}
+				if got != want {
+					t.Errorf("GetEpisodesMeta episode %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestShowRef(t *testing.T) {
+	tests := []struct {
+		animeID        string
+		wantID         int
+		wantSource     string
+		wantExternalID string
+		wantErr        bool
+	}{
+		{animeID: "209867", wantID: 209867},
+		{animeID: "tmdb:209867", wantID: 209867},
+		{animeID: "https://www.themoviedb.org/tv/209867-frieren", wantID: 209867},
+		{animeID: "imdb:tt22248376", wantSource: "imdb_id", wantExternalID: "tt22248376"},
+		{animeID: "tt22248376", wantSource: "imdb_id", wantExternalID: "tt22248376"},
+		{animeID: "tvdb:424536", wantSource: "tvdb_id", wantExternalID: "424536"},
+		{animeID: "tvdb:frieren", wantErr: true},
+		{animeID: "frieren", wantErr: true},
+	}
+	for _, tt := range tests {
+		id, source, externalID, err := showRef(tt.animeID)
+		if (err != nil) != tt.wantErr || id != tt.wantID || source != tt.wantSource || externalID != tt.wantExternalID {
+			t.Errorf("showRef(%q) = %d, %q, %q, %v, want %d, %q, %q (error %v)",
+				tt.animeID, id, source, externalID, err, tt.wantID, tt.wantSource, tt.wantExternalID, tt.wantErr)
+		}
+	}
+}
+
+func TestIsReadAccessToken(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"eyJhbGciOiJIUzI1NiJ9.e30.sig", true},
+		{"0123456789abcdef0123456789abcdef", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isReadAccessToken(tt.key); got != tt.want {
+			t.Errorf("isReadAccessToken(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
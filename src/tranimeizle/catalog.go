@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the metadata a detail screen needs
+type AnimeDetails struct {
+	scraper.Anime
+	Score float64 `json:"score,omitempty"` // Site rating out of 10
+}
+
+// titleSuffix matches the "İzle" (watch) the site appends to titles
+var titleSuffix = regexp.MustCompile(`\s+(İzle|izle|IZLE)$`)
+
+// cleanTitle strips the site's suffix from a show title
+func cleanTitle(title string) string {
+	return strings.TrimSpace(titleSuffix.ReplaceAllString(htmlx.NormalizeText(title), ""))
+}
+
+// animeIDFromHref extracts the anime ID, the slug, from a link to the show
+// such as /anime/one-piece-izle. Full URLs and bare slugs are accepted as well.
+func animeIDFromHref(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	href = strings.Trim(href, "/")
+	href = strings.TrimPrefix(href, "anime/")
+	if href == "" || strings.Contains(href, "/") {
+		return ""
+	}
+	return href
+}
+
+// absoluteURL resolves a link of the site, which uses relative image URLs
+func (s *Scraper) absoluteURL(href string) string {
+	if href == "" || strings.HasPrefix(href, "http") {
+		return href
+	}
+	return s.baseURL + "/" + strings.TrimPrefix(href, "/")
+}
+
+// listing fetches a page of show cards, as search and the lists render them
+func (s *Scraper) listing(ctx context.Context, path string, query url.Values) ([]scraper.Anime, error) {
+	doc, err := s.getPage(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	doc.Find("div.flx-block").Each(func(_ int, card *goquery.Selection) {
+		id := animeIDFromHref(htmlx.Attr(card.Find("a[href*='/anime/']").First(), "href", ""))
+		if id == "" {
+			return
+		}
+		animes = append(animes, scraper.Anime{
+			ID:           id,
+			Title:        cleanTitle(htmlx.TextFirst(card, "div.bar h4", "h4")),
+			ThumbnailURL: s.absoluteURL(htmlx.AttrAny(card.Find("img").First(), "", "data-src", "src")),
+			Status:       scraper.StatusUnknown,
+		})
+	})
+	return animes, nil
+}
+
+// SearchAnime searches for anime by title
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	values := url.Values{}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	return s.listing(ctx, "/arama/"+url.PathEscape(query), values)
+}
+
+// GetPopularAnime retrieves the site's most watched shows
+func (s *Scraper) GetPopularAnime(ctx context.Context, page int) ([]scraper.Anime, error) {
+	return s.listing(ctx, "/listeler/populer/sayfa-"+strconv.Itoa(max(page, 1)), nil)
+}
+
+// animePage fetches the page of a show
+func (s *Scraper) animePage(ctx context.Context, animeID string) (*goquery.Document, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece-izle)", animeID)
+	}
+	doc, err := s.getPage(ctx, "/anime/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find("div.playlist-title").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	return doc, nil
+}
+
+// GetAnimeDetails retrieves description, genres and score for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	var genres []string
+	doc.Find("ul.post-categories li a").Each(func(_ int, link *goquery.Selection) {
+		genres = append(genres, htmlx.Text(link))
+	})
+	var titles []string
+	if alt := htmlx.TextFirst(doc.Selection, "div.playlist-title h2"); alt != "" {
+		titles = append(titles, alt)
+	}
+
+	details := AnimeDetails{
+		Anime: scraper.Anime{
+			ID:                animeIDFromHref(animeID),
+			Title:             cleanTitle(htmlx.TextFirst(doc.Selection, "div.playlist-title h1")),
+			Description:       htmlx.TextFirst(doc.Selection, "div.p-10-t"),
+			Genre:             strings.Join(genres, ", "),
+			ThumbnailURL:      s.absoluteURL(htmlx.AttrAny(doc.Find("div.poster img").First(), "", "data-src", "src")),
+			Status:            scraper.StatusUnknown,
+			AlternativeTitles: titles,
+			Episodes:          len(pageEpisodes(doc)),
+		},
+	}
+	details.Score, _ = strconv.ParseFloat(strings.Replace(htmlx.TextFirst(doc.Selection, "div.rating-score"), ",", ".", 1), 64)
+	return details, nil
+}
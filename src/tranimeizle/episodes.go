@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// episodeLink is an entry of the episode list of an anime page
+type episodeLink struct {
+	path   string // Episode page, e.g. /one-piece-1-bolum-izle
+	number float64
+	title  string
+}
+
+// episodeNumberPattern finds the number in an episode link, e.g.
+// /one-piece-1100-bolum-izle ("bölüm" is episode)
+var episodeNumberPattern = regexp.MustCompile(`-(\d+)-bolum-izle/?$`)
+
+// pageEpisodes reads the episode list of an anime page, oldest first. The
+// site lists the newest first.
+func pageEpisodes(doc *goquery.Document) []episodeLink {
+	episodes := []episodeLink{}
+	seen := map[float64]bool{}
+	doc.Find("a[href*='-bolum-izle']").Each(func(_ int, link *goquery.Selection) {
+		href := htmlx.Attr(link, "href", "")
+		if u, err := url.Parse(href); err == nil {
+			href = u.Path
+		}
+		match := episodeNumberPattern.FindStringSubmatch(href)
+		if match == nil {
+			return
+		}
+		number, err := strconv.ParseFloat(match[1], 64)
+		if err != nil || seen[number] {
+			return
+		}
+		seen[number] = true
+		title := cleanTitle(htmlx.AttrAny(link, "", "title"))
+		if title == "" {
+			title = cleanTitle(htmlx.Text(link))
+		}
+		episodes = append(episodes, episodeLink{path: href, number: number, title: title})
+	})
+	sort.Slice(episodes, func(i, j int) bool { return episodes[i].number < episodes[j].number })
+	return episodes
+}
+
+// GetEpisodeList returns the episodes of an anime
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := []scraper.Episode{}
+	for _, link := range pageEpisodes(doc) {
+		name := link.title
+		if name == "" {
+			name = strconv.FormatFloat(link.number, 'f', -1, 64) + ". Bölüm"
+		}
+		episodes = append(episodes, scraper.Episode{
+			ID:            s.baseURL + link.path,
+			Name:          name,
+			EpisodeNumber: link.number,
+		})
+	}
+	return episodes, nil
+}
+
+// Video extends scraper.Video with the fansub group and player the stream was
+// resolved from
+type Video struct {
+	scraper.Video
+	Fansub string `json:"fansub,omitempty"` // Fansub group whose subtitles the video carries
+	Server string `json:"server,omitempty"` // Player name as the site shows it, e.g. "Sibnet"
+}
+
+// Server is a player a fansub group's release of an episode is embedded from
+type Server struct {
+	Fansub    string `json:"fansub"`             // e.g. "AnimeSeverler"
+	Name      string `json:"name"`               // e.g. "Sibnet" or "VOE"
+	EmbedURL  string `json:"embedUrl,omitempty"` // Player page, when the extension requested it
+	Supported bool   `json:"supported"`          // Whether the extension resolves the player into streams
+}
+
+// Warning reports a supported player whose streams could not be extracted, so
+// frontends can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // Fansub group and player, e.g. "AnimeSeverler / Sibnet"
+	Provider string `json:"provider,omitempty"` // Host of the player
+	Reason   string `json:"reason"`
+}
+
+// VideoResponse lists the streams resolved from an episode's players along
+// with every player of every fansub group, including those the extension
+// cannot resolve
+type VideoResponse struct {
+	Streams  []Video   `json:"streams"`
+	Servers  []Server  `json:"servers"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// source is a player button of a fansub group's release
+type source struct {
+	fansub string
+	name   string
+	id     string // data-id, the key of /api/sourcePlayer
+}
+
+// GetVideoList returns every player of an episode, by fansub group, with the
+// streams of the players a video host extractor resolves. The preferred
+// fansub group or player is tried first.
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	var episode *episodeLink
+	for _, link := range pageEpisodes(doc) {
+		if link.number == episodeNumber {
+			episode = &link
+		}
+	}
+	if episode == nil {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g not found", episodeNumber)
+	}
+	page, err := s.getPage(ctx, episode.path, nil)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	referer := s.baseURL + episode.path
+
+	episodeID := htmlx.Attr(page.Find("input#EpisodeId").First(), "value", "")
+	if episodeID == "" {
+		return VideoResponse{}, exterr.New(exterr.Parse, "no episode ID on %s", episode.path)
+	}
+	sources, err := s.episodeSources(ctx, page, episodeID, referer)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+
+	response := VideoResponse{Streams: []Video{}, Servers: []Server{}, Warnings: []Warning{}}
+	for _, src := range sources {
+		server := Server{Fansub: src.fansub, Name: src.name}
+		// Players are named after their host, so the name picks the extractor
+		extractor := s.extractors[strings.ToLower(src.name)]
+		if extractor == nil {
+			response.Servers = append(response.Servers, server)
+			continue
+		}
+		server.Supported = true
+		label := src.fansub + " / " + src.name
+		embedURL, err := s.sourcePlayer(ctx, src.id, referer)
+		if err != nil {
+			response.Warnings = append(response.Warnings, Warning{Source: label, Reason: err.Error()})
+			response.Servers = append(response.Servers, server)
+			continue
+		}
+		server.EmbedURL = embedURL
+		response.Servers = append(response.Servers, server)
+
+		result, err := extractor.Extract(ctx, embedURL, referer)
+		if err != nil {
+			response.Warnings = append(response.Warnings, Warning{Source: label, Provider: extractor.Domains()[0], Reason: err.Error()})
+			continue
+		}
+		for _, stream := range result.Streams {
+			response.Streams = append(response.Streams, Video{
+				Video: scraper.Video{
+					ID:       animeIDFromHref(animeID),
+					Quality:  stream.Quality,
+					VideoURL: stream.URL,
+					Headers:  stream.Headers,
+				},
+				Fansub: src.fansub,
+				Server: src.name,
+			})
+		}
+	}
+	return response, nil
+}
+
+// episodeSources lists the players of every fansub group of an episode page,
+// those of the preferred fansub group or player first. The page only names
+// the groups; their players are requested from /api/fansubSources.
+func (s *Scraper) episodeSources(ctx context.Context, page *goquery.Document, episodeID, referer string) ([]source, error) {
+	var sources []source
+	fansubs := page.Find("div.fansubSelector[data-fid]")
+	if fansubs.Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "episode has no fansub releases")
+	}
+	var fetchErr error
+	fansubs.EachWithBreak(func(_ int, fansub *goquery.Selection) bool {
+		name := htmlx.Text(fansub)
+		body, err := s.postAPI(ctx, "/api/fansubSources", url.Values{"EpisodeId": {episodeID}, "FansubId": {htmlx.Attr(fansub, "data-fid", "")}}, referer)
+		if err != nil {
+			fetchErr = err
+			return false
+		}
+		doc, err := htmlx.ParseString(string(body))
+		if err != nil {
+			fetchErr = exterr.New(exterr.Parse, "%w", err)
+			return false
+		}
+		doc.Find("li.sourceBtn[data-id]").Each(func(_ int, button *goquery.Selection) {
+			sources = append(sources, source{fansub: name, name: htmlx.Text(button), id: htmlx.Attr(button, "data-id", "")})
+		})
+		return true
+	})
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	preferred := func(src source) bool {
+		return s.server != "" && (strings.EqualFold(src.fansub, s.server) || strings.EqualFold(src.name, s.server))
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return preferred(sources[i]) && !preferred(sources[j]) })
+	return sources, nil
+}
+
+// iframePattern finds the player a source's HTML embeds
+var iframePattern = regexp.MustCompile(`<iframe[^>]+src="([^"]+)"`)
+
+// sourcePlayer requests the player of a source, which /api/sourcePlayer
+// returns as an iframe in JSON
+func (s *Scraper) sourcePlayer(ctx context.Context, id, referer string) (string, error) {
+	body, err := s.postAPI(ctx, "/api/sourcePlayer/"+url.PathEscape(id), url.Values{}, referer)
+	if err != nil {
+		return "", err
+	}
+	var response struct {
+		Source string `json:"source"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", exterr.New(exterr.Parse, "invalid player response: %w", err)
+	}
+	match := iframePattern.FindStringSubmatch(response.Source)
+	if match == nil {
+		return "", exterr.New(exterr.Parse, "no player in the source response")
+	}
+	embedURL := match[1]
+	if strings.HasPrefix(embedURL, "//") {
+		embedURL = "https:" + embedURL
+	}
+	return embedURL, nil
+}
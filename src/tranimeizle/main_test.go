@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "one-piece-izle" {
+		t.Fatalf("SearchAnime results = %+v, want one-piece-izle first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 3", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, 1)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	servers := map[string]bool{}
+	for _, v := range videos.Streams {
+		if v.Fansub == "" {
+			t.Errorf("GetVideoList stream %+v has no fansub", v)
+		}
+		servers[v.Server] = true
+	}
+	for _, want := range []string{"Sibnet", "YourUpload"} {
+		if !servers[want] {
+			t.Errorf("GetVideoList streams = %+v, missing server %s", videos.Streams, want)
+		}
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"One Piece İzle", "One Piece"},
+		{"  One Piece   izle ", "One Piece"},
+		{"One Piece", "One Piece"},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.title); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestPageEpisodes(t *testing.T) {
+	const page = `<ul>
+<li><a href="https://www.tranimeizle.io/one-piece-2-bolum-izle" title="One Piece 2. Bölüm İzle">2</a></li>
+<li><a href="/one-piece-1-bolum-izle">One Piece 1. Bölüm izle</a></li>
+<li><a href="/one-piece-1-bolum-izle">duplicate</a></li>
+<li><a href="/one-piece-bolum-izle">no number</a></li>
+</ul>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []episodeLink{
+		{path: "/one-piece-1-bolum-izle", number: 1, title: "One Piece 1. Bölüm"},
+		{path: "/one-piece-2-bolum-izle", number: 2, title: "One Piece 2. Bölüm"},
+	}
+	if got := pageEpisodes(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("pageEpisodes() = %+v, want %+v", got, want)
+	}
+}
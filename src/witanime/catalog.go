@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// AnimeDetails extends scraper.Anime with the metadata a detail screen needs
+type AnimeDetails struct {
+	scraper.Anime
+	Type     string `json:"type,omitempty"`     // e.g. TV, Movie or OVA
+	Season   string `json:"season,omitempty"`   // Airing season as the site states it, e.g. "خريف 1999"
+	Aired    string `json:"aired,omitempty"`    // Release year as the site states it
+	Duration string `json:"duration,omitempty"` // Episode length as the site states it, e.g. "24 دقيقة"
+}
+
+// bidiControls matches the invisible direction marks and embeddings the site
+// wraps Latin titles in on its right-to-left pages. Left in, they reorder the
+// text around them when the title is shown in a left-to-right interface.
+var bidiControls = regexp.MustCompile("[\u061c\u200e\u200f\u202a-\u202e\u2066-\u2069]")
+
+// titleAffixes matches what the site adds to show titles: "انمي" (anime)
+// before and "مترجم" (subtitled) after
+var titleAffixes = regexp.MustCompile(`^انمي\s+|\s+مترجم(ة)?$`)
+
+// plainText strips direction marks from text and normalizes its spaces, so
+// mixed Arabic and Latin text displays in either direction
+func plainText(text string) string {
+	return htmlx.NormalizeText(bidiControls.ReplaceAllString(text, ""))
+}
+
+// cleanTitle strips direction marks and the site's affixes from a title
+func cleanTitle(title string) string {
+	return strings.TrimSpace(titleAffixes.ReplaceAllString(plainText(title), ""))
+}
+
+// animeIDFromHref extracts the anime ID, the slug, from a link to the show
+// such as /anime/one-piece/. Full URLs and bare slugs are accepted as well.
+func animeIDFromHref(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	href = strings.Trim(href, "/")
+	href = strings.TrimPrefix(href, "anime/")
+	if href == "" || strings.Contains(href, "/") {
+		return ""
+	}
+	return href
+}
+
+// airingStatus maps the site's airing statuses onto the scraper statuses
+func airingStatus(status string) string {
+	switch plainText(status) {
+	case "يعرض الان", "يعرض الآن":
+		return scraper.StatusOngoing
+	case "مكتمل":
+		return scraper.StatusCompleted
+	}
+	return scraper.StatusUnknown
+}
+
+// labeledValue splits a "Label: value" line of the anime page's info box
+func labeledValue(text string) (label, value string) {
+	label, value, _ = strings.Cut(plainText(text), ":")
+	return strings.TrimSpace(label), strings.TrimSpace(value)
+}
+
+// animeCards reads the show cards search and the anime lists render
+func animeCards(doc *goquery.Document) []scraper.Anime {
+	animes := []scraper.Anime{}
+	doc.Find("div.anime-card-container").Each(func(_ int, card *goquery.Selection) {
+		link := card.Find("div.anime-card-title h3 a").First()
+		id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+		if id == "" {
+			return
+		}
+		animes = append(animes, scraper.Anime{
+			ID:           id,
+			Title:        cleanTitle(htmlx.Text(link)),
+			ThumbnailURL: htmlx.AttrAny(card.Find("div.anime-card-poster img").First(), "", "data-src", "src"),
+			Status:       airingStatus(htmlx.TextFirst(card, "div.anime-card-status")),
+		})
+	})
+	return animes
+}
+
+// pagePath prefixes path with the WordPress page segment for pages after the first
+func pagePath(path string, page int) string {
+	if page > 1 {
+		return strings.TrimSuffix(path, "/") + "/page/" + strconv.Itoa(page) + "/"
+	}
+	return path
+}
+
+// SearchAnime searches for anime by title
+func (s *Scraper) SearchAnime(ctx context.Context, query string, page int) ([]scraper.Anime, error) {
+	doc, err := s.getPage(ctx, pagePath("/", page), url.Values{"search_param": {"animes"}, "s": {query}})
+	if err != nil {
+		return nil, err
+	}
+	return animeCards(doc), nil
+}
+
+// GetLatestUpdates retrieves the shows of the newest episodes, the most
+// recently updated first
+func (s *Scraper) GetLatestUpdates(ctx context.Context, page int) ([]scraper.Anime, error) {
+	doc, err := s.getPage(ctx, pagePath("/episode/", page), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	animes := []scraper.Anime{}
+	seen := map[string]bool{}
+	doc.Find("div.episodes-card-container").Each(func(_ int, card *goquery.Selection) {
+		link := card.Find("div.ep-card-anime-title h3 a").First()
+		id := animeIDFromHref(htmlx.Attr(link, "href", ""))
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		animes = append(animes, scraper.Anime{
+			ID:           id,
+			Title:        cleanTitle(htmlx.Text(link)),
+			ThumbnailURL: htmlx.AttrAny(card.Find("img").First(), "", "data-src", "src"),
+			Status:       scraper.StatusOngoing,
+		})
+	})
+	return animes, nil
+}
+
+// animePage fetches the page of a show
+func (s *Scraper) animePage(ctx context.Context, animeID string) (*goquery.Document, error) {
+	id := animeIDFromHref(animeID)
+	if id == "" {
+		return nil, exterr.New(exterr.InvalidArgument, "invalid anime ID %q (expected e.g. one-piece)", animeID)
+	}
+	doc, err := s.getPage(ctx, "/anime/"+id+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find("h1.anime-details-title").Length() == 0 {
+		return nil, exterr.New(exterr.NotFound, "anime %q not found", animeID)
+	}
+	return doc, nil
+}
+
+// GetAnimeDetails retrieves description, genres and airing status for an anime
+func (s *Scraper) GetAnimeDetails(ctx context.Context, animeID string) (AnimeDetails, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return AnimeDetails{}, err
+	}
+
+	var genres []string
+	doc.Find("ul.anime-genres li a").Each(func(_ int, link *goquery.Selection) {
+		genres = append(genres, plainText(link.Text()))
+	})
+	details := AnimeDetails{
+		Anime: scraper.Anime{
+			ID:           animeIDFromHref(animeID),
+			Title:        cleanTitle(htmlx.TextFirst(doc.Selection, "h1.anime-details-title")),
+			Description:  plainText(htmlx.TextFirst(doc.Selection, "p.anime-story")),
+			Genre:        strings.Join(genres, ", "),
+			ThumbnailURL: htmlx.Attr(doc.Find("div.anime-thumbnail img").First(), "src", ""),
+			Status:       scraper.StatusUnknown,
+		},
+	}
+	doc.Find("div.anime-info").Each(func(_ int, info *goquery.Selection) {
+		label, value := labeledValue(info.Text())
+		switch label {
+		case "النوع": // Type
+			details.Type = value
+		case "بداية العرض": // Start of airing
+			details.Aired = value
+		case "حالة الأنمي": // Airing status
+			details.Status = airingStatus(value)
+		case "عدد الحلقات": // Episode count
+			details.Episodes, _ = strconv.Atoi(value)
+		case "مدة الحلقة": // Episode length
+			details.Duration = value
+		case "الموسم": // Season
+			details.Season = value
+		}
+	})
+	if details.Episodes == 0 {
+		details.Episodes = len(pageEpisodes(doc))
+	}
+	return details, nil
+}
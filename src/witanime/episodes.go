@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wraient/pair-extensions/pkg/exterr"
+	"github.com/wraient/pair-extensions/pkg/hosters"
+	"github.com/wraient/pair-extensions/pkg/htmlx"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// episodeLink is an entry of the episode list of an anime page
+type episodeLink struct {
+	url    string // Episode page
+	number float64
+	title  string
+}
+
+// episodeNumberPattern finds the number in an episode title, e.g. "الحلقة 1100"
+// ("episode 1100")
+var episodeNumberPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*$`)
+
+// pageEpisodes reads the episode list of an anime page, oldest first
+func pageEpisodes(doc *goquery.Document) []episodeLink {
+	episodes := []episodeLink{}
+	seen := map[float64]bool{}
+	doc.Find("div.episodes-card-container div.episodes-card-title h3 a").Each(func(_ int, link *goquery.Selection) {
+		title := plainText(link.Text())
+		match := episodeNumberPattern.FindStringSubmatch(title)
+		if match == nil {
+			return
+		}
+		number, err := strconv.ParseFloat(match[1], 64)
+		if err != nil || seen[number] {
+			return
+		}
+		seen[number] = true
+		episodes = append(episodes, episodeLink{url: htmlx.Attr(link, "href", ""), number: number, title: title})
+	})
+	sort.Slice(episodes, func(i, j int) bool { return episodes[i].number < episodes[j].number })
+	return episodes
+}
+
+// GetEpisodeList returns the episodes of an anime
+func (s *Scraper) GetEpisodeList(ctx context.Context, animeID string) ([]scraper.Episode, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := []scraper.Episode{}
+	for _, link := range pageEpisodes(doc) {
+		episodes = append(episodes, scraper.Episode{
+			ID:            link.url,
+			Name:          link.title,
+			EpisodeNumber: link.number,
+		})
+	}
+	return episodes, nil
+}
+
+// Video extends scraper.Video with the server the stream was resolved from
+type Video struct {
+	scraper.Video
+	Server string `json:"server,omitempty"` // Server name as the site shows it, e.g. "mp4upload"
+}
+
+// Server is a video host an episode is embedded from
+type Server struct {
+	Name      string `json:"name"`               // e.g. "mp4upload" or "doodstream"
+	EmbedURL  string `json:"embedUrl,omitempty"` // Player page
+	Supported bool   `json:"supported"`          // Whether the extension resolves the server into streams
+}
+
+// Warning reports a supported server whose streams could not be extracted, so
+// frontends can say "some servers are unavailable" instead of failing silently
+type Warning struct {
+	Source   string `json:"source"`             // Server name, e.g. "mp4upload"
+	Provider string `json:"provider,omitempty"` // Host of the player
+	Reason   string `json:"reason"`
+}
+
+// VideoResponse lists the streams resolved from an episode's servers along
+// with every server the episode page offers, including those the extension
+// cannot resolve
+type VideoResponse struct {
+	Streams  []Video   `json:"streams"`
+	Servers  []Server  `json:"servers"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// serverURL decodes the player link of a server button. Witanime encodes it
+// in base64 in data-url; Anime4up has it in plain text in data-ep-url.
+func serverURL(button *goquery.Selection) string {
+	raw := htmlx.AttrAny(button, "", "data-ep-url", "data-url")
+	if !strings.HasPrefix(raw, "http") && !strings.HasPrefix(raw, "//") {
+		decoded, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return ""
+		}
+		raw = string(decoded)
+	}
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	}
+	if u, err := url.Parse(raw); err != nil || u.Host == "" {
+		return ""
+	}
+	return raw
+}
+
+// pageServers reads the server list of an episode page, the preferred server first
+func (s *Scraper) pageServers(doc *goquery.Document) []Server {
+	servers := []Server{}
+	doc.Find("ul#episode-servers li a").Each(func(_ int, button *goquery.Selection) {
+		name := plainText(htmlx.TextFirst(button, "span.ser"))
+		if name == "" {
+			name = plainText(button.Text())
+		}
+		embedURL := serverURL(button)
+		servers = append(servers, Server{
+			Name:      name,
+			EmbedURL:  embedURL,
+			Supported: hosters.For(s.extractors, embedURL) != nil,
+		})
+	})
+	preferred := func(server Server) bool { return s.server != "" && strings.EqualFold(server.Name, s.server) }
+	sort.SliceStable(servers, func(i, j int) bool { return preferred(servers[i]) && !preferred(servers[j]) })
+	return servers
+}
+
+// GetVideoList returns every server of an episode with the streams of the
+// servers a video host extractor resolves. The preferred server is tried first.
+func (s *Scraper) GetVideoList(ctx context.Context, animeID string, episodeNumber float64) (VideoResponse, error) {
+	doc, err := s.animePage(ctx, animeID)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	var episode *episodeLink
+	for _, link := range pageEpisodes(doc) {
+		if link.number == episodeNumber {
+			episode = &link
+		}
+	}
+	if episode == nil {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g not found", episodeNumber)
+	}
+	u, err := url.Parse(episode.url)
+	if err != nil {
+		return VideoResponse{}, exterr.New(exterr.Parse, "invalid episode link %q: %w", episode.url, err)
+	}
+	page, err := s.getPage(ctx, u.EscapedPath(), nil)
+	if err != nil {
+		return VideoResponse{}, err
+	}
+	referer := s.baseURL + u.EscapedPath()
+
+	response := VideoResponse{Streams: []Video{}, Servers: s.pageServers(page), Warnings: []Warning{}}
+	if len(response.Servers) == 0 {
+		return VideoResponse{}, exterr.New(exterr.NotFound, "episode %g has no servers", episodeNumber)
+	}
+	for _, server := range response.Servers {
+		extractor := hosters.For(s.extractors, server.EmbedURL)
+		if extractor == nil {
+			continue
+		}
+		result, err := extractor.Extract(ctx, server.EmbedURL, referer)
+		if err != nil {
+			response.Warnings = append(response.Warnings, Warning{Source: server.Name, Provider: extractor.Domains()[0], Reason: err.Error()})
+			continue
+		}
+		for _, stream := range result.Streams {
+			response.Streams = append(response.Streams, Video{
+				Video: scraper.Video{
+					ID:       animeIDFromHref(animeID),
+					Quality:  stream.Quality,
+					VideoURL: stream.URL,
+					Headers:  stream.Headers,
+				},
+				Server: server.Name,
+			})
+		}
+	}
+	return response, nil
+}
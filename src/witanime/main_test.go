@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// newMockScraper returns a scraper whose requests are served from the fixtures
+func newMockScraper(t *testing.T) *Scraper {
+	t.Helper()
+	s := NewScraper()
+	stop, err := s.client.UseMock("fixtures")
+	if err != nil {
+		t.Fatalf("UseMock: %v", err)
+	}
+	t.Cleanup(func() { stop() })
+	return s
+}
+
+func TestMockFlow(t *testing.T) {
+	s := newMockScraper(t)
+	ctx := context.Background()
+
+	results, err := s.SearchAnime(ctx, "one piece", 1)
+	if err != nil {
+		t.Fatalf("SearchAnime: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "one-piece" {
+		t.Fatalf("SearchAnime results = %+v, want one-piece first", results)
+	}
+
+	episodes, err := s.GetEpisodeList(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetEpisodeList: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("GetEpisodeList returned %d episodes, want 3", len(episodes))
+	}
+
+	videos, err := s.GetVideoList(ctx, results[0].ID, 1)
+	if err != nil {
+		t.Fatalf("GetVideoList: %v", err)
+	}
+	servers := map[string]bool{}
+	for _, v := range videos.Streams {
+		servers[strings.ToLower(v.Server)] = true
+	}
+	for _, want := range []string{"mp4upload", "doodstream", "uqload"} {
+		if !servers[want] {
+			t.Errorf("GetVideoList streams = %+v, missing server %s", videos.Streams, want)
+		}
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"انمي One Piece مترجم", "One Piece"},
+		{"\u200fOne Piece\u200f", "One Piece"},
+		{"One Piece", "One Piece"},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.title); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestPagePath(t *testing.T) {
+	tests := []struct {
+		path string
+		page int
+		want string
+	}{
+		{"/episode/", 1, "/episode/"},
+		{"/episode/", 3, "/episode/page/3/"},
+		{"/episode", 2, "/episode/page/2/"},
+	}
+	for _, tt := range tests {
+		if got := pagePath(tt.path, tt.page); got != tt.want {
+			t.Errorf("pagePath(%q, %d) = %q, want %q", tt.path, tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestServerURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		button string
+		want   string
+	}{
+		{"base64", `<a data-url="aHR0cHM6Ly93d3cubXA0dXBsb2FkLmNvbS9lbWJlZC1hYmMuaHRtbA==">`, "https://www.mp4upload.com/embed-abc.html"},
+		{"plain", `<a data-ep-url="https://uqload.ws/embed-abc.html">`, "https://uqload.ws/embed-abc.html"},
+		{"scheme relative", `<a data-ep-url="//dood.li/e/abc">`, "https://dood.li/e/abc"},
+		{"invalid base64", `<a data-url="not base64!">`, ""},
+		{"no host", `<a data-url="L2VtYmVk">`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.button))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := serverURL(doc.Find("a")); got != tt.want {
+				t.Errorf("serverURL(%s) = %q, want %q", tt.button, got, tt.want)
+			}
+		})
+	}
+}
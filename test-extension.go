@@ -4,13 +4,23 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"go/build"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // TestResult represents the result of a single test
@@ -36,8 +46,45 @@ type ExtensionTestReport struct {
 	ExtensionInfo   interface{}  `json:"extension_info,omitempty"`
 	WorkingSources  []string     `json:"working_sources"`
 	FailedSources   []string     `json:"failed_sources"`
+	Discontinued    []string     `json:"discontinued_sources,omitempty"` // Retired sources, skipped instead of tested
 	Tests           []TestResult `json:"tests"`
 	Recommendations []string     `json:"recommendations"`
+	Dependencies    []Dependency `json:"dependencies,omitempty"`
+	NetworkHosts    []HostAccess `json:"network_hosts,omitempty"`
+	BandwidthBytes  int64        `json:"bandwidthBytes"`              // Bytes downloaded by the extension over the whole run
+	CommandUsage    []CommandUse `json:"command_bandwidth,omitempty"` // Bytes downloaded per command
+	StreamWarnings  []StreamWarn `json:"provider_warnings,omitempty"` // Sources that failed while others returned streams
+}
+
+// StreamWarn is a provider failure an extension reported in a stream-url response's warnings
+type StreamWarn struct {
+	Source   string `json:"source"`
+	Provider string `json:"provider,omitempty"`
+	Reason   string `json:"reason"`
+	Count    int    `json:"count"` // Number of stream-url runs reporting it
+}
+
+// CommandUse records the bandwidth an extension command used across its runs
+type CommandUse struct {
+	Command        string `json:"command"`
+	Runs           int    `json:"runs"`
+	BandwidthBytes int64  `json:"bandwidthBytes"`
+}
+
+// HostAccess records a host the extension contacted during the test run
+type HostAccess struct {
+	Host           string `json:"host"`
+	Requests       int    `json:"requests"`
+	BandwidthBytes int64  `json:"bandwidthBytes"`
+	Declared       bool   `json:"declared"` // Whether the host is covered by the declared network permissions
+}
+
+// Dependency represents an audited module dependency of an extension
+type Dependency struct {
+	Path            string   `json:"path"`
+	Version         string   `json:"version"`
+	License         string   `json:"license"`
+	Vulnerabilities []string `json:"vulnerabilities,omitempty"`
 }
 
 // ExtensionInfo represents the structure returned by extension-info command
@@ -48,19 +95,52 @@ type ExtensionInfo struct {
 	Version string       `json:"version"`
 	NSFW    bool         `json:"nsfw"`
 	Sources []SourceInfo `json:"sources"`
+
+	Permissions *Permissions `json:"permissions,omitempty"`
+}
+
+// Permissions represents the permission set an extension declares
+type Permissions struct {
+	Network    []string `json:"network"`
+	Filesystem []string `json:"filesystem"`
+	Binaries   []string `json:"binaries"`
 }
 
 // SourceInfo represents individual source information
 type SourceInfo struct {
-	ID                   string `json:"id"`
-	Name                 string `json:"name"`
-	BaseURL              string `json:"baseURL"`
-	Language             string `json:"language"`
-	NSFW                 bool   `json:"nsfw"`
-	RateLimit            int    `json:"rateLimit"`
-	SupportsLatest       bool   `json:"supportsLatest"`
-	SupportsSearch       bool   `json:"supportsSearch"`
-	SupportsRelatedAnime bool   `json:"supportsRelatedAnime"`
+	ID                   string   `json:"id"`
+	Name                 string   `json:"name"`
+	BaseURL              string   `json:"baseURL"`
+	Language             string   `json:"language"`
+	NSFW                 bool     `json:"nsfw"`
+	RateLimit            int      `json:"rateLimit"`
+	SupportsLatest       bool     `json:"supportsLatest"`
+	SupportsSearch       bool     `json:"supportsSearch"`
+	SupportsRelatedAnime bool     `json:"supportsRelatedAnime"`
+	Type                 string   `json:"type,omitempty"`         // "local" for sources serving files from the user's machine or network
+	Capabilities         []string `json:"capabilities,omitempty"` // "metadata-only" for sources describing anime without episodes or streams
+	Status               string   `json:"status,omitempty"`       // "discontinued" for retired sources
+	Successor            string   `json:"successor,omitempty"`    // ID of the source replacing a discontinued one
+}
+
+// sourceDiscontinued is the status of a source whose site is gone for good
+const sourceDiscontinued = "discontinued"
+
+// capabilityMetadataOnly marks a source that has search and details but no
+// episodes or streams, such as a tracker used to enrich other sources
+const capabilityMetadataOnly = "metadata-only"
+
+// metadataOnly reports whether the source declares the metadata-only capability
+func (s SourceInfo) metadataOnly() bool {
+	return slices.Contains(s.Capabilities, capabilityMetadataOnly)
+}
+
+// discontinuedDetail describes a skipped discontinued source
+func discontinuedDetail(source SourceInfo) string {
+	if source.Successor != "" {
+		return fmt.Sprintf("%s: ⏭️ discontinued, succeeded by %s", source.Name, source.Successor)
+	}
+	return fmt.Sprintf("%s: ⏭️ discontinued", source.Name)
 }
 
 // ExtensionTester handles testing of extensions
@@ -70,6 +150,22 @@ type ExtensionTester struct {
 	report        *ExtensionTestReport
 	verbose       bool
 	outputFormat  string
+
+	// Sandbox state for leak and storage checks
+	runID        string            // Marker passed to every extension process via PAIR_TESTER_RUN
+	sandboxDir   string            // Root of the directories handed to the extension
+	tempDir      string            // Designated temp directory passed to the extension via TMPDIR
+	homeDir      string            // Fake home directory passed via HOME, which must stay empty
+	storageDirs  map[string]string // Designated data/cache/config/state directories keyed by env var
+	tempSnapshot map[string]bool   // System temp directory entries before the extension ran
+	extSnapshot  map[string]bool   // Extension directory entries before the extension ran
+	proxy        *recordingProxy   // Forwards and records every request the extension makes
+	commandUsage map[string]*CommandUse
+
+	// Release state for the version agreement check
+	version      string // Version stamped into the build with -X main.version, if any
+	manifestPath string // Published extension.json to compare against, if any
+	tag          string // Release tag to compare against; defaults to the <pkg>-v* tag at HEAD
 }
 
 // NewExtensionTester creates a new extension tester
@@ -140,7 +236,16 @@ func (et *ExtensionTester) getSuggestions(testName, message string) string {
 		"Build Extension":        "Ensure your Go code compiles without errors. Check for missing dependencies in go.mod.",
 		"Extension Info Command": "Implement the GetExtensionInfo() method that returns proper ExtensionInfo structure.",
 		"JSON Validation":        "Make sure your commands output valid JSON. Use json.Marshal() for consistent formatting.",
-		"Source Testing":         "Verify your scraper can connect to the target website and handle rate limits properly.",
+		"Dependency Audit":       "Replace dependencies with copyleft licenses and upgrade modules with known vulnerabilities (see https://osv.dev).",
+		"Offline Commands":       "Serve extension-info, list-sources, capabilities, version and filters from static data without any network requests.",
+		"Source Consistency":     "Make sure source-info accepts every ID returned by list-sources and reports the same fields. Avoid hardcoding source IDs in multiple places.",
+		"Source Testing":         "Verify your scraper can connect to the target website and handle rate limits properly. Sources streaming from the local machine or network must declare \"type\": \"local\".",
+		"Dub Pipeline":           "Pass --translation through search, episodes and stream-url, and return an error instead of sub streams when no dub exists.",
+		"Resource Leaks":         "Wait for child processes (e.g. ffprobe) before exiting and write temp files only under $TMPDIR, removing them when done.",
+		"Storage Directories":    "Write cache, state and cookies under $PAIR_DATA_DIR/$PAIR_CACHE_DIR, falling back to the XDG directories, never the home root or working directory.",
+		"Stdout Purity":          "Print only the JSON result to stdout, or on failure an {\"error\": {\"code\": ..., \"message\": ...}} object (see pkg/exterr); send banners, progress and log output to stderr with fmt.Fprintf(os.Stderr, ...).",
+		"Version Agreement":      "Report an X.Y.Z version from a single variable stamped at release with -ldflags \"-X main.version=X.Y.Z\", and tag the release <pkg>-vX.Y.Z from the same version as the manifest.",
+		"Network Permissions":    "Declare every contacted domain under permissions.network in extension-info (\"*.example.com\" covers subdomains) instead of \"*\", or find out why the extension reached an unexpected host.",
 		"Search Functionality":   "Implement proper search logic that can handle common anime titles like 'naruto', 'one piece'.",
 		"Episode Listing":        "Ensure your GetEpisodeList() method returns episodes with proper ID and episode numbers.",
 		"Stream URL Generation":  "Implement GetVideoList() that returns working stream URLs with proper headers.",
@@ -173,8 +278,13 @@ func (et *ExtensionTester) buildExtension() (bool, string, string) {
 	}
 	et.binaryPath = filepath.Join(absExtensionPath, binaryName)
 
-	// Build command
-	cmd := exec.Command("go", "build", "-o", binaryName, ".")
+	// Build command; the nsfw tag includes extensions isolated behind it
+	args := []string{"build", "-tags", "nsfw", "-o", binaryName}
+	if et.version != "" {
+		// Stamp the version the way the release builder does
+		args = append(args, "-ldflags", "-X main.version="+et.version)
+	}
+	cmd := exec.Command("go", append(args, ".")...)
 	cmd.Dir = absExtensionPath
 
 	output, err := cmd.CombinedOutput()
@@ -223,8 +333,78 @@ func (et *ExtensionTester) testExtensionInfo() (bool, string, string) {
 		return false, "Missing required fields", fmt.Sprintf("Missing: %s", strings.Join(missing, ", "))
 	}
 
+	// NSFW extensions must build only with the nsfw tag so distributions can leave them out
+	nsfw := extInfo.NSFW
+	for _, source := range extInfo.Sources {
+		nsfw = nsfw || source.NSFW
+	}
+	if tagged := hasNSFWBuildTag(et.extensionPath); nsfw != tagged {
+		if nsfw {
+			return false, "NSFW extension is not isolated", "Add a //go:build nsfw constraint to every Go file of the extension"
+		}
+		return false, "SFW extension is behind the nsfw build tag", "Remove the //go:build nsfw constraint or mark the extension as nsfw"
+	}
+
 	et.report.ExtensionInfo = extInfo
-	return true, fmt.Sprintf("Extension info valid (%d sources found)", len(extInfo.Sources)), ""
+	if extInfo.Permissions == nil {
+		return true, fmt.Sprintf("Extension info valid (%d sources found)", len(extInfo.Sources)), "No permissions declared; the pair app cannot show a permissions summary"
+	}
+	return true, fmt.Sprintf("Extension info valid (%d sources found, %d network domains declared)", len(extInfo.Sources), len(extInfo.Permissions.Network)), ""
+}
+
+// hasNSFWBuildTag reports whether the package in dir only builds with the nsfw tag
+func hasNSFWBuildTag(dir string) bool {
+	ctx := build.Default
+	if _, err := ctx.ImportDir(dir, 0); err == nil {
+		return false
+	}
+
+	ctx.BuildTags = append(ctx.BuildTags, "nsfw")
+	_, err := ctx.ImportDir(dir, 0)
+	return err == nil
+}
+
+// testSourceConsistency verifies that every source returned by list-sources is
+// accepted by source-info and that both commands report the same fields
+func (et *ExtensionTester) testSourceConsistency() (bool, string, string) {
+	output, err := et.runCommand("list-sources")
+	if err != nil {
+		return false, "List-sources command failed", err.Error()
+	}
+
+	var sources []SourceInfo
+	if err := json.Unmarshal([]byte(output), &sources); err != nil {
+		return false, "Invalid JSON output from list-sources", fmt.Sprintf("JSON parse error: %v", err)
+	}
+
+	if len(sources) == 0 {
+		return false, "No sources returned by list-sources", ""
+	}
+
+	problems := []string{}
+	for _, listed := range sources {
+		output, err := et.runCommand("source-info", "--source", listed.ID)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: source-info rejected ID %q", listed.Name, listed.ID))
+			continue
+		}
+
+		var info SourceInfo
+		if err := json.Unmarshal([]byte(output), &info); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid JSON from source-info", listed.Name))
+			continue
+		}
+
+		if !reflect.DeepEqual(info, listed) {
+			problems = append(problems, fmt.Sprintf("%s: fields differ (list-sources: %+v, source-info: %+v)", listed.Name, listed, info))
+		}
+	}
+
+	if len(problems) > 0 {
+		return false, fmt.Sprintf("%d/%d sources inconsistent", len(problems), len(sources)), strings.Join(problems, "; ")
+	}
+
+	return true, fmt.Sprintf("All %d sources consistent between list-sources and source-info", len(sources)), ""
 }
 
 // testAllSources tests all sources in the extension
@@ -243,6 +423,14 @@ func (et *ExtensionTester) testAllSources() (bool, string, string) {
 	details := []string{}
 
 	for _, source := range extInfo.Sources {
+		// Retired sources stay listed so clients can migrate, but are not expected to work
+		if source.Status == sourceDiscontinued {
+			et.report.Discontinued = append(et.report.Discontinued, source.Name)
+			details = append(details, discontinuedDetail(source))
+			totalSources--
+			continue
+		}
+
 		if et.verbose {
 			fmt.Printf("  🔍 Testing source: %s (ID: %s)\n", source.Name, source.ID)
 		}
@@ -261,8 +449,32 @@ func (et *ExtensionTester) testAllSources() (bool, string, string) {
 			continue
 		}
 
+		// Test latest updates when the source declares support for them
+		if source.SupportsLatest && !et.testSourceListing("latest", source.ID) {
+			et.report.FailedSources = append(et.report.FailedSources, source.Name)
+			details = append(details, fmt.Sprintf("%s: declares supportsLatest but latest failed", source.Name))
+			continue
+		}
+
+		// Metadata-only sources have no episodes or streams; their details must work instead
+		if source.metadataOnly() {
+			if et.testSourceDetails(source.ID) {
+				et.report.WorkingSources = append(et.report.WorkingSources, source.Name)
+				workingSources++
+				details = append(details, fmt.Sprintf("%s: ✅ metadata working (search → details)", source.Name))
+			} else {
+				et.report.FailedSources = append(et.report.FailedSources, source.Name)
+				details = append(details, fmt.Sprintf("%s: declares metadata-only but details failed", source.Name))
+			}
+			continue
+		}
+
 		// Test full pipeline (search → episodes → streams)
-		if et.testSourcePipeline(source.ID, source.Name) {
+		working, problem := et.testSourcePipeline(source)
+		if problem != "" {
+			et.report.FailedSources = append(et.report.FailedSources, source.Name)
+			details = append(details, fmt.Sprintf("%s: %s", source.Name, problem))
+		} else if working {
 			et.report.WorkingSources = append(et.report.WorkingSources, source.Name)
 			workingSources++
 			details = append(details, fmt.Sprintf("%s: ✅ full pipeline working", source.Name))
@@ -272,6 +484,9 @@ func (et *ExtensionTester) testAllSources() (bool, string, string) {
 		}
 	}
 
+	if totalSources == 0 {
+		return true, fmt.Sprintf("All %d sources discontinued, skipped", len(extInfo.Sources)), strings.Join(details, "; ")
+	}
 	if workingSources == 0 {
 		return false, "No sources working", strings.Join(details, "; ")
 	}
@@ -310,8 +525,7 @@ func (et *ExtensionTester) testSourceSearch(sourceID, sourceName string) bool {
 		}
 
 		// Check if we got results
-		var results []interface{}
-		if json.Unmarshal([]byte(output), &results) == nil && len(results) > 0 {
+		if results, ok := parseSearchResults(output); ok && len(results) > 0 {
 			return true
 		}
 	}
@@ -319,8 +533,58 @@ func (et *ExtensionTester) testSourceSearch(sourceID, sourceName string) bool {
 	return false
 }
 
-// testSourcePipeline tests the complete pipeline: search → episodes → streams
-func (et *ExtensionTester) testSourcePipeline(sourceID, sourceName string) bool {
+// testSourceListing tests a browse command such as latest that returns a page of anime
+func (et *ExtensionTester) testSourceListing(command, sourceID string) bool {
+	output, err := et.runCommand(command, "--page", "1", "--source", sourceID)
+	if err != nil {
+		return false
+	}
+
+	var results []interface{}
+	return json.Unmarshal([]byte(output), &results) == nil && len(results) > 0
+}
+
+// testSourceDetails tests that the details of the first search result can be
+// read, which is what metadata-only sources are used for
+func (et *ExtensionTester) testSourceDetails(sourceID string) bool {
+	queries := []string{"naruto", "one piece", "attack on titan"}
+
+	for _, query := range queries {
+		searchOutput, err := et.runCommand("search", "--query", query, "--page", "1", "--source", sourceID)
+		if err != nil {
+			continue
+		}
+
+		searchResults, ok := parseSearchResults(searchOutput)
+		if !ok || len(searchResults) == 0 {
+			continue
+		}
+
+		animeID, ok := searchResults[0]["anime_id"].(string)
+		if !ok {
+			continue
+		}
+
+		detailsOutput, err := et.runCommand("details", "--anime", animeID, "--source", sourceID)
+		if err != nil {
+			continue
+		}
+
+		var details map[string]interface{}
+		if json.Unmarshal([]byte(detailsOutput), &details) == nil {
+			if title, _ := details["title"].(string); title != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// testSourcePipeline tests the complete pipeline: search → episodes → streams.
+// It returns a problem when a stream URL points at the local machine or network
+// and the source is not declared as local.
+func (et *ExtensionTester) testSourcePipeline(source SourceInfo) (bool, string) {
 	queries := []string{"naruto", "one piece", "attack on titan"}
 
 	for _, query := range queries {
@@ -330,8 +594,8 @@ func (et *ExtensionTester) testSourcePipeline(sourceID, sourceName string) bool
 			continue
 		}
 
-		var searchResults []map[string]interface{}
-		if json.Unmarshal([]byte(searchOutput), &searchResults) != nil || len(searchResults) == 0 {
+		searchResults, ok := parseSearchResults(searchOutput)
+		if !ok || len(searchResults) == 0 {
 			continue
 		}
 
@@ -367,11 +631,23 @@ func (et *ExtensionTester) testSourcePipeline(sourceID, sourceName string) bool
 			continue
 		}
 
+		et.recordStreamWarnings(streamResponse)
+
 		streams, ok := streamResponse["streams"].([]interface{})
 		if !ok || len(streams) == 0 {
 			continue
 		}
 
+		if source.Type != "local" {
+			for _, stream := range streams {
+				for _, streamURL := range streamURLs(stream) {
+					if reason := unsafeStreamURL(streamURL); reason != "" {
+						return false, fmt.Sprintf("stream URL %s %s, but the source is not declared local", streamURL, reason)
+					}
+				}
+			}
+		}
+
 		// Test if first stream URL is accessible
 		firstStream, ok := streams[0].(map[string]interface{})
 		if !ok {
@@ -383,22 +659,272 @@ func (et *ExtensionTester) testSourcePipeline(sourceID, sourceName string) bool
 			continue
 		}
 
-		// Quick accessibility test
-		if et.testURLAccessibility(videoURL) {
-			return true
+		// Quick accessibility test, sending the headers the stream requires
+		headers := map[string]string{}
+		if streamHeaders, ok := firstStream["headers"].(map[string]interface{}); ok {
+			for key, value := range streamHeaders {
+				if value, ok := value.(string); ok {
+					headers[key] = value
+				}
+			}
+		}
+		if et.testURLAccessibility(videoURL, headers) {
+			return true, ""
 		}
 	}
 
-	return false
+	return false, ""
+}
+
+// recordStreamWarnings adds the provider failures listed in a stream-url
+// response's optional "warnings" array to the report
+func (et *ExtensionTester) recordStreamWarnings(streamResponse map[string]interface{}) {
+	warnings, _ := streamResponse["warnings"].([]interface{})
+	for _, entry := range warnings {
+		warning, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		source, _ := warning["source"].(string)
+		provider, _ := warning["provider"].(string)
+		reason, _ := warning["reason"].(string)
+
+		found := false
+		for i := range et.report.StreamWarnings {
+			known := &et.report.StreamWarnings[i]
+			if known.Source == source && known.Provider == provider && known.Reason == reason {
+				known.Count++
+				found = true
+				break
+			}
+		}
+		if !found {
+			et.report.StreamWarnings = append(et.report.StreamWarnings, StreamWarn{Source: source, Provider: provider, Reason: reason, Count: 1})
+		}
+	}
+}
+
+// dubQueries are shows known to have both subbed and dubbed episodes
+var dubQueries = []string{"naruto", "one piece", "attack on titan"}
+
+// testDubPipeline runs search → episodes → streams with --translation dub for
+// every source and checks the dub selection is honored end to end rather than
+// silently falling back to sub. Extensions without a --translation flag are skipped.
+func (et *ExtensionTester) testDubPipeline() (bool, string, string) {
+	extInfo, ok := et.report.ExtensionInfo.(ExtensionInfo)
+	if !ok {
+		return false, "No extension info available", "Run extension-info test first"
+	}
+
+	details := []string{}
+	problems := []string{}
+	for _, source := range extInfo.Sources {
+		// Metadata-only sources have no streams to dub
+		if source.Status == sourceDiscontinued || source.metadataOnly() {
+			continue
+		}
+		problem, detail := et.testSourceDub(source)
+		if problem == errTranslationUnsupported {
+			return true, "Translation types not supported, skipped", ""
+		}
+		if problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", source.Name, problem))
+			continue
+		}
+		details = append(details, fmt.Sprintf("%s: %s", source.Name, detail))
+	}
+
+	if len(problems) > 0 {
+		return false, "Dub selection not honored", strings.Join(append(problems, details...), "; ")
+	}
+	if len(details) == 0 {
+		return true, "No sources with streams, skipped", ""
+	}
+	return true, fmt.Sprintf("Dub pipeline working for %d sources", len(details)), strings.Join(details, "; ")
+}
+
+// errTranslationUnsupported is returned by testSourceDub for extensions without --translation
+const errTranslationUnsupported = "translation unsupported"
+
+// testSourceDub runs the dub pipeline for one source. It returns a problem, or
+// a description of what was verified.
+func (et *ExtensionTester) testSourceDub(source SourceInfo) (string, string) {
+	for _, query := range dubQueries {
+		searchOutput, err := et.runCommand("search", "--query", query, "--page", "1", "--source", source.ID, "--translation", "dub")
+		if err != nil {
+			if strings.Contains(searchOutput, "flag provided but not defined: -translation") {
+				return errTranslationUnsupported, ""
+			}
+			continue
+		}
+
+		searchResults, ok := parseSearchResults(searchOutput)
+		if !ok {
+			continue
+		}
+
+		for _, anime := range searchResults {
+			animeID, _ := anime["anime_id"].(string)
+			if episodes, _ := anime["episodes"].(float64); animeID == "" || episodes == 0 {
+				continue
+			}
+			if subDub, ok := anime["sub_dub"].(string); ok && subDub != "dub" && subDub != "both" {
+				return fmt.Sprintf("search --translation dub returned %q marked %q", animeID, subDub), ""
+			}
+
+			episodesOutput, err := et.runCommand("episodes", "--anime", animeID, "--source", source.ID, "--translation", "dub")
+			if err != nil {
+				return fmt.Sprintf("episodes --translation dub failed for %q", animeID), ""
+			}
+			var episodes []map[string]interface{}
+			if json.Unmarshal([]byte(episodesOutput), &episodes) != nil || len(episodes) == 0 {
+				return fmt.Sprintf("episodes --translation dub returned no episodes for %q", animeID), ""
+			}
+
+			// Episodes may list every translation; pick one marked as dubbed when markers exist
+			episodeNumber := 0.0
+			for _, episode := range episodes {
+				number, _ := episode["episode_number"].(float64)
+				if isDubbed(episode) {
+					episodeNumber = number
+					break
+				}
+			}
+			if episodeNumber == 0 {
+				return fmt.Sprintf("no episode of %q is marked as dubbed", animeID), ""
+			}
+
+			episodeArg := fmt.Sprintf("%g", episodeNumber)
+			dubOutput, err := et.runCommand("stream-url", "--anime", animeID, "--episode", episodeArg, "--source", source.ID, "--translation", "dub")
+			if err != nil {
+				return fmt.Sprintf("stream-url --translation dub failed for %q episode %s", animeID, episodeArg), ""
+			}
+			dubURLs := responseStreamURLs(dubOutput)
+			if len(dubURLs) == 0 {
+				return fmt.Sprintf("stream-url --translation dub returned no streams for %q episode %s", animeID, episodeArg), ""
+			}
+
+			// Identical stream URLs for sub and dub mean the dub request fell back to sub
+			subOutput, err := et.runCommand("stream-url", "--anime", animeID, "--episode", episodeArg, "--source", source.ID, "--translation", "sub")
+			if err == nil {
+				subURLs := responseStreamURLs(subOutput)
+				if len(subURLs) > 0 && strings.Join(subURLs, "\n") == strings.Join(dubURLs, "\n") {
+					return fmt.Sprintf("dub and sub streams of %q episode %s are identical", animeID, episodeArg), ""
+				}
+			}
+
+			return "", fmt.Sprintf("%d dub streams for %q episode %s", len(dubURLs), animeID, episodeArg)
+		}
+	}
+
+	return "no dubbed show found", ""
+}
+
+// isDubbed reports whether an episode entry is available dubbed. Entries
+// without sub_dub or languages markers are assumed to be.
+func isDubbed(episode map[string]interface{}) bool {
+	if subDub, ok := episode["sub_dub"].(string); ok {
+		return subDub == "dub" || subDub == "both"
+	}
+	if languages, ok := episode["languages"].([]interface{}); ok {
+		for _, language := range languages {
+			if language == "dub" {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// responseStreamURLs returns every stream and mirror URL in stream-url output
+func responseStreamURLs(output string) []string {
+	var response struct {
+		Streams []interface{} `json:"streams"`
+	}
+	if json.Unmarshal([]byte(output), &response) != nil {
+		return nil
+	}
+
+	urls := []string{}
+	for _, stream := range response.Streams {
+		urls = append(urls, streamURLs(stream)...)
+	}
+	return urls
+}
+
+// streamURLs returns the video URL and mirror URLs of a stream entry
+func streamURLs(stream interface{}) []string {
+	entry, ok := stream.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	urls := []string{}
+	if videoURL, ok := entry["videourl"].(string); ok && videoURL != "" {
+		urls = append(urls, videoURL)
+	}
+	if mirrors, ok := entry["mirrors"].([]interface{}); ok {
+		for _, mirror := range mirrors {
+			if mirrorURL, ok := mirror.(string); ok && mirrorURL != "" {
+				urls = append(urls, mirrorURL)
+			}
+		}
+	}
+	return urls
+}
+
+// unsafeStreamURL reports why a stream URL would make the player access local
+// files or the local network, or returns an empty string if it is safe
+func unsafeStreamURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "is not a valid URL"
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+	case "file":
+		return "points at a local file"
+	default:
+		return fmt.Sprintf("uses the unsupported scheme %q", parsed.Scheme)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return "points at localhost"
+	}
+
+	ips := []net.IP{}
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else if resolved, err := net.LookupIP(host); err == nil {
+		// Hostnames can resolve to private addresses too
+		ips = resolved
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			return fmt.Sprintf("points at the private address %s", ip)
+		}
+	}
+	return ""
 }
 
-// testURLAccessibility tests if a URL is accessible
-func (et *ExtensionTester) testURLAccessibility(url string) bool {
+// testURLAccessibility tests if a URL is accessible with the headers the stream declares
+func (et *ExtensionTester) testURLAccessibility(url string, headers map[string]string) bool {
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 	}
 
-	resp, err := client.Head(url)
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return false
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return false
 	}
@@ -411,6 +937,16 @@ func (et *ExtensionTester) testURLAccessibility(url string) bool {
 // testCommandStructure tests if all required commands are implemented
 func (et *ExtensionTester) testCommandStructure() (bool, string, string) {
 	requiredCommands := []string{"extension-info", "list-sources", "source-info", "search", "episodes", "stream-url"}
+	// Extensions whose sources are all metadata-only serve details in place of episodes and streams
+	if extInfo, ok := et.report.ExtensionInfo.(ExtensionInfo); ok && len(extInfo.Sources) > 0 {
+		metadataOnly := true
+		for _, source := range extInfo.Sources {
+			metadataOnly = metadataOnly && source.metadataOnly()
+		}
+		if metadataOnly {
+			requiredCommands = []string{"extension-info", "list-sources", "source-info", "search", "details"}
+		}
+	}
 	implemented := []string{}
 	missing := []string{}
 
@@ -439,59 +975,1184 @@ func (et *ExtensionTester) testCommandStructure() (bool, string, string) {
 
 // runCommand executes a command on the built binary
 func (et *ExtensionTester) runCommand(args ...string) (string, error) {
-	cmd := exec.Command(et.binaryPath, args...)
+	return et.runCommandWithEnv(nil, args...)
+}
 
-	// Get absolute path for extension directory
-	absExtensionPath, err := filepath.Abs(et.extensionPath)
+// runCommandWithEnv executes a command on the built binary with extra
+// environment variables. It returns the data of a successful command's
+// envelope, and stdout followed by stderr otherwise.
+func (et *ExtensionTester) runCommandWithEnv(extraEnv []string, args ...string) (string, error) {
+	cmd, err := et.command(extraEnv, args...)
 	if err != nil {
 		return "", err
 	}
-	cmd.Dir = absExtensionPath
 
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	before := et.bandwidthSoFar()
+	err = cmd.Run()
+	et.recordCommandUsage(args, before)
+	if err == nil {
+		if data, ok := envelopeData(stdout.String()); ok {
+			return data, nil
+		}
+	}
+	return stdout.String() + stderr.String(), err
 }
 
-// generateRecommendations generates recommendations based on test results
-func (et *ExtensionTester) generateRecommendations() {
-	recommendations := []string{}
-
-	// Check overall results
-	if et.report.TestsPassed == 0 {
-		recommendations = append(recommendations, "Start with implementing basic extension-info command")
+// runCommandSplit executes a command on the built binary and returns stdout and stderr separately
+func (et *ExtensionTester) runCommandSplit(args ...string) (string, string, error) {
+	cmd, err := et.command(nil, args...)
+	if err != nil {
+		return "", "", err
 	}
 
-	if len(et.report.WorkingSources) == 0 && len(et.report.FailedSources) > 0 {
-		recommendations = append(recommendations, "Focus on fixing source connectivity and search functionality")
-	}
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	before := et.bandwidthSoFar()
+	err = cmd.Run()
+	et.recordCommandUsage(args, before)
+	return stdout.String(), stderr.String(), err
+}
 
-	if et.report.TestsPassed < et.report.TestsRun/2 {
-		recommendations = append(recommendations, "Review implementation.md for detailed requirements")
+// bandwidthSoFar returns the bytes downloaded through the recording proxy so far
+func (et *ExtensionTester) bandwidthSoFar() int64 {
+	if et.proxy == nil {
+		return 0
 	}
+	return et.proxy.totalBandwidth()
+}
 
-	// Specific recommendations based on failed tests
-	for _, test := range et.report.Tests {
-		if !test.Passed {
-			switch {
-			case strings.Contains(test.Name, "Build"):
-				recommendations = append(recommendations, "Fix compilation errors before proceeding")
-			case strings.Contains(test.Name, "JSON"):
-				recommendations = append(recommendations, "Ensure all outputs are valid JSON format")
-			case strings.Contains(test.Name, "Source"):
-				recommendations = append(recommendations, "Check network connectivity and API endpoints")
-			}
-		}
+// recordCommandUsage attributes the bytes downloaded since before to the
+// command in args. Commands run one at a time, so the difference is the
+// command's own bandwidth.
+func (et *ExtensionTester) recordCommandUsage(args []string, before int64) {
+	if et.proxy == nil || len(args) == 0 {
+		return
 	}
+	if et.commandUsage == nil {
+		et.commandUsage = map[string]*CommandUse{}
+	}
+	usage, ok := et.commandUsage[args[0]]
+	if !ok {
+		usage = &CommandUse{Command: args[0]}
+		et.commandUsage[args[0]] = usage
+	}
+	usage.Runs++
+	usage.BandwidthBytes += et.bandwidthSoFar() - before
+}
 
-	et.report.Recommendations = recommendations
+// recordBandwidth copies the bandwidth measured by the recording proxy into the report
+func (et *ExtensionTester) recordBandwidth() {
+	et.report.BandwidthBytes = et.bandwidthSoFar()
+	et.report.CommandUsage = []CommandUse{}
+	for _, usage := range et.commandUsage {
+		et.report.CommandUsage = append(et.report.CommandUsage, *usage)
+	}
+	sort.Slice(et.report.CommandUsage, func(i, j int) bool {
+		a, b := et.report.CommandUsage[i], et.report.CommandUsage[j]
+		if a.BandwidthBytes != b.BandwidthBytes {
+			return a.BandwidthBytes > b.BandwidthBytes
+		}
+		return a.Command < b.Command
+	})
 }
 
-// printReport prints the test report in the specified format
-func (et *ExtensionTester) printReport() {
-	et.report.OverallResult = et.report.TestsPassed > 0 && len(et.report.WorkingSources) > 0
+// command prepares a command on the built binary inside the tester sandbox
+func (et *ExtensionTester) command(extraEnv []string, args ...string) (*exec.Cmd, error) {
+	cmd := exec.Command(et.binaryPath, args...)
 
-	switch et.outputFormat {
-	case "json":
+	// Get absolute path for extension directory
+	absExtensionPath, err := filepath.Abs(et.extensionPath)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Dir = absExtensionPath
+
+	// Tag the process tree so leaked children can be found, and point temp
+	// files and persistent storage at the designated directories
+	cmd.Env = os.Environ()
+	if et.runID != "" {
+		cmd.Env = append(cmd.Env, "PAIR_TESTER_RUN="+et.runID)
+	}
+	if et.tempDir != "" {
+		cmd.Env = append(cmd.Env, "TMPDIR="+et.tempDir)
+	}
+	if et.homeDir != "" {
+		cmd.Env = append(cmd.Env, "HOME="+et.homeDir)
+	}
+	for envVar, dir := range et.storageDirs {
+		cmd.Env = append(cmd.Env, envVar+"="+dir)
+	}
+	if et.proxy != nil {
+		cmd.Env = append(cmd.Env, proxyEnv(et.proxy.listener)...)
+	}
+	cmd.Env = append(cmd.Env, extraEnv...)
+	return cmd, nil
+}
+
+// checkStdoutPurity reports why stdout is not exactly one JSON value, or an empty string if it is
+func checkStdoutPurity(stdout string) string {
+	if !utf8.ValidString(stdout) {
+		return "stdout is not valid UTF-8"
+	}
+	if strings.HasPrefix(stdout, "\uFEFF") {
+		return "stdout starts with a byte order mark"
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(stdout))
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Sprintf("stdout is not JSON (%v): %s", err, truncate(stdout, 60))
+	}
+
+	// Anything after the JSON value, such as a trailing log line, corrupts the frontend's parsing
+	if rest := strings.TrimSpace(stdout[decoder.InputOffset():]); rest != "" {
+		return fmt.Sprintf("extra output after the JSON value: %s", truncate(rest, 60))
+	}
+	return ""
+}
+
+// envelopeData returns the data of the {"status": "success", "data": ...}
+// envelope pair's extension client reads, reporting whether stdout is one
+func envelopeData(stdout string) (string, bool) {
+	var output struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal([]byte(stdout), &output) != nil || output.Status != "success" || output.Data == nil {
+		return "", false
+	}
+	return string(output.Data), true
+}
+
+// parseSearchResults decodes search output, which is either a bare array of
+// anime or an object holding them in results alongside pagination metadata
+func parseSearchResults(output string) ([]map[string]interface{}, bool) {
+	var results []map[string]interface{}
+	if json.Unmarshal([]byte(output), &results) == nil {
+		return results, true
+	}
+
+	var page struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if json.Unmarshal([]byte(output), &page) != nil || page.Results == nil {
+		return nil, false
+	}
+	return page.Results, true
+}
+
+// checkErrorOutput reports why the stdout of a failed command is not an error
+// envelope such as {"status": "error", "error": "...", "code": "network"}, or
+// an empty string if it is
+func checkErrorOutput(stdout string) string {
+	if problem := checkStdoutPurity(stdout); problem != "" {
+		return problem
+	}
+	var output struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Code   string `json:"code"`
+	}
+	if json.Unmarshal([]byte(stdout), &output) != nil || output.Status != "error" {
+		return "output on stdout that is not an error envelope: " + truncate(stdout, 60)
+	}
+	if output.Code == "" || output.Error == "" {
+		return "an error envelope without a code and message: " + truncate(stdout, 60)
+	}
+	return ""
+}
+
+// truncate shortens s to at most n runes for display
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
+
+// testStdoutPurity checks that commands write only their JSON payload to
+// stdout and send banners, progress and log lines to stderr
+func (et *ExtensionTester) testStdoutPurity() (bool, string, string) {
+	commands := [][]string{
+		{"extension-info"},
+		{"list-sources"},
+		{"source-info"},
+		{"search", "--query", "naruto", "--page", "1"},
+	}
+
+	problems := []string{}
+	skipped := []string{}
+	checked := 0
+	var animeID string
+	for i := 0; i < len(commands); i++ {
+		args := commands[i]
+		name := strings.Join(args, " ")
+		stdout, _, err := et.runCommandSplit(args...)
+
+		// Failing commands (e.g. without network) may leave stdout empty or
+		// print an error object, but must not print junk to it
+		if err != nil {
+			if strings.TrimSpace(stdout) != "" {
+				if problem := checkErrorOutput(stdout); problem != "" {
+					problems = append(problems, fmt.Sprintf("%s: failed with %s", name, problem))
+					continue
+				}
+			}
+			skipped = append(skipped, name)
+			continue
+		}
+
+		checked++
+		if problem := checkStdoutPurity(stdout); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, problem))
+			continue
+		}
+		data, ok := envelopeData(stdout)
+		if !ok {
+			problems = append(problems, fmt.Sprintf(`%s: stdout is not the {"status": "success", "data": ...} envelope pair reads: %s`, name, truncate(stdout, 60)))
+			continue
+		}
+		stdout = data
+
+		// Follow the search result into episodes and streams
+		switch args[0] {
+		case "search":
+			if results, ok := parseSearchResults(stdout); ok && len(results) > 0 {
+				animeID, _ = results[0]["anime_id"].(string)
+				if animeID != "" {
+					commands = append(commands, []string{"episodes", "--anime", animeID})
+				}
+			}
+		case "episodes":
+			var episodes []map[string]interface{}
+			if json.Unmarshal([]byte(stdout), &episodes) == nil && len(episodes) > 0 {
+				if number, ok := episodes[0]["episode_number"].(float64); ok {
+					commands = append(commands, []string{"stream-url", "--anime", animeID, "--episode", fmt.Sprintf("%g", number)})
+				}
+			}
+		}
+	}
+
+	details := ""
+	if len(skipped) > 0 {
+		details = fmt.Sprintf("Failed without stdout output, not checked: %s", strings.Join(skipped, ", "))
+	}
+
+	if len(problems) > 0 {
+		return false, "Non-JSON output on stdout", strings.Join(append(problems, details), "; ")
+	}
+
+	return true, fmt.Sprintf("%d commands write only JSON to stdout", checked), details
+}
+
+// offlineCommands lists commands that must work without any network access
+var offlineCommands = []string{"extension-info", "list-sources", "capabilities", "version", "filters"}
+
+// blockingProxy is an HTTP proxy that refuses every request and counts the attempts
+type blockingProxy struct {
+	listener net.Listener
+	mu       sync.Mutex
+	attempts []string
+}
+
+// startBlockingProxy starts a blocking proxy on a random local port
+func startBlockingProxy() (*blockingProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := &blockingProxy{listener: listener}
+	go http.Serve(listener, proxy)
+	return proxy, nil
+}
+
+// ServeHTTP records the attempted request and refuses it
+func (p *blockingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.attempts = append(p.attempts, r.Host)
+	p.mu.Unlock()
+	http.Error(w, "network access blocked by extension tester", http.StatusForbidden)
+}
+
+// takeAttempts returns and clears the recorded request hosts
+func (p *blockingProxy) takeAttempts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	attempts := p.attempts
+	p.attempts = nil
+	return attempts
+}
+
+// env returns environment variables routing all HTTP(S) traffic through the proxy
+func (p *blockingProxy) env() []string {
+	return proxyEnv(p.listener)
+}
+
+// proxyEnv returns environment variables routing all HTTP(S) traffic through
+// the proxy listening on listener
+func proxyEnv(listener net.Listener) []string {
+	proxyURL := "http://" + listener.Addr().String()
+	return []string{
+		"HTTP_PROXY=" + proxyURL, "HTTPS_PROXY=" + proxyURL, "ALL_PROXY=" + proxyURL,
+		"http_proxy=" + proxyURL, "https_proxy=" + proxyURL, "all_proxy=" + proxyURL,
+		"NO_PROXY=", "no_proxy=",
+	}
+}
+
+// recordingProxy is an HTTP proxy that forwards requests, tunnels HTTPS and
+// counts the requests and downloaded bytes per host. Tunnelled bytes include
+// TLS overhead, so they match what a metered connection is billed for.
+type recordingProxy struct {
+	listener net.Listener
+	mu       sync.Mutex
+	hosts    map[string]int
+	bytes    map[string]int64
+}
+
+// startRecordingProxy starts a recording proxy on a random local port
+func startRecordingProxy() (*recordingProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := &recordingProxy{listener: listener, hosts: map[string]int{}, bytes: map[string]int64{}}
+	go http.Serve(listener, proxy)
+	return proxy, nil
+}
+
+// ServeHTTP records the request host and forwards the request
+func (p *recordingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	p.mu.Lock()
+	p.hosts[host]++
+	p.mu.Unlock()
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r, host)
+		return
+	}
+
+	// Plain HTTP requests arrive with an absolute URL and are forwarded as they are
+	r.RequestURI = ""
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(&countingWriter{Writer: w, proxy: p, host: host}, resp.Body)
+}
+
+// countingWriter adds the bytes written to the client to a host's download count
+type countingWriter struct {
+	io.Writer
+	proxy *recordingProxy
+	host  string
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.proxy.mu.Lock()
+	w.proxy.bytes[w.host] += int64(n)
+	w.proxy.mu.Unlock()
+	return n, err
+}
+
+// tunnel connects a CONNECT request to its target and copies bytes both ways
+func (p *recordingProxy) tunnel(w http.ResponseWriter, r *http.Request, host string) {
+	target, err := net.DialTimeout("tcp", r.Host, 30*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		target.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		target.Close()
+		return
+	}
+	client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+
+	go func() {
+		defer target.Close()
+		defer client.Close()
+		io.Copy(target, client)
+	}()
+	go func() {
+		defer target.Close()
+		defer client.Close()
+		io.Copy(&countingWriter{Writer: client, proxy: p, host: strings.ToLower(host)}, target)
+	}()
+}
+
+// snapshot returns the contacted hosts with their request counts
+func (p *recordingProxy) snapshot() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hosts := make(map[string]int, len(p.hosts))
+	for host, count := range p.hosts {
+		hosts[host] = count
+	}
+	return hosts
+}
+
+// bandwidth returns the downloaded bytes per host
+func (p *recordingProxy) bandwidth() map[string]int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	bytes := make(map[string]int64, len(p.bytes))
+	for host, n := range p.bytes {
+		bytes[host] = n
+	}
+	return bytes
+}
+
+// totalBandwidth returns the bytes downloaded through the proxy so far
+func (p *recordingProxy) totalBandwidth() int64 {
+	var total int64
+	for _, n := range p.bandwidth() {
+		total += n
+	}
+	return total
+}
+
+// hostDeclared reports whether host is covered by the declared network
+// domains: an exact match or "*.domain" for its subdomains. A bare "*" covers
+// nothing, so hosts are always checked against the explicit entries.
+func hostDeclared(host string, declared []string) bool {
+	for _, domain := range declared {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		switch {
+		case strings.HasPrefix(domain, "*."):
+			if strings.HasSuffix(host, domain[1:]) {
+				return true
+			}
+		case host == domain:
+			return true
+		}
+	}
+	return false
+}
+
+// testNetworkPermissions compares the hosts contacted during the whole test
+// run with the network domains declared in extension-info
+func (et *ExtensionTester) testNetworkPermissions() (bool, string, string) {
+	if et.proxy == nil {
+		return false, "Recording proxy unavailable", "Outgoing requests could not be recorded"
+	}
+
+	info, ok := et.report.ExtensionInfo.(ExtensionInfo)
+	if !ok {
+		return false, "Extension info unavailable", "The Extension Info test must pass before network permissions can be checked"
+	}
+	declared := []string{}
+	if info.Permissions != nil {
+		declared = info.Permissions.Network
+	}
+
+	hosts := et.proxy.snapshot()
+	bandwidth := et.proxy.bandwidth()
+	names := make([]string, 0, len(hosts))
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+
+	undeclared := []string{}
+	et.report.NetworkHosts = []HostAccess{}
+	for _, host := range names {
+		access := HostAccess{Host: host, Requests: hosts[host], BandwidthBytes: bandwidth[host], Declared: hostDeclared(host, declared)}
+		et.report.NetworkHosts = append(et.report.NetworkHosts, access)
+		if !access.Declared {
+			undeclared = append(undeclared, host)
+		}
+	}
+
+	// "*" hides which hosts an extension talks to from users and from this check
+	wildcard := slices.ContainsFunc(declared, func(domain string) bool { return strings.TrimSpace(domain) == "*" })
+	problems := []string{}
+	if wildcard {
+		problems = append(problems, "\"*\" is declared; list the API, stream and hoster domains instead")
+	}
+	if len(undeclared) > 0 {
+		problems = append(problems, fmt.Sprintf("undeclared: %s", strings.Join(undeclared, ", ")))
+	}
+
+	if len(problems) > 0 {
+		message := fmt.Sprintf("Contacted %d undeclared host(s)", len(undeclared))
+		if wildcard {
+			message = "Declares \"*\" for network access"
+		}
+		return false, message, fmt.Sprintf("%s; declared: %s", strings.Join(problems, "; "), strings.Join(declared, ", "))
+	}
+	return true, fmt.Sprintf("All %d contacted hosts are declared", len(names)), ""
+}
+
+// testOfflineCommands checks that purely local commands succeed without making
+// network requests, using a proxy that blocks and records every request
+func (et *ExtensionTester) testOfflineCommands() (bool, string, string) {
+	proxy, err := startBlockingProxy()
+	if err != nil {
+		return false, "Failed to start blocking proxy", err.Error()
+	}
+	defer proxy.listener.Close()
+
+	passed := []string{}
+	skipped := []string{}
+	problems := []string{}
+	for _, command := range offlineCommands {
+		output, err := et.runCommandWithEnv(proxy.env(), command)
+		attempts := proxy.takeAttempts()
+
+		if err != nil && strings.Contains(strings.ToLower(output), "unknown command") {
+			skipped = append(skipped, command)
+			continue
+		}
+
+		switch {
+		case len(attempts) > 0:
+			problems = append(problems, fmt.Sprintf("%s made %d network request(s) to %s", command, len(attempts), strings.Join(attempts, ", ")))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s failed without network access: %v", command, err))
+		default:
+			passed = append(passed, command)
+		}
+	}
+
+	details := ""
+	if len(skipped) > 0 {
+		details = fmt.Sprintf("Not implemented: %s", strings.Join(skipped, ", "))
+	}
+
+	if len(problems) > 0 {
+		return false, "Offline commands require network access", strings.Join(append(problems, details), "; ")
+	}
+
+	return true, fmt.Sprintf("%d commands work offline (%s)", len(passed), strings.Join(passed, ", ")), details
+}
+
+// semverPattern matches the X.Y.Z versions releases are numbered with
+var semverPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// testVersionAgreement checks that extension-info, the version command, the
+// manifest and the release tag (named <pkg>-vX.Y.Z) all carry the same version
+func (et *ExtensionTester) testVersionAgreement() (bool, string, string) {
+	stdout, _, err := et.runCommandSplit("extension-info")
+	if err != nil {
+		return false, "Extension-info command failed", err.Error()
+	}
+	data, ok := envelopeData(stdout)
+	if !ok {
+		return false, "Extension-info does not print the success envelope", truncate(stdout, 60)
+	}
+	var info ExtensionInfo
+	if err := json.Unmarshal([]byte(data), &info); err != nil {
+		return false, "Invalid JSON output", fmt.Sprintf("JSON parse error: %v", err)
+	}
+	if !semverPattern.MatchString(info.Version) {
+		return false, "Version is not in X.Y.Z form", fmt.Sprintf("extension-info reports %q", info.Version)
+	}
+
+	checked := []string{"extension-info"}
+	skipped := []string{}
+	problems := []string{}
+	if et.version != "" && info.Version != et.version {
+		problems = append(problems, fmt.Sprintf("extension-info reports %q although %q was stamped with -X main.version", info.Version, et.version))
+	}
+	compare := func(what, version string) {
+		if version != info.Version {
+			problems = append(problems, fmt.Sprintf("%s reports %q, extension-info %q", what, version, info.Version))
+			return
+		}
+		checked = append(checked, what)
+	}
+
+	stdout, stderr, err := et.runCommandSplit("version")
+	versionData, _ := envelopeData(stdout)
+	var versionOutput struct {
+		Version string `json:"version"`
+	}
+	switch {
+	case err != nil && strings.Contains(strings.ToLower(stdout+stderr), "unknown command"):
+		skipped = append(skipped, "version command (not implemented)")
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("version command failed: %v", err))
+	case json.Unmarshal([]byte(versionData), &versionOutput) != nil:
+		problems = append(problems, "version command does not print the success envelope")
+	default:
+		compare("version command", versionOutput.Version)
+	}
+
+	if et.manifestPath == "" {
+		skipped = append(skipped, "manifest (no -manifest given)")
+	} else if data, err := os.ReadFile(et.manifestPath); err != nil {
+		problems = append(problems, fmt.Sprintf("cannot read manifest: %v", err))
+	} else {
+		var manifest ExtensionInfo
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid manifest JSON: %v", err))
+		} else if manifest.Package != info.Package {
+			problems = append(problems, fmt.Sprintf("manifest is for package %q, not %q", manifest.Package, info.Package))
+		} else {
+			compare("manifest", manifest.Version)
+		}
+	}
+
+	prefix := info.Package + "-v"
+	tag := et.tag
+	if tag == "" {
+		tag = et.releaseTag(prefix)
+	}
+	switch {
+	case tag == "":
+		skipped = append(skipped, fmt.Sprintf("tag (HEAD is not tagged %sX.Y.Z)", prefix))
+	case !strings.HasPrefix(tag, prefix):
+		problems = append(problems, fmt.Sprintf("tag %q does not follow the %sX.Y.Z convention", tag, prefix))
+	default:
+		compare("tag "+tag, strings.TrimPrefix(tag, prefix))
+	}
+
+	details := ""
+	if len(skipped) > 0 {
+		details = fmt.Sprintf("Not checked: %s", strings.Join(skipped, ", "))
+	}
+
+	if len(problems) > 0 {
+		return false, "Release versions disagree", strings.Join(append(problems, details), "; ")
+	}
+
+	return true, fmt.Sprintf("Version %s agrees across %s", info.Version, strings.Join(checked, ", ")), details
+}
+
+// releaseTag returns the git tag with the given prefix pointing at the
+// extension's HEAD commit, or "" when there is none or git is unavailable
+func (et *ExtensionTester) releaseTag(prefix string) string {
+	output, err := exec.Command("git", "-C", et.extensionPath, "tag", "--points-at", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	for _, tag := range strings.Fields(string(output)) {
+		if strings.HasPrefix(tag, prefix) {
+			return tag
+		}
+	}
+	return ""
+}
+
+// incompatibleLicenses lists licenses whose terms conflict with distributing
+// extensions as prebuilt binaries alongside the rest of the repository
+var incompatibleLicenses = map[string]bool{"GPL-3.0": true, "GPL-2.0": true, "AGPL-3.0": true, "SSPL": true}
+
+// licenseMarkers identifies a license from distinctive phrases in its text, most specific first
+var licenseMarkers = []struct {
+	marker  string
+	license string
+}{
+	{"GNU AFFERO GENERAL PUBLIC LICENSE", "AGPL-3.0"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE", "LGPL"},
+	{"Version 3, 29 June 2007", "GPL-3.0"},
+	{"GNU GENERAL PUBLIC LICENSE", "GPL-2.0"},
+	{"Server Side Public License", "SSPL"},
+	{"Mozilla Public License", "MPL-2.0"},
+	{"Apache License", "Apache-2.0"},
+	{"Permission is hereby granted, free of charge", "MIT"},
+	{"Redistribution and use in source and binary forms", "BSD"},
+	{"ISC License", "ISC"},
+	{"This is free and unencumbered software released into the public domain", "Unlicense"},
+}
+
+// findGoMod returns the path of the go.mod governing dir
+func findGoMod(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no go.mod found")
+		}
+		dir = parent
+	}
+}
+
+// parseGoModRequires returns the required modules of a go.mod file
+func parseGoModRequires(data string) []Dependency {
+	deps := []Dependency{}
+	inBlock := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = strings.TrimSpace(line[:comment])
+		}
+
+		switch {
+		case line == "require (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inBlock:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			deps = append(deps, Dependency{Path: fields[0], Version: fields[1]})
+		}
+	}
+	return deps
+}
+
+// escapeModulePath applies the module cache case encoding (uppercase letters become !lowercase)
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			b.WriteRune(r + ('a' - 'A'))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// detectLicense identifies the license of a module from its copy in the module cache
+func detectLicense(modCache string, dep Dependency) string {
+	dir := filepath.Join(modCache, escapeModulePath(dep.Path)+"@"+dep.Version)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "unknown (module not downloaded)"
+	}
+
+	for _, entry := range entries {
+		name := strings.ToUpper(entry.Name())
+		if entry.IsDir() || !(strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "LICENCE") || strings.HasPrefix(name, "COPYING")) {
+			continue
+		}
+
+		text, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, marker := range licenseMarkers {
+			if strings.Contains(string(text), marker.marker) {
+				return marker.license
+			}
+		}
+		return "unknown"
+	}
+	return "none"
+}
+
+// queryOSV looks up known vulnerabilities for the dependencies in the OSV database
+func queryOSV(deps []Dependency) (map[string][]string, error) {
+	type osvQuery struct {
+		Package struct {
+			Name      string `json:"name"`
+			Ecosystem string `json:"ecosystem"`
+		} `json:"package"`
+		Version string `json:"version"`
+	}
+
+	queries := []osvQuery{}
+	for _, dep := range deps {
+		var q osvQuery
+		q.Package.Name = dep.Path
+		q.Package.Ecosystem = "Go"
+		q.Version = strings.TrimPrefix(dep.Version, "v")
+		queries = append(queries, q)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"queries": queries})
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Post("https://api.osv.dev/v1/querybatch", "application/json", strings.NewReader(string(payload)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OSV returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Results []struct {
+			Vulns []struct {
+				ID string `json:"id"`
+			} `json:"vulns"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	vulns := map[string][]string{}
+	for i, result := range response.Results {
+		if i >= len(deps) {
+			break
+		}
+		for _, vuln := range result.Vulns {
+			vulns[deps[i].Path] = append(vulns[deps[i].Path], vuln.ID)
+		}
+	}
+	return vulns, nil
+}
+
+// testDependencyAudit checks the extension's dependencies for incompatible
+// licenses and known vulnerabilities, recording the findings in the report
+func (et *ExtensionTester) testDependencyAudit() (bool, string, string) {
+	goModPath, err := findGoMod(et.extensionPath)
+	if err != nil {
+		return false, "Cannot locate go.mod", err.Error()
+	}
+
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return false, "Cannot read go.mod", err.Error()
+	}
+
+	deps := parseGoModRequires(string(data))
+	if len(deps) == 0 {
+		return true, "No dependencies to audit", ""
+	}
+
+	modCache := os.Getenv("GOMODCACHE")
+	if modCache == "" {
+		if output, err := exec.Command("go", "env", "GOMODCACHE").Output(); err == nil {
+			modCache = strings.TrimSpace(string(output))
+		}
+	}
+
+	notes := []string{}
+	vulns, err := queryOSV(deps)
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("vulnerability lookup skipped: %v", err))
+	}
+
+	problems := []string{}
+	for i := range deps {
+		deps[i].License = detectLicense(modCache, deps[i])
+		deps[i].Vulnerabilities = vulns[deps[i].Path]
+
+		if incompatibleLicenses[deps[i].License] {
+			problems = append(problems, fmt.Sprintf("%s@%s uses incompatible license %s", deps[i].Path, deps[i].Version, deps[i].License))
+		}
+		if len(deps[i].Vulnerabilities) > 0 {
+			problems = append(problems, fmt.Sprintf("%s@%s has known vulnerabilities: %s", deps[i].Path, deps[i].Version, strings.Join(deps[i].Vulnerabilities, ", ")))
+		}
+		if strings.HasPrefix(deps[i].License, "unknown") || deps[i].License == "none" {
+			notes = append(notes, fmt.Sprintf("%s: license %s", deps[i].Path, deps[i].License))
+		}
+	}
+	et.report.Dependencies = deps
+
+	if len(problems) > 0 {
+		return false, fmt.Sprintf("%d dependency issue(s) found", len(problems)), strings.Join(append(problems, notes...), "; ")
+	}
+
+	return true, fmt.Sprintf("%d dependencies audited, no issues found", len(deps)), strings.Join(notes, "; ")
+}
+
+// storageEnvVars maps the storage environment variables handed to extensions to
+// their sandbox subdirectory. PAIR_* variables take precedence over XDG ones.
+var storageEnvVars = map[string]string{
+	"PAIR_DATA_DIR":   "data",
+	"PAIR_CACHE_DIR":  "cache",
+	"XDG_DATA_HOME":   "xdg-data",
+	"XDG_CACHE_HOME":  "xdg-cache",
+	"XDG_CONFIG_HOME": "xdg-config",
+	"XDG_STATE_HOME":  "xdg-state",
+}
+
+// prepareSandbox creates the designated temp, home and storage directories and
+// snapshots the system temp and extension directories before the extension runs
+func (et *ExtensionTester) prepareSandbox() error {
+	et.runID = fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+
+	sandboxDir, err := os.MkdirTemp("", "extension-tester-")
+	if err != nil {
+		return err
+	}
+	et.sandboxDir = sandboxDir
+
+	et.tempDir = filepath.Join(sandboxDir, "tmp")
+	et.homeDir = filepath.Join(sandboxDir, "home")
+	et.storageDirs = map[string]string{}
+	for envVar, subdir := range storageEnvVars {
+		et.storageDirs[envVar] = filepath.Join(sandboxDir, subdir)
+	}
+
+	for _, dir := range append([]string{et.tempDir, et.homeDir}, mapValues(et.storageDirs)...) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	proxy, err := startRecordingProxy()
+	if err != nil {
+		return err
+	}
+	et.proxy = proxy
+
+	et.tempSnapshot = snapshotDir(os.TempDir())
+	et.extSnapshot = snapshotDir(et.extensionPath)
+	return nil
+}
+
+// cleanupSandbox removes the designated directories and stops the recording proxy
+func (et *ExtensionTester) cleanupSandbox() {
+	if et.proxy != nil {
+		et.proxy.listener.Close()
+	}
+	if et.sandboxDir != "" {
+		os.RemoveAll(et.sandboxDir)
+	}
+}
+
+// mapValues returns the values of m
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
+
+// listFiles returns the paths of all files below dir, relative to dir
+func listFiles(dir string) []string {
+	files := []string{}
+	filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files
+}
+
+// testStorageDirectories checks that files written by the extension (cache,
+// state, cookies) land under PAIR_DATA_DIR/PAIR_CACHE_DIR or the XDG
+// directories rather than the home directory or working directory
+func (et *ExtensionTester) testStorageDirectories() (bool, string, string) {
+	if et.sandboxDir == "" {
+		return false, "Storage sandbox unavailable", "The designated directories could not be created"
+	}
+
+	problems := []string{}
+	if stray := listFiles(et.homeDir); len(stray) > 0 {
+		problems = append(problems, fmt.Sprintf("files written to home directory: %s", strings.Join(stray, ", ")))
+	}
+	if stray := newEntries(et.extensionPath, et.extSnapshot, filepath.Base(et.binaryPath)); len(stray) > 0 {
+		problems = append(problems, fmt.Sprintf("files written to working directory: %s", strings.Join(stray, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return false, "Extension wrote files outside the designated directories", strings.Join(problems, "; ")
+	}
+
+	stored := 0
+	for _, dir := range et.storageDirs {
+		stored += len(listFiles(dir))
+	}
+	return true, fmt.Sprintf("Storage respects the designated directories (%d files stored)", stored), ""
+}
+
+// snapshotDir returns the set of entry names in dir
+func snapshotDir(dir string) map[string]bool {
+	snapshot := map[string]bool{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return snapshot
+	}
+	for _, entry := range entries {
+		snapshot[entry.Name()] = true
+	}
+	return snapshot
+}
+
+// newEntries returns the entries of dir that are not in the snapshot, ignoring the given names
+func newEntries(dir string, snapshot map[string]bool, ignore ...string) []string {
+	ignored := map[string]bool{}
+	for _, name := range ignore {
+		ignored[name] = true
+	}
+
+	added := []string{}
+	for name := range snapshotDir(dir) {
+		if !snapshot[name] && !ignored[name] {
+			added = append(added, name)
+		}
+	}
+	return added
+}
+
+// findOrphanProcesses returns the PIDs and command lines of processes still carrying the run marker.
+// Only Linux exposes process environments through /proc; other platforms report no orphans.
+func (et *ExtensionTester) findOrphanProcesses() []string {
+	if runtime.GOOS != "linux" || et.runID == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	marker := "PAIR_TESTER_RUN=" + et.runID
+	orphans := []string{}
+	for _, entry := range entries {
+		pid := entry.Name()
+		if pid == "" || pid[0] < '0' || pid[0] > '9' {
+			continue
+		}
+
+		environ, err := os.ReadFile(filepath.Join("/proc", pid, "environ"))
+		if err != nil {
+			continue
+		}
+
+		for _, variable := range strings.Split(string(environ), "\x00") {
+			if variable == marker {
+				cmdline, _ := os.ReadFile(filepath.Join("/proc", pid, "cmdline"))
+				orphans = append(orphans, fmt.Sprintf("pid %s (%s)", pid, strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))))
+				break
+			}
+		}
+	}
+	return orphans
+}
+
+// testResourceLeaks checks that the extension left no running child processes
+// and no temp files outside the designated temp directory
+func (et *ExtensionTester) testResourceLeaks() (bool, string, string) {
+	problems := []string{}
+
+	if orphans := et.findOrphanProcesses(); len(orphans) > 0 {
+		problems = append(problems, fmt.Sprintf("orphan processes: %s", strings.Join(orphans, ", ")))
+	}
+
+	if leaked := newEntries(os.TempDir(), et.tempSnapshot, filepath.Base(et.sandboxDir)); len(leaked) > 0 {
+		problems = append(problems, fmt.Sprintf("files left in %s: %s", os.TempDir(), strings.Join(leaked, ", ")))
+	}
+
+	if leaked := newEntries(et.extensionPath, et.extSnapshot, filepath.Base(et.binaryPath)); len(leaked) > 0 {
+		problems = append(problems, fmt.Sprintf("files left in extension directory: %s", strings.Join(leaked, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return false, "Extension leaked resources", strings.Join(problems, "; ")
+	}
+
+	return true, "No orphan processes or leaked temp files", ""
+}
+
+// generateRecommendations generates recommendations based on test results
+func (et *ExtensionTester) generateRecommendations() {
+	recommendations := []string{}
+
+	// Check overall results
+	if et.report.TestsPassed == 0 {
+		recommendations = append(recommendations, "Start with implementing basic extension-info command")
+	}
+
+	if len(et.report.WorkingSources) == 0 && len(et.report.FailedSources) > 0 {
+		recommendations = append(recommendations, "Focus on fixing source connectivity and search functionality")
+	}
+
+	if et.report.TestsPassed < et.report.TestsRun/2 {
+		recommendations = append(recommendations, "Review implementation.md for detailed requirements")
+	}
+
+	// Specific recommendations based on failed tests
+	for _, test := range et.report.Tests {
+		if !test.Passed {
+			switch {
+			case strings.Contains(test.Name, "Build"):
+				recommendations = append(recommendations, "Fix compilation errors before proceeding")
+			case strings.Contains(test.Name, "JSON"):
+				recommendations = append(recommendations, "Ensure all outputs are valid JSON format")
+			case strings.Contains(test.Name, "Source"):
+				recommendations = append(recommendations, "Check network connectivity and API endpoints")
+			}
+		}
+	}
+
+	// Flag commands that download far more than metadata and playlists need
+	for _, usage := range et.report.CommandUsage {
+		if usage.Runs > 0 && usage.BandwidthBytes/int64(usage.Runs) > heavyCommandBytes {
+			recommendations = append(recommendations, fmt.Sprintf("The %s command downloads %s per run; prefer API endpoints over full pages and avoid downloading media to probe it",
+				usage.Command, formatBytes(usage.BandwidthBytes/int64(usage.Runs))))
+		}
+	}
+
+	et.report.Recommendations = recommendations
+}
+
+// heavyCommandBytes is the average download per command run above which the tester recommends slimming it down
+const heavyCommandBytes = 5 << 20
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 MiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// printReport prints the test report in the specified format
+func (et *ExtensionTester) printReport() {
+	// An extension whose sources are all discontinued passes without working sources
+	retired := len(et.report.Discontinued) > 0 && len(et.report.FailedSources) == 0
+	et.report.OverallResult = et.report.TestsPassed > 0 && (len(et.report.WorkingSources) > 0 || retired)
+
+	switch et.outputFormat {
+	case "json":
 		et.printJSONReport()
 	case "detailed":
 		et.printDetailedReport()
@@ -526,6 +2187,27 @@ func (et *ExtensionTester) printSummaryReport() {
 		}
 	}
 
+	if len(et.report.Discontinued) > 0 {
+		fmt.Printf("\n⏭️  Discontinued Sources (%d):\n", len(et.report.Discontinued))
+		for _, source := range et.report.Discontinued {
+			fmt.Printf("  ⏭️  %s\n", source)
+		}
+	}
+
+	if len(et.report.StreamWarnings) > 0 {
+		fmt.Printf("\n⚠️  Provider Warnings (%d):\n", len(et.report.StreamWarnings))
+		for _, warning := range et.report.StreamWarnings {
+			fmt.Printf("  ⚠️  %s: %s (%dx)\n", warning.Source, warning.Reason, warning.Count)
+		}
+	}
+
+	if et.report.BandwidthBytes > 0 {
+		fmt.Printf("\n📶 Bandwidth: %s downloaded\n", formatBytes(et.report.BandwidthBytes))
+		for _, usage := range et.report.CommandUsage {
+			fmt.Printf("  %s: %s over %d run(s)\n", usage.Command, formatBytes(usage.BandwidthBytes), usage.Runs)
+		}
+	}
+
 	if len(et.report.Recommendations) > 0 {
 		fmt.Printf("\n💡 Recommendations:\n")
 		for i, rec := range et.report.Recommendations {
@@ -594,15 +2276,48 @@ func (et *ExtensionTester) RunTests() {
 		return
 	}
 
+	if err := et.prepareSandbox(); err != nil {
+		fmt.Printf("⚠️  Could not prepare sandbox directories: %v\n", err)
+	}
+	defer et.cleanupSandbox()
+
 	// Test 2: Extension Info
 	et.runTest("Extension Info Command", et.testExtensionInfo)
 
 	// Test 3: Command Structure
 	et.runTest("Command Structure", et.testCommandStructure)
 
-	// Test 4: Source Testing
+	// Test 4: Offline Commands
+	et.runTest("Offline Commands", et.testOfflineCommands)
+
+	// Test 5: Source Consistency
+	et.runTest("Source Consistency", et.testSourceConsistency)
+
+	// Test 6: Source Testing
 	et.runTest("Source Testing", et.testAllSources)
 
+	// Test 7: Dub Pipeline
+	et.runTest("Dub Pipeline", et.testDubPipeline)
+
+	// Test 8: Resource Leaks
+	et.runTest("Resource Leaks", et.testResourceLeaks)
+
+	// Test 9: Storage Directories
+	et.runTest("Storage Directories", et.testStorageDirectories)
+
+	// Test 10: Dependency Audit
+	et.runTest("Dependency Audit", et.testDependencyAudit)
+
+	// Test 11: Stdout Purity
+	et.runTest("Stdout Purity", et.testStdoutPurity)
+
+	// Test 12: Version Agreement
+	et.runTest("Version Agreement", et.testVersionAgreement)
+
+	// Test 13: Network Permissions, after every other command has run through the proxy
+	et.runTest("Network Permissions", et.testNetworkPermissions)
+	et.recordBandwidth()
+
 	et.report.Duration = time.Since(start).String()
 	et.generateRecommendations()
 
@@ -619,6 +2334,9 @@ func main() {
 		extensionPath = flag.String("path", ".", "Path to the extension directory")
 		verbose       = flag.Bool("verbose", false, "Enable verbose output")
 		outputFormat  = flag.String("format", "summary", "Output format: summary, detailed, json")
+		version       = flag.String("version", "", "Release version to stamp into the build with -ldflags \"-X main.version=...\"")
+		manifest      = flag.String("manifest", "", "Published extension.json whose version must match the binary")
+		tag           = flag.String("tag", "", "Release tag whose version must match the binary (default: the <pkg>-v* tag at HEAD)")
 		help          = flag.Bool("help", false, "Show help message")
 	)
 
@@ -634,6 +2352,8 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s -path ./src/allanime -verbose\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Get detailed report in JSON format\n")
 		fmt.Fprintf(os.Stderr, "  %s -path ./src/myextension -format json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Check a release against its manifest and tag\n")
+		fmt.Fprintf(os.Stderr, "  %s -path ./src/allanime -version 0.1.1 -manifest bin/allanime.json -tag allanime-v0.1.1\n\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -678,6 +2398,9 @@ func main() {
 
 	// Create and run tester
 	tester := NewExtensionTester(*extensionPath, *verbose, *outputFormat)
+	tester.version = *version
+	tester.manifestPath = *manifest
+	tester.tag = *tag
 	tester.RunTests()
 
 	// Exit with appropriate code
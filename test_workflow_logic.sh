@@ -8,21 +8,24 @@ echo ""
 
 # Test extension
 EXTENSION="allanime"
-BINARY_PATH="./bin/allanime-linux-x86_64"
+BUILD_DIR=$(mktemp -d)
+BINARY_PATH="$BUILD_DIR/$EXTENSION-test"
 
 echo "Testing extension: $EXTENSION"
 
-# Test binary exists
-if [ ! -f "$BINARY_PATH" ]; then
-    echo "❌ Binary not found: $BINARY_PATH"
+# Build the binary from the current source, as the workflow does, so the
+# checks never run against a stale build that predates the output envelope
+go build -o "$BINARY_PATH" "./src/$EXTENSION" || {
+    echo "❌ Build failed: ./src/$EXTENSION"
     exit 1
-fi
-echo "✅ Binary found: $BINARY_PATH"
+}
+echo "✅ Binary built: $BINARY_PATH"
 
 # Test extension-info command
 echo ""
 echo "=== Testing extension-info command ==="
-output=$($BINARY_PATH extension-info)
+# Output comes wrapped in pair's {"status","data"} envelope
+output=$($BINARY_PATH extension-info | jq '.data')
 echo "Extension info output:"
 echo "$output"
 
@@ -96,6 +99,7 @@ echo "✅ Sources extraction works correctly"
 
 # Cleanup
 rm -f "$temp_file"
+rm -rf "$BUILD_DIR"
 
 echo ""
 echo "=== All tests passed! ==="